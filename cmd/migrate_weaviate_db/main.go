@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/startup/weaviate_migrations"
+
+	_ "github.com/joho/godotenv/autoload"
+)
+
+func main() {
+	status := flag.Bool("status", false, "Print the current events class version and pending migrations, then exit")
+	dryRun := flag.Bool("dry-run", false, "Report what would be migrated without writing anything")
+	batchSize := flag.Int("batch-size", weaviate_migrations.DEFAULT_BATCH_SIZE, "Objects read and written per request")
+	maxPasses := flag.Int("max-passes", weaviate_migrations.DEFAULT_MAX_PASSES, "Copy passes to attempt before giving up on the counts matching")
+	drainWait := flag.Duration("drain-wait", weaviate_migrations.DEFAULT_DRAIN_WAIT, "How long to wait after switching classes before copying late writes")
+	resyncFrom := flag.String("resync-from", "", "Only copy late writes from this old class into the class the alias points at")
+	pointTo := flag.String("point-to", "", "Move the alias straight to this class without copying, to roll back a migration")
+	flag.Parse()
+
+	ctx := context.Background()
+	client, err := services.GetWeaviateClient()
+	if err != nil {
+		log.Fatalf("FATAL: Could not get Weaviate client: %v", err)
+	}
+
+	if *status {
+		schemaAlias, err := services.GetSchemaAlias(ctx, client, services.EVENT_CLASS_ALIAS)
+		if err != nil {
+			log.Fatalf("FATAL: Could not read the '%s' alias: %v", services.EVENT_CLASS_ALIAS, err)
+		}
+		if schemaAlias == nil {
+			log.Printf("The '%s' alias is not set yet, it is created on the next app start or migration run", services.EVENT_CLASS_ALIAS)
+			return
+		}
+		log.Printf("'%s' -> '%s' (version %d)", schemaAlias.Alias, schemaAlias.ClassName, schemaAlias.Version)
+		for _, migration := range weaviate_migrations.Pending(schemaAlias.Version) {
+			log.Printf("pending: %d '%s' %s", migration.Version, migration.ClassName, migration.Description)
+		}
+		return
+	}
+
+	if *pointTo != "" {
+		schemaAlias, err := weaviate_migrations.PointEventAlias(ctx, client, *pointTo)
+		if err != nil {
+			log.Fatalf("FATAL: Could not point the '%s' alias at '%s': %v", services.EVENT_CLASS_ALIAS, *pointTo, err)
+		}
+		log.Printf("'%s' -> '%s' (version %d)", schemaAlias.Alias, schemaAlias.ClassName, schemaAlias.Version)
+		return
+	}
+
+	opts := weaviate_migrations.Options{
+		BatchSize: *batchSize,
+		MaxPasses: *maxPasses,
+		DrainWait: *drainWait,
+		DryRun:    *dryRun,
+	}
+
+	if *resyncFrom != "" {
+		result, err := weaviate_migrations.Resync(ctx, client, *resyncFrom, opts)
+		if err != nil {
+			log.Fatalf("FATAL: Resync from '%s' failed: %v", *resyncFrom, err)
+		}
+		log.Printf("Resync complete: copied %d late writes from '%s' to '%s' (%d -> %d objects)",
+			result.Stragglers, result.FromClass, result.ToClass, result.SourceCount, result.TargetCount)
+		return
+	}
+
+	results, err := weaviate_migrations.Run(ctx, client, opts)
+	for _, result := range results {
+		log.Printf("Migration %d '%s' -> '%s': %d source objects, %d target objects, copied %d, pruned %d, %d late writes, %d passes (dry run: %t)",
+			result.Version, result.FromClass, result.ToClass, result.SourceCount, result.TargetCount,
+			result.Copied, result.Pruned, result.Stragglers, result.Passes, result.DryRun)
+	}
+	if err != nil {
+		log.Fatalf("FATAL: %v", err)
+	}
+	if len(results) == 0 {
+		log.Println("No pending Weaviate migrations.")
+	}
+}
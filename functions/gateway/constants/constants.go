@@ -29,6 +29,9 @@ const CompetitionWaitingRoomParticipantTablePrefix = "CompetitionWaitingRoomPart
 const VotesTablePrefix = "Votes"
 
 // const WeaviateEventClassName = "EventStrict" // old version
+
+// WeaviateEventClassName is the events class fresh installs start on. Running
+// instances follow the `Event` schema alias instead, see MIGRATIONS.md
 const WeaviateEventClassName = "EventStrict_2025_11_07_000000"
 
 const ACT string = "ACT"
@@ -36,7 +39,13 @@ const EVENT_ID_KEY string = "eventId"
 const PRIMARY_OWNER_KEY string = "primaryOwner"
 const COMPETITIONS_ID_KEY string = "competitionId"
 const ROUND_NUMBER_KEY string = "roundNumber"
+const RECURRENCE_ID_KEY string = "recurrenceId"
 const USER_ID_KEY string = "userId"
+const DATA_EXPORT_ID_KEY string = "dataExportId"
+const QUARANTINED_EVENT_ID_KEY string = "quarantinedEventId"
+const WEBHOOK_ID_KEY string = "webhookId"
+const WEBHOOK_DELIVERY_ID_KEY string = "webhookDeliveryId"
+const API_KEY_ID_KEY string = "apiKeyId"
 const SUBDOMAIN_KEY = "subdomain"
 const INTERESTS_KEY = "interests"
 const META_ABOUT_KEY = "about"
@@ -44,6 +53,7 @@ const META_LOC_KEY = "loc"
 const ERR_KV_KEY_EXISTS = "key already exists in KV store"
 const GO_TEST_ENV = "test"
 const MNM_OPTIONS_CTX_KEY = "mnmOptions"
+const PAGE_META_CTX_KEY = "pageMeta"
 
 const PKCE_VERIFIER_COOKIE_NAME = "mnm_pkce_verifier"
 const MNM_ACCESS_TOKEN_COOKIE_NAME = "mnm_access_token"
@@ -59,9 +69,15 @@ const AUTH_METADATA_KEY = "urn:zitadel:iam:user:metadata"
 const GO_ACT_SERVER_PORT = "8000"
 
 const DEFAULT_PAGINATION_LIMIT = 50
+const DEFAULT_EVENT_SEARCH_LIMIT = 100
+const MAX_EVENT_SEARCH_LIMIT = 500
+const MAX_MAP_SEARCH_LIMIT = 2000
 const DEFAULT_MAX_RADIUS = 999999
 const DEFAULT_SEARCH_RADIUS = 500.0
 const DEFAULT_EXPANDED_SEARCH_RADIUS = 2500.0
+const DEFAULT_SIMILAR_EVENTS_LIMIT = 6
+const MAX_SIMILAR_EVENTS_LIMIT = 24
+const DEFAULT_SIMILAR_EVENTS_RADIUS = 100.0
 
 // INITIAL_EMPTY_LAT_LONG represents an intentionally invalid coordinate value
 // used to distinguish between missing location data and valid coordinates (including 0,0 "null island")
@@ -70,12 +86,21 @@ const INITIAL_EMPTY_LAT_LONG = 9e+10
 // placeholder for unset end time, December 4th, 292,277,026,596 AD, at 20:10:55 UTC
 const DEFAULT_UNDEFINED_END_TIME = math.MaxInt64
 
+// OPEN_ENDED_EVENT_DURATION is how long, in seconds, an event without an end
+// time is assumed to run when searching for events that overlap a window
+const OPEN_ENDED_EVENT_DURATION = 3 * 60 * 60
+
 const EventOwnerNameDelimiter = " _|_ "
 
 const EV_MODE_CAROUSEL = "CAROUSEL"
 const EV_MODE_UPCOMING = "DETAILED"
 const EV_MODE_LIST = "LIST"
 const EV_MODE_ADMIN_LIST = "ADMIN_LIST"
+
+// Home feed order for signed in users, anonymous users always get the
+// chronological feed
+const FEED_MODE_FOR_YOU = "for_you"
+const FEED_MODE_CHRONOLOGICAL = "chronological"
 const UNPUB_SUFFIX = "_UNPUB"
 
 // NOTE: used by the frontend dropdown, but not included in the event source type string
@@ -96,7 +121,8 @@ const (
 )
 
 const (
-	SESHU_KNOWN_SOURCE_FB = "FACEBOOK"
+	SESHU_KNOWN_SOURCE_FB  = "FACEBOOK"
+	SESHU_KNOWN_SOURCE_ICS = "ICS"
 )
 
 // TIME_COMPRESSION_RATIO controls the speed of time-dependent operations for testing
@@ -288,6 +314,19 @@ type SitePage struct {
 	SubnavItems []string
 }
 
+// PageMeta is the Open Graph / Twitter card and structured data a page handler
+// stores under PAGE_META_CTX_KEY for `Layout` to render in the document head
+type PageMeta struct {
+	Title       string
+	Description string
+	Url         string
+	ImageUrl    string
+	// Open Graph object type, e.g. "website" or "profile"
+	OgType string
+	// Serialized as-is into a `application/ld+json` script, nil to omit
+	JSONLD interface{}
+}
+
 var SitePages = map[string]SitePage{
 	// NOTE: the {trailingslash:\\/?} is required for a route to match with or without a trailing slash, the
 	// solution is from this github comment (see discussion as well) https://github.com/gorilla/mux/issues/30#issuecomment-1666428538
@@ -339,6 +378,12 @@ var EventFields struct {
 	UpdatedBy             string
 	RefUrl                string
 	HideCrossPromo        string
+	RecurrenceRule        string
+	RecurrenceStart       string
+	RecurrenceRDates      string
+	RecurrenceExDates     string
+	RecurrenceId          string
+	RecurrenceOverride    string
 	LocalizedStartDate    string
 	LocalizedStartTime    string
 }
@@ -376,9 +421,34 @@ type Event struct {
 	ShadowOwners          []string      `json:"shadowOwners,omitempty"`
 	SourceUrl             string        `json:"sourceUrl,omitempty"`
 
+	// Series parents may carry an RFC 5545 recurrence set, the rule is
+	// anchored at RecurrenceStart (StartTime rolls forward to the next
+	// occurrence). Times are unix seconds
+	RecurrenceRule    string  `json:"recurrenceRule,omitempty"`
+	RecurrenceStart   int64   `json:"recurrenceStart,omitempty"`
+	RecurrenceRDates  []int64 `json:"recurrenceRDates,omitempty"`
+	RecurrenceExDates []int64 `json:"recurrenceExDates,omitempty"`
+	// Series children: the originally scheduled start of the occurrence, and
+	// whether it was edited by hand and must not be regenerated from the rule
+	RecurrenceId       int64 `json:"recurrenceId,omitempty"`
+	RecurrenceOverride bool  `json:"recurrenceOverride,omitempty"`
+
 	// New fields for UI use only
 	LocalizedStartDate string `json:"localStartDate,omitempty"`
 	LocalizedStartTime string `json:"localStartTime,omitempty"`
+	// Set only when the event was ranked into a "for you" feed
+	Rank *EventRank `json:"rank,omitempty"`
+}
+
+// EventRank explains where an event landed in a personalized feed. Each
+// signal is in [0, 1] and Score is their weighted sum
+type EventRank struct {
+	Score     float64  `json:"score"`
+	Interest  float64  `json:"interest"`
+	Recency   float64  `json:"recency"`
+	Proximity float64  `json:"proximity"`
+	Owner     float64  `json:"owner"`
+	Reasons   []string `json:"reasons,omitempty"`
 }
 
 func init() {
@@ -473,6 +543,20 @@ func humanizeFieldName(field string) string {
 		return "Competition Config ID"
 	case "ShadowOwners":
 		return "Shadow Owners"
+	case "RecurrenceRule":
+		return "Recurrence Rule"
+	case "RecurrenceStart":
+		return "Recurrence Start"
+	case "RecurrenceRDates":
+		return "Additional Dates"
+	case "RecurrenceExDates":
+		return "Excluded Dates"
+	case "RecurrenceId":
+		return "Recurrence ID"
+	case "RecurrenceOverride":
+		return "Recurrence Override"
+	case "Rank":
+		return "Feed Rank"
 	default:
 		panic(fmt.Sprintf("No display name mapping for field: %s", field))
 	}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/services/dynamodb_service"
+	"github.com/meetnearme/api/functions/gateway/transport"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+// ACCOUNT_DELETION_CONFIRMATION must be typed back to request a deletion
+const ACCOUNT_DELETION_CONFIRMATION = "DELETE"
+
+// AccountDeletionPayload confirms the request. `UserId` lets a superAdmin act
+// on someone else's account
+type AccountDeletionPayload struct {
+	Confirm string `json:"confirm"`
+	UserId  string `json:"userId"`
+}
+
+type AccountDeletionHandler struct {
+	Store func(ctx context.Context) (interfaces.PostgresServiceInterface, error)
+}
+
+func NewAccountDeletionHandler() *AccountDeletionHandler {
+	return &AccountDeletionHandler{Store: services.GetPostgresService}
+}
+
+// GetAccountDeletionSources wires the stores an account deletion is applied to
+func GetAccountDeletionSources(ctx context.Context) (services.AccountDeletionSources, error) {
+	eventStore, err := services.GetEventStore()
+	if err != nil {
+		return services.AccountDeletionSources{}, fmt.Errorf("failed to get event store: %w", err)
+	}
+	postgresService, err := services.GetPostgresService(ctx)
+	if err != nil {
+		return services.AccountDeletionSources{}, fmt.Errorf("failed to get postgres service: %w", err)
+	}
+	return services.AccountDeletionSources{
+		Events:           eventStore,
+		DynamoDB:         transport.GetDB(),
+		Purchases:        dynamodb_service.NewPurchaseService(),
+		CompetitionVotes: dynamodb_service.NewCompetitionVoteService(),
+		WaitingRoom:      dynamodb_service.NewCompetitionWaitingRoomParticipantService(),
+		Postgres:         postgresService,
+		Stripe:           services.NewStripeSubscriptionService(),
+		DeleteSubdomain:  helpers.DeleteSubdomainFromDB,
+		DeleteIdentity:   helpers.DeleteZitadelUser,
+	}, nil
+}
+
+// RequestAccountDeletion schedules the account for deletion after the grace
+// period. The body must carry `"confirm": "DELETE"`
+func (h *AccountDeletionHandler) RequestAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	var payload AccountDeletionPayload
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to read request body: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(string(body)) != "" {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			transport.SendServerRes(w, []byte("Invalid JSON payload: "+err.Error()), http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+
+	userId, requestedBy, ok := getAccountDeletionUser(w, r, payload.UserId)
+	if !ok {
+		return
+	}
+	if payload.Confirm != ACCOUNT_DELETION_CONFIRMATION {
+		transport.SendServerRes(w, []byte(`Confirm the deletion by sending "confirm": "`+ACCOUNT_DELETION_CONFIRMATION+`"`), http.StatusBadRequest, nil)
+		return
+	}
+
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	deletion, err := services.RequestAccountDeletion(r.Context(), store, userId, requestedBy, time.Now().UTC())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to request account deletion: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	sendAccountDeletion(w, deletion, http.StatusAccepted)
+}
+
+// GetAccountDeletion reports the account's deletion, including the receipt
+// of each store it has been applied to
+func (h *AccountDeletionHandler) GetAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	userId, _, ok := getAccountDeletionUser(w, r, r.URL.Query().Get("userId"))
+	if !ok {
+		return
+	}
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	deletion, err := store.GetAccountDeletion(r.Context(), userId)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get account deletion: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	if deletion == nil {
+		transport.SendServerRes(w, []byte(services.ErrAccountDeletionNotFound.Error()), http.StatusNotFound, nil)
+		return
+	}
+	sendAccountDeletion(w, *deletion, http.StatusOK)
+}
+
+// CancelAccountDeletion withdraws a deletion that's still in its grace period
+func (h *AccountDeletionHandler) CancelAccountDeletion(w http.ResponseWriter, r *http.Request) {
+	userId, canceledBy, ok := getAccountDeletionUser(w, r, r.URL.Query().Get("userId"))
+	if !ok {
+		return
+	}
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	deletion, err := services.CancelAccountDeletion(r.Context(), store, userId, time.Now().UTC())
+	switch {
+	case errors.Is(err, services.ErrAccountDeletionNotFound):
+		transport.SendServerRes(w, []byte(err.Error()), http.StatusNotFound, nil)
+		return
+	case errors.Is(err, services.ErrAccountDeletionStarted):
+		transport.SendServerRes(w, []byte(err.Error()), http.StatusConflict, nil)
+		return
+	case err != nil:
+		transport.SendServerRes(w, []byte("Failed to cancel account deletion: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	log.Printf("INFO: account deletion for user %s canceled by %s", userId, canceledBy)
+	sendAccountDeletion(w, deletion, http.StatusOK)
+}
+
+// getAccountDeletionUser resolves whose account the request is about. Only a
+// superAdmin can name another user
+func getAccountDeletionUser(w http.ResponseWriter, r *http.Request, targetUserId string) (string, string, bool) {
+	userInfo := constants.UserInfo{}
+	if _, ok := r.Context().Value("userInfo").(constants.UserInfo); ok {
+		userInfo = r.Context().Value("userInfo").(constants.UserInfo)
+	}
+	if userInfo.Sub == "" {
+		transport.SendServerRes(w, []byte("Missing user ID"), http.StatusUnauthorized, nil)
+		return "", "", false
+	}
+	if targetUserId == "" || targetUserId == userInfo.Sub {
+		return userInfo.Sub, userInfo.Sub, true
+	}
+	roleClaims := []constants.RoleClaim{}
+	if claims, ok := r.Context().Value("roleClaims").([]constants.RoleClaim); ok {
+		roleClaims = claims
+	}
+	if !helpers.HasRequiredRole(roleClaims, []string{constants.Roles[constants.SuperAdmin]}) {
+		transport.SendServerRes(w, []byte("Only super admins can manage another user's account deletion"), http.StatusForbidden, nil)
+		return "", "", false
+	}
+	return targetUserId, userInfo.Sub, true
+}
+
+func sendAccountDeletion(w http.ResponseWriter, deletion types.AccountDeletion, status int) {
+	res, err := json.Marshal(deletion)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, res, status, nil)
+}
+
+func RequestAccountDeletionHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewAccountDeletionHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.RequestAccountDeletion(w, r)
+	}
+}
+
+func GetAccountDeletionHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewAccountDeletionHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.GetAccountDeletion(w, r)
+	}
+}
+
+func CancelAccountDeletionHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewAccountDeletionHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.CancelAccountDeletion(w, r)
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/test_helpers"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+func TestAccountDeletionHandlers(t *testing.T) {
+	deletions := map[string]types.AccountDeletion{}
+	store := &test_helpers.MockPostgresService{
+		GetAccountDeletionFunc: func(ctx context.Context, userId string) (*types.AccountDeletion, error) {
+			deletion, ok := deletions[userId]
+			if !ok {
+				return nil, nil
+			}
+			return &deletion, nil
+		},
+		SaveAccountDeletionFunc: func(ctx context.Context, deletion types.AccountDeletion) error {
+			deletions[deletion.UserId] = deletion
+			return nil
+		},
+	}
+	handler := &AccountDeletionHandler{Store: func(ctx context.Context) (interfaces.PostgresServiceInterface, error) {
+		return store, nil
+	}}
+
+	superAdmin := []constants.RoleClaim{{Role: constants.Roles[constants.SuperAdmin]}}
+	serve := func(method, target, body, userId string, roleClaims []constants.RoleClaim) (*httptest.ResponseRecorder, types.AccountDeletion) {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		ctx := req.Context()
+		if userId != "" {
+			ctx = context.WithValue(ctx, "userInfo", constants.UserInfo{Sub: userId})
+		}
+		if roleClaims != nil {
+			ctx = context.WithValue(ctx, "roleClaims", roleClaims)
+		}
+		req = req.WithContext(ctx)
+		rr := httptest.NewRecorder()
+		switch method {
+		case http.MethodPost:
+			handler.RequestAccountDeletion(rr, req)
+		case http.MethodGet:
+			handler.GetAccountDeletion(rr, req)
+		case http.MethodDelete:
+			handler.CancelAccountDeletion(rr, req)
+		}
+		var deletion types.AccountDeletion
+		if rr.Code < 400 {
+			if err := json.Unmarshal(rr.Body.Bytes(), &deletion); err != nil {
+				t.Fatalf("failed to decode deletion: %v", err)
+			}
+		}
+		return rr, deletion
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		body       string
+		userId     string
+		roleClaims []constants.RoleClaim
+		wantStatus int
+	}{
+		{name: "requires a user", method: http.MethodPost, target: "/api/account-deletion", body: `{"confirm":"DELETE"}`, wantStatus: http.StatusUnauthorized},
+		{name: "requires confirmation", method: http.MethodPost, target: "/api/account-deletion", body: `{"confirm":"yes"}`, userId: "user-1", wantStatus: http.StatusBadRequest},
+		{name: "rejects bad json", method: http.MethodPost, target: "/api/account-deletion", body: `{"confirm":`, userId: "user-1", wantStatus: http.StatusUnprocessableEntity},
+		{name: "only super admins delete other users", method: http.MethodPost, target: "/api/account-deletion", body: `{"confirm":"DELETE","userId":"user-2"}`, userId: "user-1", wantStatus: http.StatusForbidden},
+		{name: "nothing requested yet", method: http.MethodGet, target: "/api/account-deletion", userId: "user-1", wantStatus: http.StatusNotFound},
+		{name: "nothing to cancel", method: http.MethodDelete, target: "/api/account-deletion", userId: "user-1", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if rr, _ := serve(tt.method, tt.target, tt.body, tt.userId, tt.roleClaims); rr.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("self service request and cancel", func(t *testing.T) {
+		rr, deletion := serve(http.MethodPost, "/api/account-deletion", `{"confirm":"DELETE"}`, "user-1", nil)
+		if rr.Code != http.StatusAccepted || deletion.UserId != "user-1" || deletion.Status != services.ACCOUNT_DELETION_STATUS_PENDING {
+			t.Fatalf("expected a pending deletion, got %d %+v", rr.Code, deletion)
+		}
+		if rr, got := serve(http.MethodGet, "/api/account-deletion", "", "user-1", nil); rr.Code != http.StatusOK || got.ScheduledFor != deletion.ScheduledFor {
+			t.Errorf("expected the deletion back, got %d %+v", rr.Code, got)
+		}
+		if rr, got := serve(http.MethodDelete, "/api/account-deletion", "", "user-1", nil); rr.Code != http.StatusOK || got.Status != services.ACCOUNT_DELETION_STATUS_CANCELED {
+			t.Errorf("expected the deletion canceled, got %d %+v", rr.Code, got)
+		}
+	})
+
+	t.Run("super admin deletes another user", func(t *testing.T) {
+		rr, deletion := serve(http.MethodPost, "/api/account-deletion", `{"confirm":"DELETE","userId":"user-2"}`, "admin-1", superAdmin)
+		if rr.Code != http.StatusAccepted || deletion.UserId != "user-2" || deletion.RequestedBy != "admin-1" {
+			t.Fatalf("expected a deletion of user-2 requested by admin-1, got %d %+v", rr.Code, deletion)
+		}
+		if rr, _ := serve(http.MethodGet, "/api/account-deletion?userId=user-2", "", "user-1", nil); rr.Code != http.StatusForbidden {
+			t.Errorf("expected another user to be refused, got %d", rr.Code)
+		}
+		if rr, got := serve(http.MethodGet, "/api/account-deletion?userId=user-2", "", "admin-1", superAdmin); rr.Code != http.StatusOK || got.UserId != "user-2" {
+			t.Errorf("expected the super admin to see it, got %d %+v", rr.Code, got)
+		}
+	})
+
+	t.Run("started deletions can't be canceled", func(t *testing.T) {
+		deletions["user-3"] = types.AccountDeletion{UserId: "user-3", Status: services.ACCOUNT_DELETION_STATUS_FAILED, Receipt: types.AccountDeletionReceipt{{Step: services.ACCOUNT_DELETION_STEP_EVENTS}}}
+		if rr, _ := serve(http.MethodDelete, "/api/account-deletion", "", "user-3", nil); rr.Code != http.StatusConflict {
+			t.Errorf("expected status %d, got %d", http.StatusConflict, rr.Code)
+		}
+	})
+}
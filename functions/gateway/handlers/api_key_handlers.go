@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/transport"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+type APIKeysHandler struct {
+	Store func(ctx context.Context) (interfaces.PostgresServiceInterface, error)
+}
+
+func NewAPIKeysHandler() *APIKeysHandler {
+	return &APIKeysHandler{Store: services.GetPostgresService}
+}
+
+// GetAPIKeys lists the logged-in user's keys, revoked ones included
+func (h *APIKeysHandler) GetAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userId, _, ok := getOwnerUser(w, r)
+	if !ok {
+		return
+	}
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	keys, err := store.GetAPIKeys(r.Context(), userId)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get API keys: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	if keys == nil {
+		keys = []types.APIKey{}
+	}
+	res, err := json.Marshal(keys)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, res, http.StatusOK, nil)
+}
+
+// CreateAPIKey issues a key acting as the logged-in user. The response is
+// the only time the key itself is shown
+func (h *APIKeysHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userInfo, _ := r.Context().Value("userInfo").(constants.UserInfo)
+	userId, _, ok := getOwnerUser(w, r)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to read request body: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+	var insert types.APIKeyInsert
+	if err := json.Unmarshal(body, &insert); err != nil {
+		transport.SendServerRes(w, []byte("Invalid JSON payload: "+err.Error()), http.StatusUnprocessableEntity, err)
+		return
+	}
+	if err := validate.Struct(&insert); err != nil {
+		transport.SendServerRes(w, []byte("Invalid body: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+	created, err := services.NewAPIKey(userInfo, insert, time.Now())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Invalid body: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	existing, err := store.GetAPIKeys(r.Context(), userId)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get API keys: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	active := 0
+	for _, key := range existing {
+		if key.RevokedAt == 0 {
+			active++
+		}
+	}
+	if active >= services.MAX_API_KEYS {
+		msg := fmt.Sprintf("You already have %d API keys, revoke one to add another", active)
+		transport.SendServerRes(w, []byte(msg), http.StatusConflict, nil)
+		return
+	}
+	if err := store.SaveAPIKey(r.Context(), created.APIKey); err != nil {
+		transport.SendServerRes(w, []byte("Failed to save API key: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+
+	res, err := json.Marshal(created)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, res, http.StatusCreated, nil)
+}
+
+// RevokeAPIKey stops a key from working. It stays listed with `revokedAt`
+func (h *APIKeysHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userId, isSuperAdmin, ok := getOwnerUser(w, r)
+	if !ok {
+		return
+	}
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	key, err := store.GetAPIKey(r.Context(), mux.Vars(r)[constants.API_KEY_ID_KEY])
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get API key: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	if key == nil || (key.OwnerId != userId && !isSuperAdmin) {
+		transport.SendServerRes(w, []byte("API key not found"), http.StatusNotFound, nil)
+		return
+	}
+	if key.RevokedAt == 0 {
+		key.RevokedAt = time.Now().Unix()
+		if err := store.SaveAPIKey(r.Context(), *key); err != nil {
+			transport.SendServerRes(w, []byte("Failed to revoke API key: "+err.Error()), http.StatusInternalServerError, err)
+			return
+		}
+		log.Printf("INFO: revoked API key %s (%s) of %s", key.Id, key.Prefix, key.OwnerId)
+	}
+
+	res, err := json.Marshal(key)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, res, http.StatusOK, nil)
+}
+
+func GetAPIKeysHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewAPIKeysHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.GetAPIKeys(w, r)
+	}
+}
+
+func CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewAPIKeysHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.CreateAPIKey(w, r)
+	}
+}
+
+func RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewAPIKeysHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.RevokeAPIKey(w, r)
+	}
+}
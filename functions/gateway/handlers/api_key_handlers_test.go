@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/test_helpers"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+func TestAPIKeysHandlers(t *testing.T) {
+	keys := map[string]types.APIKey{
+		"key-1": {Id: "key-1", OwnerId: "user-1", Name: "sync", KeyHash: "hash-1", Scopes: types.APIKeyScopes{services.API_KEY_SCOPE_EVENTS_READ}},
+		"key-2": {Id: "key-2", OwnerId: "user-2", Name: "other", KeyHash: "hash-2", Scopes: types.APIKeyScopes{services.API_KEY_SCOPE_EVENTS_READ}},
+	}
+	store := &test_helpers.MockPostgresService{
+		GetAPIKeysFunc: func(ctx context.Context, ownerId string) ([]types.APIKey, error) {
+			found := []types.APIKey{}
+			for _, key := range keys {
+				if key.OwnerId == ownerId {
+					found = append(found, key)
+				}
+			}
+			return found, nil
+		},
+		GetAPIKeyFunc: func(ctx context.Context, id string) (*types.APIKey, error) {
+			key, ok := keys[id]
+			if !ok {
+				return nil, nil
+			}
+			return &key, nil
+		},
+		SaveAPIKeyFunc: func(ctx context.Context, key types.APIKey) error {
+			keys[key.Id] = key
+			return nil
+		},
+	}
+	handler := &APIKeysHandler{
+		Store: func(ctx context.Context) (interfaces.PostgresServiceInterface, error) {
+			return store, nil
+		},
+	}
+
+	newRequest := func(method, body, userId string, vars map[string]string) *http.Request {
+		req := httptest.NewRequest(method, "/api/api-keys", bytes.NewBufferString(body))
+		if vars != nil {
+			req = mux.SetURLVars(req, vars)
+		}
+		ctx := req.Context()
+		if userId != "" {
+			ctx = context.WithValue(ctx, "userInfo", constants.UserInfo{Sub: userId, Email: userId + "@example.com"})
+		}
+		return req.WithContext(ctx)
+	}
+
+	t.Run("requires a user", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.GetAPIKeys(rr, newRequest(http.MethodGet, "", "", nil))
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+
+	t.Run("lists own keys without hashes", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.GetAPIKeys(rr, newRequest(http.MethodGet, "", "user-1", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var listed []types.APIKey
+		if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil {
+			t.Fatalf("failed to decode API keys: %v", err)
+		}
+		if len(listed) != 1 || listed[0].Id != "key-1" {
+			t.Errorf("expected key-1 only, got %+v", listed)
+		}
+		if strings.Contains(rr.Body.String(), "hash-1") {
+			t.Errorf("expected no key hashes in the response, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("create validates the body", func(t *testing.T) {
+		for _, body := range []string{
+			`{"name":"sync","scopes":[]}`,
+			`{"scopes":["events:read"]}`,
+			`{"name":"sync","scopes":["events:delete"]}`,
+		} {
+			rr := httptest.NewRecorder()
+			handler.CreateAPIKey(rr, newRequest(http.MethodPost, body, "user-1", nil))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d: %s", body, http.StatusBadRequest, rr.Code, rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("create returns the key once", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.CreateAPIKey(rr, newRequest(http.MethodPost, `{"name":"importer","scopes":["events:write"]}`, "user-3", nil))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+		var created types.CreatedAPIKey
+		if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+			t.Fatalf("failed to decode API key: %v", err)
+		}
+		if created.OwnerId != "user-3" || !strings.HasPrefix(created.Key, "mnm_") {
+			t.Errorf("unexpected API key %+v", created)
+		}
+		saved := keys[created.Id]
+		if saved.KeyHash != services.HashAPIKey(created.Key) {
+			t.Errorf("expected the key to be saved hashed, got %+v", saved)
+		}
+		if saved.UserInfo.Email != "user-3@example.com" {
+			t.Errorf("expected the owner's user info to be saved, got %+v", saved.UserInfo)
+		}
+	})
+
+	t.Run("create is limited per owner", func(t *testing.T) {
+		for i := 0; i < services.MAX_API_KEYS; i++ {
+			id := fmt.Sprintf("key-full-%d", i)
+			keys[id] = types.APIKey{Id: id, OwnerId: "user-full"}
+		}
+		rr := httptest.NewRecorder()
+		handler.CreateAPIKey(rr, newRequest(http.MethodPost, `{"name":"one more","scopes":["events:read"]}`, "user-full", nil))
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status %d, got %d: %s", http.StatusConflict, rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("someone else's key is not found", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.RevokeAPIKey(rr, newRequest(http.MethodDelete, "", "user-1", map[string]string{constants.API_KEY_ID_KEY: "key-2"}))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+		if keys["key-2"].RevokedAt != 0 {
+			t.Error("expected key-2 to stay active")
+		}
+	})
+
+	t.Run("revoke own key", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.RevokeAPIKey(rr, newRequest(http.MethodDelete, "", "user-1", map[string]string{constants.API_KEY_ID_KEY: "key-1"}))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		if keys["key-1"].RevokedAt == 0 {
+			t.Error("expected key-1 to be revoked")
+		}
+	})
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/services/dynamodb_service"
+	"github.com/meetnearme/api/functions/gateway/transport"
+)
+
+// DataExportPayload optionally names the user to export, only superAdmins
+// can export someone other than themselves
+type DataExportPayload struct {
+	UserId string `json:"userId"`
+}
+
+type DataExportHandler struct {
+	Store func(ctx context.Context) (interfaces.PostgresServiceInterface, error)
+}
+
+func NewDataExportHandler() *DataExportHandler {
+	return &DataExportHandler{Store: services.GetPostgresService}
+}
+
+// GetDataExportSources wires the stores an export reads from
+func GetDataExportSources(ctx context.Context) (services.DataExportSources, error) {
+	eventStore, err := services.GetEventStore()
+	if err != nil {
+		return services.DataExportSources{}, fmt.Errorf("failed to get event store: %w", err)
+	}
+	postgresService, err := services.GetPostgresService(ctx)
+	if err != nil {
+		return services.DataExportSources{}, fmt.Errorf("failed to get postgres service: %w", err)
+	}
+	return services.DataExportSources{
+		Events:             eventStore,
+		DynamoDB:           transport.GetDB(),
+		Purchases:          dynamodb_service.NewPurchaseService(),
+		RegistrationFields: dynamodb_service.NewRegistrationFieldsService(),
+		CompetitionConfigs: dynamodb_service.NewCompetitionConfigService(),
+		CompetitionRounds:  dynamodb_service.NewCompetitionRoundService(),
+		CompetitionVotes:   dynamodb_service.NewCompetitionVoteService(),
+		Postgres:           postgresService,
+	}, nil
+}
+
+// StartDataExport queues a ZIP export of everything tied to the logged-in
+// user, or to `userId` in the body when a superAdmin asks, for the ACT worker
+// to build. It answers 202 with the job to poll at `GET /api/data-exports/{id}`
+func (h *DataExportHandler) StartDataExport(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userInfo := constants.UserInfo{}
+	if _, ok := ctx.Value("userInfo").(constants.UserInfo); ok {
+		userInfo = ctx.Value("userInfo").(constants.UserInfo)
+	}
+	if userInfo.Sub == "" {
+		transport.SendServerRes(w, []byte("Missing user ID"), http.StatusUnauthorized, nil)
+		return
+	}
+
+	var payload DataExportPayload
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to read request body: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+	if strings.TrimSpace(string(body)) != "" {
+		if err := json.Unmarshal(body, &payload); err != nil {
+			transport.SendServerRes(w, []byte("Invalid JSON payload: "+err.Error()), http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+	userId := userInfo.Sub
+	if payload.UserId != "" && payload.UserId != userInfo.Sub {
+		if !isDataExportSuperAdmin(r) {
+			transport.SendServerRes(w, []byte("Only super admins can export another user's data"), http.StatusForbidden, nil)
+			return
+		}
+		userId = payload.UserId
+	}
+
+	store, err := h.Store(ctx)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to start data export: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	job, err := services.StartDataExport(ctx, store, userId, userInfo.Sub, time.Now().UTC())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to start data export: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	log.Printf("INFO: user %s started data export %s for user %s", userInfo.Sub, job.Id, userId)
+
+	res, err := json.Marshal(job)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, res, http.StatusAccepted, nil)
+}
+
+// GetDataExport reports the status of a data export
+func (h *DataExportHandler) GetDataExport(w http.ResponseWriter, r *http.Request) {
+	job, _, ok := h.getDataExportForRequest(w, r)
+	if !ok {
+		return
+	}
+	res, err := json.Marshal(job)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, res, http.StatusOK, nil)
+}
+
+// DownloadDataExport serves a finished data export as a ZIP
+func (h *DataExportHandler) DownloadDataExport(w http.ResponseWriter, r *http.Request) {
+	job, data, ok := h.getDataExportForRequest(w, r)
+	if !ok {
+		return
+	}
+	if job.Status != services.DATA_EXPORT_STATUS_COMPLETE {
+		transport.SendServerRes(w, []byte("Data export is "+strings.ToLower(job.Status)), http.StatusConflict, nil)
+		return
+	}
+
+	filename := fmt.Sprintf("meetnearme-data-%s-%s.zip", job.UserId, job.CompletedAt.Format("2006-01-02"))
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		log.Printf("ERR: failed to write data export %s: %v", job.Id, err)
+	}
+}
+
+func isDataExportSuperAdmin(r *http.Request) bool {
+	roleClaims := []constants.RoleClaim{}
+	if claims, ok := r.Context().Value("roleClaims").([]constants.RoleClaim); ok {
+		roleClaims = claims
+	}
+	return helpers.HasRequiredRole(roleClaims, []string{constants.Roles[constants.SuperAdmin]})
+}
+
+// getDataExportForRequest looks up the export in the path for the logged-in
+// user. Exports belonging to someone else are reported as missing unless the
+// caller requested them or is a superAdmin
+func (h *DataExportHandler) getDataExportForRequest(w http.ResponseWriter, r *http.Request) (services.DataExportJob, []byte, bool) {
+	userInfo := constants.UserInfo{}
+	if _, ok := r.Context().Value("userInfo").(constants.UserInfo); ok {
+		userInfo = r.Context().Value("userInfo").(constants.UserInfo)
+	}
+	if userInfo.Sub == "" {
+		transport.SendServerRes(w, []byte("Missing user ID"), http.StatusUnauthorized, nil)
+		return services.DataExportJob{}, nil, false
+	}
+
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get data export: "+err.Error()), http.StatusInternalServerError, err)
+		return services.DataExportJob{}, nil, false
+	}
+	job, data, ok, err := services.GetDataExport(r.Context(), store, mux.Vars(r)[constants.DATA_EXPORT_ID_KEY], time.Now().UTC())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get data export: "+err.Error()), http.StatusInternalServerError, err)
+		return services.DataExportJob{}, nil, false
+	}
+	if ok && job.UserId != userInfo.Sub && job.RequestedBy != userInfo.Sub {
+		ok = isDataExportSuperAdmin(r)
+	}
+	if !ok {
+		transport.SendServerRes(w, []byte("Data export not found, it may have expired"), http.StatusNotFound, nil)
+		return services.DataExportJob{}, nil, false
+	}
+	return job, data, true
+}
+
+func StartDataExportHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewDataExportHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.StartDataExport(w, r)
+	}
+}
+
+func GetDataExportHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewDataExportHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.GetDataExport(w, r)
+	}
+}
+
+func DownloadDataExportHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewDataExportHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.DownloadDataExport(w, r)
+	}
+}
@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dynamodb_types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/gorilla/mux"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/types"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+)
+
+// emptyExportStore answers every export lookup with nothing
+type emptyExportStore struct {
+	types.PurchaseServiceInterface
+	types.RegistrationFieldsServiceInterface
+	types.CompetitionConfigServiceInterface
+	types.CompetitionRoundServiceInterface
+	types.CompetitionVoteServiceInterface
+	interfaces.PostgresServiceInterface
+	mu      sync.Mutex
+	exports map[string]types.DataExport
+}
+
+func (s *emptyExportStore) GetPurchasesByUserID(ctx context.Context, db types.DynamoDBAPI, userId string, limit int32, startKey string) ([]types.Purchase, map[string]dynamodb_types.AttributeValue, error) {
+	return []types.Purchase{}, nil, nil
+}
+
+func (s *emptyExportStore) GetCompetitionConfigsByPrimaryOwner(ctx context.Context, db types.DynamoDBAPI, primaryOwner string, isSelf bool) (*[]types.CompetitionConfig, error) {
+	return &[]types.CompetitionConfig{}, nil
+}
+
+func (s *emptyExportStore) GetCompetitionVotesByUserId(ctx context.Context, db types.DynamoDBAPI, userId string) ([]types.CompetitionVote, error) {
+	return []types.CompetitionVote{}, nil
+}
+
+func (s *emptyExportStore) SaveDataExport(ctx context.Context, export types.DataExport) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.exports == nil {
+		s.exports = map[string]types.DataExport{}
+	}
+	s.exports[export.Id] = export
+	return nil
+}
+
+func (s *emptyExportStore) GetDataExport(ctx context.Context, id string) (*types.DataExport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	export, ok := s.exports[id]
+	if !ok {
+		return nil, nil
+	}
+	return &export, nil
+}
+
+func (s *emptyExportStore) GetPendingDataExport(ctx context.Context, userId string) (*types.DataExport, error) {
+	return nil, nil
+}
+
+func (s *emptyExportStore) GetDueDataExports(ctx context.Context, startedBefore int64) ([]types.DataExport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := []types.DataExport{}
+	for _, export := range s.exports {
+		if export.Status == services.DATA_EXPORT_STATUS_PENDING {
+			due = append(due, export)
+		}
+	}
+	return due, nil
+}
+
+func (s *emptyExportStore) DeleteExpiredDataExports(ctx context.Context, now int64) (int64, error) {
+	return 0, nil
+}
+
+func (s *emptyExportStore) GetSeshuJobs(ctx context.Context, limit, offset int) ([]types.SeshuJob, int64, error) {
+	return []types.SeshuJob{}, 0, nil
+}
+
+func TestDataExportHandlers(t *testing.T) {
+	weaviateStatus := http.StatusOK
+	mockWeaviateServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(weaviateStatus)
+		w.Write([]byte(`{"data":{"Get":{"` + services.EventClassName() + `":[]}}}`))
+	}))
+	defer mockWeaviateServer.Close()
+	client, err := weaviate.NewClient(weaviate.Config{Host: strings.TrimPrefix(mockWeaviateServer.URL, "http://"), Scheme: "http"})
+	if err != nil {
+		t.Fatalf("failed to create weaviate client: %v", err)
+	}
+	store := &emptyExportStore{}
+	sources := services.DataExportSources{
+		Events:             services.NewWeaviateEventStore(client),
+		Purchases:          store,
+		RegistrationFields: store,
+		CompetitionConfigs: store,
+		CompetitionRounds:  store,
+		CompetitionVotes:   store,
+		Postgres:           store,
+	}
+	handler := &DataExportHandler{Store: func(ctx context.Context) (interfaces.PostgresServiceInterface, error) {
+		return store, nil
+	}}
+
+	superAdmin := []constants.RoleClaim{{Role: constants.Roles[constants.SuperAdmin]}}
+	newRequest := func(method, path, body, userId string, roleClaims []constants.RoleClaim) *http.Request {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		ctx := req.Context()
+		if userId != "" {
+			ctx = context.WithValue(ctx, "userInfo", constants.UserInfo{Sub: userId})
+		}
+		if roleClaims != nil {
+			ctx = context.WithValue(ctx, "roleClaims", roleClaims)
+		}
+		return req.WithContext(ctx)
+	}
+	start := func(t *testing.T, body, userId string, roleClaims []constants.RoleClaim) (int, services.DataExportJob) {
+		rr := httptest.NewRecorder()
+		handler.StartDataExport(rr, newRequest("POST", "/api/data-exports", body, userId, roleClaims))
+		var job services.DataExportJob
+		if rr.Code == http.StatusAccepted {
+			if err := json.Unmarshal(rr.Body.Bytes(), &job); err != nil {
+				t.Fatalf("failed to decode job: %v", err)
+			}
+		}
+		return rr.Code, job
+	}
+	get := func(id, userId string, roleClaims []constants.RoleClaim, download bool) *httptest.ResponseRecorder {
+		path := "/api/data-exports/" + id
+		if download {
+			path += "/download"
+		}
+		req := mux.SetURLVars(newRequest("GET", path, "", userId, roleClaims), map[string]string{constants.DATA_EXPORT_ID_KEY: id})
+		rr := httptest.NewRecorder()
+		if download {
+			handler.DownloadDataExport(rr, req)
+		} else {
+			handler.GetDataExport(rr, req)
+		}
+		return rr
+	}
+	// build runs the ACT worker, then reads the export back as `userId`
+	build := func(t *testing.T, id, userId string) services.DataExportJob {
+		var job services.DataExportJob
+		if err := json.Unmarshal(get(id, userId, nil, false).Body.Bytes(), &job); err != nil || job.Status != services.DATA_EXPORT_STATUS_PENDING {
+			t.Fatalf("expected export %s to be pending, got %+v %v", id, job, err)
+		}
+		if _, err := services.ProcessDueDataExports(context.Background(), sources, time.Now().UTC()); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := json.Unmarshal(get(id, userId, nil, false).Body.Bytes(), &job); err != nil {
+			t.Fatalf("failed to decode job: %v", err)
+		}
+		return job
+	}
+
+	t.Run("requires a user", func(t *testing.T) {
+		if code, _ := start(t, "", "", nil); code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, code)
+		}
+	})
+
+	t.Run("only super admins export other users", func(t *testing.T) {
+		if code, _ := start(t, `{"userId":"user-2"}`, "user-1", nil); code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, code)
+		}
+		if code, _ := start(t, `{"userId":`, "user-1", nil); code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status %d for a bad body, got %d", http.StatusUnprocessableEntity, code)
+		}
+	})
+
+	t.Run("self service export downloads as a zip", func(t *testing.T) {
+		code, job := start(t, "", "user-1", nil)
+		if code != http.StatusAccepted || job.UserId != "user-1" || job.RequestedBy != "user-1" {
+			t.Fatalf("expected an accepted export for user-1, got %d %+v", code, job)
+		}
+		job = build(t, job.Id, "user-1")
+		if job.Status != services.DATA_EXPORT_STATUS_COMPLETE {
+			t.Fatalf("expected the export to complete, got %+v", job)
+		}
+
+		if rr := get(job.Id, "user-3", nil, false); rr.Code != http.StatusNotFound {
+			t.Errorf("expected another user to get %d, got %d", http.StatusNotFound, rr.Code)
+		}
+		if rr := get(job.Id, "admin-1", superAdmin, false); rr.Code != http.StatusOK {
+			t.Errorf("expected a super admin to get %d, got %d", http.StatusOK, rr.Code)
+		}
+
+		rr := get(job.Id, "user-1", nil, true)
+		if rr.Code != http.StatusOK || rr.Header().Get("Content-Type") != "application/zip" {
+			t.Fatalf("expected a zip download, got %d %s", rr.Code, rr.Header().Get("Content-Type"))
+		}
+		if !strings.Contains(rr.Header().Get("Content-Disposition"), `filename="meetnearme-data-user-1-`) {
+			t.Errorf("unexpected Content-Disposition %q", rr.Header().Get("Content-Disposition"))
+		}
+		archive, err := zip.NewReader(bytes.NewReader(rr.Body.Bytes()), int64(rr.Body.Len()))
+		if err != nil || len(archive.File) != job.Files["manifest.json"]+1 {
+			t.Errorf("expected every export file in the zip, got %v", err)
+		}
+	})
+
+	t.Run("super admin export of another user", func(t *testing.T) {
+		weaviateStatus = http.StatusInternalServerError
+		defer func() { weaviateStatus = http.StatusOK }()
+
+		code, job := start(t, `{"userId":"user-2"}`, "admin-1", superAdmin)
+		if code != http.StatusAccepted || job.UserId != "user-2" || job.RequestedBy != "admin-1" {
+			t.Fatalf("expected an accepted export for user-2, got %d %+v", code, job)
+		}
+		job = build(t, job.Id, "admin-1")
+		if job.Status != services.DATA_EXPORT_STATUS_FAILED || job.Error == "" {
+			t.Fatalf("expected the export to fail with the weaviate error, got %+v", job)
+		}
+		if rr := get(job.Id, "user-2", nil, true); rr.Code != http.StatusConflict {
+			t.Errorf("expected a failed export download to get %d, got %d", http.StatusConflict, rr.Code)
+		}
+	})
+
+	t.Run("reports unavailable stores", func(t *testing.T) {
+		failing := &DataExportHandler{Store: func(ctx context.Context) (interfaces.PostgresServiceInterface, error) {
+			return nil, errors.New("no postgres")
+		}}
+		rr := httptest.NewRecorder()
+		failing.StartDataExport(rr, newRequest("POST", "/api/data-exports", "", "user-1", nil))
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+		}
+	})
+}
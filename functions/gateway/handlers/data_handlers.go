@@ -11,6 +11,7 @@ import (
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -106,19 +107,30 @@ func (h *WeaviateHandler) PostEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	weaviateClient, err := services.GetWeaviateClient()
+	eventStore, err := services.GetEventStore()
 	if err != nil {
-		transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err)
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
 
 	createEvents := []types.Event{createEvent}
+	prepareRecurringSeries(createEvents)
 	ctx := r.Context()
-	res, err := services.BulkUpsertEventsToWeaviate(ctx, weaviateClient, createEvents)
+	res, err := eventStore.UpsertEvents(ctx, createEvents)
 	if err != nil {
 		transport.SendServerRes(w, []byte("Failed to upsert event: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
+	if err := syncRecurringSeries(ctx, eventStore, createEvents); err != nil {
+		transport.SendServerRes(w, []byte("Failed to materialize recurring series: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	for i := range createEvents {
+		if i < len(res) && res[i].ID != "" {
+			createEvents[i].Id = string(res[i].ID)
+		}
+	}
+	services.EmitEventWebhooks(ctx, services.WEBHOOK_EVENT_CREATED, createEvents)
 
 	json, err := json.Marshal(res)
 	if err != nil {
@@ -137,58 +149,105 @@ func PostEventHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 	}
 }
 
-func HandleBatchEventValidation(w http.ResponseWriter, r *http.Request, requireIds bool) ([]types.Event, int, error) {
-	var payload struct {
-		Events []services.RawEvent `json:"events" validate:"required,min=1"`
+const (
+	// EVENT_VALIDATION_WARNINGS_HEADER carries the JSON encoded warnings a
+	// batch was accepted with, the response body stays the upsert result
+	EVENT_VALIDATION_WARNINGS_HEADER = "X-Event-Validation-Warnings"
+	// EVENT_VALIDATION_WARNING_COUNT_HEADER is how many warnings there were,
+	// the warnings header only holds the first ones when they don't all fit
+	EVENT_VALIDATION_WARNING_COUNT_HEADER = "X-Event-Validation-Warning-Count"
+	// eventValidationWarningsHeaderMaxBytes keeps the header well under the
+	// 8KB many proxies and clients allow for all headers together
+	eventValidationWarningsHeaderMaxBytes = 4096
+)
+
+// setEventValidationWarningsHeaders sets the count of `warnings` and as many
+// of them as fit in `eventValidationWarningsHeaderMaxBytes`
+func setEventValidationWarningsHeaders(header http.Header, warnings []types.EventValidationIssue) {
+	header.Set(EVENT_VALIDATION_WARNING_COUNT_HEADER, strconv.Itoa(len(warnings)))
+	fitting := []json.RawMessage{}
+	size := len("[]")
+	for _, warning := range warnings {
+		encoded, err := json.Marshal(warning)
+		if err != nil {
+			continue
+		}
+		if size+len(encoded)+1 > eventValidationWarningsHeaderMaxBytes {
+			break
+		}
+		fitting = append(fitting, encoded)
+		size += len(encoded) + 1
 	}
+	if encoded, err := json.Marshal(fitting); err == nil {
+		header.Set(EVENT_VALIDATION_WARNINGS_HEADER, string(encoded))
+	}
+}
+
+// BatchEventsPayload is the body of the batch create and update routes
+type BatchEventsPayload struct {
+	Events []services.RawEvent `json:"events" validate:"required,min=1"`
+}
+
+// HandleBatchEventValidation decodes and validates a batch of events. With a
+// `validator`, events breaking an error rule fail the batch and the returned
+// issues are the warnings it passed with
+func HandleBatchEventValidation(w http.ResponseWriter, r *http.Request, requireIds bool, validator *services.EventValidator) ([]types.Event, []types.EventValidationIssue, int, error) {
+	var payload BatchEventsPayload
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("failed to read request body: %w", err)
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("failed to read request body: %w", err)
 	}
 
 	err = json.Unmarshal(body, &payload)
 	if err != nil {
-		return nil, http.StatusUnprocessableEntity, fmt.Errorf("invalid JSON payload: %w", err)
+		return nil, nil, http.StatusUnprocessableEntity, fmt.Errorf("invalid JSON payload: %w", err)
 	}
 
 	err = validate.Struct(&payload)
 	if err != nil {
-		return nil, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err)
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("invalid body: %w", err)
 	}
 
 	// Additional check with custom message
 	if len(payload.Events) == 0 {
-		return nil, http.StatusBadRequest, fmt.Errorf("events array must contain at least one event")
+		return nil, nil, http.StatusBadRequest, fmt.Errorf("events array must contain at least one event")
 	}
 
-	events, statusCode, err := services.BulkValidateEvents(payload.Events, requireIds)
+	events, issues, statusCode, err := services.BulkValidateEventsWithRules(r.Context(), payload.Events, requireIds, validator)
 	if err != nil {
-		return nil, statusCode, fmt.Errorf("invalid body: %w", err)
+		return nil, issues, statusCode, fmt.Errorf("invalid body: %w", err)
 	}
 
-	return events, http.StatusOK, nil
+	return events, issues, http.StatusOK, nil
 }
 
 func (h *WeaviateHandler) PostBatchEvents(w http.ResponseWriter, r *http.Request) {
-	events, status, err := HandleBatchEventValidation(w, r, false)
-
+	eventStore, err := services.GetEventStore()
 	if err != nil {
-		transport.SendServerRes(w, []byte(err.Error()), status, err)
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
 
-	weaviateClient, err := services.GetWeaviateClient()
+	events, warnings, status, err := HandleBatchEventValidation(w, r, false, services.NewEventValidator(eventStore))
 	if err != nil {
-		transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err)
+		transport.SendServerRes(w, []byte(err.Error()), status, err)
 		return
 	}
 
-	res, err := services.BulkUpsertEventsToWeaviate(r.Context(), weaviateClient, events)
+	prepareRecurringSeries(events)
+	res, err := eventStore.UpsertEvents(r.Context(), events)
 	if err != nil {
 		transport.SendServerRes(w, []byte("Failed to upsert events: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
+	if err := syncRecurringSeries(r.Context(), eventStore, events); err != nil {
+		transport.SendServerRes(w, []byte("Failed to materialize recurring series: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
 
+	if len(warnings) > 0 {
+		setEventValidationWarningsHeaders(w.Header(), warnings)
+	}
 	json, err := json.Marshal(res)
 	if err != nil {
 		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
@@ -206,20 +265,34 @@ func PostBatchEventsHandler(w http.ResponseWriter, r *http.Request) http.Handler
 }
 
 func (h *WeaviateHandler) GetOneEvent(w http.ResponseWriter, r *http.Request) {
-	weaviateClient, err := services.GetWeaviateClient()
+	eventStore, err := services.GetEventStore()
 	if err != nil {
-		transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err)
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
 	eventId := mux.Vars(r)[constants.EVENT_ID_KEY]
 	parseDates := r.URL.Query().Get("parse_dates")
 	var event *types.Event
-	event, err = services.GetWeaviateEventByID(r.Context(), weaviateClient, eventId, parseDates)
+	event, err = eventStore.GetEventByID(r.Context(), eventId, parseDates)
 	if err != nil {
 		transport.SendServerRes(w, []byte("Failed to get event: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
 
+	if r.URL.Query().Get("format") == "jsonld" {
+		jsonLD, err := json.Marshal(services.BuildEventJSONLD(*event, eventPurchasable(r.Context(), event)))
+		if err != nil {
+			transport.SendServerRes(w, []byte("Error marshaling JSON-LD"), http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/ld+json")
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write(jsonLD); err != nil {
+			log.Printf("ERR: failed to write event JSON-LD: %v", err)
+		}
+		return
+	}
+
 	json, err := json.Marshal(event)
 	if err != nil {
 		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
@@ -228,6 +301,21 @@ func (h *WeaviateHandler) GetOneEvent(w http.ResponseWriter, r *http.Request) {
 	transport.SendServerRes(w, json, http.StatusOK, nil)
 }
 
+// eventPurchasable loads the purchasables listed as `offers` in an event's
+// structured data. Nil when the event sells nothing or they can't be read,
+// the rest of the structured data is still worth serving
+func eventPurchasable(ctx context.Context, event *types.Event) *types.Purchasable {
+	if event == nil || !event.HasPurchasable {
+		return nil
+	}
+	purchasable, err := dynamodb_service.NewPurchasableService().GetPurchasablesByEventID(ctx, transport.GetDB(), event.Id)
+	if err != nil {
+		log.Printf("Failed to get purchasables for event %s structured data: %v", event.Id, err)
+		return nil
+	}
+	return purchasable
+}
+
 func GetOneEventHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 	weaviateService := services.NewWeaviateService()
 	handler := NewWeaviateHandler(weaviateService)
@@ -237,22 +325,27 @@ func GetOneEventHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc
 }
 
 func (h *WeaviateHandler) BulkUpdateEvents(w http.ResponseWriter, r *http.Request) {
-	weaviateClient, err := services.GetWeaviateClient()
+	eventStore, err := services.GetEventStore()
 	if err != nil {
-		transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err)
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
 
-	events, status, err := HandleBatchEventValidation(w, r, true)
+	events, _, status, err := HandleBatchEventValidation(w, r, true, nil)
 	if err != nil {
 		transport.SendServerRes(w, []byte("Failed to extract event from payload: "+err.Error()), status, err)
 		return
 	}
-	res, err := services.BulkUpdateWeaviateEventsByID(r.Context(), weaviateClient, events)
+	res, err := eventStore.UpdateEvents(r.Context(), events)
 	if err != nil {
 		transport.SendServerRes(w, []byte("Failed to upsert event: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
+	if err := syncRecurringSeries(r.Context(), eventStore, events); err != nil {
+		transport.SendServerRes(w, []byte("Failed to materialize recurring series: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	services.EmitEventWebhooks(r.Context(), services.WEBHOOK_EVENT_UPDATED, events)
 
 	json, err := json.Marshal(res)
 	if err != nil {
@@ -474,9 +567,9 @@ func SearchUsersHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc
 }
 
 func (h *WeaviateHandler) UpdateOneEvent(w http.ResponseWriter, r *http.Request) {
-	weaviateClient, err := services.GetWeaviateClient()
+	eventStore, err := services.GetEventStore()
 	if err != nil {
-		transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err)
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
 
@@ -495,11 +588,15 @@ func (h *WeaviateHandler) UpdateOneEvent(w http.ResponseWriter, r *http.Request)
 	updateEvent.Id = eventId
 	updateEvents := []types.Event{updateEvent}
 
-	res, err := services.BulkUpdateWeaviateEventsByID(r.Context(), weaviateClient, updateEvents)
+	res, err := eventStore.UpdateEvents(r.Context(), updateEvents)
 	if err != nil {
 		transport.SendServerRes(w, []byte("Failed to upsert event: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
+	if err := syncRecurringSeries(r.Context(), eventStore, updateEvents); err != nil {
+		transport.SendServerRes(w, []byte("Failed to materialize recurring series: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
 
 	json, err := json.Marshal(res)
 	if err != nil {
@@ -521,14 +618,18 @@ func (h *WeaviateHandler) SearchEvents(w http.ResponseWriter, r *http.Request) {
 	// Extract parameter values from the request query parameters
 	q, _, userLocation, radius, startTimeUnix, endTimeUnix, _, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds := GetSearchParamsFromReq(r)
 
-	weaviateClient, err := services.GetWeaviateClient()
+	eventStore, err := services.GetEventStore()
 	if err != nil {
-		transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err)
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
 
 	var res types.EventSearchResponse
-	res, err = services.SearchWeaviateEvents(r.Context(), weaviateClient, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds)
+	res, err = eventStore.SearchEventsPage(r.Context(), q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds, GetSearchPageFromReq(r))
+	if errors.Is(err, services.ErrInvalidSearchCursor) {
+		transport.SendServerRes(w, []byte(err.Error()), http.StatusBadRequest, err)
+		return
+	}
 	if err != nil {
 		transport.SendServerRes(w, []byte("Failed to search events: "+err.Error()), http.StatusInternalServerError, err)
 		return
@@ -549,6 +650,554 @@ func SearchEventsHandler(w http.ResponseWriter, r *http.Request) http.HandlerFun
 	}
 }
 
+// GetICalEvents serves any search expressible via `GetSearchParamsFromReq` as a
+// subscribable `text/calendar` feed. The time window is relative to "now" when
+// `start_time` is omitted, so a subscribed URL keeps rolling forward
+func (h *WeaviateHandler) GetICalEvents(w http.ResponseWriter, r *http.Request) {
+	q, _, userLocation, radius, startTimeUnix, endTimeUnix, _, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds := GetSearchParamsFromReq(r)
+
+	// The feed is public, never leak unpublished events into it
+	publishedTypes := []string{}
+	for _, sourceType := range eventSourceTypes {
+		if !strings.HasSuffix(sourceType, constants.UNPUB_SUFFIX) {
+			publishedTypes = append(publishedTypes, sourceType)
+		}
+	}
+
+	eventStore, err := services.GetEventStore()
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+
+	ctx := r.Context()
+	res, err := eventStore.SearchEvents(ctx, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, publishedTypes, eventSourceIds)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to search events: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+
+	// Series parents are searchable but calendars need the concrete occurrences,
+	// so swap each parent for its published children within the same window
+	parentIds := []string{}
+	for _, event := range res.Events {
+		if event.EventSourceType == constants.ES_SERIES_PARENT {
+			parentIds = append(parentIds, event.Id)
+		}
+	}
+	childrenByParent := map[string][]types.Event{}
+	if len(parentIds) > 0 {
+		children, err := services.SearchAllEventsInWindow(ctx, eventStore, startTimeUnix, endTimeUnix, []string{}, []string{constants.ES_EVENT_SERIES}, parentIds)
+		if err != nil {
+			transport.SendServerRes(w, []byte("Failed to search event series children: "+err.Error()), http.StatusInternalServerError, err)
+			return
+		}
+		for _, child := range children {
+			childrenByParent[child.EventSourceId] = append(childrenByParent[child.EventSourceId], child)
+		}
+	}
+
+	feedEvents := []types.Event{}
+	seen := map[string]bool{}
+	for _, event := range res.Events {
+		candidates := []types.Event{event}
+		if children, ok := childrenByParent[event.Id]; ok && event.EventSourceType == constants.ES_SERIES_PARENT {
+			candidates = children
+		}
+		for _, candidate := range candidates {
+			if seen[candidate.Id] {
+				continue
+			}
+			seen[candidate.Id] = true
+			feedEvents = append(feedEvents, candidate)
+		}
+	}
+
+	calName := "Meet Near Me Events"
+	if q != "" {
+		calName += ": " + q
+	}
+	feed := services.BuildICalendarFeed(calName, feedEvents, time.Now())
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="events.ics"`)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(feed)); err != nil {
+		log.Printf("ERR: failed to write iCal feed: %v", err)
+	}
+}
+
+func GetICalEventsHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	weaviateService := services.NewWeaviateService()
+	handler := NewWeaviateHandler(weaviateService)
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.GetICalEvents(w, r)
+	}
+}
+
+// GetMapEvents serves the events inside a map viewport (`bbox` as
+// west,south,east,north) as a GeoJSON FeatureCollection. Below
+// `services.MapClusterMaxZoom` nearby events come back as cluster points so a
+// zoomed out map doesn't have to download every event
+func (h *WeaviateHandler) GetMapEvents(w http.ResponseWriter, r *http.Request) {
+	transport.SetCORSAllowAll(w, r)
+
+	bounds, err := services.ParseMapBounds(r.URL.Query().Get("bbox"))
+	if err != nil {
+		transport.SendServerRes(w, []byte(err.Error()), http.StatusBadRequest, err)
+		return
+	}
+
+	zoom := 0
+	if zoomStr := r.URL.Query().Get("zoom"); zoomStr != "" {
+		zoom, err = strconv.Atoi(zoomStr)
+		if err != nil || zoom < 0 {
+			transport.SendServerRes(w, []byte("zoom must be a non-negative integer"), http.StatusBadRequest, err)
+			return
+		}
+		zoom = min(zoom, services.MapMaxZoom)
+	}
+
+	q, _, _, _, startTimeUnix, endTimeUnix, _, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds := GetSearchParamsFromReq(r)
+
+	page := GetSearchPageFromReq(r)
+	if page.Limit == 0 {
+		page.Limit = constants.MAX_MAP_SEARCH_LIMIT
+	}
+	page.Facets = false
+	page.Bounds = &bounds
+
+	eventStore, err := services.GetEventStore()
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+
+	res, err := eventStore.SearchEventsPage(r.Context(), q, nil, 0, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds, page)
+	if errors.Is(err, services.ErrInvalidSearchCursor) {
+		transport.SendServerRes(w, []byte(err.Error()), http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to search events: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+
+	collection := types.GeoJSONFeatureCollection{
+		Type:      types.GeoJSONFeatureCollectionType,
+		BBox:      []float64{bounds.West, bounds.South, bounds.East, bounds.North},
+		Features:  services.ClusterEventsGeoJSON(res.Events, zoom),
+		Total:     res.Total,
+		Truncated: res.HasMore,
+	}
+	body, err := json.Marshal(collection)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling GeoJSON"), http.StatusInternalServerError, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/geo+json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(body); err != nil {
+		log.Printf("ERR: failed to write GeoJSON response: %v", err)
+	}
+}
+
+func GetMapEventsHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	weaviateService := services.NewWeaviateService()
+	handler := NewWeaviateHandler(weaviateService)
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.GetMapEvents(w, r)
+	}
+}
+
+// searchSimilarEventsFromReq loads the event named in the route and the
+// events recommended alongside it. The returned status is meaningful only
+// when err is non-nil
+func searchSimilarEventsFromReq(r *http.Request) ([]types.Event, int, error) {
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 {
+			return nil, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer")
+		}
+		limit = parsed
+	}
+	radius := constants.DEFAULT_SIMILAR_EVENTS_RADIUS
+	if radiusStr := r.URL.Query().Get("radius"); radiusStr != "" {
+		parsed, err := strconv.ParseFloat(radiusStr, 64)
+		if err != nil || parsed <= 0 {
+			return nil, http.StatusBadRequest, fmt.Errorf("radius must be a positive number of miles")
+		}
+		radius = parsed
+	}
+
+	eventStore, err := services.GetEventStore()
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to get event store: %w", err)
+	}
+
+	eventId := mux.Vars(r)[constants.EVENT_ID_KEY]
+	sources, err := eventStore.BulkGetEventByID(r.Context(), []string{eventId}, "")
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to get event: %w", err)
+	}
+	if len(sources) == 0 {
+		return nil, http.StatusNotFound, fmt.Errorf("no event found with id: %s", eventId)
+	}
+
+	events, err := eventStore.SearchSimilarEvents(r.Context(), *sources[0], radius, limit, r.URL.Query().Get("parse_dates"))
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("failed to search similar events: %w", err)
+	}
+	return events, http.StatusOK, nil
+}
+
+func (h *WeaviateHandler) GetSimilarEvents(w http.ResponseWriter, r *http.Request) {
+	transport.SetCORSAllowAll(w, r)
+
+	events, status, err := searchSimilarEventsFromReq(r)
+	if err != nil {
+		transport.SendServerRes(w, []byte(err.Error()), status, err)
+		return
+	}
+
+	json, err := json.Marshal(types.EventSearchResponse{Events: events, Total: len(events)})
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, json, http.StatusOK, nil)
+}
+
+func GetSimilarEventsHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	weaviateService := services.NewWeaviateService()
+	handler := NewWeaviateHandler(weaviateService)
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.GetSimilarEvents(w, r)
+	}
+}
+
+type ICalImportPayload struct {
+	Url             string `json:"url"`
+	Schedule        bool   `json:"schedule"`
+	LocationAddress string `json:"location_address"`
+}
+
+// isBodyTooLarge is true when reading a body cut off by
+// `http.MaxBytesReader`
+func isBodyTooLarge(err error) bool {
+	var maxBytesErr *http.MaxBytesError
+	return errors.As(err, &maxBytesErr)
+}
+
+// ImportICalEvents ingests an .ics document for the logged-in user. It accepts
+// a JSON body with a feed `url`, a multipart upload in the `file` field, or a
+// raw `text/calendar` body. With `schedule: true` the feed URL is also
+// registered as an ICS Seshu job so it keeps syncing on the scrape loop
+func ImportICalEvents(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	ctx := r.Context()
+	userInfo := constants.UserInfo{}
+	if _, ok := ctx.Value("userInfo").(constants.UserInfo); ok {
+		userInfo = ctx.Value("userInfo").(constants.UserInfo)
+	}
+	if userInfo.Sub == "" {
+		return transport.SendServerRes(w, []byte("Missing user ID"), http.StatusUnauthorized, nil)
+	}
+
+	// Room for the largest feed plus multipart framing
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxICalFeedBytes+(1<<20))
+	tooLarge := func(err error) http.HandlerFunc {
+		return transport.SendServerRes(w, []byte(fmt.Sprintf("Calendar exceeds %d bytes", services.MaxICalFeedBytes)), http.StatusRequestEntityTooLarge, err)
+	}
+
+	var payload ICalImportPayload
+	var data []byte
+	var sourceKey string
+	contentType := r.Header.Get("Content-Type")
+
+	switch {
+	case strings.HasPrefix(contentType, "application/json"):
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			if isBodyTooLarge(err) {
+				return tooLarge(err)
+			}
+			return transport.SendServerRes(w, []byte("Failed to read request body: "+err.Error()), http.StatusBadRequest, err)
+		}
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return transport.SendServerRes(w, []byte("Invalid JSON payload: "+err.Error()), http.StatusUnprocessableEntity, err)
+		}
+		payload.Url = strings.TrimSpace(payload.Url)
+		if payload.Url == "" {
+			return transport.SendServerRes(w, []byte("Missing feed url"), http.StatusBadRequest, nil)
+		}
+		feedUrl := payload.Url
+		if strings.HasPrefix(strings.ToLower(feedUrl), "webcal://") {
+			feedUrl = "https://" + feedUrl[len("webcal://"):]
+		}
+		normalizedUrl, err := helpers.NormalizeURL(feedUrl)
+		if err != nil {
+			return transport.SendServerRes(w, []byte("Invalid feed url: "+err.Error()), http.StatusBadRequest, err)
+		}
+		sourceKey = normalizedUrl
+		data, err = services.FetchICalFeed(ctx, feedUrl)
+		if err != nil {
+			return transport.SendServerRes(w, []byte("Failed to fetch feed: "+err.Error()), http.StatusBadGateway, err)
+		}
+	case strings.HasPrefix(contentType, "multipart/form-data"):
+		if err := r.ParseMultipartForm(10 << 20); err != nil {
+			if isBodyTooLarge(err) {
+				return tooLarge(err)
+			}
+			return transport.SendServerRes(w, []byte("Failed to parse upload: "+err.Error()), http.StatusBadRequest, err)
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return transport.SendServerRes(w, []byte("Missing .ics file upload: "+err.Error()), http.StatusBadRequest, err)
+		}
+		defer file.Close()
+		data, err = io.ReadAll(file)
+		if err != nil {
+			return transport.SendServerRes(w, []byte("Failed to read upload: "+err.Error()), http.StatusBadRequest, err)
+		}
+		if len(data) > services.MaxICalFeedBytes {
+			return tooLarge(nil)
+		}
+		payload.LocationAddress = r.FormValue("location_address")
+		sourceKey = "ics-upload:" + header.Filename
+	default:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			if isBodyTooLarge(err) {
+				return tooLarge(err)
+			}
+			return transport.SendServerRes(w, []byte("Failed to read request body: "+err.Error()), http.StatusBadRequest, err)
+		}
+		if len(body) > services.MaxICalFeedBytes {
+			return tooLarge(nil)
+		}
+		data = body
+		payload.LocationAddress = r.URL.Query().Get("location_address")
+		source := r.URL.Query().Get("source")
+		if source == "" {
+			source = "calendar.ics"
+		}
+		sourceKey = "ics-upload:" + source
+	}
+
+	opts := services.ICalImportOptions{
+		OwnerId:   userInfo.Sub,
+		OwnerName: userInfo.Name,
+		SourceKey: sourceKey,
+	}
+	if payload.Url != "" {
+		opts.SourceUrl = payload.Url
+	}
+
+	// Optional default venue for VEVENTs that don't carry a LOCATION / GEO
+	if payload.LocationAddress != "" {
+		lat, lon, address, err := services.GetGeoService().GetGeo(payload.LocationAddress, os.Getenv("APEX_URL"))
+		if err != nil {
+			return transport.SendServerRes(w, []byte("Failed to geocode location_address: "+err.Error()), http.StatusBadRequest, err)
+		}
+		opts.FallbackLat, _ = strconv.ParseFloat(lat, 64)
+		opts.FallbackLong, _ = strconv.ParseFloat(lon, 64)
+		opts.FallbackAddress = address
+		opts.FallbackTimezone = services.DeriveTimezoneFromCoordinates(opts.FallbackLat, opts.FallbackLong)
+	}
+
+	eventStore, err := services.GetEventStore()
+	if err != nil {
+		return transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
+	}
+
+	result, err := services.ImportICalendar(ctx, eventStore, data, opts)
+	if err != nil {
+		return transport.SendServerRes(w, []byte("Failed to import iCal events: "+err.Error()), http.StatusBadRequest, err)
+	}
+
+	if payload.Schedule && payload.Url != "" {
+		scheduleICalSeshuJob(ctx, sourceKey, userInfo.Sub, opts)
+	}
+
+	res, err := json.Marshal(result)
+	if err != nil {
+		return transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+	}
+	return transport.SendServerRes(w, res, http.StatusOK, nil)
+}
+
+func scheduleICalSeshuJob(ctx context.Context, normalizedUrl, ownerId string, opts services.ICalImportOptions) {
+	db, err := services.GetPostgresService(ctx)
+	if err != nil {
+		log.Printf("ERR: failed to get postgres service to schedule iCal feed %s: %v", normalizedUrl, err)
+		return
+	}
+	lat, long := opts.FallbackLat, opts.FallbackLong
+	if lat == 0 && long == 0 {
+		lat, long = constants.INITIAL_EMPTY_LAT_LONG, constants.INITIAL_EMPTY_LAT_LONG
+	}
+	seshuJob := internal_types.SeshuJob{
+		NormalizedUrlKey:  normalizedUrl,
+		LocationLatitude:  lat,
+		LocationLongitude: long,
+		LocationAddress:   opts.FallbackAddress,
+		LocationTimezone:  opts.FallbackTimezone,
+		ScheduledHour:     (time.Now().UTC().Hour() + 23) % 24,
+		// Structured feeds have no DOM paths to learn
+		TargetNameCSSPath:      "_BYPASS_",
+		TargetLocationCSSPath:  "_BYPASS_",
+		TargetStartTimeCSSPath: "_BYPASS_",
+		TargetHrefCSSPath:      "_BYPASS_",
+		Status:                 "HEALTHY",
+		LastScrapeSuccess:      time.Now().Unix(),
+		OwnerID:                ownerId,
+		KnownScrapeSource:      constants.SESHU_KNOWN_SOURCE_ICS,
+	}
+	if err := validate.Struct(seshuJob); err != nil {
+		log.Printf("ERR: invalid iCal SeshuJob for %s: %v", normalizedUrl, err)
+		return
+	}
+	if err := db.CreateSeshuJob(ctx, seshuJob); err != nil {
+		log.Printf("ERR: failed to schedule iCal feed %s: %v", normalizedUrl, err)
+	}
+}
+
+// CSVImportStreamLine is one line of a streamed CSV import commit, either a
+// progress update or the final report / error
+type CSVImportStreamLine struct {
+	Committed int                       `json:"committed,omitempty"`
+	Total     int                       `json:"total,omitempty"`
+	Report    *services.CSVImportReport `json:"report,omitempty"`
+	Error     string                    `json:"error,omitempty"`
+}
+
+// ImportCSVEvents imports a spreadsheet of events for the logged-in event
+// admin. It takes a multipart upload with the CSV in `file`, an optional
+// `mapping` JSON object of column header to field key, and `commit=true` to
+// write the rows that pass validation. Without `commit` it only returns the
+// per-row validation report. Commits requested with
+// `Accept: application/x-ndjson` stream progress lines before the report
+func (h *WeaviateHandler) ImportCSVEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userInfo := constants.UserInfo{}
+	if _, ok := ctx.Value("userInfo").(constants.UserInfo); ok {
+		userInfo = ctx.Value("userInfo").(constants.UserInfo)
+	}
+	if userInfo.Sub == "" {
+		transport.SendServerRes(w, []byte("Missing user ID"), http.StatusUnauthorized, nil)
+		return
+	}
+	roleClaims := []constants.RoleClaim{}
+	if claims, ok := ctx.Value("roleClaims").([]constants.RoleClaim); ok {
+		roleClaims = claims
+	}
+	if !helpers.HasRequiredRole(roleClaims, []string{constants.Roles[constants.SuperAdmin], constants.Roles[constants.EventAdmin]}) {
+		transport.SendServerRes(w, []byte("Only event editors can import events"), http.StatusForbidden, nil)
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, services.MaxCSVImportBytes+(1<<20))
+	if err := r.ParseMultipartForm(services.MaxCSVImportBytes); err != nil {
+		transport.SendServerRes(w, []byte("Failed to parse upload: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		transport.SendServerRes(w, []byte("Missing .csv file upload: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to read upload: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+
+	opts := services.CSVImportOptions{
+		OwnerId:   userInfo.Sub,
+		OwnerName: userInfo.Name,
+		FileName:  header.Filename,
+	}
+	if mapping := r.FormValue("mapping"); mapping != "" {
+		if err := json.Unmarshal([]byte(mapping), &opts.Mapping); err != nil {
+			transport.SendServerRes(w, []byte("Invalid mapping: "+err.Error()), http.StatusUnprocessableEntity, err)
+			return
+		}
+	}
+	commit := r.FormValue("commit") == "true"
+
+	eventStore, err := services.GetEventStore()
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+
+	if !commit || !strings.Contains(r.Header.Get("Accept"), "application/x-ndjson") {
+		report, err := services.ImportCSVEvents(ctx, eventStore, data, opts, !commit)
+		if err != nil && report.Columns == nil {
+			transport.SendServerRes(w, []byte("Invalid CSV file: "+err.Error()), http.StatusBadRequest, err)
+			return
+		}
+		if err != nil {
+			transport.SendServerRes(w, []byte(fmt.Sprintf("CSV import stopped after %d of %d events: %s", report.Committed, report.Valid, err.Error())), http.StatusInternalServerError, err)
+			return
+		}
+		res, err := json.Marshal(report)
+		if err != nil {
+			transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+			return
+		}
+		transport.SendServerRes(w, res, http.StatusOK, nil)
+		return
+	}
+
+	// Validation errors still come back as a plain error response, the
+	// stream only starts once there's something to commit
+	report, rawEvents, err := services.PrepareCSVImport(ctx, eventStore, data, opts)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Invalid CSV file: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	encoder := json.NewEncoder(w)
+	writeLine := func(line CSVImportStreamLine) {
+		if err := encoder.Encode(line); err != nil {
+			log.Printf("ERR: failed to write CSV import progress: %v", err)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	if len(report.MissingFields) == 0 {
+		report.DryRun = false
+		opts.Progress = func(committed, total int) {
+			writeLine(CSVImportStreamLine{Committed: committed, Total: total})
+		}
+		report.Committed, err = services.CommitCSVImport(ctx, eventStore, rawEvents, opts)
+		if err != nil {
+			log.Printf("ERR: CSV import of '%s' stopped after %d events: %v", opts.FileName, report.Committed, err)
+			writeLine(CSVImportStreamLine{Report: &report, Error: err.Error()})
+			return
+		}
+	}
+	writeLine(CSVImportStreamLine{Report: &report})
+}
+
+func ImportCSVEventsHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	weaviateService := services.NewWeaviateService()
+	handler := NewWeaviateHandler(weaviateService)
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.ImportCSVEvents(w, r)
+	}
+}
+
 func CreateSubscriptionCheckoutSession(w http.ResponseWriter, r *http.Request) (err error) {
 	ctx := r.Context()
 	userInfo := constants.UserInfo{}
@@ -1010,7 +1659,29 @@ func TransformPurchaseToUpdate(purchase internal_types.Purchase) internal_types.
 	}
 }
 
+// emitPurchaseSettledWebhook reports a settled purchase to the owners of the
+// event it was made for
+func emitPurchaseSettledWebhook(ctx context.Context, purchase internal_types.Purchase) {
+	eventStore, err := services.GetEventStore()
+	if err != nil {
+		log.Printf("ERR: failed to get event store for purchase webhook: %v", err)
+		return
+	}
+	event, err := eventStore.GetEventByID(ctx, purchase.EventID, "")
+	if err != nil || event == nil {
+		log.Printf("ERR: failed to get event %s for purchase webhook: %v", purchase.EventID, err)
+		return
+	}
+	services.EmitWebhookEvents(ctx, services.WebhookMessage{
+		Type:     services.WEBHOOK_PURCHASE_SETTLED,
+		OwnerIds: event.EventOwners,
+		Data:     purchase,
+	})
+}
+
 func (h *PurchasableWebhookHandler) HandleCheckoutWebhook(w http.ResponseWriter, r *http.Request) (err error) {
+	var eventType string
+	defer func() { services.RecordStripeWebhook("checkout", eventType, err) }()
 	const MaxBodyBytes = int64(65536)
 	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
 	payload, err := io.ReadAll(r.Body)
@@ -1034,6 +1705,7 @@ func (h *PurchasableWebhookHandler) HandleCheckoutWebhook(w http.ResponseWriter,
 		transport.SendServerRes(w, []byte(msg), http.StatusBadRequest, nil)
 		return err
 	}
+	eventType = string(event.Type)
 	switch event.Type {
 	case "checkout.session.completed":
 		var checkoutSession stripe.CheckoutSession
@@ -1067,11 +1739,17 @@ func (h *PurchasableWebhookHandler) HandleCheckoutWebhook(w http.ResponseWriter,
 			purchaseUpdate.StripeTransactionId = checkoutSession.PaymentIntent.ID
 		}
 
-		_, err = h.PurchaseService.UpdatePurchase(r.Context(), db, eventID, userID, createdAt, purchaseUpdate)
+		settled, err := h.PurchaseService.UpdatePurchase(r.Context(), db, eventID, userID, createdAt, purchaseUpdate)
 		if err != nil {
 			transport.SendServerRes(w, []byte("Failed to update purchase status to SETTLED: "), http.StatusInternalServerError, err)
 			return err
 		}
+		if settled == nil {
+			settled = purchase
+			settled.Status = purchaseUpdate.Status
+			settled.StripeTransactionId = purchaseUpdate.StripeTransactionId
+		}
+		emitPurchaseSettledWebhook(r.Context(), *settled)
 		msg := fmt.Sprintf("Checkout session marked as SETTLED for stripe clientReferenceID: %s", clientReferenceID)
 		log.Println(msg)
 		transport.SendServerRes(w, []byte(msg), http.StatusOK, err)
@@ -1183,6 +1861,8 @@ func HandleCheckoutWebhookHandler(w http.ResponseWriter, r *http.Request) http.H
 }
 
 func (h *SubscriptionWebhookHandler) HandleSubscriptionWebhook(w http.ResponseWriter, r *http.Request) (err error) {
+	var eventType string
+	defer func() { services.RecordStripeWebhook("subscription", eventType, err) }()
 	const MaxBodyBytes = int64(65536)
 	r.Body = http.MaxBytesReader(w, r.Body, MaxBodyBytes)
 	payload, err := io.ReadAll(r.Body)
@@ -1202,6 +1882,7 @@ func (h *SubscriptionWebhookHandler) HandleSubscriptionWebhook(w http.ResponseWr
 		transport.SendServerRes(w, []byte(msg), http.StatusBadRequest, nil)
 		return err
 	}
+	eventType = string(event.Type)
 
 	switch event.Type {
 	case constants.STRIPE_WEBHOOK_EVENT_CUSTOMER_SUBSCRIPTION_CREATED:
@@ -1880,9 +2561,9 @@ func UpdateEventRegPurchHandler(w http.ResponseWriter, r *http.Request) http.Han
 		}
 
 		// Update events
-		weaviateClient, err := services.GetWeaviateClient()
+		eventStore, err := services.GetEventStore()
 		if err != nil {
-			transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err)
+			transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 			return
 		}
 
@@ -1909,7 +2590,7 @@ func UpdateEventRegPurchHandler(w http.ResponseWriter, r *http.Request) http.Han
 		// delete "sweeper" we do in the `defer` function below
 
 		farFutureTime, _ := time.Parse(time.RFC3339, "2099-05-01T12:00:00Z")
-		childEventsToDelete, err := services.SearchWeaviateEvents(ctx, weaviateClient, "", []float64{0, 0}, 1000000, 0, farFutureTime.Unix(), []string{}, "", "", "", []string{constants.ES_EVENT_SERIES, constants.ES_EVENT_SERIES_UNPUB}, []string{eventId})
+		childEventsToDelete, err := eventStore.SearchEvents(ctx, "", []float64{0, 0}, 1000000, 0, farFutureTime.Unix(), []string{}, "", "", "", []string{constants.ES_EVENT_SERIES, constants.ES_EVENT_SERIES_UNPUB}, []string{eventId})
 		if err != nil {
 			transport.SendServerRes(w, []byte("Failed to search for existing child events: "+err.Error()), http.StatusInternalServerError, err)
 			return
@@ -1922,11 +2603,14 @@ func UpdateEventRegPurchHandler(w http.ResponseWriter, r *http.Request) http.Han
 			deleteDenyList[event.Id] = true
 		}
 
-		// Filter out events we want to keep
+		// Filter out events we want to keep, children of a recurring series are
+		// owned by its rule and pruned by the series sync instead
 		var eventsToDelete []types.Event
-		for _, event := range childEventsToDelete.Events {
-			if !deleteDenyList[event.Id] {
-				eventsToDelete = append(eventsToDelete, event)
+		if !services.IsRecurringSeriesParent(events[0]) {
+			for _, event := range childEventsToDelete.Events {
+				if !deleteDenyList[event.Id] {
+					eventsToDelete = append(eventsToDelete, event)
+				}
 			}
 		}
 
@@ -1938,7 +2622,7 @@ func UpdateEventRegPurchHandler(w http.ResponseWriter, r *http.Request) http.Han
 					deleteEventsArr[i] = event.Id
 				}
 
-				_, err = services.BulkDeleteEventsFromWeaviate(ctx, weaviateClient, deleteEventsArr)
+				err = eventStore.BulkDeleteEvents(ctx, deleteEventsArr)
 				if err != nil {
 					transport.SendServerRes(w, []byte("Failed to delete old child events: "+err.Error()), http.StatusInternalServerError, err)
 					return
@@ -1946,11 +2630,15 @@ func UpdateEventRegPurchHandler(w http.ResponseWriter, r *http.Request) http.Han
 			}
 		}()
 
-		eventsRes, err := services.BulkUpsertEventsToWeaviate(ctx, weaviateClient, events)
+		eventsRes, err := eventStore.UpsertEvents(ctx, events)
 		if err != nil {
 			transport.SendServerRes(w, []byte("Failed to upsert events to weaviate: "+err.Error()), http.StatusInternalServerError, err)
 			return
 		}
+		if err := syncRecurringSeries(ctx, eventStore, events[:1]); err != nil {
+			transport.SendServerRes(w, []byte("Failed to materialize recurring series: "+err.Error()), http.StatusInternalServerError, err)
+			return
+		}
 
 		var parentEventData types.Event
 		if len(events) > 0 {
@@ -1982,6 +2670,136 @@ func UpdateEventRegPurchHandler(w http.ResponseWriter, r *http.Request) http.Han
 	}
 }
 
+// prepareRecurringSeries gives new recurring series parents an id up front,
+// their children are keyed off it
+func prepareRecurringSeries(events []types.Event) {
+	for i := range events {
+		if services.IsRecurringSeriesParent(events[i]) && events[i].Id == "" {
+			events[i].Id = uuid.NewString()
+		}
+	}
+}
+
+// syncRecurringSeries materializes the children of any recurring series
+// parent among `events`
+func syncRecurringSeries(ctx context.Context, eventStore services.EventStore, events []types.Event) error {
+	for _, event := range events {
+		if !services.IsRecurringSeriesParent(event) {
+			continue
+		}
+		if _, err := services.SyncEventSeries(ctx, eventStore, event, time.Now()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getEditableSeriesOccurrence resolves the series parent and occurrence slot
+// from the route and checks the user may edit the series. It writes the error
+// response itself and returns ok=false on failure
+func getEditableSeriesOccurrence(w http.ResponseWriter, r *http.Request) (eventStore services.EventStore, parent types.Event, recurrenceId int64, ok bool) {
+	ctx := r.Context()
+	vars := mux.Vars(r)
+
+	userInfo := constants.UserInfo{}
+	if _, ok := ctx.Value("userInfo").(constants.UserInfo); ok {
+		userInfo = ctx.Value("userInfo").(constants.UserInfo)
+	}
+	roleClaims := []constants.RoleClaim{}
+	if claims, ok := ctx.Value("roleClaims").([]constants.RoleClaim); ok {
+		roleClaims = claims
+	}
+	if userInfo.Sub == "" {
+		transport.SendServerRes(w, []byte("Missing user ID"), http.StatusUnauthorized, nil)
+		return nil, parent, 0, false
+	}
+
+	recurrenceId, err := strconv.ParseInt(vars[constants.RECURRENCE_ID_KEY], 10, 64)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Invalid recurrence id: "+err.Error()), http.StatusBadRequest, err)
+		return nil, parent, 0, false
+	}
+
+	eventStore, err = services.GetEventStore()
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
+		return nil, parent, 0, false
+	}
+
+	event, err := eventStore.GetEventByID(ctx, vars[constants.EVENT_ID_KEY], "")
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get event: "+err.Error()), http.StatusNotFound, err)
+		return nil, parent, 0, false
+	}
+	if !services.IsRecurringSeriesParent(*event) {
+		err := errors.New("event is not a recurring series")
+		transport.SendServerRes(w, []byte(err.Error()), http.StatusBadRequest, err)
+		return nil, parent, 0, false
+	}
+	if !helpers.CanEditEvent(event, &userInfo, roleClaims) {
+		err := errors.New("only event owners can edit this series")
+		transport.SendServerRes(w, []byte(err.Error()), http.StatusForbidden, err)
+		return nil, parent, 0, false
+	}
+
+	return eventStore, *event, recurrenceId, true
+}
+
+// CancelSeriesOccurrenceHandler cancels a single occurrence of a recurring
+// series by adding it to the parent's excluded dates
+func CancelSeriesOccurrenceHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		eventStore, parent, recurrenceId, ok := getEditableSeriesOccurrence(w, r)
+		if !ok {
+			return
+		}
+
+		parent, err := services.CancelSeriesOccurrence(r.Context(), eventStore, parent, recurrenceId, time.Now())
+		if err != nil {
+			transport.SendServerRes(w, []byte("Failed to cancel occurrence: "+err.Error()), http.StatusInternalServerError, err)
+			return
+		}
+
+		res, err := json.Marshal(parent)
+		if err != nil {
+			transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+			return
+		}
+		transport.SendServerRes(w, res, http.StatusOK, nil)
+	}
+}
+
+// OverrideSeriesOccurrenceHandler replaces a single occurrence of a recurring
+// series with the event in the request body, later edits to the series rule
+// leave it as-is for as long as the occurrence remains in the schedule
+func OverrideSeriesOccurrenceHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		eventStore, parent, recurrenceId, ok := getEditableSeriesOccurrence(w, r)
+		if !ok {
+			return
+		}
+
+		occurrence, status, err := ValidateSingleEventPaylod(w, r, false)
+		if err != nil {
+			transport.SendServerRes(w, []byte("Failed to extract event from payload: "+err.Error()), status, err)
+			return
+		}
+
+		occurrence, err = services.OverrideSeriesOccurrence(r.Context(), eventStore, parent, recurrenceId, occurrence, time.Now())
+		if err != nil {
+			transport.SendServerRes(w, []byte("Failed to override occurrence: "+err.Error()), http.StatusBadRequest, err)
+			return
+		}
+
+		res, err := json.Marshal(occurrence)
+		if err != nil {
+			transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+			return
+		}
+		transport.SendServerRes(w, res, http.StatusOK, nil)
+	}
+}
+
 func BulkDeleteEventsHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		BulkDeleteEvents(w, r)
@@ -2003,19 +2821,33 @@ func BulkDeleteEvents(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	weaviateClient, err := services.GetWeaviateClient()
+	eventStore, err := services.GetEventStore()
 	if err != nil {
-		transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err)
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
 
 	// TODO: check that the event user has permission to delete via `eventOwners` array
 
-	_, err = services.BulkDeleteEventsFromWeaviate(r.Context(), weaviateClient, bulkDeleteEventsPayload.Events)
+	// Deleted events can't be looked up afterwards, their owners and the
+	// snapshot sent to webhooks come from before the delete
+	deleted, err := eventStore.BulkGetEventByID(r.Context(), bulkDeleteEventsPayload.Events, "")
+	if err != nil {
+		log.Printf("ERR: failed to get events before delete for webhooks: %v", err)
+	}
+
+	err = eventStore.BulkDeleteEvents(r.Context(), bulkDeleteEventsPayload.Events)
 	if err != nil {
 		transport.SendServerRes(w, []byte("Failed to delete events from weaviate: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
+	deletedEvents := make([]types.Event, 0, len(deleted))
+	for _, event := range deleted {
+		if event != nil {
+			deletedEvents = append(deletedEvents, *event)
+		}
+	}
+	services.EmitEventWebhooks(r.Context(), services.WEBHOOK_EVENT_DELETED, deletedEvents)
 
 	transport.SendServerRes(w, []byte("Events deleted successfully"), http.StatusOK, nil)
 }
@@ -2046,55 +2878,13 @@ func (h *WeaviateHandler) PostReShare(w http.ResponseWriter, r *http.Request) {
 
 	eventId := r.URL.Query().Get("event_id")
 
-	weaviateClient, err := services.GetWeaviateClient()
+	eventStore, err := services.GetEventStore()
 	if err != nil {
-		transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err)
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
 
-	resp, err := weaviateClient.Data().ObjectsGetter().
-		WithID(eventId).
-		WithClassName(constants.WeaviateEventClassName).
-		Do(ctx)
-
-	var existingShadowOwners []string
-	if resp != nil && len(resp) > 0 && resp[0] != nil {
-		if props, ok := resp[0].Properties.(map[string]interface{}); ok {
-			if shadowOwners, exists := props["shadowOwners"]; exists {
-				if shadowOwnersSlice, ok := shadowOwners.([]interface{}); ok {
-					for _, owner := range shadowOwnersSlice {
-						if ownerStr, ok := owner.(string); ok {
-							existingShadowOwners = append(existingShadowOwners, ownerStr)
-						}
-					}
-				}
-			}
-		}
-	}
-
-	// Always append the current userId
-	existingShadowOwners = append(existingShadowOwners, userId)
-
-	// Remove duplicates
-	seen := make(map[string]bool)
-	unique := []string{}
-	for _, owner := range existingShadowOwners {
-		if !seen[owner] {
-			seen[owner] = true
-			unique = append(unique, owner)
-		}
-	}
-	existingShadowOwners = unique
-
-	err = weaviateClient.Data().Updater().
-		WithMerge(). // merges properties into the object
-		WithID(eventId).
-		WithClassName(constants.WeaviateEventClassName).
-		WithProperties(map[string]interface{}{
-			"shadowOwners": existingShadowOwners, // Only the 'points' property is updated
-		}).
-		Do(ctx)
-
+	err = eventStore.AddShadowOwner(ctx, eventId, userId)
 	if err != nil {
 		transport.SendServerRes(w, []byte("Failed to re share event: "+err.Error()), http.StatusInternalServerError, err)
 		return
@@ -11,11 +11,13 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -341,6 +343,438 @@ func TestPostEventHandler(t *testing.T) {
 
 // Need to move these to Weaviate
 
+func TestEventHandlersWithMemoryStore(t *testing.T) {
+	t.Setenv("EVENT_STORE", services.EVENT_STORE_MEMORY)
+	services.ResetEventStore()
+	defer services.ResetEventStore()
+
+	startTime := time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)
+	postBody := `{"eventOwnerName":"Event Owner","eventOwners":["owner-1"],"eventSourceType":"` + constants.ES_SINGLE_EVENT + `","name":"Memory Jazz Night","description":"A test event","address":"123 Test St","lat":51.5074,"long":-0.1278,"timezone":"Europe/London","startTime":"` + startTime + `"}`
+	req := httptest.NewRequest("POST", "/api/event", bytes.NewBufferString(postBody))
+	rr := httptest.NewRecorder()
+	PostEventHandler(rr, req)(rr, req)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("post: expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var created []models.ObjectsGetResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil || len(created) != 1 {
+		t.Fatalf("post: unexpected response %s (%v)", rr.Body.String(), err)
+	}
+	eventId := created[0].ID.String()
+
+	search := func(rawQuery string) types.EventSearchResponse {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/events?lat=51.5074&lon=-0.1278&radius=10&"+rawQuery, nil)
+		rr := httptest.NewRecorder()
+		SearchEventsHandler(rr, req)(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("search: expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var res types.EventSearchResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+			t.Fatalf("search: failed to unmarshal response: %v", err)
+		}
+		return res
+	}
+
+	if res := search("q=jazz"); len(res.Events) != 1 || res.Events[0].Id != eventId {
+		t.Errorf("search: expected the posted event, got %+v", res.Events)
+	}
+	if res := search("owners=someone-else"); len(res.Events) != 0 {
+		t.Errorf("search: expected no events for another owner, got %+v", res.Events)
+	}
+
+	req = httptest.NewRequest("GET", "/api/events/"+eventId, nil)
+	req = mux.SetURLVars(req, map[string]string{constants.EVENT_ID_KEY: eventId})
+	rr = httptest.NewRecorder()
+	GetOneEventHandler(rr, req)(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Memory Jazz Night") {
+		t.Errorf("get: expected the posted event, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest("POST", "/api/events/re-share?event_id="+eventId, nil)
+	ctx := context.WithValue(req.Context(), "userInfo", constants.UserInfo{Sub: "sharer"})
+	ctx = context.WithValue(ctx, "roleClaims", []constants.RoleClaim{{Role: string(constants.SubGrowth)}})
+	rr = httptest.NewRecorder()
+	PostReShareHandler(rr, req)(rr, req.WithContext(ctx))
+	if rr.Code != http.StatusOK {
+		t.Errorf("re-share: expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if res := search("owners=sharer"); len(res.Events) != 1 {
+		t.Errorf("search: expected the re-shared event for its shadow owner, got %+v", res.Events)
+	}
+
+	req = httptest.NewRequest("DELETE", "/api/events", bytes.NewBufferString(`{"events":["`+eventId+`"]}`))
+	rr = httptest.NewRecorder()
+	BulkDeleteEventsHandler(rr, req)(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Errorf("delete: expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if res := search(""); len(res.Events) != 0 {
+		t.Errorf("search: expected no events after delete, got %+v", res.Events)
+	}
+}
+
+func TestImportICalEvents(t *testing.T) {
+	originalWeaviateHost := os.Getenv("WEAVIATE_HOST")
+	originalWeaviateScheme := os.Getenv("WEAVIATE_SCHEME")
+	originalWeaviatePort := os.Getenv("WEAVIATE_PORT")
+
+	defer func() {
+		os.Setenv("WEAVIATE_HOST", originalWeaviateHost)
+		os.Setenv("WEAVIATE_SCHEME", originalWeaviateScheme)
+		os.Setenv("WEAVIATE_PORT", originalWeaviatePort)
+	}()
+
+	staleEventId := uuid.New().String()
+	futureStart := time.Now().Add(72 * time.Hour).UTC()
+	var upsertedIds []string
+	var deleteCalls int
+
+	hostAndPort := test_helpers.GetNextPort()
+	mockWeaviateServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/meta":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"version":"1.23.4"}`))
+		case "/v1/graphql":
+			// A previously imported event that has since been removed from the feed
+			mockResponse := models.GraphQLResponse{
+				Data: map[string]models.JSONObject{
+					"Get": map[string]interface{}{
+						constants.WeaviateEventClassName: []interface{}{
+							map[string]interface{}{
+								"name":            "Removed From Feed",
+								"eventSourceType": constants.ES_SINGLE_EVENT,
+								"eventSourceId":   "ics-upload:club.ics",
+								"timezone":        "America/New_York",
+								"startTime":       futureStart.Unix(),
+								"_additional":     map[string]interface{}{"id": staleEventId},
+							},
+						},
+					},
+				},
+			}
+			responseBytes, err := json.Marshal(mockResponse)
+			if err != nil {
+				t.Fatalf("failed to marshal mock GraphQL response: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+		case "/v1/batch/objects":
+			if r.Method == http.MethodDelete {
+				deleteCalls++
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"results":{"matches":1,"successful":1,"failed":0}}`))
+				return
+			}
+			var requestBody struct {
+				Objects []*models.Object `json:"objects"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+				t.Fatalf("failed to decode request body: %v", err)
+			}
+			response := make([]*models.ObjectsGetResponse, len(requestBody.Objects))
+			for i, obj := range requestBody.Objects {
+				upsertedIds = append(upsertedIds, obj.ID.String())
+				status := "SUCCESS"
+				response[i] = &models.ObjectsGetResponse{
+					Object: models.Object{ID: obj.ID, Class: obj.Class},
+					Result: &models.ObjectsGetResponseAO2Result{Status: &status},
+				}
+			}
+			responseBytes, err := json.Marshal(response)
+			if err != nil {
+				t.Fatalf("failed to marshal mock response: %v", err)
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+		default:
+			t.Errorf("mock server received request to unhandled path: %s", r.URL.Path)
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+
+	listener, err := test_helpers.BindToPort(t, hostAndPort)
+	if err != nil {
+		t.Fatalf("BindToPort failed: %v", err)
+	}
+	mockWeaviateServer.Listener = listener
+	mockWeaviateServer.Start()
+	defer mockWeaviateServer.Close()
+
+	actualParts := strings.Split(listener.Addr().String(), ":")
+	os.Setenv("WEAVIATE_HOST", actualParts[0])
+	os.Setenv("WEAVIATE_PORT", actualParts[1])
+	os.Setenv("WEAVIATE_SCHEME", "http")
+	os.Setenv("WEAVIATE_API_KEY_ALLOWED_KEYS", "test-weaviate-api-key")
+
+	icsBody := "BEGIN:VCALENDAR\r\n" +
+		"VERSION:2.0\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:open-mic@example.com\r\n" +
+		"SUMMARY:Open Mic\r\n" +
+		"DTSTART:" + futureStart.Format("20060102T150405Z") + "\r\n" +
+		"DURATION:PT2H\r\n" +
+		"GEO:40.7128;-74.0060\r\n" +
+		"LOCATION:The Bitter End\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	t.Run("rejects anonymous imports", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/ical/import?source=club.ics", strings.NewReader(icsBody))
+		req.Header.Set("Content-Type", "text/calendar")
+		rr := httptest.NewRecorder()
+		ImportICalEvents(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+
+	t.Run("rejects oversized bodies", func(t *testing.T) {
+		oversized := strings.Repeat("X", services.MaxICalFeedBytes+(1<<20)+1)
+		req := httptest.NewRequest("POST", "/api/ical/import?source=club.ics", strings.NewReader(oversized))
+		req.Header.Set("Content-Type", "text/calendar")
+		req = req.WithContext(context.WithValue(req.Context(), "userInfo", constants.UserInfo{Sub: "owner-1", Name: "Club"}))
+		rr := httptest.NewRecorder()
+		ImportICalEvents(rr, req)
+		if rr.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("expected status %d, got %d", http.StatusRequestEntityTooLarge, rr.Code)
+		}
+	})
+
+	t.Run("rejects documents without events", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/ical/import?source=club.ics", strings.NewReader("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"))
+		req.Header.Set("Content-Type", "text/calendar")
+		req = req.WithContext(context.WithValue(req.Context(), "userInfo", constants.UserInfo{Sub: "owner-1", Name: "Club"}))
+		rr := httptest.NewRecorder()
+		ImportICalEvents(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+		}
+		if deleteCalls != 0 {
+			t.Errorf("expected an empty document not to delete anything")
+		}
+	})
+
+	t.Run("imports an uploaded calendar and reconciles by UID", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/api/ical/import?source=club.ics", strings.NewReader(icsBody))
+		req.Header.Set("Content-Type", "text/calendar")
+		req = req.WithContext(context.WithValue(req.Context(), "userInfo", constants.UserInfo{Sub: "owner-1", Name: "Club"}))
+		rr := httptest.NewRecorder()
+		ImportICalEvents(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var result services.ICalImportResult
+		if err := json.Unmarshal(rr.Body.Bytes(), &result); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if result.SourceKey != "ics-upload:club.ics" || result.Parsed != 1 || result.Inserted != 1 || result.Deleted != 1 {
+			t.Errorf("unexpected import result: %+v", result)
+		}
+		expectedId := services.ICalEventId("owner-1", "open-mic@example.com", time.Time{})
+		if len(upsertedIds) != 1 || upsertedIds[0] != expectedId {
+			t.Errorf("expected upsert of %s, got %v", expectedId, upsertedIds)
+		}
+		if deleteCalls != 1 {
+			t.Errorf("expected the stale event to be deleted, got %d delete calls", deleteCalls)
+		}
+	})
+}
+
+func TestImportCSVEvents(t *testing.T) {
+	originalWeaviateHost := os.Getenv("WEAVIATE_HOST")
+	originalWeaviateScheme := os.Getenv("WEAVIATE_SCHEME")
+	originalWeaviatePort := os.Getenv("WEAVIATE_PORT")
+
+	defer func() {
+		os.Setenv("WEAVIATE_HOST", originalWeaviateHost)
+		os.Setenv("WEAVIATE_SCHEME", originalWeaviateScheme)
+		os.Setenv("WEAVIATE_PORT", originalWeaviatePort)
+	}()
+
+	// Row "A-1" was imported by an earlier upload of the same sheet
+	existingId := services.CSVImportEventId("owner-1", "A-1")
+	var upsertedIds []string
+
+	hostAndPort := test_helpers.GetNextPort()
+	mockWeaviateServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/meta":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"version":"1.23.4"}`))
+		case "/v1/graphql":
+			mockResponse := models.GraphQLResponse{
+				Data: map[string]models.JSONObject{
+					"Get": map[string]interface{}{
+						constants.WeaviateEventClassName: []interface{}{
+							map[string]interface{}{
+								"name":            "Bingo",
+								"eventOwners":     []interface{}{"owner-1"},
+								"eventSourceType": constants.ES_SINGLE_EVENT,
+								"timezone":        "America/Chicago",
+								"startTime":       time.Now().Add(72 * time.Hour).Unix(),
+								"_additional":     map[string]interface{}{"id": existingId},
+							},
+						},
+					},
+				},
+			}
+			responseBytes, err := json.Marshal(mockResponse)
+			if err != nil {
+				t.Fatalf("failed to marshal mock GraphQL response: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+		case "/v1/batch/objects":
+			var requestBody struct {
+				Objects []*models.Object `json:"objects"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+				t.Fatalf("failed to decode request body: %v", err)
+			}
+			response := make([]*models.ObjectsGetResponse, len(requestBody.Objects))
+			for i, obj := range requestBody.Objects {
+				upsertedIds = append(upsertedIds, obj.ID.String())
+				status := "SUCCESS"
+				response[i] = &models.ObjectsGetResponse{
+					Object: models.Object{ID: obj.ID, Class: obj.Class},
+					Result: &models.ObjectsGetResponseAO2Result{Status: &status},
+				}
+			}
+			responseBytes, err := json.Marshal(response)
+			if err != nil {
+				t.Fatalf("failed to marshal mock response: %v", err)
+			}
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+		default:
+			t.Errorf("mock server received request to unhandled path: %s", r.URL.Path)
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+
+	listener, err := test_helpers.BindToPort(t, hostAndPort)
+	if err != nil {
+		t.Fatalf("BindToPort failed: %v", err)
+	}
+	mockWeaviateServer.Listener = listener
+	mockWeaviateServer.Start()
+	defer mockWeaviateServer.Close()
+
+	actualParts := strings.Split(listener.Addr().String(), ":")
+	os.Setenv("WEAVIATE_HOST", actualParts[0])
+	os.Setenv("WEAVIATE_PORT", actualParts[1])
+	os.Setenv("WEAVIATE_SCHEME", "http")
+	os.Setenv("WEAVIATE_API_KEY_ALLOWED_KEYS", "test-weaviate-api-key")
+
+	startDate := time.Now().Add(72 * time.Hour).Format("2006-01-02")
+	csvBody := "Ref,Title,Date,Start Time,Venue,Latitude,Longitude\n" +
+		"A-1,Bingo," + startDate + ",7:00 PM,Hall,41.88,-87.63\n" +
+		"A-2,Trivia," + startDate + ",8:00 PM,Hall,41.88,-87.63\n" +
+		"A-3,Karaoke," + startDate + ",whenever,Hall,41.88,-87.63\n"
+
+	newRequest := func(fields map[string]string, withRole bool) *http.Request {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		part, err := writer.CreateFormFile("file", "events.csv")
+		if err != nil {
+			t.Fatalf("failed to create form file: %v", err)
+		}
+		part.Write([]byte(csvBody))
+		for key, value := range fields {
+			writer.WriteField(key, value)
+		}
+		writer.Close()
+		req := httptest.NewRequest("POST", "/api/events/import/csv", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		ctx := context.WithValue(req.Context(), "userInfo", constants.UserInfo{Sub: "owner-1", Name: "Club"})
+		if withRole {
+			ctx = context.WithValue(ctx, "roleClaims", []constants.RoleClaim{{Role: constants.Roles[constants.EventAdmin]}})
+		}
+		return req.WithContext(ctx)
+	}
+	handler := NewWeaviateHandler(services.NewWeaviateService())
+
+	t.Run("requires an event admin", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ImportCSVEvents(rr, newRequest(nil, false))
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rr.Code)
+		}
+	})
+
+	t.Run("rejects an invalid mapping", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ImportCSVEvents(rr, newRequest(map[string]string{"mapping": `{"Venue":`}, true))
+		if rr.Code != http.StatusUnprocessableEntity {
+			t.Errorf("expected status %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+		}
+	})
+
+	t.Run("dry run reports every row without writing", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.ImportCSVEvents(rr, newRequest(map[string]string{"mapping": `{"Ref":"id"}`}, true))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var report services.CSVImportReport
+		if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+			t.Fatalf("failed to decode report: %v", err)
+		}
+		if !report.DryRun || report.Valid != 2 || report.Invalid != 1 || report.Updates != 1 || report.Inserts != 1 {
+			t.Errorf("unexpected report: %+v", report)
+		}
+		if len(report.Rows) != 3 || report.Rows[0].Action != services.CSV_IMPORT_ACTION_UPDATE || report.Rows[0].Timezone != "America/Chicago" || len(report.Rows[2].Errors) != 1 {
+			t.Errorf("unexpected rows: %+v", report.Rows)
+		}
+		if len(upsertedIds) != 0 {
+			t.Errorf("expected a dry run not to write, got %v", upsertedIds)
+		}
+	})
+
+	t.Run("commit streams progress then the report", func(t *testing.T) {
+		req := newRequest(map[string]string{"mapping": `{"Ref":"id"}`, "commit": "true"}, true)
+		req.Header.Set("Accept", "application/x-ndjson")
+		rr := httptest.NewRecorder()
+		handler.ImportCSVEvents(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var lines []CSVImportStreamLine
+		decoder := json.NewDecoder(rr.Body)
+		for decoder.More() {
+			var line CSVImportStreamLine
+			if err := decoder.Decode(&line); err != nil {
+				t.Fatalf("failed to decode stream line: %v", err)
+			}
+			lines = append(lines, line)
+		}
+		if len(lines) != 2 || lines[0].Committed != 2 || lines[0].Total != 2 {
+			t.Fatalf("expected one progress line and the report, got %+v", lines)
+		}
+		last := lines[len(lines)-1]
+		if last.Error != "" || last.Report == nil || last.Report.DryRun || last.Report.Committed != 2 {
+			t.Errorf("unexpected final line: %+v", last)
+		}
+		expected := []string{existingId, services.CSVImportEventId("owner-1", "A-2")}
+		if strings.Join(upsertedIds, ",") != strings.Join(expected, ",") {
+			t.Errorf("expected upserts of %v, got %v", expected, upsertedIds)
+		}
+	})
+}
+
 func TestPostBatchEvents(t *testing.T) {
 	// --- Standard Test Setup (same pattern) ---
 	originalWeaviateHost := os.Getenv("WEAVIATE_HOST")
@@ -414,6 +848,35 @@ func TestPostBatchEvents(t *testing.T) {
 			w.WriteHeader(http.StatusOK)
 			w.Write(responseBytes)
 
+		case "/v1/graphql":
+			t.Logf("   └─ Handling /v1/graphql (duplicate lookup)")
+			// An event already listed at the same time and place as the batch's
+			mockResponse := models.GraphQLResponse{
+				Data: map[string]models.JSONObject{
+					"Get": map[string]interface{}{
+						constants.WeaviateEventClassName: []interface{}{
+							map[string]interface{}{
+								"name":            "Already Listed",
+								"eventOwners":     []interface{}{"owner-456"},
+								"eventSourceType": constants.ES_SINGLE_EVENT,
+								"timezone":        "America/New_York",
+								"startTime":       int64(4095309600),
+								"lat":             40.1,
+								"long":            -74.1,
+								"_additional":     map[string]interface{}{"id": "existing-event-id"},
+							},
+						},
+					},
+				},
+			}
+			responseBytes, err := json.Marshal(mockResponse)
+			if err != nil {
+				t.Fatalf("failed to marshal mock GraphQL response: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+
 		default:
 			t.Logf("   └─ ⚠️  UNHANDLED PATH: %s", r.URL.Path)
 			t.Errorf("mock server received request to unhandled path: %s", r.URL.Path)
@@ -476,11 +939,26 @@ func TestPostBatchEvents(t *testing.T) {
 		t.Fatalf("Setup failed: Could not marshal partially invalid request body: %v", err)
 	}
 
+	marshalBatch := func(events ...services.RawEvent) string {
+		body, err := json.Marshal(map[string][]services.RawEvent{"events": events})
+		if err != nil {
+			t.Fatalf("Setup failed: Could not marshal request body: %v", err)
+		}
+		return string(body)
+	}
+	shortenedImage := "https://bit.ly/batch-poster"
+	warnedEvent := createValidRawEvent(uuid.New().String(), "Event With A Short Link", "https://example.com/short-link")
+	warnedEvent.ImageUrl = &shortenedImage
+	pastEvent := createValidRawEvent(uuid.New().String(), "Event Long Gone", "https://example.com/long-gone")
+	pastEvent.StartTime = "2001-10-10T10:00:00Z"
+	duplicateEvent := createValidRawEvent(uuid.New().String(), "Already Listed!", "https://example.com/already-listed")
+
 	tests := []struct {
-		name              string
-		requestBody       string
-		expectedStatus    int
-		expectedBodyCheck func(t *testing.T, body string)
+		name                string
+		requestBody         string
+		expectedStatus      int
+		expectedBodyCheck   func(t *testing.T, body string)
+		expectedHeaderCheck func(t *testing.T, header http.Header)
 	}{
 		{
 			name:           "Valid batch of events posts successfully",
@@ -506,32 +984,69 @@ func TestPostBatchEvents(t *testing.T) {
 			},
 		},
 		{
-			name:           "Invalid JSON payload",
-			requestBody:    `{"events":[{"name":"Test Event","description":}]}`,
-			expectedStatus: http.StatusUnprocessableEntity,
-			expectedBodyCheck: func(t *testing.T, body string) {
-				if !strings.Contains(strings.ToLower(body), "invalid json payload") {
-					t.Errorf("Expected body to contain 'invalid json payload', but got '%s'", body)
+			name:           "Batch with a warning is accepted and reports it",
+			requestBody:    marshalBatch(warnedEvent),
+			expectedStatus: http.StatusCreated,
+			expectedHeaderCheck: func(t *testing.T, header http.Header) {
+				var warnings []types.EventValidationIssue
+				if err := json.Unmarshal([]byte(header.Get(EVENT_VALIDATION_WARNINGS_HEADER)), &warnings); err != nil {
+					t.Fatalf("Expected warnings header, got '%s': %v", header.Get(EVENT_VALIDATION_WARNINGS_HEADER), err)
+				}
+				if len(warnings) != 1 || warnings[0].Rule != services.EVENT_RULE_SUSPICIOUS_URL {
+					t.Errorf("Expected one suspicious_url warning, got %+v", warnings)
+				}
+				if count := header.Get(EVENT_VALIDATION_WARNING_COUNT_HEADER); count != "1" {
+					t.Errorf("Expected a warning count of 1, got %q", count)
 				}
 			},
 		},
 		{
-			name:           "Empty events array",
-			requestBody:    `{"events":[]}`,
+			name:           "Batch with an event in the past is rejected",
+			requestBody:    marshalBatch(warnedEvent, pastEvent),
 			expectedStatus: http.StatusBadRequest,
 			expectedBodyCheck: func(t *testing.T, body string) {
-				if !strings.Contains(body, "Field validation for 'Events' failed on the 'min' tag") {
-					t.Errorf("Expected validation error for empty events array, but got '%s'", body)
+				if !strings.Contains(body, "invalid event at index 1: start_in_past") {
+					t.Errorf("Expected start_in_past error, but got '%s'", body)
 				}
 			},
 		},
 		{
-			name:           "Missing events field",
-			requestBody:    `{}`,
+			name:           "Batch duplicating a listed event is rejected",
+			requestBody:    marshalBatch(duplicateEvent),
 			expectedStatus: http.StatusBadRequest,
 			expectedBodyCheck: func(t *testing.T, body string) {
-				if !strings.Contains(body, "Field validation for 'Events' failed on the 'required' tag") {
-					t.Errorf("Expected validation error for missing events field, but got '%s'", body)
+				if !strings.Contains(body, "duplicates existing event existing-event-id") {
+					t.Errorf("Expected duplicate error, but got '%s'", body)
+				}
+			},
+		},
+		{
+			name:           "Invalid JSON payload",
+			requestBody:    `{"events":[{"name":"Test Event","description":}]}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedBodyCheck: func(t *testing.T, body string) {
+				if !strings.Contains(strings.ToLower(body), "invalid json payload") {
+					t.Errorf("Expected body to contain 'invalid json payload', but got '%s'", body)
+				}
+			},
+		},
+		{
+			name:           "Empty events array",
+			requestBody:    `{"events":[]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBodyCheck: func(t *testing.T, body string) {
+				if !strings.Contains(body, "Field validation for 'Events' failed on the 'min' tag") {
+					t.Errorf("Expected validation error for empty events array, but got '%s'", body)
+				}
+			},
+		},
+		{
+			name:           "Missing events field",
+			requestBody:    `{}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedBodyCheck: func(t *testing.T, body string) {
+				if !strings.Contains(body, "Field validation for 'Events' failed on the 'required' tag") {
+					t.Errorf("Expected validation error for missing events field, but got '%s'", body)
 				}
 			},
 		},
@@ -560,6 +1075,11 @@ func TestPostBatchEvents(t *testing.T) {
 			if tt.expectedBodyCheck != nil {
 				tt.expectedBodyCheck(t, rr.Body.String())
 			}
+			if tt.expectedHeaderCheck != nil {
+				tt.expectedHeaderCheck(t, rr.Header())
+			} else if warnings := rr.Header().Get(EVENT_VALIDATION_WARNINGS_HEADER); warnings != "" {
+				t.Errorf("Expected no warnings, got %s", warnings)
+			}
 		})
 	}
 }
@@ -621,67 +1141,795 @@ func TestSearchEvents(t *testing.T) {
 			w.Write([]byte(metaResponse))
 
 		case "/v1/graphql":
-			t.Logf("   └─ Handling /v1/graphql search")
-			if r.Method != "POST" {
-				t.Errorf("expected method POST for /v1/graphql, got %s", r.Method)
-			}
-
-			// Parse the query to determine what to return
+			t.Logf("   └─ Handling /v1/graphql search")
+			if r.Method != "POST" {
+				t.Errorf("expected method POST for /v1/graphql, got %s", r.Method)
+			}
+
+			// Parse the query to determine what to return
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("failed to read request body: %v", err)
+			}
+
+			queryStr := string(body)
+			t.Logf("   └─ GraphQL Query: %s", queryStr)
+
+			var mockResponse models.GraphQLResponse
+
+			// Return different responses based on the search term
+			if strings.Contains(queryStr, "category0:") {
+				facetCount := func(n int) []interface{} {
+					return []interface{}{map[string]interface{}{"meta": map[string]interface{}{"count": n}}}
+				}
+				aggregate := map[string]interface{}{
+					"all":  facetCount(1),
+					"paid": facetCount(0),
+				}
+				for i := range constants.Categories {
+					aggregate[fmt.Sprintf("category%d", i)] = facetCount(0)
+				}
+				aggregate["category1"] = facetCount(1)
+				for i := 0; i < 60; i++ {
+					aggregate[fmt.Sprintf("date%d", i)] = facetCount(0)
+				}
+				mockResponse = models.GraphQLResponse{
+					Data: map[string]models.JSONObject{"Aggregate": aggregate},
+				}
+			} else if strings.Contains(queryStr, "Aggregate") {
+				mockResponse = models.GraphQLResponse{
+					Data: map[string]models.JSONObject{
+						"Aggregate": map[string]interface{}{
+							constants.WeaviateEventClassName: []interface{}{
+								map[string]interface{}{
+									"meta": map[string]interface{}{"count": 7},
+								},
+							},
+						},
+					},
+				}
+			} else if strings.Contains(queryStr, "paginated") {
+				pagedEvents := []interface{}{}
+				for i := 0; i < 3; i++ {
+					pagedEvents = append(pagedEvents, map[string]interface{}{
+						"name":            fmt.Sprintf("Paginated Event %d", i),
+						"eventOwnerName":  "Tech Org",
+						"eventSourceType": constants.ES_SINGLE_EVENT,
+						"timezone":        "America/Los_Angeles",
+						"startTime":       time.Now().Add(time.Duration(i+1) * time.Hour).Unix(),
+						"_additional": map[string]interface{}{
+							"id": fmt.Sprintf("paginated-event-%d", i),
+						},
+					})
+				}
+				mockResponse = models.GraphQLResponse{
+					Data: map[string]models.JSONObject{
+						"Get": map[string]interface{}{
+							constants.WeaviateEventClassName: pagedEvents,
+						},
+					},
+				}
+			} else if strings.Contains(queryStr, "programming") {
+				// Return one matching event for "programming" search
+				mockResponse = models.GraphQLResponse{
+					Data: map[string]models.JSONObject{
+						"Get": map[string]interface{}{
+							constants.WeaviateEventClassName: []interface{}{
+								map[string]interface{}{
+									"name":            "Conference on Go Programming",
+									"description":     "A deep dive into the Go language and its powerful ecosystem.",
+									"eventOwnerName":  "Tech Org",
+									"eventSourceType": constants.ES_SINGLE_EVENT,
+									"address":         "123 Tech Way, Silicon Valley, CA",
+									"lat":             37.3861,
+									"long":            -122.0839,
+									"timezone":        "America/Los_Angeles",
+									"startTime":       time.Now().Add(48 * time.Hour).Unix(),
+									"_additional": map[string]interface{}{
+										"id": "programming-event-123",
+									},
+								},
+							},
+						},
+					},
+				}
+			} else if strings.Contains(queryStr, "nonexistenttermxyz") {
+				// Return empty results for non-existent term
+				mockResponse = models.GraphQLResponse{
+					Data: map[string]models.JSONObject{
+						"Get": map[string]interface{}{
+							constants.WeaviateEventClassName: []interface{}{},
+						},
+					},
+				}
+			} else {
+				// Default case - return empty
+				mockResponse = models.GraphQLResponse{
+					Data: map[string]models.JSONObject{
+						"Get": map[string]interface{}{
+							constants.WeaviateEventClassName: []interface{}{},
+						},
+					},
+				}
+			}
+
+			responseBytes, err := json.Marshal(mockResponse)
+			if err != nil {
+				t.Fatalf("failed to marshal mock GraphQL response: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+
+		default:
+			t.Logf("   └─ ⚠️  UNHANDLED PATH: %s", r.URL.Path)
+			t.Errorf("mock server received request to unhandled path: %s", r.URL.Path)
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+
+	// Use the same binding pattern as working test
+	listener, err := test_helpers.BindToPort(t, hostAndPort)
+	if err != nil {
+		t.Fatalf("BindToPort failed: %v", err)
+	}
+	mockWeaviateServer.Listener = listener
+	mockWeaviateServer.Start()
+	defer mockWeaviateServer.Close()
+
+	// Set environment variables to the actual bound port
+	actualAddr := listener.Addr().String()
+	actualParts := strings.Split(actualAddr, ":")
+	actualHost, actualPort := actualParts[0], actualParts[1]
+
+	os.Setenv("WEAVIATE_HOST", actualHost)
+	os.Setenv("WEAVIATE_PORT", actualPort)
+	os.Setenv("WEAVIATE_SCHEME", "http")
+	os.Setenv("WEAVIATE_API_KEY_ALLOWED_KEYS", "test-weaviate-api-key")
+
+	t.Logf("🔧 SEARCH TEST SETUP COMPLETE")
+	t.Logf("   └─ Mock Server bound to: %s", actualAddr)
+	t.Logf("   └─ WEAVIATE_HOST: %s", os.Getenv("WEAVIATE_HOST"))
+	t.Logf("   └─ WEAVIATE_PORT: %s", os.Getenv("WEAVIATE_PORT"))
+
+	// --- Define the Test Table ---
+	tests := []struct {
+		name              string
+		path              string
+		expectedStatus    int
+		expectedBodyCheck func(t *testing.T, body string)
+	}{
+		{
+			name:           "Search with specific term finds correct event",
+			path:           "/events?q=programming",
+			expectedStatus: http.StatusOK,
+			expectedBodyCheck: func(t *testing.T, body string) {
+				var res types.EventSearchResponse
+				if err := json.Unmarshal([]byte(body), &res); err != nil {
+					t.Fatalf("Failed to unmarshal response body: %v", err)
+				}
+				if len(res.Events) != 1 {
+					t.Fatalf("Expected to find 1 event, but got %d", len(res.Events))
+				}
+
+				// Check the returned event details
+				if res.Events[0].Id != "programming-event-123" {
+					t.Errorf("Expected to find event 'programming-event-123', but got '%s'", res.Events[0].Id)
+				}
+				if res.Events[0].Name != "Conference on Go Programming" {
+					t.Errorf("Expected event name 'Conference on Go Programming', but got '%s'", res.Events[0].Name)
+				}
+			},
+		},
+		{
+			name:           "Search for term with no matches returns empty list",
+			path:           "/events?q=nonexistenttermxyz",
+			expectedStatus: http.StatusOK,
+			expectedBodyCheck: func(t *testing.T, body string) {
+				var res types.EventSearchResponse
+				if err := json.Unmarshal([]byte(body), &res); err != nil {
+					t.Fatalf("Failed to unmarshal response body: %v", err)
+				}
+				if len(res.Events) != 0 {
+					t.Errorf("Expected 0 events for a nonexistent term, but got %d", len(res.Events))
+				}
+			},
+		},
+		{
+			name:           "Search with limit returns a page, total and cursor",
+			path:           "/events?q=paginated&limit=2",
+			expectedStatus: http.StatusOK,
+			expectedBodyCheck: func(t *testing.T, body string) {
+				var res types.EventSearchResponse
+				if err := json.Unmarshal([]byte(body), &res); err != nil {
+					t.Fatalf("Failed to unmarshal response body: %v", err)
+				}
+				if len(res.Events) != 2 {
+					t.Fatalf("Expected a page of 2 events, but got %d", len(res.Events))
+				}
+				if !res.HasMore || res.NextCursor == "" {
+					t.Errorf("Expected hasMore with a nextCursor, got hasMore=%v nextCursor=%q", res.HasMore, res.NextCursor)
+				}
+				if res.Total != 7 {
+					t.Errorf("Expected total 7 from the aggregate count, got %d", res.Total)
+				}
+			},
+		},
+		{
+			name:           "Search with facets returns category and price counts",
+			path:           "/events?q=programming&facets=1&tz=America/Denver",
+			expectedStatus: http.StatusOK,
+			expectedBodyCheck: func(t *testing.T, body string) {
+				var res types.EventSearchResponse
+				if err := json.Unmarshal([]byte(body), &res); err != nil {
+					t.Fatalf("Failed to unmarshal response body: %v", err)
+				}
+				if res.Facets == nil {
+					t.Fatalf("Expected facets in the response")
+				}
+				if len(res.Facets.Categories) != len(constants.Categories) {
+					t.Fatalf("Expected %d category facets, got %d", len(constants.Categories), len(res.Facets.Categories))
+				}
+				if res.Facets.Categories[1].Key != constants.Categories[1].Slug || res.Facets.Categories[1].Count != 1 {
+					t.Errorf("Unexpected category facet: %+v", res.Facets.Categories[1])
+				}
+				if res.Facets.Price.Free != 1 || res.Facets.Price.Paid != 0 {
+					t.Errorf("Unexpected price facet: %+v", res.Facets.Price)
+				}
+				if res.Facets.DateBucket != "week" || len(res.Facets.Dates) == 0 {
+					t.Errorf("Expected weekly date buckets for the default window, got %q with %d buckets", res.Facets.DateBucket, len(res.Facets.Dates))
+				}
+			},
+		},
+		{
+			name:           "Search with a malformed cursor is rejected",
+			path:           "/events?q=programming&cursor=not-a-cursor",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Search without query parameter returns empty results",
+			path:           "/events",
+			expectedStatus: http.StatusOK,
+			expectedBodyCheck: func(t *testing.T, body string) {
+				var res types.EventSearchResponse
+				if err := json.Unmarshal([]byte(body), &res); err != nil {
+					t.Fatalf("Failed to unmarshal response body: %v", err)
+				}
+				// Should return empty results when no query provided
+				if len(res.Events) != 0 {
+					t.Errorf("Expected 0 events when no query provided, but got %d", len(res.Events))
+				}
+			},
+		},
+	}
+
+	// --- The Test Runner ---
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Logf("🧪 RUNNING SEARCH TEST: %s", tt.name)
+
+			// ACT: Perform the HTTP request
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+
+			// Fix: Get the handler function and then call it
+			handlerFunc := SearchEventsHandler(rr, req)
+			handlerFunc(rr, req)
+
+			t.Logf("📊 SEARCH TEST RESULTS:")
+			t.Logf("   └─ Status: %d (expected %d)", rr.Code, tt.expectedStatus)
+			t.Logf("   └─ Body: %s", rr.Body.String())
+
+			// ASSERT: Check the results
+			if rr.Code != tt.expectedStatus {
+				t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
+			}
+			if tt.expectedBodyCheck != nil {
+				tt.expectedBodyCheck(t, rr.Body.String())
+			}
+		})
+	}
+
+	t.Run("Cursor continues its own search and is rejected by others", func(t *testing.T) {
+		search := func(path string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest("GET", path, nil)
+			rr := httptest.NewRecorder()
+			SearchEventsHandler(rr, req)(rr, req)
+			return rr
+		}
+
+		first := search("/events?q=paginated&limit=2")
+		var res types.EventSearchResponse
+		if err := json.Unmarshal(first.Body.Bytes(), &res); err != nil {
+			t.Fatalf("Failed to unmarshal response body: %v", err)
+		}
+		if res.NextCursor == "" {
+			t.Fatalf("Expected a nextCursor on the first page")
+		}
+
+		if rr := search("/events?q=paginated&limit=2&cursor=" + url.QueryEscape(res.NextCursor)); rr.Code != http.StatusOK {
+			t.Errorf("Expected the cursor to continue its own search, got status %d: %s", rr.Code, rr.Body.String())
+		}
+		if rr := search("/events?q=programming&cursor=" + url.QueryEscape(res.NextCursor)); rr.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for a cursor from another search, got %d", http.StatusBadRequest, rr.Code)
+		}
+	})
+}
+
+func TestSetEventValidationWarningsHeaders(t *testing.T) {
+	warnings := []types.EventValidationIssue{}
+	for i := 0; i < 500; i++ {
+		warnings = append(warnings, types.EventValidationIssue{
+			Index: i, Rule: services.EVENT_RULE_SUSPICIOUS_URL, Severity: services.EVENT_VALIDATION_SEVERITY_WARNING,
+			Message: strings.Repeat("x", 100),
+		})
+	}
+	header := http.Header{}
+	setEventValidationWarningsHeaders(header, warnings)
+
+	value := header.Get(EVENT_VALIDATION_WARNINGS_HEADER)
+	if len(value) > eventValidationWarningsHeaderMaxBytes {
+		t.Errorf("Expected the header capped at %d bytes, got %d", eventValidationWarningsHeaderMaxBytes, len(value))
+	}
+	var fitting []types.EventValidationIssue
+	if err := json.Unmarshal([]byte(value), &fitting); err != nil {
+		t.Fatalf("Expected a JSON array, got %v", err)
+	}
+	if len(fitting) == 0 || len(fitting) >= len(warnings) || fitting[0].Index != 0 {
+		t.Errorf("Expected the first warnings that fit, got %d", len(fitting))
+	}
+	if count := header.Get(EVENT_VALIDATION_WARNING_COUNT_HEADER); count != "500" {
+		t.Errorf("Expected the count of every warning, got %q", count)
+	}
+}
+
+func TestGetICalEvents(t *testing.T) {
+	originalWeaviateHost := os.Getenv("WEAVIATE_HOST")
+	originalWeaviateScheme := os.Getenv("WEAVIATE_SCHEME")
+	originalWeaviatePort := os.Getenv("WEAVIATE_PORT")
+
+	defer func() {
+		os.Setenv("WEAVIATE_HOST", originalWeaviateHost)
+		os.Setenv("WEAVIATE_SCHEME", originalWeaviateScheme)
+		os.Setenv("WEAVIATE_PORT", originalWeaviatePort)
+	}()
+
+	startTime := time.Now().Add(48 * time.Hour).Unix()
+	var graphqlQueries []string
+
+	hostAndPort := test_helpers.GetNextPort()
+	mockWeaviateServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/meta":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"version":"1.23.4"}`))
+		case "/v1/graphql":
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("failed to read request body: %v", err)
+			}
+			queryStr := string(body)
+			graphqlQueries = append(graphqlQueries, queryStr)
+
+			var results []interface{}
+			if strings.Contains(queryStr, `eventSourceId\"] valueText`) {
+				// Children lookup for the series parent
+				results = []interface{}{
+					map[string]interface{}{
+						"name":            "Weekly Meetup",
+						"eventSourceType": constants.ES_EVENT_SERIES,
+						"eventSourceId":   "series-parent-1",
+						"timezone":        "America/Chicago",
+						"startTime":       startTime + 7*24*60*60,
+						"_additional":     map[string]interface{}{"id": "series-child-1"},
+					},
+				}
+			} else {
+				results = []interface{}{
+					map[string]interface{}{
+						"name":            "Conference on Go Programming",
+						"eventSourceType": constants.ES_SINGLE_EVENT,
+						"timezone":        "America/Chicago",
+						"startTime":       startTime,
+						"_additional":     map[string]interface{}{"id": "single-event-1"},
+					},
+					map[string]interface{}{
+						"name":            "Weekly Meetup",
+						"eventSourceType": constants.ES_SERIES_PARENT,
+						"timezone":        "America/Chicago",
+						"startTime":       startTime,
+						"_additional":     map[string]interface{}{"id": "series-parent-1"},
+					},
+				}
+			}
+
+			mockResponse := models.GraphQLResponse{
+				Data: map[string]models.JSONObject{
+					"Get": map[string]interface{}{
+						constants.WeaviateEventClassName: results,
+					},
+				},
+			}
+			responseBytes, err := json.Marshal(mockResponse)
+			if err != nil {
+				t.Fatalf("failed to marshal mock GraphQL response: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+		default:
+			t.Errorf("mock server received request to unhandled path: %s", r.URL.Path)
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+
+	listener, err := test_helpers.BindToPort(t, hostAndPort)
+	if err != nil {
+		t.Fatalf("BindToPort failed: %v", err)
+	}
+	mockWeaviateServer.Listener = listener
+	mockWeaviateServer.Start()
+	defer mockWeaviateServer.Close()
+
+	actualParts := strings.Split(listener.Addr().String(), ":")
+	os.Setenv("WEAVIATE_HOST", actualParts[0])
+	os.Setenv("WEAVIATE_PORT", actualParts[1])
+	os.Setenv("WEAVIATE_SCHEME", "http")
+	os.Setenv("WEAVIATE_API_KEY_ALLOWED_KEYS", "test-weaviate-api-key")
+
+	req := httptest.NewRequest("GET", "/api/ical/events?owners=owner-1&event_source_types="+constants.ES_SINGLE_EVENT+","+constants.ES_SINGLE_EVENT_UNPUB+","+constants.ES_SERIES_PARENT, nil)
+	rr := httptest.NewRecorder()
+	handlerFunc := GetICalEventsHandler(rr, req)
+	handlerFunc(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if contentType := rr.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/calendar") {
+		t.Errorf("expected text/calendar content type, got %q", contentType)
+	}
+
+	body := rr.Body.String()
+	if !strings.Contains(body, "UID:single-event-1\r\n") {
+		t.Errorf("expected single event in feed")
+	}
+	if !strings.Contains(body, "UID:series-child-1\r\n") || !strings.Contains(body, "RELATED-TO;RELTYPE=PARENT:series-parent-1\r\n") {
+		t.Errorf("expected series child linked to its parent in feed")
+	}
+	if strings.Contains(body, "UID:series-parent-1\r\n") {
+		t.Errorf("expected series parent to be replaced by its children")
+	}
+	if !strings.Contains(body, "TZID:America/Chicago\r\n") {
+		t.Errorf("expected VTIMEZONE for America/Chicago")
+	}
+
+	if len(graphqlQueries) != 2 {
+		t.Fatalf("expected 2 weaviate queries, got %d", len(graphqlQueries))
+	}
+	if strings.Contains(graphqlQueries[0], constants.ES_SINGLE_EVENT_UNPUB) {
+		t.Errorf("expected unpublished event source types to be dropped from the feed search")
+	}
+}
+
+func TestGetMapEvents(t *testing.T) {
+	originalWeaviateHost := os.Getenv("WEAVIATE_HOST")
+	originalWeaviateScheme := os.Getenv("WEAVIATE_SCHEME")
+	originalWeaviatePort := os.Getenv("WEAVIATE_PORT")
+
+	defer func() {
+		os.Setenv("WEAVIATE_HOST", originalWeaviateHost)
+		os.Setenv("WEAVIATE_SCHEME", originalWeaviateScheme)
+		os.Setenv("WEAVIATE_PORT", originalWeaviatePort)
+	}()
+
+	startTime := time.Now().Add(48 * time.Hour).Unix()
+	var graphqlQueries []string
+
+	hostAndPort := test_helpers.GetNextPort()
+	mockWeaviateServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/meta":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"version":"1.23.4"}`))
+		case "/v1/graphql":
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("failed to read request body: %v", err)
+			}
+			graphqlQueries = append(graphqlQueries, string(body))
+
+			event := func(id string, lat, long float64) map[string]interface{} {
+				return map[string]interface{}{
+					"name":            "Event " + id,
+					"eventSourceType": constants.ES_SINGLE_EVENT,
+					"timezone":        "Pacific/Fiji",
+					"startTime":       startTime,
+					"lat":             lat,
+					"long":            long,
+					"_additional":     map[string]interface{}{"id": id},
+				}
+			}
+			mockResponse := models.GraphQLResponse{
+				Data: map[string]models.JSONObject{
+					"Get": map[string]interface{}{
+						constants.WeaviateEventClassName: []interface{}{
+							event("suva-1", -18.1416, 178.4419),
+							event("suva-2", -18.1420, 178.4425),
+							event("taveuni-1", -16.85, -179.95),
+						},
+					},
+				},
+			}
+			responseBytes, err := json.Marshal(mockResponse)
+			if err != nil {
+				t.Fatalf("failed to marshal mock GraphQL response: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+		default:
+			t.Errorf("mock server received request to unhandled path: %s", r.URL.Path)
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+
+	listener, err := test_helpers.BindToPort(t, hostAndPort)
+	if err != nil {
+		t.Fatalf("BindToPort failed: %v", err)
+	}
+	mockWeaviateServer.Listener = listener
+	mockWeaviateServer.Start()
+	defer mockWeaviateServer.Close()
+
+	actualParts := strings.Split(listener.Addr().String(), ":")
+	os.Setenv("WEAVIATE_HOST", actualParts[0])
+	os.Setenv("WEAVIATE_PORT", actualParts[1])
+	os.Setenv("WEAVIATE_SCHEME", "http")
+	os.Setenv("WEAVIATE_API_KEY_ALLOWED_KEYS", "test-weaviate-api-key")
+
+	getMapEvents := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		rr := httptest.NewRecorder()
+		GetMapEventsHandler(rr, req)(rr, req)
+		return rr
+	}
+
+	t.Run("viewport across the antimeridian", func(t *testing.T) {
+		graphqlQueries = nil
+		rr := getMapEvents("/api/map/events?bbox=175,-20,185,-15&zoom=5")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		if contentType := rr.Header().Get("Content-Type"); contentType != "application/geo+json" {
+			t.Errorf("expected application/geo+json content type, got %q", contentType)
+		}
+
+		var collection types.GeoJSONFeatureCollection
+		if err := json.Unmarshal(rr.Body.Bytes(), &collection); err != nil {
+			t.Fatalf("failed to unmarshal GeoJSON: %v", err)
+		}
+		if collection.Type != types.GeoJSONFeatureCollectionType {
+			t.Errorf("expected a FeatureCollection, got %q", collection.Type)
+		}
+		if !reflect.DeepEqual(collection.BBox, []float64{175, -20, -175, -15}) {
+			t.Errorf("expected the bbox to be wrapped into [-180, 180], got %v", collection.BBox)
+		}
+		if len(collection.Features) != 2 {
+			t.Fatalf("expected the two Suva events to be clustered, got %d features", len(collection.Features))
+		}
+		if collection.Features[0].Properties["cluster"] != true || collection.Features[0].Properties["pointCount"] != float64(2) {
+			t.Errorf("expected a cluster of 2, got %+v", collection.Features[0].Properties)
+		}
+		if collection.Features[1].Id != "taveuni-1" {
+			t.Errorf("expected taveuni-1 as a single point, got %+v", collection.Features[1])
+		}
+
+		if len(graphqlQueries) != 1 {
+			t.Fatalf("expected 1 weaviate query, got %d", len(graphqlQueries))
+		}
+		for _, want := range []string{"operator: Or", "valueNumber: 175", "valueNumber: 180", "valueNumber: -180", "valueNumber: -175"} {
+			if !strings.Contains(graphqlQueries[0], want) {
+				t.Errorf("expected the longitude filter to be split at the antimeridian, missing %q", want)
+			}
+		}
+	})
+
+	t.Run("max zoom returns single points", func(t *testing.T) {
+		rr := getMapEvents(fmt.Sprintf("/api/map/events?bbox=175,-20,185,-15&zoom=%d", services.MapClusterMaxZoom))
+		var collection types.GeoJSONFeatureCollection
+		if err := json.Unmarshal(rr.Body.Bytes(), &collection); err != nil {
+			t.Fatalf("failed to unmarshal GeoJSON: %v", err)
+		}
+		if len(collection.Features) != 3 {
+			t.Errorf("expected 3 points, got %d", len(collection.Features))
+		}
+	})
+
+	for _, path := range []string{
+		"/api/map/events",
+		"/api/map/events?bbox=1,2,3",
+		"/api/map/events?bbox=0,10,1,5",
+		"/api/map/events?bbox=0,0,1,1&zoom=-1",
+	} {
+		if rr := getMapEvents(path); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", path, http.StatusBadRequest, rr.Code)
+		}
+	}
+}
+
+func TestGetOneEventJSONLD(t *testing.T) {
+	originalWeaviateHost := os.Getenv("WEAVIATE_HOST")
+	originalWeaviateScheme := os.Getenv("WEAVIATE_SCHEME")
+	originalWeaviatePort := os.Getenv("WEAVIATE_PORT")
+
+	defer func() {
+		os.Setenv("WEAVIATE_HOST", originalWeaviateHost)
+		os.Setenv("WEAVIATE_SCHEME", originalWeaviateScheme)
+		os.Setenv("WEAVIATE_PORT", originalWeaviatePort)
+	}()
+	t.Setenv("APEX_URL", "https://meetnear.me")
+
+	eventId := "22222222-2222-2222-2222-222222222222"
+	startTime := time.Date(2030, 6, 1, 23, 0, 0, 0, time.UTC).Unix()
+
+	hostAndPort := test_helpers.GetNextPort()
+	mockWeaviateServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/meta":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"version":"1.23.4"}`))
+		case "/v1/graphql":
+			mockResponse := models.GraphQLResponse{
+				Data: map[string]models.JSONObject{
+					"Get": map[string]interface{}{
+						constants.WeaviateEventClassName: []interface{}{
+							map[string]interface{}{
+								"name":            "Trivia Night",
+								"description":     "Bring a team",
+								"eventOwners":     []interface{}{"owner-1"},
+								"eventOwnerName":  "Quiz Club",
+								"eventSourceType": constants.ES_SINGLE_EVENT,
+								"timezone":        "America/New_York",
+								"startTime":       startTime,
+								"address":         "The Tavern, 123 Main St",
+								"lat":             40.7128,
+								"long":            -74.0060,
+								"_additional":     map[string]interface{}{"id": eventId},
+							},
+						},
+					},
+				},
+			}
+			responseBytes, err := json.Marshal(mockResponse)
+			if err != nil {
+				t.Fatalf("failed to marshal mock GraphQL response: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+		default:
+			t.Errorf("mock server received request to unhandled path: %s", r.URL.Path)
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+
+	listener, err := test_helpers.BindToPort(t, hostAndPort)
+	if err != nil {
+		t.Fatalf("BindToPort failed: %v", err)
+	}
+	mockWeaviateServer.Listener = listener
+	mockWeaviateServer.Start()
+	defer mockWeaviateServer.Close()
+
+	actualParts := strings.Split(listener.Addr().String(), ":")
+	os.Setenv("WEAVIATE_HOST", actualParts[0])
+	os.Setenv("WEAVIATE_PORT", actualParts[1])
+	os.Setenv("WEAVIATE_SCHEME", "http")
+	os.Setenv("WEAVIATE_API_KEY_ALLOWED_KEYS", "test-weaviate-api-key")
+
+	req := httptest.NewRequest("GET", "/api/events/"+eventId+"?format=jsonld", nil)
+	req = mux.SetURLVars(req, map[string]string{constants.EVENT_ID_KEY: eventId})
+	rr := httptest.NewRecorder()
+	GetOneEventHandler(rr, req)(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if contentType := rr.Header().Get("Content-Type"); contentType != "application/ld+json" {
+		t.Errorf("expected application/ld+json content type, got %s", contentType)
+	}
+	var res map[string]interface{}
+	if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if res["@context"] != "https://schema.org" || res["@type"] != "Event" || res["name"] != "Trivia Night" {
+		t.Errorf("unexpected JSON-LD: %s", rr.Body.String())
+	}
+	if res["startDate"] != "2030-06-01T19:00:00-04:00" {
+		t.Errorf("expected start date in the event's timezone, got %v", res["startDate"])
+	}
+	if res["url"] != "https://meetnear.me/event/"+eventId {
+		t.Errorf("unexpected url %v", res["url"])
+	}
+	organizer, _ := res["organizer"].(map[string]interface{})
+	if organizer["name"] != "Quiz Club" {
+		t.Errorf("unexpected organizer %v", res["organizer"])
+	}
+	location, _ := res["location"].(map[string]interface{})
+	if location["@type"] != "Place" || location["address"] != "The Tavern, 123 Main St" {
+		t.Errorf("unexpected location %v", res["location"])
+	}
+}
+
+func TestGetSimilarEvents(t *testing.T) {
+	originalWeaviateHost := os.Getenv("WEAVIATE_HOST")
+	originalWeaviateScheme := os.Getenv("WEAVIATE_SCHEME")
+	originalWeaviatePort := os.Getenv("WEAVIATE_PORT")
+
+	defer func() {
+		os.Setenv("WEAVIATE_HOST", originalWeaviateHost)
+		os.Setenv("WEAVIATE_SCHEME", originalWeaviateScheme)
+		os.Setenv("WEAVIATE_PORT", originalWeaviatePort)
+	}()
+
+	sourceId := "11111111-1111-1111-1111-111111111111"
+	startTime := time.Now().Add(48 * time.Hour).Unix()
+	var similarQuery string
+
+	hostAndPort := test_helpers.GetNextPort()
+	mockWeaviateServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/meta":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"version":"1.23.4"}`))
+		case "/v1/graphql":
 			body, err := io.ReadAll(r.Body)
 			if err != nil {
 				t.Fatalf("failed to read request body: %v", err)
 			}
-
-			queryStr := string(body)
-			t.Logf("   └─ GraphQL Query: %s", queryStr)
-
-			var mockResponse models.GraphQLResponse
-
-			// Return different responses based on the search term
-			if strings.Contains(queryStr, "programming") {
-				// Return one matching event for "programming" search
-				mockResponse = models.GraphQLResponse{
-					Data: map[string]models.JSONObject{
-						"Get": map[string]interface{}{
-							constants.WeaviateEventClassName: []interface{}{
-								map[string]interface{}{
-									"name":            "Conference on Go Programming",
-									"description":     "A deep dive into the Go language and its powerful ecosystem.",
-									"eventOwnerName":  "Tech Org",
-									"eventSourceType": constants.ES_SINGLE_EVENT,
-									"address":         "123 Tech Way, Silicon Valley, CA",
-									"lat":             37.3861,
-									"long":            -122.0839,
-									"timezone":        "America/Los_Angeles",
-									"startTime":       time.Now().Add(48 * time.Hour).Unix(),
-									"_additional": map[string]interface{}{
-										"id": "programming-event-123",
-									},
-								},
-							},
-						},
-					},
-				}
-			} else if strings.Contains(queryStr, "nonexistenttermxyz") {
-				// Return empty results for non-existent term
-				mockResponse = models.GraphQLResponse{
-					Data: map[string]models.JSONObject{
-						"Get": map[string]interface{}{
-							constants.WeaviateEventClassName: []interface{}{},
-						},
-					},
+			query := string(body)
+
+			event := func(id, sourceType string) map[string]interface{} {
+				return map[string]interface{}{
+					"name":            "Event " + id,
+					"eventOwners":     []interface{}{"owner-1"},
+					"eventSourceType": sourceType,
+					"timezone":        "America/New_York",
+					"startTime":       startTime,
+					"lat":             40.7128,
+					"long":            -74.0060,
+					"_additional":     map[string]interface{}{"id": id},
 				}
-			} else {
-				// Default case - return empty
-				mockResponse = models.GraphQLResponse{
-					Data: map[string]models.JSONObject{
-						"Get": map[string]interface{}{
-							constants.WeaviateEventClassName: []interface{}{},
-						},
+			}
+			var hits []interface{}
+			switch {
+			case strings.Contains(query, "nearObject"):
+				similarQuery = query
+				hits = []interface{}{event("similar-1", constants.ES_SINGLE_EVENT), event("similar-2", constants.ES_SINGLE_EVENT)}
+			case strings.Contains(query, sourceId):
+				hits = []interface{}{event(sourceId, constants.ES_SERIES_PARENT)}
+			default:
+				hits = []interface{}{}
+			}
+			mockResponse := models.GraphQLResponse{
+				Data: map[string]models.JSONObject{
+					"Get": map[string]interface{}{
+						constants.WeaviateEventClassName: hits,
 					},
-				}
+				},
 			}
-
 			responseBytes, err := json.Marshal(mockResponse)
 			if err != nil {
 				t.Fatalf("failed to marshal mock GraphQL response: %v", err)
@@ -689,15 +1937,12 @@ func TestSearchEvents(t *testing.T) {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusOK)
 			w.Write(responseBytes)
-
 		default:
-			t.Logf("   └─ ⚠️  UNHANDLED PATH: %s", r.URL.Path)
 			t.Errorf("mock server received request to unhandled path: %s", r.URL.Path)
 			http.Error(w, "Not Found", http.StatusNotFound)
 		}
 	}))
 
-	// Use the same binding pattern as working test
 	listener, err := test_helpers.BindToPort(t, hostAndPort)
 	if err != nil {
 		t.Fatalf("BindToPort failed: %v", err)
@@ -706,106 +1951,54 @@ func TestSearchEvents(t *testing.T) {
 	mockWeaviateServer.Start()
 	defer mockWeaviateServer.Close()
 
-	// Set environment variables to the actual bound port
-	actualAddr := listener.Addr().String()
-	actualParts := strings.Split(actualAddr, ":")
-	actualHost, actualPort := actualParts[0], actualParts[1]
-
-	os.Setenv("WEAVIATE_HOST", actualHost)
-	os.Setenv("WEAVIATE_PORT", actualPort)
+	actualParts := strings.Split(listener.Addr().String(), ":")
+	os.Setenv("WEAVIATE_HOST", actualParts[0])
+	os.Setenv("WEAVIATE_PORT", actualParts[1])
 	os.Setenv("WEAVIATE_SCHEME", "http")
 	os.Setenv("WEAVIATE_API_KEY_ALLOWED_KEYS", "test-weaviate-api-key")
 
-	t.Logf("🔧 SEARCH TEST SETUP COMPLETE")
-	t.Logf("   └─ Mock Server bound to: %s", actualAddr)
-	t.Logf("   └─ WEAVIATE_HOST: %s", os.Getenv("WEAVIATE_HOST"))
-	t.Logf("   └─ WEAVIATE_PORT: %s", os.Getenv("WEAVIATE_PORT"))
-
-	// --- Define the Test Table ---
-	tests := []struct {
-		name              string
-		path              string
-		expectedStatus    int
-		expectedBodyCheck func(t *testing.T, body string)
-	}{
-		{
-			name:           "Search with specific term finds correct event",
-			path:           "/events?q=programming",
-			expectedStatus: http.StatusOK,
-			expectedBodyCheck: func(t *testing.T, body string) {
-				var res types.EventSearchResponse
-				if err := json.Unmarshal([]byte(body), &res); err != nil {
-					t.Fatalf("Failed to unmarshal response body: %v", err)
-				}
-				if len(res.Events) != 1 {
-					t.Fatalf("Expected to find 1 event, but got %d", len(res.Events))
-				}
-
-				// Check the returned event details
-				if res.Events[0].Id != "programming-event-123" {
-					t.Errorf("Expected to find event 'programming-event-123', but got '%s'", res.Events[0].Id)
-				}
-				if res.Events[0].Name != "Conference on Go Programming" {
-					t.Errorf("Expected event name 'Conference on Go Programming', but got '%s'", res.Events[0].Name)
-				}
-			},
-		},
-		{
-			name:           "Search for term with no matches returns empty list",
-			path:           "/events?q=nonexistenttermxyz",
-			expectedStatus: http.StatusOK,
-			expectedBodyCheck: func(t *testing.T, body string) {
-				var res types.EventSearchResponse
-				if err := json.Unmarshal([]byte(body), &res); err != nil {
-					t.Fatalf("Failed to unmarshal response body: %v", err)
-				}
-				if len(res.Events) != 0 {
-					t.Errorf("Expected 0 events for a nonexistent term, but got %d", len(res.Events))
-				}
-			},
-		},
-		{
-			name:           "Search without query parameter returns empty results",
-			path:           "/events",
-			expectedStatus: http.StatusOK,
-			expectedBodyCheck: func(t *testing.T, body string) {
-				var res types.EventSearchResponse
-				if err := json.Unmarshal([]byte(body), &res); err != nil {
-					t.Fatalf("Failed to unmarshal response body: %v", err)
-				}
-				// Should return empty results when no query provided
-				if len(res.Events) != 0 {
-					t.Errorf("Expected 0 events when no query provided, but got %d", len(res.Events))
-				}
-			},
-		},
+	getSimilarEvents := func(eventId, rawQuery string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/events/"+eventId+"/similar?"+rawQuery, nil)
+		req = mux.SetURLVars(req, map[string]string{constants.EVENT_ID_KEY: eventId})
+		rr := httptest.NewRecorder()
+		GetSimilarEventsHandler(rr, req)(rr, req)
+		return rr
 	}
 
-	// --- The Test Runner ---
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			t.Logf("🧪 RUNNING SEARCH TEST: %s", tt.name)
-
-			// ACT: Perform the HTTP request
-			req := httptest.NewRequest("GET", tt.path, nil)
-			rr := httptest.NewRecorder()
-
-			// Fix: Get the handler function and then call it
-			handlerFunc := SearchEventsHandler(rr, req)
-			handlerFunc(rr, req)
+	t.Run("returns nearest events outside the series", func(t *testing.T) {
+		rr := getSimilarEvents(sourceId, "limit=3")
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var res types.EventSearchResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &res); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		if len(res.Events) != 2 || res.Events[0].Id != "similar-1" {
+			t.Errorf("unexpected events: %+v", res.Events)
+		}
+		for _, want := range []string{
+			"nearObject:{id: \\\"" + sourceId + "\\\"}",
+			"limit: 3",
+			"hideCrossPromo",
+			"operator: NotEqual path: [\\\"eventSourceId\\\"] valueText: \\\"" + sourceId + "\\\"",
+		} {
+			if !strings.Contains(similarQuery, want) {
+				t.Errorf("expected similar events query to contain %s\n%s", want, similarQuery)
+			}
+		}
+	})
 
-			t.Logf("📊 SEARCH TEST RESULTS:")
-			t.Logf("   └─ Status: %d (expected %d)", rr.Code, tt.expectedStatus)
-			t.Logf("   └─ Body: %s", rr.Body.String())
+	t.Run("unknown event", func(t *testing.T) {
+		if rr := getSimilarEvents("missing", ""); rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
 
-			// ASSERT: Check the results
-			if rr.Code != tt.expectedStatus {
-				t.Errorf("Handler returned wrong status code: got %v want %v", rr.Code, tt.expectedStatus)
-			}
-			if tt.expectedBodyCheck != nil {
-				tt.expectedBodyCheck(t, rr.Body.String())
-			}
-		})
+	for _, rawQuery := range []string{"limit=0", "limit=abc", "radius=-5"} {
+		if rr := getSimilarEvents(sourceId, rawQuery); rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected status %d, got %d", rawQuery, http.StatusBadRequest, rr.Code)
+		}
 	}
 }
 
@@ -1046,6 +2239,175 @@ func TestBulkUpdateEvents(t *testing.T) {
 	}
 }
 
+func TestCancelSeriesOccurrence(t *testing.T) {
+	originalWeaviateHost := os.Getenv("WEAVIATE_HOST")
+	originalWeaviateScheme := os.Getenv("WEAVIATE_SCHEME")
+	originalWeaviatePort := os.Getenv("WEAVIATE_PORT")
+
+	defer func() {
+		os.Setenv("WEAVIATE_HOST", originalWeaviateHost)
+		os.Setenv("WEAVIATE_SCHEME", originalWeaviateScheme)
+		os.Setenv("WEAVIATE_PORT", originalWeaviatePort)
+	}()
+
+	parentId := uuid.New().String()
+	newYork, _ := time.LoadLocation("America/New_York")
+	now := time.Now().In(newYork)
+	dtstart := time.Date(now.Year(), now.Month(), now.Day(), 19, 0, 0, 0, newYork).AddDate(0, 0, 1)
+	cancelled := dtstart.AddDate(0, 0, 7)
+
+	var upserted []*models.Object
+	var deleteBodies []string
+
+	hostAndPort := test_helpers.GetNextPort()
+	mockWeaviateServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/meta":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"version":"1.23.4"}`))
+		case "/v1/graphql":
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatalf("failed to read request body: %v", err)
+			}
+			results := []interface{}{}
+			// The parent lookup by id, the children search finds nothing yet
+			if !strings.Contains(string(body), `eventSourceId\"] valueText`) {
+				results = append(results, map[string]interface{}{
+					"name":            "Weekly Run",
+					"description":     "Meet at the fountain",
+					"eventOwners":     []string{"owner-1"},
+					"eventOwnerName":  "Run Club",
+					"eventSourceType": constants.ES_SERIES_PARENT,
+					"address":         "Central Park",
+					"lat":             40.7128,
+					"long":            -74.0060,
+					"timezone":        "America/New_York",
+					"startTime":       dtstart.Unix(),
+					"endTime":         dtstart.Add(time.Hour).Unix(),
+					"recurrenceRule":  "FREQ=WEEKLY;COUNT=4",
+					"recurrenceStart": dtstart.Unix(),
+					"_additional":     map[string]interface{}{"id": parentId},
+				})
+			}
+			responseBytes, err := json.Marshal(models.GraphQLResponse{
+				Data: map[string]models.JSONObject{
+					"Get": map[string]interface{}{constants.WeaviateEventClassName: results},
+				},
+			})
+			if err != nil {
+				t.Fatalf("failed to marshal mock GraphQL response: %v", err)
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+		case "/v1/batch/objects":
+			if r.Method == http.MethodDelete {
+				body, _ := io.ReadAll(r.Body)
+				deleteBodies = append(deleteBodies, string(body))
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusOK)
+				w.Write([]byte(`{"results":{"matches":1,"successful":1,"failed":0}}`))
+				return
+			}
+			var requestBody struct {
+				Objects []*models.Object `json:"objects"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+				t.Fatalf("failed to decode request body: %v", err)
+			}
+			upserted = append(upserted, requestBody.Objects...)
+			response := make([]*models.ObjectsGetResponse, len(requestBody.Objects))
+			for i, obj := range requestBody.Objects {
+				status := "SUCCESS"
+				response[i] = &models.ObjectsGetResponse{
+					Object: models.Object{ID: obj.ID, Class: obj.Class},
+					Result: &models.ObjectsGetResponseAO2Result{Status: &status},
+				}
+			}
+			responseBytes, _ := json.Marshal(response)
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+		default:
+			t.Errorf("mock server received request to unhandled path: %s", r.URL.Path)
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+
+	listener, err := test_helpers.BindToPort(t, hostAndPort)
+	if err != nil {
+		t.Fatalf("BindToPort failed: %v", err)
+	}
+	mockWeaviateServer.Listener = listener
+	mockWeaviateServer.Start()
+	defer mockWeaviateServer.Close()
+
+	actualParts := strings.Split(listener.Addr().String(), ":")
+	os.Setenv("WEAVIATE_HOST", actualParts[0])
+	os.Setenv("WEAVIATE_PORT", actualParts[1])
+	os.Setenv("WEAVIATE_SCHEME", "http")
+	os.Setenv("WEAVIATE_API_KEY_ALLOWED_KEYS", "test-weaviate-api-key")
+
+	newRequest := func(userId string) *http.Request {
+		req := httptest.NewRequest("DELETE", fmt.Sprintf("/api/events/%s/occurrences/%d", parentId, cancelled.Unix()), nil)
+		req = mux.SetURLVars(req, map[string]string{
+			constants.EVENT_ID_KEY:      parentId,
+			constants.RECURRENCE_ID_KEY: strconv.FormatInt(cancelled.Unix(), 10),
+		})
+		return req.WithContext(context.WithValue(req.Context(), "userInfo", constants.UserInfo{Sub: userId}))
+	}
+
+	t.Run("rejects users who don't own the series", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := newRequest("someone-else")
+		CancelSeriesOccurrenceHandler(rr, req)(rr, req)
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("expected status %d, got %d", http.StatusForbidden, rr.Code)
+		}
+		if len(upserted) != 0 || len(deleteBodies) != 0 {
+			t.Errorf("expected no writes for an unauthorized user")
+		}
+	})
+
+	t.Run("excludes the occurrence and re-materializes the series", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		req := newRequest("owner-1")
+		CancelSeriesOccurrenceHandler(rr, req)(rr, req)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+
+		var parent types.Event
+		if err := json.Unmarshal(rr.Body.Bytes(), &parent); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if len(parent.RecurrenceExDates) != 1 || parent.RecurrenceExDates[0] != cancelled.Unix() {
+			t.Errorf("expected the occurrence in recurrenceExDates, got %v", parent.RecurrenceExDates)
+		}
+
+		if len(deleteBodies) != 1 || !strings.Contains(deleteBodies[0], services.SeriesOccurrenceId(parentId, cancelled.Unix())) {
+			t.Errorf("expected the cancelled child to be deleted, got %v", deleteBodies)
+		}
+		children := 0
+		for _, obj := range upserted {
+			props, _ := obj.Properties.(map[string]interface{})
+			if props["eventSourceType"] != constants.ES_EVENT_SERIES {
+				continue
+			}
+			children++
+			if obj.ID.String() == services.SeriesOccurrenceId(parentId, cancelled.Unix()) {
+				t.Errorf("expected the cancelled occurrence not to be re-created")
+			}
+		}
+		if children != 3 {
+			t.Errorf("expected 3 remaining children to be materialized, got %d", children)
+		}
+	})
+}
+
 func TestUpdateOneEvent(t *testing.T) {
 	originalWeaviateHost := os.Getenv("WEAVIATE_HOST")
 	originalWeaviateScheme := os.Getenv("WEAVIATE_SCHEME")
@@ -1,6 +1,7 @@
 package dynamodb_handlers
 
 import (
+	"context"
 	"encoding/json"
 	"io"
 	"log"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/gorilla/mux"
 	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/services"
 	dynamodb_service "github.com/meetnearme/api/functions/gateway/services/dynamodb_service"
 	"github.com/meetnearme/api/functions/gateway/transport"
 	internal_types "github.com/meetnearme/api/functions/gateway/types"
@@ -51,6 +53,7 @@ func (h *CompetitionRoundHandler) PutCompetitionRounds(w http.ResponseWriter, r
 		transport.SendServerRes(w, []byte("Failed to create eventCompetitionRound: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
+	emitRoundsScoredWebhooks(r.Context(), db, createCompetitionRounds)
 
 	response, err := json.Marshal(res)
 	if err != nil {
@@ -62,6 +65,36 @@ func (h *CompetitionRoundHandler) PutCompetitionRounds(w http.ResponseWriter, r
 	transport.SendServerRes(w, response, http.StatusCreated, nil)
 }
 
+// emitRoundsScoredWebhooks reports each round that has a score to the owners
+// of its competition
+func emitRoundsScoredWebhooks(ctx context.Context, db internal_types.DynamoDBAPI, rounds []internal_types.CompetitionRoundUpdate) {
+	scored := map[string][]internal_types.CompetitionRoundUpdate{}
+	for _, round := range rounds {
+		if round.CompetitorAScore != 0 || round.CompetitorBScore != 0 {
+			scored[round.CompetitionId] = append(scored[round.CompetitionId], round)
+		}
+	}
+
+	configService := dynamodb_service.NewCompetitionConfigService()
+	var messages []services.WebhookMessage
+	for competitionId, competitionRounds := range scored {
+		config, err := configService.GetCompetitionConfigById(ctx, db, competitionId)
+		if err != nil {
+			log.Printf("ERR: failed to get competition %s for round webhooks: %v", competitionId, err)
+			continue
+		}
+		ownerIds := append([]string{config.PrimaryOwner}, config.AuxilaryOwners...)
+		for _, round := range competitionRounds {
+			messages = append(messages, services.WebhookMessage{
+				Type:     services.WEBHOOK_COMPETITION_ROUND_SCORED,
+				OwnerIds: ownerIds,
+				Data:     round,
+			})
+		}
+	}
+	services.EmitWebhookEvents(ctx, messages...)
+}
+
 func (h *CompetitionRoundHandler) GetAllCompetitionRounds(w http.ResponseWriter, r *http.Request) {
 
 	vars := mux.Vars(r)
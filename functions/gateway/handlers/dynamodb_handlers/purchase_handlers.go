@@ -210,12 +210,12 @@ func (h *PurchaseHandler) GetPurchasesByEventID(w http.ResponseWriter, r *http.R
 		roleClaims = ctx.Value("roleClaims").([]constants.RoleClaim)
 	}
 	// Validate event ownership
-	weaviateClient, err := services.GetWeaviateClient()
+	eventStore, err := services.GetEventStore()
 	if err != nil {
-		transport.SendServerRes(w, []byte("Failed to get Marqo client: "+err.Error()), http.StatusInternalServerError, err)
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 		return
 	}
-	event, err := services.GetWeaviateEventByID(ctx, weaviateClient, eventId, "")
+	event, err := eventStore.GetEventByID(ctx, eventId, "")
 	if err != nil {
 		transport.SendServerRes(w, []byte("Failed to get event: "+err.Error()), http.StatusInternalServerError, err)
 		return
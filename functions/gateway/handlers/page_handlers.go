@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -57,6 +58,11 @@ func ParseStartEndTime(startTimeStr, endTimeStr string) (_startTimeUnix, _endTim
 	} else if strings.ToLower(startTimeStr) == "this_year" {
 		startTime = time.Now()
 		endTime = startTime.AddDate(1, 0, 0)
+		// NOTE: "happening_now" is only meaningful as an overlap search, see
+		// `SearchOverlapFromReq`
+	} else if strings.ToLower(startTimeStr) == "happening_now" {
+		startTime = time.Now()
+		endTime = startTime
 	}
 
 	// return early if one of the above are found
@@ -210,6 +216,103 @@ func GetSearchParamsFromReq(r *http.Request) (query, city string, userLocation [
 	return q, city, []float64{lat, long}, radius, startTimeUnix, endTimeUnix, cfLocation, ownerIds, decodedCategories, address, parseDates, eventSourceTypes, eventSourceIds
 }
 
+// GetSearchPageFromReq reads the `limit` and `cursor` paging params and the
+// `facets` / `tz` / `overlap` params that sit alongside the filters parsed by
+// `GetSearchParamsFromReq`. Out of range limits are clamped by the search
+// service rather than rejected, an unknown `tz` falls back to UTC
+func GetSearchPageFromReq(r *http.Request) services.EventSearchPage {
+	page := services.EventSearchPage{
+		Cursor: r.URL.Query().Get("cursor"),
+	}
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		if limit, err := strconv.Atoi(limitStr); err == nil {
+			page.Limit = limit
+		}
+	}
+	if facets, err := strconv.ParseBool(r.URL.Query().Get("facets")); err == nil {
+		page.Facets = facets
+	}
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		if loc, err := time.LoadLocation(tz); err == nil {
+			page.Timezone = loc
+		}
+	}
+	page.Overlap = SearchOverlapFromReq(r)
+	return page
+}
+
+// SearchOverlapFromReq reports whether a search should match events running
+// at any point in its time window rather than only those starting in it,
+// either with `overlap=true` or the `start_time=happening_now` shortcut
+func SearchOverlapFromReq(r *http.Request) bool {
+	if strings.ToLower(r.URL.Query().Get("start_time")) == "happening_now" {
+		return true
+	}
+	overlap, _ := strconv.ParseBool(r.URL.Query().Get("overlap"))
+	return overlap
+}
+
+// forYouFeedRequested reports whether a request asks for the "for you" order,
+// signed in or not
+func forYouFeedRequested(r *http.Request, defaultMode string) bool {
+	query := r.URL.Query()
+	mode := query.Get("feed")
+	if mode == "" {
+		mode = defaultMode
+	}
+	if mode != constants.FEED_MODE_FOR_YOU {
+		return false
+	}
+	return query.Get("q") == "" && query.Get("owners") == "" && mux.Vars(r)[constants.USER_ID_KEY] == "" && helpers.GetMnmOptionsFromContext(r.Context())["userId"] == ""
+}
+
+// EventsPartialWantsSession reports whether GetEventsPartial would rank the
+// request "for you", the only thing the public partial reads a session for
+func EventsPartialWantsSession(r *http.Request) bool {
+	return forYouFeedRequested(r, constants.FEED_MODE_FOR_YOU)
+}
+
+// PersonalizeFeedFromReq re-ranks a page of search results for the signed in
+// user when the `feed` param (or `defaultMode` without one) asks for the
+// "for you" order. Anonymous users, text searches and feeds scoped to an
+// owner keep the order they were searched in. Ranking only re-orders events
+// within the page, the pages themselves still follow the search order so the
+// start time cursor stays valid
+func PersonalizeFeedFromReq(r *http.Request, events []types.Event, searchLocation []float64, defaultMode string) []types.Event {
+	ctx := r.Context()
+	userInfo, _ := ctx.Value("userInfo").(constants.UserInfo)
+	if userInfo.Sub == "" || len(events) == 0 {
+		return events
+	}
+
+	if !forYouFeedRequested(r, defaultMode) {
+		return events
+	}
+
+	signals := services.FeedSignals{UserId: userInfo.Sub}
+	if len(searchLocation) == 2 && searchLocation[0] != constants.INITIAL_EMPTY_LAT_LONG && searchLocation[1] != constants.INITIAL_EMPTY_LAT_LONG {
+		signals.Location = searchLocation
+	}
+	if userMetaClaims, ok := ctx.Value("userMetaClaims").(map[string]interface{}); ok {
+		signals.Interests = helpers.GetUserInterestFromMap(userMetaClaims, constants.INTERESTS_KEY)
+		if _, lat, lon, ok := helpers.GetUserLocationFromMap(userMetaClaims); ok && signals.Location == nil {
+			signals.Location = []float64{lat, lon}
+		}
+	}
+
+	// Ranking still works without the owner signal, so a failed lookup is
+	// only logged
+	eventStore, err := services.GetEventStore()
+	if err == nil {
+		signals.Owners, err = eventStore.GetReSharedOwners(ctx, userInfo.Sub)
+	}
+	if err != nil {
+		log.Printf("Warning: could not load re-shared owners for feed ranking: %v", err)
+	}
+
+	return services.RankEventsForUser(events, signals, time.Now())
+}
+
 func DeriveEventsFromRequest(r *http.Request) ([]types.Event, constants.CdnLocation, string, []float64, *types.UserSearchResult, int, error) {
 	// Extract parameter values from the request query parameters
 	q, city, userLocation, radius, startTimeUnix, endTimeUnix, cfLocation, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds := GetSearchParamsFromReq(r)
@@ -272,9 +375,9 @@ func DeriveEventsFromRequest(r *http.Request) ([]types.Event, constants.CdnLocat
 			}
 
 			// Get search results
-			weaviateClient, err := services.GetWeaviateClient()
+			eventStore, err := services.GetEventStore()
 			if err != nil {
-				searchChan <- searchResult{types.EventSearchResponse{}, errors.New("failed to get weaviate client: " + err.Error())}
+				searchChan <- searchResult{types.EventSearchResponse{}, errors.New("failed to get event store: " + err.Error())}
 				return
 			}
 
@@ -284,7 +387,7 @@ func DeriveEventsFromRequest(r *http.Request) ([]types.Event, constants.CdnLocat
 				ownerIds = []string{userId}
 			}
 
-			res, err := services.SearchWeaviateEvents(ctx, weaviateClient, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds)
+			res, err := eventStore.SearchEventsPage(ctx, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds, services.EventSearchPage{Overlap: SearchOverlapFromReq(r)})
 			searchChan <- searchResult{res, err}
 		}()
 	} else {
@@ -292,9 +395,9 @@ func DeriveEventsFromRequest(r *http.Request) ([]types.Event, constants.CdnLocat
 		close(userChan)
 		close(aboutChan)
 
-		weaviateClient, err := services.GetWeaviateClient()
+		eventStore, err := services.GetEventStore()
 		if err != nil {
-			searchChan <- searchResult{types.EventSearchResponse{}, errors.New("failed to get weaviate client: " + err.Error())}
+			searchChan <- searchResult{types.EventSearchResponse{}, errors.New("failed to get event store: " + err.Error())}
 			return []types.Event{}, cfLocation, city, []float64{}, nil, http.StatusInternalServerError, err
 		}
 
@@ -305,7 +408,7 @@ func DeriveEventsFromRequest(r *http.Request) ([]types.Event, constants.CdnLocat
 			ownerIds = []string{mnmUserId}
 		}
 
-		res, err := services.SearchWeaviateEvents(ctx, weaviateClient, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds)
+		res, err := eventStore.SearchEventsPage(ctx, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds, services.EventSearchPage{Overlap: SearchOverlapFromReq(r)})
 		searchChan <- searchResult{res, err}
 	}
 
@@ -377,12 +480,15 @@ func GetHomeOrUserPage(w http.ResponseWriter, r *http.Request) http.HandlerFunc
 		}
 		return transport.SendHtmlRes(w, []byte(err.Error()), status, "page", err)
 	}
+	events = PersonalizeFeedFromReq(r, events, userLocation, constants.FEED_MODE_FOR_YOU)
 
 	userInfo := constants.UserInfo{}
 	if _, ok := ctx.Value("userInfo").(constants.UserInfo); ok {
 		userInfo = ctx.Value("userInfo").(constants.UserInfo)
 	}
 
+	ctx = context.WithValue(ctx, constants.PAGE_META_CTX_KEY, services.BuildEventListPageMeta(pageUser, events))
+
 	homePage := pages.HomePage(
 		ctx,
 		events,
@@ -491,11 +597,11 @@ func GetAddOrEditEventPage(w http.ResponseWriter, r *http.Request) http.HandlerF
 		pageObj = constants.SitePages["add-event"]
 	} else {
 		pageObj = constants.SitePages["edit-event"]
-		weaviateClient, err := services.GetWeaviateClient()
+		eventStore, err := services.GetEventStore()
 		if err != nil {
-			return transport.SendHtmlRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, "page", err)
+			return transport.SendHtmlRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, "page", err)
 		}
-		eventPtr, err := services.GetWeaviateEventByID(ctx, weaviateClient, eventId, "")
+		eventPtr, err := eventStore.GetEventByID(ctx, eventId, "")
 		if err != nil {
 			return transport.SendHtmlRes(w, []byte("Failed to get event: "+err.Error()), http.StatusInternalServerError, "page", err)
 		}
@@ -564,11 +670,11 @@ func GetEventAttendeesPage(w http.ResponseWriter, r *http.Request) http.HandlerF
 	var event types.Event
 	var isEditor bool = false
 	if eventId != "" {
-		weaviateClient, err := services.GetWeaviateClient()
+		eventStore, err := services.GetEventStore()
 		if err != nil {
-			return transport.SendHtmlRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, "page", err)
+			return transport.SendHtmlRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, "page", err)
 		}
-		eventPtr, err := services.GetWeaviateEventByID(ctx, weaviateClient, eventId, "")
+		eventPtr, err := eventStore.GetEventByID(ctx, eventId, "")
 		if err != nil {
 			return transport.SendHtmlRes(w, []byte("Failed to get event: "+err.Error()), http.StatusInternalServerError, "page", err)
 		}
@@ -640,7 +746,7 @@ func GetDataRequestPage(w http.ResponseWriter, r *http.Request) http.HandlerFunc
 	if _, ok := ctx.Value("userInfo").(constants.UserInfo); ok {
 		userInfo = ctx.Value("userInfo").(constants.UserInfo)
 	}
-	dataRequestPage := pages.DataRequestPage(constants.SitePages["data-request"])
+	dataRequestPage := pages.DataRequestPage(constants.SitePages["data-request"], userInfo.Sub != "")
 	layoutTemplate := pages.Layout(constants.SitePages["data-request"], userInfo, dataRequestPage, types.Event{}, false, ctx, []string{})
 	var buf bytes.Buffer
 	err := layoutTemplate.Render(ctx, &buf)
@@ -705,11 +811,11 @@ func GetEventDetailsPage(w http.ResponseWriter, r *http.Request) http.HandlerFun
 	ctx := r.Context()
 	eventId := mux.Vars(r)[constants.EVENT_ID_KEY]
 	parseDates := r.URL.Query().Get("parse_dates")
-	weaviateClient, err := services.GetWeaviateClient()
+	eventStore, err := services.GetEventStore()
 	if err != nil {
-		return transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err)
+		return transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 	}
-	event, err := services.GetWeaviateEventByID(ctx, weaviateClient, eventId, parseDates)
+	event, err := eventStore.GetEventByID(ctx, eventId, parseDates)
 	if err != nil || event.Id == "" {
 		event = &internal_types.Event{}
 	}
@@ -722,6 +828,9 @@ func GetEventDetailsPage(w http.ResponseWriter, r *http.Request) http.HandlerFun
 		roleClaims = ctx.Value("roleClaims").([]constants.RoleClaim)
 	}
 	canEdit := helpers.CanEditEvent(event, &userInfo, roleClaims)
+	if event.Id != "" {
+		ctx = context.WithValue(ctx, constants.PAGE_META_CTX_KEY, services.BuildEventPageMeta(*event, eventPurchasable(ctx, event)))
+	}
 	eventDetailsPage := pages.EventDetailsPage(*event, userInfo, canEdit)
 	layoutTemplate := pages.Layout(constants.SitePages["event-detail"], userInfo, eventDetailsPage, *event, false, ctx, []string{})
 	var buf bytes.Buffer
@@ -19,6 +19,7 @@ import (
 	"github.com/meetnearme/api/functions/gateway/constants"
 	"github.com/meetnearme/api/functions/gateway/helpers"
 	"github.com/meetnearme/api/functions/gateway/test_helpers"
+	"github.com/meetnearme/api/functions/gateway/types"
 	"github.com/playwright-community/playwright-go"
 	"github.com/weaviate/weaviate/entities/models"
 )
@@ -180,6 +181,14 @@ func TestGetHomeOrUserPage(t *testing.T) {
 		t.Errorf("Second event title is missing from the page")
 	}
 
+	// Listed events are described for search engines in the document head
+	if !strings.Contains(rr.Body.String(), `<script type="application/ld+json">`) || !strings.Contains(rr.Body.String(), `"@type":"ItemList"`) {
+		t.Errorf("Expected schema.org ItemList JSON-LD in the page head")
+	}
+	if !strings.Contains(rr.Body.String(), `<meta property="og:type" content="website">`) {
+		t.Errorf("Expected Open Graph tags in the page head")
+	}
+
 	// Verify that unpublished event types are NOT present
 	// Since our filter uses "field" tokenization and ContainsAny,
 	// it should only match exact values: "SLF" and "SLF_EVS"
@@ -1093,6 +1102,96 @@ func TestGetHomeOrUserPage_EventTypeFiltering(t *testing.T) {
 	}
 }
 
+func TestPersonalizeFeedFromReq(t *testing.T) {
+	originalWeaviateHost := os.Getenv("WEAVIATE_HOST")
+	originalWeaviateScheme := os.Getenv("WEAVIATE_SCHEME")
+	originalWeaviatePort := os.Getenv("WEAVIATE_PORT")
+
+	defer func() {
+		os.Setenv("WEAVIATE_HOST", originalWeaviateHost)
+		os.Setenv("WEAVIATE_SCHEME", originalWeaviateScheme)
+		os.Setenv("WEAVIATE_PORT", originalWeaviatePort)
+	}()
+
+	hostAndPort := test_helpers.GetNextPort()
+	mockWeaviateServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.WriteHeader(http.StatusOK)
+		case "/v1/meta":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"version":"1.23.4"}`))
+		case "/v1/graphql":
+			// the only query is for events the user re-shared
+			mockResponse := models.GraphQLResponse{
+				Data: map[string]models.JSONObject{
+					"Get": map[string]interface{}{
+						constants.WeaviateEventClassName: []interface{}{
+							map[string]interface{}{"eventOwners": []interface{}{"organizer-1"}},
+						},
+					},
+				},
+			}
+			responseBytes, _ := json.Marshal(mockResponse)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusOK)
+			w.Write(responseBytes)
+		default:
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+	listener, err := test_helpers.BindToPort(t, hostAndPort)
+	if err != nil {
+		t.Fatalf("BindToPort failed: %v", err)
+	}
+	mockWeaviateServer.Listener = listener
+	mockWeaviateServer.Start()
+	defer mockWeaviateServer.Close()
+
+	actualParts := strings.Split(listener.Addr().String(), ":")
+	os.Setenv("WEAVIATE_HOST", actualParts[0])
+	os.Setenv("WEAVIATE_PORT", actualParts[1])
+	os.Setenv("WEAVIATE_SCHEME", "http")
+	os.Setenv("WEAVIATE_API_KEY_ALLOWED_KEYS", "test-weaviate-api-key")
+
+	now := time.Now()
+	events := []types.Event{
+		{Id: "chronological-first", StartTime: now.Add(time.Hour).Unix(), EventOwners: []string{"stranger"}},
+		{Id: "followed", StartTime: now.Add(2 * time.Hour).Unix(), EventOwners: []string{"organizer-1"}},
+	}
+	signedIn := func(req *http.Request) *http.Request {
+		ctx := context.WithValue(req.Context(), "userInfo", constants.UserInfo{Sub: "user-1"})
+		return req.WithContext(ctx)
+	}
+
+	tests := []struct {
+		name        string
+		req         *http.Request
+		defaultMode string
+		wantFirst   string
+		wantRanked  bool
+	}{
+		{"anonymous users keep the search order", httptest.NewRequest("GET", "/", nil), constants.FEED_MODE_FOR_YOU, "chronological-first", false},
+		{"signed in users get the for you feed by default", signedIn(httptest.NewRequest("GET", "/", nil)), constants.FEED_MODE_FOR_YOU, "followed", true},
+		{"chronological toggle", signedIn(httptest.NewRequest("GET", "/?feed=chronological", nil)), constants.FEED_MODE_FOR_YOU, "chronological-first", false},
+		{"opt in when the default is chronological", signedIn(httptest.NewRequest("GET", "/?feed=for_you", nil)), constants.FEED_MODE_CHRONOLOGICAL, "followed", true},
+		{"text searches keep relevance order", signedIn(httptest.NewRequest("GET", "/?q=jazz", nil)), constants.FEED_MODE_FOR_YOU, "chronological-first", false},
+		{"owner pages keep their order", signedIn(httptest.NewRequest("GET", "/?owners=organizer-2", nil)), constants.FEED_MODE_FOR_YOU, "chronological-first", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PersonalizeFeedFromReq(tt.req, events, []float64{40.7128, -74.0060}, tt.defaultMode)
+			if got[0].Id != tt.wantFirst {
+				t.Errorf("first event = %s, want %s", got[0].Id, tt.wantFirst)
+			}
+			if ranked := got[0].Rank != nil; ranked != tt.wantRanked {
+				t.Errorf("ranked = %v, want %v", ranked, tt.wantRanked)
+			}
+		})
+	}
+}
+
 func TestGetAdminPage(t *testing.T) {
 	req, err := http.NewRequest("GET", "/profile", nil)
 	if err != nil {
@@ -1710,6 +1809,35 @@ func TestGetSearchParamsFromReq(t *testing.T) {
 	}
 }
 
+func TestSearchOverlapFromReq(t *testing.T) {
+	tests := []struct {
+		name        string
+		queryParams map[string]string
+		want        bool
+	}{
+		{name: "default", queryParams: map[string]string{"start_time": "today"}, want: false},
+		{name: "overlap param", queryParams: map[string]string{"start_time": "today", "overlap": "true"}, want: true},
+		{name: "happening now", queryParams: map[string]string{"start_time": "happening_now"}, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/search?"+encodeParams(tt.queryParams), nil)
+			if got := SearchOverlapFromReq(req); got != tt.want {
+				t.Errorf("SearchOverlapFromReq() = %v, want %v", got, tt.want)
+			}
+			if got := GetSearchPageFromReq(req).Overlap; got != tt.want {
+				t.Errorf("GetSearchPageFromReq().Overlap = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	before := time.Now().Unix()
+	start, end := ParseStartEndTime("happening_now", "")
+	if start < before || end != start {
+		t.Errorf("expected happening_now to be an instant at now, got %d - %d", start, end)
+	}
+}
+
 func encodeParams(params map[string]string) string {
 	values := url.Values{}
 	for k, v := range params {
@@ -161,9 +161,9 @@ func GetEventsPartial(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 
 		q, _, userLocation, radius, startTimeUnix, endTimeUnix, _, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds := GetSearchParamsFromReq(r)
 
-		weaviateClient, err := services.GetWeaviateClient()
+		eventStore, err := services.GetEventStore()
 		if err != nil {
-			transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err).ServeHTTP(w, r)
+			transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err).ServeHTTP(w, r)
 			return
 		}
 
@@ -176,12 +176,23 @@ func GetEventsPartial(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 			ownerIds = []string{mnmUserId}
 		}
 
-		res, err := services.SearchWeaviateEvents(ctx, weaviateClient, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds)
+		res, err := eventStore.SearchEventsPage(ctx, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds, GetSearchPageFromReq(r))
+		if errors.Is(err, services.ErrInvalidSearchCursor) {
+			transport.SendHtmlRes(w, []byte(err.Error()), http.StatusBadRequest, "partial", err).ServeHTTP(w, r)
+			return
+		}
 		if err != nil {
 			transport.SendServerRes(w, []byte("Failed to get events via search: "+err.Error()), http.StatusInternalServerError, err).ServeHTTP(w, r)
 			return
 		}
 
+		// The partial body is markup, so paging metadata travels in headers for
+		// htmx callers that want to request the next page
+		w.Header().Set("X-Total-Count", strconv.Itoa(res.Total))
+		if res.NextCursor != "" {
+			w.Header().Set("X-Next-Cursor", res.NextCursor)
+		}
+
 		events := res.Events
 		listMode := r.URL.Query().Get("list_mode")
 
@@ -191,6 +202,7 @@ func GetEventsPartial(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 				return events[i].StartTime < events[j].StartTime
 			})
 		}
+		events = PersonalizeFeedFromReq(r, events, userLocation, constants.FEED_MODE_FOR_YOU)
 
 		roleClaims := []constants.RoleClaim{}
 		if claims, ok := ctx.Value("roleClaims").([]constants.RoleClaim); ok {
@@ -222,6 +234,25 @@ func GetEventsPartial(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 	}
 }
 
+func GetSimilarEventsPartial(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		events, status, err := searchSimilarEventsFromReq(r)
+		if err != nil {
+			transport.SendHtmlRes(w, []byte(err.Error()), status, "partial", err).ServeHTTP(w, r)
+			return
+		}
+
+		var buf bytes.Buffer
+		err = pages.SimilarEvents(events).Render(r.Context(), &buf)
+		if err != nil {
+			transport.SendHtmlRes(w, []byte(err.Error()), http.StatusInternalServerError, "partial", err).ServeHTTP(w, r)
+			return
+		}
+
+		transport.SendHtmlRes(w, buf.Bytes(), http.StatusOK, "partial", nil).ServeHTTP(w, r)
+	}
+}
+
 func GetEmbedHtml(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 	ctx := r.Context()
 
@@ -250,16 +281,16 @@ func GetEmbedHtml(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 	// Override ownerIds to use the userId from query parameter
 	ownerIds := []string{userId}
 
-	weaviateClient, err := services.GetWeaviateClient()
+	eventStore, err := services.GetEventStore()
 	if err != nil {
 		return func(w http.ResponseWriter, r *http.Request) {
 			transport.SetCORSAllowAll(w, r)
-			transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err).ServeHTTP(w, r)
+			transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err).ServeHTTP(w, r)
 		}
 	}
 
 	// Search for events
-	res, err := services.SearchWeaviateEvents(ctx, weaviateClient, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds)
+	res, err := eventStore.SearchEventsPage(ctx, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds, services.EventSearchPage{Overlap: SearchOverlapFromReq(r)})
 	if err != nil {
 		return func(w http.ResponseWriter, r *http.Request) {
 			transport.SetCORSAllowAll(w, r)
@@ -444,6 +475,57 @@ func GetPurchasesAdminPartial(w http.ResponseWriter, r *http.Request) http.Handl
 	return transport.SendHtmlRes(w, buf.Bytes(), http.StatusOK, "partial", nil)
 }
 
+func GetEventImportAdminPartial(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	ctx := r.Context()
+
+	userInfo, ok := ctx.Value("userInfo").(constants.UserInfo)
+	if !ok || userInfo.Sub == "" {
+		return transport.SendHtmlErrorPartial([]byte("Unauthorized: Missing user ID"), http.StatusUnauthorized)
+	}
+	roleClaims := []constants.RoleClaim{}
+	if claims, ok := ctx.Value("roleClaims").([]constants.RoleClaim); ok {
+		roleClaims = claims
+	}
+	if !helpers.HasRequiredRole(roleClaims, []string{constants.Roles[constants.SuperAdmin], constants.Roles[constants.EventAdmin]}) {
+		return transport.SendHtmlErrorPartial([]byte("Only event editors can import events"), http.StatusForbidden)
+	}
+
+	fieldsJSON, err := json.Marshal(services.CSVImportFields)
+	if err != nil {
+		return transport.SendHtmlRes(w, []byte(err.Error()), http.StatusInternalServerError, "partial", err)
+	}
+
+	var buf bytes.Buffer
+	err = partials.EventImportAdminPartial(string(fieldsJSON)).Render(ctx, &buf)
+	if err != nil {
+		return transport.SendHtmlRes(w, []byte(err.Error()), http.StatusInternalServerError, "partial", err)
+	}
+
+	return transport.SendHtmlRes(w, buf.Bytes(), http.StatusOK, "partial", nil)
+}
+
+func GetWebhooksAdminPartial(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	ctx := r.Context()
+
+	userInfo, ok := ctx.Value("userInfo").(constants.UserInfo)
+	if !ok || userInfo.Sub == "" {
+		return transport.SendHtmlErrorPartial([]byte("Unauthorized: Missing user ID"), http.StatusUnauthorized)
+	}
+
+	eventTypesJSON, err := json.Marshal(services.WebhookEventTypes)
+	if err != nil {
+		return transport.SendHtmlRes(w, []byte(err.Error()), http.StatusInternalServerError, "partial", err)
+	}
+
+	var buf bytes.Buffer
+	err = partials.WebhooksAdminPartial(string(eventTypesJSON)).Render(ctx, &buf)
+	if err != nil {
+		return transport.SendHtmlRes(w, []byte(err.Error()), http.StatusInternalServerError, "partial", err)
+	}
+
+	return transport.SendHtmlRes(w, buf.Bytes(), http.StatusOK, "partial", nil)
+}
+
 func GetEventAdminChildrenPartial(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 	ctx := r.Context()
 
@@ -453,9 +535,9 @@ func GetEventAdminChildrenPartial(w http.ResponseWriter, r *http.Request) http.H
 	farFutureTime, _ := time.Parse(time.RFC3339, "2099-01-01T00:00:00Z")
 	endTimeUnix = farFutureTime.Unix()
 
-	weaviateClient, err := services.GetWeaviateClient()
+	eventStore, err := services.GetEventStore()
 	if err != nil {
-		return transport.SendServerRes(w, []byte("Failed to get weaviate client: "+err.Error()), http.StatusInternalServerError, err)
+		return transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
 	}
 
 	eventId := mux.Vars(r)[constants.EVENT_ID_KEY]
@@ -473,13 +555,13 @@ func GetEventAdminChildrenPartial(w http.ResponseWriter, r *http.Request) http.H
 
 	// Launch parent event fetch in goroutine
 	go func() {
-		parent, err := services.GetWeaviateEventByID(ctx, weaviateClient, eventId, "")
+		parent, err := eventStore.GetEventByID(ctx, eventId, "")
 		parentChan <- eventParentResult{event: parent, err: err}
 	}()
 
 	// Launch search in parallel
 	go func() {
-		res, err := services.SearchWeaviateEvents(ctx, weaviateClient, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds)
+		res, err := eventStore.SearchEvents(ctx, q, userLocation, radius, startTimeUnix, endTimeUnix, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds)
 		if err != nil {
 			searchChan <- eventSearchResult{err: err}
 			return
@@ -1429,14 +1511,15 @@ func SubmitSeshuSession(w http.ResponseWriter, r *http.Request) http.HandlerFunc
 		// }
 
 		go func() {
-			// Use background context since HTTP request context will be canceled after response
-			bgCtx := context.Background()
+			// The HTTP request context is canceled after the response, keep
+			// only its values so the scrape stays in the request's trace
+			bgCtx := context.WithoutCancel(ctx)
 
 			if jobAborted {
 				return
 			}
 
-			extractedEvents, _, err := services.ExtractEventsFromHTML(seshuJob, constants.SESHU_MODE_SCRAPE, scrapeType, &services.RealScrapingService{})
+			extractedEvents, _, err := services.ExtractEventsFromHTML(bgCtx, seshuJob, constants.SESHU_MODE_SCRAPE, scrapeType, &services.RealScrapingService{})
 			if err != nil {
 				log.Printf("Failed to extract events from %s: %v", seshuJob.NormalizedUrlKey, err)
 			}
@@ -1447,7 +1530,7 @@ func SubmitSeshuSession(w http.ResponseWriter, r *http.Request) http.HandlerFunc
 				log.Printf("Extracted %d events from %s", len(extractedEvents), seshuJob.NormalizedUrlKey)
 			}
 
-			err = services.PushExtractedEventsToDB(extractedEvents, seshuJob, make(map[string]string))
+			err = services.PushExtractedEventsToDB(bgCtx, extractedEvents, seshuJob, make(map[string]string))
 			if err != nil {
 				log.Println("Error pushing ingested events to DB:", err)
 				seshuJob.Status = "FAILING"
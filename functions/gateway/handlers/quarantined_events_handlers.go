@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/transport"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+type QuarantinedEventsHandler struct {
+	Store func(ctx context.Context) (interfaces.PostgresServiceInterface, error)
+}
+
+func NewQuarantinedEventsHandler() *QuarantinedEventsHandler {
+	return &QuarantinedEventsHandler{Store: services.GetPostgresService}
+}
+
+// GetQuarantinedEvents lists the logged-in user's quarantined events. A
+// superAdmin sees everyone's, or one owner's with `?ownerId=`
+func (h *QuarantinedEventsHandler) GetQuarantinedEvents(w http.ResponseWriter, r *http.Request) {
+	userId, isSuperAdmin, ok := getOwnerUser(w, r)
+	if !ok {
+		return
+	}
+	ownerId := userId
+	if isSuperAdmin {
+		ownerId = r.URL.Query().Get("ownerId")
+	}
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	events, err := store.GetQuarantinedEvents(r.Context(), ownerId)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get quarantined events: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	if events == nil {
+		events = []types.QuarantinedEvent{}
+	}
+	res, err := json.Marshal(events)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, res, http.StatusOK, nil)
+}
+
+// PublishQuarantinedEvent publishes a quarantined event as it is, its owner
+// having reviewed the issues it was held for
+func (h *QuarantinedEventsHandler) PublishQuarantinedEvent(w http.ResponseWriter, r *http.Request) {
+	store, quarantined, ok := h.getQuarantinedEvent(w, r)
+	if !ok {
+		return
+	}
+	eventStore, err := services.GetEventStore()
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get event store: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	res, err := services.PublishQuarantinedEvent(r.Context(), store, eventStore, *quarantined)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to publish quarantined event: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	json, err := json.Marshal(res)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, json, http.StatusCreated, nil)
+}
+
+// DiscardQuarantinedEvent drops a quarantined event without publishing it
+func (h *QuarantinedEventsHandler) DiscardQuarantinedEvent(w http.ResponseWriter, r *http.Request) {
+	store, quarantined, ok := h.getQuarantinedEvent(w, r)
+	if !ok {
+		return
+	}
+	if err := store.DeleteQuarantinedEvent(r.Context(), quarantined.Id); err != nil {
+		transport.SendServerRes(w, []byte("Failed to discard quarantined event: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	log.Printf("INFO: discarded quarantined event %s from %s", quarantined.Id, quarantined.SeshuJobUrl)
+	transport.SendServerRes(w, []byte("Quarantined event discarded"), http.StatusOK, nil)
+}
+
+// getQuarantinedEvent loads the event named in the path, answering 404 when
+// it's missing or belongs to someone else and the caller isn't a superAdmin
+func (h *QuarantinedEventsHandler) getQuarantinedEvent(w http.ResponseWriter, r *http.Request) (interfaces.PostgresServiceInterface, *types.QuarantinedEvent, bool) {
+	userId, isSuperAdmin, ok := getOwnerUser(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return nil, nil, false
+	}
+	quarantined, err := store.GetQuarantinedEvent(r.Context(), mux.Vars(r)[constants.QUARANTINED_EVENT_ID_KEY])
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get quarantined event: "+err.Error()), http.StatusInternalServerError, err)
+		return nil, nil, false
+	}
+	if quarantined == nil || (quarantined.OwnerId != userId && !isSuperAdmin) {
+		transport.SendServerRes(w, []byte("Quarantined event not found"), http.StatusNotFound, nil)
+		return nil, nil, false
+	}
+	return store, quarantined, true
+}
+
+// getOwnerUser is the logged-in user's ID and whether they're a superAdmin,
+// answering 401 when nobody is logged in
+func getOwnerUser(w http.ResponseWriter, r *http.Request) (string, bool, bool) {
+	userInfo := constants.UserInfo{}
+	if _, ok := r.Context().Value("userInfo").(constants.UserInfo); ok {
+		userInfo = r.Context().Value("userInfo").(constants.UserInfo)
+	}
+	if userInfo.Sub == "" {
+		transport.SendServerRes(w, []byte("Missing user ID"), http.StatusUnauthorized, nil)
+		return "", false, false
+	}
+	roleClaims := []constants.RoleClaim{}
+	if claims, ok := r.Context().Value("roleClaims").([]constants.RoleClaim); ok {
+		roleClaims = claims
+	}
+	return userInfo.Sub, helpers.HasRequiredRole(roleClaims, []string{constants.Roles[constants.SuperAdmin]}), true
+}
+
+func GetQuarantinedEventsHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewQuarantinedEventsHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.GetQuarantinedEvents(w, r)
+	}
+}
+
+func PublishQuarantinedEventHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewQuarantinedEventsHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.PublishQuarantinedEvent(w, r)
+	}
+}
+
+func DiscardQuarantinedEventHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewQuarantinedEventsHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.DiscardQuarantinedEvent(w, r)
+	}
+}
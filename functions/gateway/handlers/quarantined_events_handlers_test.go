@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/test_helpers"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+func TestQuarantinedEventsHandlers(t *testing.T) {
+	quarantined := map[string]types.QuarantinedEvent{
+		"q-1": {Id: "q-1", OwnerId: "user-1", Name: "Poetry Slam"},
+		"q-2": {Id: "q-2", OwnerId: "user-2", Name: "Open Mic"},
+	}
+	store := &test_helpers.MockPostgresService{
+		GetQuarantinedEventsFunc: func(ctx context.Context, ownerId string) ([]types.QuarantinedEvent, error) {
+			events := []types.QuarantinedEvent{}
+			for _, event := range quarantined {
+				if ownerId == "" || event.OwnerId == ownerId {
+					events = append(events, event)
+				}
+			}
+			return events, nil
+		},
+		GetQuarantinedEventFunc: func(ctx context.Context, id string) (*types.QuarantinedEvent, error) {
+			event, ok := quarantined[id]
+			if !ok {
+				return nil, nil
+			}
+			return &event, nil
+		},
+		DeleteQuarantinedEventFunc: func(ctx context.Context, id string) error {
+			delete(quarantined, id)
+			return nil
+		},
+	}
+	handler := &QuarantinedEventsHandler{Store: func(ctx context.Context) (interfaces.PostgresServiceInterface, error) {
+		return store, nil
+	}}
+
+	superAdmin := []constants.RoleClaim{{Role: constants.Roles[constants.SuperAdmin]}}
+	newRequest := func(method, target, id, userId string, roleClaims []constants.RoleClaim) *http.Request {
+		req := httptest.NewRequest(method, target, nil)
+		if id != "" {
+			req = mux.SetURLVars(req, map[string]string{constants.QUARANTINED_EVENT_ID_KEY: id})
+		}
+		ctx := req.Context()
+		if userId != "" {
+			ctx = context.WithValue(ctx, "userInfo", constants.UserInfo{Sub: userId})
+		}
+		if roleClaims != nil {
+			ctx = context.WithValue(ctx, "roleClaims", roleClaims)
+		}
+		return req.WithContext(ctx)
+	}
+	list := func(target, userId string, roleClaims []constants.RoleClaim) []types.QuarantinedEvent {
+		rr := httptest.NewRecorder()
+		handler.GetQuarantinedEvents(rr, newRequest(http.MethodGet, target, "", userId, roleClaims))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var events []types.QuarantinedEvent
+		if err := json.Unmarshal(rr.Body.Bytes(), &events); err != nil {
+			t.Fatalf("failed to decode quarantined events: %v", err)
+		}
+		return events
+	}
+
+	t.Run("requires a user", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.GetQuarantinedEvents(rr, newRequest(http.MethodGet, "/api/quarantined-events", "", "", nil))
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+
+	t.Run("owners only see their own", func(t *testing.T) {
+		if events := list("/api/quarantined-events?ownerId=user-2", "user-1", nil); len(events) != 1 || events[0].Id != "q-1" {
+			t.Errorf("expected only q-1, got %+v", events)
+		}
+	})
+
+	t.Run("super admins see everyone's or one owner's", func(t *testing.T) {
+		if events := list("/api/quarantined-events", "admin-1", superAdmin); len(events) != 2 {
+			t.Errorf("expected both events, got %+v", events)
+		}
+		if events := list("/api/quarantined-events?ownerId=user-2", "admin-1", superAdmin); len(events) != 1 || events[0].Id != "q-2" {
+			t.Errorf("expected only q-2, got %+v", events)
+		}
+	})
+
+	t.Run("discard", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			id         string
+			userId     string
+			roleClaims []constants.RoleClaim
+			wantStatus int
+		}{
+			{name: "someone else's event", id: "q-2", userId: "user-1", wantStatus: http.StatusNotFound},
+			{name: "missing event", id: "q-404", userId: "user-1", wantStatus: http.StatusNotFound},
+			{name: "own event", id: "q-1", userId: "user-1", wantStatus: http.StatusOK},
+			{name: "super admin", id: "q-2", userId: "admin-1", roleClaims: superAdmin, wantStatus: http.StatusOK},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rr := httptest.NewRecorder()
+				handler.DiscardQuarantinedEvent(rr, newRequest(http.MethodDelete, "/api/quarantined-events/"+tt.id, tt.id, tt.userId, tt.roleClaims))
+				if rr.Code != tt.wantStatus {
+					t.Errorf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+				}
+			})
+		}
+		if len(quarantined) != 0 {
+			t.Errorf("expected both events discarded, got %+v", quarantined)
+		}
+	})
+}
@@ -247,8 +247,14 @@
         throw new Error('HTMX failed to load');
       }
       // Widget HTML Fetching
-      const embedUrl =
+      let embedUrl =
         baseUrl + '/api/html/embed?userId=' + encodeURIComponent(userId);
+      // Optional time window, e.g. data-start-time="happening_now"
+      const startTime =
+        currentScript && currentScript.getAttribute('data-start-time');
+      if (startTime) {
+        embedUrl += '&start_time=' + encodeURIComponent(startTime);
+      }
       fetch(embedUrl, {
         method: 'GET',
         headers: {
@@ -244,7 +244,7 @@ func DeleteSeshuJob(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 	return transport.SendHtmlRes(w, []byte(""), http.StatusOK, "partial", nil)
 }
 
-func ProcessGatherSeshuJobs(ctx context.Context, nowUnix, lastFileUnix int64) (int, bool, int, error) {
+func ProcessGatherSeshuJobs(ctx context.Context, nowUnix, lastFileUnix int64) (published int, skipped bool, status int, err error) {
 
 	log.Printf("Last execution time UTC: %s", time.Unix(lastFileUnix, 0).UTC().Format(time.RFC3339))
 
@@ -264,6 +264,13 @@ func ProcessGatherSeshuJobs(ctx context.Context, nowUnix, lastFileUnix int64) (i
 		return 0, true, http.StatusOK, nil
 	}
 
+	// jobs published here carry this span to their consumer over NATS
+	ctx, span := services.StartSpan(ctx, "seshu.gather_jobs")
+	defer func() {
+		span.SetAttributes(services.TRACE_ATTR_JOBS_PUBLISHED.Int(published))
+		services.EndSpan(span, err)
+	}()
+
 	db, err := services.GetPostgresService(ctx)
 	if err != nil {
 		return 0, false, http.StatusInternalServerError, fmt.Errorf("failed to initialize Postgres service: %w", err)
@@ -320,7 +327,6 @@ func ProcessGatherSeshuJobs(ctx context.Context, nowUnix, lastFileUnix int64) (i
 		}
 	}
 
-	published := 0
 	for _, job := range jobs {
 		if err := nats.PublishMsg(ctx, job); err != nil {
 			jobKey := "unknown"
@@ -67,6 +67,110 @@ func (m *MockPostgresService) ScanSeshuJobsWithInHour(ctx context.Context, hour
 	return nil, nil
 }
 
+func (m *MockPostgresService) GetAccountDeletion(ctx context.Context, userId string) (*internal_types.AccountDeletion, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) SaveAccountDeletion(ctx context.Context, deletion internal_types.AccountDeletion) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetDueAccountDeletions(ctx context.Context, now int64, statuses []string) ([]internal_types.AccountDeletion, error) {
+	return []internal_types.AccountDeletion{}, nil
+}
+
+func (m *MockPostgresService) SaveQuarantinedEvents(ctx context.Context, events []internal_types.QuarantinedEvent) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetQuarantinedEvents(ctx context.Context, ownerId string) ([]internal_types.QuarantinedEvent, error) {
+	return []internal_types.QuarantinedEvent{}, nil
+}
+
+func (m *MockPostgresService) GetQuarantinedEvent(ctx context.Context, id string) (*internal_types.QuarantinedEvent, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) DeleteQuarantinedEvent(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *MockPostgresService) SaveWebhookSubscription(ctx context.Context, subscription internal_types.WebhookSubscription) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetWebhookSubscriptions(ctx context.Context, ownerId string) ([]internal_types.WebhookSubscription, error) {
+	return []internal_types.WebhookSubscription{}, nil
+}
+
+func (m *MockPostgresService) GetWebhookSubscription(ctx context.Context, id string) (*internal_types.WebhookSubscription, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *MockPostgresService) SaveWebhookDeliveries(ctx context.Context, deliveries []internal_types.WebhookDelivery) error {
+	return nil
+}
+
+func (m *MockPostgresService) UpdateWebhookDelivery(ctx context.Context, delivery internal_types.WebhookDelivery) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetWebhookDelivery(ctx context.Context, id string) (*internal_types.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetWebhookDeliveries(ctx context.Context, subscriptionId string, limit int) ([]internal_types.WebhookDelivery, error) {
+	return []internal_types.WebhookDelivery{}, nil
+}
+
+func (m *MockPostgresService) SaveAPIKey(ctx context.Context, key internal_types.APIKey) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetAPIKeys(ctx context.Context, ownerId string) ([]internal_types.APIKey, error) {
+	return []internal_types.APIKey{}, nil
+}
+
+func (m *MockPostgresService) GetAPIKey(ctx context.Context, id string) (*internal_types.APIKey, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetAPIKeyByHash(ctx context.Context, keyHash string) (*internal_types.APIKey, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) TouchAPIKey(ctx context.Context, id string, usedAt int64) error {
+	return nil
+}
+
+func (m *MockPostgresService) DeleteAPIKeys(ctx context.Context, ownerId string) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockPostgresService) SaveDataExport(ctx context.Context, export internal_types.DataExport) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetDataExport(ctx context.Context, id string) (*internal_types.DataExport, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetPendingDataExport(ctx context.Context, userId string) (*internal_types.DataExport, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetDueDataExports(ctx context.Context, startedBefore int64) ([]internal_types.DataExport, error) {
+	return []internal_types.DataExport{}, nil
+}
+
+func (m *MockPostgresService) DeleteExpiredDataExports(ctx context.Context, now int64) (int64, error) {
+	return 0, nil
+}
+
 func (m *MockPostgresService) Close() error {
 	return nil
 }
@@ -75,10 +179,11 @@ func (m *MockPostgresService) Close() error {
 var _ interfaces.PostgresServiceInterface = (*MockPostgresService)(nil)
 
 type MockNatsService struct {
-	PeekTopFunc func(ctx context.Context) (*jetstream.RawStreamMsg, error)
-	PublishFunc func(ctx context.Context, job interface{}) error
-	ConsumeFunc func(ctx context.Context, workers int) error
-	CloseFunc   func() error
+	PeekTopFunc                func(ctx context.Context) (*jetstream.RawStreamMsg, error)
+	PublishFunc                func(ctx context.Context, job interface{}) error
+	ConsumeFunc                func(ctx context.Context, workers int) error
+	PublishWebhookDeliveryFunc func(ctx context.Context, deliveryId string) error
+	CloseFunc                  func() error
 }
 
 func (m *MockNatsService) PeekTopOfQueue(ctx context.Context) (*jetstream.RawStreamMsg, error) {
@@ -101,6 +206,13 @@ func (m *MockNatsService) ConsumeMsg(ctx context.Context, workers int) error {
 	}
 	return m.ConsumeFunc(ctx, workers)
 }
+func (m *MockNatsService) PublishWebhookDelivery(ctx context.Context, deliveryId string) error {
+	if m.PublishWebhookDeliveryFunc != nil {
+		return m.PublishWebhookDeliveryFunc(ctx, deliveryId)
+	}
+	return nil
+}
+
 func (m *MockNatsService) Close() error {
 	if m.CloseFunc == nil {
 		return nil
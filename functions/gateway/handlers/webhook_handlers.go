@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/transport"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+type WebhooksHandler struct {
+	Store func(ctx context.Context) (interfaces.PostgresServiceInterface, error)
+	Queue func(ctx context.Context) (interfaces.NatsServiceInterface, error)
+}
+
+func NewWebhooksHandler() *WebhooksHandler {
+	return &WebhooksHandler{Store: services.GetPostgresService, Queue: services.GetNatsService}
+}
+
+// GetWebhooks lists the logged-in user's subscriptions, without their secrets
+func (h *WebhooksHandler) GetWebhooks(w http.ResponseWriter, r *http.Request) {
+	userId, _, ok := getOwnerUser(w, r)
+	if !ok {
+		return
+	}
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	subscriptions, err := store.GetWebhookSubscriptions(r.Context(), userId)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get webhooks: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	if subscriptions == nil {
+		subscriptions = []types.WebhookSubscription{}
+	}
+	for i := range subscriptions {
+		subscriptions[i].Secret = ""
+	}
+	res, err := json.Marshal(subscriptions)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, res, http.StatusOK, nil)
+}
+
+// CreateWebhook registers an endpoint. The response is the only time its
+// signing secret is shown
+func (h *WebhooksHandler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	userId, _, ok := getOwnerUser(w, r)
+	if !ok {
+		return
+	}
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to read request body: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+	var insert types.WebhookSubscriptionInsert
+	if err := json.Unmarshal(body, &insert); err != nil {
+		transport.SendServerRes(w, []byte("Invalid JSON payload: "+err.Error()), http.StatusUnprocessableEntity, err)
+		return
+	}
+	if err := validate.Struct(&insert); err != nil {
+		transport.SendServerRes(w, []byte("Invalid body: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+	subscription, err := services.NewWebhookSubscription(userId, insert, time.Now())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Invalid body: "+err.Error()), http.StatusBadRequest, err)
+		return
+	}
+
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	existing, err := store.GetWebhookSubscriptions(r.Context(), userId)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get webhooks: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	if len(existing) >= services.MAX_WEBHOOK_SUBSCRIPTIONS {
+		msg := fmt.Sprintf("You already have %d webhooks, delete one to add another", len(existing))
+		transport.SendServerRes(w, []byte(msg), http.StatusConflict, nil)
+		return
+	}
+	if err := store.SaveWebhookSubscription(r.Context(), subscription); err != nil {
+		transport.SendServerRes(w, []byte("Failed to save webhook: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+
+	res, err := json.Marshal(subscription)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, res, http.StatusCreated, nil)
+}
+
+// DeleteWebhook stops deliveries to an endpoint and drops its delivery log
+func (h *WebhooksHandler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	store, subscription, ok := h.getWebhook(w, r)
+	if !ok {
+		return
+	}
+	if err := store.DeleteWebhookSubscription(r.Context(), subscription.Id); err != nil {
+		transport.SendServerRes(w, []byte("Failed to delete webhook: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	log.Printf("INFO: deleted webhook %s to %s", subscription.Id, subscription.Url)
+	transport.SendServerRes(w, []byte("Webhook deleted"), http.StatusOK, nil)
+}
+
+// GetWebhookDeliveries is a subscription's delivery log, latest first
+func (h *WebhooksHandler) GetWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	store, subscription, ok := h.getWebhook(w, r)
+	if !ok {
+		return
+	}
+	deliveries, err := store.GetWebhookDeliveries(r.Context(), subscription.Id, services.WEBHOOK_DELIVERY_LOG_LIMIT)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get webhook deliveries: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	if deliveries == nil {
+		deliveries = []types.WebhookDelivery{}
+	}
+	res, err := json.Marshal(deliveries)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, res, http.StatusOK, nil)
+}
+
+// RedeliverWebhook sends a logged delivery again with a fresh set of retries
+func (h *WebhooksHandler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	store, subscription, ok := h.getWebhook(w, r)
+	if !ok {
+		return
+	}
+	delivery, err := store.GetWebhookDelivery(r.Context(), mux.Vars(r)[constants.WEBHOOK_DELIVERY_ID_KEY])
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get webhook delivery: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	if delivery == nil || delivery.SubscriptionId != subscription.Id {
+		transport.SendServerRes(w, []byte("Webhook delivery not found"), http.StatusNotFound, nil)
+		return
+	}
+	queue, err := h.Queue(r.Context())
+	if err != nil || queue == nil {
+		transport.SendServerRes(w, []byte("Webhook deliveries are unavailable"), http.StatusServiceUnavailable, err)
+		return
+	}
+	redelivered, err := services.RedeliverWebhook(r.Context(), store, queue, *delivery, time.Now())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to redeliver webhook: "+err.Error()), http.StatusInternalServerError, err)
+		return
+	}
+	res, err := json.Marshal(redelivered)
+	if err != nil {
+		transport.SendServerRes(w, []byte("Error marshaling JSON"), http.StatusInternalServerError, err)
+		return
+	}
+	transport.SendServerRes(w, res, http.StatusAccepted, nil)
+}
+
+// getWebhook loads the subscription named in the path, answering 404 when
+// it's missing or belongs to someone else and the caller isn't a superAdmin
+func (h *WebhooksHandler) getWebhook(w http.ResponseWriter, r *http.Request) (interfaces.PostgresServiceInterface, *types.WebhookSubscription, bool) {
+	userId, isSuperAdmin, ok := getOwnerUser(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+	store, err := h.Store(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return nil, nil, false
+	}
+	subscription, err := store.GetWebhookSubscription(r.Context(), mux.Vars(r)[constants.WEBHOOK_ID_KEY])
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get webhook: "+err.Error()), http.StatusInternalServerError, err)
+		return nil, nil, false
+	}
+	if subscription == nil || (subscription.OwnerId != userId && !isSuperAdmin) {
+		transport.SendServerRes(w, []byte("Webhook not found"), http.StatusNotFound, nil)
+		return nil, nil, false
+	}
+	subscription.Secret = ""
+	return store, subscription, true
+}
+
+func GetWebhooksHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewWebhooksHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.GetWebhooks(w, r)
+	}
+}
+
+func CreateWebhookHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewWebhooksHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.CreateWebhook(w, r)
+	}
+}
+
+func DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewWebhooksHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.DeleteWebhook(w, r)
+	}
+}
+
+func GetWebhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewWebhooksHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.GetWebhookDeliveries(w, r)
+	}
+}
+
+func RedeliverWebhookHandler(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	handler := NewWebhooksHandler()
+	return func(w http.ResponseWriter, r *http.Request) {
+		handler.RedeliverWebhook(w, r)
+	}
+}
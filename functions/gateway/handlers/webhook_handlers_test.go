@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/test_helpers"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+type fakeWebhookQueue struct {
+	interfaces.NatsServiceInterface
+	published []string
+}
+
+func (f *fakeWebhookQueue) PublishWebhookDelivery(ctx context.Context, deliveryId string) error {
+	f.published = append(f.published, deliveryId)
+	return nil
+}
+
+func TestWebhooksHandlers(t *testing.T) {
+	subscriptions := map[string]types.WebhookSubscription{
+		"wh-1": {Id: "wh-1", OwnerId: "user-1", Url: "https://one.example.com/hook", Secret: "whsec_one", EventTypes: types.WebhookEventTypes{services.WEBHOOK_EVENT_CREATED}},
+		"wh-2": {Id: "wh-2", OwnerId: "user-2", Url: "https://two.example.com/hook", Secret: "whsec_two", EventTypes: types.WebhookEventTypes{services.WEBHOOK_EVENT_CREATED}},
+	}
+	deliveries := map[string]types.WebhookDelivery{
+		"d-1": {Id: "d-1", SubscriptionId: "wh-1", OwnerId: "user-1", Status: services.WEBHOOK_DELIVERY_STATUS_FAILED, Attempts: 6},
+		"d-2": {Id: "d-2", SubscriptionId: "wh-2", OwnerId: "user-2", Status: services.WEBHOOK_DELIVERY_STATUS_FAILED, Attempts: 6},
+	}
+	store := &test_helpers.MockPostgresService{
+		GetWebhookSubscriptionsFunc: func(ctx context.Context, ownerId string) ([]types.WebhookSubscription, error) {
+			found := []types.WebhookSubscription{}
+			for _, subscription := range subscriptions {
+				if subscription.OwnerId == ownerId {
+					found = append(found, subscription)
+				}
+			}
+			return found, nil
+		},
+		GetWebhookSubscriptionFunc: func(ctx context.Context, id string) (*types.WebhookSubscription, error) {
+			subscription, ok := subscriptions[id]
+			if !ok {
+				return nil, nil
+			}
+			return &subscription, nil
+		},
+		SaveWebhookSubscriptionFunc: func(ctx context.Context, subscription types.WebhookSubscription) error {
+			subscriptions[subscription.Id] = subscription
+			return nil
+		},
+		DeleteWebhookSubscriptionFunc: func(ctx context.Context, id string) error {
+			delete(subscriptions, id)
+			return nil
+		},
+		GetWebhookDeliveryFunc: func(ctx context.Context, id string) (*types.WebhookDelivery, error) {
+			delivery, ok := deliveries[id]
+			if !ok {
+				return nil, nil
+			}
+			return &delivery, nil
+		},
+		UpdateWebhookDeliveryFunc: func(ctx context.Context, delivery types.WebhookDelivery) error {
+			deliveries[delivery.Id] = delivery
+			return nil
+		},
+	}
+	queue := &fakeWebhookQueue{}
+	handler := &WebhooksHandler{
+		Store: func(ctx context.Context) (interfaces.PostgresServiceInterface, error) {
+			return store, nil
+		},
+		Queue: func(ctx context.Context) (interfaces.NatsServiceInterface, error) {
+			return queue, nil
+		},
+	}
+
+	newRequest := func(method, body, userId string, vars map[string]string) *http.Request {
+		req := httptest.NewRequest(method, "/api/webhooks", bytes.NewBufferString(body))
+		if vars != nil {
+			req = mux.SetURLVars(req, vars)
+		}
+		ctx := req.Context()
+		if userId != "" {
+			ctx = context.WithValue(ctx, "userInfo", constants.UserInfo{Sub: userId})
+		}
+		return req.WithContext(ctx)
+	}
+
+	t.Run("requires a user", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.GetWebhooks(rr, newRequest(http.MethodGet, "", "", nil))
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("expected status %d, got %d", http.StatusUnauthorized, rr.Code)
+		}
+	})
+
+	t.Run("lists own webhooks without secrets", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.GetWebhooks(rr, newRequest(http.MethodGet, "", "user-1", nil))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var listed []types.WebhookSubscription
+		if err := json.Unmarshal(rr.Body.Bytes(), &listed); err != nil {
+			t.Fatalf("failed to decode webhooks: %v", err)
+		}
+		if len(listed) != 1 || listed[0].Id != "wh-1" || listed[0].Secret != "" {
+			t.Errorf("expected wh-1 without its secret, got %+v", listed)
+		}
+		if strings.Contains(rr.Body.String(), "whsec_") {
+			t.Errorf("expected no secrets in the response, got %s", rr.Body.String())
+		}
+	})
+
+	t.Run("create validates the body", func(t *testing.T) {
+		for _, body := range []string{
+			`{"url":"https://example.com/hook","eventTypes":[]}`,
+			`{"url":"http://example.com/hook","eventTypes":["event.created"]}`,
+			`{"url":"https://example.com/hook","eventTypes":["event.exploded"]}`,
+		} {
+			rr := httptest.NewRecorder()
+			handler.CreateWebhook(rr, newRequest(http.MethodPost, body, "user-1", nil))
+			if rr.Code != http.StatusBadRequest {
+				t.Errorf("%s: expected status %d, got %d: %s", body, http.StatusBadRequest, rr.Code, rr.Body.String())
+			}
+		}
+	})
+
+	t.Run("create returns the secret once", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.CreateWebhook(rr, newRequest(http.MethodPost, `{"url":"https://three.example.com/hook","eventTypes":["purchase.settled"]}`, "user-3", nil))
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+		var created types.WebhookSubscription
+		if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+			t.Fatalf("failed to decode webhook: %v", err)
+		}
+		if created.OwnerId != "user-3" || !strings.HasPrefix(created.Secret, "whsec_") {
+			t.Errorf("unexpected webhook %+v", created)
+		}
+		if subscriptions[created.Id].Secret != created.Secret {
+			t.Errorf("expected the secret to be saved")
+		}
+	})
+
+	t.Run("create is limited per owner", func(t *testing.T) {
+		for i := 0; i < services.MAX_WEBHOOK_SUBSCRIPTIONS; i++ {
+			id := fmt.Sprintf("wh-full-%d", i)
+			subscriptions[id] = types.WebhookSubscription{Id: id, OwnerId: "user-full"}
+		}
+		rr := httptest.NewRecorder()
+		handler.CreateWebhook(rr, newRequest(http.MethodPost, `{"url":"https://example.com/hook","eventTypes":["event.created"]}`, "user-full", nil))
+		if rr.Code != http.StatusConflict {
+			t.Errorf("expected status %d, got %d: %s", http.StatusConflict, rr.Code, rr.Body.String())
+		}
+	})
+
+	t.Run("someone else's webhook is not found", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.DeleteWebhook(rr, newRequest(http.MethodDelete, "", "user-1", map[string]string{constants.WEBHOOK_ID_KEY: "wh-2"}))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+		}
+		if _, ok := subscriptions["wh-2"]; !ok {
+			t.Error("expected wh-2 to be kept")
+		}
+
+		rr = httptest.NewRecorder()
+		handler.RedeliverWebhook(rr, newRequest(http.MethodPost, "", "user-1", map[string]string{constants.WEBHOOK_ID_KEY: "wh-1", constants.WEBHOOK_DELIVERY_ID_KEY: "d-2"}))
+		if rr.Code != http.StatusNotFound {
+			t.Errorf("expected another webhook's delivery to be %d, got %d", http.StatusNotFound, rr.Code)
+		}
+	})
+
+	t.Run("redeliver queues the delivery again", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.RedeliverWebhook(rr, newRequest(http.MethodPost, "", "user-1", map[string]string{constants.WEBHOOK_ID_KEY: "wh-1", constants.WEBHOOK_DELIVERY_ID_KEY: "d-1"}))
+		if rr.Code != http.StatusAccepted {
+			t.Fatalf("expected status %d, got %d: %s", http.StatusAccepted, rr.Code, rr.Body.String())
+		}
+		if delivery := deliveries["d-1"]; delivery.Status != services.WEBHOOK_DELIVERY_STATUS_PENDING || delivery.Attempts != 0 {
+			t.Errorf("expected d-1 to start over, got %+v", delivery)
+		}
+		if len(queue.published) != 1 || queue.published[0] != "d-1" {
+			t.Errorf("expected d-1 to be queued, got %v", queue.published)
+		}
+	})
+
+	t.Run("delete own webhook", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		handler.DeleteWebhook(rr, newRequest(http.MethodDelete, "", "user-1", map[string]string{constants.WEBHOOK_ID_KEY: "wh-1"}))
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		if _, ok := subscriptions["wh-1"]; ok {
+			t.Error("expected wh-1 to be deleted")
+		}
+	})
+}
@@ -1033,6 +1033,36 @@ func UpdateUserMetadataKey(userID, key, value string) error {
 	return nil
 }
 
+// DeleteZitadelUser removes the user from Zitadel. A user that's already gone
+// counts as deleted so an interrupted account deletion can be retried
+func DeleteZitadelUser(userID string) error {
+	if userID == "" {
+		return fmt.Errorf("user ID cannot be empty")
+	}
+	url := fmt.Sprintf(DefaultProtocol+"%s/management/v1/users/%s", os.Getenv("ZITADEL_INSTANCE_HOST"), userID)
+
+	req, err := http.NewRequest("DELETE", url, nil)
+	if err != nil {
+		return fmt.Errorf("error creating delete user request: %w", err)
+	}
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Authorization", "Bearer "+os.Getenv("ZITADEL_BOT_ADMIN_TOKEN"))
+
+	client := &http.Client{}
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error executing delete user request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusNoContent && res.StatusCode != http.StatusNotFound {
+		body, _ := io.ReadAll(res.Body)
+		return fmt.Errorf("failed to delete user: status %d, body: %s", res.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return nil
+}
+
 // Define a struct for the request payload
 type createUserPayload struct {
 	UserID string `json:"userId"`
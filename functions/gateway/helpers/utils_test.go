@@ -772,6 +772,63 @@ func TestUpdateUserMetadataKey(t *testing.T) {
 	}
 }
 
+func TestDeleteZitadelUser(t *testing.T) {
+	InitDefaultProtocol()
+	originalZitadelInstanceUrl := os.Getenv("ZITADEL_INSTANCE_HOST")
+	testZitadelEndpoint := test_helpers.GetNextPort()
+	os.Setenv("ZITADEL_INSTANCE_HOST", testZitadelEndpoint)
+	defer func() {
+		os.Setenv("ZITADEL_INSTANCE_HOST", originalZitadelInstanceUrl)
+	}()
+
+	mockZitadelServer := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "DELETE" || !strings.HasPrefix(r.URL.Path, "/management/v1/users/") {
+			http.Error(w, fmt.Sprintf("unexpected request: %s %s", r.Method, r.URL), http.StatusBadRequest)
+			return
+		}
+		switch strings.TrimPrefix(r.URL.Path, "/management/v1/users/") {
+		case "gone_user":
+			http.Error(w, `{"code": 5, "message": "User could not be found"}`, http.StatusNotFound)
+		case "error_user":
+			http.Error(w, `{"code": 7, "message": "No matching permissions found"}`, http.StatusForbidden)
+		default:
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`{"details": {}}`))
+		}
+	}))
+	mockZitadelServer.Listener.Close()
+	listener, err := test_helpers.BindToPort(t, testZitadelEndpoint)
+	if err != nil {
+		t.Fatalf("Failed to start mock Zitadel server after retries: %v", err)
+	}
+	mockZitadelServer.Listener = listener
+	mockZitadelServer.Start()
+	defer mockZitadelServer.Close()
+
+	tests := []struct {
+		name        string
+		userID      string
+		expectError bool
+	}{
+		{name: "successful delete", userID: "123"},
+		{name: "already deleted", userID: "gone_user"},
+		{name: "permission denied", userID: "error_user", expectError: true},
+		{name: "empty user ID", userID: "", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DeleteZitadelUser(tt.userID)
+			if tt.expectError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
 func TestUtcToUnix64(t *testing.T) {
 	// Load test timezone
 	chicagoTZ, err := time.LoadLocation("America/Chicago")
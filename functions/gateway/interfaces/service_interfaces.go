@@ -29,6 +29,32 @@ type PostgresServiceInterface interface {
 	UpdateSeshuJob(ctx context.Context, job types.SeshuJob) error
 	DeleteSeshuJob(ctx context.Context, id string) error
 	ScanSeshuJobsWithInHour(ctx context.Context, hours int) ([]types.SeshuJob, error)
+	GetAccountDeletion(ctx context.Context, userId string) (*types.AccountDeletion, error)
+	SaveAccountDeletion(ctx context.Context, deletion types.AccountDeletion) error
+	GetDueAccountDeletions(ctx context.Context, now int64, statuses []string) ([]types.AccountDeletion, error)
+	SaveQuarantinedEvents(ctx context.Context, events []types.QuarantinedEvent) error
+	GetQuarantinedEvents(ctx context.Context, ownerId string) ([]types.QuarantinedEvent, error)
+	GetQuarantinedEvent(ctx context.Context, id string) (*types.QuarantinedEvent, error)
+	DeleteQuarantinedEvent(ctx context.Context, id string) error
+	SaveWebhookSubscription(ctx context.Context, subscription types.WebhookSubscription) error
+	GetWebhookSubscriptions(ctx context.Context, ownerId string) ([]types.WebhookSubscription, error)
+	GetWebhookSubscription(ctx context.Context, id string) (*types.WebhookSubscription, error)
+	DeleteWebhookSubscription(ctx context.Context, id string) error
+	SaveWebhookDeliveries(ctx context.Context, deliveries []types.WebhookDelivery) error
+	UpdateWebhookDelivery(ctx context.Context, delivery types.WebhookDelivery) error
+	GetWebhookDelivery(ctx context.Context, id string) (*types.WebhookDelivery, error)
+	GetWebhookDeliveries(ctx context.Context, subscriptionId string, limit int) ([]types.WebhookDelivery, error)
+	SaveAPIKey(ctx context.Context, key types.APIKey) error
+	GetAPIKeys(ctx context.Context, ownerId string) ([]types.APIKey, error)
+	GetAPIKey(ctx context.Context, id string) (*types.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*types.APIKey, error)
+	TouchAPIKey(ctx context.Context, id string, usedAt int64) error
+	DeleteAPIKeys(ctx context.Context, ownerId string) (int64, error)
+	SaveDataExport(ctx context.Context, export types.DataExport) error
+	GetDataExport(ctx context.Context, id string) (*types.DataExport, error)
+	GetPendingDataExport(ctx context.Context, userId string) (*types.DataExport, error)
+	GetDueDataExports(ctx context.Context, startedBefore int64) ([]types.DataExport, error)
+	DeleteExpiredDataExports(ctx context.Context, now int64) (int64, error)
 	Close() error
 }
 
@@ -36,6 +62,7 @@ type NatsServiceInterface interface {
 	PeekTopOfQueue(ctx context.Context) (*jetstream.RawStreamMsg, error)
 	PublishMsg(ctx context.Context, job interface{}) error
 	ConsumeMsg(ctx context.Context, workers int) error
+	PublishWebhookDelivery(ctx context.Context, deliveryId string) error
 	Close() error
 }
 
@@ -47,6 +74,7 @@ type StripeSubscriptionServiceInterface interface {
 	UpdateCustomerMetadata(customerID, externalID string) error
 	CreateCustomer(externalID, email, name string) (*stripe.Customer, error)
 	GetOrCreateCustomerByExternalID(externalID, email, name string) (*stripe.Customer, error)
+	RedactCustomer(customerID string) error
 }
 
 var ErrInvalidLocation = errors.New("location is not valid")
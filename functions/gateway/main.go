@@ -3,7 +3,6 @@ package main
 // TODO: test "endTime" and add to UI
 
 import (
-	"bufio"
 	"context"
 	"encoding/base64"
 	"encoding/json"
@@ -13,8 +12,10 @@ import (
 	"net/http"
 	"net/url"
 	"os"
-	"strconv"
+	"os/signal"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
@@ -24,6 +25,7 @@ import (
 	"github.com/gorilla/mux"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/weaviate/weaviate/entities/models"
 	"github.com/zitadel/zitadel-go/v3/pkg/authorization"
 	"github.com/zitadel/zitadel-go/v3/pkg/authorization/oauth"
 
@@ -31,22 +33,67 @@ import (
 	"github.com/meetnearme/api/functions/gateway/handlers"
 	"github.com/meetnearme/api/functions/gateway/handlers/dynamodb_handlers"
 	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/openapi"
 	"github.com/meetnearme/api/functions/gateway/services"
 	"github.com/meetnearme/api/functions/gateway/startup"
 	"github.com/meetnearme/api/functions/gateway/transport"
+	"github.com/meetnearme/api/functions/gateway/types"
 )
 
 type AuthType string
 
 const (
-	None               AuthType = "none"
-	Check              AuthType = "check"
-	Require            AuthType = "require"
-	RequireServiceUser AuthType = "require_service_user"
-	seshulooptime               = 30 * time.Second // Real-time interval (will be compressed by TIME_COMPRESSION_RATIO)
-	maxseshuloopcount           = 10
-	seshuCronWorkers            = 1
-	timestampFile               = "last_update.txt"
+	None                    AuthType = "none"
+	Check                   AuthType = "check"
+	Require                 AuthType = "require"
+	RequireServiceUser      AuthType = "require_service_user"
+	seshulooptime                    = 30 * time.Second // Real-time interval (will be compressed by TIME_COMPRESSION_RATIO)
+	maxseshuloopcount                = 10
+	seshuCronWorkers                 = 1
+	webhookWorkers                   = 4
+	seriesLoopTime                   = 1 * time.Hour // Real-time interval (will be compressed by TIME_COMPRESSION_RATIO)
+	accountDeletionLoopTime          = 1 * time.Hour
+	dataExportLoopTime               = 15 * time.Second
+	// readinessDrainDelay is how long `/readyz` fails before the server stops,
+	// long enough for the load balancer to see it and stop routing to us
+	readinessDrainDelay = 5 * time.Second
+	// shutdownTimeout bounds the wait for in-flight requests and background
+	// workers after the drain delay, it outlasts the Seshu job drain
+	shutdownTimeout = 20 * time.Second
+)
+
+// Rate limits of the routes that are expensive or easy to abuse, see
+// `Route.Limit`
+var (
+	searchRateLimit = services.RateLimitPolicy{
+		Name:      "search",
+		PerIP:     services.RateLimit{Requests: 120, Per: time.Minute},
+		PerUser:   services.RateLimit{Requests: 240, Per: time.Minute},
+		PerAPIKey: services.RateLimit{Requests: 600, Per: time.Minute},
+	}
+	embedRateLimit = services.RateLimitPolicy{
+		Name:  "embed",
+		PerIP: services.RateLimit{Requests: 60, Per: time.Minute},
+	}
+	// every geo lookup is a paid ScrapingBee render
+	geoRateLimit = services.RateLimitPolicy{
+		Name:  "geo",
+		PerIP: services.RateLimit{Requests: 10, Per: time.Hour},
+	}
+	// imports geocode addresses the file has no coordinates for
+	csvImportRateLimit = services.RateLimitPolicy{
+		Name:    "csv-import",
+		PerUser: services.RateLimit{Requests: 20, Per: time.Hour},
+	}
+	icalImportRateLimit = services.RateLimitPolicy{
+		Name:    "ical-import",
+		PerUser: services.RateLimit{Requests: 20, Per: time.Hour},
+	}
+	seshuSessionRateLimit = services.RateLimitPolicy{
+		Name:    "seshu-session",
+		PerIP:   services.RateLimit{Requests: 30, Per: time.Hour},
+		PerUser: services.RateLimit{Requests: 20, Per: time.Hour},
+	}
 )
 
 type Route struct {
@@ -54,156 +101,210 @@ type Route struct {
 	Method  string
 	Handler func(http.ResponseWriter, *http.Request) http.HandlerFunc
 	Auth    AuthType
+	Doc     RouteDoc
+	// Limit is the rate limit `addRoute` applies after auth, nil for none
+	Limit *services.RateLimitPolicy
+}
+
+// RouteDoc describes a route in the OpenAPI document served at
+// `/api/openapi.json`. `Request` and `Response` are zero values of the types
+// the handler decodes and encodes, left nil when the body isn't JSON or
+// isn't a single Go type
+type RouteDoc struct {
+	Summary  string
+	Request  any
+	Response any
+	// Status is the success status when it isn't 200
+	Status int
+	// ContentType is the success response's when it isn't JSON, routes
+	// under `/api/html/` default to HTML
+	ContentType string
 }
 
 func (app *App) InitRoutes() []Route {
 	routes := []Route{
-		{"/auth/login", "GET", handlers.HandleLogin, None},
-		{"/auth/callback", "GET", handlers.HandleCallback, None},
-		{"/auth/refresh", "GET", handlers.HandleRefresh, Require},
-		{"/auth/logout", "GET", handlers.HandleLogout, None},
-		{constants.SitePages["home"].Slug, "GET", handlers.GetHomeOrUserPage, Check},
-		{constants.SitePages["about"].Slug, "GET", handlers.GetAboutPage, Check},
-		{constants.SitePages["user"].Slug, "GET", handlers.GetHomeOrUserPage, Check},
-		{constants.SitePages["add-event-source"].Slug, "GET", handlers.GetAddEventSourcePage, Require},
-		{constants.SitePages["add-event"].Slug, "GET", handlers.GetAddOrEditEventPage, Require},
-		{constants.SitePages["edit-event"].Slug, "GET", handlers.GetAddOrEditEventPage, Require},
-		{constants.SitePages["attendees-event"].Slug, "GET", handlers.GetEventAttendeesPage, Require},
-		{constants.SitePages["map-embed"].Slug, "GET", handlers.GetMapEmbedPage, Check},
-		{constants.SitePages["privacy-policy"].Slug, "GET", handlers.GetPrivacyPolicyPage, Check},
-		{constants.SitePages["data-request"].Slug, "GET", handlers.GetDataRequestPage, Check},
-		{constants.SitePages["terms-of-service"].Slug, "GET", handlers.GetTermsOfServicePage, Check},
-		{constants.SitePages["pricing"].Slug, "GET", handlers.GetPricingPage, Check},
+		{"/auth/login", "GET", handlers.HandleLogin, None, RouteDoc{Summary: "Log in"}, nil},
+		{"/auth/callback", "GET", handlers.HandleCallback, None, RouteDoc{Summary: "Finish logging in"}, nil},
+		{"/auth/refresh", "GET", handlers.HandleRefresh, Require, RouteDoc{Summary: "Refresh the session"}, nil},
+		{"/auth/logout", "GET", handlers.HandleLogout, None, RouteDoc{Summary: "Log out"}, nil},
+		{constants.SitePages["home"].Slug, "GET", handlers.GetHomeOrUserPage, Check, RouteDoc{Summary: "Home page"}, nil},
+		{constants.SitePages["about"].Slug, "GET", handlers.GetAboutPage, Check, RouteDoc{Summary: "About page"}, nil},
+		{constants.SitePages["user"].Slug, "GET", handlers.GetHomeOrUserPage, Check, RouteDoc{Summary: "User page"}, nil},
+		{constants.SitePages["add-event-source"].Slug, "GET", handlers.GetAddEventSourcePage, Require, RouteDoc{Summary: "Add event source page"}, nil},
+		{constants.SitePages["add-event"].Slug, "GET", handlers.GetAddOrEditEventPage, Require, RouteDoc{Summary: "Add event page"}, nil},
+		{constants.SitePages["edit-event"].Slug, "GET", handlers.GetAddOrEditEventPage, Require, RouteDoc{Summary: "Edit event page"}, nil},
+		{constants.SitePages["attendees-event"].Slug, "GET", handlers.GetEventAttendeesPage, Require, RouteDoc{Summary: "Event attendees page"}, nil},
+		{constants.SitePages["map-embed"].Slug, "GET", handlers.GetMapEmbedPage, Check, RouteDoc{Summary: "Map embed page"}, nil},
+		{constants.SitePages["privacy-policy"].Slug, "GET", handlers.GetPrivacyPolicyPage, Check, RouteDoc{Summary: "Privacy policy page"}, nil},
+		{constants.SitePages["data-request"].Slug, "GET", handlers.GetDataRequestPage, Check, RouteDoc{Summary: "Data request page"}, nil},
+		{constants.SitePages["terms-of-service"].Slug, "GET", handlers.GetTermsOfServicePage, Check, RouteDoc{Summary: "Terms of service page"}, nil},
+		{constants.SitePages["pricing"].Slug, "GET", handlers.GetPricingPage, Check, RouteDoc{Summary: "Pricing page"}, nil},
 		// TODO: sometimes `Check` will fail to retrieve the user info, this is different
 		// from `Require` which always creates a new session if the user isn't logged in...
 		// the complexity is we might want "in the middle", which would be "auto-refresh
 		// the session, but DO NOT redirect to /login if the user's session is expired'"
 		// session duration might be a Zitadel configuration issue
-		{constants.SitePages["event-detail"].Slug, "GET", handlers.GetEventDetailsPage, Check},
+		{constants.SitePages["event-detail"].Slug, "GET", handlers.GetEventDetailsPage, Check, RouteDoc{Summary: "Event detail page"}, nil},
 		// Below for competition engagement modules
 		// {constants.SitePages["competitions"].Slug, "GET", handlers.GetCompetitionsPage, Check},
-		{constants.SitePages["competition-edit"].Slug, "GET", handlers.GetAddOrEditCompetitionPage, Require},
-		{constants.SitePages["competition-new"].Slug, "GET", handlers.GetAddOrEditCompetitionPage, Require},
+		{constants.SitePages["competition-edit"].Slug, "GET", handlers.GetAddOrEditCompetitionPage, Require, RouteDoc{Summary: "Edit competition page"}, nil},
+		{constants.SitePages["competition-new"].Slug, "GET", handlers.GetAddOrEditCompetitionPage, Require, RouteDoc{Summary: "Add competition page"}, nil},
 
 		// NOTE: ⚠️⚠️⚠️⚠️ we use a catch-all route for `admin` here but it needs to come LAST
 		// moving this higher will break admin sub-routes that are not handled by this catch-all route
-		{constants.SitePages["admin"].Slug, "GET", handlers.GetAdminPage, Require},
+		{constants.SitePages["admin"].Slug, "GET", handlers.GetAdminPage, Require, RouteDoc{Summary: "Admin pages"}, nil},
 		// API routes
 
-		// == START == need to expose these via permanent key for headless clients
-		{"/api/event{trailingslash:\\/?}", "POST", handlers.PostEventHandler, Require},
+		// == START == also available to headless clients with an API key, see
+		// `services.APIKeyScopeFor` for the scope each one needs
+		{"/api/event{trailingslash:\\/?}", "POST", handlers.PostEventHandler, Require, RouteDoc{Summary: "Create an event", Request: services.RawEvent{}, Response: []models.ObjectsGetResponse{}, Status: http.StatusCreated}, nil},
 		// These below are public apis somewhat legacy for Adalo
-		{"/api/events{trailingslash:\\/?}", "POST", handlers.PostBatchEventsHandler, Require},
-		{"/api/events{trailingslash:\\/?}", "GET", handlers.SearchEventsHandler, None},
-		{"/api/events{trailingslash:\\/?}", "PUT", handlers.BulkUpdateEventsHandler, Require},
-		{"/api/events/{" + constants.EVENT_ID_KEY + "}", "GET", handlers.GetOneEventHandler, None},
-		{"/api/events/{" + constants.EVENT_ID_KEY + "}", "PUT", handlers.UpdateOneEventHandler, Require},
+		{"/api/events{trailingslash:\\/?}", "POST", handlers.PostBatchEventsHandler, Require, RouteDoc{Summary: "Create events", Request: handlers.BatchEventsPayload{}, Response: []models.ObjectsGetResponse{}, Status: http.StatusCreated}, nil},
+		{"/api/events{trailingslash:\\/?}", "GET", handlers.SearchEventsHandler, Check, RouteDoc{Summary: "Search events", Response: types.EventSearchResponse{}}, &searchRateLimit},
+		{"/api/map/events{trailingslash:\\/?}", "GET", handlers.GetMapEventsHandler, None, RouteDoc{Summary: "Get events for the map"}, &searchRateLimit},
+		{"/api/events{trailingslash:\\/?}", "PUT", handlers.BulkUpdateEventsHandler, Require, RouteDoc{Summary: "Update events", Request: handlers.BatchEventsPayload{}, Response: []models.ObjectsGetResponse{}}, nil},
+		{"/api/ical/events{trailingslash:\\/?}", "GET", handlers.GetICalEventsHandler, None, RouteDoc{Summary: "Get an iCalendar feed of events", ContentType: "text/calendar"}, &searchRateLimit},
+		{"/api/ical/import{trailingslash:\\/?}", "POST", handlers.ImportICalEvents, Require, RouteDoc{Summary: "Import iCalendar events", Request: handlers.ICalImportPayload{}, Response: services.ICalImportResult{}}, &icalImportRateLimit},
+		{"/api/events/import/csv{trailingslash:\\/?}", "POST", handlers.ImportCSVEventsHandler, Require, RouteDoc{Summary: "Import events from a CSV file", Response: services.CSVImportReport{}}, &csvImportRateLimit},
+		{"/api/data-exports{trailingslash:\\/?}", "POST", handlers.StartDataExportHandler, Require, RouteDoc{Summary: "Start a data export", Request: handlers.DataExportPayload{}, Response: services.DataExportJob{}, Status: http.StatusAccepted}, nil},
+		{"/api/data-exports/{" + constants.DATA_EXPORT_ID_KEY + "}", "GET", handlers.GetDataExportHandler, Require, RouteDoc{Summary: "Get a data export", Response: services.DataExportJob{}}, nil},
+		{"/api/data-exports/{" + constants.DATA_EXPORT_ID_KEY + "}/download{trailingslash:\\/?}", "GET", handlers.DownloadDataExportHandler, Require, RouteDoc{Summary: "Download a data export", ContentType: "application/zip"}, nil},
+		{"/api/account-deletion{trailingslash:\\/?}", "POST", handlers.RequestAccountDeletionHandler, Require, RouteDoc{Summary: "Request account deletion", Request: handlers.AccountDeletionPayload{}, Response: types.AccountDeletion{}, Status: http.StatusAccepted}, nil},
+		{"/api/account-deletion{trailingslash:\\/?}", "GET", handlers.GetAccountDeletionHandler, Require, RouteDoc{Summary: "Get the pending account deletion", Response: types.AccountDeletion{}}, nil},
+		{"/api/account-deletion{trailingslash:\\/?}", "DELETE", handlers.CancelAccountDeletionHandler, Require, RouteDoc{Summary: "Cancel account deletion", Response: types.AccountDeletion{}}, nil},
+		{"/api/quarantined-events{trailingslash:\\/?}", "GET", handlers.GetQuarantinedEventsHandler, Require, RouteDoc{Summary: "List quarantined events", Response: []types.QuarantinedEvent{}}, nil},
+		{"/api/quarantined-events/{" + constants.QUARANTINED_EVENT_ID_KEY + "}/publish{trailingslash:\\/?}", "POST", handlers.PublishQuarantinedEventHandler, Require, RouteDoc{Summary: "Publish a quarantined event", Response: []models.ObjectsGetResponse{}, Status: http.StatusCreated}, nil},
+		{"/api/quarantined-events/{" + constants.QUARANTINED_EVENT_ID_KEY + "}", "DELETE", handlers.DiscardQuarantinedEventHandler, Require, RouteDoc{Summary: "Discard a quarantined event"}, nil},
+		{"/api/webhooks{trailingslash:\\/?}", "GET", handlers.GetWebhooksHandler, Require, RouteDoc{Summary: "List webhooks", Response: []types.WebhookSubscription{}}, nil},
+		{"/api/webhooks{trailingslash:\\/?}", "POST", handlers.CreateWebhookHandler, Require, RouteDoc{Summary: "Create a webhook", Request: types.WebhookSubscriptionInsert{}, Response: types.WebhookSubscription{}, Status: http.StatusCreated}, nil},
+		{"/api/webhooks/{" + constants.WEBHOOK_ID_KEY + "}", "DELETE", handlers.DeleteWebhookHandler, Require, RouteDoc{Summary: "Delete a webhook"}, nil},
+		{"/api/webhooks/{" + constants.WEBHOOK_ID_KEY + "}/deliveries{trailingslash:\\/?}", "GET", handlers.GetWebhookDeliveriesHandler, Require, RouteDoc{Summary: "List webhook deliveries", Response: []types.WebhookDelivery{}}, nil},
+		{"/api/webhooks/{" + constants.WEBHOOK_ID_KEY + "}/deliveries/{" + constants.WEBHOOK_DELIVERY_ID_KEY + "}/redeliver{trailingslash:\\/?}", "POST", handlers.RedeliverWebhookHandler, Require, RouteDoc{Summary: "Redeliver a webhook delivery", Response: types.WebhookDelivery{}, Status: http.StatusAccepted}, nil},
+		{"/api/events/{" + constants.EVENT_ID_KEY + "}", "GET", handlers.GetOneEventHandler, None, RouteDoc{Summary: "Get an event", Response: types.Event{}}, nil},
+		{"/api/events/{" + constants.EVENT_ID_KEY + "}", "PUT", handlers.UpdateOneEventHandler, Require, RouteDoc{Summary: "Update an event", Request: services.RawEvent{}, Response: []models.ObjectsGetResponse{}}, nil},
+		{"/api/events/{" + constants.EVENT_ID_KEY + "}/similar{trailingslash:\\/?}", "GET", handlers.GetSimilarEventsHandler, None, RouteDoc{Summary: "Get similar events", Response: types.EventSearchResponse{}}, &searchRateLimit},
+		{"/api/events/{" + constants.EVENT_ID_KEY + "}/occurrences/{" + constants.RECURRENCE_ID_KEY + ":[0-9]+}", "PUT", handlers.OverrideSeriesOccurrenceHandler, Require, RouteDoc{Summary: "Override a series occurrence", Request: services.RawEvent{}, Response: types.Event{}}, nil},
+		{"/api/events/{" + constants.EVENT_ID_KEY + "}/occurrences/{" + constants.RECURRENCE_ID_KEY + ":[0-9]+}", "DELETE", handlers.CancelSeriesOccurrenceHandler, Require, RouteDoc{Summary: "Cancel a series occurrence", Response: types.Event{}}, nil},
 		// This is to delete directly which we do not do in the UI
-		{"/api/events", "DELETE", handlers.BulkDeleteEventsHandler, Require},
-
-		{"/api/event-reg-purch{trailingslash:\\/?}", "PUT", handlers.UpdateEventRegPurchHandler, Require},
-		{"/api/event-reg-purch/{" + constants.EVENT_ID_KEY + "}", "PUT", handlers.UpdateEventRegPurchHandler, Require},
-		{"/api/locations{trailingslash:\\/?}", "GET", handlers.SearchLocationsHandler, None},
-		//  == END == need to expose these via permanent key for headless clients
-		{"/api/auth/users/update-mnm-options{trailingslash:\\/?}", "POST", handlers.SetMnmOptions, Require},
-		{"/api/auth/users/delete-subdomain{trailingslash:\\/?}", "POST", handlers.DeleteMnmSubdomain, Require},
-		{"/api/auth/users/update-interests{trailingslash:\\/?}", "POST", handlers.UpdateUserInterests, Require},
-		{"/api/auth/users/update-about{trailingslash:\\/?}", "POST", handlers.UpdateUserAbout, Require},
-		{"/api/auth/users/update-location{trailingslash:\\/?}", "POST", handlers.UpdateUserLocation, Require},
-		{"/api/auth/check-role{trailingslash:\\/?}", "GET", handlers.CheckRole, Require},
+		{"/api/events", "DELETE", handlers.BulkDeleteEventsHandler, Require, RouteDoc{Summary: "Delete events", Request: handlers.BulkDeleteEventsPayload{}}, nil},
+
+		{"/api/event-reg-purch{trailingslash:\\/?}", "PUT", handlers.UpdateEventRegPurchHandler, Require, RouteDoc{Summary: "Update events with registration and purchasables", Request: handlers.UpdateEventRegPurchPayload{}}, nil},
+		{"/api/event-reg-purch/{" + constants.EVENT_ID_KEY + "}", "PUT", handlers.UpdateEventRegPurchHandler, Require, RouteDoc{Summary: "Update an event with registration and purchasables", Request: handlers.UpdateEventRegPurchPayload{}}, nil},
+		{"/api/locations{trailingslash:\\/?}", "GET", handlers.SearchLocationsHandler, None, RouteDoc{Summary: "Search locations", Response: []helpers.City{}}, nil},
+		//  == END == also available to headless clients with an API key
+		{"/api/api-keys{trailingslash:\\/?}", "GET", handlers.GetAPIKeysHandler, Require, RouteDoc{Summary: "List API keys", Response: []types.APIKey{}}, nil},
+		{"/api/api-keys{trailingslash:\\/?}", "POST", handlers.CreateAPIKeyHandler, Require, RouteDoc{Summary: "Create an API key", Request: types.APIKeyInsert{}, Response: types.CreatedAPIKey{}, Status: http.StatusCreated}, nil},
+		{"/api/api-keys/{" + constants.API_KEY_ID_KEY + "}", "DELETE", handlers.RevokeAPIKeyHandler, Require, RouteDoc{Summary: "Revoke an API key", Response: types.APIKey{}}, nil},
+		{"/api/auth/users/update-mnm-options{trailingslash:\\/?}", "POST", handlers.SetMnmOptions, Require, RouteDoc{Summary: "Update Meet Near Me options", ContentType: openapi.ContentTypeHTML}, nil},
+		{"/api/auth/users/delete-subdomain{trailingslash:\\/?}", "POST", handlers.DeleteMnmSubdomain, Require, RouteDoc{Summary: "Delete the subdomain", ContentType: openapi.ContentTypeHTML}, nil},
+		{"/api/auth/users/update-interests{trailingslash:\\/?}", "POST", handlers.UpdateUserInterests, Require, RouteDoc{Summary: "Update interests", ContentType: openapi.ContentTypeHTML}, nil},
+		{"/api/auth/users/update-about{trailingslash:\\/?}", "POST", handlers.UpdateUserAbout, Require, RouteDoc{Summary: "Update about", ContentType: openapi.ContentTypeHTML}, nil},
+		{"/api/auth/users/update-location{trailingslash:\\/?}", "POST", handlers.UpdateUserLocation, Require, RouteDoc{Summary: "Update location", ContentType: openapi.ContentTypeHTML}, nil},
+		{"/api/auth/check-role{trailingslash:\\/?}", "GET", handlers.CheckRole, Require, RouteDoc{Summary: "Check a role is active"}, nil},
 		// TODO: delete this comment once user location is implemented in profile,
 		// "/api/location/geo" is for use there
-		{"/api/location/geo{trailingslash:\\/?}", "POST", handlers.GeoLookup, None},
-		{"/api/location/city{trailingslash:\\/?}", "GET", handlers.CityLookup, None},
-		{"/api/user-search{trailingslash:\\/?}", "GET", handlers.SearchUsersHandler, Require},
-		{"/api/users{trailingslash:\\/?}", "GET", handlers.GetUsersHandler, None},
-		{"/api/html/events{trailingslash:\\/?}", "GET", handlers.GetEventsPartial, None},
-		{"/api/html/embed{trailingslash:\\/?}", "GET", handlers.GetEmbedHtml, None},
-		{"/api/embed.js", "GET", handlers.GetEmbedScript, None},
-		{"/api/html/event-series-form/{" + constants.EVENT_ID_KEY + "}", "GET", handlers.GetEventAdminChildrenPartial, None},
-		{"/api/html/seshu/session/submit{trailingslash:\\/?}", "POST", handlers.SubmitSeshuSession, Require},
-		{"/api/html/seshu/session/location{trailingslash:\\/?}", "PUT", handlers.GeoThenPatchSeshuSession, Require},
-		{"/api/html/seshu/session/events{trailingslash:\\/?}", "PUT", handlers.SubmitSeshuEvents, Require},
-		{"/api/html/competition-config/owner/{" + constants.USER_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionConfigsHtmlByPrimaryOwnerHandler, None},
-		{"/api/html/profile-interests{trailingslash:\\/?}", "GET", handlers.GetProfileInterestsPartial, Require},
-		{"/api/html/subscriptions{trailingslash:\\/?}", "GET", handlers.GetSubscriptionsPartial, Require},
-		{"/api/html/event-sources{trailingslash:\\/?}", "GET", handlers.GetSeshuJobsAdmin, Require},
-		{"/api/html/purchases{trailingslash:\\/?}", "GET", handlers.GetPurchasesAdminPartial, Require},
+		{"/api/location/geo{trailingslash:\\/?}", "POST", handlers.GeoLookup, None, RouteDoc{Summary: "Look up coordinates", Request: handlers.GeoLookupInputPayload{}, ContentType: openapi.ContentTypeHTML}, &geoRateLimit},
+		{"/api/location/city{trailingslash:\\/?}", "GET", handlers.CityLookup, None, RouteDoc{Summary: "Look up the nearest city"}, nil},
+		{"/api/user-search{trailingslash:\\/?}", "GET", handlers.SearchUsersHandler, Require, RouteDoc{Summary: "Search users", Response: []types.UserSearchResult{}}, nil},
+		{"/api/users{trailingslash:\\/?}", "GET", handlers.GetUsersHandler, None, RouteDoc{Summary: "Get users", Response: []types.UserSearchResultDangerous{}}, nil},
+		// only the "for you" feed reads the session, see `checkWhen`
+		{"/api/html/events{trailingslash:\\/?}", "GET", app.checkWhen(handlers.EventsPartialWantsSession, handlers.GetEventsPartial), None, RouteDoc{Summary: "Render events"}, nil},
+		{"/api/html/events/{" + constants.EVENT_ID_KEY + "}/similar{trailingslash:\\/?}", "GET", handlers.GetSimilarEventsPartial, None, RouteDoc{Summary: "Render similar events"}, &searchRateLimit},
+		{"/api/html/embed{trailingslash:\\/?}", "GET", handlers.GetEmbedHtml, None, RouteDoc{Summary: "Render the embed"}, &embedRateLimit},
+		{"/api/embed.js", "GET", handlers.GetEmbedScript, None, RouteDoc{Summary: "Get the embed script", ContentType: "application/javascript"}, nil},
+		{"/api/html/event-series-form/{" + constants.EVENT_ID_KEY + "}", "GET", handlers.GetEventAdminChildrenPartial, None, RouteDoc{Summary: "Render the event series form"}, nil},
+		{"/api/html/seshu/session/submit{trailingslash:\\/?}", "POST", handlers.SubmitSeshuSession, Require, RouteDoc{Summary: "Submit an event source"}, &seshuSessionRateLimit},
+		{"/api/html/seshu/session/location{trailingslash:\\/?}", "PUT", handlers.GeoThenPatchSeshuSession, Require, RouteDoc{Summary: "Set an event source location", Request: handlers.GeoThenSeshuPatchInputPayload{}}, &seshuSessionRateLimit},
+		{"/api/html/seshu/session/events{trailingslash:\\/?}", "PUT", handlers.SubmitSeshuEvents, Require, RouteDoc{Summary: "Submit event source events", Request: handlers.SeshuSessionEventsPayload{}}, &seshuSessionRateLimit},
+		{"/api/html/competition-config/owner/{" + constants.USER_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionConfigsHtmlByPrimaryOwnerHandler, None, RouteDoc{Summary: "Render competitions of an owner"}, nil},
+		{"/api/html/profile-interests{trailingslash:\\/?}", "GET", handlers.GetProfileInterestsPartial, Require, RouteDoc{Summary: "Render profile interests"}, nil},
+		{"/api/html/subscriptions{trailingslash:\\/?}", "GET", handlers.GetSubscriptionsPartial, Require, RouteDoc{Summary: "Render subscriptions"}, nil},
+		{"/api/html/event-sources{trailingslash:\\/?}", "GET", handlers.GetSeshuJobsAdmin, Require, RouteDoc{Summary: "Render event sources"}, nil},
+		{"/api/html/purchases{trailingslash:\\/?}", "GET", handlers.GetPurchasesAdminPartial, Require, RouteDoc{Summary: "Render purchases"}, nil},
+		{"/api/html/event-import{trailingslash:\\/?}", "GET", handlers.GetEventImportAdminPartial, Require, RouteDoc{Summary: "Render event import"}, nil},
+		{"/api/html/webhooks{trailingslash:\\/?}", "GET", handlers.GetWebhooksAdminPartial, Require, RouteDoc{Summary: "Render webhooks"}, nil},
 
 		// // Purchasables routes
-		{"/api/purchasables/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "POST", dynamodb_handlers.CreatePurchasableHandler, Require},   // Create a new purchasable
-		{"/api/purchasables/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "GET", dynamodb_handlers.GetPurchasableHandler, None},          // Get all purchasables
-		{"/api/purchasables/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "PUT", dynamodb_handlers.UpdatePurchasableHandler, Require},    // Update an existing purchasable
-		{"/api/purchasables/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "DELETE", dynamodb_handlers.DeletePurchasableHandler, Require}, // Delete a purchasable
+		{"/api/purchasables/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "POST", dynamodb_handlers.CreatePurchasableHandler, Require, RouteDoc{Summary: "Create purchasables", Request: types.PurchasableInsert{}, Response: types.Purchasable{}, Status: http.StatusCreated}, nil}, // Create a new purchasable
+		{"/api/purchasables/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "GET", dynamodb_handlers.GetPurchasableHandler, None, RouteDoc{Summary: "Get purchasables", Response: types.Purchasable{}}, nil},                                                                           // Get all purchasables
+		{"/api/purchasables/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "PUT", dynamodb_handlers.UpdatePurchasableHandler, Require, RouteDoc{Summary: "Update purchasables", Request: types.PurchasableUpdate{}, Response: types.Purchasable{}}, nil},                              // Update an existing purchasable
+		{"/api/purchasables/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "DELETE", dynamodb_handlers.DeletePurchasableHandler, Require, RouteDoc{Summary: "Delete purchasables"}, nil},                                                                                              // Delete a purchasable
 
 		// RegistrationFields
-		{"/api/registration-fields/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "POST", dynamodb_handlers.CreateRegistrationFieldsHandler, Require},
-		{"/api/registration-fields/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "GET", dynamodb_handlers.GetRegistrationFieldsByEventIDHandler, None},
-		{"/api/registration-fields/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "PUT", dynamodb_handlers.UpdateRegistrationFieldsHandler, Require},
-		{"/api/registration-fields/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "DELETE", dynamodb_handlers.DeleteRegistrationFieldsHandler, Require},
+		{"/api/registration-fields/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "POST", dynamodb_handlers.CreateRegistrationFieldsHandler, Require, RouteDoc{Summary: "Create registration fields", Request: types.RegistrationFieldsInsert{}, Response: types.RegistrationFields{}, Status: http.StatusCreated}, nil},
+		{"/api/registration-fields/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "GET", dynamodb_handlers.GetRegistrationFieldsByEventIDHandler, None, RouteDoc{Summary: "Get registration fields", Response: types.RegistrationFields{}}, nil},
+		{"/api/registration-fields/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "PUT", dynamodb_handlers.UpdateRegistrationFieldsHandler, Require, RouteDoc{Summary: "Update registration fields", Request: types.RegistrationFieldsUpdate{}, Response: types.RegistrationFields{}}, nil},
+		{"/api/registration-fields/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "DELETE", dynamodb_handlers.DeleteRegistrationFieldsHandler, Require, RouteDoc{Summary: "Delete registration fields"}, nil},
 
 		// Purchases
-		{"/api/purchases/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}/{user_id:[0-9a-fA-F-]+}", "POST", dynamodb_handlers.CreatePurchaseHandler, Require},                     // Create a new event Purchase
-		{"/api/purchases/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}/{user_id:[0-9a-fA-F-]+}/{created_at:[0-9]+}", "GET", dynamodb_handlers.GetPurchaseByPkHandler, Require}, // Get a specific event Purchase
-		{"/api/purchases/event/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "GET", dynamodb_handlers.GetPurchasesByEventIDHandler, Require},                                 // Get all event Purchases
-		{"/api/purchases/user/{user_id:[0-9a-fA-F-]+}", "GET", dynamodb_handlers.GetPurchasesByUserIDHandler, Require},
-		{"/api/purchases/has-for-event", "POST", dynamodb_handlers.HasPurchaseForEventHandler, Require},                                                                     // User has a purchase for one of two events
-		{"/api/purchases/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}/{user_id:[0-9a-fA-F-]+}/{created_at:[0-9]+}", "PUT", dynamodb_handlers.UpdatePurchaseHandler, None}, // Update an existing event Purchase
-		{"/api/purchases/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}/{user_id:[0-9a-fA-F-]+}", "DELETE", dynamodb_handlers.DeletePurchaseHandler, None},
+		{"/api/purchases/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}/{user_id:[0-9a-fA-F-]+}", "POST", dynamodb_handlers.CreatePurchaseHandler, Require, RouteDoc{Summary: "Create a purchase", Request: types.PurchaseInsert{}, Response: types.Purchase{}, Status: http.StatusCreated}, nil}, // Create a new event Purchase
+		{"/api/purchases/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}/{user_id:[0-9a-fA-F-]+}/{created_at:[0-9]+}", "GET", dynamodb_handlers.GetPurchaseByPkHandler, Require, RouteDoc{Summary: "Get a purchase", Response: types.Purchase{}}, nil},                                             // Get a specific event Purchase
+		{"/api/purchases/event/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "GET", dynamodb_handlers.GetPurchasesByEventIDHandler, Require, RouteDoc{Summary: "List purchases of an event"}, nil},                                                                                             // Get all event Purchases
+		{"/api/purchases/user/{user_id:[0-9a-fA-F-]+}", "GET", dynamodb_handlers.GetPurchasesByUserIDHandler, Require, RouteDoc{Summary: "List purchases of a user"}, nil},
+		{"/api/purchases/has-for-event", "POST", dynamodb_handlers.HasPurchaseForEventHandler, Require, RouteDoc{Summary: "Check for a purchase of an event", Request: types.HasPurchaseForEventPayload{}}, nil},                                                                      // User has a purchase for one of two events
+		{"/api/purchases/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}/{user_id:[0-9a-fA-F-]+}/{created_at:[0-9]+}", "PUT", dynamodb_handlers.UpdatePurchaseHandler, None, RouteDoc{Summary: "Update a purchase", Request: types.PurchaseUpdate{}, Response: types.Purchase{}}, nil}, // Update an existing event Purchase
+		{"/api/purchases/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}/{user_id:[0-9a-fA-F-]+}", "DELETE", dynamodb_handlers.DeletePurchaseHandler, None, RouteDoc{Summary: "Delete a purchase"}, nil},
 
 		// Competition Config
-		{"/api/competition-config", "PUT", dynamodb_handlers.UpdateCompetitionConfigHandler, Require},
-		{"/api/competition-config/owner", "GET", dynamodb_handlers.GetCompetitionConfigsByPrimaryOwnerHandler, Require},
-		{"/api/competition-config/owner/{" + constants.USER_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionConfigsByPrimaryOwnerHandler, None},
-		{"/api/competition-config/{" + constants.COMPETITIONS_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionConfigByIdHandler, Require},
+		{"/api/competition-config", "PUT", dynamodb_handlers.UpdateCompetitionConfigHandler, Require, RouteDoc{Summary: "Create a competition", Request: types.CompetitionConfigUpdate{}, Response: types.CompetitionConfig{}, Status: http.StatusCreated}, nil},
+		{"/api/competition-config/owner", "GET", dynamodb_handlers.GetCompetitionConfigsByPrimaryOwnerHandler, Require, RouteDoc{Summary: "List own competitions", Response: []types.CompetitionConfig{}}, nil},
+		{"/api/competition-config/owner/{" + constants.USER_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionConfigsByPrimaryOwnerHandler, None, RouteDoc{Summary: "List competitions of an owner", Response: []types.CompetitionConfig{}}, nil},
+		{"/api/competition-config/{" + constants.COMPETITIONS_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionConfigByIdHandler, Require, RouteDoc{Summary: "Get a competition", Response: types.CompetitionConfigResponse{}}, nil},
 		// verify below is correct with brian, was not accessing userId from context
-		{"/api/competition-config/{" + constants.COMPETITIONS_ID_KEY + "}", "PUT", dynamodb_handlers.UpdateCompetitionConfigHandler, Require},
-		{"/api/competition-config/{" + constants.COMPETITIONS_ID_KEY + "}", "DELETE", dynamodb_handlers.DeleteCompetitionConfigHandler, Require},
+		{"/api/competition-config/{" + constants.COMPETITIONS_ID_KEY + "}", "PUT", dynamodb_handlers.UpdateCompetitionConfigHandler, Require, RouteDoc{Summary: "Update a competition", Request: types.CompetitionConfigUpdate{}, Response: types.CompetitionConfig{}, Status: http.StatusCreated}, nil},
+		{"/api/competition-config/{" + constants.COMPETITIONS_ID_KEY + "}", "DELETE", dynamodb_handlers.DeleteCompetitionConfigHandler, Require, RouteDoc{Summary: "Delete a competition"}, nil},
 
 		// Competition Round
-		{"/api/competition-round/{" + constants.COMPETITIONS_ID_KEY + "}", "PUT", dynamodb_handlers.PutCompetitionRoundsHandler, Require}, // creation or update
-		{"/api/competition-round/competition-sum/{" + constants.COMPETITIONS_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionRoundsScoreSums, None},
-		{"/api/competition-round/competition/{" + constants.COMPETITIONS_ID_KEY + "}", "GET", dynamodb_handlers.GetAllCompetitionRoundsHandler, None},                                     // Gets all rounds for a competition using begins_with
-		{"/api/competition-round/event/{" + constants.EVENT_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionRoundsByEventIdHandler, Require},                                         // This gets a single round item by the event id it is associated with
-		{"/api/competition-round/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.ROUND_NUMBER_KEY + "}", "GET", dynamodb_handlers.GetCompetitionRoundByPrimaryKeyHandler, Require}, // This gets a single round item by its own id
-		{"/api/competition-round/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.ROUND_NUMBER_KEY + "}", "DELETE", dynamodb_handlers.DeleteCompetitionRoundHandler, Require},
+		{"/api/competition-round/{" + constants.COMPETITIONS_ID_KEY + "}", "PUT", dynamodb_handlers.PutCompetitionRoundsHandler, Require, RouteDoc{Summary: "Save competition rounds", Request: []types.CompetitionRoundUpdate{}, Status: http.StatusCreated}, nil}, // creation or update
+		{"/api/competition-round/competition-sum/{" + constants.COMPETITIONS_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionRoundsScoreSums, None, RouteDoc{Summary: "Get competition scores"}, nil},
+		{"/api/competition-round/competition/{" + constants.COMPETITIONS_ID_KEY + "}", "GET", dynamodb_handlers.GetAllCompetitionRoundsHandler, None, RouteDoc{Summary: "List competition rounds", Response: []types.CompetitionRound{}}, nil},                                   // Gets all rounds for a competition using begins_with
+		{"/api/competition-round/event/{" + constants.EVENT_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionRoundsByEventIdHandler, Require, RouteDoc{Summary: "List competition rounds of an event", Response: []types.CompetitionRound{}}, nil},                           // This gets a single round item by the event id it is associated with
+		{"/api/competition-round/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.ROUND_NUMBER_KEY + "}", "GET", dynamodb_handlers.GetCompetitionRoundByPrimaryKeyHandler, Require, RouteDoc{Summary: "Get a competition round", Response: types.CompetitionRound{}}, nil}, // This gets a single round item by its own id
+		{"/api/competition-round/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.ROUND_NUMBER_KEY + "}", "DELETE", dynamodb_handlers.DeleteCompetitionRoundHandler, Require, RouteDoc{Summary: "Delete a competition round"}, nil},
 		// summing, ending point for leader board needed here
 
 		// Competition Waiting Room
-		{"/api/waiting-room/{" + constants.COMPETITIONS_ID_KEY + "}", "PUT", dynamodb_handlers.PutCompetitionWaitingRoomParticipantHandler, Require},
-		{"/api/waiting-room/{" + constants.COMPETITIONS_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionWaitingRoomParticipantsHandler, Require},
-		{"/api/waiting-room/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.USER_ID_KEY + "}", "DELETE", dynamodb_handlers.DeleteCompetitionWaitingRoomParticipantHandler, Require},
+		{"/api/waiting-room/{" + constants.COMPETITIONS_ID_KEY + "}", "PUT", dynamodb_handlers.PutCompetitionWaitingRoomParticipantHandler, Require, RouteDoc{Summary: "Join a competition waiting room", Request: types.CompetitionWaitingRoomParticipantUpdate{}, Status: http.StatusCreated}, nil},
+		{"/api/waiting-room/{" + constants.COMPETITIONS_ID_KEY + "}", "GET", dynamodb_handlers.GetCompetitionWaitingRoomParticipantsHandler, Require, RouteDoc{Summary: "List competition waiting room participants", Response: []types.CompetitionWaitingRoomParticipant{}}, nil},
+		{"/api/waiting-room/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.USER_ID_KEY + "}", "DELETE", dynamodb_handlers.DeleteCompetitionWaitingRoomParticipantHandler, Require, RouteDoc{Summary: "Remove a competition waiting room participant"}, nil},
 
 		// // Competition Vote
-		{"/api/votes/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.ROUND_NUMBER_KEY + "}", "PUT", dynamodb_handlers.PutCompetitionVoteHandler, Require},
-		{"/api/votes/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.ROUND_NUMBER_KEY + "}", "GET", dynamodb_handlers.GetCompetitionVotesByRoundHandler, Require},
-		{"/api/votes/tally-votes/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.ROUND_NUMBER_KEY + "}", "GET", dynamodb_handlers.GetCompetitionVotesTallyForRoundHandler, Require},
-		{"/api/votes", "DELETE", dynamodb_handlers.DeleteCompetitionVoteHandler, Require},
+		{"/api/votes/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.ROUND_NUMBER_KEY + "}", "PUT", dynamodb_handlers.PutCompetitionVoteHandler, Require, RouteDoc{Summary: "Vote in a competition round", Request: types.CompetitionVoteUpdate{}, Status: http.StatusCreated}, nil},
+		{"/api/votes/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.ROUND_NUMBER_KEY + "}", "GET", dynamodb_handlers.GetCompetitionVotesByRoundHandler, Require, RouteDoc{Summary: "List votes of a competition round", Response: []types.CompetitionVote{}}, nil},
+		{"/api/votes/tally-votes/{" + constants.COMPETITIONS_ID_KEY + "}/{" + constants.ROUND_NUMBER_KEY + "}", "GET", dynamodb_handlers.GetCompetitionVotesTallyForRoundHandler, Require, RouteDoc{Summary: "Tally votes of a competition round", Response: map[string]int64{}}, nil},
+		{"/api/votes", "DELETE", dynamodb_handlers.DeleteCompetitionVoteHandler, Require, RouteDoc{Summary: "Delete a competition vote"}, nil},
 
 		// Checkout Sessions
-		{"/api/checkout/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "POST", handlers.CreateCheckoutSessionHandler, Check},
-		{"/api/checkout-subscription{trailingslash:\\/?}", "GET", handlers.CreateSubscriptionCheckoutSessionHandler, Check},
+		{"/api/checkout/{" + constants.EVENT_ID_KEY + ":[0-9a-fA-F-]+}", "POST", handlers.CreateCheckoutSessionHandler, Check, RouteDoc{Summary: "Create a checkout session", Request: types.PurchaseInsert{}, Response: handlers.PurchaseResponse{}}, nil},
+		{"/api/checkout-subscription{trailingslash:\\/?}", "GET", handlers.CreateSubscriptionCheckoutSessionHandler, Check, RouteDoc{Summary: "Start a subscription checkout", Status: http.StatusFound}, nil},
 
 		// Customer Portal
-		{"/api/customer-portal/session{trailingslash:\\/?}", "POST", handlers.CreateCustomerPortalSessionHandler, Require},
+		{"/api/customer-portal/session{trailingslash:\\/?}", "POST", handlers.CreateCustomerPortalSessionHandler, Require, RouteDoc{Summary: "Open the customer portal", Status: http.StatusFound}, nil},
 
 		// Webhooks
-		{"/api/webhook/checkout{trailingslash:\\/?}", "POST", handlers.HandleCheckoutWebhookHandler, None},
-		{"/api/webhook/subscription{trailingslash:\\/?}", "POST", handlers.HandleSubscriptionWebhookHandler, None},
+		{"/api/webhook/checkout{trailingslash:\\/?}", "POST", handlers.HandleCheckoutWebhookHandler, None, RouteDoc{Summary: "Receive Stripe checkout events"}, nil},
+		{"/api/webhook/subscription{trailingslash:\\/?}", "POST", handlers.HandleSubscriptionWebhookHandler, None, RouteDoc{Summary: "Receive Stripe subscription events"}, nil},
 
 		//SeshuSession
-		{"/api/html/session/submit{trailingslash:\\/?}", "POST", handlers.HandleSeshuSessionSubmit, Require},
+		{"/api/html/session/submit{trailingslash:\\/?}", "POST", handlers.HandleSeshuSessionSubmit, Require, RouteDoc{Summary: "Submit a Seshu session"}, &seshuSessionRateLimit},
 
 		// SeshuJobs
-		{"/api/seshujob", "GET", handlers.GetSeshuJobs, Require},
+		{"/api/seshujob", "GET", handlers.GetSeshuJobs, Require, RouteDoc{Summary: "Render Seshu jobs", ContentType: openapi.ContentTypeHTML}, nil},
 		// DISABLED to prevent abuse
 		// {"/api/seshujob", "POST", handlers.CreateSeshuJob, Require},
 		// {"/api/seshujob/{key}", "PUT", handlers.UpdateSeshuJob, Require},
-		{"/api/seshu-job", "DELETE", handlers.DeleteSeshuJob, Require},
+		{"/api/seshu-job", "DELETE", handlers.DeleteSeshuJob, Require, RouteDoc{Summary: "Delete a Seshu job", ContentType: openapi.ContentTypeHTML}, nil},
 		// {"/api/gather-seshu-jobs", "POST", handlers.GatherSeshuJobsHandler, Require},
 
 		// Re-share
-		{"/api/data/re-share", "POST", handlers.PostReShareHandler, Require},
+		{"/api/data/re-share", "POST", handlers.PostReShareHandler, Require, RouteDoc{Summary: "Re-share events"}, nil},
+
+		{"/api/openapi.json", "GET", handleOpenAPI(app), None, RouteDoc{Summary: "Get the OpenAPI document", Response: openapi.Document{}}, nil},
+
+		// Probes
+		{"/healthz", "GET", handleHealthz, None, RouteDoc{Summary: "Liveness probe", Response: services.HealthReport{}}, nil},
+		{"/readyz", "GET", handleReadyz(app), Check, RouteDoc{Summary: "Readiness probe", Response: services.HealthReport{}}, nil},
+		{"/api/seshu/leader", "GET", handleLeaderStatus, Check, RouteDoc{Summary: "Get the Seshu scheduler leader", Response: services.LeaderStatus{}}, nil},
 	}
 
 	// Only expose /metrics endpoint when IS_LOCAL_ACT=true (local development)
@@ -214,6 +315,7 @@ func (app *App) InitRoutes() []Route {
 			Method:  "GET",
 			Handler: handleMetrics,
 			Auth:    None,
+			Doc:     RouteDoc{Summary: "Prometheus metrics"},
 		})
 	}
 
@@ -242,6 +344,174 @@ type App struct {
 	Nats       *services.NatsService
 }
 
+// openAPIRoutes converts the `/api/` routes for the OpenAPI document, pages
+// and auth redirects aren't part of the API
+func openAPIRoutes(routes []Route) []openapi.Route {
+	apiRoutes := []openapi.Route{}
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		contentType := route.Doc.ContentType
+		if contentType == "" && strings.HasPrefix(route.Path, "/api/html/") {
+			contentType = openapi.ContentTypeHTML
+		}
+		apiRoutes = append(apiRoutes, openapi.Route{
+			Path:        route.Path,
+			Method:      route.Method,
+			Summary:     route.Doc.Summary,
+			Request:     route.Doc.Request,
+			Response:    route.Doc.Response,
+			Status:      route.Doc.Status,
+			ContentType: contentType,
+			Security:    openAPISecurity(route),
+		})
+	}
+	return apiRoutes
+}
+
+// openAPISecurity lists how a route can be called, the same ways `addRoute`
+// accepts for its `Auth`
+func openAPISecurity(route Route) []openapi.SecurityRequirement {
+	switch route.Auth {
+	case Require:
+		security := []openapi.SecurityRequirement{
+			{openapi.SecurityBearer: {}},
+			{openapi.SecurityCookie: {}},
+		}
+		return append(security, openAPIKeySecurity(route)...)
+	case RequireServiceUser:
+		return []openapi.SecurityRequirement{{openapi.SecurityCookie: {}}}
+	case Check:
+		// anonymous callers get the public version of the response
+		security := []openapi.SecurityRequirement{{}, {openapi.SecurityCookie: {}}}
+		return append(security, openAPIKeySecurity(route)...)
+	}
+	return nil
+}
+
+// openAPIKeySecurity is the API key requirement of a route, none when no
+// scope covers it
+func openAPIKeySecurity(route Route) []openapi.SecurityRequirement {
+	// scopes are matched on request paths, without mux's patterns
+	path, _ := openapi.PathTemplate(route.Path)
+	if scope := services.APIKeyScopeFor(route.Method, path); scope != "" {
+		return []openapi.SecurityRequirement{{openapi.SecurityAPIKey: {scope}}}
+	}
+	return nil
+}
+
+func buildOpenAPIDocument(routes []Route) (openapi.Document, error) {
+	var servers []openapi.Server
+	if apexURL := os.Getenv("APEX_URL"); apexURL != "" {
+		servers = append(servers, openapi.Server{URL: apexURL})
+	}
+	return openapi.Build(openapi.Info{
+		Title:       "Meet Near Me API",
+		Version:     "1.0.0",
+		Description: "Generated from the gateway's route table",
+	}, servers, openAPIRoutes(routes))
+}
+
+// handleOpenAPI serves the OpenAPI document, built once from the route table
+// on the first request
+func handleOpenAPI(app *App) func(http.ResponseWriter, *http.Request) http.HandlerFunc {
+	var once sync.Once
+	var document []byte
+	var err error
+	return func(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			transport.SetCORSAllowAll(w, r)
+			if r.Method == http.MethodOptions {
+				return
+			}
+			once.Do(func() {
+				var doc openapi.Document
+				doc, err = buildOpenAPIDocument(app.InitRoutes())
+				if err == nil {
+					document, err = json.Marshal(doc)
+				}
+			})
+			if err != nil {
+				transport.SendServerRes(w, []byte("Failed to build OpenAPI document: "+err.Error()), http.StatusInternalServerError, err)
+				return
+			}
+			transport.SendServerRes(w, document, http.StatusOK, nil)
+		}
+	}
+}
+
+// handleHealthz answers as long as the process serves requests, a failing
+// dependency shouldn't get the instance restarted
+func handleHealthz(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeHealthReport(w, services.HealthReport{Status: services.HEALTH_STATUS_OK})
+	}
+}
+
+// handleReadyz checks every dependency, at most once per
+// `services.READINESS_CACHE_TTL`, and answers 503 when one is failing or the
+// instance is shutting down. Only superAdmins see the breakdown
+func handleReadyz(app *App) func(http.ResponseWriter, *http.Request) http.HandlerFunc {
+	zitadelClient := &http.Client{Timeout: services.HEALTH_CHECK_TIMEOUT}
+	probe := services.NewReadinessProbe(services.READINESS_CACHE_TTL, func() []services.HealthCheck {
+		return []services.HealthCheck{
+			services.PostgresHealthCheck(app.PostGresDB),
+			services.NatsHealthCheck(app.Nats),
+			services.WeaviateHealthCheck(),
+			services.DynamoDBHealthCheck(transport.GetDB()),
+			services.ZitadelHealthCheck(zitadelClient),
+		}
+	})
+	return func(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			report := probe.Report(r.Context(), time.Now())
+			roleClaims, _ := r.Context().Value("roleClaims").([]constants.RoleClaim)
+			if !helpers.HasRequiredRole(roleClaims, []string{constants.Roles[constants.SuperAdmin]}) {
+				report = report.Summary()
+			}
+			writeHealthReport(w, report)
+		}
+	}
+}
+
+// handleLeaderStatus shows whether the Seshu scheduler lease is held.
+// Super admins also see which instance holds it and whether it's the one
+// answering, hostnames aren't for anyone else
+func handleLeaderStatus(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		status, err := services.GetLeaderStatus(r.Context())
+		if err != nil {
+			transport.SendServerRes(w, []byte("Failed to read the leader lease: "+err.Error()), http.StatusServiceUnavailable, err)
+			return
+		}
+		roleClaims, _ := r.Context().Value("roleClaims").([]constants.RoleClaim)
+		if !helpers.HasRequiredRole(roleClaims, []string{constants.Roles[constants.SuperAdmin]}) {
+			status = status.Summary()
+		}
+		data, err := json.Marshal(status)
+		if err != nil {
+			transport.SendServerRes(w, []byte("Failed to marshal leader status: "+err.Error()), http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		transport.SendServerRes(w, data, http.StatusOK, nil)
+	}
+}
+
+func writeHealthReport(w http.ResponseWriter, report services.HealthReport) {
+	status := http.StatusOK
+	if report.Status != services.HEALTH_STATUS_OK {
+		status = http.StatusServiceUnavailable
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Printf("ERR: failed to write health report: %v", err)
+	}
+}
+
 func (app *App) runStartupTasks() error {
 	// Import the startup package to trigger init() functions
 	_ = startup.Registry
@@ -254,6 +524,8 @@ func NewApp() *App {
 	app := &App{
 		Router: mux.NewRouter(),
 	}
+	app.Router.Use(services.TracingMiddleware)
+	app.Router.Use(services.MetricsMiddleware)
 	app.Router.Use(stateRedirectMiddleware)
 	app.Router.Use(withContext)
 	app.Router.Use(WithDerivedOptionsFromReq)
@@ -286,7 +558,118 @@ func (app *App) InitStripe() {
 	services.InitStripe()
 }
 
+// rateLimited counts requests to `handler` against `policy` and answers 429
+// once they run out. It runs after auth, so a logged in user or API key
+// gets its own bucket instead of sharing one with its IP
+func rateLimited(policy services.RateLimitPolicy, handler func(http.ResponseWriter, *http.Request) http.HandlerFunc) func(http.ResponseWriter, *http.Request) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				handler(w, r).ServeHTTP(w, r)
+				return
+			}
+			result, limited := services.CheckRateLimit(r, policy, time.Now())
+			if limited {
+				services.SetRateLimitHeaders(w.Header(), result)
+				if !result.Allowed {
+					transport.SendServerRes(w, []byte("Too many requests, try again later"), http.StatusTooManyRequests, nil)
+					return
+				}
+			}
+			handler(w, r).ServeHTTP(w, r)
+		}
+	}
+}
+
+// apiKeyRequestContext is swapped out by tests
+var apiKeyRequestContext = services.APIKeyRequestContext
+
+// serveWithAPIKey serves a request authenticated with an API key, false when
+// it doesn't carry one. Headless clients get an error instead of the login
+// redirect or an anonymous response when their key doesn't work
+func serveWithAPIKey(w http.ResponseWriter, r *http.Request, handler func(http.ResponseWriter, *http.Request) http.HandlerFunc) bool {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, services.API_KEY_AUTH_PREFIX) {
+		return false
+	}
+	store, err := services.GetPostgresService(r.Context())
+	if err != nil {
+		transport.SendServerRes(w, []byte("Failed to get postgres service: "+err.Error()), http.StatusInternalServerError, err)
+		return true
+	}
+	ctx, status, err := apiKeyRequestContext(r.Context(), store, strings.TrimPrefix(authHeader, services.API_KEY_AUTH_PREFIX), r.Method, r.URL.Path, time.Now())
+	if err != nil {
+		transport.SendServerRes(w, []byte(err.Error()), status, err)
+		return true
+	}
+	r = r.WithContext(ctx)
+	handler(w, r).ServeHTTP(w, r)
+	return true
+}
+
+// introspectSession is swapped out by tests
+var introspectSession = func(app *App, ctx context.Context, accessToken string) (*oauth.IntrospectionContext, error) {
+	return app.AuthZ.CheckAuthorization(ctx, accessToken)
+}
+
+// withSession adds the user of the request's access token cookie to its
+// context, the request is returned as is without a cookie or when the token
+// doesn't introspect
+func (app *App) withSession(r *http.Request) *http.Request {
+	// Get the access token from cookies
+	accessTokenCookie, err := r.Cookie(constants.MNM_ACCESS_TOKEN_COOKIE_NAME)
+	if err != nil {
+		return r
+	}
+
+	accessToken := "Bearer " + accessTokenCookie.Value
+
+	// Use the Authorizer to introspect the access token
+	authCtx, err := introspectSession(app, r.Context(), accessToken)
+	if err != nil {
+		return r
+	}
+
+	claims := authCtx.Claims
+	roleClaims, userMetaClaims := services.ExtractClaimsMeta(claims)
+
+	userInfo := constants.UserInfo{}
+	data, err := json.MarshalIndent(authCtx, "", "	")
+	if err != nil {
+		return r
+	}
+
+	err = json.Unmarshal(data, &userInfo)
+	if err != nil {
+		return r
+	}
+	ctx := context.WithValue(r.Context(), "userInfo", userInfo)
+	if roleClaims != nil {
+		ctx = context.WithValue(ctx, "roleClaims", roleClaims)
+	}
+	if userMetaClaims != nil {
+		ctx = context.WithValue(ctx, "userMetaClaims", userMetaClaims)
+	}
+	return r.WithContext(ctx)
+}
+
+// checkWhen reads the session of a public route like `Check` does, but only
+// for the requests `wants` picks, every other request is served anonymously
+// without paying for the introspection
+func (app *App) checkWhen(wants func(*http.Request) bool, handler func(http.ResponseWriter, *http.Request) http.HandlerFunc) func(http.ResponseWriter, *http.Request) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+		if wants(r) {
+			r = app.withSession(r)
+		}
+		return handler(w, r)
+	}
+}
+
 func (app *App) addRoute(route Route) {
+	if route.Limit != nil {
+		route.Handler = rateLimited(*route.Limit, route.Handler)
+	}
+
 	var handler http.HandlerFunc
 	var accessTokenCookie *http.Cookie
 	var refreshTokenCookie *http.Cookie
@@ -302,6 +685,10 @@ func (app *App) addRoute(route Route) {
 			authHeader := r.Header.Get("Authorization")
 			redirectUrl := r.URL.String()
 
+			if serveWithAPIKey(w, r, route.Handler) {
+				return
+			}
+
 			if strings.HasPrefix(authHeader, "Bearer ") {
 				accessToken = strings.TrimPrefix(authHeader, "Bearer ")
 			} else {
@@ -436,45 +823,11 @@ func (app *App) addRoute(route Route) {
 		}
 	case Check:
 		handler = func(w http.ResponseWriter, r *http.Request) {
-			// Get the access token from cookies
-			accessTokenCookie, err = r.Cookie(constants.MNM_ACCESS_TOKEN_COOKIE_NAME)
-			if err != nil {
-				route.Handler(w, r).ServeHTTP(w, r)
-				return
-			}
-
-			accessToken := "Bearer " + accessTokenCookie.Value
-
-			// Use the Authorizer to introspect the access token
-			authCtx, err := app.AuthZ.CheckAuthorization(r.Context(), accessToken)
-			if err != nil {
-				route.Handler(w, r).ServeHTTP(w, r)
-				return
-			}
-
-			claims := authCtx.Claims
-			roleClaims, userMetaClaims := services.ExtractClaimsMeta(claims)
-
-			userInfo := constants.UserInfo{}
-			data, err := json.MarshalIndent(authCtx, "", "	")
-			if err != nil {
-				route.Handler(w, r).ServeHTTP(w, r)
+			if serveWithAPIKey(w, r, route.Handler) {
 				return
 			}
 
-			err = json.Unmarshal(data, &userInfo)
-			if err != nil {
-				route.Handler(w, r).ServeHTTP(w, r)
-				return
-			}
-			ctx := context.WithValue(r.Context(), "userInfo", userInfo)
-			if roleClaims != nil {
-				ctx = context.WithValue(ctx, "roleClaims", roleClaims)
-			}
-			if userMetaClaims != nil {
-				ctx = context.WithValue(ctx, "userMetaClaims", userMetaClaims)
-			}
-			r = r.WithContext(ctx)
+			r = app.withSession(r)
 			route.Handler(w, r).ServeHTTP(w, r)
 		}
 	case RequireServiceUser:
@@ -565,6 +918,15 @@ func (app *App) InitNats() {
 		log.Fatalf("Failed to initialize NATS: %v", err)
 	}
 	app.Nats = nats.(*services.NatsService)
+
+	// Share rate limits across instances, each instance counts on its own
+	// when the bucket can't be created
+	rateLimitStore, err := app.Nats.NewRateLimitStore(ctx)
+	if err != nil {
+		log.Printf("ERR: rate limits are per instance: %v", err)
+		return
+	}
+	services.SetRateLimitStore(rateLimitStore)
 }
 
 // Middleware to inject context into the request
@@ -648,12 +1010,6 @@ func startSeshuLoop(ctx context.Context) {
 			constants.TIME_COMPRESSION_RATIO, seshulooptime, compressedInterval)
 	}
 
-	lastUpdate := readFirstLine(timestampFile)
-
-	defer func() {
-		overwriteTimestamp("last_update.txt", lastUpdate) // Write on graceful shutdown
-	}()
-
 	for {
 		select {
 		case <-ctx.Done():
@@ -661,13 +1017,24 @@ func startSeshuLoop(ctx context.Context) {
 			return
 
 		case <-ticker.C:
-			if os.Getenv("IS_ACT_LEADER") != "true" {
-				log.Printf("[INFO] Not the leader (IS_ACT_LEADER=%s). Skipping.", os.Getenv("IS_ACT_LEADER"))
+			token, leading := services.IsLeader()
+			if !leading {
+				log.Println("[INFO] Not the leader. Skipping.")
 				continue
 			}
 
+			// The last run is kept next to the lease, so a new leader carries
+			// on from the previous one's schedule
 			nowUnix := time.Now().UTC().Unix()
-			count, skipped, status, err := handlers.ProcessGatherSeshuJobs(ctx, nowUnix, lastUpdate)
+			lastUpdate, err := services.GetSchedulerLastRun(ctx, nowUnix)
+			if err != nil {
+				log.Printf("[ERROR] Failed to read the last Seshu run: %v", err)
+				continue
+			}
+
+			// Jobs carry the token of this term so a deposed leader's are
+			// dropped, see `services.WithFencingToken`
+			count, skipped, status, err := handlers.ProcessGatherSeshuJobs(services.WithFencingToken(ctx, token), nowUnix, lastUpdate)
 			if err != nil {
 				log.Printf("[ERROR] Failed to process gather seshu jobs: %v", err)
 				continue
@@ -689,69 +1056,154 @@ func startSeshuLoop(ctx context.Context) {
 			}
 
 			log.Printf("[INFO] Successfully gathered %d seshu jobs", count)
-			lastUpdate = nowUnix
-			overwriteTimestamp("last_update.txt", lastUpdate)
+			if err := services.SetSchedulerLastRun(ctx, nowUnix); err != nil {
+				log.Printf("[ERROR] Failed to record the Seshu run: %v", err)
+			}
 		}
 	}
 }
 
-func ensureTimestampFileExists(file string) error {
-	if _, err := os.Stat(file); os.IsNotExist(err) {
-		log.Printf("[INFO] File %s does not exist. Creating it...", file)
-		f, err := os.Create(file)
-		if err != nil {
-			return err
-		}
-		defer f.Close()
+// startEventClassAliasLoop follows the Weaviate `Event` alias so a schema
+// migration switches this instance to the new events class without a restart
+func startEventClassAliasLoop(ctx context.Context) {
+	if os.Getenv("EVENT_STORE") == services.EVENT_STORE_MEMORY {
+		return
+	}
+	ticker := time.NewTicker(services.EVENT_CLASS_ALIAS_REFRESH_INTERVAL)
+	defer ticker.Stop()
 
-		//write initial value (e.g., current timestamp)
-		_, err = f.WriteString(fmt.Sprintf("%d\n", time.Now().UTC().Unix()))
-		if err != nil {
-			return err
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[INFO] Event class alias loop stopped by context.")
+			return
+
+		case <-ticker.C:
+			weaviateClient, err := services.GetWeaviateClient()
+			if err != nil {
+				log.Printf("[ERROR] Failed to get weaviate client for event class alias: %v", err)
+				continue
+			}
+			if _, err := services.RefreshEventClassName(ctx, weaviateClient); err != nil {
+				log.Printf("[ERROR] Failed to refresh event class alias: %v", err)
+			}
 		}
 	}
-	return nil
 }
 
-func readFirstLine(path string) int64 {
-	file, err := os.Open(path)
-	if err != nil {
-		log.Printf("[WARN] Could not open timestamp file. Using current time. Error: %v", err)
-		return time.Now().UTC().Unix()
-	}
-	defer file.Close()
+// startSeriesLoop keeps the materialized children of recurring series
+// rolling forward, only the leader instance writes
+func startSeriesLoop(ctx context.Context) {
+	ticker := time.NewTicker(helpers.CompressDuration(seriesLoopTime))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[INFO] Series loop stopped by context.")
+			return
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line != "" {
-			timestamp, err := strconv.ParseInt(line, 10, 64)
+		case <-ticker.C:
+			if _, leading := services.IsLeader(); !leading {
+				continue
+			}
+
+			eventStore, err := services.GetEventStore()
+			if err != nil {
+				log.Printf("[ERROR] Failed to get event store for series sync: %v", err)
+				continue
+			}
+			synced, err := services.SyncAllEventSeries(ctx, eventStore, time.Now())
 			if err != nil {
-				log.Printf("[WARN] Invalid timestamp format in first line. Using current time. Error: %v", err)
-				return time.Now().UTC().Unix()
+				log.Printf("[ERROR] Failed to sync recurring series: %v", err)
+				continue
 			}
-			return timestamp
+			log.Printf("[INFO] Synced %d recurring series", synced)
 		}
 	}
+}
 
-	if err := scanner.Err(); err != nil {
-		log.Printf("[WARN] Error reading timestamp file. Using current time. Error: %v", err)
-	}
+// startAccountDeletionLoop runs account deletions whose grace period is over
+// and retries ones that stopped part way, only the leader instance runs them
+func startAccountDeletionLoop(ctx context.Context) {
+	ticker := time.NewTicker(accountDeletionLoopTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[INFO] Account deletion loop stopped by context.")
+			return
 
-	// If file is empty
-	return time.Now().UTC().Unix()
+		case <-ticker.C:
+			if _, leading := services.IsLeader(); !leading {
+				continue
+			}
+
+			sources, err := handlers.GetAccountDeletionSources(ctx)
+			if err != nil {
+				log.Printf("[ERROR] Failed to get account deletion sources: %v", err)
+				continue
+			}
+			completed, err := services.ProcessDueAccountDeletions(ctx, sources, time.Now().UTC())
+			if err != nil {
+				log.Printf("[ERROR] Failed to process account deletions: %v", err)
+				continue
+			}
+			if completed > 0 {
+				log.Printf("[INFO] Completed %d account deletions", completed)
+			}
+		}
+	}
 }
 
-func overwriteTimestamp(path string, timestamp int64) {
-	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
-	if err != nil {
-		log.Printf("[ERROR] Could not open timestamp file for overwriting: %v", err)
-		return
+// startDataExportLoop builds queued data exports and deletes expired ones,
+// only the leader instance runs them
+func startDataExportLoop(ctx context.Context) {
+	ticker := time.NewTicker(dataExportLoopTime)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[INFO] Data export loop stopped by context.")
+			return
+
+		case <-ticker.C:
+			if _, leading := services.IsLeader(); !leading {
+				continue
+			}
+
+			sources, err := handlers.GetDataExportSources(ctx)
+			if err != nil {
+				log.Printf("[ERROR] Failed to get data export sources: %v", err)
+				continue
+			}
+			completed, err := services.ProcessDueDataExports(ctx, sources, time.Now().UTC())
+			if err != nil {
+				log.Printf("[ERROR] Failed to process data exports: %v", err)
+				continue
+			}
+			if completed > 0 {
+				log.Printf("[INFO] Completed %d data exports", completed)
+			}
+		}
 	}
-	defer f.Close()
+}
 
-	if _, err := f.WriteString(fmt.Sprintf("%d\n", timestamp)); err != nil {
-		log.Printf("[ERROR] Failed to write timestamp to file: %v", err)
+// waitUntil waits for `wg` unless `ctx` is done first, it reports whether
+// everything finished
+func waitUntil(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
 	}
 }
 
@@ -759,12 +1211,18 @@ func main() {
 	deploymentTarget := os.Getenv("DEPLOYMENT_TARGET")
 
 	flag.Parse()
+
+	shutdownTracing, err := services.InitTracing(context.Background())
+	if err != nil {
+		log.Printf("ERR: tracing disabled: %v", err)
+	} else {
+		defer shutdownTracing(context.Background())
+	}
+
 	app := NewApp()
 	app.InitializeAuth()
 	app.SetupNotFoundHandler()
 
-	ensureTimestampFileExists(timestampFile)
-
 	// This is the package level instance of Db in handlers
 	_ = transport.GetDB()
 	defer app.PostGresDB.Close()
@@ -789,8 +1247,9 @@ func main() {
 
 		// Start serving
 		loggingRouter := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// Skip logging for /metrics endpoint to reduce log noise from Prometheus scraping
-			shouldLog := r.URL.Path != "/metrics"
+			// Skip logging for /metrics and the probes to reduce log noise from
+			// Prometheus scraping and load balancer health checks
+			shouldLog := r.URL.Path != "/metrics" && r.URL.Path != "/healthz" && r.URL.Path != "/readyz"
 
 			var start time.Time
 			if shouldLog {
@@ -822,23 +1281,89 @@ func main() {
 			}
 		}()
 
-		go func() {
+		// The loops and consumers below return once `seshuCtx` is cancelled,
+		// shutdown waits for them
+		var background sync.WaitGroup
+		runInBackground := func(fn func()) {
+			background.Add(1)
+			go func() {
+				defer background.Done()
+				fn()
+			}()
+		}
+
+		// Leadership of the scheduled loops is a lease in NATS KV, without
+		// the bucket `IS_ACT_LEADER` decides as before
+		elector, err := app.Nats.NewLeaderElector(seshuCtx, services.SESHU_SCHEDULER_LEASE)
+		if err != nil {
+			log.Printf("ERR: leader election disabled, falling back to IS_ACT_LEADER: %v", err)
+		} else {
+			services.SetLeaderElector(elector)
+			log.Printf("[INFO] Campaigning for %s as %s", services.SESHU_SCHEDULER_LEASE, elector.InstanceId())
+			runInBackground(func() {
+				elector.Run(seshuCtx)
+			})
+		}
+
+		runInBackground(func() {
 			if err := app.Nats.ConsumeMsg(seshuCtx, seshuCronWorkers); err != nil {
 				log.Fatal(err)
 			}
-		}()
+		})
 
-		go func() {
+		runInBackground(func() {
+			if err := app.Nats.ConsumeWebhookDeliveries(seshuCtx, webhookWorkers); err != nil {
+				log.Printf("[ERROR] Webhook delivery consumer stopped: %v", err)
+			}
+		})
+
+		runInBackground(func() {
 			startSeshuLoop(seshuCtx)
-		}()
+		})
+
+		runInBackground(func() {
+			startSeriesLoop(seshuCtx)
+		})
 
-		select {}
+		runInBackground(func() {
+			startEventClassAliasLoop(seshuCtx)
+		})
+
+		runInBackground(func() {
+			startAccountDeletionLoop(seshuCtx)
+		})
+
+		runInBackground(func() {
+			startDataExportLoop(seshuCtx)
+		})
+
+		stop := make(chan os.Signal, 1)
+		signal.Notify(stop, syscall.SIGTERM, os.Interrupt)
+		received := <-stop
+		services.SetDraining()
+		log.Printf("Received %s, failing readiness for %v before shutting down", received, readinessDrainDelay)
+		time.Sleep(readinessDrainDelay)
+
+		// Stops the loops and stops pulling NATS messages. Running Seshu jobs get up to
+		// `services.SESHU_JOB_DRAIN_TIMEOUT` to finish before they're cancelled
+		// and Nak'd
+		cancel()
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancelShutdown()
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Printf("[ERROR] HTTP server did not shut down cleanly: %v", err)
+		}
+		if !waitUntil(shutdownCtx, &background) {
+			log.Println("[WARN] Background workers still running at the shutdown deadline.")
+		}
+		log.Println("[INFO] Shutdown complete.")
 
 	} else {
 		adapter := gorillamux.NewV2(app.Router)
 
 		lambda.Start(func(ctx context.Context, request events.APIGatewayV2HTTPRequest) (events.APIGatewayV2HTTPResponse, error) {
 			ctx = context.WithValue(ctx, constants.ApiGwV2ReqKey, request)
+			defer services.FlushTraces(ctx)
 			return adapter.ProxyWithContext(ctx, request)
 		})
 	}
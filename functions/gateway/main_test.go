@@ -3,17 +3,25 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/aws/aws-lambda-go/events"
 	"github.com/gorilla/mux"
 	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/handlers"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/openapi"
+	"github.com/meetnearme/api/functions/gateway/services"
 	"github.com/meetnearme/api/functions/gateway/test_helpers"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"github.com/zitadel/zitadel-go/v3/pkg/authorization/oauth"
 )
 
 /*
@@ -31,6 +39,11 @@ This test suite provides comprehensive coverage for the main.go router file incl
    - TestRouteSetup: Tests SetupRoutes() function
    - TestRouteStructure: Tests Route struct
    - TestRouteAuthTypes: Tests different authentication types (None, Check, Require)
+   - TestRoutesDocumented: Tests every route has a summary for the OpenAPI document
+   - TestOpenAPIDocument: Tests the document served at /api/openapi.json
+   - TestRouteLimit: Tests the rate limit a route declares
+   - TestRouteLimitPerAPIKey: Tests API key clients are limited by their key
+   - TestCheckWhen: Tests the events partial only reads a session for the "for you" feed
 
 3. Middleware Testing
    - TestMiddleware: Tests withContext middleware
@@ -49,6 +62,8 @@ This test suite provides comprehensive coverage for the main.go router file incl
 
 6. Background Services
    - TestSeshuLoop: Tests the seshu loop functionality
+   - TestWaitUntil: Tests waiting on background workers during shutdown
+   - TestLeaderStatus: Tests the Seshu scheduler leader status endpoint
 
 7. Integration Testing
    - TestAppIntegration: Tests full app integration
@@ -238,13 +253,13 @@ func TestRouteSetup(t *testing.T) {
 				w.WriteHeader(http.StatusOK)
 				w.Write([]byte("test route"))
 			}
-		}, None},
+		}, None, RouteDoc{}, nil},
 		{"/test-auth", "GET", func(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
 			return func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusOK)
 				w.Write([]byte("test auth route"))
 			}
-		}, Require},
+		}, Require, RouteDoc{}, nil},
 	}
 
 	app.SetupRoutes(routes)
@@ -263,6 +278,153 @@ func TestRouteSetup(t *testing.T) {
 	}
 }
 
+func TestRouteLimit(t *testing.T) {
+	app := &App{
+		Router: mux.NewRouter(),
+	}
+	limit := services.RateLimitPolicy{Name: "test-route-limit", PerIP: services.RateLimit{Requests: 1, Per: time.Hour}}
+	app.SetupRoutes([]Route{
+		{"/test-limited", "GET", func(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}
+		}, None, RouteDoc{}, &limit},
+	})
+
+	codes := []int{}
+	for range 2 {
+		req := httptest.NewRequest(http.MethodGet, "/test-limited", nil)
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+		codes = append(codes, w.Code)
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusTooManyRequests {
+		t.Errorf("Expected the route's limit to answer the second request with 429, got %v", codes)
+	}
+}
+
+func TestRouteLimitPerAPIKey(t *testing.T) {
+	originalContext := apiKeyRequestContext
+	defer func() { apiKeyRequestContext = originalContext }()
+	apiKeyRequestContext = func(ctx context.Context, store interfaces.PostgresServiceInterface, rawKey, method, path string, now time.Time) (context.Context, int, error) {
+		if rawKey != "mnm_valid" {
+			return ctx, http.StatusUnauthorized, services.ErrInvalidAPIKey
+		}
+		ctx = context.WithValue(ctx, "userInfo", constants.UserInfo{Sub: "key-owner"})
+		return context.WithValue(ctx, "apiKeyId", "key-1"), http.StatusOK, nil
+	}
+
+	app := &App{
+		Router: mux.NewRouter(),
+	}
+	limit := services.RateLimitPolicy{
+		Name:      "test-api-key-limit",
+		PerIP:     services.RateLimit{Requests: 1, Per: time.Hour},
+		PerUser:   services.RateLimit{Requests: 1, Per: time.Hour},
+		PerAPIKey: services.RateLimit{Requests: 2, Per: time.Hour},
+	}
+	app.SetupRoutes([]Route{
+		{"/test-api-key-limited", "GET", func(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}
+		}, Check, RouteDoc{}, &limit},
+	})
+
+	send := func(apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/test-api-key-limited", nil)
+		req.RemoteAddr = "192.0.2.10:4321"
+		if apiKey != "" {
+			req.Header.Set("Authorization", services.API_KEY_AUTH_PREFIX+apiKey)
+		}
+		w := httptest.NewRecorder()
+		app.Router.ServeHTTP(w, req)
+		return w
+	}
+
+	codes := []int{}
+	for range 3 {
+		w := send("mnm_valid")
+		codes = append(codes, w.Code)
+		if got := w.Header().Get("RateLimit-Limit"); got != "2" {
+			t.Errorf("Expected the API key limit of 2 in RateLimit-Limit, got %q", got)
+		}
+	}
+	if codes[0] != http.StatusOK || codes[1] != http.StatusOK || codes[2] != http.StatusTooManyRequests {
+		t.Errorf("Expected the API key's third request to be refused, got %v", codes)
+	}
+	// The key's bucket is its own, the same IP without it still has a token
+	if w := send(""); w.Code != http.StatusOK {
+		t.Errorf("Expected an anonymous request from the same IP to be served, got %d", w.Code)
+	}
+	if w := send("mnm_invalid"); w.Code != http.StatusUnauthorized {
+		t.Errorf("Expected an invalid API key to be refused, got %d", w.Code)
+	}
+}
+
+// TestCheckWhen tests the public events partial only introspects a session
+// when the "for you" feed is asked for
+func TestCheckWhen(t *testing.T) {
+	originalIntrospect := introspectSession
+	defer func() { introspectSession = originalIntrospect }()
+	lookups := 0
+	introspectSession = func(app *App, ctx context.Context, accessToken string) (*oauth.IntrospectionContext, error) {
+		lookups++
+		if accessToken != "Bearer valid-token" {
+			return nil, errors.New("inactive token")
+		}
+		return &oauth.IntrospectionContext{IntrospectionResponse: oidc.IntrospectionResponse{Active: true, Subject: "user-1"}}, nil
+	}
+
+	app := &App{
+		Router: mux.NewRouter(),
+	}
+	app.SetupRoutes([]Route{
+		{"/test-events-partial", "GET", app.checkWhen(handlers.EventsPartialWantsSession, func(w http.ResponseWriter, r *http.Request) http.HandlerFunc {
+			userInfo, _ := r.Context().Value("userInfo").(constants.UserInfo)
+			return func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(userInfo.Sub))
+			}
+		}), None, RouteDoc{}, nil},
+	})
+
+	tests := []struct {
+		name        string
+		query       string
+		cookie      string
+		wantSub     string
+		wantLookups int
+	}{
+		{name: "anonymous request", query: "", wantSub: "", wantLookups: 0},
+		{name: "anonymous for you request", query: "?feed=for_you", wantSub: "", wantLookups: 0},
+		{name: "session on the default feed", query: "", cookie: "valid-token", wantSub: "user-1", wantLookups: 1},
+		{name: "session on the chronological feed", query: "?feed=chronological", cookie: "valid-token", wantSub: "", wantLookups: 0},
+		{name: "session on a text search", query: "?q=jazz", cookie: "valid-token", wantSub: "", wantLookups: 0},
+		{name: "expired session", query: "", cookie: "expired-token", wantSub: "", wantLookups: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookups = 0
+			req := httptest.NewRequest(http.MethodGet, "/test-events-partial"+tt.query, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: constants.MNM_ACCESS_TOKEN_COOKIE_NAME, Value: tt.cookie})
+			}
+			w := httptest.NewRecorder()
+			app.Router.ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected the public route to be served, got %d", w.Code)
+			}
+			if got := w.Body.String(); got != tt.wantSub {
+				t.Errorf("Expected user %q, got %q", tt.wantSub, got)
+			}
+			if lookups != tt.wantLookups {
+				t.Errorf("Expected %d session lookups, got %d", tt.wantLookups, lookups)
+			}
+		})
+	}
+}
+
 // TestMiddleware tests the middleware functions
 func TestMiddleware(t *testing.T) {
 	// Test withContext middleware
@@ -428,33 +590,6 @@ func TestAuthTypeConstants(t *testing.T) {
 	}
 }
 
-// TestTimestampFileFunctions tests the timestamp file utility functions
-func TestTimestampFileFunctions(t *testing.T) {
-	testFile := "test_timestamp.txt"
-	defer os.Remove(testFile)
-
-	// Test ensureTimestampFileExists
-	err := ensureTimestampFileExists(testFile)
-	if err != nil {
-		t.Errorf("ensureTimestampFileExists failed: %v", err)
-	}
-
-	// Test readFirstLine
-	timestamp := readFirstLine(testFile)
-	if timestamp <= 0 {
-		t.Error("Expected positive timestamp")
-	}
-
-	// Test overwriteTimestamp
-	newTimestamp := time.Now().UTC().Unix()
-	overwriteTimestamp(testFile, newTimestamp)
-
-	readTimestamp := readFirstLine(testFile)
-	if readTimestamp != newTimestamp {
-		t.Errorf("Expected timestamp %d, got %d", newTimestamp, readTimestamp)
-	}
-}
-
 // TestPortHelpers tests the port helper functions
 func TestPortHelpers(t *testing.T) {
 	// Test GetNextPort
@@ -495,6 +630,26 @@ func TestSeshuLoop(t *testing.T) {
 	time.Sleep(50 * time.Millisecond)
 }
 
+func TestWaitUntil(t *testing.T) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		wg.Done()
+	}()
+	if !waitUntil(context.Background(), &wg) {
+		t.Error("expected the wait to finish")
+	}
+
+	wg.Add(1)
+	defer wg.Done()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if waitUntil(ctx, &wg) {
+		t.Error("expected the wait to give up at the deadline")
+	}
+}
+
 // TestAppIntegration tests the full app integration
 func TestAppIntegration(t *testing.T) {
 	// Save original environment variables
@@ -630,10 +785,6 @@ func TestConstants(t *testing.T) {
 	if seshuCronWorkers != 1 {
 		t.Errorf("Expected seshuCronWorkers to be 1, got %d", seshuCronWorkers)
 	}
-
-	if timestampFile != "last_update.txt" {
-		t.Errorf("Expected timestampFile to be 'last_update.txt', got '%s'", timestampFile)
-	}
 }
 
 // TestJSONMarshalUnmarshal tests the JSON handling in auth flows
@@ -696,6 +847,155 @@ func TestRouteStructure(t *testing.T) {
 	}
 }
 
+// TestRoutesDocumented fails when a route is added without a `Doc`, the
+// OpenAPI document is generated from them
+func TestRoutesDocumented(t *testing.T) {
+	app := &App{}
+	for _, route := range app.InitRoutes() {
+		if strings.TrimSpace(route.Doc.Summary) == "" {
+			t.Errorf("%s %s has no Doc.Summary", route.Method, route.Path)
+		}
+	}
+}
+
+// TestOpenAPIDocument tests the document built from the route table
+func TestOpenAPIDocument(t *testing.T) {
+	app := &App{}
+	routes := app.InitRoutes()
+	doc, err := buildOpenAPIDocument(routes)
+	if err != nil {
+		t.Fatalf("failed to build OpenAPI document: %v", err)
+	}
+
+	for path := range doc.Paths {
+		if !strings.HasPrefix(path, "/api/") || strings.Contains(path, "trailingslash") {
+			t.Errorf("unexpected path %s", path)
+		}
+	}
+	for _, route := range routes {
+		if !strings.HasPrefix(route.Path, "/api/") {
+			continue
+		}
+		path, _ := openapi.PathTemplate(route.Path)
+		if _, ok := doc.Paths[path][strings.ToLower(route.Method)]; !ok {
+			t.Errorf("expected %s %s in the document", route.Method, path)
+		}
+	}
+
+	search := doc.Paths["/api/events"]["get"]
+	if search.Responses["200"].Content[openapi.ContentTypeJSON].Schema.Ref != "#/components/schemas/EventSearchResponse" {
+		t.Errorf("expected search to respond with EventSearchResponse, got %+v", search.Responses["200"])
+	}
+	searchAnonymous, searchAPIKey := false, false
+	for _, requirement := range search.Security {
+		if len(requirement) == 0 {
+			searchAnonymous = true
+		}
+		if scopes, ok := requirement[openapi.SecurityAPIKey]; ok && len(scopes) == 1 && scopes[0] == services.API_KEY_SCOPE_EVENTS_READ {
+			searchAPIKey = true
+		}
+	}
+	if !searchAnonymous || !searchAPIKey {
+		t.Errorf("expected search to be public and accept an API key with %s, got %+v", services.API_KEY_SCOPE_EVENTS_READ, search.Security)
+	}
+
+	create := doc.Paths["/api/event"]["post"]
+	if create.RequestBody == nil || create.Responses["201"].Description == "" {
+		t.Errorf("expected create to take a body and answer 201, got %+v", create)
+	}
+	foundAPIKey := false
+	for _, requirement := range create.Security {
+		if scopes, ok := requirement[openapi.SecurityAPIKey]; ok && len(scopes) == 1 && scopes[0] == services.API_KEY_SCOPE_EVENTS_WRITE {
+			foundAPIKey = true
+		}
+	}
+	if !foundAPIKey {
+		t.Errorf("expected create to accept an API key with %s, got %+v", services.API_KEY_SCOPE_EVENTS_WRITE, create.Security)
+	}
+
+	apiKeys := doc.Paths["/api/api-keys"]["get"]
+	for _, requirement := range apiKeys.Security {
+		if _, ok := requirement[openapi.SecurityAPIKey]; ok {
+			t.Errorf("expected API keys not to manage API keys, got %+v", apiKeys.Security)
+		}
+	}
+
+	if _, ok := doc.Paths["/api/html/events"]["get"].Responses["200"].Content[openapi.ContentTypeHTML]; !ok {
+		t.Error("expected /api/html/ routes to respond with HTML")
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		handleOpenAPI(app)(w, r).ServeHTTP(w, r)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var served openapi.Document
+	if err := json.Unmarshal(rr.Body.Bytes(), &served); err != nil {
+		t.Fatalf("failed to decode served document: %v", err)
+	}
+	if served.OpenAPI != openapi.Version || len(served.Paths) != len(doc.Paths) {
+		t.Errorf("expected the served document to match, got version %s with %d paths", served.OpenAPI, len(served.Paths))
+	}
+}
+
+func TestHealthProbes(t *testing.T) {
+	rr := httptest.NewRecorder()
+	handleHealthz(rr, nil).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"status":"ok"`) {
+		t.Errorf("expected liveness to be ok, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	writeHealthReport(rr, services.HealthReport{
+		Status: services.HEALTH_STATUS_FAILING,
+		Checks: map[string]services.HealthCheckResult{
+			"postgres": {Status: services.HEALTH_STATUS_FAILING, Error: "connection refused"},
+		},
+	})
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 when a dependency fails, got %d", rr.Code)
+	}
+	var report services.HealthReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatalf("failed to decode report: %v", err)
+	}
+	if report.Checks["postgres"].Error != "connection refused" {
+		t.Errorf("expected the breakdown in the body, got %+v", report)
+	}
+}
+
+func TestLeaderStatus(t *testing.T) {
+	t.Setenv("IS_ACT_LEADER", "true")
+	rr := httptest.NewRecorder()
+	handleLeaderStatus(rr, nil).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/seshu/leader", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d %s", rr.Code, rr.Body.String())
+	}
+	var status services.LeaderStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if status.Leader || !status.HasLeader || status.Election || status.Lease != services.SESHU_SCHEDULER_LEASE {
+		t.Errorf("expected anonymous callers to only see that there's a leader, got %+v", status)
+	}
+
+	rr = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/seshu/leader", nil)
+	req = req.WithContext(context.WithValue(req.Context(), "roleClaims", []constants.RoleClaim{{Role: constants.Roles[constants.SuperAdmin]}}))
+	handleLeaderStatus(rr, nil).ServeHTTP(rr, req)
+	if err := json.Unmarshal(rr.Body.Bytes(), &status); err != nil {
+		t.Fatalf("failed to decode status: %v", err)
+	}
+	if !status.Leader || !status.HasLeader {
+		t.Errorf("expected IS_ACT_LEADER to decide without an elector, got %+v", status)
+	}
+}
+
 // TestAppStructure tests the App struct and its methods
 func TestAppStructure(t *testing.T) {
 	app := &App{
@@ -760,13 +1060,6 @@ func TestEdgeCases(t *testing.T) {
 	if emptyApp.Router != nil {
 		t.Error("Expected router to be nil")
 	}
-
-	// Test timestamp file with non-existent directory
-	nonExistentFile := "/non/existent/path/test.txt"
-	timestamp := readFirstLine(nonExistentFile)
-	if timestamp <= 0 {
-		t.Error("Expected positive timestamp for non-existent file")
-	}
 }
 
 // TestMiddlewareChaining tests that middleware can be chained properly
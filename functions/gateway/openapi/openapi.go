@@ -0,0 +1,443 @@
+// Package openapi builds an OpenAPI 3.1 document out of the gateway's route
+// table, with JSON schemas read from the Go types handlers send and receive
+package openapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+)
+
+const Version = "3.1.0"
+
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeHTML = "text/html"
+)
+
+// Names of the security schemes every document declares
+const (
+	SecurityBearer = "bearerAuth"
+	SecurityCookie = "cookieAuth"
+	SecurityAPIKey = "apiKeyAuth"
+)
+
+// Route is one route of the table, described
+type Route struct {
+	// Path is the gorilla/mux path template, patterns and all
+	Path    string
+	Method  string
+	Summary string
+	// Tag groups routes, the first path segment after `/api/` when empty
+	Tag string
+	// Request is a value of the JSON body's type, nil when there's none
+	Request any
+	// Response is a value of the successful response's JSON body type, nil
+	// when it isn't documented or isn't JSON
+	Response any
+	// Status is the successful response's status, 200 when zero
+	Status int
+	// ContentType is the successful response's media type, JSON when empty
+	ContentType string
+	// Security lists the alternative ways to authenticate, nil for public
+	// routes. An empty requirement means authentication is optional
+	Security []SecurityRequirement
+}
+
+type SecurityRequirement map[string][]string
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type Server struct {
+	URL string `json:"url"`
+}
+
+type Document struct {
+	OpenAPI    string                          `json:"openapi"`
+	Info       Info                            `json:"info"`
+	Servers    []Server                        `json:"servers,omitempty"`
+	Paths      map[string]map[string]Operation `json:"paths"`
+	Components Components                      `json:"components"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []SecurityRequirement `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+// Schema is the part of JSON Schema the generated document uses
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+// errorSchema is the body `transport.SendServerRes` answers errors with
+var errorSchema = &Schema{
+	Type: "object",
+	Properties: map[string]*Schema{
+		"error": {
+			Type:       "object",
+			Properties: map[string]*Schema{"message": {Type: "string"}},
+			Required:   []string{"message"},
+		},
+	},
+	Required: []string{"error"},
+}
+
+// Build describes `routes` in a document. Routes that end up with the same
+// path and method as an earlier one are skipped, like mux would never reach
+// them
+func Build(info Info, servers []Server, routes []Route) (Document, error) {
+	schemas := newSchemaRegistry()
+	schemas.components["Error"] = errorSchema
+
+	doc := Document{
+		OpenAPI: Version,
+		Info:    info,
+		Servers: servers,
+		Paths:   map[string]map[string]Operation{},
+		Components: Components{
+			Schemas: schemas.components,
+			SecuritySchemes: map[string]SecurityScheme{
+				SecurityBearer: {Type: "http", Scheme: "bearer", Description: "Zitadel access token"},
+				SecurityCookie: {Type: "apiKey", In: "cookie", Name: constants.MNM_ACCESS_TOKEN_COOKIE_NAME, Description: "Zitadel access token of a browser session"},
+				SecurityAPIKey: {Type: "apiKey", In: "header", Name: "Authorization", Description: "`ApiKey <key>`, the requirement lists the scope a route needs"},
+			},
+		},
+	}
+
+	operationIds := map[string]string{}
+	for _, route := range routes {
+		path, params := PathTemplate(route.Path)
+		method := strings.ToLower(route.Method)
+		if _, ok := doc.Paths[path][method]; ok {
+			continue
+		}
+		if strings.TrimSpace(route.Summary) == "" {
+			return Document{}, fmt.Errorf("%s %s has no summary", route.Method, route.Path)
+		}
+
+		op := Operation{
+			OperationID: OperationID(route.Summary),
+			Summary:     route.Summary,
+			Parameters:  params,
+			Responses:   map[string]Response{},
+			Security:    route.Security,
+		}
+		if previous, ok := operationIds[op.OperationID]; ok {
+			return Document{}, fmt.Errorf("%s %s and %s have the same summary %q", route.Method, route.Path, previous, route.Summary)
+		}
+		operationIds[op.OperationID] = route.Method + " " + route.Path
+
+		tag := route.Tag
+		if tag == "" {
+			tag = defaultTag(path)
+		}
+		if tag != "" {
+			op.Tags = []string{tag}
+		}
+
+		if route.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{ContentTypeJSON: {Schema: schemas.schemaFor(reflect.TypeOf(route.Request))}},
+			}
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := Response{Description: http.StatusText(status)}
+		contentType := route.ContentType
+		if contentType == "" {
+			contentType = ContentTypeJSON
+		}
+		if route.Response != nil {
+			success.Content = map[string]MediaType{contentType: {Schema: schemas.schemaFor(reflect.TypeOf(route.Response))}}
+		} else if status != http.StatusNoContent && status < http.StatusMultipleChoices {
+			success.Content = map[string]MediaType{contentType: {}}
+		}
+		op.Responses[fmt.Sprint(status)] = success
+		op.Responses["default"] = Response{
+			Description: "Error",
+			Content:     map[string]MediaType{ContentTypeJSON: {Schema: &Schema{Ref: "#/components/schemas/Error"}}},
+		}
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = map[string]Operation{}
+		}
+		doc.Paths[path][method] = op
+	}
+	return doc, nil
+}
+
+// PathTemplate turns a mux path template into an OpenAPI one and its path
+// parameters. Optional trailing slashes are dropped and variable patterns
+// become the parameter's `pattern`
+func PathTemplate(muxPath string) (string, []Parameter) {
+	var path strings.Builder
+	var params []Parameter
+	for i := 0; i < len(muxPath); i++ {
+		if muxPath[i] != '{' {
+			path.WriteByte(muxPath[i])
+			continue
+		}
+		depth, end := 0, -1
+		for j := i; j < len(muxPath); j++ {
+			switch muxPath[j] {
+			case '{':
+				depth++
+			case '}':
+				depth--
+			}
+			if depth == 0 {
+				end = j
+				break
+			}
+		}
+		if end == -1 {
+			path.WriteString(muxPath[i:])
+			break
+		}
+		name, pattern, _ := strings.Cut(muxPath[i+1:end], ":")
+		i = end
+		if name == "trailingslash" {
+			continue
+		}
+		schema := &Schema{Type: "string"}
+		if pattern != "" {
+			schema.Pattern = "^" + pattern + "$"
+		}
+		params = append(params, Parameter{Name: name, In: "path", Required: true, Schema: schema})
+		path.WriteString("{" + name + "}")
+	}
+	return path.String(), params
+}
+
+// OperationID is the lowerCamelCase of `summary`, e.g. "Search events" is
+// `searchEvents`
+func OperationID(summary string) string {
+	words := strings.FieldsFunc(summary, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var id strings.Builder
+	for i, word := range words {
+		runes := []rune(strings.ToLower(word))
+		if i > 0 {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+		id.WriteString(string(runes))
+	}
+	return id.String()
+}
+
+func defaultTag(path string) string {
+	rest, ok := strings.CutPrefix(path, "/api/")
+	if !ok {
+		return ""
+	}
+	segment, _, _ := strings.Cut(rest, "/")
+	if strings.ContainsAny(segment, "{.") {
+		return ""
+	}
+	return segment
+}
+
+var (
+	jsonMarshaler = reflect.TypeFor[json.Marshaler]()
+	textMarshaler = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+type schemaRegistry struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{components: map[string]*Schema{}, names: map[reflect.Type]string{}}
+}
+
+// schemaFor describes `t` the way encoding/json marshals it. Named structs
+// become components so they're described once and can refer to themselves
+func (r *schemaRegistry) schemaFor(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.PkgPath() + "." + t.Name() {
+	case "time.Time":
+		return &Schema{Type: "string", Format: "date-time"}
+	case "encoding/json.RawMessage":
+		return &Schema{}
+	}
+	// types marshaling themselves can't be described from their fields
+	if reflect.PointerTo(t).Implements(jsonMarshaler) {
+		return &Schema{}
+	}
+	if reflect.PointerTo(t).Implements(textMarshaler) {
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schemaFor(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.structSchema(t)
+		}
+		name, ok := r.names[t]
+		if !ok {
+			name = r.componentName(t)
+			r.names[t] = name
+			r.components[name] = &Schema{}
+			*r.components[name] = *r.structSchema(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	// interfaces, funcs and the like can be anything
+	return &Schema{}
+}
+
+// componentName is the type's name, prefixed with its package when another
+// package already has a type of that name
+func (r *schemaRegistry) componentName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.Index(name, "["); i != -1 {
+		name = name[:i]
+	}
+	if _, taken := r.components[name]; !taken {
+		return name
+	}
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i != -1 {
+		pkg = pkg[i+1:]
+	}
+	prefixed := pkg + "." + name
+	for i := 2; ; i++ {
+		if _, taken := r.components[prefixed]; !taken {
+			return prefixed
+		}
+		prefixed = fmt.Sprintf("%s.%s%d", pkg, name, i)
+	}
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	r.addFields(schema, t)
+	sort.Strings(schema.Required)
+	schema.Required = slices.Compact(schema.Required)
+	return schema
+}
+
+func (r *schemaRegistry) addFields(schema *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+
+		fieldType := field.Type
+		for fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+		// untagged embedded structs are flattened, like encoding/json does
+		if field.Anonymous && name == "" && fieldType.Kind() == reflect.Struct {
+			r.addFields(schema, fieldType)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		fieldSchema := r.schemaFor(field.Type)
+		if strings.Contains(options, "string") && fieldSchema.Ref == "" {
+			fieldSchema = &Schema{Type: "string"}
+		}
+		schema.Properties[name] = fieldSchema
+		if !strings.Contains(options, "omitempty") && !strings.Contains(options, "omitzero") && field.Type.Kind() != reflect.Pointer {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAddress struct {
+	City string `json:"city"`
+}
+
+type testTree struct {
+	Name     string     `json:"name"`
+	Children []testTree `json:"children,omitempty"`
+}
+
+type testBase struct {
+	Id string `json:"id"`
+}
+
+type testPayload struct {
+	testBase
+	Title     string            `json:"title"`
+	Count     int64             `json:"count,omitempty"`
+	Price     float64           `json:"price,string"`
+	Address   *testAddress      `json:"address"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Raw       json.RawMessage   `json:"raw,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Secret    string            `json:"-"`
+	Untagged  bool
+	private   string
+}
+
+func TestPathTemplate(t *testing.T) {
+	tests := []struct {
+		muxPath  string
+		want     string
+		params   []string
+		patterns []string
+	}{
+		{"/api/events{trailingslash:\\/?}", "/api/events", nil, nil},
+		{"/api/events/{eventId}", "/api/events/{eventId}", []string{"eventId"}, []string{""}},
+		{
+			"/api/purchases/{eventId:[0-9a-fA-F-]+}/{created_at:[0-9]+}",
+			"/api/purchases/{eventId}/{created_at}",
+			[]string{"eventId", "created_at"},
+			[]string{"^[0-9a-fA-F-]+$", "^[0-9]+$"},
+		},
+		{"/api/a/{id:[a-z]{2,3}}/b", "/api/a/{id}/b", []string{"id"}, []string{"^[a-z]{2,3}$"}},
+	}
+	for _, tt := range tests {
+		path, params := PathTemplate(tt.muxPath)
+		if path != tt.want {
+			t.Errorf("PathTemplate(%q) path = %q, want %q", tt.muxPath, path, tt.want)
+		}
+		if len(params) != len(tt.params) {
+			t.Fatalf("PathTemplate(%q) got %d params, want %d", tt.muxPath, len(params), len(tt.params))
+		}
+		for i, param := range params {
+			if param.Name != tt.params[i] || param.In != "path" || !param.Required || param.Schema.Pattern != tt.patterns[i] {
+				t.Errorf("PathTemplate(%q) unexpected param %+v", tt.muxPath, param)
+			}
+		}
+	}
+}
+
+func TestOperationID(t *testing.T) {
+	tests := map[string]string{
+		"Search events":                      "searchEvents",
+		"Get the OpenAPI document":           "getTheOpenapiDocument",
+		"Tally votes of a competition round": "tallyVotesOfACompetitionRound",
+		"Re-share events":                    "reShareEvents",
+	}
+	for summary, want := range tests {
+		if got := OperationID(summary); got != want {
+			t.Errorf("OperationID(%q) = %q, want %q", summary, got, want)
+		}
+	}
+}
+
+func TestSchemaFor(t *testing.T) {
+	registry := newSchemaRegistry()
+	ref := registry.schemaFor(reflect.TypeOf([]testPayload{}))
+	if ref.Type != "array" || ref.Items.Ref != "#/components/schemas/testPayload" {
+		t.Fatalf("expected an array of testPayload, got %+v", ref)
+	}
+
+	schema := registry.components["testPayload"]
+	for _, name := range []string{"id", "title", "count", "price", "address", "labels", "raw", "created_at", "Untagged"} {
+		if schema.Properties[name] == nil {
+			t.Errorf("expected property %q", name)
+		}
+	}
+	for _, name := range []string{"Secret", "-", "private", "testBase"} {
+		if schema.Properties[name] != nil {
+			t.Errorf("expected no property %q", name)
+		}
+	}
+	if got := strings.Join(schema.Required, ","); got != "Untagged,created_at,id,price,title" {
+		t.Errorf("unexpected required properties %s", got)
+	}
+	if prop := schema.Properties["created_at"]; prop.Type != "string" || prop.Format != "date-time" {
+		t.Errorf("expected created_at to be a date-time, got %+v", prop)
+	}
+	if prop := schema.Properties["price"]; prop.Type != "string" {
+		t.Errorf("expected the string option to be respected, got %+v", prop)
+	}
+	if prop := schema.Properties["labels"]; prop.Type != "object" || prop.AdditionalProperties.Type != "string" {
+		t.Errorf("expected labels to be a map of strings, got %+v", prop)
+	}
+	if prop := schema.Properties["address"]; prop.Ref != "#/components/schemas/testAddress" {
+		t.Errorf("expected address to refer to its component, got %+v", prop)
+	}
+
+	registry.schemaFor(reflect.TypeOf(testTree{}))
+	tree := registry.components["testTree"]
+	if tree == nil || tree.Properties["children"].Items.Ref != "#/components/schemas/testTree" {
+		t.Errorf("expected testTree to refer to itself, got %+v", tree)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	routes := []Route{
+		{
+			Path:     "/api/events{trailingslash:\\/?}",
+			Method:   http.MethodPost,
+			Summary:  "Create events",
+			Request:  testPayload{},
+			Response: []testPayload{},
+			Status:   http.StatusCreated,
+			Security: []SecurityRequirement{{SecurityBearer: {}}},
+		},
+		{Path: "/api/events", Method: http.MethodPost, Summary: "Never reached"},
+		{Path: "/api/html/events", Method: http.MethodGet, Summary: "Render events", ContentType: ContentTypeHTML},
+	}
+	doc, err := Build(Info{Title: "Test", Version: "1"}, nil, routes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if doc.OpenAPI != Version {
+		t.Errorf("expected version %s, got %s", Version, doc.OpenAPI)
+	}
+
+	op, ok := doc.Paths["/api/events"]["post"]
+	if !ok {
+		t.Fatalf("expected POST /api/events, got %+v", doc.Paths)
+	}
+	if op.OperationID != "createEvents" || len(op.Tags) != 1 || op.Tags[0] != "events" {
+		t.Errorf("unexpected operation %+v", op)
+	}
+	if op.RequestBody == nil || op.RequestBody.Content[ContentTypeJSON].Schema.Ref != "#/components/schemas/testPayload" {
+		t.Errorf("expected a testPayload request body, got %+v", op.RequestBody)
+	}
+	if _, ok := op.Responses["201"]; !ok {
+		t.Errorf("expected a 201 response, got %+v", op.Responses)
+	}
+	if op.Responses["default"].Content[ContentTypeJSON].Schema.Ref != "#/components/schemas/Error" {
+		t.Errorf("expected errors to refer to the Error schema, got %+v", op.Responses["default"])
+	}
+
+	html := doc.Paths["/api/html/events"]["get"]
+	if _, ok := html.Responses["200"].Content[ContentTypeHTML]; !ok {
+		t.Errorf("expected an HTML response, got %+v", html.Responses)
+	}
+	if html.Security != nil {
+		t.Errorf("expected a public route, got %+v", html.Security)
+	}
+
+	if _, err := json.Marshal(doc); err != nil {
+		t.Errorf("failed to marshal document: %v", err)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	if _, err := Build(Info{}, nil, []Route{{Path: "/api/events", Method: http.MethodGet}}); err == nil {
+		t.Error("expected a route without a summary to fail")
+	}
+	_, err := Build(Info{}, nil, []Route{
+		{Path: "/api/events", Method: http.MethodGet, Summary: "Search events"},
+		{Path: "/api/search", Method: http.MethodGet, Summary: "Search events"},
+	})
+	if err == nil {
+		t.Error("expected routes with the same summary to fail")
+	}
+}
@@ -0,0 +1,425 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"log"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+const (
+	ACCOUNT_DELETION_STATUS_PENDING    = "PENDING"
+	ACCOUNT_DELETION_STATUS_PROCESSING = "PROCESSING"
+	ACCOUNT_DELETION_STATUS_FAILED     = "FAILED"
+	ACCOUNT_DELETION_STATUS_COMPLETE   = "COMPLETE"
+	ACCOUNT_DELETION_STATUS_CANCELED   = "CANCELED"
+)
+
+// AccountDeletionGracePeriod is how long a user has to change their mind
+// before anything is deleted
+const AccountDeletionGracePeriod = 30 * 24 * time.Hour
+
+const (
+	ACCOUNT_DELETION_STEP_API_KEYS     = "api_keys"
+	ACCOUNT_DELETION_STEP_WEBHOOKS     = "webhooks"
+	ACCOUNT_DELETION_STEP_EVENTS       = "weaviate_events"
+	ACCOUNT_DELETION_STEP_PURCHASES    = "purchases"
+	ACCOUNT_DELETION_STEP_VOTES        = "competition_votes"
+	ACCOUNT_DELETION_STEP_WAITING_ROOM = "waiting_room"
+	ACCOUNT_DELETION_STEP_SESHU_JOBS   = "seshu_jobs"
+	ACCOUNT_DELETION_STEP_SUBDOMAIN    = "subdomain"
+	ACCOUNT_DELETION_STEP_STRIPE       = "stripe_customer"
+	ACCOUNT_DELETION_STEP_IDENTITY     = "zitadel_user"
+)
+
+// AccountDeletionSteps run in this order. API keys and webhooks go first so
+// nothing can act as the user or be sent to them while the rest is deleted.
+// The subdomain is stored as
+// Zitadel metadata and the Zitadel user is what lets someone sign in and
+// follow the deletion, so Zitadel goes last
+var AccountDeletionSteps = []string{
+	ACCOUNT_DELETION_STEP_API_KEYS,
+	ACCOUNT_DELETION_STEP_WEBHOOKS,
+	ACCOUNT_DELETION_STEP_EVENTS,
+	ACCOUNT_DELETION_STEP_PURCHASES,
+	ACCOUNT_DELETION_STEP_VOTES,
+	ACCOUNT_DELETION_STEP_WAITING_ROOM,
+	ACCOUNT_DELETION_STEP_SESHU_JOBS,
+	ACCOUNT_DELETION_STEP_SUBDOMAIN,
+	ACCOUNT_DELETION_STEP_STRIPE,
+	ACCOUNT_DELETION_STEP_IDENTITY,
+}
+
+var (
+	ErrAccountDeletionNotFound = errors.New("no account deletion has been requested")
+	ErrAccountDeletionStarted  = errors.New("account deletion has already started")
+)
+
+// AccountDeletionSources are the stores a deletion is applied to. The
+// Cloudflare and Zitadel calls are funcs so tests don't need those services
+type AccountDeletionSources struct {
+	Events           EventStore
+	DynamoDB         types.DynamoDBAPI
+	Purchases        types.PurchaseServiceInterface
+	CompetitionVotes types.CompetitionVoteServiceInterface
+	WaitingRoom      types.CompetitionWaitingRoomParticipantServiceInterface
+	Postgres         interfaces.PostgresServiceInterface
+	Stripe           interfaces.StripeSubscriptionServiceInterface
+	DeleteSubdomain  func(userId string) error
+	DeleteIdentity   func(userId string) error
+}
+
+// RequestAccountDeletion schedules the user's account for deletion once the
+// grace period is over. Asking again while a deletion is outstanding returns
+// that deletion unchanged
+func RequestAccountDeletion(ctx context.Context, store interfaces.PostgresServiceInterface, userId, requestedBy string, now time.Time) (types.AccountDeletion, error) {
+	existing, err := store.GetAccountDeletion(ctx, userId)
+	if err != nil {
+		return types.AccountDeletion{}, fmt.Errorf("failed to get account deletion: %w", err)
+	}
+	if existing != nil && existing.Status != ACCOUNT_DELETION_STATUS_CANCELED {
+		return *existing, nil
+	}
+
+	deletion := types.AccountDeletion{
+		UserId:       userId,
+		RequestedBy:  requestedBy,
+		Status:       ACCOUNT_DELETION_STATUS_PENDING,
+		AnonymousId:  "deleted-" + uuid.NewString(),
+		RequestedAt:  now.Unix(),
+		ScheduledFor: now.Add(AccountDeletionGracePeriod).Unix(),
+		Receipt:      types.AccountDeletionReceipt{},
+		UpdatedAt:    now.Unix(),
+	}
+	if err := store.SaveAccountDeletion(ctx, deletion); err != nil {
+		return types.AccountDeletion{}, fmt.Errorf("failed to save account deletion: %w", err)
+	}
+	log.Printf("INFO: account deletion for user %s requested by %s, scheduled for %s", userId, requestedBy, time.Unix(deletion.ScheduledFor, 0).UTC().Format(time.RFC3339))
+	return deletion, nil
+}
+
+// CancelAccountDeletion withdraws a deletion that's still in its grace period
+func CancelAccountDeletion(ctx context.Context, store interfaces.PostgresServiceInterface, userId string, now time.Time) (types.AccountDeletion, error) {
+	deletion, err := store.GetAccountDeletion(ctx, userId)
+	if err != nil {
+		return types.AccountDeletion{}, fmt.Errorf("failed to get account deletion: %w", err)
+	}
+	if deletion == nil || deletion.Status == ACCOUNT_DELETION_STATUS_CANCELED {
+		return types.AccountDeletion{}, ErrAccountDeletionNotFound
+	}
+	if deletion.Status != ACCOUNT_DELETION_STATUS_PENDING || len(deletion.Receipt) > 0 {
+		return *deletion, ErrAccountDeletionStarted
+	}
+
+	deletion.Status = ACCOUNT_DELETION_STATUS_CANCELED
+	deletion.UpdatedAt = now.Unix()
+	if err := store.SaveAccountDeletion(ctx, *deletion); err != nil {
+		return types.AccountDeletion{}, fmt.Errorf("failed to save account deletion: %w", err)
+	}
+	log.Printf("INFO: account deletion for user %s canceled", userId)
+	return *deletion, nil
+}
+
+// ProcessDueAccountDeletions runs every deletion whose grace period is over,
+// including ones that stopped part way through. It returns how many finished
+func ProcessDueAccountDeletions(ctx context.Context, sources AccountDeletionSources, now time.Time) (int, error) {
+	due, err := sources.Postgres.GetDueAccountDeletions(ctx, now.Unix(), []string{
+		ACCOUNT_DELETION_STATUS_PENDING,
+		ACCOUNT_DELETION_STATUS_PROCESSING,
+		ACCOUNT_DELETION_STATUS_FAILED,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due account deletions: %w", err)
+	}
+
+	completed := 0
+	for _, deletion := range due {
+		if _, err := RunAccountDeletion(ctx, sources, deletion, now); err != nil {
+			log.Printf("ERR: account deletion for user %s stopped: %v", deletion.UserId, err)
+			continue
+		}
+		completed++
+	}
+	return completed, nil
+}
+
+// RunAccountDeletion applies the deletion to each store in
+// AccountDeletionSteps order. Every finished step is saved to the receipt
+// before the next one starts, so after a failure the next run picks up at the
+// step that failed. Steps tolerate their data already being gone
+func RunAccountDeletion(ctx context.Context, sources AccountDeletionSources, deletion types.AccountDeletion, now time.Time) (types.AccountDeletion, error) {
+	if deletion.Status == ACCOUNT_DELETION_STATUS_COMPLETE || deletion.Status == ACCOUNT_DELETION_STATUS_CANCELED {
+		return deletion, nil
+	}
+	if now.Unix() < deletion.ScheduledFor {
+		return deletion, fmt.Errorf("grace period runs until %s", time.Unix(deletion.ScheduledFor, 0).UTC().Format(time.RFC3339))
+	}
+
+	deletion.Status = ACCOUNT_DELETION_STATUS_PROCESSING
+	deletion.Attempts++
+	deletion.UpdatedAt = now.Unix()
+	if err := sources.Postgres.SaveAccountDeletion(ctx, deletion); err != nil {
+		return deletion, fmt.Errorf("failed to save account deletion: %w", err)
+	}
+
+	for _, step := range AccountDeletionSteps {
+		if slices.ContainsFunc(deletion.Receipt, func(entry types.AccountDeletionReceiptEntry) bool { return entry.Step == step }) {
+			continue
+		}
+		count, detail, err := runAccountDeletionStep(ctx, sources, deletion, step)
+		if err != nil {
+			deletion.Status = ACCOUNT_DELETION_STATUS_FAILED
+			deletion.LastError = fmt.Sprintf("%s: %v", step, err)
+			deletion.UpdatedAt = time.Now().UTC().Unix()
+			if saveErr := sources.Postgres.SaveAccountDeletion(ctx, deletion); saveErr != nil {
+				log.Printf("ERR: failed to save account deletion for user %s: %v", deletion.UserId, saveErr)
+			}
+			return deletion, fmt.Errorf("%s step failed: %w", step, err)
+		}
+
+		deletion.Receipt = appendAccountDeletionReceipt(deletion.Receipt, deletion.UserId, types.AccountDeletionReceiptEntry{
+			Step:   step,
+			Count:  count,
+			Detail: detail,
+			At:     time.Now().UTC().Unix(),
+		})
+		deletion.UpdatedAt = time.Now().UTC().Unix()
+		if err := sources.Postgres.SaveAccountDeletion(ctx, deletion); err != nil {
+			return deletion, fmt.Errorf("failed to save account deletion after %s: %w", step, err)
+		}
+	}
+
+	deletion.Status = ACCOUNT_DELETION_STATUS_COMPLETE
+	deletion.LastError = ""
+	deletion.CompletedAt = time.Now().UTC().Unix()
+	deletion.UpdatedAt = deletion.CompletedAt
+	if err := sources.Postgres.SaveAccountDeletion(ctx, deletion); err != nil {
+		return deletion, fmt.Errorf("failed to save account deletion: %w", err)
+	}
+
+	receipt, _ := json.Marshal(deletion.Receipt)
+	log.Printf("INFO: account deletion receipt for user %s, final hash %s: %s", deletion.UserId, deletion.Receipt[len(deletion.Receipt)-1].Hash, receipt)
+	return deletion, nil
+}
+
+func runAccountDeletionStep(ctx context.Context, sources AccountDeletionSources, deletion types.AccountDeletion, step string) (int, string, error) {
+	userId := deletion.UserId
+	switch step {
+	case ACCOUNT_DELETION_STEP_API_KEYS:
+		deleted, err := sources.Postgres.DeleteAPIKeys(ctx, userId)
+		return int(deleted), "", err
+
+	case ACCOUNT_DELETION_STEP_WEBHOOKS:
+		// Deleting a subscription deletes its delivery log, queued
+		// deliveries are dropped once they're gone
+		subscriptions, err := sources.Postgres.GetWebhookSubscriptions(ctx, userId)
+		if err != nil {
+			return 0, "", err
+		}
+		deleted := 0
+		for _, subscription := range subscriptions {
+			if subscription.OwnerId != userId {
+				continue
+			}
+			if err := sources.Postgres.DeleteWebhookSubscription(ctx, subscription.Id); err != nil {
+				return 0, "", err
+			}
+			deleted++
+		}
+		return deleted, "with their delivery logs", nil
+
+	case ACCOUNT_DELETION_STEP_EVENTS:
+		return deleteUserEvents(ctx, sources.Events, userId)
+
+	case ACCOUNT_DELETION_STEP_PURCHASES:
+		purchases, err := getUserPurchases(ctx, sources.Purchases, sources.DynamoDB, userId)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, purchase := range purchases {
+			if _, err := sources.Purchases.AnonymizePurchase(ctx, sources.DynamoDB, purchase, deletion.AnonymousId); err != nil {
+				return 0, "", err
+			}
+		}
+		return len(purchases), "anonymized as " + deletion.AnonymousId, nil
+
+	case ACCOUNT_DELETION_STEP_VOTES:
+		votes, err := sources.CompetitionVotes.GetCompetitionVotesByUserId(ctx, sources.DynamoDB, userId)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, vote := range votes {
+			if err := sources.CompetitionVotes.DeleteCompetitionVote(ctx, sources.DynamoDB, vote.CompositePartitionKey, userId); err != nil {
+				return 0, "", err
+			}
+		}
+		return len(votes), "", nil
+
+	case ACCOUNT_DELETION_STEP_WAITING_ROOM:
+		participants, err := sources.WaitingRoom.GetCompetitionWaitingRoomParticipantsByUserId(ctx, sources.DynamoDB, userId)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, participant := range participants {
+			if err := sources.WaitingRoom.DeleteCompetitionWaitingRoomParticipant(ctx, sources.DynamoDB, participant.CompetitionId, userId); err != nil {
+				return 0, "", err
+			}
+		}
+		return len(participants), "", nil
+
+	case ACCOUNT_DELETION_STEP_SESHU_JOBS:
+		// GetSeshuJobs filters to the owner in the context's userInfo
+		ownerCtx := context.WithValue(ctx, "userInfo", constants.UserInfo{Sub: userId})
+		jobs, _, err := sources.Postgres.GetSeshuJobs(ownerCtx, 0, 0)
+		if err != nil {
+			return 0, "", err
+		}
+		deleted := 0
+		for _, job := range jobs {
+			if job.OwnerID != userId {
+				continue
+			}
+			if err := sources.Postgres.DeleteSeshuJob(ctx, job.NormalizedUrlKey); err != nil {
+				return 0, "", err
+			}
+			deleted++
+		}
+		// Events their Seshu jobs had held back for review
+		quarantined, err := sources.Postgres.GetQuarantinedEvents(ctx, userId)
+		if err != nil {
+			return 0, "", err
+		}
+		for _, event := range quarantined {
+			if err := sources.Postgres.DeleteQuarantinedEvent(ctx, event.Id); err != nil {
+				return 0, "", err
+			}
+		}
+		return deleted, fmt.Sprintf("%d quarantined events", len(quarantined)), nil
+
+	case ACCOUNT_DELETION_STEP_SUBDOMAIN:
+		return 0, "", sources.DeleteSubdomain(userId)
+
+	case ACCOUNT_DELETION_STEP_STRIPE:
+		customer, err := sources.Stripe.SearchCustomerByExternalID(userId)
+		if err != nil {
+			return 0, "", err
+		}
+		if customer == nil {
+			return 0, "no customer", nil
+		}
+		if err := sources.Stripe.RedactCustomer(customer.ID); err != nil {
+			return 0, "", err
+		}
+		return 1, "redacted " + customer.ID, nil
+
+	case ACCOUNT_DELETION_STEP_IDENTITY:
+		if err := sources.DeleteIdentity(userId); err != nil {
+			return 0, "", err
+		}
+		return 1, "", nil
+	}
+	return 0, "", fmt.Errorf("unknown step %q", step)
+}
+
+// deleteUserEvents takes the user off every event they own or shadow-owns.
+// Events left with no owners are deleted, shared events stay with the other
+// owners. Each page is written before the next is read, so there's no limit
+// on how many events a user can have
+func deleteUserEvents(ctx context.Context, store EventStore, userId string) (int, string, error) {
+	deleted, shared := 0, 0
+	isUser := func(id string) bool { return id == userId }
+	err := forEachUserEventPage(ctx, store, userId, func(page []types.Event) error {
+		toDelete := []string{}
+		toUpdate := []types.Event{}
+		for _, event := range page {
+			if !slices.ContainsFunc(event.EventOwners, isUser) && !slices.ContainsFunc(event.ShadowOwners, isUser) {
+				continue
+			}
+			event.EventOwners = slices.DeleteFunc(event.EventOwners, isUser)
+			event.ShadowOwners = slices.DeleteFunc(event.ShadowOwners, isUser)
+			if len(event.EventOwners) == 0 {
+				toDelete = append(toDelete, event.Id)
+			} else {
+				toUpdate = append(toUpdate, event)
+			}
+		}
+		if len(toDelete) > 0 {
+			if err := store.BulkDeleteEvents(ctx, toDelete); err != nil {
+				return err
+			}
+		}
+		if len(toUpdate) > 0 {
+			if _, err := store.UpdateEvents(ctx, toUpdate); err != nil {
+				return err
+			}
+		}
+		deleted += len(toDelete)
+		shared += len(toUpdate)
+		return nil
+	})
+	if err != nil {
+		return 0, "", err
+	}
+	return deleted + shared, fmt.Sprintf("deleted %d, removed from %d shared", deleted, shared), nil
+}
+
+// appendAccountDeletionReceipt chains the entry onto the receipt. The hash
+// covers the previous entry's hash, so changing or removing any entry breaks
+// every hash after it
+func appendAccountDeletionReceipt(receipt types.AccountDeletionReceipt, userId string, entry types.AccountDeletionReceiptEntry) types.AccountDeletionReceipt {
+	prev := ""
+	if len(receipt) > 0 {
+		prev = receipt[len(receipt)-1].Hash
+	}
+	entry.Hash = accountDeletionReceiptHash(prev, userId, entry)
+	return append(receipt, entry)
+}
+
+// VerifyAccountDeletionReceipt recomputes the receipt's hash chain and reports
+// the first entry that doesn't match
+func VerifyAccountDeletionReceipt(deletion types.AccountDeletion) error {
+	prev := ""
+	for i, entry := range deletion.Receipt {
+		if accountDeletionReceiptHash(prev, deletion.UserId, entry) != entry.Hash {
+			return fmt.Errorf("receipt entry %d (%s) has been altered", i, entry.Step)
+		}
+		prev = entry.Hash
+	}
+	return nil
+}
+
+// accountDeletionReceiptHash is an HMAC when ACCOUNT_DELETION_RECEIPT_KEY is
+// set, so only the server can produce a valid chain, and a plain SHA-256 chain
+// otherwise
+func accountDeletionReceiptHash(prev, userId string, entry types.AccountDeletionReceiptEntry) string {
+	var h hash.Hash
+	if key := os.Getenv("ACCOUNT_DELETION_RECEIPT_KEY"); key != "" {
+		h = hmac.New(sha256.New, []byte(key))
+	} else {
+		h = sha256.New()
+	}
+	h.Write([]byte(strings.Join([]string{
+		prev,
+		userId,
+		entry.Step,
+		strconv.Itoa(entry.Count),
+		entry.Detail,
+		strconv.FormatInt(entry.At, 10),
+	}, "\n")))
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,490 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dynamodb_types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/types"
+	"github.com/stripe/stripe-go/v83"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+const (
+	deletionOwnedEventId  = "2b0d8b8e-41a4-4b47-9d5e-6d6f3c1f0a01"
+	deletionSharedEventId = "2b0d8b8e-41a4-4b47-9d5e-6d6f3c1f0a02"
+)
+
+type fakeDeletionStore struct {
+	interfaces.PostgresServiceInterface
+	mu          sync.Mutex
+	deletions   map[string]types.AccountDeletion
+	seshuJobs   []types.SeshuJob
+	deletedJobs []string
+	quarantined []types.QuarantinedEvent
+	apiKeys     []types.APIKey
+	webhooks    []types.WebhookSubscription
+	deliveries  []types.WebhookDelivery
+}
+
+func (f *fakeDeletionStore) GetAccountDeletion(ctx context.Context, userId string) (*types.AccountDeletion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	deletion, ok := f.deletions[userId]
+	if !ok {
+		return nil, nil
+	}
+	return &deletion, nil
+}
+
+func (f *fakeDeletionStore) SaveAccountDeletion(ctx context.Context, deletion types.AccountDeletion) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	deletion.Receipt = append(types.AccountDeletionReceipt{}, deletion.Receipt...)
+	f.deletions[deletion.UserId] = deletion
+	return nil
+}
+
+func (f *fakeDeletionStore) GetDueAccountDeletions(ctx context.Context, now int64, statuses []string) ([]types.AccountDeletion, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	due := []types.AccountDeletion{}
+	for _, deletion := range f.deletions {
+		for _, status := range statuses {
+			if deletion.Status == status && deletion.ScheduledFor <= now {
+				due = append(due, deletion)
+			}
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeDeletionStore) GetSeshuJobs(ctx context.Context, limit, offset int) ([]types.SeshuJob, int64, error) {
+	userInfo := ctx.Value("userInfo").(constants.UserInfo)
+	jobs := []types.SeshuJob{}
+	for _, job := range f.seshuJobs {
+		if job.OwnerID == userInfo.Sub {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, int64(len(jobs)), nil
+}
+
+func (f *fakeDeletionStore) DeleteSeshuJob(ctx context.Context, id string) error {
+	f.deletedJobs = append(f.deletedJobs, id)
+	return nil
+}
+
+func (f *fakeDeletionStore) GetQuarantinedEvents(ctx context.Context, ownerId string) ([]types.QuarantinedEvent, error) {
+	events := []types.QuarantinedEvent{}
+	for _, event := range f.quarantined {
+		if event.OwnerId == ownerId {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (f *fakeDeletionStore) DeleteQuarantinedEvent(ctx context.Context, id string) error {
+	f.quarantined = slices.DeleteFunc(f.quarantined, func(event types.QuarantinedEvent) bool {
+		return event.Id == id
+	})
+	return nil
+}
+
+func (f *fakeDeletionStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (*types.APIKey, error) {
+	for _, key := range f.apiKeys {
+		if key.KeyHash == keyHash {
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeDeletionStore) TouchAPIKey(ctx context.Context, id string, usedAt int64) error {
+	return nil
+}
+
+func (f *fakeDeletionStore) DeleteAPIKeys(ctx context.Context, ownerId string) (int64, error) {
+	before := len(f.apiKeys)
+	f.apiKeys = slices.DeleteFunc(f.apiKeys, func(key types.APIKey) bool {
+		return key.OwnerId == ownerId
+	})
+	return int64(before - len(f.apiKeys)), nil
+}
+
+func (f *fakeDeletionStore) GetWebhookSubscriptions(ctx context.Context, ownerId string) ([]types.WebhookSubscription, error) {
+	subscriptions := []types.WebhookSubscription{}
+	for _, subscription := range f.webhooks {
+		if subscription.OwnerId == ownerId {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (f *fakeDeletionStore) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	f.webhooks = slices.DeleteFunc(f.webhooks, func(subscription types.WebhookSubscription) bool {
+		return subscription.Id == id
+	})
+	f.deliveries = slices.DeleteFunc(f.deliveries, func(delivery types.WebhookDelivery) bool {
+		return delivery.SubscriptionId == id
+	})
+	return nil
+}
+
+type fakeDeletionDynamo struct {
+	types.PurchaseServiceInterface
+	types.CompetitionVoteServiceInterface
+	types.CompetitionWaitingRoomParticipantServiceInterface
+	anonymized   []string
+	deletedVotes []string
+	leftRooms    []string
+	voteLookups  int
+}
+
+func (f *fakeDeletionDynamo) GetPurchasesByUserID(ctx context.Context, db types.DynamoDBAPI, userId string, limit int32, startKey string) ([]types.Purchase, map[string]dynamodb_types.AttributeValue, error) {
+	return []types.Purchase{{EventID: "event-a", UserID: userId, CompositeKey: "event-a_" + userId + "_1"}}, nil, nil
+}
+
+func (f *fakeDeletionDynamo) AnonymizePurchase(ctx context.Context, db types.DynamoDBAPI, purchase types.Purchase, anonymousUserId string) (*types.Purchase, error) {
+	f.anonymized = append(f.anonymized, purchase.CompositeKey+"->"+anonymousUserId)
+	purchase.UserID = anonymousUserId
+	return &purchase, nil
+}
+
+func (f *fakeDeletionDynamo) GetCompetitionVotesByUserId(ctx context.Context, db types.DynamoDBAPI, userId string) ([]types.CompetitionVote, error) {
+	f.voteLookups++
+	return []types.CompetitionVote{{CompositePartitionKey: "comp-1_1", UserId: userId}, {CompositePartitionKey: "comp-1_2", UserId: userId}}, nil
+}
+
+func (f *fakeDeletionDynamo) DeleteCompetitionVote(ctx context.Context, db types.DynamoDBAPI, compositePartitionKey, userId string) error {
+	f.deletedVotes = append(f.deletedVotes, compositePartitionKey)
+	return nil
+}
+
+func (f *fakeDeletionDynamo) GetCompetitionWaitingRoomParticipantsByUserId(ctx context.Context, db types.DynamoDBAPI, userId string) ([]types.CompetitionWaitingRoomParticipant, error) {
+	return []types.CompetitionWaitingRoomParticipant{{CompetitionId: "comp-2", UserId: userId}}, nil
+}
+
+func (f *fakeDeletionDynamo) DeleteCompetitionWaitingRoomParticipant(ctx context.Context, db types.DynamoDBAPI, competitionId, userId string) error {
+	f.leftRooms = append(f.leftRooms, competitionId)
+	return nil
+}
+
+type fakeDeletionStripe struct {
+	interfaces.StripeSubscriptionServiceInterface
+	err      error
+	redacted []string
+}
+
+func (f *fakeDeletionStripe) SearchCustomerByExternalID(externalID string) (*stripe.Customer, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &stripe.Customer{ID: "cus_123"}, nil
+}
+
+func (f *fakeDeletionStripe) RedactCustomer(customerID string) error {
+	f.redacted = append(f.redacted, customerID)
+	return nil
+}
+
+// weaviateDeletionRecorder serves the user's events and records what the
+// deletion removes and rewrites
+type weaviateDeletionRecorder struct {
+	mu      sync.Mutex
+	deletes []string
+	updates []models.Object
+}
+
+func newAccountDeletionTestWeaviate(t *testing.T, recorder *weaviateDeletionRecorder) *weaviate.Client {
+	start := time.Date(2030, 6, 1, 23, 0, 0, 0, time.UTC).Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/v1/graphql":
+			json.NewEncoder(w).Encode(models.GraphQLResponse{Data: map[string]models.JSONObject{
+				"Get": map[string]interface{}{EventClassName(): []interface{}{
+					map[string]interface{}{
+						"name": "Open Mic", "eventOwners": []interface{}{"user-1"}, "eventOwnerName": "Mic Club",
+						"eventSourceType": constants.ES_SINGLE_EVENT, "timezone": "America/New_York", "startTime": start,
+						"_additional": map[string]interface{}{"id": deletionOwnedEventId},
+					},
+					map[string]interface{}{
+						"name": "Group Run", "eventOwners": []interface{}{"user-1", "user-2"}, "shadowOwners": []interface{}{"user-1", "user-3"},
+						"eventOwnerName": "Run Club", "eventSourceType": constants.ES_SINGLE_EVENT, "timezone": "America/New_York", "startTime": start,
+						"_additional": map[string]interface{}{"id": deletionSharedEventId},
+					},
+				}},
+			}})
+		case r.URL.Path == "/v1/batch/objects" && r.Method == http.MethodDelete:
+			recorder.deletes = append(recorder.deletes, string(body))
+			w.Write([]byte(`{"results": {"matches": 1, "successful": 1}}`))
+		case r.URL.Path == "/v1/batch/objects" && r.Method == http.MethodPost:
+			var batch struct {
+				Objects []models.Object `json:"objects"`
+			}
+			json.Unmarshal(body, &batch)
+			recorder.updates = append(recorder.updates, batch.Objects...)
+			w.Write([]byte(`[]`))
+		default:
+			http.Error(w, "Not Found", http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := weaviate.NewClient(weaviate.Config{Host: strings.TrimPrefix(server.URL, "http://"), Scheme: "http"})
+	if err != nil {
+		t.Fatalf("failed to create weaviate client: %v", err)
+	}
+	return client
+}
+
+func TestRequestAndCancelAccountDeletion(t *testing.T) {
+	ctx := context.Background()
+	store := &fakeDeletionStore{deletions: map[string]types.AccountDeletion{}}
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	deletion, err := RequestAccountDeletion(ctx, store, "user-1", "user-1", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if deletion.Status != ACCOUNT_DELETION_STATUS_PENDING || deletion.ScheduledFor != now.Add(AccountDeletionGracePeriod).Unix() || !strings.HasPrefix(deletion.AnonymousId, "deleted-") {
+		t.Errorf("expected a pending deletion after the grace period, got %+v", deletion)
+	}
+	again, _ := RequestAccountDeletion(ctx, store, "user-1", "user-1", now.Add(time.Hour))
+	if again.RequestedAt != deletion.RequestedAt || again.AnonymousId != deletion.AnonymousId {
+		t.Errorf("expected the outstanding deletion back, got %+v", again)
+	}
+
+	if _, err := RunAccountDeletion(ctx, AccountDeletionSources{Postgres: store}, deletion, now.Add(time.Hour)); err == nil {
+		t.Errorf("expected the grace period to hold the deletion back")
+	}
+
+	canceled, err := CancelAccountDeletion(ctx, store, "user-1", now.Add(time.Hour))
+	if err != nil || canceled.Status != ACCOUNT_DELETION_STATUS_CANCELED {
+		t.Fatalf("expected the deletion canceled, got %+v, %v", canceled, err)
+	}
+	if _, err := CancelAccountDeletion(ctx, store, "user-1", now); !errors.Is(err, ErrAccountDeletionNotFound) {
+		t.Errorf("expected nothing left to cancel, got %v", err)
+	}
+
+	renewed, _ := RequestAccountDeletion(ctx, store, "user-1", "admin-1", now.Add(48*time.Hour))
+	if renewed.Status != ACCOUNT_DELETION_STATUS_PENDING || renewed.RequestedBy != "admin-1" || renewed.AnonymousId == deletion.AnonymousId {
+		t.Errorf("expected a fresh deletion after canceling, got %+v", renewed)
+	}
+
+	renewed.Receipt = appendAccountDeletionReceipt(renewed.Receipt, "user-1", types.AccountDeletionReceiptEntry{Step: ACCOUNT_DELETION_STEP_EVENTS})
+	renewed.Status = ACCOUNT_DELETION_STATUS_FAILED
+	store.SaveAccountDeletion(ctx, renewed)
+	if _, err := CancelAccountDeletion(ctx, store, "user-1", now); !errors.Is(err, ErrAccountDeletionStarted) {
+		t.Errorf("expected a started deletion to refuse canceling, got %v", err)
+	}
+}
+
+func TestRunAccountDeletion(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+	store := &fakeDeletionStore{
+		deletions:   map[string]types.AccountDeletion{},
+		seshuJobs:   []types.SeshuJob{{NormalizedUrlKey: "example.com/mine", OwnerID: "user-1"}, {NormalizedUrlKey: "example.com/theirs", OwnerID: "user-2"}},
+		quarantined: []types.QuarantinedEvent{{Id: "q-1", OwnerId: "user-1"}, {Id: "q-2", OwnerId: "user-2"}},
+		webhooks:    []types.WebhookSubscription{{Id: "hook-1", OwnerId: "user-1"}, {Id: "hook-2", OwnerId: "user-2"}},
+		deliveries:  []types.WebhookDelivery{{Id: "d-1", SubscriptionId: "hook-1", OwnerId: "user-1"}, {Id: "d-2", SubscriptionId: "hook-2", OwnerId: "user-2"}},
+	}
+	recorder := &weaviateDeletionRecorder{}
+	dynamo := &fakeDeletionDynamo{}
+	stripeService := &fakeDeletionStripe{err: errors.New("stripe is down")}
+	subdomains, identities := []string{}, []string{}
+	sources := AccountDeletionSources{
+		Events:           NewWeaviateEventStore(newAccountDeletionTestWeaviate(t, recorder)),
+		Purchases:        dynamo,
+		CompetitionVotes: dynamo,
+		WaitingRoom:      dynamo,
+		Postgres:         store,
+		Stripe:           stripeService,
+		DeleteSubdomain:  func(userId string) error { subdomains = append(subdomains, userId); return nil },
+		DeleteIdentity:   func(userId string) error { identities = append(identities, userId); return nil },
+	}
+
+	keys := map[string]string{}
+	for _, owner := range []string{"user-1", "user-2"} {
+		created, err := NewAPIKey(constants.UserInfo{Sub: owner}, types.APIKeyInsert{Name: "sync", Scopes: []string{API_KEY_SCOPE_EVENTS_WRITE}}, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		store.apiKeys = append(store.apiKeys, created.APIKey)
+		keys[owner] = created.Key
+	}
+	if _, err := AuthenticateAPIKey(ctx, store, keys["user-1"], API_KEY_SCOPE_EVENTS_WRITE, now); err != nil {
+		t.Fatalf("expected the key to authenticate before the deletion, got %v", err)
+	}
+
+	requested, err := RequestAccountDeletion(ctx, store, "user-1", "user-1", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if completed, _ := ProcessDueAccountDeletions(ctx, sources, now.Add(time.Hour)); completed != 0 || len(recorder.deletes) != 0 {
+		t.Fatalf("expected nothing to run inside the grace period")
+	}
+
+	afterGrace := now.Add(AccountDeletionGracePeriod + time.Hour)
+	completed, err := ProcessDueAccountDeletions(ctx, sources, afterGrace)
+	if err != nil || completed != 0 {
+		t.Fatalf("expected the stripe failure to stop the deletion, got %d, %v", completed, err)
+	}
+	failed, _ := store.GetAccountDeletion(ctx, "user-1")
+	if failed.Status != ACCOUNT_DELETION_STATUS_FAILED || !strings.HasPrefix(failed.LastError, ACCOUNT_DELETION_STEP_STRIPE) || len(failed.Receipt) != 8 {
+		t.Fatalf("expected the deletion to stop at stripe with 8 finished steps, got %+v", failed)
+	}
+	if len(identities) != 0 {
+		t.Errorf("expected zitadel to wait for the earlier steps")
+	}
+
+	if len(recorder.deletes) != 1 || !strings.Contains(recorder.deletes[0], deletionOwnedEventId) || strings.Contains(recorder.deletes[0], deletionSharedEventId) {
+		t.Errorf("expected only the solely owned event deleted, got %v", recorder.deletes)
+	}
+	if len(recorder.updates) != 1 {
+		t.Fatalf("expected the shared event rewritten, got %v", recorder.updates)
+	}
+	shared, _ := json.Marshal(recorder.updates[0].Properties)
+	if strings.Contains(string(shared), "user-1") || !strings.Contains(string(shared), "user-2") || !strings.Contains(string(shared), "user-3") {
+		t.Errorf("expected user-1 removed from the shared event's owners, got %s", shared)
+	}
+	if len(dynamo.anonymized) != 1 || dynamo.anonymized[0] != "event-a_user-1_1->"+requested.AnonymousId {
+		t.Errorf("expected the purchase anonymized, got %v", dynamo.anonymized)
+	}
+	if len(dynamo.deletedVotes) != 2 || len(dynamo.leftRooms) != 1 || len(subdomains) != 1 {
+		t.Errorf("expected votes, waiting rooms and subdomain cleared, got %v %v %v", dynamo.deletedVotes, dynamo.leftRooms, subdomains)
+	}
+	if len(store.deletedJobs) != 1 || store.deletedJobs[0] != "example.com/mine" {
+		t.Errorf("expected only the user's seshu job deleted, got %v", store.deletedJobs)
+	}
+	if len(store.quarantined) != 1 || store.quarantined[0].Id != "q-2" {
+		t.Errorf("expected only the user's quarantined event deleted, got %v", store.quarantined)
+	}
+	if len(store.webhooks) != 1 || store.webhooks[0].Id != "hook-2" || len(store.deliveries) != 1 || store.deliveries[0].Id != "d-2" {
+		t.Errorf("expected only the user's webhooks and their deliveries deleted, got %v %v", store.webhooks, store.deliveries)
+	}
+	if _, err := AuthenticateAPIKey(ctx, store, keys["user-1"], API_KEY_SCOPE_EVENTS_WRITE, afterGrace); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("expected the deleted user's key to stop authenticating, got %v", err)
+	}
+	if _, err := AuthenticateAPIKey(ctx, store, keys["user-2"], API_KEY_SCOPE_EVENTS_WRITE, afterGrace); err != nil {
+		t.Errorf("expected other users' keys to keep working, got %v", err)
+	}
+	if failed.Receipt[0].Step != ACCOUNT_DELETION_STEP_API_KEYS || failed.Receipt[0].Count != 1 {
+		t.Errorf("expected the receipt to record the deleted key, got %+v", failed.Receipt[0])
+	}
+
+	stripeService.err = nil
+	completed, err = ProcessDueAccountDeletions(ctx, sources, afterGrace.Add(time.Hour))
+	if err != nil || completed != 1 {
+		t.Fatalf("expected the retry to finish, got %d, %v", completed, err)
+	}
+	done, _ := store.GetAccountDeletion(ctx, "user-1")
+	if done.Status != ACCOUNT_DELETION_STATUS_COMPLETE || done.LastError != "" || done.Attempts != 2 || len(done.Receipt) != len(AccountDeletionSteps) {
+		t.Fatalf("expected a complete deletion with every step, got %+v", done)
+	}
+	if dynamo.voteLookups != 1 || len(stripeService.redacted) != 1 || len(identities) != 1 {
+		t.Errorf("expected the retry to resume at stripe, got %d vote lookups, %v, %v", dynamo.voteLookups, stripeService.redacted, identities)
+	}
+	for i, step := range AccountDeletionSteps {
+		if done.Receipt[i].Step != step {
+			t.Errorf("expected receipt entry %d to be %s, got %s", i, step, done.Receipt[i].Step)
+		}
+	}
+
+	if err := VerifyAccountDeletionReceipt(*done); err != nil {
+		t.Errorf("expected the receipt to verify, got %v", err)
+	}
+	tampered := *done
+	tampered.Receipt = append(types.AccountDeletionReceipt{}, done.Receipt...)
+	tampered.Receipt[4].Count = 0
+	if err := VerifyAccountDeletionReceipt(tampered); err == nil || !strings.Contains(err.Error(), ACCOUNT_DELETION_STEP_VOTES) {
+		t.Errorf("expected the altered entry reported, got %v", err)
+	}
+	tampered.Receipt = append(done.Receipt[:1:1], done.Receipt[2:]...)
+	if err := VerifyAccountDeletionReceipt(tampered); err == nil {
+		t.Errorf("expected a dropped entry to break the chain")
+	}
+
+	if completed, _ := ProcessDueAccountDeletions(ctx, sources, afterGrace.Add(2*time.Hour)); completed != 0 || len(identities) != 1 {
+		t.Errorf("expected a complete deletion to stay complete")
+	}
+}
+
+func TestAccountDeletionReceiptKey(t *testing.T) {
+	original := os.Getenv("ACCOUNT_DELETION_RECEIPT_KEY")
+	defer os.Setenv("ACCOUNT_DELETION_RECEIPT_KEY", original)
+
+	entry := types.AccountDeletionReceiptEntry{Step: ACCOUNT_DELETION_STEP_EVENTS, Count: 3, At: 1893456000}
+	os.Setenv("ACCOUNT_DELETION_RECEIPT_KEY", "")
+	unkeyed := types.AccountDeletion{UserId: "user-1", Receipt: appendAccountDeletionReceipt(nil, "user-1", entry)}
+
+	os.Setenv("ACCOUNT_DELETION_RECEIPT_KEY", "secret")
+	keyed := types.AccountDeletion{UserId: "user-1", Receipt: appendAccountDeletionReceipt(nil, "user-1", entry)}
+	if keyed.Receipt[0].Hash == unkeyed.Receipt[0].Hash {
+		t.Errorf("expected the key to change the hash")
+	}
+	if err := VerifyAccountDeletionReceipt(keyed); err != nil {
+		t.Errorf("expected the keyed receipt to verify, got %v", err)
+	}
+	if err := VerifyAccountDeletionReceipt(unkeyed); err == nil {
+		t.Errorf("expected a receipt made without the key to fail verification")
+	}
+}
+
+func TestDeleteUserEventsPagesPastTheSearchCap(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEventStore()
+	start := time.Now().Add(time.Hour).Unix()
+	events := []types.Event{}
+	for i := 0; i < 2*userDataPageSize+5; i++ {
+		event := types.Event{
+			Id: fmt.Sprintf("00000000-0000-4000-8000-%012d", i), Name: "Owned", EventOwners: []string{"111111"},
+			// Runs of events sharing a start time straddle the page boundaries
+			EventSourceType: constants.ES_SINGLE_EVENT, StartTime: start + int64(i/7), Timezone: *time.UTC,
+		}
+		if i%10 == 0 {
+			event.EventOwners = append(event.EventOwners, "222222")
+		}
+		events = append(events, event)
+	}
+	if err := store.BulkUpsertEvent(ctx, events); err != nil {
+		t.Fatalf("failed to seed store: %v", err)
+	}
+
+	count, detail, err := deleteUserEvents(ctx, store, "111111")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if count != len(events) || detail != "deleted 184, removed from 21 shared" {
+		t.Errorf("expected every event handled, got %d %q", count, detail)
+	}
+	left, err := getUserEvents(ctx, store, "111111")
+	if err != nil || len(left) != 0 {
+		t.Errorf("expected no events left for the user, got %d, %v", len(left), err)
+	}
+	shared, err := getUserEvents(ctx, store, "222222")
+	if err != nil || len(shared) != 21 {
+		t.Errorf("expected the shared events kept for the other owner, got %d, %v", len(shared), err)
+	}
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+// API_KEY_AUTH_PREFIX starts the `Authorization` header of a request made
+// with an API key, e.g. `Authorization: ApiKey mnm_...`
+const API_KEY_AUTH_PREFIX = "ApiKey "
+
+const (
+	API_KEY_SCOPE_EVENTS_READ        = "events:read"
+	API_KEY_SCOPE_EVENTS_WRITE       = "events:write"
+	API_KEY_SCOPE_PURCHASES_READ     = "purchases:read"
+	API_KEY_SCOPE_PURCHASES_WRITE    = "purchases:write"
+	API_KEY_SCOPE_COMPETITIONS_READ  = "competitions:read"
+	API_KEY_SCOPE_COMPETITIONS_WRITE = "competitions:write"
+	API_KEY_SCOPE_WEBHOOKS_READ      = "webhooks:read"
+	API_KEY_SCOPE_WEBHOOKS_WRITE     = "webhooks:write"
+)
+
+// APIKeyScopes is every scope a key can be given. A `:write` scope also
+// grants the matching `:read`
+var APIKeyScopes = []string{
+	API_KEY_SCOPE_EVENTS_READ,
+	API_KEY_SCOPE_EVENTS_WRITE,
+	API_KEY_SCOPE_PURCHASES_READ,
+	API_KEY_SCOPE_PURCHASES_WRITE,
+	API_KEY_SCOPE_COMPETITIONS_READ,
+	API_KEY_SCOPE_COMPETITIONS_WRITE,
+	API_KEY_SCOPE_WEBHOOKS_READ,
+	API_KEY_SCOPE_WEBHOOKS_WRITE,
+}
+
+// MAX_API_KEYS is how many unrevoked keys an owner can have
+const MAX_API_KEYS = 20
+
+const (
+	apiKeyPrefix = "mnm_"
+	// apiKeyDisplayLength is how much of a key is kept to tell keys apart
+	apiKeyDisplayLength = 12
+	// apiKeyLastUsedResolution keeps a busy key from writing its last use
+	// on every request
+	apiKeyLastUsedResolution = time.Minute
+	// apiKeyRoleCacheTTL is how long an owner's Zitadel roles are reused, and
+	// so how long a removed role keeps working through their keys
+	apiKeyRoleCacheTTL = 5 * time.Minute
+)
+
+// apiKeyResources maps the first path segment after `/api/` to the
+// resource its scopes are named after. Routes missing here, like account
+// deletion, data exports or managing API keys, need a Zitadel session
+var apiKeyResources = map[string]string{
+	"event":               "events",
+	"events":              "events",
+	"ical":                "events",
+	"quarantined-events":  "events",
+	"data":                "events",
+	"purchases":           "purchases",
+	"purchasables":        "purchases",
+	"registration-fields": "purchases",
+	"event-reg-purch":     "purchases",
+	"competition-config":  "competitions",
+	"competition-round":   "competitions",
+	"waiting-room":        "competitions",
+	"votes":               "competitions",
+	"webhooks":            "webhooks",
+}
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid or revoked API key")
+	ErrAPIKeyScope   = errors.New("API key not allowed")
+)
+
+// apiKeyRoleLookup is swapped out by tests
+var apiKeyRoleLookup = helpers.GetUserRoles
+
+type cachedAPIKeyRoles struct {
+	claims  []constants.RoleClaim
+	expires time.Time
+}
+
+var apiKeyRoleCache = struct {
+	sync.Mutex
+	owners map[string]cachedAPIKeyRoles
+}{owners: map[string]cachedAPIKeyRoles{}}
+
+// APIKeyScopeFor is the scope a request to `path` needs, empty when API
+// keys can't be used for it. GET and HEAD read, everything else writes
+func APIKeyScopeFor(method, path string) string {
+	rest, ok := strings.CutPrefix(path, "/api/")
+	if !ok {
+		return ""
+	}
+	segment, _, _ := strings.Cut(rest, "/")
+	resource, ok := apiKeyResources[segment]
+	if !ok {
+		return ""
+	}
+	if method == http.MethodGet || method == http.MethodHead {
+		return resource + ":read"
+	}
+	return resource + ":write"
+}
+
+// HasAPIKeyScope reports whether `scopes` grant `scope`
+func HasAPIKeyScope(scopes []string, scope string) bool {
+	if slices.Contains(scopes, scope) {
+		return true
+	}
+	resource, ok := strings.CutSuffix(scope, ":read")
+	return ok && slices.Contains(scopes, resource+":write")
+}
+
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAPIKey validates a new key for the user in `userInfo` and generates it.
+// The returned `Key` is the only copy, only its hash is stored
+func NewAPIKey(userInfo constants.UserInfo, insert types.APIKeyInsert, now time.Time) (types.CreatedAPIKey, error) {
+	name := strings.TrimSpace(insert.Name)
+	if name == "" {
+		return types.CreatedAPIKey{}, errors.New("name is required")
+	}
+	scopes := types.APIKeyScopes{}
+	for _, scope := range insert.Scopes {
+		if !slices.Contains(APIKeyScopes, scope) {
+			return types.CreatedAPIKey{}, fmt.Errorf("unknown scope %q, expected one of %s", scope, strings.Join(APIKeyScopes, ", "))
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return types.CreatedAPIKey{}, errors.New("at least one scope is required")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return types.CreatedAPIKey{}, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+	return types.CreatedAPIKey{
+		APIKey: types.APIKey{
+			Id:        uuid.NewString(),
+			OwnerId:   userInfo.Sub,
+			Name:      name,
+			Prefix:    key[:apiKeyDisplayLength],
+			KeyHash:   HashAPIKey(key),
+			Scopes:    scopes,
+			UserInfo:  types.APIKeyUserInfo(userInfo),
+			CreatedAt: now.Unix(),
+		},
+		Key: key,
+	}, nil
+}
+
+// AuthenticateAPIKey finds the unrevoked key `rawKey` and checks it grants
+// `scope`, as given by `APIKeyScopeFor`. Errors wrap `ErrInvalidAPIKey` or
+// `ErrAPIKeyScope` when the key is at fault
+func AuthenticateAPIKey(ctx context.Context, store interfaces.PostgresServiceInterface, rawKey, scope string, now time.Time) (*types.APIKey, error) {
+	rawKey = strings.TrimSpace(rawKey)
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	key, err := store.GetAPIKeyByHash(ctx, HashAPIKey(rawKey))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	if key == nil || key.RevokedAt != 0 {
+		return nil, ErrInvalidAPIKey
+	}
+	if scope == "" {
+		return nil, fmt.Errorf("%w: this endpoint needs a logged in session", ErrAPIKeyScope)
+	}
+	if !HasAPIKeyScope(key.Scopes, scope) {
+		return nil, fmt.Errorf("%w: this endpoint needs the %s scope", ErrAPIKeyScope, scope)
+	}
+
+	if now.Sub(time.Unix(key.LastUsedAt, 0)) >= apiKeyLastUsedResolution {
+		if err := store.TouchAPIKey(ctx, key.Id, now.Unix()); err != nil {
+			log.Printf("ERR: failed to record use of API key %s: %v", key.Id, err)
+		} else {
+			key.LastUsedAt = now.Unix()
+		}
+	}
+	return key, nil
+}
+
+// APIKeyRoleClaims are the Zitadel roles `ownerId` holds now, shaped like
+// the role claims of a session so handlers can't tell the two apart
+func APIKeyRoleClaims(ownerId string, now time.Time) ([]constants.RoleClaim, error) {
+	apiKeyRoleCache.Lock()
+	cached, ok := apiKeyRoleCache.owners[ownerId]
+	apiKeyRoleCache.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.claims, nil
+	}
+
+	roles, err := apiKeyRoleLookup(ownerId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles of %s: %w", ownerId, err)
+	}
+	claims := make([]constants.RoleClaim, 0, len(roles))
+	for _, role := range roles {
+		claims = append(claims, constants.RoleClaim{Role: role, ProjectID: *projectID})
+	}
+
+	apiKeyRoleCache.Lock()
+	apiKeyRoleCache.owners[ownerId] = cachedAPIKeyRoles{claims: claims, expires: now.Add(apiKeyRoleCacheTTL)}
+	apiKeyRoleCache.Unlock()
+	return claims, nil
+}
+
+// APIKeyRequestContext authenticates a request made with `rawKey` and adds
+// the key owner's `userInfo` and `roleClaims` to `ctx`, the same values a
+// Zitadel session provides. The status is what to answer when it fails
+func APIKeyRequestContext(ctx context.Context, store interfaces.PostgresServiceInterface, rawKey, method, path string, now time.Time) (context.Context, int, error) {
+	key, err := AuthenticateAPIKey(ctx, store, rawKey, APIKeyScopeFor(method, path), now)
+	switch {
+	case errors.Is(err, ErrInvalidAPIKey):
+		return ctx, http.StatusUnauthorized, err
+	case errors.Is(err, ErrAPIKeyScope):
+		return ctx, http.StatusForbidden, err
+	case err != nil:
+		return ctx, http.StatusInternalServerError, err
+	}
+	roleClaims, err := APIKeyRoleClaims(key.OwnerId, now)
+	if err != nil {
+		return ctx, http.StatusServiceUnavailable, err
+	}
+	ctx = context.WithValue(ctx, "userInfo", constants.UserInfo(key.UserInfo))
+	ctx = context.WithValue(ctx, "roleClaims", roleClaims)
+	ctx = context.WithValue(ctx, "apiKeyId", key.Id)
+	return ctx, http.StatusOK, nil
+}
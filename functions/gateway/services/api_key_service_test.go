@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+type fakeAPIKeyStore struct {
+	interfaces.PostgresServiceInterface
+	keys    map[string]types.APIKey
+	touched []string
+}
+
+func (f *fakeAPIKeyStore) GetAPIKeyByHash(ctx context.Context, keyHash string) (*types.APIKey, error) {
+	for _, key := range f.keys {
+		if key.KeyHash == keyHash {
+			return &key, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeAPIKeyStore) TouchAPIKey(ctx context.Context, id string, usedAt int64) error {
+	f.touched = append(f.touched, id)
+	key := f.keys[id]
+	key.LastUsedAt = usedAt
+	f.keys[id] = key
+	return nil
+}
+
+func TestAPIKeyScopeFor(t *testing.T) {
+	tests := []struct {
+		method, path, want string
+	}{
+		{http.MethodGet, "/api/events", API_KEY_SCOPE_EVENTS_READ},
+		{http.MethodPost, "/api/event", API_KEY_SCOPE_EVENTS_WRITE},
+		{http.MethodPut, "/api/events/123", API_KEY_SCOPE_EVENTS_WRITE},
+		{http.MethodGet, "/api/purchases/user/abc", API_KEY_SCOPE_PURCHASES_READ},
+		{http.MethodPost, "/api/competition-round/upsert", API_KEY_SCOPE_COMPETITIONS_WRITE},
+		{http.MethodDelete, "/api/webhooks/1", API_KEY_SCOPE_WEBHOOKS_WRITE},
+		{http.MethodGet, "/api/api-keys", ""},
+		{http.MethodPost, "/api/users/me/delete", ""},
+		{http.MethodGet, "/admin/home", ""},
+	}
+	for _, tt := range tests {
+		if got := APIKeyScopeFor(tt.method, tt.path); got != tt.want {
+			t.Errorf("APIKeyScopeFor(%s, %s) = %q, want %q", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestHasAPIKeyScope(t *testing.T) {
+	scopes := []string{API_KEY_SCOPE_EVENTS_WRITE, API_KEY_SCOPE_PURCHASES_READ}
+	if !HasAPIKeyScope(scopes, API_KEY_SCOPE_EVENTS_READ) {
+		t.Error("expected events:write to grant events:read")
+	}
+	if HasAPIKeyScope(scopes, API_KEY_SCOPE_PURCHASES_WRITE) {
+		t.Error("expected purchases:read not to grant purchases:write")
+	}
+	if HasAPIKeyScope(scopes, API_KEY_SCOPE_WEBHOOKS_READ) {
+		t.Error("expected no webhooks scope")
+	}
+}
+
+func TestNewAPIKey(t *testing.T) {
+	userInfo := constants.UserInfo{Sub: "user-1", Email: "one@example.com"}
+	now := time.Unix(1700000000, 0)
+
+	for _, insert := range []types.APIKeyInsert{
+		{Name: " ", Scopes: []string{API_KEY_SCOPE_EVENTS_READ}},
+		{Name: "sync", Scopes: []string{}},
+		{Name: "sync", Scopes: []string{"events:delete"}},
+	} {
+		if _, err := NewAPIKey(userInfo, insert, now); err == nil {
+			t.Errorf("expected %+v to be rejected", insert)
+		}
+	}
+
+	created, err := NewAPIKey(userInfo, types.APIKeyInsert{
+		Name:   " sync ",
+		Scopes: []string{API_KEY_SCOPE_EVENTS_WRITE, API_KEY_SCOPE_EVENTS_WRITE},
+	}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(created.Key, apiKeyPrefix) || len(created.Key) != len(apiKeyPrefix)+64 {
+		t.Errorf("unexpected key %q", created.Key)
+	}
+	if created.KeyHash != HashAPIKey(created.Key) || strings.Contains(created.KeyHash, created.Key) {
+		t.Errorf("expected the key to be stored hashed, got %q", created.KeyHash)
+	}
+	if created.Prefix != created.Key[:apiKeyDisplayLength] {
+		t.Errorf("unexpected prefix %q", created.Prefix)
+	}
+	if created.Name != "sync" || created.OwnerId != "user-1" || created.CreatedAt != now.Unix() {
+		t.Errorf("unexpected key %+v", created.APIKey)
+	}
+	if len(created.Scopes) != 1 {
+		t.Errorf("expected duplicate scopes to be dropped, got %v", created.Scopes)
+	}
+	if created.UserInfo.Email != "one@example.com" {
+		t.Errorf("expected the owner's user info to be kept, got %+v", created.UserInfo)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	store := &fakeAPIKeyStore{keys: map[string]types.APIKey{
+		"key-1": {Id: "key-1", OwnerId: "user-1", KeyHash: HashAPIKey("mnm_active"), Scopes: types.APIKeyScopes{API_KEY_SCOPE_EVENTS_WRITE}},
+		"key-2": {Id: "key-2", OwnerId: "user-1", KeyHash: HashAPIKey("mnm_revoked"), Scopes: types.APIKeyScopes{API_KEY_SCOPE_EVENTS_WRITE}, RevokedAt: now.Unix() - 10},
+	}}
+	ctx := context.Background()
+
+	for _, rawKey := range []string{"", "not-a-key", "mnm_unknown", "mnm_revoked"} {
+		if _, err := AuthenticateAPIKey(ctx, store, rawKey, API_KEY_SCOPE_EVENTS_READ, now); !errors.Is(err, ErrInvalidAPIKey) {
+			t.Errorf("%q: expected ErrInvalidAPIKey, got %v", rawKey, err)
+		}
+	}
+	for _, scope := range []string{"", API_KEY_SCOPE_PURCHASES_READ} {
+		if _, err := AuthenticateAPIKey(ctx, store, "mnm_active", scope, now); !errors.Is(err, ErrAPIKeyScope) {
+			t.Errorf("%q: expected ErrAPIKeyScope, got %v", scope, err)
+		}
+	}
+
+	key, err := AuthenticateAPIKey(ctx, store, "mnm_active", API_KEY_SCOPE_EVENTS_READ, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.Id != "key-1" || key.LastUsedAt != now.Unix() {
+		t.Errorf("unexpected key %+v", key)
+	}
+	if _, err := AuthenticateAPIKey(ctx, store, "mnm_active", API_KEY_SCOPE_EVENTS_WRITE, now.Add(30*time.Second)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := AuthenticateAPIKey(ctx, store, "mnm_active", API_KEY_SCOPE_EVENTS_WRITE, now.Add(2*time.Minute)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(store.touched) != 2 {
+		t.Errorf("expected the last use to be recorded at most once a minute, got %v", store.touched)
+	}
+}
+
+func TestAPIKeyRequestContext(t *testing.T) {
+	originalLookup, originalProjectID := apiKeyRoleLookup, projectID
+	projectID = &[]string{"test-project"}[0]
+	lookups := 0
+	apiKeyRoleLookup = func(userID string) ([]string, error) {
+		lookups++
+		if userID == "user-broken" {
+			return nil, errors.New("zitadel is down")
+		}
+		return []string{string(constants.EventAdmin)}, nil
+	}
+	apiKeyRoleCache.owners = map[string]cachedAPIKeyRoles{}
+	defer func() {
+		apiKeyRoleLookup, projectID = originalLookup, originalProjectID
+		apiKeyRoleCache.owners = map[string]cachedAPIKeyRoles{}
+	}()
+
+	now := time.Unix(1700000000, 0)
+	store := &fakeAPIKeyStore{keys: map[string]types.APIKey{
+		"key-1": {Id: "key-1", OwnerId: "user-1", KeyHash: HashAPIKey("mnm_one"), Scopes: types.APIKeyScopes{API_KEY_SCOPE_EVENTS_WRITE}, UserInfo: types.APIKeyUserInfo{Sub: "user-1"}},
+		"key-2": {Id: "key-2", OwnerId: "user-broken", KeyHash: HashAPIKey("mnm_two"), Scopes: types.APIKeyScopes{API_KEY_SCOPE_EVENTS_WRITE}},
+	}}
+
+	tests := []struct {
+		name, rawKey, method, path string
+		want                       int
+	}{
+		{"unknown key", "mnm_nope", http.MethodGet, "/api/events", http.StatusUnauthorized},
+		{"missing scope", "mnm_one", http.MethodGet, "/api/purchases/user/1", http.StatusForbidden},
+		{"session only route", "mnm_one", http.MethodGet, "/api/api-keys", http.StatusForbidden},
+		{"roles unavailable", "mnm_two", http.MethodGet, "/api/events", http.StatusServiceUnavailable},
+		{"allowed", "mnm_one", http.MethodPost, "/api/event", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, status, _ := APIKeyRequestContext(context.Background(), store, tt.rawKey, tt.method, tt.path, now)
+			if status != tt.want {
+				t.Fatalf("expected status %d, got %d", tt.want, status)
+			}
+			if status != http.StatusOK {
+				return
+			}
+			userInfo, _ := ctx.Value("userInfo").(constants.UserInfo)
+			if userInfo.Sub != "user-1" {
+				t.Errorf("expected userInfo of user-1, got %+v", userInfo)
+			}
+			if apiKeyId, _ := ctx.Value("apiKeyId").(string); apiKeyId != "key-1" {
+				t.Errorf("expected apiKeyId key-1, got %q", apiKeyId)
+			}
+			roleClaims, _ := ctx.Value("roleClaims").([]constants.RoleClaim)
+			if len(roleClaims) != 1 || roleClaims[0].Role != string(constants.EventAdmin) || roleClaims[0].ProjectID != "test-project" {
+				t.Errorf("unexpected roleClaims %+v", roleClaims)
+			}
+		})
+	}
+
+	lookups = 0
+	for i := 0; i < 3; i++ {
+		if _, status, err := APIKeyRequestContext(context.Background(), store, "mnm_one", http.MethodGet, "/api/events", now.Add(time.Minute)); status != http.StatusOK {
+			t.Fatalf("expected status %d, got %d: %v", http.StatusOK, status, err)
+		}
+	}
+	if lookups != 0 {
+		t.Errorf("expected cached roles to be reused, got %d lookups", lookups)
+	}
+	if _, _, err := APIKeyRequestContext(context.Background(), store, "mnm_one", http.MethodGet, "/api/events", now.Add(apiKeyRoleCacheTTL)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lookups != 1 {
+		t.Errorf("expected roles to be looked up again once the cache expires, got %d lookups", lookups)
+	}
+}
@@ -0,0 +1,652 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/meetnearme/api/functions/gateway/constants"
+)
+
+const (
+	// Uploads larger than this are rejected before parsing
+	MaxCSVImportBytes = 5 << 20
+	// Files are validated within a request, so keep them to a size that
+	// validates in one
+	MaxCSVImportRows = 1000
+	// MaxCSVImportGeocodes is how many distinct addresses of a file are
+	// looked up, every lookup that isn't cached is a paid render. Rows past it
+	// need lat and long columns
+	MaxCSVImportGeocodes = 100
+	// Rows written per BulkUpsertEvent call, progress is reported
+	// after each one
+	CSVImportChunkSize = 50
+
+	CSV_IMPORT_ACTION_INSERT = "insert"
+	CSV_IMPORT_ACTION_UPDATE = "update"
+
+	// csvGeocodeWorkers bounds the address lookups running at once
+	csvGeocodeWorkers = 5
+)
+
+var errCSVGeocodeLimit = fmt.Errorf("the file has more than %d addresses to look up, add lat and long columns", MaxCSVImportGeocodes)
+
+// CSVImportField is an event field a spreadsheet column can be mapped to.
+// Columns whose normalized header matches one of `aliases` map to it unless
+// the upload says otherwise
+type CSVImportField struct {
+	Key      string `json:"key"`
+	Label    string `json:"label"`
+	Required bool   `json:"required"`
+	aliases  []string
+}
+
+var CSVImportFields = []CSVImportField{
+	{Key: "id", Label: "External ID", aliases: []string{"id", "externalid", "eventid", "uid", "ref", "reference"}},
+	{Key: "name", Label: "Name", Required: true, aliases: []string{"name", "title", "eventname", "eventtitle", "event", "summary"}},
+	{Key: "description", Label: "Description", aliases: []string{"description", "details", "about", "body", "notes"}},
+	{Key: "date", Label: "Date", aliases: []string{"date", "eventdate", "day", "startdate"}},
+	{Key: "startTime", Label: "Start", Required: true, aliases: []string{"starttime", "start", "startsat", "startdatetime", "begins", "time"}},
+	{Key: "endTime", Label: "End", aliases: []string{"endtime", "end", "endsat", "enddatetime", "finish"}},
+	{Key: "address", Label: "Address", Required: true, aliases: []string{"address", "location", "venue", "venueaddress", "where", "place"}},
+	{Key: "lat", Label: "Latitude", aliases: []string{"lat", "latitude"}},
+	{Key: "long", Label: "Longitude", aliases: []string{"long", "lng", "lon", "longitude"}},
+	{Key: "timezone", Label: "Timezone", aliases: []string{"timezone", "tz", "timezonename"}},
+	{Key: "imageUrl", Label: "Image URL", aliases: []string{"imageurl", "image", "imagelink", "photo", "picture"}},
+	{Key: "sourceUrl", Label: "Event URL", aliases: []string{"sourceurl", "url", "link", "website", "eventurl", "ticketurl", "tickets"}},
+	{Key: "categories", Label: "Categories", aliases: []string{"categories", "category"}},
+	{Key: "tags", Label: "Tags", aliases: []string{"tags", "keywords"}},
+}
+
+// CSVImportOptions scopes an import to the uploading owner and file
+type CSVImportOptions struct {
+	OwnerId   string
+	OwnerName string
+	FileName  string
+	// Mapping maps column headers to CSVImportField keys. Columns it leaves
+	// out are matched by header, an empty key ignores the column
+	Mapping map[string]string
+	// Progress is called after each committed chunk
+	Progress func(committed, total int)
+}
+
+type CSVImportRow struct {
+	// Row is the 1-based line in the file, the header is row 1
+	Row       int      `json:"row"`
+	EventId   string   `json:"eventId,omitempty"`
+	Action    string   `json:"action,omitempty"`
+	Name      string   `json:"name,omitempty"`
+	StartTime string   `json:"startTime,omitempty"`
+	Address   string   `json:"address,omitempty"`
+	Timezone  string   `json:"timezone,omitempty"`
+	Errors    []string `json:"errors,omitempty"`
+	Warnings  []string `json:"warnings,omitempty"`
+}
+
+type CSVImportReport struct {
+	FileName string            `json:"fileName"`
+	Columns  []string          `json:"columns"`
+	Mapping  map[string]string `json:"mapping"`
+	// MissingFields lists required fields no column is mapped to, rows are
+	// only validated once it's empty
+	MissingFields []string       `json:"missingFields,omitempty"`
+	Rows          []CSVImportRow `json:"rows"`
+	Valid         int            `json:"valid"`
+	Invalid       int            `json:"invalid"`
+	Inserts       int            `json:"inserts"`
+	Updates       int            `json:"updates"`
+	Committed     int            `json:"committed"`
+	DryRun        bool           `json:"dryRun"`
+}
+
+// CSVImportEventId derives a stable Weaviate UUID for a row so re-uploading
+// the same file updates events in place. `key` is the row's external ID, or
+// its name, start and address when the file has none
+func CSVImportEventId(ownerId, key string) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(ownerId+"|csv|"+key)).String()
+}
+
+// ParseCSVImportFile reads the header row and records of an uploaded file
+func ParseCSVImportFile(data []byte) ([]string, [][]string, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse CSV: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("the file is empty")
+	}
+	columns := make([]string, len(records[0]))
+	for i, column := range records[0] {
+		columns[i] = strings.TrimSpace(column)
+	}
+	rows := [][]string{}
+	for _, record := range records[1:] {
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+		rows = append(rows, record)
+	}
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("the file has no rows below the header")
+	}
+	if len(rows) > MaxCSVImportRows {
+		return nil, nil, fmt.Errorf("the file has %d rows, split it into files of at most %d", len(rows), MaxCSVImportRows)
+	}
+	return columns, rows, nil
+}
+
+func normalizeCSVHeader(header string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(header) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ResolveCSVImportMapping combines the explicit `mapping` with header
+// matching and returns the required fields still unmapped
+func ResolveCSVImportMapping(columns []string, mapping map[string]string) (map[string]string, []string, error) {
+	fields := map[string]bool{}
+	for _, field := range CSVImportFields {
+		fields[field.Key] = true
+	}
+	known := map[string]bool{}
+	for _, column := range columns {
+		known[column] = true
+	}
+
+	resolved := map[string]string{}
+	taken := map[string]string{}
+	for column, key := range mapping {
+		if !known[column] {
+			return nil, nil, fmt.Errorf("mapped column '%s' is not in the file", column)
+		}
+		resolved[column] = key
+		if key == "" {
+			continue
+		}
+		if !fields[key] {
+			return nil, nil, fmt.Errorf("column '%s' is mapped to unknown field '%s'", column, key)
+		}
+		if other, ok := taken[key]; ok {
+			return nil, nil, fmt.Errorf("columns '%s' and '%s' are both mapped to '%s'", other, column, key)
+		}
+		taken[key] = column
+	}
+
+	for _, column := range columns {
+		if _, ok := resolved[column]; ok {
+			continue
+		}
+		resolved[column] = ""
+		header := normalizeCSVHeader(column)
+		for _, field := range CSVImportFields {
+			if _, ok := taken[field.Key]; ok {
+				continue
+			}
+			if slices.Contains(field.aliases, header) {
+				resolved[column] = field.Key
+				taken[field.Key] = column
+				break
+			}
+		}
+	}
+
+	missing := []string{}
+	for _, field := range CSVImportFields {
+		if _, ok := taken[field.Key]; field.Required && !ok {
+			missing = append(missing, field.Key)
+		}
+	}
+	return resolved, missing, nil
+}
+
+var (
+	csvDateTimeLayouts = []string{
+		"2006-01-02T15:04:05", "2006-01-02T15:04", "2006-01-02 15:04:05", "2006-01-02 15:04",
+		"2006-01-02 3:04 PM", "2006-01-02 3:04PM", "1/2/2006 15:04:05", "1/2/2006 15:04",
+		"1/2/2006 3:04:05 PM", "1/2/2006 3:04 PM", "1/2/2006 3:04PM", "Jan 2, 2006 3:04 PM", "January 2, 2006 3:04 PM",
+	}
+	csvDateLayouts  = []string{"2006-01-02", "1/2/2006", "Jan 2, 2006", "January 2, 2006", "Mon, Jan 2, 2006"}
+	csvClockLayouts = []string{"15:04", "15:04:05", "3:04 PM", "3:04PM", "3:04:05 PM", "3 PM", "3PM"}
+)
+
+// parseCSVTime reads a spreadsheet date and/or time of day as wall clock time
+// in `loc`, values with a UTC offset keep it
+func parseCSVTime(value string, loc *time.Location) (t time.Time, hasDate, hasClock bool, err error) {
+	value = strings.ToUpper(strings.Join(strings.Fields(value), " "))
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.In(loc), true, true, nil
+	}
+	for _, layout := range csvDateTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true, true, nil
+		}
+	}
+	for _, layout := range csvDateLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true, false, nil
+		}
+	}
+	for _, layout := range csvClockLayouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, false, true, nil
+		}
+	}
+	return time.Time{}, false, false, fmt.Errorf("unrecognized date/time '%s'", value)
+}
+
+// resolveCSVTime combines a time column with the row's date column when the
+// time column only holds a time of day
+func resolveCSVTime(value, dateValue string, loc *time.Location) (t time.Time, clockOnly bool, err error) {
+	t, hasDate, hasClock, err := parseCSVTime(value, loc)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if hasDate && hasClock {
+		return t, false, nil
+	}
+	if !hasClock {
+		return time.Time{}, false, fmt.Errorf("'%s' has no time of day", value)
+	}
+	if dateValue == "" {
+		return time.Time{}, false, fmt.Errorf("'%s' has no date and the row has no date column", value)
+	}
+	date, hasDate, _, err := parseCSVTime(dateValue, loc)
+	if err != nil || !hasDate {
+		return time.Time{}, false, fmt.Errorf("invalid date '%s'", dateValue)
+	}
+	return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(), 0, loc), true, nil
+}
+
+func splitCSVList(value string) []string {
+	items := []string{}
+	for _, item := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ';' || r == '|' }) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// csvCategoryNames maps lowercased category and subcategory names to their
+// canonical spelling
+func csvCategoryNames() map[string]string {
+	names := map[string]string{}
+	for _, category := range constants.Categories {
+		names[strings.ToLower(category.Name)] = category.Name
+		for _, item := range category.Items {
+			names[strings.ToLower(item.Name)] = item.Name
+		}
+	}
+	return names
+}
+
+func isCSVHttpUrl(value string) bool {
+	parsed, err := url.Parse(value)
+	return err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") && parsed.Host != ""
+}
+
+// csvRecordField is the value of the column mapped to `key`
+func csvRecordField(record []string, fieldIndex map[string]int, key string) string {
+	i, ok := fieldIndex[key]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// parseCSVCoordinates is false unless both values are in range
+func parseCSVCoordinates(latValue, longValue string) (float64, float64, bool) {
+	lat, latErr := strconv.ParseFloat(latValue, 64)
+	long, longErr := strconv.ParseFloat(longValue, 64)
+	if latErr != nil || longErr != nil || lat < -90 || lat > 90 || long < -180 || long > 180 {
+		return 0, 0, false
+	}
+	return lat, long, true
+}
+
+type csvGeocodeResult struct {
+	lat, long float64
+	err       error
+}
+
+// geocodeCSVAddresses looks up the distinct addresses of rows without
+// coordinates, `csvGeocodeWorkers` at a time. Addresses past
+// `MaxCSVImportGeocodes` get `errCSVGeocodeLimit` instead
+func geocodeCSVAddresses(ctx context.Context, records [][]string, fieldIndex map[string]int) map[string]csvGeocodeResult {
+	results := map[string]csvGeocodeResult{}
+	addresses := []string{}
+	for _, record := range records {
+		address := csvRecordField(record, fieldIndex, "address")
+		if address == "" {
+			continue
+		}
+		if _, _, ok := parseCSVCoordinates(csvRecordField(record, fieldIndex, "lat"), csvRecordField(record, fieldIndex, "long")); ok {
+			continue
+		}
+		if _, seen := results[address]; seen {
+			continue
+		}
+		if len(addresses) >= MaxCSVImportGeocodes {
+			results[address] = csvGeocodeResult{err: errCSVGeocodeLimit}
+			continue
+		}
+		results[address] = csvGeocodeResult{}
+		addresses = append(addresses, address)
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	queue := make(chan string)
+	for range min(csvGeocodeWorkers, len(addresses)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for address := range queue {
+				result := csvGeocodeResult{err: ctx.Err()}
+				if result.err == nil {
+					result.lat, result.long, _, result.err = geocodeLocation(address)
+				}
+				mu.Lock()
+				results[address] = result
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, address := range addresses {
+		queue <- address
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+// csvRecordToRawEvent maps one record to a RawEvent, collecting every
+// problem with it in the returned row rather than stopping at the first.
+// `geocoded` has the coordinates of its address when it has none of its own
+func csvRecordToRawEvent(record []string, fieldIndex map[string]int, opts CSVImportOptions, categoryNames map[string]string, geocoded map[string]csvGeocodeResult) (RawEvent, CSVImportRow) {
+	row := CSVImportRow{}
+	get := func(key string) string {
+		return csvRecordField(record, fieldIndex, key)
+	}
+	addError := func(format string, args ...interface{}) {
+		row.Errors = append(row.Errors, fmt.Sprintf(format, args...))
+	}
+
+	row.Name = get("name")
+	if row.Name == "" {
+		addError("name is empty")
+	}
+	row.Address = get("address")
+
+	lat, long := 0.0, 0.0
+	hasCoordinates := false
+	if latValue, longValue := get("lat"), get("long"); latValue != "" || longValue != "" {
+		if lat, long, hasCoordinates = parseCSVCoordinates(latValue, longValue); !hasCoordinates {
+			addError("invalid coordinates '%s, %s'", latValue, longValue)
+		}
+	}
+	if row.Address == "" {
+		addError("address is empty")
+	} else if !hasCoordinates {
+		if result, ok := geocoded[row.Address]; !ok {
+			addError("couldn't find address '%s': it wasn't looked up", row.Address)
+		} else if result.err != nil {
+			addError("couldn't find address '%s': %v", row.Address, result.err)
+		} else {
+			lat, long, hasCoordinates = result.lat, result.long, true
+		}
+	}
+
+	row.Timezone = get("timezone")
+	if row.Timezone == "" && hasCoordinates {
+		row.Timezone = DeriveTimezoneFromCoordinates(lat, long)
+	}
+	var loc *time.Location
+	if row.Timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(row.Timezone); err != nil {
+			addError("unknown timezone '%s'", row.Timezone)
+		}
+	} else if hasCoordinates {
+		addError("couldn't determine a timezone, add a timezone column")
+	}
+
+	var start, end time.Time
+	if loc != nil {
+		var err error
+		var startClockOnly bool
+		if value := get("startTime"); value == "" {
+			addError("start is empty")
+		} else if start, startClockOnly, err = resolveCSVTime(value, get("date"), loc); err != nil {
+			addError("invalid start: %v", err)
+		} else {
+			row.StartTime = start.Format(time.RFC3339)
+		}
+		if value := get("endTime"); value != "" && !start.IsZero() {
+			var endClockOnly bool
+			if end, endClockOnly, err = resolveCSVTime(value, start.Format("2006-01-02"), loc); err != nil {
+				addError("invalid end: %v", err)
+			} else if !end.After(start) {
+				// "10 PM" to "1 AM" runs past midnight
+				if startClockOnly || endClockOnly {
+					end = end.AddDate(0, 0, 1)
+				} else {
+					addError("end is not after start")
+				}
+			}
+		}
+	}
+
+	description := get("description")
+	if description == "" {
+		description = row.Name
+	}
+	raw := RawEvent{
+		RawEventData: RawEventData{
+			EventOwners:     []string{opts.OwnerId},
+			EventOwnerName:  opts.OwnerName,
+			EventSourceType: constants.ES_SINGLE_EVENT,
+			Name:            row.Name,
+			Description:     description,
+			Address:         row.Address,
+			Lat:             lat,
+			Long:            long,
+			Timezone:        row.Timezone,
+		},
+	}
+	sourceKey := "csv-upload:" + opts.FileName
+	raw.EventSourceId = &sourceKey
+	if !start.IsZero() {
+		raw.StartTime = start.UTC().Format(time.RFC3339)
+	}
+	if !end.IsZero() {
+		raw.EndTime = end.UTC().Format(time.RFC3339)
+	}
+
+	for _, key := range []string{"imageUrl", "sourceUrl"} {
+		value := get(key)
+		if value == "" {
+			continue
+		}
+		if !isCSVHttpUrl(value) {
+			row.Warnings = append(row.Warnings, fmt.Sprintf("ignored %s '%s', it isn't an http(s) link", key, value))
+			continue
+		}
+		if key == "imageUrl" {
+			raw.ImageUrl = &value
+		} else {
+			raw.SourceUrl = &value
+		}
+	}
+
+	categories := []string{}
+	tags := splitCSVList(get("tags"))
+	for _, category := range splitCSVList(get("categories")) {
+		if name, ok := categoryNames[strings.ToLower(category)]; ok {
+			categories = append(categories, name)
+		} else {
+			tags = append(tags, category)
+			row.Warnings = append(row.Warnings, fmt.Sprintf("'%s' isn't a known category, imported as a tag", category))
+		}
+	}
+	if len(categories) > 0 {
+		raw.Categories = &categories
+	}
+	if len(tags) > 0 {
+		raw.Tags = &tags
+	}
+
+	key := get("id")
+	if key == "" && !start.IsZero() {
+		key = strings.ToLower(row.Name) + "|" + strconv.FormatInt(start.Unix(), 10) + "|" + strings.ToLower(row.Address)
+	}
+	if key != "" {
+		raw.Id = CSVImportEventId(opts.OwnerId, key)
+		row.EventId = raw.Id
+	}
+	return raw, row
+}
+
+// PrepareCSVImport maps, geocodes and validates every row of `data` without
+// writing anything. It returns the per-row report and the RawEvents of the
+// rows that passed, ready for CommitCSVImport
+func PrepareCSVImport(ctx context.Context, store EventStore, data []byte, opts CSVImportOptions) (CSVImportReport, []RawEvent, error) {
+	report := CSVImportReport{FileName: opts.FileName, Rows: []CSVImportRow{}, DryRun: true}
+	if opts.OwnerId == "" {
+		return report, nil, fmt.Errorf("CSV import requires an owner")
+	}
+	if opts.OwnerName == "" {
+		opts.OwnerName = opts.OwnerId
+	}
+
+	columns, records, err := ParseCSVImportFile(data)
+	if err != nil {
+		return report, nil, err
+	}
+	report.Columns = columns
+	report.Mapping, report.MissingFields, err = ResolveCSVImportMapping(columns, opts.Mapping)
+	if err != nil {
+		return report, nil, err
+	}
+	if len(report.MissingFields) > 0 {
+		return report, nil, nil
+	}
+
+	fieldIndex := map[string]int{}
+	for i, column := range columns {
+		if key := report.Mapping[column]; key != "" {
+			fieldIndex[key] = i
+		}
+	}
+
+	categoryNames := csvCategoryNames()
+	geocoded := geocodeCSVAddresses(ctx, records, fieldIndex)
+	rawEvents := []RawEvent{}
+	rowIndexes := []int{}
+	seenIds := map[string]int{}
+	for i, record := range records {
+		raw, row := csvRecordToRawEvent(record, fieldIndex, opts, categoryNames, geocoded)
+		row.Row = i + 2
+		if len(row.Errors) == 0 {
+			if _, _, err := SingleValidateEvent(raw, true); err != nil {
+				row.Errors = append(row.Errors, err.Error())
+			}
+		}
+		if other, ok := seenIds[row.EventId]; ok && row.EventId != "" {
+			row.Errors = append(row.Errors, fmt.Sprintf("same event as row %d", other))
+		} else if row.EventId != "" {
+			seenIds[row.EventId] = row.Row
+		}
+		if len(row.Errors) == 0 {
+			rawEvents = append(rawEvents, raw)
+			rowIndexes = append(rowIndexes, len(report.Rows))
+			report.Valid++
+		} else {
+			report.Invalid++
+		}
+		report.Rows = append(report.Rows, row)
+	}
+
+	existingIds := map[string]bool{}
+	for start := 0; start < len(rawEvents); start += CSVImportChunkSize {
+		end := min(start+CSVImportChunkSize, len(rawEvents))
+		ids := []string{}
+		for _, raw := range rawEvents[start:end] {
+			ids = append(ids, raw.Id)
+		}
+		existing, err := store.BulkGetEventByID(ctx, ids, "")
+		if err != nil {
+			return report, nil, fmt.Errorf("failed to look up previously imported events: %w", err)
+		}
+		for _, event := range existing {
+			existingIds[event.Id] = true
+		}
+	}
+	for _, i := range rowIndexes {
+		if existingIds[report.Rows[i].EventId] {
+			report.Rows[i].Action = CSV_IMPORT_ACTION_UPDATE
+			report.Updates++
+		} else {
+			report.Rows[i].Action = CSV_IMPORT_ACTION_INSERT
+			report.Inserts++
+		}
+	}
+	return report, rawEvents, nil
+}
+
+// CommitCSVImport validates `rawEvents` with BulkValidateEvents and upserts
+// them in chunks, reporting progress after each. It returns how many were
+// written, which is less than len(rawEvents) when a chunk fails
+func CommitCSVImport(ctx context.Context, store EventStore, rawEvents []RawEvent, opts CSVImportOptions) (int, error) {
+	if len(rawEvents) == 0 {
+		return 0, nil
+	}
+	events, _, err := BulkValidateEvents(rawEvents, true)
+	if err != nil {
+		return 0, err
+	}
+	committed := 0
+	for start := 0; start < len(events); start += CSVImportChunkSize {
+		end := min(start+CSVImportChunkSize, len(events))
+		if err := store.BulkUpsertEvent(ctx, events[start:end]); err != nil {
+			return committed, fmt.Errorf("failed to import rows %d-%d: %w", start+1, end, err)
+		}
+		committed = end
+		if opts.Progress != nil {
+			opts.Progress(committed, len(events))
+		}
+	}
+	log.Printf("INFO: CSV import of '%s' for %s committed %d events", opts.FileName, opts.OwnerId, committed)
+	return committed, nil
+}
+
+// ImportCSVEvents validates `data` and, unless `dryRun`, commits the rows
+// that passed. Rows that failed are reported and skipped
+func ImportCSVEvents(ctx context.Context, store EventStore, data []byte, opts CSVImportOptions, dryRun bool) (CSVImportReport, error) {
+	report, rawEvents, err := PrepareCSVImport(ctx, store, data, opts)
+	if err != nil || dryRun || len(report.MissingFields) > 0 {
+		return report, err
+	}
+	report.DryRun = false
+	report.Committed, err = CommitCSVImport(ctx, store, rawEvents, opts)
+	return report, err
+}
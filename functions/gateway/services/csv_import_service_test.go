@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+)
+
+func TestParseCSVImportFile(t *testing.T) {
+	columns, rows, err := ParseCSVImportFile([]byte("\xef\xbb\xbfTitle , Start\n\"Trivia, Night\",2030-06-01 19:00\n,\n\nBingo,2030-06-02 19:00\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(columns, "|") != "Title|Start" {
+		t.Errorf("expected BOM and padding stripped from headers, got %q", columns)
+	}
+	if len(rows) != 2 || rows[0][0] != "Trivia, Night" || rows[1][0] != "Bingo" {
+		t.Errorf("expected blank rows skipped, got %q", rows)
+	}
+
+	if _, _, err := ParseCSVImportFile([]byte("Title,Start\n")); err == nil {
+		t.Errorf("expected an error for a file without rows")
+	}
+	tooMany := "Title\n" + strings.Repeat("Event\n", MaxCSVImportRows+1)
+	if _, _, err := ParseCSVImportFile([]byte(tooMany)); err == nil || !strings.Contains(err.Error(), fmt.Sprint(MaxCSVImportRows)) {
+		t.Errorf("expected a row limit error, got %v", err)
+	}
+}
+
+func TestResolveCSVImportMapping(t *testing.T) {
+	columns := []string{"Event Title", "Start Date", "Start Time", "Venue", "Notes", "Organizer"}
+
+	t.Run("matches headers", func(t *testing.T) {
+		mapping, missing, err := ResolveCSVImportMapping(columns, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := map[string]string{"Event Title": "name", "Start Date": "date", "Start Time": "startTime", "Venue": "address", "Notes": "description", "Organizer": ""}
+		for column, key := range want {
+			if mapping[column] != key {
+				t.Errorf("column %q mapped to %q, want %q", column, mapping[column], key)
+			}
+		}
+		if len(missing) != 0 {
+			t.Errorf("expected no missing fields, got %v", missing)
+		}
+	})
+
+	t.Run("explicit mapping wins", func(t *testing.T) {
+		mapping, missing, err := ResolveCSVImportMapping(columns, map[string]string{"Organizer": "description", "Notes": "", "Venue": ""})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if mapping["Organizer"] != "description" || mapping["Notes"] != "" {
+			t.Errorf("unexpected mapping %v", mapping)
+		}
+		if strings.Join(missing, ",") != "address" {
+			t.Errorf("expected address missing once Venue is ignored, got %v", missing)
+		}
+	})
+
+	for name, mapping := range map[string]map[string]string{
+		"unknown column": {"Nope": "name"},
+		"unknown field":  {"Venue": "venueName"},
+		"field twice":    {"Venue": "name", "Event Title": "name"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := ResolveCSVImportMapping(columns, mapping); err == nil {
+				t.Errorf("expected an error for %v", mapping)
+			}
+		})
+	}
+}
+
+func TestResolveCSVTime(t *testing.T) {
+	chicago, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	tests := []struct {
+		value, date string
+		want        string
+		clockOnly   bool
+		wantErr     bool
+	}{
+		{value: "2030-06-01 19:00", want: "2030-06-01T19:00:00-05:00"},
+		{value: "6/1/2030 7:30 pm", want: "2030-06-01T19:30:00-05:00"},
+		{value: "2030-06-01T19:00:00Z", want: "2030-06-01T14:00:00-05:00"},
+		{value: "7 PM", date: "June 1, 2030", want: "2030-06-01T19:00:00-05:00", clockOnly: true},
+		{value: "19:00", date: "2030-06-01", want: "2030-06-01T19:00:00-05:00", clockOnly: true},
+		{value: "19:00", wantErr: true},
+		{value: "2030-06-01", wantErr: true},
+		{value: "next tuesday", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value+"|"+tt.date, func(t *testing.T) {
+			got, clockOnly, err := resolveCSVTime(tt.value, tt.date, chicago)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Format(time.RFC3339) != tt.want || clockOnly != tt.clockOnly {
+				t.Errorf("got %s (clock only %v), want %s (%v)", got.Format(time.RFC3339), clockOnly, tt.want, tt.clockOnly)
+			}
+		})
+	}
+}
+
+func TestCSVRecordToRawEvent(t *testing.T) {
+	origEnv := os.Getenv("GO_ENV")
+	defer os.Setenv("GO_ENV", origEnv)
+	os.Setenv("GO_ENV", "test")
+
+	opts := CSVImportOptions{OwnerId: "owner-1", OwnerName: "Quiz Club", FileName: "events.csv"}
+	fieldIndex := map[string]int{"name": 0, "date": 1, "startTime": 2, "endTime": 3, "address": 4, "categories": 5, "sourceUrl": 6}
+	categoryNames := csvCategoryNames()
+	knownCategory := constants.Categories[0].Name
+
+	record := []string{"Late Trivia", "2030-06-01", "10:00 PM", "1:00 AM", "The Tavern, New York", strings.ToUpper(knownCategory) + "; Pub Games", "not a url"}
+	geocoded := geocodeCSVAddresses(context.Background(), [][]string{record}, fieldIndex)
+	raw, row := csvRecordToRawEvent(record, fieldIndex, opts, categoryNames, geocoded)
+	if len(row.Errors) != 0 {
+		t.Fatalf("unexpected errors %v", row.Errors)
+	}
+	// The mock geo service resolves every address to New York
+	if raw.Timezone != "America/New_York" || raw.Lat == 0 || raw.Address != "The Tavern, New York" {
+		t.Errorf("expected a geocoded location with derived timezone, got %+v", raw.RawEventData)
+	}
+	if raw.StartTime != "2030-06-02T02:00:00Z" || raw.EndTime != "2030-06-02T05:00:00Z" {
+		t.Errorf("expected the end to roll past midnight, got %v to %v", raw.StartTime, raw.EndTime)
+	}
+	if raw.Categories == nil || (*raw.Categories)[0] != knownCategory {
+		t.Errorf("expected the canonical category, got %v", raw.Categories)
+	}
+	if raw.Tags == nil || (*raw.Tags)[0] != "Pub Games" {
+		t.Errorf("expected the unknown category as a tag, got %v", raw.Tags)
+	}
+	if raw.SourceUrl != nil || len(row.Warnings) != 2 {
+		t.Errorf("expected the bad url dropped with a warning, got %v / %v", raw.SourceUrl, row.Warnings)
+	}
+	if raw.Description != "Late Trivia" || raw.EventSourceType != constants.ES_SINGLE_EVENT || *raw.EventSourceId != "csv-upload:events.csv" {
+		t.Errorf("unexpected event defaults %+v", raw)
+	}
+	if _, _, err := SingleValidateEvent(raw, true); err != nil {
+		t.Errorf("expected the row to validate, got %v", err)
+	}
+
+	again, _ := csvRecordToRawEvent([]string{"Late Trivia", "2030-06-01", "10:00 PM", "", "The Tavern, New York", "", ""}, fieldIndex, opts, categoryNames, geocoded)
+	if again.Id == "" || again.Id != raw.Id {
+		t.Errorf("expected the same row to keep its id, got %s and %s", raw.Id, again.Id)
+	}
+	otherOwner, _ := csvRecordToRawEvent([]string{"Late Trivia", "2030-06-01", "10:00 PM", "", "The Tavern, New York", "", ""}, fieldIndex, CSVImportOptions{OwnerId: "owner-2"}, categoryNames, geocoded)
+	if otherOwner.Id == raw.Id {
+		t.Errorf("expected ids to be scoped to the owner")
+	}
+
+	withExternalId := map[string]int{"id": 0, "name": 1, "startTime": 2, "address": 3, "lat": 4, "long": 5, "timezone": 6}
+	first, row := csvRecordToRawEvent([]string{"A-1", "Bingo", "2030-06-01 19:00", "Hall", "41.88", "-87.63", "America/Chicago"}, withExternalId, opts, categoryNames, nil)
+	renamed, _ := csvRecordToRawEvent([]string{"A-1", "Bingo Night", "2030-06-08 19:00", "Hall", "41.88", "-87.63", "America/Chicago"}, withExternalId, opts, categoryNames, nil)
+	if len(row.Errors) != 0 || first.Id != renamed.Id {
+		t.Errorf("expected the external id to identify the row, got %v / %s vs %s", row.Errors, first.Id, renamed.Id)
+	}
+	if first.Lat != 41.88 || first.StartTime != "2030-06-02T00:00:00Z" {
+		t.Errorf("expected the row's coordinates and timezone, got %v %v", first.Lat, first.StartTime)
+	}
+
+	badRecord := []string{"", "", "whenever", "Hall", "91", "0", "America/Chicago"}
+	_, row = csvRecordToRawEvent(badRecord, withExternalId, opts, categoryNames, geocodeCSVAddresses(context.Background(), [][]string{badRecord}, withExternalId))
+	if len(row.Errors) != 3 {
+		t.Errorf("expected every problem reported, got %v", row.Errors)
+	}
+}
+
+func TestGeocodeCSVAddresses(t *testing.T) {
+	origEnv := os.Getenv("GO_ENV")
+	defer os.Setenv("GO_ENV", origEnv)
+	os.Setenv("GO_ENV", "test")
+
+	fieldIndex := map[string]int{"address": 0, "lat": 1, "long": 2}
+	records := [][]string{
+		{"Hall", "41.88", "-87.63"},
+		{"Hall", "", ""},
+		{"Hall", "", ""},
+		{"", "", ""},
+	}
+	for i := range MaxCSVImportGeocodes {
+		records = append(records, []string{fmt.Sprintf("%d Main St", i), "", ""})
+	}
+
+	geocoded := geocodeCSVAddresses(context.Background(), records, fieldIndex)
+	if len(geocoded) != MaxCSVImportGeocodes+1 {
+		t.Fatalf("expected each distinct address once, got %d", len(geocoded))
+	}
+	if result := geocoded["Hall"]; result.err != nil || result.lat == 0 {
+		t.Errorf("expected the address to be looked up, got %+v", result)
+	}
+	last := fmt.Sprintf("%d Main St", MaxCSVImportGeocodes-1)
+	if result := geocoded[last]; !errors.Is(result.err, errCSVGeocodeLimit) {
+		t.Errorf("expected addresses past the limit to be skipped, got %+v", result)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := geocodeCSVAddresses(ctx, records[1:2], fieldIndex)["Hall"]; !errors.Is(result.err, context.Canceled) {
+		t.Errorf("expected no lookups once the request is gone, got %+v", result)
+	}
+}
@@ -0,0 +1,557 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/types"
+
+	dynamodb_types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	DATA_EXPORT_STATUS_PENDING  = "PENDING"
+	DATA_EXPORT_STATUS_COMPLETE = "COMPLETE"
+	DATA_EXPORT_STATUS_FAILED   = "FAILED"
+)
+
+// DataExportTTL is how long a finished export stays downloadable
+const DataExportTTL = 24 * time.Hour
+
+// dataExportTimeout bounds one attempt at building an export, an attempt
+// started longer ago than this is presumed to have died with its instance
+const dataExportTimeout = 10 * time.Minute
+
+const dataExportMaxAttempts = 3
+
+// Page size shared by the user data export and account deletion, and the
+// most events an export holds
+const (
+	userDataPageSize     = 100
+	userDataMaxEventHits = 10000
+)
+
+// DataExportSources are the stores an export reads from, the handler wires
+// the real services and tests swap in mocks
+type DataExportSources struct {
+	Events             EventStore
+	DynamoDB           types.DynamoDBAPI
+	Purchases          types.PurchaseServiceInterface
+	RegistrationFields types.RegistrationFieldsServiceInterface
+	CompetitionConfigs types.CompetitionConfigServiceInterface
+	CompetitionRounds  types.CompetitionRoundServiceInterface
+	CompetitionVotes   types.CompetitionVoteServiceInterface
+	Postgres           interfaces.PostgresServiceInterface
+}
+
+// DataExportJob is the API view of an export. `RequestedBy` differs from `UserId` when a
+// superAdmin exports someone else's data
+type DataExportJob struct {
+	Id          string         `json:"id"`
+	UserId      string         `json:"userId"`
+	RequestedBy string         `json:"requestedBy"`
+	Status      string         `json:"status"`
+	Error       string         `json:"error,omitempty"`
+	Files       map[string]int `json:"files,omitempty"`
+	Size        int            `json:"size,omitempty"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	CompletedAt *time.Time     `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time     `json:"expiresAt,omitempty"`
+}
+
+// DataExportManifest is `manifest.json` at the root of an export, `Files`
+// counts the records in each file
+type DataExportManifest struct {
+	UserId      string         `json:"userId"`
+	RequestedBy string         `json:"requestedBy"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+	Files       map[string]int `json:"files"`
+}
+
+// DataExportCompetition is a competition the user runs along with its rounds
+type DataExportCompetition struct {
+	Config types.CompetitionConfig  `json:"config"`
+	Rounds []types.CompetitionRound `json:"rounds"`
+}
+
+// DataExportRegistration is one set of answers the user gave to an event's
+// registration questions when purchasing
+type DataExportRegistration struct {
+	EventId     string                   `json:"eventId"`
+	EventName   string                   `json:"eventName"`
+	ItemName    string                   `json:"itemName"`
+	PurchasedAt int64                    `json:"purchasedAt"`
+	Responses   []map[string]interface{} `json:"responses"`
+}
+
+// StartDataExport queues an export of everything tied to `userId` for the ACT
+// worker and returns right away. An export already pending for the same user
+// is returned instead of queueing another one
+func StartDataExport(ctx context.Context, store interfaces.PostgresServiceInterface, userId, requestedBy string, now time.Time) (DataExportJob, error) {
+	pending, err := store.GetPendingDataExport(ctx, userId)
+	if err != nil {
+		return DataExportJob{}, fmt.Errorf("failed to get pending data export: %w", err)
+	}
+	if pending != nil {
+		return newDataExportJob(*pending), nil
+	}
+
+	export := types.DataExport{
+		Id:          uuid.NewString(),
+		UserId:      userId,
+		RequestedBy: requestedBy,
+		Status:      DATA_EXPORT_STATUS_PENDING,
+		Files:       types.DataExportFiles{},
+		CreatedAt:   now.Unix(),
+		UpdatedAt:   now.Unix(),
+	}
+	if err := store.SaveDataExport(ctx, export); err != nil {
+		return DataExportJob{}, fmt.Errorf("failed to save data export: %w", err)
+	}
+	return newDataExportJob(export), nil
+}
+
+// GetDataExport returns an export's job and, once it's complete, the ZIP.
+// Expired exports are reported as missing until the worker deletes them
+func GetDataExport(ctx context.Context, store interfaces.PostgresServiceInterface, id string, now time.Time) (DataExportJob, []byte, bool, error) {
+	export, err := store.GetDataExport(ctx, id)
+	if err != nil {
+		return DataExportJob{}, nil, false, fmt.Errorf("failed to get data export: %w", err)
+	}
+	if export == nil || (export.ExpiresAt > 0 && now.Unix() > export.ExpiresAt) {
+		return DataExportJob{}, nil, false, nil
+	}
+	return newDataExportJob(*export), export.Data, true, nil
+}
+
+// ProcessDueDataExports deletes expired exports and builds pending ones,
+// including any whose last attempt died with its instance. It returns how
+// many were built
+func ProcessDueDataExports(ctx context.Context, sources DataExportSources, now time.Time) (int, error) {
+	deleted, err := sources.Postgres.DeleteExpiredDataExports(ctx, now.Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired data exports: %w", err)
+	}
+	if deleted > 0 {
+		log.Printf("INFO: deleted %d expired data exports", deleted)
+	}
+
+	due, err := sources.Postgres.GetDueDataExports(ctx, now.Add(-dataExportTimeout).Unix())
+	if err != nil {
+		return 0, fmt.Errorf("failed to get due data exports: %w", err)
+	}
+
+	completed := 0
+	for _, export := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		export, err := RunDataExport(ctx, sources, export, time.Now().UTC())
+		if err != nil {
+			log.Printf("ERR: data export %s for user %s stopped: %v", export.Id, export.UserId, err)
+			continue
+		}
+		if export.Status == DATA_EXPORT_STATUS_COMPLETE {
+			completed++
+		}
+	}
+	return completed, nil
+}
+
+// RunDataExport builds the export and saves the ZIP with it. The attempt is
+// saved before the build starts, so an export whose instance dies is picked
+// up again once `dataExportTimeout` passes, and fails after
+// `dataExportMaxAttempts` tries
+func RunDataExport(ctx context.Context, sources DataExportSources, export types.DataExport, now time.Time) (types.DataExport, error) {
+	if export.Status != DATA_EXPORT_STATUS_PENDING {
+		return export, nil
+	}
+	if export.Attempts >= dataExportMaxAttempts {
+		return finishDataExport(ctx, sources.Postgres, export, nil, nil, fmt.Errorf("gave up after %d attempts", export.Attempts), now)
+	}
+
+	export.Attempts++
+	export.StartedAt = now.Unix()
+	export.UpdatedAt = now.Unix()
+	if err := sources.Postgres.SaveDataExport(ctx, export); err != nil {
+		return export, fmt.Errorf("failed to save data export: %w", err)
+	}
+
+	buildCtx, cancel := context.WithTimeout(ctx, dataExportTimeout)
+	defer cancel()
+	data, manifest, err := BuildDataExport(buildCtx, sources, export.UserId, export.RequestedBy, now)
+	if err != nil && ctx.Err() != nil {
+		// Shutting down, the next leader retries the export
+		return export, err
+	}
+	return finishDataExport(ctx, sources.Postgres, export, data, manifest.Files, err, time.Now().UTC())
+}
+
+func finishDataExport(ctx context.Context, store interfaces.PostgresServiceInterface, export types.DataExport, data []byte, files map[string]int, buildErr error, now time.Time) (types.DataExport, error) {
+	export.CompletedAt = now.Unix()
+	export.ExpiresAt = now.Add(DataExportTTL).Unix()
+	export.UpdatedAt = now.Unix()
+	if buildErr != nil {
+		log.Printf("ERR: data export %s for user %s failed: %v", export.Id, export.UserId, buildErr)
+		export.Status = DATA_EXPORT_STATUS_FAILED
+		export.Error = buildErr.Error()
+	} else {
+		export.Status = DATA_EXPORT_STATUS_COMPLETE
+		export.Files = files
+		export.Size = len(data)
+		export.Data = data
+	}
+	if err := store.SaveDataExport(ctx, export); err != nil {
+		return export, fmt.Errorf("failed to save data export: %w", err)
+	}
+	return export, nil
+}
+
+func newDataExportJob(export types.DataExport) DataExportJob {
+	job := DataExportJob{
+		Id:          export.Id,
+		UserId:      export.UserId,
+		RequestedBy: export.RequestedBy,
+		Status:      export.Status,
+		Error:       export.Error,
+		Files:       export.Files,
+		Size:        export.Size,
+		CreatedAt:   time.Unix(export.CreatedAt, 0).UTC(),
+	}
+	if len(job.Files) == 0 {
+		job.Files = nil
+	}
+	if export.CompletedAt > 0 {
+		completedAt := time.Unix(export.CompletedAt, 0).UTC()
+		job.CompletedAt = &completedAt
+	}
+	if export.ExpiresAt > 0 {
+		expiresAt := time.Unix(export.ExpiresAt, 0).UTC()
+		job.ExpiresAt = &expiresAt
+	}
+	return job
+}
+
+// BuildDataExport gathers the events a user owns or shadow-owns, their
+// purchases and registration answers, the competitions they run, the votes
+// they've cast and their Seshu jobs into a ZIP of JSON files with CSV copies
+// of the tabular ones. Any source failing fails the export, a data request
+// answered with a partial copy would be worse than a retry
+func BuildDataExport(ctx context.Context, sources DataExportSources, userId, requestedBy string, now time.Time) ([]byte, DataExportManifest, error) {
+	manifest := DataExportManifest{
+		UserId:      userId,
+		RequestedBy: requestedBy,
+		GeneratedAt: now,
+		Files:       map[string]int{},
+	}
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	writeFile := func(name string, count int, data []byte) error {
+		f, err := archive.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: now})
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+		manifest.Files[name] = count
+		return nil
+	}
+	writeJSON := func(name string, count int, v interface{}) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", name, err)
+		}
+		return writeFile(name, count, data)
+	}
+	writeCSV := func(name string, header []string, rows [][]string) error {
+		var csvBuf bytes.Buffer
+		w := csv.NewWriter(&csvBuf)
+		if err := w.Write(header); err != nil {
+			return err
+		}
+		if err := w.WriteAll(rows); err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+		return writeFile(name, len(rows), csvBuf.Bytes())
+	}
+
+	events, err := getUserEvents(ctx, sources.Events, userId)
+	if err != nil {
+		return nil, manifest, fmt.Errorf("events: %w", err)
+	}
+	if err := writeJSON("events.json", len(events), events); err != nil {
+		return nil, manifest, err
+	}
+	if err := writeCSV("events.csv", dataExportEventsHeader, dataExportEventRows(events, userId)); err != nil {
+		return nil, manifest, err
+	}
+
+	registrationFields := []types.RegistrationFields{}
+	for _, event := range events {
+		if !slices.Contains(event.EventOwners, userId) {
+			continue
+		}
+		fields, err := sources.RegistrationFields.GetRegistrationFieldsByEventID(ctx, sources.DynamoDB, event.Id)
+		if err != nil {
+			return nil, manifest, fmt.Errorf("registration fields for event %s: %w", event.Id, err)
+		}
+		if fields != nil && fields.EventId != "" {
+			registrationFields = append(registrationFields, *fields)
+		}
+	}
+	if err := writeJSON("registration_fields.json", len(registrationFields), registrationFields); err != nil {
+		return nil, manifest, err
+	}
+
+	purchases, err := getUserPurchases(ctx, sources.Purchases, sources.DynamoDB, userId)
+	if err != nil {
+		return nil, manifest, fmt.Errorf("purchases: %w", err)
+	}
+	if err := writeJSON("purchases.json", len(purchases), purchases); err != nil {
+		return nil, manifest, err
+	}
+	if err := writeCSV("purchases.csv", dataExportPurchasesHeader, dataExportPurchaseRows(purchases)); err != nil {
+		return nil, manifest, err
+	}
+	registrations := []DataExportRegistration{}
+	for _, purchase := range purchases {
+		for _, item := range purchase.PurchasedItems {
+			if len(item.RegResponses) == 0 {
+				continue
+			}
+			registrations = append(registrations, DataExportRegistration{
+				EventId:     purchase.EventID,
+				EventName:   purchase.EventName,
+				ItemName:    item.Name,
+				PurchasedAt: purchase.CreatedAt,
+				Responses:   item.RegResponses,
+			})
+		}
+	}
+	if err := writeJSON("registrations.json", len(registrations), registrations); err != nil {
+		return nil, manifest, err
+	}
+
+	configs, err := sources.CompetitionConfigs.GetCompetitionConfigsByPrimaryOwner(ctx, sources.DynamoDB, userId, false)
+	if err != nil {
+		return nil, manifest, fmt.Errorf("competitions: %w", err)
+	}
+	competitions := []DataExportCompetition{}
+	if configs != nil {
+		for _, config := range *configs {
+			rounds, err := sources.CompetitionRounds.GetCompetitionRounds(ctx, sources.DynamoDB, config.Id)
+			if err != nil {
+				return nil, manifest, fmt.Errorf("rounds for competition %s: %w", config.Id, err)
+			}
+			competition := DataExportCompetition{Config: config, Rounds: []types.CompetitionRound{}}
+			if rounds != nil {
+				competition.Rounds = *rounds
+			}
+			competitions = append(competitions, competition)
+		}
+	}
+	if err := writeJSON("competitions.json", len(competitions), competitions); err != nil {
+		return nil, manifest, err
+	}
+
+	votes, err := sources.CompetitionVotes.GetCompetitionVotesByUserId(ctx, sources.DynamoDB, userId)
+	if err != nil {
+		return nil, manifest, fmt.Errorf("votes: %w", err)
+	}
+	if err := writeJSON("votes.json", len(votes), votes); err != nil {
+		return nil, manifest, err
+	}
+
+	// GetSeshuJobs scopes to the user in the context
+	seshuCtx := context.WithValue(ctx, "userInfo", constants.UserInfo{Sub: userId})
+	seshuJobs, _, err := sources.Postgres.GetSeshuJobs(seshuCtx, 0, 0)
+	if err != nil {
+		return nil, manifest, fmt.Errorf("seshu jobs: %w", err)
+	}
+	if seshuJobs == nil {
+		seshuJobs = []types.SeshuJob{}
+	}
+	if err := writeJSON("seshu_jobs.json", len(seshuJobs), seshuJobs); err != nil {
+		return nil, manifest, err
+	}
+	if err := writeCSV("seshu_jobs.csv", dataExportSeshuJobsHeader, dataExportSeshuJobRows(seshuJobs)); err != nil {
+		return nil, manifest, err
+	}
+
+	if err := writeJSON("manifest.json", len(manifest.Files), manifest); err != nil {
+		return nil, manifest, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, manifest, fmt.Errorf("failed to finish export archive: %w", err)
+	}
+	return buf.Bytes(), manifest, nil
+}
+
+// getUserEvents pages through every event the user owns or shadow-owns,
+// published or not, and loads them in full. The whole export is built in
+// memory so it stops at `userDataMaxEventHits`
+func getUserEvents(ctx context.Context, store EventStore, userId string) ([]types.Event, error) {
+	events := []types.Event{}
+	err := forEachUserEventPage(ctx, store, userId, func(page []types.Event) error {
+		events = append(events, page...)
+		if len(events) > userDataMaxEventHits {
+			return fmt.Errorf("more than %d events", userDataMaxEventHits)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// forEachUserEventPage calls `fn` with each page of the user's events loaded
+// in full. Pages are keyed on start time, so `fn` can delete or update the
+// page it was handed without the next page skipping any events
+func forEachUserEventPage(ctx context.Context, store EventStore, userId string, fn func(page []types.Event) error) error {
+	cursor := ""
+	for {
+		res, err := store.SearchEventsPage(ctx, "", nil, 0, 0, 0, []string{userId}, "", "", "",
+			constants.ALL_EVENT_SOURCE_TYPES, nil, EventSearchPage{Limit: userDataPageSize, Cursor: cursor})
+		if err != nil {
+			return err
+		}
+		ids := make([]string, 0, len(res.Events))
+		for _, event := range res.Events {
+			ids = append(ids, event.Id)
+		}
+		if len(ids) > 0 {
+			loaded, err := store.BulkGetEventByID(ctx, ids, "")
+			if err != nil {
+				return err
+			}
+			page := make([]types.Event, 0, len(loaded))
+			for _, event := range loaded {
+				if event != nil {
+					page = append(page, *event)
+				}
+			}
+			if err := fn(page); err != nil {
+				return err
+			}
+		}
+		if !res.HasMore || res.NextCursor == "" {
+			return nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+func getUserPurchases(ctx context.Context, purchaseService types.PurchaseServiceInterface, db types.DynamoDBAPI, userId string) ([]types.Purchase, error) {
+	purchases := []types.Purchase{}
+	startKey := ""
+	for {
+		page, lastKey, err := purchaseService.GetPurchasesByUserID(ctx, db, userId, userDataPageSize, startKey)
+		if err != nil {
+			return nil, err
+		}
+		purchases = append(purchases, page...)
+		compositeKey, ok := lastKey["compositeKey"].(*dynamodb_types.AttributeValueMemberS)
+		if !ok || compositeKey.Value == "" || compositeKey.Value == startKey {
+			return purchases, nil
+		}
+		startKey = compositeKey.Value
+	}
+}
+
+var dataExportEventsHeader = []string{"id", "role", "name", "eventSourceType", "startTime", "endTime", "timezone", "address", "lat", "long", "categories", "tags", "sourceUrl"}
+
+func dataExportEventRows(events []types.Event, userId string) [][]string {
+	rows := make([][]string, 0, len(events))
+	for _, event := range events {
+		role := "shadowOwner"
+		if slices.Contains(event.EventOwners, userId) {
+			role = "owner"
+		}
+		loc := time.UTC
+		if event.Timezone.String() != "" {
+			loc = &event.Timezone
+		}
+		endTime := ""
+		if event.EndTime > 0 {
+			endTime = time.Unix(event.EndTime, 0).In(loc).Format(time.RFC3339)
+		}
+		rows = append(rows, []string{
+			event.Id,
+			role,
+			event.Name,
+			event.EventSourceType,
+			time.Unix(event.StartTime, 0).In(loc).Format(time.RFC3339),
+			endTime,
+			event.Timezone.String(),
+			event.Address,
+			strconv.FormatFloat(event.Lat, 'f', -1, 64),
+			strconv.FormatFloat(event.Long, 'f', -1, 64),
+			strings.Join(event.Categories, "; "),
+			strings.Join(event.Tags, "; "),
+			event.SourceUrl,
+		})
+	}
+	return rows
+}
+
+var dataExportPurchasesHeader = []string{"eventId", "eventName", "status", "items", "total", "currency", "createdAt"}
+
+func dataExportPurchaseRows(purchases []types.Purchase) [][]string {
+	rows := make([][]string, 0, len(purchases))
+	for _, purchase := range purchases {
+		items := make([]string, 0, len(purchase.PurchasedItems))
+		for _, item := range purchase.PurchasedItems {
+			items = append(items, fmt.Sprintf("%d x %s", item.Quantity, item.Name))
+		}
+		rows = append(rows, []string{
+			purchase.EventID,
+			purchase.EventName,
+			purchase.Status,
+			strings.Join(items, "; "),
+			// Totals are stored in the currency's minor unit
+			fmt.Sprintf("%.2f", float64(purchase.Total)/100),
+			purchase.Currency,
+			time.Unix(purchase.CreatedAt, 0).UTC().Format(time.RFC3339),
+		})
+	}
+	return rows
+}
+
+var dataExportSeshuJobsHeader = []string{"url", "status", "knownScrapeSource", "scheduledHour", "locationAddress", "locationTimezone", "lastScrapeSuccess", "lastScrapeFailure"}
+
+func dataExportSeshuJobRows(jobs []types.SeshuJob) [][]string {
+	formatUnix := func(unix int64) string {
+		if unix <= 0 {
+			return ""
+		}
+		return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+	}
+	rows := make([][]string, 0, len(jobs))
+	for _, job := range jobs {
+		rows = append(rows, []string{
+			job.NormalizedUrlKey,
+			job.Status,
+			job.KnownScrapeSource,
+			strconv.Itoa(job.ScheduledHour),
+			job.LocationAddress,
+			job.LocationTimezone,
+			formatUnix(job.LastScrapeSuccess),
+			formatUnix(job.LastScrapeFailure),
+		})
+	}
+	return rows
+}
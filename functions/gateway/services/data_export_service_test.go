@@ -0,0 +1,376 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	dynamodb_types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/types"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+type fakeExportPurchases struct {
+	types.PurchaseServiceInterface
+	pages [][]types.Purchase
+}
+
+func (f *fakeExportPurchases) GetPurchasesByUserID(ctx context.Context, db types.DynamoDBAPI, userId string, limit int32, startKey string) ([]types.Purchase, map[string]dynamodb_types.AttributeValue, error) {
+	page := 0
+	if startKey != "" {
+		page = 1
+	}
+	var lastKey map[string]dynamodb_types.AttributeValue
+	if page < len(f.pages)-1 {
+		lastKey = map[string]dynamodb_types.AttributeValue{
+			"compositeKey": &dynamodb_types.AttributeValueMemberS{Value: f.pages[page][0].CompositeKey},
+		}
+	}
+	return f.pages[page], lastKey, nil
+}
+
+type fakeExportRegistrationFields struct {
+	types.RegistrationFieldsServiceInterface
+	eventIds []string
+}
+
+func (f *fakeExportRegistrationFields) GetRegistrationFieldsByEventID(ctx context.Context, db types.DynamoDBAPI, eventId string) (*types.RegistrationFields, error) {
+	f.eventIds = append(f.eventIds, eventId)
+	return &types.RegistrationFields{EventId: eventId, Fields: []types.RegistrationField{{Name: "tshirt"}}}, nil
+}
+
+type fakeExportCompetitions struct {
+	types.CompetitionConfigServiceInterface
+	types.CompetitionRoundServiceInterface
+	types.CompetitionVoteServiceInterface
+	votesErr error
+}
+
+func (f *fakeExportCompetitions) GetCompetitionConfigsByPrimaryOwner(ctx context.Context, db types.DynamoDBAPI, primaryOwner string, isSelf bool) (*[]types.CompetitionConfig, error) {
+	return &[]types.CompetitionConfig{{Id: "comp-1", PrimaryOwner: primaryOwner, Name: "Karaoke Cup"}}, nil
+}
+
+func (f *fakeExportCompetitions) GetCompetitionRounds(ctx context.Context, db types.DynamoDBAPI, competitionId string) (*[]types.CompetitionRound, error) {
+	return &[]types.CompetitionRound{{CompetitionId: competitionId, RoundNumber: 1}}, nil
+}
+
+func (f *fakeExportCompetitions) GetCompetitionVotesByUserId(ctx context.Context, db types.DynamoDBAPI, userId string) ([]types.CompetitionVote, error) {
+	if f.votesErr != nil {
+		return nil, f.votesErr
+	}
+	return []types.CompetitionVote{{CompositePartitionKey: "comp-9_2", UserId: userId, VoteRecipientId: "singer", VoteValue: 1}}, nil
+}
+
+type fakeExportPostgres struct {
+	interfaces.PostgresServiceInterface
+	mu      sync.Mutex
+	exports map[string]types.DataExport
+}
+
+func (f *fakeExportPostgres) SaveDataExport(ctx context.Context, export types.DataExport) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.exports == nil {
+		f.exports = map[string]types.DataExport{}
+	}
+	f.exports[export.Id] = export
+	return nil
+}
+
+func (f *fakeExportPostgres) GetDataExport(ctx context.Context, id string) (*types.DataExport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	export, ok := f.exports[id]
+	if !ok {
+		return nil, nil
+	}
+	return &export, nil
+}
+
+func (f *fakeExportPostgres) GetPendingDataExport(ctx context.Context, userId string) (*types.DataExport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, export := range f.exports {
+		if export.UserId == userId && export.Status == DATA_EXPORT_STATUS_PENDING {
+			return &export, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeExportPostgres) GetDueDataExports(ctx context.Context, startedBefore int64) ([]types.DataExport, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	due := []types.DataExport{}
+	for _, export := range f.exports {
+		if export.Status == DATA_EXPORT_STATUS_PENDING && export.StartedAt <= startedBefore {
+			export.Data = nil
+			due = append(due, export)
+		}
+	}
+	return due, nil
+}
+
+func (f *fakeExportPostgres) DeleteExpiredDataExports(ctx context.Context, now int64) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	deleted := int64(0)
+	for id, export := range f.exports {
+		if export.ExpiresAt > 0 && export.ExpiresAt <= now {
+			delete(f.exports, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (f *fakeExportPostgres) GetSeshuJobs(ctx context.Context, limit, offset int) ([]types.SeshuJob, int64, error) {
+	userInfo := ctx.Value("userInfo").(constants.UserInfo)
+	return []types.SeshuJob{{NormalizedUrlKey: "example.com/events", OwnerID: userInfo.Sub, Status: "HEALTHY", ScheduledHour: 4}}, 1, nil
+}
+
+func newDataExportTestSources(t *testing.T) (DataExportSources, *fakeExportRegistrationFields, *fakeExportCompetitions) {
+	start := time.Date(2030, 6, 1, 23, 0, 0, 0, time.UTC).Unix()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/graphql" {
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		// Both the owner search and the full fetch by id see the same events
+		response := models.GraphQLResponse{
+			Data: map[string]models.JSONObject{
+				"Get": map[string]interface{}{
+					EventClassName(): []interface{}{
+						map[string]interface{}{
+							"name":            "Open Mic",
+							"eventOwners":     []interface{}{"user-1"},
+							"eventSourceType": constants.ES_SINGLE_EVENT,
+							"timezone":        "America/New_York",
+							"startTime":       start,
+							"address":         "The Bitter End",
+							"categories":      []interface{}{"Music", "Comedy"},
+							"_additional":     map[string]interface{}{"id": "event-owned"},
+						},
+						map[string]interface{}{
+							"name":            "Reshared Run",
+							"eventOwners":     []interface{}{"user-2"},
+							"shadowOwners":    []interface{}{"user-1"},
+							"eventSourceType": constants.ES_SINGLE_EVENT_UNPUB,
+							"timezone":        "America/New_York",
+							"startTime":       start,
+							"_additional":     map[string]interface{}{"id": "event-shadowed"},
+						},
+					},
+				},
+			},
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(response)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := weaviate.NewClient(weaviate.Config{Host: strings.TrimPrefix(server.URL, "http://"), Scheme: "http"})
+	if err != nil {
+		t.Fatalf("failed to create weaviate client: %v", err)
+	}
+	registrationFields := &fakeExportRegistrationFields{}
+	competitions := &fakeExportCompetitions{}
+	return DataExportSources{
+		Events: NewWeaviateEventStore(client),
+		Purchases: &fakeExportPurchases{pages: [][]types.Purchase{
+			{{EventID: "event-a", EventName: "Gala", CompositeKey: "event-a_user-1_1", Status: "SETTLED", Total: 2500, Currency: "USD", CreatedAt: start,
+				PurchasedItems: []types.PurchasedItem{{Name: "Ticket", Quantity: 2, RegResponses: []map[string]interface{}{{"tshirt": "M"}}}}}},
+			{{EventID: "event-b", EventName: "Picnic", CompositeKey: "event-b_user-1_2", Status: "SETTLED", CreatedAt: start,
+				PurchasedItems: []types.PurchasedItem{{Name: "RSVP", Quantity: 1}}}},
+		}},
+		RegistrationFields: registrationFields,
+		CompetitionConfigs: competitions,
+		CompetitionRounds:  competitions,
+		CompetitionVotes:   competitions,
+		Postgres:           &fakeExportPostgres{},
+	}, registrationFields, competitions
+}
+
+func readDataExportZip(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("export is not a valid zip: %v", err)
+	}
+	files := map[string][]byte{}
+	for _, f := range archive.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		files[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+	return files
+}
+
+func TestBuildDataExport(t *testing.T) {
+	sources, registrationFields, _ := newDataExportTestSources(t)
+	now := time.Date(2030, 7, 1, 0, 0, 0, 0, time.UTC)
+
+	data, manifest, err := BuildDataExport(context.Background(), sources, "user-1", "admin-1", now)
+	if err != nil {
+		t.Fatalf("BuildDataExport() error = %v", err)
+	}
+	files := readDataExportZip(t, data)
+
+	wantCounts := map[string]int{
+		"events.json": 2, "events.csv": 2, "registration_fields.json": 1,
+		"purchases.json": 2, "purchases.csv": 2, "registrations.json": 1,
+		"competitions.json": 1, "votes.json": 1, "seshu_jobs.json": 1, "seshu_jobs.csv": 1,
+	}
+	for name, count := range wantCounts {
+		if _, ok := files[name]; !ok {
+			t.Errorf("expected %s in the export", name)
+		}
+		if manifest.Files[name] != count {
+			t.Errorf("expected %d records in %s, got %d", count, name, manifest.Files[name])
+		}
+	}
+
+	var written DataExportManifest
+	if err := json.Unmarshal(files["manifest.json"], &written); err != nil {
+		t.Fatalf("failed to decode manifest.json: %v", err)
+	}
+	if written.UserId != "user-1" || written.RequestedBy != "admin-1" || !written.GeneratedAt.Equal(now) || len(written.Files) != len(wantCounts) {
+		t.Errorf("unexpected manifest %+v", written)
+	}
+
+	// Registration questions belong to the owner, not whoever re-shared the event
+	if strings.Join(registrationFields.eventIds, ",") != "event-owned" {
+		t.Errorf("expected registration fields for owned events only, got %v", registrationFields.eventIds)
+	}
+
+	events, err := csv.NewReader(bytes.NewReader(files["events.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read events.csv: %v", err)
+	}
+	if events[1][1] != "owner" || events[1][4] != "2030-06-01T19:00:00-04:00" || events[1][10] != "Music; Comedy" || events[2][1] != "shadowOwner" {
+		t.Errorf("unexpected events.csv rows %q", events[1:])
+	}
+	purchases, err := csv.NewReader(bytes.NewReader(files["purchases.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to read purchases.csv: %v", err)
+	}
+	if purchases[1][3] != "2 x Ticket" || purchases[1][4] != "25.00" || purchases[2][0] != "event-b" {
+		t.Errorf("unexpected purchases.csv rows %q", purchases[1:])
+	}
+
+	var registrations []DataExportRegistration
+	if err := json.Unmarshal(files["registrations.json"], &registrations); err != nil {
+		t.Fatalf("failed to decode registrations.json: %v", err)
+	}
+	if registrations[0].EventId != "event-a" || registrations[0].Responses[0]["tshirt"] != "M" {
+		t.Errorf("unexpected registrations %+v", registrations)
+	}
+	var seshuJobs []types.SeshuJob
+	if err := json.Unmarshal(files["seshu_jobs.json"], &seshuJobs); err != nil {
+		t.Fatalf("failed to decode seshu_jobs.json: %v", err)
+	}
+	if seshuJobs[0].OwnerID != "user-1" {
+		t.Errorf("expected seshu jobs scoped to the exported user, got %+v", seshuJobs)
+	}
+}
+
+func TestBuildDataExportFailsOnSourceError(t *testing.T) {
+	sources, _, competitions := newDataExportTestSources(t)
+	competitions.votesErr = errors.New("table unavailable")
+
+	data, _, err := BuildDataExport(context.Background(), sources, "user-1", "user-1", time.Now())
+	if err == nil || !strings.Contains(err.Error(), "votes: table unavailable") || data != nil {
+		t.Errorf("expected the export to fail on the votes error, got %v", err)
+	}
+}
+
+func TestStartDataExport(t *testing.T) {
+	sources, _, _ := newDataExportTestSources(t)
+	store := sources.Postgres
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	job, err := StartDataExport(ctx, store, "user-1", "user-1", now)
+	if err != nil || job.Status != DATA_EXPORT_STATUS_PENDING || job.Id == "" {
+		t.Fatalf("expected a pending job, got %+v %v", job, err)
+	}
+	again, err := StartDataExport(ctx, store, "user-1", "admin-1", now)
+	if err != nil || again.Id != job.Id {
+		t.Errorf("expected the pending export to be returned, got %+v %v", again, err)
+	}
+
+	// Any instance sees the export the worker builds
+	completed, err := ProcessDueDataExports(ctx, sources, now)
+	if err != nil || completed != 1 {
+		t.Fatalf("expected one export to be built, got %d %v", completed, err)
+	}
+	job, data, ok, err := GetDataExport(ctx, store, job.Id, now)
+	if err != nil || !ok {
+		t.Fatalf("expected export %s to be stored, got %v", job.Id, err)
+	}
+	if job.Status != DATA_EXPORT_STATUS_COMPLETE || job.Size != len(data) || job.Files["events.json"] != 2 {
+		t.Fatalf("expected a complete export, got %+v", job)
+	}
+	if job.ExpiresAt == nil || job.ExpiresAt.Sub(*job.CompletedAt) != DataExportTTL {
+		t.Errorf("expected the export to expire after %v, got %+v", DataExportTTL, job)
+	}
+	readDataExportZip(t, data)
+
+	// Expired exports are reported as missing, then deleted by the worker
+	expired := job.ExpiresAt.Add(time.Second)
+	if _, _, ok, _ := GetDataExport(ctx, store, job.Id, expired); ok {
+		t.Errorf("expected the expired export to be gone")
+	}
+	if _, err := ProcessDueDataExports(ctx, sources, expired); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if export, _ := store.GetDataExport(ctx, job.Id); export != nil {
+		t.Errorf("expected the expired export to be deleted")
+	}
+}
+
+func TestRunDataExportRetries(t *testing.T) {
+	sources, _, _ := newDataExportTestSources(t)
+	store := sources.Postgres
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	job, err := StartDataExport(ctx, store, "user-1", "user-1", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// An attempt that started recently is still running on the leader
+	running, _ := store.GetDataExport(ctx, job.Id)
+	running.Attempts, running.StartedAt = 1, now.Unix()
+	store.SaveDataExport(ctx, *running)
+	if completed, _ := ProcessDueDataExports(ctx, sources, now); completed != 0 {
+		t.Errorf("expected a running export to be left alone, got %d built", completed)
+	}
+
+	// One that started before the timeout died with its instance
+	if completed, _ := ProcessDueDataExports(ctx, sources, now.Add(dataExportTimeout)); completed != 1 {
+		t.Errorf("expected a stalled export to be retried, got %d built", completed)
+	}
+
+	stalled := types.DataExport{Id: "stalled", UserId: "user-2", Status: DATA_EXPORT_STATUS_PENDING, Attempts: dataExportMaxAttempts}
+	export, err := RunDataExport(ctx, sources, stalled, now)
+	if err != nil || export.Status != DATA_EXPORT_STATUS_FAILED || export.Error == "" {
+		t.Errorf("expected the export to fail after %d attempts, got %+v %v", dataExportMaxAttempts, export, err)
+	}
+}
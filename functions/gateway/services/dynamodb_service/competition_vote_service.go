@@ -95,6 +95,45 @@ func (s *CompetitionVoteService) GetCompetitionVotesByCompetitionRound(ctx conte
 	return competitionRoundVotes, nil
 }
 
+// GetCompetitionVotesByUserId lists the votes a user has cast in any round.
+// The votes table is keyed by round, so this scans it. Votes expire with the
+// round, which keeps the table small enough for that to be reasonable
+func (s *CompetitionVoteService) GetCompetitionVotesByUserId(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, userId string) ([]internal_types.CompetitionVote, error) {
+	if votesTableName == "" {
+		return nil, fmt.Errorf("ERR: votesTableName is empty")
+	}
+
+	filterEx := expression.Name("userId").Equal(expression.Value(userId))
+	expr, err := expression.NewBuilder().WithFilter(filterEx).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build expression: %w", err)
+	}
+
+	votes := []internal_types.CompetitionVote{}
+	var startKey map[string]dynamodb_types.AttributeValue
+	for {
+		result, err := dynamodbClient.Scan(ctx, &dynamodb.ScanInput{
+			TableName:                 aws.String(votesTableName),
+			FilterExpression:          expr.Filter(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan votes: %w", err)
+		}
+		var page []internal_types.CompetitionVote
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal items: %v", err)
+		}
+		votes = append(votes, page...)
+		if len(result.LastEvaluatedKey) == 0 {
+			return votes, nil
+		}
+		startKey = result.LastEvaluatedKey
+	}
+}
+
 func (s *CompetitionVoteService) DeleteCompetitionVote(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, compositePartitionKey, userId string) error {
 	if votesTableName == "" {
 		return fmt.Errorf("ERR: votesTableName is empty")
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodb_types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/meetnearme/api/functions/gateway/test_helpers"
 	internal_types "github.com/meetnearme/api/functions/gateway/types"
 )
@@ -148,3 +149,55 @@ func TestPutCompetitionVote(t *testing.T) {
 		})
 	}
 }
+
+func TestGetCompetitionVotesByUserId(t *testing.T) {
+	pages := [][]map[string]dynamodb_types.AttributeValue{
+		{{
+			"compositePartitionKey": &dynamodb_types.AttributeValueMemberS{Value: "comp-1_1"},
+			"userId":                &dynamodb_types.AttributeValueMemberS{Value: "test-user"},
+			"voteRecipientId":       &dynamodb_types.AttributeValueMemberS{Value: "recipient-1"},
+			"voteValue":             &dynamodb_types.AttributeValueMemberN{Value: "1"},
+		}},
+		{{
+			"compositePartitionKey": &dynamodb_types.AttributeValueMemberS{Value: "comp-2_3"},
+			"userId":                &dynamodb_types.AttributeValueMemberS{Value: "test-user"},
+			"voteRecipientId":       &dynamodb_types.AttributeValueMemberS{Value: "recipient-2"},
+			"voteValue":             &dynamodb_types.AttributeValueMemberN{Value: "2"},
+		}},
+	}
+	calls := 0
+	mockDB := &test_helpers.MockDynamoDBClient{
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			if *params.TableName != votesTableName {
+				t.Errorf("expected table name %s, got %s", votesTableName, *params.TableName)
+			}
+			if userId, ok := params.ExpressionAttributeValues[":0"].(*dynamodb_types.AttributeValueMemberS); !ok || userId.Value != "test-user" {
+				t.Errorf("expected a userId filter, got %v", params.ExpressionAttributeValues)
+			}
+			if (calls == 0) != (params.ExclusiveStartKey == nil) {
+				t.Errorf("expected page %d to continue from the previous page", calls)
+			}
+			output := &dynamodb.ScanOutput{Items: pages[calls]}
+			if calls == 0 {
+				output.LastEvaluatedKey = pages[0][0]
+			}
+			calls++
+			return output, nil
+		},
+	}
+
+	votes, err := NewCompetitionVoteService().GetCompetitionVotesByUserId(context.Background(), mockDB, "test-user")
+	if err != nil {
+		t.Fatalf("GetCompetitionVotesByUserId() error = %v", err)
+	}
+	if calls != 2 || len(votes) != 2 || votes[1].CompositePartitionKey != "comp-2_3" || votes[1].VoteValue != 2 {
+		t.Errorf("expected both pages of votes, got %d calls and %+v", calls, votes)
+	}
+
+	mockDB.ScanFunc = func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+		return nil, errors.New("dynamodb error")
+	}
+	if _, err := NewCompetitionVoteService().GetCompetitionVotesByUserId(context.Background(), mockDB, "test-user"); err == nil || !strings.Contains(err.Error(), "dynamodb error") {
+		t.Errorf("expected the scan error, got %v", err)
+	}
+}
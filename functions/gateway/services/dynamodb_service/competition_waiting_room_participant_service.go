@@ -98,6 +98,44 @@ func (s *CompetitionWaitingRoomParticipantService) GetCompetitionWaitingRoomPart
 	return competitionWaitingRoomParticipants, nil
 }
 
+// GetCompetitionWaitingRoomParticipantsByUserId lists the waiting rooms a
+// user has joined. The table is keyed by competition, so this scans it
+func (s *CompetitionWaitingRoomParticipantService) GetCompetitionWaitingRoomParticipantsByUserId(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, userId string) ([]internal_types.CompetitionWaitingRoomParticipant, error) {
+	if competitionWaitingRoomParticipantTableName == "" {
+		return nil, fmt.Errorf("ERR: competitionWaitingRoomParticipantTableName is empty")
+	}
+
+	filterEx := expression.Name("userId").Equal(expression.Value(userId))
+	expr, err := expression.NewBuilder().WithFilter(filterEx).Build()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build expression: %w", err)
+	}
+
+	participants := []internal_types.CompetitionWaitingRoomParticipant{}
+	var startKey map[string]dynamodb_types.AttributeValue
+	for {
+		result, err := dynamodbClient.Scan(ctx, &dynamodb.ScanInput{
+			TableName:                 aws.String(competitionWaitingRoomParticipantTableName),
+			FilterExpression:          expr.Filter(),
+			ExpressionAttributeNames:  expr.Names(),
+			ExpressionAttributeValues: expr.Values(),
+			ExclusiveStartKey:         startKey,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan waiting room participants: %w", err)
+		}
+		var page []internal_types.CompetitionWaitingRoomParticipant
+		if err := attributevalue.UnmarshalListOfMaps(result.Items, &page); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal items: %v", err)
+		}
+		participants = append(participants, page...)
+		if len(result.LastEvaluatedKey) == 0 {
+			return participants, nil
+		}
+		startKey = result.LastEvaluatedKey
+	}
+}
+
 func (s *CompetitionWaitingRoomParticipantService) DeleteCompetitionWaitingRoomParticipant(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, competitionId, userId string) error {
 	input := &dynamodb.DeleteItemInput{
 		TableName: aws.String(competitionWaitingRoomParticipantTableName),
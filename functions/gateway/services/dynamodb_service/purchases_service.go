@@ -3,6 +3,7 @@ package dynamodb_service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
@@ -274,6 +275,54 @@ func (s *PurchaseService) DeletePurchase(ctx context.Context, dynamodbClient int
 	return nil
 }
 
+// AnonymizePurchase moves a purchase from the user to `anonymousUserId` and
+// drops the buyer's registration answers, keeping the sale in the event
+// owner's records. The user ID is part of the table key so the purchase is
+// copied to a new key before the original is removed. A copy left behind by
+// an earlier attempt is reused, which makes the move safe to retry
+func (s *PurchaseService) AnonymizePurchase(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, purchase internal_types.Purchase, anonymousUserId string) (*internal_types.Purchase, error) {
+	if purchasesTableName == "" {
+		return nil, fmt.Errorf("ERR: purchasesTableName is empty")
+	}
+	originalKey := purchase.CompositeKey
+
+	anonymized := purchase
+	anonymized.UserID = anonymousUserId
+	anonymized.CompositeKey = fmt.Sprintf("%s_%s_%s", purchase.EventID, anonymousUserId, purchase.CreatedAtString)
+	anonymized.PurchasedItems = make([]internal_types.PurchasedItem, len(purchase.PurchasedItems))
+	for i, item := range purchase.PurchasedItems {
+		item.RegResponses = nil
+		anonymized.PurchasedItems[i] = item
+	}
+	anonymized.UpdatedAt = time.Now().Unix()
+
+	item, err := attributevalue.MarshalMap(&anonymized)
+	if err != nil {
+		return nil, err
+	}
+	_, err = dynamodbClient.PutItem(ctx, &dynamodb.PutItemInput{
+		Item:                item,
+		TableName:           aws.String(purchasesTableName),
+		ConditionExpression: aws.String("attribute_not_exists(compositeKey)"),
+	})
+	var conditionFailed *dynamodb_types.ConditionalCheckFailedException
+	if err != nil && !errors.As(err, &conditionFailed) {
+		return nil, fmt.Errorf("failed to write anonymized purchase: %w", err)
+	}
+
+	_, err = dynamodbClient.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(purchasesTableName),
+		Key: map[string]dynamodb_types.AttributeValue{
+			"compositeKey": &dynamodb_types.AttributeValueMemberS{Value: originalKey},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete original purchase: %w", err)
+	}
+
+	return &anonymized, nil
+}
+
 func (s *PurchaseService) HasPurchaseForEvent(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, childEventId, parentEventId, userId string) (bool, error) {
 	// NOTE: right now we filter out INTERESTED items as they are a lower commitment
 	// from the end user, we expect they have "registered" via purchasable,
@@ -312,6 +361,7 @@ type MockPurchaseService struct {
 	UpdatePurchaseFunc        func(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, eventId, userId, createdAtString string, purchase internal_types.PurchaseUpdate) (*internal_types.Purchase, error)
 	DeletePurchaseFunc        func(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, eventId, userId string) error
 	HasPurchaseForEventFunc   func(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, childEventId, parentEventId, userId string) (bool, error)
+	AnonymizePurchaseFunc     func(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, purchase internal_types.Purchase, anonymousUserId string) (*internal_types.Purchase, error)
 }
 
 func (m *MockPurchaseService) InsertPurchase(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, purchase internal_types.PurchaseInsert) (*internal_types.Purchase, error) {
@@ -344,3 +394,10 @@ func (m *MockPurchaseService) HasPurchaseForEvent(ctx context.Context, dynamodbC
 	}
 	return false, nil
 }
+
+func (m *MockPurchaseService) AnonymizePurchase(ctx context.Context, dynamodbClient internal_types.DynamoDBAPI, purchase internal_types.Purchase, anonymousUserId string) (*internal_types.Purchase, error) {
+	if m.AnonymizePurchaseFunc != nil {
+		return m.AnonymizePurchaseFunc(ctx, dynamodbClient, purchase, anonymousUserId)
+	}
+	return &purchase, nil
+}
@@ -0,0 +1,88 @@
+package dynamodb_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodb_types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/meetnearme/api/functions/gateway/test_helpers"
+	internal_types "github.com/meetnearme/api/functions/gateway/types"
+)
+
+func TestAnonymizePurchase(t *testing.T) {
+	originalTableName := purchasesTableName
+	purchasesTableName = "test-purchases-table"
+	defer func() { purchasesTableName = originalTableName }()
+
+	purchase := internal_types.Purchase{
+		UserID:          "user-1",
+		EventID:         "event-1",
+		CompositeKey:    "event-1_user-1_2030-06-01T19:00:00Z",
+		EventName:       "Trivia Night",
+		Status:          "SETTLED",
+		Total:           2500,
+		CreatedAtString: "2030-06-01T19:00:00Z",
+		PurchasedItems: []internal_types.PurchasedItem{{
+			Name:         "Ticket",
+			Quantity:     1,
+			RegResponses: []map[string]interface{}{{"phone": "555-0100"}},
+		}},
+	}
+
+	tests := []struct {
+		name      string
+		putErr    error
+		deleteErr error
+		wantErr   bool
+	}{
+		{name: "moves the purchase"},
+		{name: "reuses a copy from an earlier attempt", putErr: &dynamodb_types.ConditionalCheckFailedException{Message: aws.String("exists")}},
+		{name: "write fails", putErr: errors.New("dynamodb error"), wantErr: true},
+		{name: "delete fails", deleteErr: errors.New("dynamodb error"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var written internal_types.Purchase
+			deletedKey := ""
+			mockDB := &test_helpers.MockDynamoDBClient{
+				PutItemFunc: func(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+					if err := attributevalue.UnmarshalMap(params.Item, &written); err != nil {
+						t.Fatalf("failed to unmarshal item: %v", err)
+					}
+					return &dynamodb.PutItemOutput{}, tt.putErr
+				},
+				DeleteItemFunc: func(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+					deletedKey = params.Key["compositeKey"].(*dynamodb_types.AttributeValueMemberS).Value
+					return &dynamodb.DeleteItemOutput{}, tt.deleteErr
+				},
+			}
+
+			anonymized, err := NewPurchaseService().AnonymizePurchase(context.Background(), mockDB, purchase, "deleted-abc")
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("AnonymizePurchase() error = %v", err)
+			}
+			if written.UserID != "deleted-abc" || written.CompositeKey != "event-1_deleted-abc_2030-06-01T19:00:00Z" {
+				t.Errorf("expected the purchase rekeyed to the anonymous user, got %+v", written)
+			}
+			if written.Total != 2500 || written.EventName != "Trivia Night" || written.PurchasedItems[0].RegResponses != nil {
+				t.Errorf("expected the sale kept without registration answers, got %+v", written)
+			}
+			if deletedKey != purchase.CompositeKey || anonymized.UserID != "deleted-abc" {
+				t.Errorf("expected the original purchase removed, deleted %q", deletedKey)
+			}
+			if purchase.PurchasedItems[0].RegResponses == nil {
+				t.Errorf("expected the caller's purchase left unchanged")
+			}
+		})
+	}
+}
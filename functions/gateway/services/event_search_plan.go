@@ -0,0 +1,289 @@
+package services
+
+import (
+	"log"
+	"strings"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/types"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
+)
+
+// eventSearchPlan is an event search resolved from its params and cursor,
+// ready to run. Every `EventStore` runs searches from one, so they filter
+// and page the same way
+type eventSearchPlan struct {
+	hybridQuery string
+	order       string
+	limit       int
+	overlap     bool
+	cursor      *eventSearchCursor
+	cursorBase  eventSearchCursor
+	// filters are the search's structured filters, what the total and the
+	// facets count
+	filters []*filters.WhereBuilder
+	// offset is where a score ordered page starts
+	offset int
+}
+
+func planEventSearch(
+	query string,
+	userLocation []float64,
+	maxDistance float64,
+	startTime, endTime int64,
+	ownerIds []string,
+	categories string,
+	address string,
+	eventSourceTypes []string,
+	eventSourceIds []string,
+	page EventSearchPage,
+) (eventSearchPlan, error) {
+	plan := eventSearchPlan{overlap: page.Overlap}
+	plan.limit = ClampEventSearchLimit(page.Limit)
+	if page.Bounds != nil && page.Limit > plan.limit {
+		// Map viewports are clustered server side, so they can afford more hits
+		plan.limit = min(page.Limit, constants.MAX_MAP_SEARCH_LIMIT)
+	}
+
+	fullTextQueryParts := []string{}
+	if query != "" {
+		fullTextQueryParts = append(fullTextQueryParts, query)
+	}
+	if categories != "" {
+		fullTextQueryParts = append(fullTextQueryParts, categories)
+	}
+	if address != "" {
+		fullTextQueryParts = append(fullTextQueryParts, address)
+	}
+	plan.hybridQuery = strings.Join(fullTextQueryParts, " ")
+
+	plan.order = eventSearchOrderStartTime
+	if plan.hybridQuery != "" {
+		plan.order = eventSearchOrderScore
+	}
+	plan.cursorBase = eventSearchCursor{
+		Order:       plan.order,
+		Fingerprint: eventSearchFingerprint(plan.order, plan.hybridQuery, userLocation, maxDistance, page.Bounds, page.Overlap, ownerIds, eventSourceTypes, eventSourceIds),
+		Start:       startTime,
+		End:         endTime,
+	}
+	cursor, err := decodeEventSearchCursor(page.Cursor)
+	if err != nil {
+		return plan, err
+	}
+	if cursor != nil {
+		if cursor.Order != plan.cursorBase.Order || cursor.Fingerprint != plan.cursorBase.Fingerprint {
+			return plan, ErrInvalidSearchCursor
+		}
+		plan.cursorBase.Start = cursor.Start
+		plan.cursorBase.End = cursor.End
+		plan.cursorBase.Total = cursor.Total
+		if plan.order == eventSearchOrderScore {
+			plan.offset = cursor.Offset
+		}
+	}
+	plan.cursor = cursor
+
+	whereOperands := []*filters.WhereBuilder{}
+
+	// Time Filter, if both are 0 or negative no time filter is applied. Will
+	// usually be unix now, pinned to the first page's value when paging
+	if timeFilter := eventTimeFilter(plan.searchStart(), plan.searchEnd(), page.Overlap); timeFilter != nil {
+		whereOperands = append(whereOperands, timeFilter)
+	}
+
+	// Location Filter, a map viewport replaces the center + radius circle
+	if page.Bounds != nil {
+		whereOperands = append(whereOperands, locationWhereFilters(page.Bounds.South, page.Bounds.North, page.Bounds.LongitudeRanges())...)
+	} else if len(userLocation) == 2 && maxDistance > 0 {
+		minLat, maxLat, minLong1, maxLong1, minLong2, maxLong2, needsSplit := calculateSearchBounds(userLocation, maxDistance)
+		longRanges := [][2]float64{{minLong1, maxLong1}}
+		if needsSplit {
+			longRanges = append(longRanges, [2]float64{minLong2, maxLong2})
+		}
+		whereOperands = append(whereOperands, locationWhereFilters(minLat, maxLat, longRanges)...)
+	}
+
+	// Owner Filter - Search both eventOwners and shadowOwners fields
+	if len(ownerIds) > 0 {
+		eventOwnersFilter := (&filters.WhereBuilder{}).
+			WithPath([]string{"eventOwners"}).
+			WithOperator(filters.ContainsAny).
+			WithValueText(ownerIds...)
+
+		shadowOwnersFilter := (&filters.WhereBuilder{}).
+			WithPath([]string{"shadowOwners"}).
+			WithOperator(filters.ContainsAny).
+			WithValueText(ownerIds...)
+
+		ownerFilter := (&filters.WhereBuilder{}).
+			WithOperator(filters.Or).
+			WithOperands([]*filters.WhereBuilder{eventOwnersFilter, shadowOwnersFilter})
+
+		whereOperands = append(whereOperands, ownerFilter)
+	}
+
+	// Type Filter (Integrated)
+	typesToSearch := eventSourceTypes
+	if len(typesToSearch) == 0 && len(constants.DEFAULT_SEARCHABLE_EVENT_SOURCE_TYPES) > 0 {
+		typesToSearch = constants.DEFAULT_SEARCHABLE_EVENT_SOURCE_TYPES
+	}
+	if len(typesToSearch) > 0 {
+		typeFilter := (&filters.WhereBuilder{}).
+			WithPath([]string{"eventSourceType"}).
+			WithOperator(filters.ContainsAny).
+			WithValueText(typesToSearch...)
+		whereOperands = append(whereOperands, typeFilter)
+	}
+
+	// Event Source ID Filter (Uncommented and integrated)
+	// Note: eventSourceId is a single text field, not an array, so we need to use Equal operator
+	if len(eventSourceIds) > 0 {
+		if len(eventSourceIds) == 1 {
+			// Single value: use Equal operator
+			sourceIdFilter := (&filters.WhereBuilder{}).
+				WithPath([]string{"eventSourceId"}).
+				WithOperator(filters.Equal).
+				WithValueText(eventSourceIds[0])
+			whereOperands = append(whereOperands, sourceIdFilter)
+		} else {
+			// Multiple values: combine with Or operator
+			sourceIdFilterOperands := make([]*filters.WhereBuilder, 0, len(eventSourceIds))
+			for _, sourceId := range eventSourceIds {
+				sourceIdFilterOperands = append(sourceIdFilterOperands, (&filters.WhereBuilder{}).
+					WithPath([]string{"eventSourceId"}).
+					WithOperator(filters.Equal).
+					WithValueText(sourceId))
+			}
+			sourceIdFilter := (&filters.WhereBuilder{}).
+				WithOperator(filters.Or).
+				WithOperands(sourceIdFilterOperands)
+			whereOperands = append(whereOperands, sourceIdFilter)
+		}
+	}
+	plan.filters = whereOperands
+
+	return plan, nil
+}
+
+func (p eventSearchPlan) searchStart() int64 {
+	return p.cursorBase.Start
+}
+
+func (p eventSearchPlan) searchEnd() int64 {
+	return p.cursorBase.End
+}
+
+// countFilter combines the structured filters, nil when there are none
+func (p eventSearchPlan) countFilter() *filters.WhereBuilder {
+	if len(p.filters) == 0 {
+		return nil
+	}
+	return (&filters.WhereBuilder{}).WithOperator(filters.And).WithOperands(p.filters)
+}
+
+// pageFilter narrows the structured filters to the events after the previous
+// page when paging by keyset, nil when there is nothing to filter on
+func (p eventSearchPlan) pageFilter() *filters.WhereBuilder {
+	whereOperands := append([]*filters.WhereBuilder{}, p.filters...)
+	if p.cursor != nil && p.order == eventSearchOrderStartTime {
+		whereOperands = append(whereOperands, (&filters.WhereBuilder{}).
+			WithPath([]string{"startTime"}).
+			WithOperator(filters.GreaterThanEqual).
+			WithValueInt(p.cursor.After))
+		for _, seenId := range p.cursor.Seen {
+			whereOperands = append(whereOperands, (&filters.WhereBuilder{}).
+				WithPath([]string{"id"}).
+				WithOperator(filters.NotEqual).
+				WithValueText(seenId))
+		}
+	}
+	if len(whereOperands) == 0 {
+		return nil
+	}
+	return (&filters.WhereBuilder{}).WithOperator(filters.And).WithOperands(whereOperands)
+}
+
+// fetchLimit asks for one extra hit, which tells us whether there is another
+// page without a count query. 0 means a score ordered search has run past
+// the window Weaviate will serve
+func (p eventSearchPlan) fetchLimit() int {
+	fetchLimit := p.limit + 1
+	if p.order == eventSearchOrderScore && p.offset+fetchLimit > maxEventSearchWindow {
+		fetchLimit = maxEventSearchWindow - p.offset
+	}
+	return max(fetchLimit, 0)
+}
+
+// exhaustedResponse is the empty page served once `fetchLimit` is 0
+func (p eventSearchPlan) exhaustedResponse() types.EventSearchResponse {
+	return types.EventSearchResponse{
+		Query:  p.hybridQuery,
+		Events: []types.Event{},
+		Total:  p.cursorBase.Total,
+	}
+}
+
+// response builds the page out of `events`, normalized from `hits` raw
+// results fetched with `fetchLimit`. `count` totals the search when the
+// first page doesn't hold all of it
+func (p eventSearchPlan) response(hits int, events []types.Event, parseDates string, count func() (int, error)) types.EventSearchResponse {
+	hasMore := hits > p.limit
+	if hasMore {
+		hits = p.limit
+		if len(events) > hits {
+			events = events[:hits]
+		}
+	}
+	if p.order == eventSearchOrderScore && p.offset+hits >= maxEventSearchWindow {
+		hasMore = false
+	}
+
+	for i := range events {
+		if parseDates == "1" && events[i].Timezone.String() != "" {
+			localizedTime, localizedDate := helpers.GetLocalDateAndTime(events[i].StartTime, events[i].Timezone)
+			events[i].LocalizedStartTime = localizedTime
+			events[i].LocalizedStartDate = localizedDate
+		}
+	}
+
+	cursorBase := p.cursorBase
+	if p.cursor == nil {
+		cursorBase.Total = hits
+		if hasMore {
+			total, err := count()
+			if err != nil {
+				log.Printf("Warning: could not count search results: %v", err)
+			} else {
+				cursorBase.Total = total
+			}
+		}
+	}
+
+	nextCursor := ""
+	if hasMore {
+		nextCursor = nextEventSearchCursor(p.cursor, cursorBase, hits, events)
+	}
+
+	// The cursor above is built from every hit so paging doesn't revisit them,
+	// only now are the open ended hits that already ended dropped. The total
+	// still counts them
+	if p.overlap {
+		overlapping := []types.Event{}
+		for _, event := range events {
+			if eventOverlapsWindow(event, p.searchStart(), p.searchEnd()) {
+				overlapping = append(overlapping, event)
+			}
+		}
+		events = overlapping
+	}
+
+	return types.EventSearchResponse{
+		Query:      p.hybridQuery,
+		Events:     events,
+		Total:      cursorBase.Total,
+		NextCursor: nextCursor,
+		HasMore:    nextCursor != "",
+	}
+}
@@ -0,0 +1,199 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/meetnearme/api/functions/gateway/types"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// EVENT_STORE_MEMORY is the `EVENT_STORE` value that keeps events in process
+// instead of Weaviate
+const EVENT_STORE_MEMORY = "memory"
+
+// EventStore is where events are kept and searched. Handlers and services
+// reach events only through it, so the gateway runs against Weaviate or,
+// with `EVENT_STORE=memory`, without any backing service at all
+type EventStore interface {
+	types.EventService
+	// SearchEventsPage is `SearchEvents` for callers that page through
+	// results, see `SearchWeaviateEventsPage`
+	SearchEventsPage(ctx context.Context, query string, userLocation []float64, maxDistance float64, startTime, endTime int64, ownerIds []string, categories string, address string, parseDates string, eventSourceTypes []string, eventSourceIds []string, page EventSearchPage) (types.EventSearchResponse, error)
+	// UpsertEvents is `BulkUpsertEvent` returning the stored objects, events
+	// without an Id are given one
+	UpsertEvents(ctx context.Context, events []types.Event) ([]models.ObjectsGetResponse, error)
+	// UpdateEvents is `UpsertEvents` for events that must already have an Id
+	UpdateEvents(ctx context.Context, events []types.Event) ([]models.ObjectsGetResponse, error)
+	FindRecurringSeriesParents(ctx context.Context) ([]types.Event, error)
+	SearchSimilarEvents(ctx context.Context, source types.Event, radius float64, limit int, parseDates string) ([]types.Event, error)
+	GetReSharedOwners(ctx context.Context, userId string) ([]string, error)
+	// AddShadowOwner re-shares an event with `userId`, leaving the rest of
+	// the event untouched
+	AddShadowOwner(ctx context.Context, eventId string, userId string) error
+}
+
+var (
+	memoryEventStore     *MemoryEventStore
+	memoryEventStoreOnce sync.Once
+)
+
+// GetEventStore returns the store selected by `EVENT_STORE`. The in-memory
+// store is shared by the whole process, Weaviate is dialed on every call
+// like `GetWeaviateClient`
+func GetEventStore() (EventStore, error) {
+	if os.Getenv("EVENT_STORE") == EVENT_STORE_MEMORY {
+		memoryEventStoreOnce.Do(func() {
+			log.Println("INFO: EVENT_STORE=memory, events are kept in process and lost on restart")
+			memoryEventStore = NewMemoryEventStore()
+		})
+		return memoryEventStore, nil
+	}
+	client, err := GetWeaviateClient()
+	if err != nil {
+		return nil, err
+	}
+	return NewWeaviateEventStore(client), nil
+}
+
+// ResetEventStore drops the shared in-memory store
+func ResetEventStore() {
+	memoryEventStore = nil
+	memoryEventStoreOnce = sync.Once{}
+}
+
+// WeaviateEventStore is the `EventStore` backed by the Weaviate events class,
+// every call is a span and a latency observation
+type WeaviateEventStore struct {
+	Client *weaviate.Client
+}
+
+func NewWeaviateEventStore(client *weaviate.Client) *WeaviateEventStore {
+	return &WeaviateEventStore{Client: client}
+}
+
+func (s *WeaviateEventStore) BulkUpsertEvent(ctx context.Context, events []types.Event) (err error) {
+	ctx, end := startWeaviateCall(ctx, "BulkUpsertEvent")
+	defer func() { end(err) }()
+	_, err = BulkUpsertEventsToWeaviate(ctx, s.Client, events)
+	return err
+}
+
+func (s *WeaviateEventStore) SearchEvents(ctx context.Context, query string, userLocation []float64, maxDistance float64, startTime, endTime int64, ownerIds []string, categories string, address string, parseDates string, eventSourceTypes []string, eventSourceIds []string) (result types.EventSearchResponse, err error) {
+	ctx, end := startWeaviateCall(ctx, "SearchEvents")
+	defer func() { end(err) }()
+	result, err = SearchWeaviateEvents(ctx, s.Client, query, userLocation, maxDistance, startTime, endTime, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds)
+	return
+}
+
+func (s *WeaviateEventStore) SearchEventsPage(ctx context.Context, query string, userLocation []float64, maxDistance float64, startTime, endTime int64, ownerIds []string, categories string, address string, parseDates string, eventSourceTypes []string, eventSourceIds []string, page EventSearchPage) (result types.EventSearchResponse, err error) {
+	ctx, end := startWeaviateCall(ctx, "SearchEventsPage")
+	defer func() { end(err) }()
+	result, err = SearchWeaviateEventsPage(ctx, s.Client, query, userLocation, maxDistance, startTime, endTime, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds, page)
+	return
+}
+
+func (s *WeaviateEventStore) BulkGetEventByID(ctx context.Context, docIds []string, parseDates string) (result []*types.Event, err error) {
+	ctx, end := startWeaviateCall(ctx, "BulkGetEventByID")
+	defer func() { end(err) }()
+	result, err = BulkGetWeaviateEventByID(ctx, s.Client, docIds, parseDates)
+	return
+}
+
+func (s *WeaviateEventStore) GetEventByID(ctx context.Context, docId string, parseDates string) (result *types.Event, err error) {
+	ctx, end := startWeaviateCall(ctx, "GetEventByID")
+	defer func() { end(err) }()
+	result, err = GetWeaviateEventByID(ctx, s.Client, docId, parseDates)
+	return
+}
+
+func (s *WeaviateEventStore) BulkDeleteEvents(ctx context.Context, docIds []string) (err error) {
+	ctx, end := startWeaviateCall(ctx, "BulkDeleteEvents")
+	defer func() { end(err) }()
+	_, err = BulkDeleteEventsFromWeaviate(ctx, s.Client, docIds)
+	return err
+}
+
+func (s *WeaviateEventStore) UpsertEvents(ctx context.Context, events []types.Event) (result []models.ObjectsGetResponse, err error) {
+	ctx, end := startWeaviateCall(ctx, "UpsertEvents")
+	defer func() { end(err) }()
+	result, err = BulkUpsertEventsToWeaviate(ctx, s.Client, events)
+	return
+}
+
+func (s *WeaviateEventStore) UpdateEvents(ctx context.Context, events []types.Event) (result []models.ObjectsGetResponse, err error) {
+	ctx, end := startWeaviateCall(ctx, "UpdateEvents")
+	defer func() { end(err) }()
+	result, err = BulkUpdateWeaviateEventsByID(ctx, s.Client, events)
+	return
+}
+
+func (s *WeaviateEventStore) FindRecurringSeriesParents(ctx context.Context) (result []types.Event, err error) {
+	ctx, end := startWeaviateCall(ctx, "FindRecurringSeriesParents")
+	defer func() { end(err) }()
+	result, err = FindRecurringSeriesParents(ctx, s.Client)
+	return
+}
+
+func (s *WeaviateEventStore) SearchSimilarEvents(ctx context.Context, source types.Event, radius float64, limit int, parseDates string) (result []types.Event, err error) {
+	ctx, end := startWeaviateCall(ctx, "SearchSimilarEvents")
+	defer func() { end(err) }()
+	result, err = SearchSimilarEvents(ctx, s.Client, source, radius, limit, parseDates)
+	return
+}
+
+func (s *WeaviateEventStore) GetReSharedOwners(ctx context.Context, userId string) (result []string, err error) {
+	ctx, end := startWeaviateCall(ctx, "GetReSharedOwners")
+	defer func() { end(err) }()
+	result, err = GetReSharedOwners(ctx, s.Client, userId)
+	return
+}
+
+func (s *WeaviateEventStore) AddShadowOwner(ctx context.Context, eventId string, userId string) (err error) {
+	ctx, end := startWeaviateCall(ctx, "AddShadowOwner")
+	defer func() { end(err) }()
+	className := EventClassName()
+	resp, err := s.Client.Data().ObjectsGetter().
+		WithID(eventId).
+		WithClassName(className).
+		Do(ctx)
+
+	var existingShadowOwners []string
+	if err == nil && len(resp) > 0 && resp[0] != nil {
+		if props, ok := resp[0].Properties.(map[string]interface{}); ok {
+			if shadowOwnersSlice, ok := props["shadowOwners"].([]interface{}); ok {
+				for _, owner := range shadowOwnersSlice {
+					if ownerStr, ok := owner.(string); ok {
+						existingShadowOwners = append(existingShadowOwners, ownerStr)
+					}
+				}
+			}
+		}
+	}
+	if !slices.Contains(existingShadowOwners, userId) {
+		existingShadowOwners = append(existingShadowOwners, userId)
+	}
+
+	return s.Client.Data().Updater().
+		WithMerge(). // merges properties into the object
+		WithID(eventId).
+		WithClassName(className).
+		WithProperties(map[string]interface{}{
+			"shadowOwners": existingShadowOwners,
+		}).
+		Do(ctx)
+}
+
+// startWeaviateCall starts the span of a Weaviate call, the returned func
+// ends it and observes its latency
+func startWeaviateCall(ctx context.Context, operation string) (context.Context, func(error)) {
+	ctx, span, start := StartExternalSpan(ctx, "weaviate."+operation, "weaviate")
+	return ctx, func(err error) {
+		RecordWeaviateRequest(operation, start, err)
+		EndExternalSpan(span, start, err)
+	}
+}
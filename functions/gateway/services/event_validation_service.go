@@ -0,0 +1,471 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/types"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+const (
+	// Errors reject the event from the batch API and quarantine it from
+	// Seshu, warnings are reported and the event is published anyway
+	EVENT_VALIDATION_SEVERITY_ERROR   = "error"
+	EVENT_VALIDATION_SEVERITY_WARNING = "warning"
+
+	EVENT_RULE_START_IN_PAST       = "start_in_past"
+	EVENT_RULE_END_BEFORE_START    = "end_before_start"
+	EVENT_RULE_TIMEZONE_REGION     = "timezone_region"
+	EVENT_RULE_ADDRESS_MISMATCH    = "address_mismatch"
+	EVENT_RULE_MISSING_DESCRIPTION = "missing_description"
+	EVENT_RULE_UNSAFE_URL          = "unsafe_url"
+	EVENT_RULE_SUSPICIOUS_URL      = "suspicious_url"
+	EVENT_RULE_DUPLICATE           = "duplicate"
+
+	// Events that started less than this long ago aren't "in the past" yet
+	eventRulePastGrace = time.Hour
+	// An address naming a city further than this from the coordinates is
+	// flagged
+	eventRuleAddressMaxMiles = 100.0
+	// Events with the same name starting within this window, this close, are
+	// the same event
+	eventRuleDuplicateWindow = 15 * time.Minute
+	eventRuleDuplicateMiles  = 0.25
+)
+
+// EventValidationRule checks one event of a batch against the rest of the
+// batch and the validator's lookups. `Check` returns why the event breaks
+// the rule, or "" when it doesn't
+type EventValidationRule struct {
+	Name     string
+	Severity string
+	Check    func(ctx context.Context, v *EventValidator, events []types.Event, i int) (string, error)
+}
+
+// EventValidator runs `Rules` over batches of events that already passed
+// struct validation
+type EventValidator struct {
+	Rules []EventValidationRule
+	Now   func() time.Time
+	// FindCandidates returns stored events inside `bounds` starting between
+	// `startTime` and `endTime`. The duplicate rule calls it once per batch
+	// and only checks the batch itself when it's nil
+	FindCandidates func(ctx context.Context, bounds MapBounds, startTime, endTime int64) ([]types.Event, error)
+
+	// candidates caches `FindCandidates` for the batch being checked
+	candidates       []types.Event
+	candidatesErr    error
+	candidatesLoaded bool
+}
+
+// DefaultEventValidationRules are the rules NewEventValidator starts with.
+// Append to it, or to a validator's `Rules`, to add a rule
+var DefaultEventValidationRules = []EventValidationRule{
+	{Name: EVENT_RULE_START_IN_PAST, Severity: EVENT_VALIDATION_SEVERITY_ERROR, Check: checkEventStartInPast},
+	{Name: EVENT_RULE_END_BEFORE_START, Severity: EVENT_VALIDATION_SEVERITY_ERROR, Check: checkEventEndBeforeStart},
+	{Name: EVENT_RULE_TIMEZONE_REGION, Severity: EVENT_VALIDATION_SEVERITY_WARNING, Check: checkEventTimezoneRegion},
+	{Name: EVENT_RULE_ADDRESS_MISMATCH, Severity: EVENT_VALIDATION_SEVERITY_WARNING, Check: checkEventAddressMismatch},
+	{Name: EVENT_RULE_MISSING_DESCRIPTION, Severity: EVENT_VALIDATION_SEVERITY_WARNING, Check: checkEventMissingDescription},
+	{Name: EVENT_RULE_UNSAFE_URL, Severity: EVENT_VALIDATION_SEVERITY_ERROR, Check: checkEventUnsafeUrl},
+	{Name: EVENT_RULE_SUSPICIOUS_URL, Severity: EVENT_VALIDATION_SEVERITY_WARNING, Check: checkEventSuspiciousUrl},
+	{Name: EVENT_RULE_DUPLICATE, Severity: EVENT_VALIDATION_SEVERITY_ERROR, Check: checkEventDuplicate},
+}
+
+// NewEventValidator checks against DefaultEventValidationRules, looking up
+// duplicates in `events` when it isn't nil
+func NewEventValidator(events EventStore) *EventValidator {
+	v := &EventValidator{
+		Rules: slices.Clone(DefaultEventValidationRules),
+		Now:   time.Now,
+	}
+	if events != nil {
+		v.FindCandidates = func(ctx context.Context, bounds MapBounds, startTime, endTime int64) ([]types.Event, error) {
+			candidates := []types.Event{}
+			page := EventSearchPage{Limit: constants.MAX_MAP_SEARCH_LIMIT, Bounds: &bounds}
+			for {
+				res, err := events.SearchEventsPage(ctx, "", nil, 0, startTime, endTime, nil, "", "", "", constants.ALL_EVENT_SOURCE_TYPES, nil, page)
+				if err != nil {
+					return nil, err
+				}
+				candidates = append(candidates, res.Events...)
+				if !res.HasMore || res.NextCursor == "" {
+					return candidates, nil
+				}
+				page.Cursor = res.NextCursor
+			}
+		}
+	}
+	return v
+}
+
+// Check runs every rule over every event. A rule that can't be checked, say
+// because a lookup failed, is skipped with a log rather than failing the batch
+func (v *EventValidator) Check(ctx context.Context, events []types.Event) []types.EventValidationIssue {
+	// Lookups are cached per batch, on a copy so `v` can check batches
+	// concurrently
+	batch := &EventValidator{Rules: v.Rules, Now: v.Now, FindCandidates: v.FindCandidates}
+	issues := []types.EventValidationIssue{}
+	for i, event := range events {
+		for _, rule := range batch.Rules {
+			message, err := rule.Check(ctx, batch, events, i)
+			if err != nil {
+				log.Printf("WARN: skipped event validation rule '%s' for event at index %d: %v", rule.Name, i, err)
+				continue
+			}
+			if message == "" {
+				continue
+			}
+			issues = append(issues, types.EventValidationIssue{
+				Index:    i,
+				EventId:  event.Id,
+				Rule:     rule.Name,
+				Severity: rule.Severity,
+				Message:  message,
+			})
+		}
+	}
+	return issues
+}
+
+func (v *EventValidator) now() time.Time {
+	if v.Now == nil {
+		return time.Now()
+	}
+	return v.Now()
+}
+
+// EventValidationErrors returns the issues with error severity
+func EventValidationErrors(issues []types.EventValidationIssue) []types.EventValidationIssue {
+	errs := []types.EventValidationIssue{}
+	for _, issue := range issues {
+		if issue.Severity == EVENT_VALIDATION_SEVERITY_ERROR {
+			errs = append(errs, issue)
+		}
+	}
+	return errs
+}
+
+// BulkValidateEventsWithRules is BulkValidateEvents followed by `validator`.
+// Error-severity issues fail the batch like a struct validation error does,
+// the returned issues are the warnings the batch was accepted with. A nil
+// `validator` only checks struct tags
+func BulkValidateEventsWithRules(ctx context.Context, rawEvents []RawEvent, requireIds bool, validator *EventValidator) ([]types.Event, []types.EventValidationIssue, int, error) {
+	events, statusCode, err := BulkValidateEvents(rawEvents, requireIds)
+	if err != nil || validator == nil {
+		return events, []types.EventValidationIssue{}, statusCode, err
+	}
+	issues := validator.Check(ctx, events)
+	if errs := EventValidationErrors(issues); len(errs) > 0 {
+		return nil, issues, http.StatusBadRequest, fmt.Errorf("invalid body: invalid event at index %d: %s: %s", errs[0].Index, errs[0].Rule, errs[0].Message)
+	}
+	return events, issues, http.StatusOK, nil
+}
+
+// QuarantineEvents splits Seshu-ingested `events` (the validated form of
+// `rawEvents`) into those fit to publish and those that broke an error rule.
+// The latter are saved to `store` for their owner to review instead
+func QuarantineEvents(ctx context.Context, store interfaces.PostgresServiceInterface, validator *EventValidator, rawEvents []RawEvent, events []types.Event, seshuJobUrl string) ([]types.Event, []types.QuarantinedEvent, error) {
+	issuesByIndex := map[int]types.EventValidationIssues{}
+	for _, issue := range validator.Check(ctx, events) {
+		if issue.Severity == EVENT_VALIDATION_SEVERITY_WARNING {
+			log.Printf("INFO: Seshu event '%s' from %s: %s: %s", events[issue.Index].Name, seshuJobUrl, issue.Rule, issue.Message)
+		}
+		issuesByIndex[issue.Index] = append(issuesByIndex[issue.Index], issue)
+	}
+
+	publish := []types.Event{}
+	quarantined := []types.QuarantinedEvent{}
+	createdAt := validator.now().Unix()
+	for i, event := range events {
+		issues := issuesByIndex[i]
+		if len(EventValidationErrors(issues)) == 0 {
+			publish = append(publish, event)
+			continue
+		}
+		raw, err := json.Marshal(rawEvents[i])
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to marshal quarantined event: %w", err)
+		}
+		ownerId := ""
+		if len(event.EventOwners) > 0 {
+			ownerId = event.EventOwners[0]
+		}
+		// Events with a stable id, like iCal ones, keep one quarantine entry
+		// across runs of their source
+		id := uuid.NewString()
+		if rawEvents[i].Id != "" {
+			id = uuid.NewSHA1(uuid.NameSpaceURL, []byte(seshuJobUrl+"|"+rawEvents[i].Id)).String()
+		}
+		quarantined = append(quarantined, types.QuarantinedEvent{
+			Id:          id,
+			OwnerId:     ownerId,
+			SeshuJobUrl: seshuJobUrl,
+			Name:        event.Name,
+			StartTime:   event.StartTime,
+			Event:       types.JSONDocument(raw),
+			Issues:      issues,
+			CreatedAt:   createdAt,
+		})
+	}
+	if err := store.SaveQuarantinedEvents(ctx, quarantined); err != nil {
+		return nil, nil, fmt.Errorf("failed to save quarantined events: %w", err)
+	}
+	return publish, quarantined, nil
+}
+
+// PublishQuarantinedEvent upserts a reviewed quarantined event and drops it
+// from quarantine. Only struct tags are checked again, publishing is the
+// owner's call on the rule issues
+func PublishQuarantinedEvent(ctx context.Context, store interfaces.PostgresServiceInterface, events EventStore, quarantined types.QuarantinedEvent) ([]models.ObjectsGetResponse, error) {
+	var raw RawEvent
+	if err := json.Unmarshal(quarantined.Event, &raw); err != nil {
+		return nil, fmt.Errorf("failed to read quarantined event: %w", err)
+	}
+	validated, _, err := BulkValidateEvents([]RawEvent{raw}, false)
+	if err != nil {
+		return nil, err
+	}
+	res, err := events.UpsertEvents(ctx, validated)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert event: %w", err)
+	}
+	if err := store.DeleteQuarantinedEvent(ctx, quarantined.Id); err != nil {
+		return nil, fmt.Errorf("published but failed to remove from quarantine: %w", err)
+	}
+	return res, nil
+}
+
+func checkEventStartInPast(ctx context.Context, v *EventValidator, events []types.Event, i int) (string, error) {
+	event := events[i]
+	now := v.now()
+	if event.StartTime >= now.Add(-eventRulePastGrace).Unix() || event.EndTime > now.Unix() {
+		return "", nil
+	}
+	return fmt.Sprintf("starts %s, which is in the past", time.Unix(event.StartTime, 0).In(&event.Timezone).Format(time.RFC3339)), nil
+}
+
+func checkEventEndBeforeStart(ctx context.Context, v *EventValidator, events []types.Event, i int) (string, error) {
+	event := events[i]
+	if event.EndTime == 0 || event.EndTime >= event.StartTime {
+		return "", nil
+	}
+	return "ends before it starts", nil
+}
+
+// checkEventTimezoneRegion compares UTC offsets at the event's start, not
+// zone names, so neighbouring zones that keep the same time aren't flagged
+func checkEventTimezoneRegion(ctx context.Context, v *EventValidator, events []types.Event, i int) (string, error) {
+	event := events[i]
+	derived := DeriveTimezoneFromCoordinates(event.Lat, event.Long)
+	if derived == "" || derived == event.Timezone.String() {
+		return "", nil
+	}
+	loc, err := time.LoadLocation(derived)
+	if err != nil {
+		return "", err
+	}
+	start := time.Unix(event.StartTime, 0)
+	_, derivedOffset := start.In(loc).Zone()
+	_, eventOffset := start.In(&event.Timezone).Zone()
+	if derivedOffset == eventOffset {
+		return "", nil
+	}
+	return fmt.Sprintf("coordinates are in %s but the event's timezone is %s", derived, event.Timezone.String()), nil
+}
+
+// checkEventAddressMismatch looks for a known city among the address's comma
+// separated parts, after the street, and flags it when every city by that
+// name is far from the coordinates
+func checkEventAddressMismatch(ctx context.Context, v *EventValidator, events []types.Event, i int) (string, error) {
+	event := events[i]
+	parts := strings.Split(event.Address, ",")
+	for _, part := range parts[min(1, len(parts)-1):] {
+		name := strings.ToLower(strings.TrimSpace(part))
+		if name == "" {
+			continue
+		}
+		nearest := -1.0
+		for _, index := range helpers.CityIndex[name] {
+			city := helpers.Cities[index]
+			if strings.ToLower(city.City) != name {
+				continue
+			}
+			miles := milesBetween(event.Lat, event.Long, city.Latitude, city.Longitude)
+			if nearest < 0 || miles < nearest {
+				nearest = miles
+			}
+		}
+		if nearest < 0 {
+			continue
+		}
+		if nearest > eventRuleAddressMaxMiles {
+			return fmt.Sprintf("address is in %s but the coordinates are %.0f mi away", strings.TrimSpace(part), nearest), nil
+		}
+		return "", nil
+	}
+	return "", nil
+}
+
+func checkEventMissingDescription(ctx context.Context, v *EventValidator, events []types.Event, i int) (string, error) {
+	event := events[i]
+	description := strings.TrimSpace(event.Description)
+	if description == "" {
+		return "has no description", nil
+	}
+	if strings.EqualFold(description, strings.TrimSpace(event.Name)) {
+		return "description only repeats the name", nil
+	}
+	return "", nil
+}
+
+// eventUrls pairs each URL field the event has set with its value
+func eventUrls(event types.Event) [][2]string {
+	urls := [][2]string{}
+	if event.SourceUrl != "" {
+		urls = append(urls, [2]string{"sourceUrl", event.SourceUrl})
+	}
+	if event.ImageUrl != "" {
+		urls = append(urls, [2]string{"imageUrl", event.ImageUrl})
+	}
+	return urls
+}
+
+func checkEventUnsafeUrl(ctx context.Context, v *EventValidator, events []types.Event, i int) (string, error) {
+	for _, fieldUrl := range eventUrls(events[i]) {
+		field, value := fieldUrl[0], fieldUrl[1]
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" {
+			return fmt.Sprintf("%s isn't a valid URL", field), nil
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Sprintf("%s uses the '%s' scheme, only http and https are allowed", field, u.Scheme), nil
+		}
+		if u.User != nil {
+			return fmt.Sprintf("%s carries credentials", field), nil
+		}
+	}
+	return "", nil
+}
+
+var suspiciousUrlHosts = []string{"bit.ly", "tinyurl.com", "t.co", "goo.gl", "ow.ly", "is.gd", "buff.ly", "rebrand.ly", "cutt.ly", "shorturl.at", "tiny.cc"}
+
+func checkEventSuspiciousUrl(ctx context.Context, v *EventValidator, events []types.Event, i int) (string, error) {
+	for _, fieldUrl := range eventUrls(events[i]) {
+		field, value := fieldUrl[0], fieldUrl[1]
+		u, err := url.Parse(value)
+		if err != nil || u.Host == "" {
+			// Reported by the unsafe URL rule
+			continue
+		}
+		host := strings.ToLower(u.Hostname())
+		switch {
+		case net.ParseIP(host) != nil:
+			return fmt.Sprintf("%s points at an IP address", field), nil
+		case slices.Contains(suspiciousUrlHosts, strings.TrimPrefix(host, "www.")):
+			return fmt.Sprintf("%s goes through the link shortener %s", field, host), nil
+		case strings.HasPrefix(host, "xn--") || strings.Contains(host, ".xn--"):
+			return fmt.Sprintf("%s has an internationalized host that may imitate another site", field), nil
+		case u.Port() != "" && u.Port() != "80" && u.Port() != "443":
+			return fmt.Sprintf("%s uses the non-standard port %s", field, u.Port()), nil
+		}
+	}
+	return "", nil
+}
+
+func normalizeEventName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
+
+func isSameEvent(a, b types.Event) bool {
+	if normalizeEventName(a.Name) != normalizeEventName(b.Name) {
+		return false
+	}
+	gap := a.StartTime - b.StartTime
+	if gap < 0 {
+		gap = -gap
+	}
+	return gap <= int64(eventRuleDuplicateWindow.Seconds()) &&
+		milesBetween(a.Lat, a.Long, b.Lat, b.Long) <= eventRuleDuplicateMiles
+}
+
+// isSeriesRelative reports whether one event is the series parent of the
+// other, a parent mirrors its next occurrence so they always look the same
+func isSeriesRelative(a, b types.Event) bool {
+	return (a.Id != "" && b.EventSourceId == a.Id) || (b.Id != "" && a.EventSourceId == b.Id)
+}
+
+// duplicateCandidateBounds is the area and time span within duplicate
+// range of any event of the batch
+func duplicateCandidateBounds(events []types.Event) (MapBounds, int64, int64) {
+	south, north := events[0].Lat, events[0].Lat
+	west, east := events[0].Long, events[0].Long
+	start, end := events[0].StartTime, events[0].StartTime
+	for _, event := range events[1:] {
+		south, north = min(south, event.Lat), max(north, event.Lat)
+		west, east = min(west, event.Long), max(east, event.Long)
+		start, end = min(start, event.StartTime), max(end, event.StartTime)
+	}
+	latPad := miToLat(eventRuleDuplicateMiles)
+	south, north = max(south-latPad, -90), min(north+latPad, 90)
+	longPad := miToLong(eventRuleDuplicateMiles, max(math.Abs(south), math.Abs(north)))
+	if math.IsInf(longPad, 0) || math.IsNaN(longPad) || longPad > 180 {
+		west, east = -180, 180
+	} else {
+		west, east = max(west-longPad, -180), min(east+longPad, 180)
+	}
+	window := int64(eventRuleDuplicateWindow.Seconds())
+	return MapBounds{West: west, South: south, East: east, North: north}, start - window, end + window
+}
+
+// duplicateCandidates loads the stored events that could duplicate any event
+// of the batch with a single lookup
+func (v *EventValidator) duplicateCandidates(ctx context.Context, events []types.Event) ([]types.Event, error) {
+	if !v.candidatesLoaded {
+		bounds, start, end := duplicateCandidateBounds(events)
+		v.candidates, v.candidatesErr = v.FindCandidates(ctx, bounds, start, end)
+		v.candidatesLoaded = true
+	}
+	return v.candidates, v.candidatesErr
+}
+
+func checkEventDuplicate(ctx context.Context, v *EventValidator, events []types.Event, i int) (string, error) {
+	event := events[i]
+	for j := 0; j < i; j++ {
+		if isSameEvent(event, events[j]) && (event.Id == "" || event.Id != events[j].Id) && !isSeriesRelative(event, events[j]) {
+			return fmt.Sprintf("same event as index %d", j), nil
+		}
+	}
+	if v.FindCandidates == nil {
+		return "", nil
+	}
+	candidates, err := v.duplicateCandidates(ctx, events)
+	if err != nil {
+		return "", err
+	}
+	for _, existing := range candidates {
+		if existing.Id != event.Id && isSameEvent(event, existing) && !isSeriesRelative(event, existing) {
+			return fmt.Sprintf("duplicates existing event %s", existing.Id), nil
+		}
+	}
+	return "", nil
+}
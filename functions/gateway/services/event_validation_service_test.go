@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+var eventValidationNow = time.Date(2030, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func validationRawEvent(name string, start time.Time) RawEvent {
+	sourceUrl := "https://example.com/jazz"
+	return RawEvent{
+		RawEventData: RawEventData{
+			EventOwners:     []string{"owner-1"},
+			EventOwnerName:  "Owner",
+			EventSourceType: constants.ES_SINGLE_EVENT,
+			Name:            name,
+			Description:     "Live music all night",
+			Address:         "111 N State St, Chicago, IL 60602",
+			Lat:             41.8832,
+			Long:            -87.6276,
+			Timezone:        "America/Chicago",
+		},
+		StartTime: start.Format(time.RFC3339),
+		SourceUrl: &sourceUrl,
+	}
+}
+
+func validationEvent(t *testing.T, mutate func(raw *RawEvent)) types.Event {
+	t.Helper()
+	raw := validationRawEvent("Jazz Night", eventValidationNow.Add(48*time.Hour))
+	if mutate != nil {
+		mutate(&raw)
+	}
+	event, err := ConvertRawEventToEvent(raw, false)
+	if err != nil {
+		t.Fatalf("failed to convert event: %v", err)
+	}
+	return event
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestEventValidationRules(t *testing.T) {
+	validator := NewEventValidator(nil)
+	validator.Now = func() time.Time { return eventValidationNow }
+
+	tests := []struct {
+		name     string
+		mutate   func(raw *RawEvent)
+		wantRule string
+	}{
+		{name: "clean event"},
+		{name: "started yesterday", mutate: func(raw *RawEvent) {
+			raw.StartTime = eventValidationNow.Add(-24 * time.Hour).Format(time.RFC3339)
+		}, wantRule: EVENT_RULE_START_IN_PAST},
+		{name: "ends before it starts", mutate: func(raw *RawEvent) {
+			raw.EndTime = eventValidationNow.Add(47 * time.Hour).Format(time.RFC3339)
+		}, wantRule: EVENT_RULE_END_BEFORE_START},
+		{name: "timezone from another region", mutate: func(raw *RawEvent) {
+			raw.Timezone = "America/Los_Angeles"
+		}, wantRule: EVENT_RULE_TIMEZONE_REGION},
+		{name: "address in another city", mutate: func(raw *RawEvent) {
+			raw.Address = "500 Main St, Houston, TX 77002"
+		}, wantRule: EVENT_RULE_ADDRESS_MISMATCH},
+		{name: "description repeats the name", mutate: func(raw *RawEvent) {
+			raw.Description = "jazz night"
+		}, wantRule: EVENT_RULE_MISSING_DESCRIPTION},
+		{name: "javascript url", mutate: func(raw *RawEvent) {
+			raw.SourceUrl = stringPtr("javascript:alert(1)")
+		}, wantRule: EVENT_RULE_UNSAFE_URL},
+		{name: "shortened url", mutate: func(raw *RawEvent) {
+			raw.ImageUrl = stringPtr("https://bit.ly/abc123")
+		}, wantRule: EVENT_RULE_SUSPICIOUS_URL},
+		{name: "ip address url", mutate: func(raw *RawEvent) {
+			raw.SourceUrl = stringPtr("http://203.0.113.7/tickets")
+		}, wantRule: EVENT_RULE_SUSPICIOUS_URL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issues := validator.Check(context.Background(), []types.Event{validationEvent(t, tt.mutate)})
+			rules := []string{}
+			for _, issue := range issues {
+				rules = append(rules, issue.Rule)
+			}
+			if tt.wantRule == "" {
+				if len(issues) > 0 {
+					t.Fatalf("expected no issues, got %+v", issues)
+				}
+				return
+			}
+			if len(issues) != 1 || issues[0].Rule != tt.wantRule {
+				t.Fatalf("expected only %s, got %v", tt.wantRule, rules)
+			}
+		})
+	}
+}
+
+func TestEventValidationDuplicates(t *testing.T) {
+	existing := validationEvent(t, nil)
+	existing.Id = "existing-1"
+
+	validator := NewEventValidator(nil)
+	validator.Now = func() time.Time { return eventValidationNow }
+	lookups := 0
+	validator.FindCandidates = func(ctx context.Context, bounds MapBounds, startTime, endTime int64) ([]types.Event, error) {
+		lookups++
+		if existing.Lat < bounds.South || existing.Lat > bounds.North || existing.Long < bounds.West || existing.Long > bounds.East {
+			t.Errorf("expected the bounds %v to cover the batch", bounds)
+		}
+		if existing.StartTime < startTime || existing.StartTime > endTime {
+			t.Errorf("expected %d..%d to cover the batch", startTime, endTime)
+		}
+		return []types.Event{existing}, nil
+	}
+
+	renamed := validationEvent(t, func(raw *RawEvent) { raw.Name = "Blues Night" })
+	sameAgain := validationEvent(t, func(raw *RawEvent) { raw.Name = "Blues night!" })
+	update := validationEvent(t, nil)
+	update.Id = existing.Id
+	copied := validationEvent(t, func(raw *RawEvent) { raw.Name = "JAZZ NIGHT" })
+
+	issues := validator.Check(context.Background(), []types.Event{renamed, sameAgain, copied})
+	want := map[int]string{1: "same event as index 0", 2: "duplicates existing event existing-1"}
+	if len(issues) != len(want) {
+		t.Fatalf("expected %d duplicate issues, got %+v", len(want), issues)
+	}
+	for _, issue := range issues {
+		if issue.Rule != EVENT_RULE_DUPLICATE || issue.Message != want[issue.Index] {
+			t.Errorf("unexpected issue %+v", issue)
+		}
+	}
+	if lookups != 1 {
+		t.Errorf("expected one lookup for the batch, got %d", lookups)
+	}
+
+	if issues := validator.Check(context.Background(), []types.Event{update}); len(issues) != 0 {
+		t.Errorf("expected an update of the existing event to pass, got %+v", issues)
+	}
+
+	validator.FindCandidates = func(ctx context.Context, bounds MapBounds, startTime, endTime int64) ([]types.Event, error) {
+		return nil, errors.New("weaviate is down")
+	}
+	if issues := validator.Check(context.Background(), []types.Event{copied}); len(issues) != 0 {
+		t.Errorf("expected a failed lookup to skip the rule, got %+v", issues)
+	}
+}
+
+func TestEventValidationDuplicatesFromStore(t *testing.T) {
+	store := NewMemoryEventStore()
+	existing := validationEvent(t, nil)
+	existing.Id = "00000000-0000-4000-8000-000000000001"
+	farAway := validationEvent(t, func(raw *RawEvent) { raw.Lat, raw.Long = 51.5, -0.1 })
+	farAway.Id = "00000000-0000-4000-8000-000000000002"
+	if err := store.BulkUpsertEvent(context.Background(), []types.Event{existing, farAway}); err != nil {
+		t.Fatalf("failed to seed store: %v", err)
+	}
+	validator := NewEventValidator(store)
+	validator.Now = func() time.Time { return eventValidationNow }
+
+	copied := validationEvent(t, func(raw *RawEvent) { raw.Name = "JAZZ NIGHT" })
+	later := validationEvent(t, func(raw *RawEvent) {
+		raw.Name = "Jazz Night"
+		raw.StartTime = eventValidationNow.Add(30 * 24 * time.Hour).Format(time.RFC3339)
+	})
+	issues := validator.Check(context.Background(), []types.Event{copied, later})
+	duplicates := []types.EventValidationIssue{}
+	for _, issue := range issues {
+		if issue.Rule == EVENT_RULE_DUPLICATE {
+			duplicates = append(duplicates, issue)
+		}
+	}
+	if len(duplicates) != 1 || duplicates[0].Index != 0 || !strings.Contains(duplicates[0].Message, existing.Id) {
+		t.Errorf("expected only the copy flagged as a duplicate of the stored event, got %+v", duplicates)
+	}
+}
+
+func TestBulkValidateEventsWithRules(t *testing.T) {
+	validator := NewEventValidator(nil)
+	validator.Now = func() time.Time { return eventValidationNow }
+	ctx := context.Background()
+
+	warned := validationRawEvent("Jazz Night", eventValidationNow.Add(48*time.Hour))
+	warned.ImageUrl = stringPtr("https://tinyurl.com/poster")
+	events, issues, status, err := BulkValidateEventsWithRules(ctx, []RawEvent{warned}, false, validator)
+	if err != nil || status != http.StatusOK || len(events) != 1 {
+		t.Fatalf("expected the batch accepted, got %d %v", status, err)
+	}
+	if len(issues) != 1 || issues[0].Severity != EVENT_VALIDATION_SEVERITY_WARNING {
+		t.Errorf("expected one warning, got %+v", issues)
+	}
+
+	past := validationRawEvent("Old Jazz Night", eventValidationNow.Add(-72*time.Hour))
+	_, issues, status, err = BulkValidateEventsWithRules(ctx, []RawEvent{warned, past}, false, validator)
+	if err == nil || status != http.StatusBadRequest {
+		t.Fatalf("expected the batch rejected, got %d %v", status, err)
+	}
+	if !strings.Contains(err.Error(), "invalid event at index 1: start_in_past") {
+		t.Errorf("expected the error to name the event and rule, got %v", err)
+	}
+	if len(EventValidationErrors(issues)) != 1 {
+		t.Errorf("expected one error issue, got %+v", issues)
+	}
+
+	if _, issues, status, err = BulkValidateEventsWithRules(ctx, []RawEvent{past}, false, nil); err != nil || status != http.StatusOK || len(issues) != 0 {
+		t.Errorf("expected no rules without a validator, got %d %v %+v", status, err, issues)
+	}
+}
+
+type fakeQuarantineStore struct {
+	interfaces.PostgresServiceInterface
+	saved []types.QuarantinedEvent
+}
+
+func (f *fakeQuarantineStore) SaveQuarantinedEvents(ctx context.Context, events []types.QuarantinedEvent) error {
+	f.saved = append(f.saved, events...)
+	return nil
+}
+
+func TestQuarantineEvents(t *testing.T) {
+	validator := NewEventValidator(nil)
+	validator.Now = func() time.Time { return eventValidationNow }
+
+	good := validationRawEvent("Jazz Night", eventValidationNow.Add(48*time.Hour))
+	bad := validationRawEvent("Poetry Slam", eventValidationNow.Add(48*time.Hour))
+	bad.SourceUrl = stringPtr("ftp://example.com/slam")
+	rawEvents := []RawEvent{good, bad}
+	events, _, err := BulkValidateEvents(rawEvents, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	store := &fakeQuarantineStore{}
+	publish, quarantined, err := QuarantineEvents(context.Background(), store, validator, rawEvents, events, "example.com/events")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(publish) != 1 || publish[0].Name != "Jazz Night" {
+		t.Errorf("expected only the good event published, got %+v", publish)
+	}
+	if len(quarantined) != 1 || len(store.saved) != 1 {
+		t.Fatalf("expected one quarantined event saved, got %d %d", len(quarantined), len(store.saved))
+	}
+	saved := store.saved[0]
+	if saved.OwnerId != "owner-1" || saved.SeshuJobUrl != "example.com/events" || saved.Name != "Poetry Slam" || saved.CreatedAt != eventValidationNow.Unix() {
+		t.Errorf("unexpected quarantined event %+v", saved)
+	}
+	if len(saved.Issues) != 1 || saved.Issues[0].Rule != EVENT_RULE_UNSAFE_URL {
+		t.Errorf("expected the unsafe url issue kept, got %+v", saved.Issues)
+	}
+	var raw RawEvent
+	if err := json.Unmarshal(saved.Event, &raw); err != nil || raw.Name != "Poetry Slam" || raw.Timezone != "America/Chicago" {
+		t.Errorf("expected the raw event kept for publishing, got %+v %v", raw, err)
+	}
+}
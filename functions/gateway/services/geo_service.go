@@ -2,8 +2,11 @@ package services
 
 import (
 	"fmt"
+	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/meetnearme/api/functions/gateway/constants"
 	"github.com/meetnearme/api/functions/gateway/types"
@@ -78,3 +81,46 @@ func (s *RealGeoService) GetGeo(locationQuery string, baseUrl string) (lat strin
 
 	return lat, lon, address, nil
 }
+
+const (
+	geocodeCacheSize = 5000
+	geocodeCacheTTL  = 24 * time.Hour
+)
+
+// geocodeCache keeps the lat, long and address of recent lookups
+var geocodeCache = newLRUCache[[3]string](geocodeCacheSize, geocodeCacheTTL)
+
+// geocodeLocation resolves a free-form location through GetGeo, caching
+// results so imports that repeat a venue, or are validated again, only look
+// it up once. Failed lookups aren't cached
+func geocodeLocation(location string) (float64, float64, string, error) {
+	key := geocodeKey(location)
+	cached, ok := geocodeCache.Get(key, time.Now())
+	if !ok {
+		lat, long, address, err := GetGeoService().GetGeo(location, os.Getenv("APEX_URL"))
+		if err != nil {
+			return 0, 0, "", err
+		}
+		cached = [3]string{lat, long, address}
+		geocodeCache.Add(key, cached, time.Now())
+	}
+	lat, err := strconv.ParseFloat(cached[0], 64)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("invalid latitude %q: %w", cached[0], err)
+	}
+	long, err := strconv.ParseFloat(cached[1], 64)
+	if err != nil {
+		return 0, 0, "", fmt.Errorf("invalid longitude %q: %w", cached[1], err)
+	}
+	address := cached[2]
+	if address == "" {
+		address = location
+	}
+	return lat, long, address, nil
+}
+
+// geocodeKey is how lookups of the same place, spelled with different case
+// or spacing, are matched
+func geocodeKey(location string) string {
+	return strings.ToLower(strings.Join(strings.Fields(location), " "))
+}
@@ -0,0 +1,270 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodb_types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	internal_types "github.com/meetnearme/api/functions/gateway/types"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	HEALTH_STATUS_OK       = "ok"
+	HEALTH_STATUS_FAILING  = "failing"
+	HEALTH_STATUS_SKIPPED  = "skipped"
+	HEALTH_STATUS_DRAINING = "draining"
+	// HEALTH_CHECK_TIMEOUT bounds each dependency check, a hung dependency
+	// fails its check instead of hanging the probe
+	HEALTH_CHECK_TIMEOUT = 3 * time.Second
+	// READINESS_CACHE_TTL is how long a readiness report is reused, every
+	// probe in that window shares one round of dependency checks
+	READINESS_CACHE_TTL = 5 * time.Second
+)
+
+// errHealthCheckSkipped marks a dependency this instance isn't configured
+// to use, it doesn't make the instance unready
+var errHealthCheckSkipped = errors.New("not configured")
+
+// healthCheckTables are the DynamoDB tables the gateway reads and writes
+var healthCheckTables = []string{
+	constants.RsvpsTablePrefix,
+	constants.PurchasesTablePrefix,
+	constants.PurchasablesTablePrefix,
+	constants.SeshuSessionTablePrefix,
+	constants.RegistrationsTablePrefix,
+	constants.RegistrationFieldsTablePrefix,
+	constants.CompetitionResultsTablePrefix,
+	constants.CompetitionConfigTablePrefix,
+	constants.CompetitionRoundsTablePrefix,
+	constants.CompetitionWaitingRoomParticipantTablePrefix,
+	constants.VotesTablePrefix,
+}
+
+type HealthCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+type HealthCheckResult struct {
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+	LatencyMs int64  `json:"latencyMs"`
+}
+
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+}
+
+// Summary is the report without the breakdown, what anonymous callers see
+func (r HealthReport) Summary() HealthReport {
+	return HealthReport{Status: r.Status}
+}
+
+var draining atomic.Bool
+
+// SetDraining makes readiness fail from now on so the load balancer stops
+// sending traffic while in-flight work finishes
+func SetDraining() {
+	draining.Store(true)
+}
+
+func IsDraining() bool {
+	return draining.Load()
+}
+
+// CheckReadiness runs `checks` concurrently, each under its own
+// `HEALTH_CHECK_TIMEOUT`. The report is ok only when none failed and the
+// instance isn't draining
+func CheckReadiness(ctx context.Context, checks []HealthCheck) HealthReport {
+	report := HealthReport{Status: HEALTH_STATUS_OK, Checks: make(map[string]HealthCheckResult, len(checks))}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, check := range checks {
+		wg.Add(1)
+		go func(check HealthCheck) {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, HEALTH_CHECK_TIMEOUT)
+			defer cancel()
+			start := time.Now()
+			err := check.Check(checkCtx)
+			result := HealthCheckResult{Status: HEALTH_STATUS_OK, LatencyMs: time.Since(start).Milliseconds()}
+			if errors.Is(err, errHealthCheckSkipped) {
+				result.Status = HEALTH_STATUS_SKIPPED
+			} else if err != nil {
+				result.Status, result.Error = HEALTH_STATUS_FAILING, err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[check.Name] = result
+			if result.Status == HEALTH_STATUS_FAILING {
+				report.Status = HEALTH_STATUS_FAILING
+			}
+		}(check)
+	}
+	wg.Wait()
+	if IsDraining() {
+		report.Status = HEALTH_STATUS_DRAINING
+	}
+	return report
+}
+
+// ReadinessProbe caches the outcome of its checks for `ttl`
+type ReadinessProbe struct {
+	checks func() []HealthCheck
+	ttl    time.Duration
+
+	mu        sync.Mutex
+	report    HealthReport
+	checkedAt time.Time
+}
+
+// NewReadinessProbe builds the checks on each round, so dependencies
+// connected after startup are picked up
+func NewReadinessProbe(ttl time.Duration, checks func() []HealthCheck) *ReadinessProbe {
+	return &ReadinessProbe{checks: checks, ttl: ttl}
+}
+
+// Report returns the last report while it's fresher than the TTL and runs
+// the checks otherwise. Callers arriving during a round wait for it rather
+// than starting their own. Draining shows right away
+func (p *ReadinessProbe) Report(ctx context.Context, now time.Time) HealthReport {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.checkedAt.IsZero() || now.Sub(p.checkedAt) >= p.ttl {
+		// A caller hanging up mustn't fail the round everyone shares
+		p.report = CheckReadiness(context.WithoutCancel(ctx), p.checks())
+		p.checkedAt = now
+	}
+	report := p.report
+	if IsDraining() {
+		report.Status = HEALTH_STATUS_DRAINING
+	}
+	return report
+}
+
+func PostgresHealthCheck(s *PostgresService) HealthCheck {
+	return HealthCheck{Name: "postgres", Check: func(ctx context.Context) error {
+		if s == nil || s.DB == nil {
+			return errors.New("not connected")
+		}
+		sqlDB, err := s.DB.DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	}}
+}
+
+// NatsHealthCheck checks the connection and that the Seshu and webhook
+// streams exist
+func NatsHealthCheck(s *NatsService) HealthCheck {
+	return HealthCheck{Name: "nats", Check: func(ctx context.Context) error {
+		if s == nil || s.conn == nil {
+			return errors.New("not connected")
+		}
+		if status := s.conn.Status(); status != nats.CONNECTED {
+			return fmt.Errorf("connection is %s", status)
+		}
+		for _, stream := range []string{streamName, webhookStreamName} {
+			if _, err := s.js.Stream(ctx, stream); err != nil {
+				return fmt.Errorf("stream %s: %w", stream, err)
+			}
+		}
+		return nil
+	}}
+}
+
+// WeaviateHealthCheck checks that the events class this instance reads
+// exists, it's skipped with `EVENT_STORE=memory`
+func WeaviateHealthCheck() HealthCheck {
+	return HealthCheck{Name: "weaviate", Check: func(ctx context.Context) error {
+		if os.Getenv("EVENT_STORE") == EVENT_STORE_MEMORY {
+			return errHealthCheckSkipped
+		}
+		client, err := GetWeaviateClient()
+		if err != nil {
+			return err
+		}
+		className := EventClassName()
+		exists, err := client.Schema().ClassExistenceChecker().WithClassName(className).Do(ctx)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("class %s does not exist", className)
+		}
+		return nil
+	}}
+}
+
+// dynamoDBTableDescriber is the part of the DynamoDB client the readiness
+// check uses
+type dynamoDBTableDescriber interface {
+	DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
+}
+
+// DynamoDBHealthCheck describes every table, which fails when a table is
+// missing, isn't usable or the credentials can't reach it, without reading
+// any items
+func DynamoDBHealthCheck(db internal_types.DynamoDBAPI) HealthCheck {
+	return HealthCheck{Name: "dynamodb", Check: func(ctx context.Context) error {
+		describer, ok := db.(dynamoDBTableDescriber)
+		if !ok {
+			return fmt.Errorf("client %T can't describe tables", db)
+		}
+		for _, prefix := range healthCheckTables {
+			tableName := helpers.GetDbTableName(prefix)
+			if tableName == "" {
+				return fmt.Errorf("table %s is not configured", prefix)
+			}
+			out, err := describer.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(tableName)})
+			if err != nil {
+				return fmt.Errorf("table %s: %w", tableName, err)
+			}
+			if out.Table == nil {
+				return fmt.Errorf("table %s: no description", tableName)
+			}
+			// Tables being updated still serve reads and writes
+			if status := out.Table.TableStatus; status != dynamodb_types.TableStatusActive && status != dynamodb_types.TableStatusUpdating {
+				return fmt.Errorf("table %s is %s", tableName, status)
+			}
+		}
+		return nil
+	}}
+}
+
+// ZitadelHealthCheck fetches the instance's OpenID configuration, every
+// login and token check depends on it
+func ZitadelHealthCheck(client *http.Client) HealthCheck {
+	return HealthCheck{Name: "zitadel", Check: func(ctx context.Context) error {
+		host := os.Getenv("ZITADEL_INSTANCE_HOST")
+		if host == "" {
+			return errors.New("ZITADEL_INSTANCE_HOST is not set")
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, helpers.DefaultProtocol+host+"/.well-known/openid-configuration", nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("status %d", res.StatusCode)
+		}
+		return nil
+	}}
+}
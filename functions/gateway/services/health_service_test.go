@@ -0,0 +1,171 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/test_helpers"
+)
+
+func TestCheckReadiness(t *testing.T) {
+	defer draining.Store(false)
+
+	checks := []HealthCheck{
+		{Name: "up", Check: func(ctx context.Context) error { return nil }},
+		{Name: "unused", Check: func(ctx context.Context) error { return errHealthCheckSkipped }},
+	}
+	report := CheckReadiness(context.Background(), checks)
+	if report.Status != HEALTH_STATUS_OK {
+		t.Fatalf("expected ok, got %+v", report)
+	}
+	if report.Checks["unused"].Status != HEALTH_STATUS_SKIPPED {
+		t.Errorf("expected a skipped check, got %+v", report.Checks["unused"])
+	}
+
+	checks = append(checks,
+		HealthCheck{Name: "down", Check: func(ctx context.Context) error { return errors.New("connection refused") }},
+		HealthCheck{Name: "hung", Check: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	report = CheckReadiness(ctx, checks)
+	if report.Status != HEALTH_STATUS_FAILING {
+		t.Fatalf("expected failing, got %+v", report)
+	}
+	if report.Checks["down"].Error != "connection refused" {
+		t.Errorf("expected the error in the breakdown, got %+v", report.Checks["down"])
+	}
+	if report.Checks["hung"].Status != HEALTH_STATUS_FAILING {
+		t.Errorf("expected a hung check to fail once its time is up, got %+v", report.Checks["hung"])
+	}
+	if report.Checks["up"].Status != HEALTH_STATUS_OK {
+		t.Errorf("expected the other checks to still be reported, got %+v", report.Checks["up"])
+	}
+
+	SetDraining()
+	if report := CheckReadiness(context.Background(), checks[:1]); report.Status != HEALTH_STATUS_DRAINING {
+		t.Errorf("expected draining, got %+v", report)
+	}
+}
+
+func TestDynamoDBHealthCheck(t *testing.T) {
+	t.Setenv("USE_REMOTE_DB", "false")
+	t.Setenv("ACT_STAGE", "")
+	described := []string{}
+	db := &test_helpers.MockDynamoDBClient{
+		ScanFunc: func(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+			t.Errorf("expected no table to be scanned, got %s", aws.ToString(params.TableName))
+			return &dynamodb.ScanOutput{}, nil
+		},
+		DescribeTableFunc: func(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+			described = append(described, aws.ToString(params.TableName))
+			return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{TableStatus: types.TableStatusActive}}, nil
+		},
+	}
+	if err := DynamoDBHealthCheck(db).Check(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(described) != len(healthCheckTables) {
+		t.Errorf("expected every table to be checked, got %v", described)
+	}
+
+	db.DescribeTableFunc = func(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+		return nil, &types.ResourceNotFoundException{Message: aws.String("table not found")}
+	}
+	if err := DynamoDBHealthCheck(db).Check(context.Background()); err == nil || !strings.Contains(err.Error(), healthCheckTables[0]) {
+		t.Errorf("expected the missing table to be named, got %v", err)
+	}
+
+	db.DescribeTableFunc = func(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+		return &dynamodb.DescribeTableOutput{Table: &types.TableDescription{TableStatus: types.TableStatusDeleting}}, nil
+	}
+	if err := DynamoDBHealthCheck(db).Check(context.Background()); err == nil || !strings.Contains(err.Error(), "DELETING") {
+		t.Errorf("expected a table being deleted to fail the check, got %v", err)
+	}
+}
+
+func TestReadinessProbe(t *testing.T) {
+	defer draining.Store(false)
+
+	rounds := 0
+	failing := false
+	probe := NewReadinessProbe(time.Minute, func() []HealthCheck {
+		rounds++
+		return []HealthCheck{{Name: "db", Check: func(ctx context.Context) error {
+			if failing {
+				return errors.New("dial tcp 10.0.0.5:5432: connection refused")
+			}
+			return ctx.Err()
+		}}}
+	})
+
+	// The round runs on after the caller that started it hangs up
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	now := time.Now()
+	if report := probe.Report(ctx, now); report.Status != HEALTH_STATUS_OK || rounds != 1 {
+		t.Fatalf("expected one ok round, got %+v after %d rounds", report, rounds)
+	}
+
+	failing = true
+	if report := probe.Report(context.Background(), now.Add(time.Second)); report.Status != HEALTH_STATUS_OK || rounds != 1 {
+		t.Errorf("expected the cached report, got %+v after %d rounds", report, rounds)
+	}
+	report := probe.Report(context.Background(), now.Add(time.Minute))
+	if report.Status != HEALTH_STATUS_FAILING || rounds != 2 {
+		t.Fatalf("expected a new failing round once the cache expired, got %+v after %d rounds", report, rounds)
+	}
+	if summary := report.Summary(); summary.Status != HEALTH_STATUS_FAILING || summary.Checks != nil {
+		t.Errorf("expected the summary to leave out the breakdown, got %+v", summary)
+	}
+
+	SetDraining()
+	if report := probe.Report(context.Background(), now.Add(time.Minute)); report.Status != HEALTH_STATUS_DRAINING || rounds != 2 {
+		t.Errorf("expected draining without another round, got %+v after %d rounds", report, rounds)
+	}
+}
+
+func TestZitadelHealthCheck(t *testing.T) {
+	healthy := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/.well-known/openid-configuration" || !healthy {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	originalProtocol := helpers.DefaultProtocol
+	helpers.DefaultProtocol = "http://"
+	defer func() { helpers.DefaultProtocol = originalProtocol }()
+	t.Setenv("ZITADEL_INSTANCE_HOST", strings.TrimPrefix(server.URL, "http://"))
+
+	check := ZitadelHealthCheck(server.Client())
+	if err := check.Check(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	healthy = false
+	if err := check.Check(context.Background()); err == nil {
+		t.Error("expected a 502 to fail the check")
+	}
+}
+
+func TestWeaviateHealthCheckSkippedInMemory(t *testing.T) {
+	t.Setenv("EVENT_STORE", EVENT_STORE_MEMORY)
+	if err := WeaviateHealthCheck().Check(context.Background()); !errors.Is(err, errHealthCheckSkipped) {
+		t.Errorf("expected the check to be skipped, got %v", err)
+	}
+}
@@ -0,0 +1,1213 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+const (
+	ICAL_PROD_ID = "-//Meet Near Me//Events Feed//EN"
+	// RFC 5545 §3.1: lines SHOULD NOT be longer than 75 octets, excluding the CRLF
+	icalMaxLineOctets = 75
+	icalLocalLayout   = "20060102T150405"
+	icalUTCLayout     = "20060102T150405Z"
+	// Mirrors the default duration used by the Google Calendar link on the event
+	// details page for events that don't carry an explicit end time
+	icalDefaultDuration = "PT1H"
+	// How often subscribing clients should re-poll the feed (RFC 7986 REFRESH-INTERVAL)
+	icalRefreshInterval = "PT1H"
+)
+
+// BuildICalendarFeed serializes events into an RFC 5545 VCALENDAR document
+// suitable for calendar subscriptions. Every distinct `Event.Timezone` gets a
+// VTIMEZONE block covering the span of the events that reference it, and each
+// event becomes a VEVENT whose UID is the stable `Event.Id`
+func BuildICalendarFeed(calName string, events []types.Event, now time.Time) string {
+	var b strings.Builder
+
+	writeICalLine(&b, "BEGIN:VCALENDAR")
+	writeICalLine(&b, "VERSION:2.0")
+	writeICalLine(&b, "PRODID:"+ICAL_PROD_ID)
+	writeICalLine(&b, "CALSCALE:GREGORIAN")
+	writeICalLine(&b, "METHOD:PUBLISH")
+	if calName != "" {
+		writeICalLine(&b, "NAME:"+escapeICalText(calName))
+		writeICalLine(&b, "X-WR-CALNAME:"+escapeICalText(calName))
+	}
+	writeICalLine(&b, "REFRESH-INTERVAL;VALUE=DURATION:"+icalRefreshInterval)
+	writeICalLine(&b, "X-PUBLISHED-TTL:"+icalRefreshInterval)
+
+	for _, tz := range collectICalTimezones(events) {
+		writeVTimezone(&b, tz.loc, tz.from, tz.to)
+	}
+
+	for _, event := range events {
+		writeVEvent(&b, event, now)
+	}
+
+	writeICalLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+type icalTimezoneSpan struct {
+	loc      *time.Location
+	from, to time.Time
+}
+
+// collectICalTimezones returns one entry per named, non-UTC timezone used by
+// the events, along with the earliest and latest instant that must be
+// representable in that zone
+func collectICalTimezones(events []types.Event) []icalTimezoneSpan {
+	spans := map[string]*icalTimezoneSpan{}
+	for _, event := range events {
+		loc := event.Timezone
+		if isICalUTC(&loc) {
+			continue
+		}
+		start := time.Unix(event.StartTime, 0)
+		end := start
+		if hasExplicitEndTime(event) {
+			end = time.Unix(event.EndTime, 0)
+		}
+		name := loc.String()
+		span, ok := spans[name]
+		if !ok {
+			locCopy := loc
+			spans[name] = &icalTimezoneSpan{loc: &locCopy, from: start, to: end}
+			continue
+		}
+		if start.Before(span.from) {
+			span.from = start
+		}
+		if end.After(span.to) {
+			span.to = end
+		}
+	}
+
+	names := make([]string, 0, len(spans))
+	for name := range spans {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	result := make([]icalTimezoneSpan, 0, len(names))
+	for _, name := range names {
+		result = append(result, *spans[name])
+	}
+	return result
+}
+
+// writeVTimezone emits a VTIMEZONE whose observances are the actual zone
+// transitions of `loc` between `from` and `to`, walked with `ZoneBounds` so
+// that we don't need to reverse-engineer the tzdata rules into RRULEs
+func writeVTimezone(b *strings.Builder, loc *time.Location, from, to time.Time) {
+	writeICalLine(b, "BEGIN:VTIMEZONE")
+	writeICalLine(b, "TZID:"+loc.String())
+
+	current := from.In(loc)
+	zoneStart, zoneEnd := current.ZoneBounds()
+	if zoneStart.IsZero() {
+		// The zone has been in effect "forever" (no recorded transition), anchor
+		// the observance at the epoch
+		name, offset := current.Zone()
+		writeObservance(b, time.Unix(0, 0).UTC(), name, offset, offset, current.IsDST())
+	} else {
+		writeTransition(b, zoneStart.In(loc))
+	}
+
+	for !zoneEnd.IsZero() && !zoneEnd.After(to) {
+		transition := zoneEnd.In(loc)
+		writeTransition(b, transition)
+		_, zoneEnd = transition.ZoneBounds()
+	}
+
+	writeICalLine(b, "END:VTIMEZONE")
+}
+
+func writeTransition(b *strings.Builder, transition time.Time) {
+	name, offsetTo := transition.Zone()
+	_, offsetFrom := transition.Add(-time.Second).Zone()
+	writeObservance(b, transition, name, offsetFrom, offsetTo, transition.IsDST())
+}
+
+func writeObservance(b *strings.Builder, onset time.Time, name string, offsetFrom, offsetTo int, isDST bool) {
+	kind := "STANDARD"
+	if isDST {
+		kind = "DAYLIGHT"
+	}
+	writeICalLine(b, "BEGIN:"+kind)
+	// RFC 5545 §3.6.5: the observance DTSTART is local time expressed in the
+	// offset that was in effect prior to the onset
+	writeICalLine(b, "DTSTART:"+onset.In(time.FixedZone("", offsetFrom)).Format(icalLocalLayout))
+	writeICalLine(b, "TZOFFSETFROM:"+formatICalOffset(offsetFrom))
+	writeICalLine(b, "TZOFFSETTO:"+formatICalOffset(offsetTo))
+	if name != "" {
+		writeICalLine(b, "TZNAME:"+escapeICalText(name))
+	}
+	writeICalLine(b, "END:"+kind)
+}
+
+func writeVEvent(b *strings.Builder, event types.Event, now time.Time) {
+	loc := event.Timezone
+
+	writeICalLine(b, "BEGIN:VEVENT")
+	writeICalLine(b, "UID:"+event.Id)
+
+	lastModified := now
+	if event.UpdatedAt > 0 {
+		lastModified = time.Unix(event.UpdatedAt, 0)
+	} else if event.CreatedAt > 0 {
+		lastModified = time.Unix(event.CreatedAt, 0)
+	}
+	// DTSTAMP is when the feed was generated, how recent an edit is travels
+	// in LAST-MODIFIED
+	writeICalLine(b, "DTSTAMP:"+now.UTC().Format(icalUTCLayout))
+	writeICalLine(b, "LAST-MODIFIED:"+lastModified.UTC().Format(icalUTCLayout))
+	if event.CreatedAt > 0 {
+		writeICalLine(b, "CREATED:"+time.Unix(event.CreatedAt, 0).UTC().Format(icalUTCLayout))
+	}
+	writeICalLine(b, "SEQUENCE:"+strconv.FormatInt(icalSequence(event), 10))
+
+	writeICalLine(b, formatICalDateTime("DTSTART", event.StartTime, &loc))
+	if hasExplicitEndTime(event) {
+		writeICalLine(b, formatICalDateTime("DTEND", event.EndTime, &loc))
+	} else {
+		writeICalLine(b, "DURATION:"+icalDefaultDuration)
+	}
+
+	writeICalLine(b, "SUMMARY:"+escapeICalText(event.Name))
+	if event.Description != "" {
+		writeICalLine(b, "DESCRIPTION:"+escapeICalText(event.Description))
+	}
+	if event.Address != "" {
+		writeICalLine(b, "LOCATION:"+escapeICalText(event.Address))
+	}
+	if event.Lat != 0 || event.Long != 0 {
+		writeICalLine(b, "GEO:"+strconv.FormatFloat(event.Lat, 'f', 6, 64)+";"+strconv.FormatFloat(event.Long, 'f', 6, 64))
+	}
+	if len(event.Categories) > 0 {
+		escaped := make([]string, len(event.Categories))
+		for i, category := range event.Categories {
+			escaped[i] = escapeICalText(category)
+		}
+		writeICalLine(b, "CATEGORIES:"+strings.Join(escaped, ","))
+	}
+
+	eventUrl := event.RefUrl
+	if eventUrl == "" && event.Id != "" {
+		eventUrl = os.Getenv("APEX_URL") + "/event/" + event.Id
+	}
+	if eventUrl != "" {
+		writeICalLine(b, "URL:"+eventUrl)
+	}
+
+	// Series children point back to their parent so clients that understand
+	// RELATED-TO can group the occurrences
+	if isEventSeriesChild(event) && event.EventSourceId != "" {
+		writeICalLine(b, "RELATED-TO;RELTYPE=PARENT:"+event.EventSourceId)
+	}
+
+	writeICalLine(b, "STATUS:CONFIRMED")
+	writeICalLine(b, "TRANSP:OPAQUE")
+	writeICalLine(b, "END:VEVENT")
+}
+
+// icalSequenceEpoch is what SEQUENCE counts seconds from, late enough that
+// the value fits the 32 bit integer some clients parse it into
+var icalSequenceEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+
+// icalSequence is the event's revision number. Events don't keep a counter,
+// so it's the seconds from `icalSequenceEpoch` to the last edit, which only
+// goes up. Clients such as Outlook and Apple Calendar ignore an updated
+// VEVENT unless its SEQUENCE went up
+func icalSequence(event types.Event) int64 {
+	revised := event.UpdatedAt
+	if revised <= 0 {
+		revised = event.CreatedAt
+	}
+	return max(revised-icalSequenceEpoch, 0)
+}
+
+func isEventSeriesChild(event types.Event) bool {
+	return event.EventSourceType == constants.ES_EVENT_SERIES || event.EventSourceType == constants.ES_EVENT_SERIES_UNPUB
+}
+
+func hasExplicitEndTime(event types.Event) bool {
+	return event.EndTime > event.StartTime && event.EndTime != constants.DEFAULT_UNDEFINED_END_TIME
+}
+
+func isICalUTC(loc *time.Location) bool {
+	name := loc.String()
+	return name == "" || name == "UTC" || name == "Local"
+}
+
+func formatICalDateTime(property string, unix int64, loc *time.Location) string {
+	t := time.Unix(unix, 0)
+	if isICalUTC(loc) {
+		return property + ":" + t.UTC().Format(icalUTCLayout)
+	}
+	return property + ";TZID=" + loc.String() + ":" + t.In(loc).Format(icalLocalLayout)
+}
+
+func formatICalOffset(offsetSeconds int) string {
+	sign := "+"
+	if offsetSeconds < 0 {
+		sign = "-"
+		offsetSeconds = -offsetSeconds
+	}
+	hours := offsetSeconds / 3600
+	minutes := (offsetSeconds % 3600) / 60
+	seconds := offsetSeconds % 60
+	if seconds != 0 {
+		return fmt.Sprintf("%s%02d%02d%02d", sign, hours, minutes, seconds)
+	}
+	return fmt.Sprintf("%s%02d%02d", sign, hours, minutes)
+}
+
+// escapeICalText escapes a TEXT value per RFC 5545 §3.3.11
+func escapeICalText(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	replacer := strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\n", `\n`,
+	)
+	return replacer.Replace(s)
+}
+
+// writeICalLine writes a content line terminated by CRLF, folding it at 75
+// octets (RFC 5545 §3.1) without splitting multi-byte UTF-8 sequences
+func writeICalLine(b *strings.Builder, line string) {
+	limit := icalMaxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// continuation lines lose one octet to the leading space
+		limit = icalMaxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
+
+// ICalEvent is a VEVENT as read from an external calendar, times are resolved
+// to absolute instants using the component's TZID (or the calendar default)
+type ICalEvent struct {
+	UID          string
+	Summary      string
+	Description  string
+	Location     string
+	Url          string
+	Status       string
+	Categories   []string
+	Lat          float64
+	Long         float64
+	HasGeo       bool
+	Start        time.Time
+	End          time.Time
+	AllDay       bool
+	RRule        string
+	RDates       []time.Time
+	ExDates      []time.Time
+	RecurrenceId time.Time
+	Sequence     int
+	LastModified time.Time
+}
+
+type icalProperty struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// ParseICalendar reads every VEVENT from an RFC 5545 document. TZIDs that
+// aren't IANA names are resolved through the VTIMEZONE's X-LIC-LOCATION, then
+// the calendar's X-WR-TIMEZONE, then `defaultTimezone`, and finally UTC
+func ParseICalendar(data []byte, defaultTimezone string) ([]ICalEvent, error) {
+	lines := unfoldICalLines(string(data))
+	if len(lines) == 0 || !strings.EqualFold(strings.TrimSpace(lines[0]), "BEGIN:VCALENDAR") {
+		return nil, fmt.Errorf("not an iCalendar document: missing BEGIN:VCALENDAR")
+	}
+
+	defaultLoc := time.UTC
+	if defaultTimezone != "" {
+		if loc, err := time.LoadLocation(defaultTimezone); err == nil {
+			defaultLoc = loc
+		}
+	}
+
+	// First pass: calendar-level timezone hints, VTIMEZONE may legally appear
+	// after the VEVENTs that reference it
+	tzAliases := map[string]string{}
+	currentTzid := ""
+	inTimezone := false
+	for _, line := range lines {
+		prop, ok := parseICalProperty(line)
+		if !ok {
+			continue
+		}
+		switch {
+		case prop.name == "BEGIN" && strings.EqualFold(prop.value, "VTIMEZONE"):
+			inTimezone = true
+			currentTzid = ""
+		case prop.name == "END" && strings.EqualFold(prop.value, "VTIMEZONE"):
+			inTimezone = false
+		case inTimezone && prop.name == "TZID":
+			currentTzid = prop.value
+		case inTimezone && prop.name == "X-LIC-LOCATION" && currentTzid != "":
+			tzAliases[currentTzid] = prop.value
+		case !inTimezone && prop.name == "X-WR-TIMEZONE":
+			if loc, err := time.LoadLocation(prop.value); err == nil {
+				defaultLoc = loc
+			}
+		}
+	}
+
+	resolveLoc := func(tzid string) *time.Location {
+		if tzid == "" {
+			return defaultLoc
+		}
+		return resolveICalTimezone(tzid, tzAliases, defaultLoc)
+	}
+
+	events := []ICalEvent{}
+	var current *ICalEvent
+	// depth of nested components (e.g. VALARM) inside the current VEVENT
+	nested := 0
+	for _, line := range lines {
+		prop, ok := parseICalProperty(line)
+		if !ok {
+			continue
+		}
+		if prop.name == "BEGIN" {
+			if strings.EqualFold(prop.value, "VEVENT") && current == nil {
+				current = &ICalEvent{}
+			} else if current != nil {
+				nested++
+			}
+			continue
+		}
+		if prop.name == "END" {
+			if current != nil && nested > 0 {
+				nested--
+			} else if current != nil && strings.EqualFold(prop.value, "VEVENT") {
+				if current.UID != "" && !current.Start.IsZero() {
+					events = append(events, *current)
+				}
+				current = nil
+			}
+			continue
+		}
+		if current == nil || nested > 0 {
+			continue
+		}
+
+		loc := resolveLoc(prop.params["TZID"])
+		switch prop.name {
+		case "UID":
+			current.UID = prop.value
+		case "SUMMARY":
+			current.Summary = unescapeICalText(prop.value)
+		case "DESCRIPTION":
+			current.Description = unescapeICalText(prop.value)
+		case "LOCATION":
+			current.Location = unescapeICalText(prop.value)
+		case "URL":
+			current.Url = prop.value
+		case "STATUS":
+			current.Status = strings.ToUpper(prop.value)
+		case "CATEGORIES":
+			for _, category := range splitICalList(prop.value) {
+				if category = strings.TrimSpace(unescapeICalText(category)); category != "" {
+					current.Categories = append(current.Categories, category)
+				}
+			}
+		case "GEO":
+			latStr, longStr, found := strings.Cut(prop.value, ";")
+			if !found {
+				continue
+			}
+			lat, latErr := strconv.ParseFloat(strings.TrimSpace(latStr), 64)
+			long, longErr := strconv.ParseFloat(strings.TrimSpace(longStr), 64)
+			if latErr == nil && longErr == nil {
+				current.Lat, current.Long, current.HasGeo = lat, long, true
+			}
+		case "DTSTART":
+			start, allDay, err := parseICalDateTime(prop.value, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DTSTART %q: %w", prop.value, err)
+			}
+			current.Start, current.AllDay = start, allDay
+		case "DTEND":
+			end, _, err := parseICalDateTime(prop.value, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DTEND %q: %w", prop.value, err)
+			}
+			current.End = end
+		case "DURATION":
+			duration, err := parseICalDuration(prop.value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid DURATION %q: %w", prop.value, err)
+			}
+			if !current.Start.IsZero() && current.End.IsZero() {
+				current.End = current.Start.Add(duration)
+			}
+		case "RRULE":
+			current.RRule = prop.value
+		case "RDATE", "EXDATE":
+			for _, value := range splitICalList(prop.value) {
+				// RDATE;VALUE=PERIOD values are "start/end", only the start matters
+				value, _, _ = strings.Cut(value, "/")
+				t, _, err := parseICalDateTime(value, loc)
+				if err != nil {
+					return nil, fmt.Errorf("invalid %s %q: %w", prop.name, value, err)
+				}
+				if prop.name == "RDATE" {
+					current.RDates = append(current.RDates, t)
+				} else {
+					current.ExDates = append(current.ExDates, t)
+				}
+			}
+		case "RECURRENCE-ID":
+			recurrenceId, _, err := parseICalDateTime(prop.value, loc)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RECURRENCE-ID %q: %w", prop.value, err)
+			}
+			current.RecurrenceId = recurrenceId
+		case "SEQUENCE":
+			if sequence, err := strconv.Atoi(prop.value); err == nil {
+				current.Sequence = sequence
+			}
+		case "LAST-MODIFIED":
+			if lastModified, _, err := parseICalDateTime(prop.value, loc); err == nil {
+				current.LastModified = lastModified
+			}
+		}
+	}
+
+	for i := range events {
+		// RFC 5545 §3.6.1: an all-day event without DTEND lasts one day
+		if events[i].End.IsZero() && events[i].AllDay {
+			events[i].End = events[i].Start.AddDate(0, 0, 1)
+		}
+	}
+
+	return events, nil
+}
+
+func resolveICalTimezone(tzid string, aliases map[string]string, fallback *time.Location) *time.Location {
+	tzid = strings.Trim(tzid, `"`)
+	if loc, err := time.LoadLocation(tzid); err == nil {
+		return loc
+	}
+	if alias, ok := aliases[tzid]; ok {
+		if loc, err := time.LoadLocation(alias); err == nil {
+			return loc
+		}
+	}
+	// Some producers prefix the IANA name, e.g. "/mozilla.org/20050126_1/America/New_York"
+	segments := strings.Split(tzid, "/")
+	for i := 1; i < len(segments); i++ {
+		if loc, err := time.LoadLocation(strings.Join(segments[i:], "/")); err == nil {
+			return loc
+		}
+	}
+	return fallback
+}
+
+func unfoldICalLines(data string) []string {
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	// strip a UTF-8 BOM if present
+	data = strings.TrimPrefix(data, "\ufeff")
+
+	lines := []string{}
+	for _, line := range strings.Split(data, "\n") {
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// parseICalProperty splits a content line into name, parameters and value,
+// honouring quoted parameter values that may contain ':' or ';'
+func parseICalProperty(line string) (icalProperty, bool) {
+	inQuotes := false
+	valueIdx := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			valueIdx = i
+			break
+		}
+	}
+	if valueIdx < 0 {
+		return icalProperty{}, false
+	}
+
+	prop := icalProperty{params: map[string]string{}, value: line[valueIdx+1:]}
+	head := line[:valueIdx]
+	parts := []string{}
+	start := 0
+	inQuotes = false
+	for i, r := range head {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ';' && !inQuotes {
+			parts = append(parts, head[start:i])
+			start = i + 1
+		}
+	}
+	parts = append(parts, head[start:])
+
+	prop.name = strings.ToUpper(strings.TrimSpace(parts[0]))
+	for _, param := range parts[1:] {
+		key, val, found := strings.Cut(param, "=")
+		if !found {
+			continue
+		}
+		prop.params[strings.ToUpper(key)] = strings.Trim(val, `"`)
+	}
+	return prop, true
+}
+
+// parseICalDateTime handles DATE, floating DATE-TIME, UTC DATE-TIME ("Z")
+// and DATE-TIME values whose TZID has already been resolved into `loc`
+func parseICalDateTime(value string, loc *time.Location) (time.Time, bool, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case len(value) == len("20060102"):
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	case strings.HasSuffix(value, "Z"):
+		t, err := time.Parse(icalUTCLayout, value)
+		return t, false, err
+	default:
+		t, err := time.ParseInLocation(icalLocalLayout, value, loc)
+		return t, false, err
+	}
+}
+
+// parseICalDuration parses an RFC 5545 §3.3.6 DURATION such as "PT1H30M" or "P1W"
+func parseICalDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	sign := time.Duration(1)
+	if strings.HasPrefix(value, "-") {
+		sign = -1
+		value = value[1:]
+	}
+	value = strings.TrimPrefix(value, "+")
+	if !strings.HasPrefix(value, "P") {
+		return 0, fmt.Errorf("duration must start with P")
+	}
+	value = value[1:]
+
+	var total time.Duration
+	inTime := false
+	num := ""
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			num += string(r)
+		case r == 'T':
+			inTime = true
+		default:
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return 0, fmt.Errorf("malformed duration")
+			}
+			num = ""
+			switch {
+			case r == 'W' && !inTime:
+				total += time.Duration(n) * 7 * 24 * time.Hour
+			case r == 'D' && !inTime:
+				total += time.Duration(n) * 24 * time.Hour
+			case r == 'H' && inTime:
+				total += time.Duration(n) * time.Hour
+			case r == 'M' && inTime:
+				total += time.Duration(n) * time.Minute
+			case r == 'S' && inTime:
+				total += time.Duration(n) * time.Second
+			default:
+				return 0, fmt.Errorf("unexpected duration designator %q", r)
+			}
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("malformed duration")
+	}
+	return sign * total, nil
+}
+
+// splitICalList splits a comma separated value, ignoring escaped commas
+func splitICalList(value string) []string {
+	parts := []string{}
+	current := strings.Builder{}
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			current.WriteRune('\\')
+			current.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == ',':
+			parts = append(parts, current.String())
+			current.Reset()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	parts = append(parts, current.String())
+	return parts
+}
+
+func unescapeICalText(s string) string {
+	replacer := strings.NewReplacer(
+		`\\`, `\`,
+		`\n`, "\n",
+		`\N`, "\n",
+		`\;`, ";",
+		`\,`, ",",
+	)
+	return replacer.Replace(s)
+}
+
+// ICalImportOptions scopes an import to a single owner and source. Events from
+// the same SourceKey are reconciled against each other on re-import
+type ICalImportOptions struct {
+	OwnerId          string
+	OwnerName        string
+	SourceKey        string
+	SourceUrl        string
+	FallbackLat      float64
+	FallbackLong     float64
+	FallbackAddress  string
+	FallbackTimezone string
+	Now              time.Time
+	Horizon          time.Time
+	// QuarantineStore, when set, holds back events breaking an error rule of
+	// the event validator for their owner to review, as Seshu sources do
+	QuarantineStore interfaces.PostgresServiceInterface
+}
+
+type ICalImportResult struct {
+	SourceKey   string   `json:"sourceKey"`
+	Parsed      int      `json:"parsed"`
+	Inserted    int      `json:"inserted"`
+	Updated     int      `json:"updated"`
+	Deleted     int      `json:"deleted"`
+	Quarantined int      `json:"quarantined,omitempty"`
+	Skipped     []string `json:"skipped,omitempty"`
+}
+
+// How far ahead recurring VEVENTs are materialized into series children
+const ICalImportHorizon = 180 * 24 * time.Hour
+
+const (
+	// MaxICalFeedBytes caps fetched feeds and uploads, larger ones are
+	// rejected rather than buffered into memory
+	MaxICalFeedBytes = 10 << 20
+	icalFetchTimeout = 30 * time.Second
+)
+
+// icalHTTPClient fetches feeds users point us at, on import and again on
+// every scheduled Seshu run
+var icalHTTPClient = NewPublicHTTPClient(icalFetchTimeout)
+
+// MaxICalImportGeocodes is how many distinct LOCATIONs without GEO one import
+// looks up, every lookup that misses the cache is a paid ScrapingBee render
+const MaxICalImportGeocodes = 100
+
+var errICalGeocodeLimit = fmt.Errorf("the feed has more than %d locations without GEO to look up", MaxICalImportGeocodes)
+
+// icalGeocodeLookup is swapped out by tests
+var icalGeocodeLookup = geocodeLocation
+
+type icalGeocodeResult struct {
+	lat, long float64
+	address   string
+	err       error
+}
+
+// icalGeocoder looks up the LOCATIONs of one import. Each is looked up once,
+// failures included, and those past `MaxICalImportGeocodes` get
+// `errICalGeocodeLimit` without a lookup
+type icalGeocoder struct {
+	results map[string]icalGeocodeResult
+	lookups int
+}
+
+func newICalGeocoder() *icalGeocoder {
+	return &icalGeocoder{results: map[string]icalGeocodeResult{}}
+}
+
+func (g *icalGeocoder) geocode(location string) (float64, float64, string, error) {
+	key := geocodeKey(location)
+	if result, ok := g.results[key]; ok {
+		return result.lat, result.long, result.address, result.err
+	}
+	result := icalGeocodeResult{err: errICalGeocodeLimit}
+	if g.lookups < MaxICalImportGeocodes {
+		g.lookups++
+		result.lat, result.long, result.address, result.err = icalGeocodeLookup(location)
+		if result.err != nil {
+			log.Printf("WARN: failed to geocode iCal location %q: %v", location, result.err)
+		}
+	}
+	g.results[key] = result
+	return result.lat, result.long, result.address, result.err
+}
+
+// ICalEventId derives a stable Weaviate UUID from the owner, the VEVENT UID
+// and (for occurrences) the original start instant, so re-importing the same
+// feed updates events in place instead of duplicating them
+func ICalEventId(ownerId, uid string, recurrenceId time.Time) string {
+	name := ownerId + "|" + uid
+	if !recurrenceId.IsZero() {
+		name += "|" + strconv.FormatInt(recurrenceId.Unix(), 10)
+	}
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(name)).String()
+}
+
+// FetchICalFeed downloads an .ics feed, `webcal://` URLs are fetched over https
+func FetchICalFeed(ctx context.Context, feedUrl string) ([]byte, error) {
+	if strings.HasPrefix(strings.ToLower(feedUrl), "webcal://") {
+		feedUrl = "https://" + feedUrl[len("webcal://"):]
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, feedUrl, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s: %w", feedUrl, err)
+	}
+	req.Header.Set("Accept", "text/calendar, */*;q=0.5")
+
+	res, err := icalHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch iCal feed %s: %w", feedUrl, err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return nil, fmt.Errorf("iCal feed %s returned status %d", feedUrl, res.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, MaxICalFeedBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read iCal feed %s: %w", feedUrl, err)
+	}
+	if len(body) > MaxICalFeedBytes {
+		return nil, fmt.Errorf("iCal feed %s exceeds %d bytes", feedUrl, MaxICalFeedBytes)
+	}
+	return body, nil
+}
+
+// ICalEventsToRawEvents maps parsed VEVENTs to RawEvents ready for
+// BulkValidateEvents. Non-recurring VEVENTs become ES_SINGLE_EVENT, recurring
+// ones become an ES_SERIES_PARENT plus one ES_EVENT_SERIES child per
+// occurrence between `opts.Now` and `opts.Horizon`. RECURRENCE-ID overrides
+// replace the matching occurrence and STATUS:CANCELLED removes it
+func ICalEventsToRawEvents(icalEvents []ICalEvent, opts ICalImportOptions) ([]RawEvent, []string) {
+	skipped := []string{}
+	rawEvents := []RawEvent{}
+	geocoder := newICalGeocoder()
+
+	masters := []ICalEvent{}
+	overrides := map[string]map[int64]ICalEvent{}
+	for _, icalEvent := range icalEvents {
+		if icalEvent.RecurrenceId.IsZero() {
+			masters = append(masters, icalEvent)
+			continue
+		}
+		if overrides[icalEvent.UID] == nil {
+			overrides[icalEvent.UID] = map[int64]ICalEvent{}
+		}
+		overrides[icalEvent.UID][icalEvent.RecurrenceId.Unix()] = icalEvent
+	}
+
+	hasMaster := map[string]bool{}
+	for _, master := range masters {
+		hasMaster[master.UID] = true
+	}
+	// Overrides whose master isn't in the feed are imported as standalone events
+	for uid, byInstant := range overrides {
+		if hasMaster[uid] {
+			continue
+		}
+		for _, override := range byInstant {
+			if raw, ok, reason := icalEventToRawEvent(override, opts, geocoder, override.Start, override.End, opts.SourceKey, constants.ES_SINGLE_EVENT, ICalEventId(opts.OwnerId, uid, override.RecurrenceId)); ok {
+				rawEvents = append(rawEvents, raw)
+			} else if reason != "" {
+				skipped = append(skipped, reason)
+			}
+		}
+	}
+
+	for _, master := range masters {
+		if master.Status == "CANCELLED" {
+			continue
+		}
+
+		var rule *RecurrenceRule
+		if master.RRule != "" {
+			parsed, err := ParseRRule(master.RRule, master.Start.Location())
+			if err != nil {
+				skipped = append(skipped, fmt.Sprintf("%s: %v, importing first occurrence only", master.UID, err))
+			} else {
+				rule = &parsed
+			}
+		}
+
+		if rule == nil && len(master.RDates) == 0 {
+			if endOrStart(master).Before(opts.Now) {
+				continue
+			}
+			if raw, ok, reason := icalEventToRawEvent(master, opts, geocoder, master.Start, master.End, opts.SourceKey, constants.ES_SINGLE_EVENT, ICalEventId(opts.OwnerId, master.UID, time.Time{})); ok {
+				rawEvents = append(rawEvents, raw)
+			} else if reason != "" {
+				skipped = append(skipped, reason)
+			}
+			continue
+		}
+
+		var duration time.Duration
+		if !master.End.IsZero() {
+			duration = master.End.Sub(master.Start)
+		}
+		occurrences := ExpandRecurrenceSet(master.Start, rule, master.RDates, master.ExDates, opts.Now.Add(-duration), opts.Horizon, MaxRecurrenceOccurrences)
+
+		parentId := ICalEventId(opts.OwnerId, master.UID, time.Time{})
+		children := []RawEvent{}
+		for _, occurrence := range occurrences {
+			source := master
+			start := occurrence
+			end := time.Time{}
+			if duration > 0 {
+				end = occurrence.Add(duration)
+			}
+			if override, ok := overrides[master.UID][occurrence.Unix()]; ok {
+				if override.Status == "CANCELLED" {
+					continue
+				}
+				source, start, end = override, override.Start, override.End
+			}
+			raw, ok, reason := icalEventToRawEvent(source, opts, geocoder, start, end, parentId, constants.ES_EVENT_SERIES, ICalEventId(opts.OwnerId, master.UID, occurrence))
+			if !ok {
+				if reason != "" {
+					skipped = append(skipped, reason)
+				}
+				continue
+			}
+			children = append(children, raw)
+		}
+
+		if len(children) == 0 {
+			continue
+		}
+
+		// The parent mirrors the next upcoming occurrence so it surfaces in
+		// the default (future-only) search like hand-made series parents do
+		parent := children[0]
+		parent.Id = parentId
+		parent.EventSourceType = constants.ES_SERIES_PARENT
+		sourceKey := opts.SourceKey
+		parent.EventSourceId = &sourceKey
+		// an overridden first occurrence shouldn't rename the whole series
+		parent.Name = master.Summary
+		if strings.TrimSpace(master.Description) != "" {
+			parent.Description = master.Description
+		}
+
+		rawEvents = append(rawEvents, parent)
+		rawEvents = append(rawEvents, children...)
+	}
+
+	return rawEvents, skipped
+}
+
+func endOrStart(icalEvent ICalEvent) time.Time {
+	if !icalEvent.End.IsZero() {
+		return icalEvent.End
+	}
+	return icalEvent.Start
+}
+
+// icalEventToRawEvent maps one VEVENT, `geocoder` looks up its LOCATION when
+// it has no GEO. Events whose location can't be resolved fall back to the
+// import's location, or are skipped with a reason without one
+func icalEventToRawEvent(icalEvent ICalEvent, opts ICalImportOptions, geocoder *icalGeocoder, start, end time.Time, eventSourceId, eventSourceType, id string) (RawEvent, bool, string) {
+	if strings.TrimSpace(icalEvent.Summary) == "" {
+		return RawEvent{}, false, fmt.Sprintf("%s: missing SUMMARY", icalEvent.UID)
+	}
+
+	lat, long, address := icalEvent.Lat, icalEvent.Long, icalEvent.Location
+	var geocodeErr error
+	if !icalEvent.HasGeo && icalEvent.Location != "" {
+		geoLat, geoLong, geoAddress, err := geocoder.geocode(icalEvent.Location)
+		if err != nil {
+			geocodeErr = err
+		} else {
+			lat, long, address = geoLat, geoLong, geoAddress
+		}
+	}
+	if lat == 0 && long == 0 {
+		lat, long = opts.FallbackLat, opts.FallbackLong
+		if address == "" {
+			address = opts.FallbackAddress
+		}
+	}
+	if address == "" || (lat == 0 && long == 0) || lat == constants.INITIAL_EMPTY_LAT_LONG {
+		if geocodeErr != nil {
+			return RawEvent{}, false, fmt.Sprintf("%s: couldn't resolve a location: %v", icalEvent.UID, geocodeErr)
+		}
+		return RawEvent{}, false, fmt.Sprintf("%s: couldn't resolve a location", icalEvent.UID)
+	}
+
+	// Timezone priority: the VEVENT's own TZID > coordinates > import fallback
+	timezone := ""
+	if loc := start.Location(); !isICalUTC(loc) {
+		timezone = loc.String()
+	}
+	if timezone == "" {
+		timezone = DeriveTimezoneFromCoordinates(lat, long)
+	}
+	if timezone == "" {
+		timezone = opts.FallbackTimezone
+	}
+	if timezone == "" {
+		timezone = "UTC"
+	}
+
+	description := icalEvent.Description
+	if strings.TrimSpace(description) == "" {
+		description = icalEvent.Summary
+	}
+
+	sourceUrl := icalEvent.Url
+	if sourceUrl == "" {
+		sourceUrl = opts.SourceUrl
+	}
+
+	raw := RawEvent{
+		RawEventData: RawEventData{
+			Id:              id,
+			EventOwners:     []string{opts.OwnerId},
+			EventOwnerName:  opts.OwnerName,
+			EventSourceType: eventSourceType,
+			Name:            icalEvent.Summary,
+			Description:     description,
+			Address:         address,
+			Lat:             lat,
+			Long:            long,
+			Timezone:        timezone,
+		},
+		EventSourceId: &eventSourceId,
+		StartTime:     start.UTC().Format(time.RFC3339),
+	}
+	if !end.IsZero() && end.After(start) {
+		raw.EndTime = end.UTC().Format(time.RFC3339)
+	}
+	if sourceUrl != "" {
+		raw.SourceUrl = &sourceUrl
+	}
+	if len(icalEvent.Categories) > 0 {
+		// Feed categories are free-form, our `Categories` are a fixed taxonomy
+		tags := icalEvent.Categories
+		raw.Tags = &tags
+	}
+	return raw, true, ""
+}
+
+// ImportICalendar parses `data` and reconciles it into Weaviate by UID:
+// events are upserted under their deterministic ids, and previously imported
+// events from the same owner + SourceKey that are no longer in the feed are
+// deleted (only upcoming ones, history is preserved)
+func ImportICalendar(ctx context.Context, store EventStore, data []byte, opts ICalImportOptions) (ICalImportResult, error) {
+	result := ICalImportResult{SourceKey: opts.SourceKey}
+	if opts.OwnerId == "" || opts.SourceKey == "" {
+		return result, fmt.Errorf("iCal import requires an owner and a source key")
+	}
+	if opts.OwnerName == "" {
+		opts.OwnerName = opts.OwnerId
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	if opts.Horizon.IsZero() {
+		opts.Horizon = opts.Now.Add(ICalImportHorizon)
+	}
+
+	icalEvents, err := ParseICalendar(data, opts.FallbackTimezone)
+	if err != nil {
+		return result, fmt.Errorf("failed to parse iCal data: %w", err)
+	}
+	if len(icalEvents) == 0 {
+		// Refuse to reconcile against an empty document, a broken feed would
+		// otherwise wipe every previously imported event
+		return result, fmt.Errorf("no VEVENT components found")
+	}
+	result.Parsed = len(icalEvents)
+
+	rawEvents, skipped := ICalEventsToRawEvents(icalEvents, opts)
+	result.Skipped = skipped
+
+	events := []types.Event{}
+	if len(rawEvents) > 0 {
+		events, _, err = BulkValidateEvents(rawEvents, true)
+		if err != nil {
+			return result, fmt.Errorf("failed to validate iCal events: %w", err)
+		}
+	}
+	if opts.QuarantineStore != nil && len(events) > 0 {
+		// Quarantined events count as gone from the feed, so a published copy
+		// from an earlier run is removed below
+		validator := NewEventValidator(store)
+		validator.Now = func() time.Time { return opts.Now }
+		var quarantined []types.QuarantinedEvent
+		events, quarantined, err = QuarantineEvents(ctx, opts.QuarantineStore, validator, rawEvents, events, opts.SourceKey)
+		if err != nil {
+			return result, fmt.Errorf("failed to quarantine iCal events: %w", err)
+		}
+		result.Quarantined = len(quarantined)
+	}
+
+	existing, err := findExistingICalEvents(ctx, store, opts)
+	if err != nil {
+		return result, err
+	}
+	existingIds := make(map[string]bool, len(existing))
+	for _, event := range existing {
+		existingIds[event.Id] = true
+	}
+
+	incomingIds := make(map[string]bool, len(events))
+	for _, event := range events {
+		incomingIds[event.Id] = true
+		if existingIds[event.Id] {
+			result.Updated++
+		} else {
+			result.Inserted++
+		}
+	}
+
+	if len(events) > 0 {
+		if err := store.BulkUpsertEvent(ctx, events); err != nil {
+			return result, fmt.Errorf("failed to upsert iCal events: %w", err)
+		}
+	}
+
+	obsoleteIds := []string{}
+	for _, event := range existing {
+		if incomingIds[event.Id] {
+			continue
+		}
+		if event.EventSourceType == constants.ES_SERIES_PARENT || event.StartTime >= opts.Now.Unix() {
+			obsoleteIds = append(obsoleteIds, event.Id)
+		}
+	}
+	if len(obsoleteIds) > 0 {
+		if err := store.BulkDeleteEvents(ctx, obsoleteIds); err != nil {
+			return result, fmt.Errorf("failed to delete obsolete iCal events: %w", err)
+		}
+	}
+	result.Deleted = len(obsoleteIds)
+
+	log.Printf("INFO: iCal import for %s (%d parsed, %d inserted, %d updated, %d deleted, %d quarantined, %d skipped)",
+		opts.SourceKey, result.Parsed, result.Inserted, result.Updated, result.Deleted, result.Quarantined, len(result.Skipped))
+	return result, nil
+}
+
+// findExistingICalEvents returns the owner's single events and series parents
+// previously imported from SourceKey, along with the parents' children
+func findExistingICalEvents(ctx context.Context, store EventStore, opts ICalImportOptions) ([]types.Event, error) {
+	topLevel, err := SearchAllEvents(ctx, store, []string{opts.OwnerId},
+		[]string{constants.ES_SINGLE_EVENT, constants.ES_SERIES_PARENT}, []string{opts.SourceKey})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search existing events for %s: %w", opts.SourceKey, err)
+	}
+
+	existing := topLevel
+	parentIds := []string{}
+	for _, event := range topLevel {
+		if event.EventSourceType == constants.ES_SERIES_PARENT {
+			parentIds = append(parentIds, event.Id)
+		}
+	}
+	if len(parentIds) > 0 {
+		children, err := SearchAllEvents(ctx, store, []string{opts.OwnerId},
+			[]string{constants.ES_EVENT_SERIES}, parentIds)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search existing series children for %s: %w", opts.SourceKey, err)
+		}
+		existing = append(existing, children...)
+	}
+	return existing, nil
+}
+
+// ImportICalSeshuJob runs a scheduled import for a Seshu job whose
+// KnownScrapeSource is SESHU_KNOWN_SOURCE_ICS
+func ImportICalSeshuJob(ctx context.Context, seshuJob types.SeshuJob) (ICalImportResult, error) {
+	data, err := FetchICalFeed(ctx, seshuJob.NormalizedUrlKey)
+	if err != nil {
+		return ICalImportResult{}, err
+	}
+
+	eventStore, err := GetEventStore()
+	if err != nil {
+		return ICalImportResult{}, fmt.Errorf("failed to get event store: %w", err)
+	}
+
+	postgresService, err := GetPostgresService(ctx)
+	if err != nil {
+		return ICalImportResult{}, fmt.Errorf("failed to get postgres service: %w", err)
+	}
+
+	ownerName := seshuJob.OwnerID
+	owner, err := helpers.GetOtherUserByID(seshuJob.OwnerID)
+	if err != nil {
+		log.Printf("Failed to get event owner from zitadel: %v", err)
+	} else if owner.DisplayName != "" {
+		ownerName = owner.DisplayName
+	}
+
+	return ImportICalendar(ctx, eventStore, data, ICalImportOptions{
+		OwnerId:          seshuJob.OwnerID,
+		OwnerName:        ownerName,
+		SourceKey:        seshuJob.NormalizedUrlKey,
+		SourceUrl:        seshuJob.NormalizedUrlKey,
+		FallbackLat:      seshuJob.LocationLatitude,
+		FallbackLong:     seshuJob.LocationLongitude,
+		FallbackAddress:  seshuJob.LocationAddress,
+		FallbackTimezone: seshuJob.LocationTimezone,
+		QuarantineStore:  postgresService,
+	})
+}
@@ -0,0 +1,520 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+func TestBuildICalendarFeed(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+
+	// 2025-03-08 19:00 EST and 2025-03-15 19:00 EDT straddle the spring-forward transition
+	beforeDST := time.Date(2025, 3, 8, 19, 0, 0, 0, newYork)
+	afterDST := time.Date(2025, 3, 15, 19, 0, 0, 0, newYork)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	events := []types.Event{
+		{
+			Id:              "single-event-1",
+			Name:            "Trivia Night; Round 1, Part A",
+			Description:     "Line one\nLine two",
+			Address:         "123 Main St, New York, NY",
+			Lat:             40.7128,
+			Long:            -74.0060,
+			StartTime:       beforeDST.Unix(),
+			EndTime:         beforeDST.Add(2 * time.Hour).Unix(),
+			Timezone:        *newYork,
+			EventSourceType: constants.ES_SINGLE_EVENT,
+			CreatedAt:       1735689600,
+			UpdatedAt:       1735689600 + 42,
+		},
+		{
+			Id:              "series-child-1",
+			Name:            "Weekly Meetup",
+			StartTime:       afterDST.Unix(),
+			EndTime:         constants.DEFAULT_UNDEFINED_END_TIME,
+			Timezone:        *newYork,
+			EventSourceType: constants.ES_EVENT_SERIES,
+			EventSourceId:   "series-parent-1",
+		},
+		{
+			Id:              "utc-event-1",
+			Name:            "Online Event",
+			StartTime:       time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC).Unix(),
+			Timezone:        *time.UTC,
+			EventSourceType: constants.ES_SINGLE_EVENT,
+		},
+	}
+
+	feed := BuildICalendarFeed("Test Feed", events, now)
+
+	t.Run("wraps a single VCALENDAR with CRLF line endings", func(t *testing.T) {
+		if !strings.HasPrefix(feed, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n") {
+			t.Errorf("feed does not start with VCALENDAR header: %q", feed[:40])
+		}
+		if !strings.HasSuffix(feed, "END:VCALENDAR\r\n") {
+			t.Errorf("feed does not end with END:VCALENDAR")
+		}
+		if strings.Contains(strings.ReplaceAll(feed, "\r\n", ""), "\n") {
+			t.Errorf("feed contains bare LF line endings")
+		}
+		if got := strings.Count(feed, "BEGIN:VEVENT"); got != 3 {
+			t.Errorf("expected 3 VEVENTs, got %d", got)
+		}
+	})
+
+	t.Run("emits one VTIMEZONE per named zone covering the DST transition", func(t *testing.T) {
+		if got := strings.Count(feed, "BEGIN:VTIMEZONE"); got != 1 {
+			t.Fatalf("expected 1 VTIMEZONE, got %d", got)
+		}
+		for _, want := range []string{
+			"TZID:America/New_York\r\n",
+			"BEGIN:STANDARD\r\n",
+			"BEGIN:DAYLIGHT\r\nDTSTART:20250309T020000\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\nTZNAME:EDT\r\n",
+		} {
+			if !strings.Contains(feed, want) {
+				t.Errorf("expected feed to contain %q", want)
+			}
+		}
+	})
+
+	t.Run("renders local times against the event timezone", func(t *testing.T) {
+		for _, want := range []string{
+			"DTSTART;TZID=America/New_York:20250308T190000\r\n",
+			"DTEND;TZID=America/New_York:20250308T210000\r\n",
+			"DTSTART;TZID=America/New_York:20250315T190000\r\n",
+			"DTSTART:20250401T120000Z\r\n",
+		} {
+			if !strings.Contains(feed, want) {
+				t.Errorf("expected feed to contain %q", want)
+			}
+		}
+	})
+
+	t.Run("uses stable UIDs and revision metadata", func(t *testing.T) {
+		for _, want := range []string{
+			"UID:single-event-1\r\n",
+			"LAST-MODIFIED:20250101T000042Z\r\n",
+			"UID:utc-event-1\r\n",
+		} {
+			if !strings.Contains(feed, want) {
+				t.Errorf("expected feed to contain %q", want)
+			}
+		}
+		if got := strings.Count(feed, "DTSTAMP:20250101T000000Z\r\n"); got != 3 {
+			t.Errorf("expected every DTSTAMP to be the generation time, got %d", got)
+		}
+		// seconds from 2020-01-01 to the last edit, 0 without edit times
+		if !strings.Contains(feed, "SEQUENCE:157852842\r\n") {
+			t.Errorf("expected SEQUENCE to follow UpdatedAt")
+		}
+		if got := strings.Count(feed, "SEQUENCE:0\r\n"); got != 2 {
+			t.Errorf("expected events without edit times to be SEQUENCE 0, got %d", got)
+		}
+	})
+
+	t.Run("increases SEQUENCE when an event is edited", func(t *testing.T) {
+		event := events[0]
+		created := event
+		created.UpdatedAt = 0
+		edited := event
+		edited.UpdatedAt += 60
+		if !(icalSequence(created) < icalSequence(event) && icalSequence(event) < icalSequence(edited)) {
+			t.Errorf("expected SEQUENCE to go up with every edit, got %d, %d, %d", icalSequence(created), icalSequence(event), icalSequence(edited))
+		}
+		if !strings.Contains(BuildICalendarFeed("Test Feed", []types.Event{edited}, now), "SEQUENCE:157852902\r\n") {
+			t.Errorf("expected the edited event's feed to carry the higher SEQUENCE")
+		}
+	})
+
+	t.Run("links series children to their parent and defaults duration", func(t *testing.T) {
+		if !strings.Contains(feed, "RELATED-TO;RELTYPE=PARENT:series-parent-1\r\n") {
+			t.Errorf("expected RELATED-TO for series child")
+		}
+		if !strings.Contains(feed, "DURATION:PT1H\r\n") {
+			t.Errorf("expected default DURATION for event without end time")
+		}
+	})
+
+	t.Run("escapes TEXT values", func(t *testing.T) {
+		if !strings.Contains(feed, `SUMMARY:Trivia Night\; Round 1\, Part A`) {
+			t.Errorf("expected escaped SUMMARY")
+		}
+		if !strings.Contains(feed, `DESCRIPTION:Line one\nLine two`) {
+			t.Errorf("expected escaped DESCRIPTION")
+		}
+	})
+}
+
+func TestWriteICalLineFolding(t *testing.T) {
+	var b strings.Builder
+	// 'é' is two octets, place one across the 75 octet boundary
+	line := "DESCRIPTION:" + strings.Repeat("a", 62) + "é" + strings.Repeat("b", 100)
+	writeICalLine(&b, line)
+
+	physical := strings.Split(strings.TrimSuffix(b.String(), "\r\n"), "\r\n")
+	if len(physical) < 2 {
+		t.Fatalf("expected line to be folded, got %d lines", len(physical))
+	}
+	unfolded := physical[0]
+	for i, l := range physical {
+		if len(l) > icalMaxLineOctets {
+			t.Errorf("line %d is %d octets, exceeds %d", i, len(l), icalMaxLineOctets)
+		}
+		if i > 0 {
+			if !strings.HasPrefix(l, " ") {
+				t.Errorf("continuation line %d does not start with a space", i)
+			}
+			unfolded += l[1:]
+		}
+	}
+	if unfolded != line {
+		t.Errorf("unfolded line does not match the original")
+	}
+	if physical[0] != "DESCRIPTION:"+strings.Repeat("a", 62) {
+		t.Errorf("expected fold before multi-byte rune, got %q", physical[0])
+	}
+}
+
+func TestFormatICalOffset(t *testing.T) {
+	tests := map[int]string{
+		0:      "+0000",
+		-18000: "-0500",
+		19800:  "+0530",
+		-12600: "-0330",
+		3601:   "+010001",
+	}
+	for offset, want := range tests {
+		if got := formatICalOffset(offset); got != want {
+			t.Errorf("formatICalOffset(%d) = %q, want %q", offset, got, want)
+		}
+	}
+}
+
+const testICalFeed = "\ufeffBEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Test//EN\r\n" +
+	"X-WR-TIMEZONE:America/Chicago\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:Eastern Standard Time\r\n" +
+	"X-LIC-LOCATION:America/New_York\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly@example.com\r\n" +
+	"SUMMARY:Weekly Run Club\\, All Paces\r\n" +
+	"DESCRIPTION:Meet at the fountain.\\nBring water\r\n" +
+	" .\r\n" +
+	"DTSTART;TZID=Eastern Standard Time:20250301T190000\r\n" +
+	"DTEND;TZID=Eastern Standard Time:20250301T200000\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=4\r\n" +
+	"EXDATE;TZID=Eastern Standard Time:20250308T190000\r\n" +
+	"GEO:40.7128;-74.0060\r\n" +
+	"LOCATION:Central Park\r\n" +
+	"CATEGORIES:Sports,Outdoors\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"DESCRIPTION:Reminder\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly@example.com\r\n" +
+	"RECURRENCE-ID;TZID=Eastern Standard Time:20250315T190000\r\n" +
+	"SUMMARY:Weekly Run Club (Hill Repeats)\r\n" +
+	"DTSTART;TZID=Eastern Standard Time:20250315T180000\r\n" +
+	"DTEND;TZID=Eastern Standard Time:20250315T190000\r\n" +
+	"GEO:40.7128;-74.0060\r\n" +
+	"LOCATION:Central Park\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:weekly@example.com\r\n" +
+	"RECURRENCE-ID;TZID=Eastern Standard Time:20250322T190000\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"SUMMARY:Weekly Run Club\r\n" +
+	"DTSTART;TZID=Eastern Standard Time:20250322T190000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:festival@example.com\r\n" +
+	"SUMMARY:Street Festival\r\n" +
+	"DTSTART;VALUE=DATE:20250405\r\n" +
+	"GEO:41.8781;-87.6298\r\n" +
+	"LOCATION:Downtown\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:past@example.com\r\n" +
+	"SUMMARY:Already Happened\r\n" +
+	"DTSTART:20240101T120000Z\r\n" +
+	"GEO:40.7128;-74.0060\r\n" +
+	"LOCATION:Somewhere\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICalendar(t *testing.T) {
+	events, err := ParseICalendar([]byte(testICalFeed), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("expected 5 VEVENTs, got %d", len(events))
+	}
+
+	master := events[0]
+	if master.Summary != "Weekly Run Club, All Paces" {
+		t.Errorf("unexpected SUMMARY: %q", master.Summary)
+	}
+	if master.Description != "Meet at the fountain.\nBring water." {
+		t.Errorf("expected unfolded, unescaped DESCRIPTION, got %q", master.Description)
+	}
+	if master.Start.Location().String() != "America/New_York" {
+		t.Errorf("expected TZID to resolve through X-LIC-LOCATION, got %s", master.Start.Location())
+	}
+	if master.Start.Hour() != 19 || master.End.Sub(master.Start) != time.Hour {
+		t.Errorf("unexpected DTSTART/DTEND: %v - %v", master.Start, master.End)
+	}
+	if master.RRule != "FREQ=WEEKLY;COUNT=4" || len(master.ExDates) != 1 {
+		t.Errorf("unexpected RRULE/EXDATE: %q %v", master.RRule, master.ExDates)
+	}
+	if !master.HasGeo || master.Lat != 40.7128 || master.Long != -74.0060 {
+		t.Errorf("unexpected GEO: %v %v", master.Lat, master.Long)
+	}
+	if len(master.Categories) != 2 {
+		t.Errorf("unexpected CATEGORIES: %v", master.Categories)
+	}
+
+	if events[1].RecurrenceId.IsZero() {
+		t.Errorf("expected RECURRENCE-ID on override")
+	}
+	if events[2].Status != "CANCELLED" {
+		t.Errorf("expected cancelled override, got %q", events[2].Status)
+	}
+
+	festival := events[3]
+	if !festival.AllDay {
+		t.Errorf("expected VALUE=DATE to be all-day")
+	}
+	if festival.Start.Location().String() != "America/Chicago" {
+		t.Errorf("expected floating date to use X-WR-TIMEZONE, got %s", festival.Start.Location())
+	}
+	if festival.End.Sub(festival.Start) != 24*time.Hour {
+		t.Errorf("expected all-day event to default to one day, got %v", festival.End.Sub(festival.Start))
+	}
+
+	if _, err := ParseICalendar([]byte("not a calendar"), ""); err == nil {
+		t.Errorf("expected error for non-iCalendar input")
+	}
+}
+
+func TestICalEventsToRawEvents(t *testing.T) {
+	events, err := ParseICalendar([]byte(testICalFeed), "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	opts := ICalImportOptions{
+		OwnerId:   "owner-1",
+		OwnerName: "Run Club",
+		SourceKey: "https://example.com/calendar.ics",
+		SourceUrl: "https://example.com/calendar.ics",
+		Now:       now,
+		Horizon:   now.Add(ICalImportHorizon),
+	}
+	rawEvents, skipped := ICalEventsToRawEvents(events, opts)
+	if len(skipped) != 0 {
+		t.Errorf("unexpected skipped events: %v", skipped)
+	}
+
+	byType := map[string][]RawEvent{}
+	for _, raw := range rawEvents {
+		byType[raw.EventSourceType] = append(byType[raw.EventSourceType], raw)
+	}
+
+	// The past single event is dropped, the festival is kept
+	singles := byType[constants.ES_SINGLE_EVENT]
+	if len(singles) != 1 || singles[0].Name != "Street Festival" {
+		t.Fatalf("expected only the festival as a single event, got %+v", singles)
+	}
+	if singles[0].Timezone != "America/Chicago" || *singles[0].EventSourceId != opts.SourceKey {
+		t.Errorf("unexpected festival timezone/source: %s %s", singles[0].Timezone, *singles[0].EventSourceId)
+	}
+
+	parents := byType[constants.ES_SERIES_PARENT]
+	if len(parents) != 1 {
+		t.Fatalf("expected 1 series parent, got %d", len(parents))
+	}
+	parent := parents[0]
+	if parent.Id != ICalEventId("owner-1", "weekly@example.com", time.Time{}) {
+		t.Errorf("expected deterministic parent id, got %s", parent.Id)
+	}
+	if parent.Name != "Weekly Run Club, All Paces" {
+		t.Errorf("expected parent to keep the master SUMMARY, got %q", parent.Name)
+	}
+
+	// COUNT=4 minus the EXDATE and the cancelled occurrence
+	children := byType[constants.ES_EVENT_SERIES]
+	if len(children) != 2 {
+		t.Fatalf("expected 2 series children, got %d", len(children))
+	}
+	for _, child := range children {
+		if *child.EventSourceId != parent.Id {
+			t.Errorf("expected child to point at parent %s, got %s", parent.Id, *child.EventSourceId)
+		}
+		if child.Timezone != "America/New_York" {
+			t.Errorf("unexpected child timezone: %s", child.Timezone)
+		}
+	}
+	if children[0].StartTime != "2025-03-02T00:00:00Z" {
+		t.Errorf("expected first child at 19:00 EST, got %s", children[0].StartTime)
+	}
+	override := children[1]
+	if override.Name != "Weekly Run Club (Hill Repeats)" || override.StartTime != "2025-03-15T22:00:00Z" {
+		t.Errorf("expected the RECURRENCE-ID override to replace the occurrence, got %q at %s", override.Name, override.StartTime)
+	}
+	if override.Id != ICalEventId("owner-1", "weekly@example.com", time.Date(2025, 3, 15, 23, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected override to keep the id of the original occurrence")
+	}
+
+	// Re-running the conversion produces the same ids so re-imports update in place
+	again, _ := ICalEventsToRawEvents(events, opts)
+	if len(again) != len(rawEvents) {
+		t.Fatalf("expected stable output length")
+	}
+	for i := range again {
+		if again[i].Id != rawEvents[i].Id {
+			t.Errorf("expected stable id at %d: %s != %s", i, again[i].Id, rawEvents[i].Id)
+		}
+	}
+}
+
+func TestICalGeocoder(t *testing.T) {
+	originalLookup := icalGeocodeLookup
+	defer func() { icalGeocodeLookup = originalLookup }()
+	lookups := map[string]int{}
+	icalGeocodeLookup = func(location string) (float64, float64, string, error) {
+		lookups[location]++
+		if location == "Nowhere" {
+			return 0, 0, "", errors.New("no results")
+		}
+		return 41.88, -87.63, location + ", Chicago", nil
+	}
+
+	geocoder := newICalGeocoder()
+	for range 3 {
+		if _, _, _, err := geocoder.geocode("Nowhere"); err == nil {
+			t.Fatal("expected the failed lookup to be returned")
+		}
+	}
+	if lookups["Nowhere"] != 1 {
+		t.Errorf("expected a failed lookup to be cached for the import, got %d lookups", lookups["Nowhere"])
+	}
+	if _, _, address, err := geocoder.geocode("  the  HALL "); err != nil || address != "  the  HALL , Chicago" {
+		t.Errorf("unexpected lookup result %q: %v", address, err)
+	}
+	if _, _, _, err := geocoder.geocode("The Hall"); err != nil || lookups["The Hall"] != 0 {
+		t.Errorf("expected the same place to be matched regardless of case and spacing: %v", err)
+	}
+
+	for i := range MaxICalImportGeocodes {
+		geocoder.geocode(fmt.Sprintf("%d Main St", i))
+	}
+	if len(lookups) != MaxICalImportGeocodes {
+		t.Errorf("expected %d lookups, got %d", MaxICalImportGeocodes, len(lookups))
+	}
+	last := fmt.Sprintf("%d Main St", MaxICalImportGeocodes-1)
+	if _, _, _, err := geocoder.geocode(last); !errors.Is(err, errICalGeocodeLimit) || lookups[last] != 0 {
+		t.Errorf("expected locations past the limit not to be looked up, got %v", err)
+	}
+
+	// Past the limit events use the import's location, or are skipped without one
+	event := ICalEvent{UID: "late@example.com", Summary: "Open Mic", Location: last}
+	start := time.Date(2030, 6, 1, 19, 0, 0, 0, time.UTC)
+	opts := ICalImportOptions{OwnerId: "owner1", FallbackLat: 41.88, FallbackLong: -87.63, FallbackAddress: "Chicago, IL"}
+	raw, ok, _ := icalEventToRawEvent(event, opts, geocoder, start, time.Time{}, "source", constants.ES_SINGLE_EVENT, "id")
+	if !ok || raw.Lat != opts.FallbackLat {
+		t.Errorf("expected the fallback location, got %+v", raw)
+	}
+	_, ok, reason := icalEventToRawEvent(event, ICalImportOptions{OwnerId: "owner1"}, geocoder, start, time.Time{}, "source", constants.ES_SINGLE_EVENT, "id")
+	if ok || !strings.Contains(reason, "locations without GEO") {
+		t.Errorf("expected the event to be skipped with the limit as reason, got %q", reason)
+	}
+}
+
+const testICalQuarantineFeed = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:trivia@example.com\r\n" +
+	"SUMMARY:Trivia Night\r\n" +
+	"DESCRIPTION:Teams of four\r\n" +
+	"DTSTART:20250305T010000Z\r\n" +
+	"DTEND:20250305T030000Z\r\n" +
+	"RRULE:FREQ=WEEKLY;COUNT=3\r\n" +
+	"LOCATION:Corner Pub\\, Chicago\r\n" +
+	"GEO:41.88;-87.63\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:slam@example.com\r\n" +
+	"SUMMARY:Poetry Slam\r\n" +
+	"DESCRIPTION:Open stage\r\n" +
+	"DTSTART:20250306T010000Z\r\n" +
+	"URL:ftp://example.com/slam\r\n" +
+	"LOCATION:Corner Pub\\, Chicago\r\n" +
+	"GEO:41.88;-87.63\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestImportICalendarQuarantine(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryEventStore()
+	quarantine := &fakeQuarantineStore{}
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	opts := ICalImportOptions{
+		OwnerId:          "owner1",
+		OwnerName:        "Corner Pub",
+		SourceKey:        "example.com/pub.ics",
+		FallbackTimezone: "America/Chicago",
+		Now:              now,
+		QuarantineStore:  quarantine,
+	}
+
+	result, err := ImportICalendar(ctx, store, []byte(testICalQuarantineFeed), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the series parent and its 3 children are published, the slam isn't
+	if result.Inserted != 4 || result.Quarantined != 1 {
+		t.Fatalf("expected 4 inserted and 1 quarantined, got %+v", result)
+	}
+	if len(quarantine.saved) != 1 || quarantine.saved[0].Name != "Poetry Slam" || quarantine.saved[0].SeshuJobUrl != opts.SourceKey {
+		t.Fatalf("expected the slam to be quarantined for its source, got %+v", quarantine.saved)
+	}
+	stored, err := SearchAllEvents(ctx, store, []string{"owner1"}, constants.ALL_EVENT_SOURCE_TYPES, nil)
+	if err != nil {
+		t.Fatalf("failed to search events: %v", err)
+	}
+	for _, event := range stored {
+		if event.Name == "Poetry Slam" {
+			t.Errorf("expected the quarantined event not to be published")
+		}
+	}
+
+	// The next run updates the published events and the same quarantine entry
+	result, err = ImportICalendar(ctx, store, []byte(testICalQuarantineFeed), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Updated != 4 || result.Quarantined != 1 {
+		t.Fatalf("expected 4 updated and 1 quarantined, got %+v", result)
+	}
+	if len(quarantine.saved) != 2 || quarantine.saved[0].Id != quarantine.saved[1].Id {
+		t.Errorf("expected the quarantine entry to keep its id across runs, got %+v", quarantine.saved)
+	}
+}
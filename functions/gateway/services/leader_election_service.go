@@ -0,0 +1,418 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	// SESHU_SCHEDULER_LEASE is the lease held by the instance running the
+	// Seshu, series, account deletion and data export loops
+	SESHU_SCHEDULER_LEASE = "seshu-scheduler"
+	// LEADER_LEASE_TTL is how long a lease lasts without renewal, a crashed
+	// leader is replaced within this long
+	LEADER_LEASE_TTL = 15 * time.Second
+	// LEADER_LEASE_RENEW_INTERVAL is how often every instance campaigns. A
+	// leader stops acting this long before its lease expires so clocks that
+	// are a little off can't make two instances lead at once
+	LEADER_LEASE_RENEW_INTERVAL = 5 * time.Second
+	// FENCING_TOKEN_HEADER carries the term of the leader that published a
+	// Seshu job
+	FENCING_TOKEN_HEADER = "Mnm-Fencing-Token"
+
+	leaderElectionBucketName = "leader-election"
+)
+
+var ErrNotLeader = errors.New("this instance is not the leader")
+
+// LeaderLease is the value stored under a lease's key. `Token` goes up
+// every time the lease changes hands, renewals keep it
+type LeaderLease struct {
+	Holder     string    `json:"holder"`
+	Token      uint64    `json:"token"`
+	AcquiredAt time.Time `json:"acquiredAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+}
+
+type LeaderStatus struct {
+	Lease    string `json:"lease"`
+	Instance string `json:"instance,omitempty"`
+	Leader   bool   `json:"leader"`
+	// Election is false when leadership comes from `IS_ACT_LEADER` because
+	// the lease bucket isn't available
+	Election bool `json:"election"`
+	// HasLeader is whether any instance holds an unexpired lease
+	HasLeader bool         `json:"hasLeader"`
+	Current   *LeaderLease `json:"current,omitempty"`
+}
+
+// Summary is the status without the instances, what anonymous callers get
+func (s LeaderStatus) Summary() LeaderStatus {
+	return LeaderStatus{Lease: s.Lease, Election: s.Election, HasLeader: s.HasLeader}
+}
+
+// leaderElectionKV is the part of `jetstream.KeyValue` the elector uses
+type leaderElectionKV interface {
+	Get(ctx context.Context, key string) (jetstream.KeyValueEntry, error)
+	Create(ctx context.Context, key string, value []byte, opts ...jetstream.KVCreateOpt) (uint64, error)
+	Update(ctx context.Context, key string, value []byte, revision uint64) (uint64, error)
+}
+
+// LeaderElector campaigns for a lease in a NATS KV bucket. Every write is
+// compare-and-set on the revision it read, so two instances can't both
+// take an expired lease
+type LeaderElector struct {
+	kv   leaderElectionKV
+	name string
+	id   string
+
+	mu       sync.Mutex
+	lease    LeaderLease
+	revision uint64
+	leading  bool
+	// validUntil is when this instance stops acting as leader unless it
+	// renews first
+	validUntil time.Time
+}
+
+// NewLeaderElector creates or updates the KV bucket holding leases and
+// returns an elector for the lease `name`
+func (s *NatsService) NewLeaderElector(ctx context.Context, name string) (*LeaderElector, error) {
+	kv, err := s.js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:      leaderElectionBucketName,
+		Description: "Leases of the instances running scheduled work",
+		History:     1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create leader election bucket: %w", err)
+	}
+	return newLeaderElector(kv, name, leaderInstanceId()), nil
+}
+
+func newLeaderElector(kv leaderElectionKV, name, id string) *LeaderElector {
+	return &LeaderElector{kv: kv, name: name, id: id}
+}
+
+// leaderInstanceId is the hostname with a random suffix, a restarted
+// container with the same hostname starts a new term
+func leaderInstanceId() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "act"
+	}
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return hostname
+	}
+	return hostname + "-" + hex.EncodeToString(suffix)
+}
+
+func (e *LeaderElector) InstanceId() string {
+	return e.id
+}
+
+// Campaign renews the lease when this instance holds it, takes it when it's
+// vacant or expired and otherwise follows whoever holds it
+func (e *LeaderElector) Campaign(ctx context.Context, now time.Time) error {
+	lease, revision, err := e.current(ctx)
+	if err != nil {
+		e.follow(LeaderLease{}, 0)
+		return err
+	}
+
+	next := lease
+	switch {
+	case revision != 0 && lease.Holder == e.id && now.Before(lease.ExpiresAt):
+	case revision == 0 || !now.Before(lease.ExpiresAt):
+		next = LeaderLease{Holder: e.id, Token: lease.Token + 1, AcquiredAt: now}
+	default:
+		e.follow(lease, revision)
+		return nil
+	}
+	next.ExpiresAt = now.Add(LEADER_LEASE_TTL)
+
+	data, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+	if revision == 0 {
+		revision, err = e.kv.Create(ctx, e.name, data)
+	} else {
+		revision, err = e.kv.Update(ctx, e.name, data, revision)
+	}
+	if err != nil {
+		// Most likely another instance wrote first, the next campaign
+		// reads who
+		e.follow(lease, 0)
+		return fmt.Errorf("failed to write lease %s: %w", e.name, err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.leading || e.lease.Token != next.Token {
+		log.Printf("[INFO] Became leader of %s with token %d", e.name, next.Token)
+	}
+	e.lease, e.revision, e.leading = next, revision, true
+	e.validUntil = now.Add(LEADER_LEASE_TTL - LEADER_LEASE_RENEW_INTERVAL)
+	return nil
+}
+
+func (e *LeaderElector) follow(lease LeaderLease, revision uint64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.leading {
+		log.Printf("[WARN] Lost leadership of %s, now held by %q", e.name, lease.Holder)
+	}
+	e.lease, e.revision, e.leading = lease, revision, false
+}
+
+// current reads the lease, revision 0 when nobody took it yet. A value
+// that can't be read is treated as expired with the revision as its token,
+// revisions only go up so the next token is still higher than any before
+func (e *LeaderElector) current(ctx context.Context) (LeaderLease, uint64, error) {
+	var lease LeaderLease
+	entry, err := e.kv.Get(ctx, e.name)
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		return lease, 0, nil
+	case err != nil:
+		return lease, 0, fmt.Errorf("failed to get lease %s: %w", e.name, err)
+	}
+	if err := json.Unmarshal(entry.Value(), &lease); err != nil {
+		log.Printf("ERR: replacing unreadable lease %s: %v", e.name, err)
+		lease = LeaderLease{Token: entry.Revision()}
+	}
+	return lease, entry.Revision(), nil
+}
+
+// Leading is the token of this instance's term, false when it isn't the
+// leader at `now`
+func (e *LeaderElector) Leading(now time.Time) (uint64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.leading || !now.Before(e.validUntil) {
+		return 0, false
+	}
+	return e.lease.Token, true
+}
+
+// Run campaigns every `LEADER_LEASE_RENEW_INTERVAL` until `ctx` is done,
+// then resigns
+func (e *LeaderElector) Run(ctx context.Context) {
+	ticker := time.NewTicker(LEADER_LEASE_RENEW_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		if err := e.Campaign(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			log.Printf("[ERROR] Leader election for %s failed: %v", e.name, err)
+		}
+		select {
+		case <-ctx.Done():
+			e.Resign(context.Background())
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Resign expires the lease now when this instance holds it, so the next
+// campaign of another instance takes over without waiting out the TTL
+func (e *LeaderElector) Resign(ctx context.Context) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if !e.leading {
+		return
+	}
+	e.leading = false
+	lease := e.lease
+	lease.ExpiresAt = time.Now()
+	data, err := json.Marshal(lease)
+	if err != nil {
+		return
+	}
+	if _, err := e.kv.Update(ctx, e.name, data, e.revision); err != nil {
+		log.Printf("[WARN] Failed to resign %s, it expires at %s: %v", e.name, e.lease.ExpiresAt.Format(time.RFC3339), err)
+		return
+	}
+	log.Printf("[INFO] Resigned leadership of %s", e.name)
+}
+
+func (e *LeaderElector) Status(ctx context.Context) (LeaderStatus, error) {
+	now := time.Now()
+	status := LeaderStatus{Lease: e.name, Instance: e.id, Election: true}
+	_, status.Leader = e.Leading(now)
+	lease, revision, err := e.current(ctx)
+	if err != nil {
+		return status, err
+	}
+	if revision != 0 {
+		status.Current = &lease
+		status.HasLeader = now.Before(lease.ExpiresAt)
+	}
+	return status, nil
+}
+
+// lastRunKey holds when the leader last finished the lease's scheduled
+// work, next to the lease so whoever leads next picks it up
+func (e *LeaderElector) lastRunKey() string {
+	return e.name + ".last-run"
+}
+
+// LastRun is the unix time the scheduled work last ran. The first time
+// anyone asks it's recorded as `now`, so a fresh deployment waits out one
+// interval instead of running straight away
+func (e *LeaderElector) LastRun(ctx context.Context, now int64) (int64, error) {
+	entry, err := e.kv.Get(ctx, e.lastRunKey())
+	if errors.Is(err, jetstream.ErrKeyNotFound) {
+		if _, err := e.kv.Create(ctx, e.lastRunKey(), []byte(strconv.FormatInt(now, 10))); err != nil {
+			return 0, fmt.Errorf("failed to create %s: %w", e.lastRunKey(), err)
+		}
+		return now, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to get %s: %w", e.lastRunKey(), err)
+	}
+	lastRun, err := strconv.ParseInt(string(entry.Value()), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unreadable %s %q: %w", e.lastRunKey(), entry.Value(), err)
+	}
+	return lastRun, nil
+}
+
+// RecordLastRun stores when the scheduled work last ran
+func (e *LeaderElector) RecordLastRun(ctx context.Context, lastRun int64) error {
+	value := []byte(strconv.FormatInt(lastRun, 10))
+	entry, err := e.kv.Get(ctx, e.lastRunKey())
+	switch {
+	case errors.Is(err, jetstream.ErrKeyNotFound):
+		_, err = e.kv.Create(ctx, e.lastRunKey(), value)
+	case err == nil:
+		_, err = e.kv.Update(ctx, e.lastRunKey(), value, entry.Revision())
+	}
+	if err != nil {
+		return fmt.Errorf("failed to record %s: %w", e.lastRunKey(), err)
+	}
+	return nil
+}
+
+var leaderElector *LeaderElector
+
+// SetLeaderElector makes `elector` decide leadership instead of
+// `IS_ACT_LEADER`, call it before the loops start
+func SetLeaderElector(elector *LeaderElector) {
+	leaderElector = elector
+}
+
+// IsLeader reports whether this instance should run the leader-only loops,
+// and the fencing token of its term. Without an elector it falls back to
+// `IS_ACT_LEADER` with no token
+func IsLeader() (uint64, bool) {
+	if leaderElector == nil {
+		return 0, os.Getenv("IS_ACT_LEADER") == "true"
+	}
+	return leaderElector.Leading(time.Now())
+}
+
+func GetLeaderStatus(ctx context.Context) (LeaderStatus, error) {
+	if leaderElector == nil {
+		_, leading := IsLeader()
+		return LeaderStatus{Lease: SESHU_SCHEDULER_LEASE, Leader: leading, HasLeader: leading}, nil
+	}
+	return leaderElector.Status(ctx)
+}
+
+// localSchedulerLastRun stands in for the lease bucket when there's no
+// elector, only this instance can be leading then
+var localSchedulerLastRun atomic.Int64
+
+// GetSchedulerLastRun is when the Seshu scheduler last gathered jobs, on
+// whichever instance was leading at the time
+func GetSchedulerLastRun(ctx context.Context, now int64) (int64, error) {
+	if leaderElector == nil {
+		localSchedulerLastRun.CompareAndSwap(0, now)
+		return localSchedulerLastRun.Load(), nil
+	}
+	return leaderElector.LastRun(ctx, now)
+}
+
+func SetSchedulerLastRun(ctx context.Context, lastRun int64) error {
+	if leaderElector == nil {
+		localSchedulerLastRun.Store(lastRun)
+		return nil
+	}
+	return leaderElector.RecordLastRun(ctx, lastRun)
+}
+
+type fencingTokenKey struct{}
+
+// WithFencingToken marks what's published under `ctx` as coming from the
+// leader term `token`
+func WithFencingToken(ctx context.Context, token uint64) context.Context {
+	return context.WithValue(ctx, fencingTokenKey{}, token)
+}
+
+func fencingToken(ctx context.Context) uint64 {
+	token, _ := ctx.Value(fencingTokenKey{}).(uint64)
+	return token
+}
+
+// checkFencingToken fails when `ctx` carries a token of a term this
+// instance no longer leads
+func checkFencingToken(ctx context.Context) error {
+	token := fencingToken(ctx)
+	if token == 0 {
+		return nil
+	}
+	if current, leading := IsLeader(); !leading || current != token {
+		return fmt.Errorf("%w, token %d is fenced off", ErrNotLeader, token)
+	}
+	return nil
+}
+
+// jobFencedOff reports whether a job is from a deposed leader: its token is
+// older than the current lease and it was published after the current term
+// started. Jobs the previous leader published while it still led are kept
+func jobFencedOff(headerToken string, published time.Time, lease LeaderLease) bool {
+	if headerToken == "" {
+		return false
+	}
+	token, err := strconv.ParseUint(headerToken, 10, 64)
+	if err != nil {
+		return false
+	}
+	return token < lease.Token && published.After(lease.AcquiredAt)
+}
+
+// isFencedOffJob checks a Seshu job against the current lease, a lease
+// that can't be read lets the job through
+func isFencedOffJob(ctx context.Context, msg jetstream.Msg) bool {
+	if leaderElector == nil {
+		return false
+	}
+	headerToken := msg.Headers().Get(FENCING_TOKEN_HEADER)
+	if headerToken == "" {
+		return false
+	}
+	metadata, err := msg.Metadata()
+	if err != nil {
+		return false
+	}
+	lease, _, err := leaderElector.current(ctx)
+	if err != nil {
+		log.Printf("[WARN] Fencing token of job not checked: %v", err)
+		return false
+	}
+	return jobFencedOff(headerToken, metadata.Timestamp, lease)
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestLeaderElectorCampaign(t *testing.T) {
+	kv := &fakeRateLimitKV{entries: map[string]fakeKVEntry{}}
+	a := newLeaderElector(kv, SESHU_SCHEDULER_LEASE, "act-a")
+	b := newLeaderElector(kv, SESHU_SCHEDULER_LEASE, "act-b")
+	ctx := context.Background()
+	now := time.Now()
+
+	if err := a.Campaign(ctx, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := b.Campaign(ctx, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token, leading := a.Leading(now); !leading || token != 1 {
+		t.Fatalf("expected the first instance to lead with token 1, got %d %v", token, leading)
+	}
+	if _, leading := b.Leading(now); leading {
+		t.Fatal("expected only one leader")
+	}
+
+	// Renewing keeps the term
+	renewed := now.Add(LEADER_LEASE_RENEW_INTERVAL)
+	if err := a.Campaign(ctx, renewed); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token, leading := a.Leading(renewed); !leading || token != 1 {
+		t.Errorf("expected the renewed lease to keep token 1, got %d %v", token, leading)
+	}
+
+	// The leader stops renewing, it stops acting before anyone takes over
+	takeover := renewed.Add(LEADER_LEASE_TTL)
+	if _, leading := a.Leading(takeover.Add(-LEADER_LEASE_RENEW_INTERVAL)); leading {
+		t.Error("expected a leader that didn't renew to step down before its lease expires")
+	}
+	if err := b.Campaign(ctx, takeover); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token, leading := b.Leading(takeover); !leading || token != 2 {
+		t.Fatalf("expected the expired lease to be taken with token 2, got %d %v", token, leading)
+	}
+	if err := a.Campaign(ctx, takeover); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, leading := a.Leading(takeover); leading {
+		t.Error("expected the old leader to follow")
+	}
+
+	status, err := a.Status(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if status.Leader || status.Current == nil || status.Current.Holder != "act-b" || status.Current.Token != 2 {
+		t.Errorf("expected the status to show the new leader, got %+v", status)
+	}
+}
+
+func TestLeaderElectorResign(t *testing.T) {
+	kv := &fakeRateLimitKV{entries: map[string]fakeKVEntry{}}
+	a := newLeaderElector(kv, SESHU_SCHEDULER_LEASE, "act-a")
+	b := newLeaderElector(kv, SESHU_SCHEDULER_LEASE, "act-b")
+	ctx := context.Background()
+
+	if err := a.Campaign(ctx, time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	a.Resign(ctx)
+	if _, leading := a.Leading(time.Now()); leading {
+		t.Error("expected a resigned leader to stop leading")
+	}
+
+	now := time.Now()
+	if err := b.Campaign(ctx, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token, leading := b.Leading(now); !leading || token != 2 {
+		t.Errorf("expected the lease to be taken without waiting out the TTL, got %d %v", token, leading)
+	}
+}
+
+func TestLeaderElectorLosesRace(t *testing.T) {
+	kv := &fakeRateLimitKV{entries: map[string]fakeKVEntry{}, conflicts: 1}
+	a := newLeaderElector(kv, SESHU_SCHEDULER_LEASE, "act-a")
+	now := time.Now()
+
+	if err := a.Campaign(context.Background(), now); err == nil {
+		t.Fatal("expected the conflicting write to fail")
+	}
+	if _, leading := a.Leading(now); leading {
+		t.Error("expected an instance that lost the write to follow")
+	}
+}
+
+func TestCheckFencingToken(t *testing.T) {
+	t.Setenv("IS_ACT_LEADER", "true")
+	defer SetLeaderElector(nil)
+
+	if token, leading := IsLeader(); !leading || token != 0 {
+		t.Errorf("expected IS_ACT_LEADER to decide without an elector, got %d %v", token, leading)
+	}
+
+	kv := &fakeRateLimitKV{entries: map[string]fakeKVEntry{}}
+	elector := newLeaderElector(kv, SESHU_SCHEDULER_LEASE, "act-a")
+	SetLeaderElector(elector)
+	if _, leading := IsLeader(); leading {
+		t.Fatal("expected the elector to override IS_ACT_LEADER")
+	}
+	if err := elector.Campaign(context.Background(), time.Now()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := checkFencingToken(context.Background()); err != nil {
+		t.Errorf("expected publishing without a token to be allowed, got %v", err)
+	}
+	if err := checkFencingToken(WithFencingToken(context.Background(), 1)); err != nil {
+		t.Errorf("expected the current term to publish, got %v", err)
+	}
+	if err := checkFencingToken(WithFencingToken(context.Background(), 2)); !errors.Is(err, ErrNotLeader) {
+		t.Errorf("expected another term's token to be fenced off, got %v", err)
+	}
+}
+
+func TestJobFencedOff(t *testing.T) {
+	acquired := time.Now()
+	lease := LeaderLease{Holder: "act-b", Token: 3, AcquiredAt: acquired}
+
+	tests := []struct {
+		name      string
+		token     string
+		published time.Time
+		want      bool
+	}{
+		{"no token", "", acquired.Add(time.Second), false},
+		{"current term", "3", acquired.Add(time.Second), false},
+		{"previous term before the takeover", "2", acquired.Add(-time.Second), false},
+		{"previous term after the takeover", "2", acquired.Add(time.Second), true},
+		{"unreadable token", "two", acquired.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := jobFencedOff(tt.token, tt.published, lease); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLeaderElectorLastRun(t *testing.T) {
+	kv := &fakeRateLimitKV{entries: map[string]fakeKVEntry{}}
+	a := newLeaderElector(kv, SESHU_SCHEDULER_LEASE, "act-a")
+	b := newLeaderElector(kv, SESHU_SCHEDULER_LEASE, "act-b")
+	ctx := context.Background()
+
+	if lastRun, err := a.LastRun(ctx, 1000); err != nil || lastRun != 1000 {
+		t.Fatalf("expected the first read to start the schedule now, got %d, %v", lastRun, err)
+	}
+	if lastRun, err := b.LastRun(ctx, 1500); err != nil || lastRun != 1000 {
+		t.Fatalf("expected another instance to see the same schedule, got %d, %v", lastRun, err)
+	}
+	if err := a.RecordLastRun(ctx, 2000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lastRun, err := b.LastRun(ctx, 2500); err != nil || lastRun != 2000 {
+		t.Errorf("expected the next leader to carry on from the recorded run, got %d, %v", lastRun, err)
+	}
+	if _, revision, err := a.current(ctx); err != nil || revision != 0 {
+		t.Errorf("expected the last run not to take the lease's key, got %d, %v", revision, err)
+	}
+}
+
+func TestLeaderStatusSummary(t *testing.T) {
+	status := LeaderStatus{Lease: SESHU_SCHEDULER_LEASE, Instance: "act-a", Leader: true, Election: true, HasLeader: true,
+		Current: &LeaderLease{Holder: "act-a", Token: 1}}
+	summary := status.Summary()
+	if summary.Instance != "" || summary.Current != nil || summary.Leader || !summary.HasLeader || !summary.Election {
+		t.Errorf("expected only whether there's a leader, got %+v", summary)
+	}
+}
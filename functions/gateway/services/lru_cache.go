@@ -0,0 +1,68 @@
+package services
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// lruCache holds at most `size` entries, dropping the least recently used
+// one to make room. Entries also expire `ttl` after they're added
+type lruCache[V any] struct {
+	size int
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type lruCacheEntry[V any] struct {
+	key     string
+	value   V
+	expires time.Time
+}
+
+func newLRUCache[V any](size int, ttl time.Duration) *lruCache[V] {
+	return &lruCache[V]{size: size, ttl: ttl, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+func (c *lruCache[V]) Get(key string, now time.Time) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		return zero, false
+	}
+	entry := element.Value.(*lruCacheEntry[V])
+	if !now.Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+func (c *lruCache[V]) Add(key string, value V, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[key]; ok {
+		element.Value = &lruCacheEntry[V]{key: key, value: value, expires: now.Add(c.ttl)}
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(&lruCacheEntry[V]{key: key, value: value, expires: now.Add(c.ttl)})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruCacheEntry[V]).key)
+	}
+}
+
+func (c *lruCache[V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLRUCache(t *testing.T) {
+	cache := newLRUCache[int](2, time.Minute)
+	now := time.Now()
+
+	cache.Add("a", 1, now)
+	cache.Add("b", 2, now)
+	if value, ok := cache.Get("a", now); !ok || value != 1 {
+		t.Fatalf("expected a cached, got %d %v", value, ok)
+	}
+	// "b" is now the least recently used
+	cache.Add("c", 3, now)
+	if _, ok := cache.Get("b", now); ok {
+		t.Error("expected the least recently used entry to be dropped")
+	}
+	if cache.Len() != 2 {
+		t.Errorf("expected the cache to stay at its size, got %d entries", cache.Len())
+	}
+
+	cache.Add("a", 4, now.Add(30*time.Second))
+	if value, ok := cache.Get("a", now.Add(time.Minute)); !ok || value != 4 {
+		t.Errorf("expected re-adding to replace the value and renew it, got %d %v", value, ok)
+	}
+	if _, ok := cache.Get("c", now.Add(time.Minute)); ok {
+		t.Error("expected the entry to expire after the TTL")
+	}
+	if cache.Len() != 1 {
+		t.Errorf("expected the expired entry to be removed, got %d entries", cache.Len())
+	}
+}
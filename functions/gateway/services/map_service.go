@@ -0,0 +1,213 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+const (
+	// At this zoom and beyond every event is its own point
+	MapClusterMaxZoom = 14
+	MapMaxZoom        = 22
+
+	// Events closer than one grid cell on screen are merged into a cluster
+	mapClusterCellPx = 60
+	mapTileSizePx    = 256
+	// Web Mercator is undefined at the poles, tiles stop at this latitude
+	mapMaxMercatorLat = 85.05112878
+	// A cluster lists at most this many of its event ids
+	mapClusterMaxEventIds = 20
+)
+
+var ErrInvalidMapBounds = errors.New("bbox must be west,south,east,north in degrees")
+
+// MapBounds is a map viewport. West is greater than East when the viewport
+// crosses the antimeridian
+type MapBounds struct {
+	West, South, East, North float64
+}
+
+// ParseMapBounds reads a `west,south,east,north` bbox, the order used by
+// GeoJSON and most map libraries
+func ParseMapBounds(raw string) (MapBounds, error) {
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return MapBounds{}, ErrInvalidMapBounds
+	}
+	values := make([]float64, 4)
+	for i, part := range parts {
+		value, err := strconv.ParseFloat(strings.TrimSpace(part), 64)
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return MapBounds{}, ErrInvalidMapBounds
+		}
+		values[i] = value
+	}
+	west, south, east, north := values[0], values[1], values[2], values[3]
+	if south < -90 || north > 90 || south > north {
+		return MapBounds{}, ErrInvalidMapBounds
+	}
+	return NewMapBounds(west, south, east, north), nil
+}
+
+// NewMapBounds folds longitudes back into [-180, 180]. Map libraries keep
+// counting past ±180 when panned across the antimeridian, so a viewport of
+// 170..190 becomes 170..-170
+func NewMapBounds(west, south, east, north float64) MapBounds {
+	if east-west >= 360 {
+		return MapBounds{West: -180, South: south, East: 180, North: north}
+	}
+	return MapBounds{
+		West:  wrapLongitude(west, false),
+		South: south,
+		East:  wrapLongitude(east, true),
+		North: north,
+	}
+}
+
+// wrapLongitude maps `lng` into [-180, 180), or (-180, 180] for an eastern
+// edge so a viewport ending exactly on the antimeridian doesn't look wrapped
+func wrapLongitude(lng float64, eastern bool) float64 {
+	wrapped := math.Mod(lng+180, 360)
+	if wrapped < 0 {
+		wrapped += 360
+	}
+	wrapped -= 180
+	if eastern && wrapped == -180 {
+		return 180
+	}
+	return wrapped
+}
+
+func (b MapBounds) CrossesAntimeridian() bool {
+	return b.West > b.East
+}
+
+// LongitudeRanges is the inverse of the antimeridian split in
+// `calculateSearchBounds`: one range normally, two when the viewport wraps
+func (b MapBounds) LongitudeRanges() [][2]float64 {
+	if b.CrossesAntimeridian() {
+		return [][2]float64{{b.West, 180}, {-180, b.East}}
+	}
+	return [][2]float64{{b.West, b.East}}
+}
+
+func (b MapBounds) String() string {
+	return fmt.Sprintf("%g,%g,%g,%g", b.West, b.South, b.East, b.North)
+}
+
+// mapPixel projects a coordinate to Web Mercator pixels at `zoom`
+func mapPixel(lat, lng float64, zoom int) (float64, float64) {
+	lat = math.Max(-mapMaxMercatorLat, math.Min(mapMaxMercatorLat, lat))
+	worldPx := float64(mapTileSizePx) * math.Exp2(float64(zoom))
+	latRad := lat * math.Pi / 180
+	x := (lng + 180) / 360 * worldPx
+	y := (1 - math.Log(math.Tan(latRad)+1/math.Cos(latRad))/math.Pi) / 2 * worldPx
+	return x, y
+}
+
+type mapCell struct {
+	x, y int
+}
+
+func mapCellAt(event types.Event, zoom int) mapCell {
+	x, y := mapPixel(event.Lat, event.Long, zoom)
+	return mapCell{x: int(math.Floor(x / mapClusterCellPx)), y: int(math.Floor(y / mapClusterCellPx))}
+}
+
+// clusterExpansionZoom is the first zoom at which `events` no longer share a
+// single cell, i.e. where clicking the cluster should zoom to
+func clusterExpansionZoom(events []types.Event, zoom int) int {
+	for z := zoom + 1; z < MapClusterMaxZoom; z++ {
+		first := mapCellAt(events[0], z)
+		for _, event := range events[1:] {
+			if mapCellAt(event, z) != first {
+				return z
+			}
+		}
+	}
+	return MapClusterMaxZoom
+}
+
+func eventPointFeature(event types.Event) types.GeoJSONFeature {
+	return types.GeoJSONFeature{
+		Type: types.GeoJSONFeatureType,
+		Id:   event.Id,
+		Geometry: types.GeoJSONPoint{
+			Type:        types.GeoJSONPointType,
+			Coordinates: []float64{event.Long, event.Lat},
+		},
+		Properties: map[string]interface{}{
+			"id":              event.Id,
+			"name":            event.Name,
+			"startTime":       event.StartTime,
+			"endTime":         event.EndTime,
+			"timezone":        event.Timezone.String(),
+			"address":         event.Address,
+			"eventSourceType": event.EventSourceType,
+		},
+	}
+}
+
+// ClusterEventsGeoJSON turns a map search into GeoJSON features. Below
+// `MapClusterMaxZoom` events that land in the same on-screen grid cell are
+// merged into one cluster point at their centroid, the first event of each
+// cell decides where that cell appears in the output
+func ClusterEventsGeoJSON(events []types.Event, zoom int) []types.GeoJSONFeature {
+	features := []types.GeoJSONFeature{}
+	if zoom >= MapClusterMaxZoom {
+		for _, event := range events {
+			features = append(features, eventPointFeature(event))
+		}
+		return features
+	}
+
+	cells := map[mapCell][]types.Event{}
+	order := []mapCell{}
+	for _, event := range events {
+		cell := mapCellAt(event, zoom)
+		if _, ok := cells[cell]; !ok {
+			order = append(order, cell)
+		}
+		cells[cell] = append(cells[cell], event)
+	}
+
+	for _, cell := range order {
+		cellEvents := cells[cell]
+		if len(cellEvents) == 1 {
+			features = append(features, eventPointFeature(cellEvents[0]))
+			continue
+		}
+
+		// A cell never spans the antimeridian, so a plain average is safe
+		var latSum, longSum float64
+		eventIds := []string{}
+		for _, event := range cellEvents {
+			latSum += event.Lat
+			longSum += event.Long
+			if len(eventIds) < mapClusterMaxEventIds {
+				eventIds = append(eventIds, event.Id)
+			}
+		}
+		count := float64(len(cellEvents))
+		features = append(features, types.GeoJSONFeature{
+			Type: types.GeoJSONFeatureType,
+			Id:   fmt.Sprintf("cluster-%d-%d-%d", zoom, cell.x, cell.y),
+			Geometry: types.GeoJSONPoint{
+				Type:        types.GeoJSONPointType,
+				Coordinates: []float64{longSum / count, latSum / count},
+			},
+			Properties: map[string]interface{}{
+				"cluster":       true,
+				"pointCount":    len(cellEvents),
+				"expansionZoom": clusterExpansionZoom(cellEvents, zoom),
+				"eventIds":      eventIds,
+			},
+		})
+	}
+	return features
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+func TestParseMapBounds(t *testing.T) {
+	tests := []struct {
+		name       string
+		raw        string
+		want       MapBounds
+		wantRanges [][2]float64
+		wantErr    bool
+	}{
+		{
+			name:       "plain viewport",
+			raw:        "-74.1,40.6,-73.8,40.9",
+			want:       MapBounds{West: -74.1, South: 40.6, East: -73.8, North: 40.9},
+			wantRanges: [][2]float64{{-74.1, -73.8}},
+		},
+		{
+			name:       "viewport crossing the antimeridian",
+			raw:        "170,-20,-170,10",
+			want:       MapBounds{West: 170, South: -20, East: -170, North: 10},
+			wantRanges: [][2]float64{{170, 180}, {-180, -170}},
+		},
+		{
+			name:       "longitudes past 180 after panning east are wrapped",
+			raw:        "170,-20,190,10",
+			want:       MapBounds{West: 170, South: -20, East: -170, North: 10},
+			wantRanges: [][2]float64{{170, 180}, {-180, -170}},
+		},
+		{
+			name:       "longitudes past -180 after panning west are wrapped",
+			raw:        "-190,-20,-170,10",
+			want:       MapBounds{West: 170, South: -20, East: -170, North: 10},
+			wantRanges: [][2]float64{{170, 180}, {-180, -170}},
+		},
+		{
+			name:       "viewport ending on the antimeridian does not wrap",
+			raw:        "160,0,180,10",
+			want:       MapBounds{West: 160, South: 0, East: 180, North: 10},
+			wantRanges: [][2]float64{{160, 180}},
+		},
+		{
+			name:       "whole world zoomed out past one copy",
+			raw:        "-300,-85,400,85",
+			want:       MapBounds{West: -180, South: -85, East: 180, North: 85},
+			wantRanges: [][2]float64{{-180, 180}},
+		},
+		{name: "missing value", raw: "1,2,3", wantErr: true},
+		{name: "not a number", raw: "a,2,3,4", wantErr: true},
+		{name: "south above north", raw: "0,10,1,5", wantErr: true},
+		{name: "latitude out of range", raw: "0,-91,1,5", wantErr: true},
+		{name: "empty", raw: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMapBounds(tt.raw)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidMapBounds) {
+					t.Fatalf("expected ErrInvalidMapBounds, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseMapBounds(%q) = %+v, want %+v", tt.raw, got, tt.want)
+			}
+			if ranges := got.LongitudeRanges(); !reflect.DeepEqual(ranges, tt.wantRanges) {
+				t.Errorf("LongitudeRanges() = %v, want %v", ranges, tt.wantRanges)
+			}
+		})
+	}
+}
+
+func TestLocationWhereFilters(t *testing.T) {
+	single := locationWhereFilters(40, 41, [][2]float64{{-75, -73}})
+	if len(single) != 2 || strings.Contains(single[1].String(), "operator: Or") {
+		t.Errorf("a single longitude range should not need an Or: %s", single[1].String())
+	}
+
+	split := locationWhereFilters(-20, 10, NewMapBounds(170, -20, -170, 10).LongitudeRanges())
+	longFilter := split[1].String()
+	for _, want := range []string{"operator: Or", "valueNumber: 170", "valueNumber: 180", "valueNumber: -180", "valueNumber: -170"} {
+		if !strings.Contains(longFilter, want) {
+			t.Errorf("expected antimeridian longitude filter to contain %q: %s", want, longFilter)
+		}
+	}
+}
+
+func TestClusterEventsGeoJSON(t *testing.T) {
+	events := []types.Event{
+		{Id: "nyc-1", Name: "Midtown", Lat: 40.7549, Long: -73.9840},
+		{Id: "nyc-2", Name: "Chelsea", Lat: 40.7465, Long: -74.0014},
+		{Id: "nyc-3", Name: "Union Sq", Lat: 40.7359, Long: -73.9911},
+		{Id: "la-1", Name: "Los Angeles", Lat: 34.0522, Long: -118.2437},
+		{Id: "fiji-east", Name: "East of the line", Lat: -17.0, Long: 179.95},
+		{Id: "fiji-west", Name: "West of the line", Lat: -17.0, Long: -179.95},
+	}
+
+	t.Run("low zoom clusters nearby events", func(t *testing.T) {
+		features := ClusterEventsGeoJSON(events, 4)
+
+		var cluster *types.GeoJSONFeature
+		points := map[string]bool{}
+		for i := range features {
+			if features[i].Properties["cluster"] == true {
+				if cluster != nil {
+					t.Fatalf("expected a single cluster, got another: %+v", features[i])
+				}
+				cluster = &features[i]
+				continue
+			}
+			points[features[i].Id] = true
+		}
+		if cluster == nil {
+			t.Fatalf("expected the Manhattan events to be clustered, got %+v", features)
+		}
+		if cluster.Properties["pointCount"] != 3 {
+			t.Errorf("pointCount = %v, want 3", cluster.Properties["pointCount"])
+		}
+		if !reflect.DeepEqual(cluster.Properties["eventIds"], []string{"nyc-1", "nyc-2", "nyc-3"}) {
+			t.Errorf("eventIds = %v", cluster.Properties["eventIds"])
+		}
+		if zoom, _ := cluster.Properties["expansionZoom"].(int); zoom <= 4 || zoom > MapClusterMaxZoom {
+			t.Errorf("expansionZoom = %v, want within (4, %d]", cluster.Properties["expansionZoom"], MapClusterMaxZoom)
+		}
+		lng, lat := cluster.Geometry.Coordinates[0], cluster.Geometry.Coordinates[1]
+		if lat < 40.73 || lat > 40.76 || lng < -74.01 || lng > -73.98 {
+			t.Errorf("cluster centroid %v,%v is outside Manhattan", lat, lng)
+		}
+		// events on either side of the antimeridian are on opposite edges of
+		// the projected world and must not be averaged into a point near 0°
+		for _, id := range []string{"la-1", "fiji-east", "fiji-west"} {
+			if !points[id] {
+				t.Errorf("expected %s to stay a single point", id)
+			}
+		}
+	})
+
+	t.Run("max zoom returns every event as a point", func(t *testing.T) {
+		features := ClusterEventsGeoJSON(events, MapClusterMaxZoom)
+		if len(features) != len(events) {
+			t.Fatalf("got %d features, want %d", len(features), len(events))
+		}
+		first := features[0]
+		if first.Geometry.Type != types.GeoJSONPointType || !reflect.DeepEqual(first.Geometry.Coordinates, []float64{-73.9840, 40.7549}) {
+			t.Errorf("unexpected geometry %+v, coordinates must be [long, lat]", first.Geometry)
+		}
+		if first.Properties["name"] != "Midtown" {
+			t.Errorf("unexpected properties %+v", first.Properties)
+		}
+	})
+}
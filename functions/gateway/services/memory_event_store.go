@@ -0,0 +1,801 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/google/uuid"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/types"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// Weights of a query token found in each property when ranking a text search,
+// standing in for Weaviate's hybrid score
+var memorySearchWeights = map[string]float64{
+	"name":           3,
+	"categories":     2,
+	"tags":           2,
+	"description":    1,
+	"address":        1,
+	"eventOwnerName": 1,
+}
+
+// memoryEventTokenization is how each text property of the events class is
+// tokenized, so filters match here exactly as they would in Weaviate
+var memoryEventTokenization = func() map[string]string {
+	tokenization := map[string]string{"id": "id"}
+	for _, property := range EventClassDefinition("").Properties {
+		if len(property.DataType) == 0 || !strings.HasPrefix(property.DataType[0], "text") {
+			continue
+		}
+		tokenization[property.Name] = "word"
+		if property.Tokenization != "" {
+			tokenization[property.Name] = property.Tokenization
+		}
+	}
+	return tokenization
+}()
+
+// memoryEventObject is a stored event, kept as the properties Weaviate would
+// hold for it
+type memoryEventObject struct {
+	id         string
+	properties map[string]interface{}
+	// createdAt and updatedAt are unix milliseconds like Weaviate's
+	// creationTimeUnix and lastUpdateTimeUnix
+	createdAt int64
+	updatedAt int64
+}
+
+// MemoryEventStore is an `EventStore` that keeps events in process. Filters
+// are evaluated against the same where filters sent to Weaviate, honoring each
+// property's tokenization. Text search has no vectors, every event passing the
+// filters is returned ranked by how many query words it contains
+type MemoryEventStore struct {
+	mu      sync.RWMutex
+	objects map[string]*memoryEventObject
+}
+
+func NewMemoryEventStore() *MemoryEventStore {
+	return &MemoryEventStore{objects: map[string]*memoryEventObject{}}
+}
+
+func (s *MemoryEventStore) BulkUpsertEvent(ctx context.Context, events []types.Event) error {
+	_, err := s.UpsertEvents(ctx, events)
+	return err
+}
+
+func (s *MemoryEventStore) UpsertEvents(ctx context.Context, events []types.Event) ([]models.ObjectsGetResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	className := EventClassName()
+	status := models.ObjectsGetResponseAO2ResultStatusSUCCESS
+	res := make([]models.ObjectsGetResponse, 0, len(events))
+	for _, event := range events {
+		id := event.Id
+		if id != "" {
+			if _, err := uuid.Parse(id); err != nil {
+				log.Printf("WARN: Provided Event.Id '%s' is not a valid UUID. A new one will be generated.", id)
+				id = ""
+			}
+		}
+		if id == "" {
+			id = uuid.NewString()
+		}
+		id = strings.ToLower(id)
+
+		properties := EventStructToMap(event)
+		stored, err := memoryEventProperties(properties)
+		if err != nil {
+			return nil, fmt.Errorf("failed to store event %s: %w", id, err)
+		}
+
+		now := time.Now().UnixMilli()
+		obj := &memoryEventObject{id: id, properties: stored, createdAt: now, updatedAt: now}
+		if existing, ok := s.objects[id]; ok {
+			obj.createdAt = existing.createdAt
+		}
+		s.objects[id] = obj
+
+		res = append(res, models.ObjectsGetResponse{
+			Object: models.Object{
+				Class:              className,
+				ID:                 strfmt.UUID(id),
+				Properties:         properties,
+				CreationTimeUnix:   obj.createdAt,
+				LastUpdateTimeUnix: obj.updatedAt,
+			},
+			Result: &models.ObjectsGetResponseAO2Result{Status: &status},
+		})
+	}
+	return res, nil
+}
+
+func (s *MemoryEventStore) UpdateEvents(ctx context.Context, events []types.Event) ([]models.ObjectsGetResponse, error) {
+	for i, event := range events {
+		if event.Id == "" {
+			return nil, fmt.Errorf("event at index %d is missing an ID, cannot perform bulk update", i)
+		}
+	}
+	return s.UpsertEvents(ctx, events)
+}
+
+func (s *MemoryEventStore) SearchEvents(ctx context.Context, query string, userLocation []float64, maxDistance float64, startTime, endTime int64, ownerIds []string, categories string, address string, parseDates string, eventSourceTypes []string, eventSourceIds []string) (types.EventSearchResponse, error) {
+	return s.SearchEventsPage(ctx, query, userLocation, maxDistance, startTime, endTime, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds, EventSearchPage{})
+}
+
+func (s *MemoryEventStore) SearchEventsPage(ctx context.Context, query string, userLocation []float64, maxDistance float64, startTime, endTime int64, ownerIds []string, categories string, address string, parseDates string, eventSourceTypes []string, eventSourceIds []string, page EventSearchPage) (types.EventSearchResponse, error) {
+	plan, err := planEventSearch(query, userLocation, maxDistance, startTime, endTime, ownerIds, categories, address, eventSourceTypes, eventSourceIds, page)
+	if err != nil {
+		return types.EventSearchResponse{Query: query, Events: []types.Event{}}, err
+	}
+
+	fetchLimit := plan.fetchLimit()
+	if fetchLimit == 0 {
+		return plan.exhaustedResponse(), nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	hits, err := s.find(plan.pageFilter())
+	if err != nil {
+		return types.EventSearchResponse{Query: query, Events: []types.Event{}}, err
+	}
+	if plan.hybridQuery != "" {
+		hits = rankMemoryEvents(hits, plan.hybridQuery)
+		hits = hits[min(plan.offset, len(hits)):]
+	} else {
+		sort.SliceStable(hits, func(i, j int) bool {
+			return memoryEventStartTime(hits[i]) < memoryEventStartTime(hits[j])
+		})
+	}
+	hits = hits[:min(fetchLimit, len(hits))]
+
+	events := []types.Event{}
+	for i, hit := range hits {
+		if i == plan.limit {
+			break
+		}
+		event, err := hit.toEvent(eventSearchFields)
+		if err != nil {
+			log.Printf("Warning: Could not normalize stored event: %v", err)
+			continue
+		}
+		events = append(events, *event)
+	}
+
+	res := plan.response(len(hits), events, parseDates, func() (int, error) {
+		matched, err := s.find(plan.countFilter())
+		return len(matched), err
+	})
+
+	if page.Facets {
+		res.Facets, err = s.facets(plan.filters, plan.searchStart(), plan.searchEnd(), page.Timezone, page.Overlap)
+		if err != nil {
+			log.Printf("Warning: could not compute search facets: %v", err)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *MemoryEventStore) GetEventByID(ctx context.Context, docId string, parseDates string) (*types.Event, error) {
+	if docId == "" {
+		return nil, fmt.Errorf("document ID cannot be empty")
+	}
+	events, err := s.BulkGetEventByID(ctx, []string{docId}, parseDates)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no event found with id: %s", docId)
+	}
+	return events[0], nil
+}
+
+func (s *MemoryEventStore) BulkGetEventByID(ctx context.Context, docIds []string, parseDates string) ([]*types.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := []*types.Event{}
+	for _, docId := range docIds {
+		obj, ok := s.objects[strings.ToLower(docId)]
+		if !ok {
+			continue
+		}
+		event, err := obj.toEvent(eventDetailFields)
+		if err != nil {
+			log.Printf("Warning: Could not normalize stored event: %v", err)
+			continue
+		}
+		if parseDates == "1" {
+			event.LocalizedStartTime, event.LocalizedStartDate = helpers.GetLocalDateAndTime(event.StartTime, event.Timezone)
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func (s *MemoryEventStore) BulkDeleteEvents(ctx context.Context, docIds []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, docId := range docIds {
+		delete(s.objects, strings.ToLower(docId))
+	}
+	return nil
+}
+
+func (s *MemoryEventStore) FindRecurringSeriesParents(ctx context.Context) ([]types.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched, err := s.find(recurringSeriesParentsFilter())
+	if err != nil {
+		return nil, err
+	}
+	parents := []types.Event{}
+	for _, obj := range matched {
+		event, err := obj.toEvent(eventDetailFields)
+		if err != nil {
+			log.Printf("Warning: Could not normalize stored event: %v", err)
+			continue
+		}
+		if event.RecurrenceRule != "" {
+			parents = append(parents, *event)
+		}
+	}
+	return parents, nil
+}
+
+// SearchSimilarEvents ranks the events passing `similarEventsWhereFilter` by
+// the share of words they have in common with `source`, in place of
+// Weaviate's vector distance
+func (s *MemoryEventStore) SearchSimilarEvents(ctx context.Context, source types.Event, radius float64, limit int, parseDates string) ([]types.Event, error) {
+	if source.Id == "" {
+		return nil, fmt.Errorf("source event ID cannot be empty")
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sourceObj, ok := s.objects[strings.ToLower(source.Id)]
+	if !ok {
+		return nil, fmt.Errorf("no event found with id: %s", source.Id)
+	}
+	matched, err := s.find(similarEventsWhereFilter(source, radius, time.Now().Unix()))
+	if err != nil {
+		return nil, err
+	}
+
+	sourceWords := sourceObj.words()
+	scores := map[string]float64{}
+	for _, obj := range matched {
+		words := obj.words()
+		shared := 0
+		for word := range words {
+			if sourceWords[word] {
+				shared++
+			}
+		}
+		if union := len(words) + len(sourceWords) - shared; union > 0 {
+			scores[obj.id] = float64(shared) / float64(union)
+		}
+	}
+	sort.SliceStable(matched, func(i, j int) bool {
+		if scores[matched[i].id] != scores[matched[j].id] {
+			return scores[matched[i].id] > scores[matched[j].id]
+		}
+		return memoryEventStartTime(matched[i]) < memoryEventStartTime(matched[j])
+	})
+
+	events := []types.Event{}
+	for _, obj := range matched[:min(ClampSimilarEventsLimit(limit), len(matched))] {
+		event, err := obj.toEvent(eventSearchFields)
+		if err != nil {
+			log.Printf("Warning: Could not normalize stored event: %v", err)
+			continue
+		}
+		if parseDates == "1" && event.Timezone.String() != "" {
+			event.LocalizedStartTime, event.LocalizedStartDate = helpers.GetLocalDateAndTime(event.StartTime, event.Timezone)
+		}
+		events = append(events, *event)
+	}
+	return events, nil
+}
+
+func (s *MemoryEventStore) GetReSharedOwners(ctx context.Context, userId string) ([]string, error) {
+	if userId == "" {
+		return []string{}, nil
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched, err := s.find((&filters.WhereBuilder{}).WithPath([]string{"shadowOwners"}).WithOperator(filters.ContainsAny).WithValueText(userId))
+	if err != nil {
+		return nil, err
+	}
+	owners := []string{}
+	seen := map[string]bool{userId: true}
+	for _, obj := range matched[:min(maxReSharedEventsForFeed, len(matched))] {
+		for _, owner := range memoryPropertyValues(obj.properties["eventOwners"]) {
+			if ownerStr, ok := owner.(string); ok && !seen[ownerStr] {
+				seen[ownerStr] = true
+				owners = append(owners, ownerStr)
+			}
+		}
+	}
+	return owners, nil
+}
+
+func (s *MemoryEventStore) AddShadowOwner(ctx context.Context, eventId string, userId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	obj, ok := s.objects[strings.ToLower(eventId)]
+	if !ok {
+		return fmt.Errorf("no event found with id: %s", eventId)
+	}
+	shadowOwners := memoryPropertyValues(obj.properties["shadowOwners"])
+	for _, owner := range shadowOwners {
+		if owner == userId {
+			return nil
+		}
+	}
+	obj.properties["shadowOwners"] = append(append([]interface{}{}, shadowOwners...), userId)
+	obj.updatedAt = time.Now().UnixMilli()
+	return nil
+}
+
+// facets counts the same aliases `buildEventFacetQuery` asks Weaviate for
+func (s *MemoryEventStore) facets(base []*filters.WhereBuilder, start, end int64, loc *time.Location, overlap bool) (*types.EventSearchFacets, error) {
+	granularity, buckets := eventFacetDateBuckets(start, end, loc)
+
+	counts := map[string]int{}
+	owners := []types.EventFacetCount{}
+	for _, facet := range eventFacetFilters(buckets, overlap) {
+		operands := append(append([]*filters.WhereBuilder{}, base...), facet.filters...)
+		var where *filters.WhereBuilder
+		if len(operands) > 0 {
+			where = (&filters.WhereBuilder{}).WithOperator(filters.And).WithOperands(operands)
+		}
+		matched, err := s.find(where)
+		if err != nil {
+			return nil, err
+		}
+		counts[facet.alias] = len(matched)
+
+		if facet.alias == "all" {
+			occurs := map[string]int{}
+			for _, obj := range matched {
+				for _, owner := range memoryPropertyValues(obj.properties["eventOwners"]) {
+					if ownerStr, ok := owner.(string); ok && ownerStr != "" {
+						occurs[ownerStr]++
+					}
+				}
+			}
+			for owner, count := range occurs {
+				owners = append(owners, types.EventFacetCount{Key: owner, Count: count})
+			}
+			sort.Slice(owners, func(i, j int) bool {
+				if owners[i].Count != owners[j].Count {
+					return owners[i].Count > owners[j].Count
+				}
+				return owners[i].Key < owners[j].Key
+			})
+			owners = owners[:min(maxEventFacetOwners, len(owners))]
+		}
+	}
+
+	return newEventFacets(func(alias string) (int, error) {
+		count, ok := counts[alias]
+		if !ok {
+			return 0, fmt.Errorf("no %s facet count", alias)
+		}
+		return count, nil
+	}, owners, granularity, buckets)
+}
+
+// find returns the stored events matching `where` ordered by id, which is
+// how Weaviate lists objects when nothing else orders them. Callers hold the
+// lock
+func (s *MemoryEventStore) find(where *filters.WhereBuilder) ([]*memoryEventObject, error) {
+	var whereFilter *models.WhereFilter
+	if where != nil {
+		whereFilter = where.Build()
+	}
+	matched := []*memoryEventObject{}
+	for _, obj := range s.objects {
+		if whereFilter != nil {
+			ok, err := obj.matches(whereFilter)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		matched = append(matched, obj)
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].id < matched[j].id
+	})
+	return matched, nil
+}
+
+// rankMemoryEvents orders `objects` by the weight of the `query` words they
+// contain, ties by start time
+func rankMemoryEvents(objects []*memoryEventObject, query string) []*memoryEventObject {
+	queryTokens := tokenizeMemoryText("word", query)
+	scores := map[string]float64{}
+	for _, obj := range objects {
+		for property, weight := range memorySearchWeights {
+			tokens := map[string]bool{}
+			for _, value := range memoryPropertyValues(obj.properties[property]) {
+				if text, ok := value.(string); ok {
+					for _, token := range tokenizeMemoryText("word", text) {
+						tokens[token] = true
+					}
+				}
+			}
+			for _, token := range queryTokens {
+				if tokens[token] {
+					scores[obj.id] += weight
+				}
+			}
+		}
+	}
+	sort.SliceStable(objects, func(i, j int) bool {
+		if scores[objects[i].id] != scores[objects[j].id] {
+			return scores[objects[i].id] > scores[objects[j].id]
+		}
+		return memoryEventStartTime(objects[i]) < memoryEventStartTime(objects[j])
+	})
+	return objects
+}
+
+func memoryEventStartTime(obj *memoryEventObject) int64 {
+	startTime, _ := memoryInt(obj.properties["startTime"])
+	return startTime
+}
+
+// memoryEventProperties round trips `properties` through JSON so they're held
+// the way Weaviate returns them, ints stay exact as `json.Number`
+func memoryEventProperties(properties map[string]interface{}) (map[string]interface{}, error) {
+	raw, err := json.Marshal(properties)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	stored := map[string]interface{}{}
+	if err := decoder.Decode(&stored); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// toEvent reads the stored event as a Weaviate query selecting `fields` would
+func (obj *memoryEventObject) toEvent(fields []graphql.Field) (*types.Event, error) {
+	objMap := map[string]interface{}{
+		"_additional": map[string]interface{}{
+			"id":                 obj.id,
+			"creationTimeUnix":   strconv.FormatInt(obj.createdAt, 10),
+			"lastUpdateTimeUnix": strconv.FormatInt(obj.updatedAt, 10),
+		},
+	}
+	for _, field := range fields {
+		if value, ok := obj.properties[field.Name]; ok && field.Name != "_additional" {
+			objMap[field.Name] = value
+		}
+	}
+	return NormalizeWeaviateResultToEvent(objMap)
+}
+
+// words is every word of the event's name, description and categories
+func (obj *memoryEventObject) words() map[string]bool {
+	words := map[string]bool{}
+	for _, property := range []string{"name", "description", "categories"} {
+		for _, value := range memoryPropertyValues(obj.properties[property]) {
+			if text, ok := value.(string); ok {
+				for _, token := range tokenizeMemoryText("word", text) {
+					words[token] = true
+				}
+			}
+		}
+	}
+	return words
+}
+
+// values is what the where filter path `path` resolves to, one entry per
+// array element and none when the property is missing
+func (obj *memoryEventObject) values(path []string) []interface{} {
+	if len(path) != 1 {
+		return nil
+	}
+	if path[0] == "id" {
+		return []interface{}{obj.id}
+	}
+	return memoryPropertyValues(obj.properties[path[0]])
+}
+
+func memoryPropertyValues(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		return v
+	default:
+		return []interface{}{v}
+	}
+}
+
+// matches evaluates a Weaviate where filter against the event
+func (obj *memoryEventObject) matches(where *models.WhereFilter) (bool, error) {
+	switch filters.WhereOperator(where.Operator) {
+	case filters.And, filters.Or:
+		isAnd := filters.WhereOperator(where.Operator) == filters.And
+		for _, operand := range where.Operands {
+			ok, err := obj.matches(operand)
+			if err != nil {
+				return false, err
+			}
+			if ok != isAnd {
+				return ok, nil
+			}
+		}
+		return isAnd, nil
+	case filters.IsNull:
+		wantNull := where.ValueBoolean != nil && *where.ValueBoolean
+		return (len(obj.values(where.Path)) == 0) == wantNull, nil
+	case filters.Equal:
+		return obj.equals(where), nil
+	case filters.NotEqual:
+		return !obj.equals(where), nil
+	case filters.GreaterThan, filters.GreaterThanEqual, filters.LessThan, filters.LessThanEqual:
+		for _, value := range obj.values(where.Path) {
+			cmp, ok := compareMemoryValue(value, where)
+			if !ok {
+				continue
+			}
+			switch filters.WhereOperator(where.Operator) {
+			case filters.GreaterThan:
+				ok = cmp > 0
+			case filters.GreaterThanEqual:
+				ok = cmp >= 0
+			case filters.LessThan:
+				ok = cmp < 0
+			case filters.LessThanEqual:
+				ok = cmp <= 0
+			}
+			if ok {
+				return true, nil
+			}
+		}
+		return false, nil
+	case filters.Like:
+		if where.ValueText == nil {
+			return false, fmt.Errorf("like filter on %v needs a text value", where.Path)
+		}
+		tokenization := memoryEventTokenization[where.Path[0]]
+		pattern := *where.ValueText
+		if tokenization == "word" {
+			pattern = strings.ToLower(pattern)
+		}
+		like, err := regexp.Compile("^" + strings.NewReplacer(`\*`, ".*", `\?`, ".").Replace(regexp.QuoteMeta(pattern)) + "$")
+		if err != nil {
+			return false, err
+		}
+		for _, token := range obj.tokens(where.Path) {
+			if like.MatchString(token) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case filters.ContainsAny, filters.ContainsAll:
+		if where.ValueTextArray == nil && where.ValueText == nil {
+			// Contains on non text values matches any or all of the given values
+			wanted := memoryFilterValues(where)
+			found := 0
+			for _, want := range wanted {
+				for _, value := range obj.values(where.Path) {
+					if cmp, ok := compareMemoryValue(value, want); ok && cmp == 0 {
+						found++
+						break
+					}
+				}
+			}
+			if filters.WhereOperator(where.Operator) == filters.ContainsAny {
+				return found > 0, nil
+			}
+			return found == len(wanted), nil
+		}
+		tokens := map[string]bool{}
+		for _, token := range obj.tokens(where.Path) {
+			tokens[token] = true
+		}
+		values := where.ValueTextArray
+		if where.ValueText != nil {
+			values = []string{*where.ValueText}
+		}
+		tokenization := memoryEventTokenization[where.Path[0]]
+		for _, value := range values {
+			for _, token := range tokenizeMemoryText(tokenization, value) {
+				if tokens[token] && filters.WhereOperator(where.Operator) == filters.ContainsAny {
+					return true, nil
+				}
+				if !tokens[token] && filters.WhereOperator(where.Operator) == filters.ContainsAll {
+					return false, nil
+				}
+			}
+		}
+		return filters.WhereOperator(where.Operator) == filters.ContainsAll, nil
+	}
+	return false, fmt.Errorf("unsupported where operator %q in the in-memory event store", where.Operator)
+}
+
+// equals is the Equal filter. Text matches an element holding every token of
+// the filter value, so "field" properties must match exactly
+func (obj *memoryEventObject) equals(where *models.WhereFilter) bool {
+	if where.ValueText == nil {
+		for _, value := range obj.values(where.Path) {
+			if cmp, ok := compareMemoryValue(value, where); ok && cmp == 0 {
+				return true
+			}
+		}
+		return false
+	}
+
+	tokenization := memoryEventTokenization[where.Path[0]]
+	wanted := tokenizeMemoryText(tokenization, *where.ValueText)
+	if len(wanted) == 0 {
+		return false
+	}
+	for _, value := range obj.values(where.Path) {
+		text, ok := value.(string)
+		if !ok {
+			continue
+		}
+		tokens := map[string]bool{}
+		for _, token := range tokenizeMemoryText(tokenization, text) {
+			tokens[token] = true
+		}
+		all := true
+		for _, token := range wanted {
+			all = all && tokens[token]
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// tokens is every token of a text property, see `tokenizeMemoryText`
+func (obj *memoryEventObject) tokens(path []string) []string {
+	if len(path) != 1 {
+		return nil
+	}
+	tokenization := memoryEventTokenization[path[0]]
+	tokens := []string{}
+	for _, value := range obj.values(path) {
+		if text, ok := value.(string); ok {
+			tokens = append(tokens, tokenizeMemoryText(tokenization, text)...)
+		}
+	}
+	return tokens
+}
+
+// tokenizeMemoryText splits text like Weaviate does for the given property
+// tokenization. "field" keeps the trimmed value whole, "word" (the default)
+// lower cases it and splits on anything not a letter or digit. Ids compare
+// case insensitively
+func tokenizeMemoryText(tokenization, text string) []string {
+	switch tokenization {
+	case "id":
+		return []string{strings.ToLower(strings.TrimSpace(text))}
+	case "field":
+		if text = strings.TrimSpace(text); text == "" {
+			return nil
+		}
+		return []string{text}
+	default:
+		return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+	}
+}
+
+// memoryFilterValues splits a filter with an array value into one filter per
+// element, for Contains on ints, numbers and booleans
+func memoryFilterValues(where *models.WhereFilter) []*models.WhereFilter {
+	wanted := []*models.WhereFilter{}
+	for i := range where.ValueIntArray {
+		wanted = append(wanted, &models.WhereFilter{ValueInt: &where.ValueIntArray[i]})
+	}
+	for i := range where.ValueNumberArray {
+		wanted = append(wanted, &models.WhereFilter{ValueNumber: &where.ValueNumberArray[i]})
+	}
+	for i := range where.ValueBooleanArray {
+		wanted = append(wanted, &models.WhereFilter{ValueBoolean: &where.ValueBooleanArray[i]})
+	}
+	if where.ValueInt != nil || where.ValueNumber != nil || where.ValueBoolean != nil {
+		wanted = append(wanted, where)
+	}
+	return wanted
+}
+
+// compareMemoryValue compares a stored value to the filter's scalar value,
+// false when they aren't comparable
+func compareMemoryValue(value interface{}, where *models.WhereFilter) (int, bool) {
+	switch {
+	case where.ValueInt != nil:
+		v, ok := memoryInt(value)
+		if !ok {
+			return 0, false
+		}
+		return compareOrdered(v, *where.ValueInt), true
+	case where.ValueNumber != nil:
+		number, ok := value.(json.Number)
+		if !ok {
+			return 0, false
+		}
+		v, err := number.Float64()
+		if err != nil {
+			return 0, false
+		}
+		return compareOrdered(v, *where.ValueNumber), true
+	case where.ValueBoolean != nil:
+		v, ok := value.(bool)
+		if !ok || v != *where.ValueBoolean {
+			return 1, ok
+		}
+		return 0, true
+	case where.ValueText != nil:
+		v, ok := value.(string)
+		if !ok {
+			return 0, false
+		}
+		return strings.Compare(v, *where.ValueText), true
+	}
+	return 0, false
+}
+
+func memoryInt(value interface{}) (int64, bool) {
+	number, ok := value.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	if v, err := number.Int64(); err == nil {
+		return v, true
+	}
+	v, err := number.Float64()
+	return int64(v), err == nil
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,283 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+const (
+	memoryEventJazz    = "11111111-1111-4111-8111-111111111111"
+	memoryEventRock    = "22222222-2222-4222-8222-222222222222"
+	memoryEventUnpub   = "33333333-3333-4333-8333-333333333333"
+	memoryEventFar     = "44444444-4444-4444-8444-444444444444"
+	memoryEventOngoing = "55555555-5555-4555-8555-555555555555"
+)
+
+func seedMemoryEventStore(t *testing.T, now int64) *MemoryEventStore {
+	t.Helper()
+	store := NewMemoryEventStore()
+	events := []types.Event{
+		{
+			Id: memoryEventJazz, Name: "Late Night Jazz", Description: "Live jazz trio downtown",
+			EventOwners: []string{"owner-a"}, EventOwnerName: "Blue Room", EventSourceType: constants.ES_SINGLE_EVENT,
+			StartTime: now + 3600, Lat: 40.0, Long: -105.0, Timezone: *time.UTC,
+			Categories: []string{"Seminars"}, HasPurchasable: true, StartingPrice: 1500,
+		},
+		{
+			Id: memoryEventRock, Name: "Rock Night", Description: "Loud guitars, no jazz",
+			EventOwners: []string{"owner-b"}, ShadowOwners: []string{"sharer"}, EventSourceType: constants.ES_SINGLE_EVENT,
+			EventSourceId: "feed-1", StartTime: now + 7200, Lat: 40.01, Long: -105.01, Timezone: *time.UTC,
+		},
+		{
+			Id: memoryEventUnpub, Name: "Jazz Draft", EventOwners: []string{"owner-a"},
+			EventSourceType: constants.ES_SINGLE_EVENT_UNPUB, StartTime: now + 1800, Lat: 40.0, Long: -105.0, Timezone: *time.UTC,
+		},
+		{
+			Id: memoryEventFar, Name: "Jazz Abroad", EventOwners: []string{"owner-a"},
+			EventSourceType: constants.ES_SINGLE_EVENT, StartTime: now + 900, Lat: 51.5, Long: -0.1, Timezone: *time.UTC,
+		},
+		{
+			Id: memoryEventOngoing, Name: "All Day Fair", EventOwners: []string{"owner-b"},
+			EventSourceType: constants.ES_SINGLE_EVENT, StartTime: now - 7200, EndTime: now + 7200, Lat: 40.0, Long: -105.0, Timezone: *time.UTC,
+		},
+	}
+	if err := store.BulkUpsertEvent(context.Background(), events); err != nil {
+		t.Fatalf("failed to seed store: %v", err)
+	}
+	return store
+}
+
+func memoryEventIds(events []types.Event) []string {
+	ids := []string{}
+	for _, event := range events {
+		ids = append(ids, event.Id)
+	}
+	return ids
+}
+
+func TestMemoryEventStoreSearchFilters(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	store := seedMemoryEventStore(t, now)
+	boulder := []float64{40.0, -105.0}
+
+	tests := []struct {
+		name             string
+		query            string
+		location         []float64
+		ownerIds         []string
+		eventSourceTypes []string
+		eventSourceIds   []string
+		want             []string
+	}{
+		{name: "upcoming by start time", want: []string{memoryEventFar, memoryEventJazz, memoryEventRock}},
+		{name: "radius drops far events", location: boulder, want: []string{memoryEventJazz, memoryEventRock}},
+		{name: "text ranks name matches first", query: "jazz", location: boulder, want: []string{memoryEventJazz, memoryEventRock}},
+		{name: "owner matches shadow owners", ownerIds: []string{"sharer"}, want: []string{memoryEventRock}},
+		// "field" tokenization must not let SLF match SLF_UNPUB
+		{name: "source type is exact", eventSourceTypes: []string{constants.ES_SINGLE_EVENT_UNPUB}, want: []string{memoryEventUnpub}},
+		{name: "source id is exact", eventSourceIds: []string{"feed"}, want: []string{}},
+		{name: "source id", eventSourceIds: []string{"feed-1"}, want: []string{memoryEventRock}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := store.SearchEvents(ctx, tt.query, tt.location, 10, now, now+86400, tt.ownerIds, "", "", "", tt.eventSourceTypes, tt.eventSourceIds)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := strings.Join(memoryEventIds(res.Events), ","); got != strings.Join(tt.want, ",") {
+				t.Errorf("events = %s, want %s", got, strings.Join(tt.want, ","))
+			}
+		})
+	}
+}
+
+func TestMemoryEventStoreOverlap(t *testing.T) {
+	now := time.Now().Unix()
+	store := seedMemoryEventStore(t, now)
+
+	res, err := store.SearchEventsPage(context.Background(), "", nil, 0, now, now, nil, "", "", "", nil, nil, EventSearchPage{Overlap: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(memoryEventIds(res.Events), ","); got != memoryEventOngoing {
+		t.Errorf("happening now = %s, want %s", got, memoryEventOngoing)
+	}
+}
+
+func TestMemoryEventStorePaging(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	store := seedMemoryEventStore(t, now)
+
+	for _, query := range []string{"", "jazz"} {
+		seen := []string{}
+		page := EventSearchPage{Limit: 1}
+		for i := 0; i < 5; i++ {
+			res, err := store.SearchEventsPage(ctx, query, nil, 0, now, now+86400, nil, "", "", "", nil, nil, page)
+			if err != nil {
+				t.Fatalf("query %q page %d: unexpected error: %v", query, i, err)
+			}
+			if res.Total != 3 {
+				t.Errorf("query %q page %d: total = %d, want 3", query, i, res.Total)
+			}
+			seen = append(seen, memoryEventIds(res.Events)...)
+			if !res.HasMore {
+				break
+			}
+			page.Cursor = res.NextCursor
+		}
+		sort.Strings(seen)
+		if got := strings.Join(seen, ","); got != strings.Join([]string{memoryEventJazz, memoryEventRock, memoryEventFar}, ",") {
+			t.Errorf("query %q paged through %s", query, got)
+		}
+	}
+
+	_, err := store.SearchEventsPage(ctx, "rock", nil, 0, now, now+86400, nil, "", "", "", nil, nil, EventSearchPage{Cursor: encodeEventSearchCursor(eventSearchCursor{Order: eventSearchOrderScore})})
+	if !errors.Is(err, ErrInvalidSearchCursor) {
+		t.Errorf("cursor from another search error = %v, want ErrInvalidSearchCursor", err)
+	}
+}
+
+func TestMemoryEventStoreFacets(t *testing.T) {
+	now := time.Now().Unix()
+	store := seedMemoryEventStore(t, now)
+
+	res, err := store.SearchEventsPage(context.Background(), "", nil, 0, now, now+86400, nil, "", "", "", nil, nil, EventSearchPage{Facets: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Facets == nil {
+		t.Fatal("expected facets")
+	}
+	if res.Facets.Price.Paid != 1 || res.Facets.Price.Free != 2 {
+		t.Errorf("price = %+v, want 1 paid and 2 free", res.Facets.Price)
+	}
+	if res.Facets.Categories[0].Count != 1 {
+		t.Errorf("%s count = %d, want 1", res.Facets.Categories[0].Label, res.Facets.Categories[0].Count)
+	}
+	if len(res.Facets.Owners) != 2 || res.Facets.Owners[0].Key != "owner-a" || res.Facets.Owners[0].Count != 2 {
+		t.Errorf("owners = %+v, want owner-a with 2 first", res.Facets.Owners)
+	}
+	dated := 0
+	for _, bucket := range res.Facets.Dates {
+		dated += bucket.Count
+	}
+	if dated != 3 {
+		t.Errorf("date buckets count %d events, want 3", dated)
+	}
+}
+
+func TestMemoryEventStoreReadsAndDeletes(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now().Unix()
+	store := seedMemoryEventStore(t, now)
+
+	event, err := store.GetEventByID(ctx, strings.ToUpper(memoryEventJazz), "1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.Id != memoryEventJazz || event.StartingPrice != 1500 || event.CreatedAt == 0 || event.LocalizedStartDate == "" {
+		t.Errorf("unexpected event %+v", event)
+	}
+
+	event.Name = "Later Night Jazz"
+	if _, err := store.UpdateEvents(ctx, []types.Event{*event}); err != nil {
+		t.Fatalf("unexpected update error: %v", err)
+	}
+	if _, err := store.UpdateEvents(ctx, []types.Event{{Name: "no id"}}); err == nil {
+		t.Error("expected an update without an id to fail")
+	}
+
+	owners, err := store.GetReSharedOwners(ctx, "sharer")
+	if err != nil || strings.Join(owners, ",") != "owner-b" {
+		t.Errorf("re-shared owners = %v, %v", owners, err)
+	}
+
+	similar, err := store.SearchSimilarEvents(ctx, *event, 50, 5, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(similar) == 0 || similar[0].Id != memoryEventRock {
+		t.Errorf("similar = %v, want %s first", memoryEventIds(similar), memoryEventRock)
+	}
+
+	if err := store.BulkDeleteEvents(ctx, []string{memoryEventJazz, memoryEventRock}); err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+	events, err := store.BulkGetEventByID(ctx, []string{memoryEventJazz, memoryEventRock, memoryEventFar}, "")
+	if err != nil || len(events) != 1 || events[0].Name != "Jazz Abroad" {
+		t.Errorf("after delete got %d events, %v", len(events), err)
+	}
+	if _, err := store.GetEventByID(ctx, memoryEventJazz, ""); err == nil {
+		t.Error("expected a deleted event to be missing")
+	}
+}
+
+func TestGetEventStoreMemory(t *testing.T) {
+	t.Setenv("EVENT_STORE", EVENT_STORE_MEMORY)
+	ResetEventStore()
+	defer ResetEventStore()
+
+	first, err := GetEventStore()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second, _ := GetEventStore()
+	if first != second {
+		t.Error("expected the in-memory store to be shared")
+	}
+	if _, ok := first.(*MemoryEventStore); !ok {
+		t.Errorf("store = %T, want *MemoryEventStore", first)
+	}
+}
+
+func TestSearchAllEvents(t *testing.T) {
+	store := NewMemoryEventStore()
+	now := time.Now().Unix()
+	total := constants.MAX_EVENT_SEARCH_LIMIT*2 + 17
+	events := make([]types.Event, 0, total+1)
+	for i := 0; i < total; i++ {
+		events = append(events, types.Event{
+			Id: fmt.Sprintf("00000000-0000-4000-8000-%012d", i), Name: "Weekly Trivia", EventOwners: []string{"owner-a"},
+			EventSourceType: constants.ES_SINGLE_EVENT, EventSourceId: "feed-1",
+			// Several share a start time, the keyset has to page past them
+			StartTime: now + int64(i/3)*3600, Timezone: *time.UTC,
+		})
+	}
+	events = append(events, types.Event{
+		Id: memoryEventJazz, Name: "Late Night Jazz", EventOwners: []string{"owner-a"},
+		EventSourceType: constants.ES_SINGLE_EVENT, EventSourceId: "feed-2", StartTime: now + 3600, Timezone: *time.UTC,
+	})
+	if err := store.BulkUpsertEvent(context.Background(), events); err != nil {
+		t.Fatalf("failed to seed store: %v", err)
+	}
+
+	found, err := SearchAllEvents(context.Background(), store, []string{"owner-a"}, []string{constants.ES_SINGLE_EVENT}, []string{"feed-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	seen := map[string]bool{}
+	for _, event := range found {
+		seen[event.Id] = true
+	}
+	if len(found) != total || len(seen) != total || seen[memoryEventJazz] {
+		t.Errorf("expected all %d events of the feed once each, got %d (%d distinct)", total, len(found), len(seen))
+	}
+
+	windowed, err := SearchAllEventsInWindow(context.Background(), store, now, now+10*3600, []string{"owner-a"}, []string{constants.ES_SINGLE_EVENT}, []string{"feed-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(windowed) != 33 {
+		t.Errorf("expected the 33 events starting in the window, got %d", len(windowed))
+	}
+}
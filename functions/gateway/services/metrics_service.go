@@ -0,0 +1,169 @@
+package services
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+const metricsNamespace = "meetnearme"
+
+// Values of the `outcome` label
+const (
+	METRIC_OUTCOME_SUCCESS = "success"
+	METRIC_OUTCOME_FAILURE = "failure"
+	// METRIC_OUTCOME_RETRY is a Seshu job put back on the queue
+	METRIC_OUTCOME_RETRY = "retry"
+	// METRIC_OUTCOME_REJECTED is input refused before any work was done, a
+	// job that doesn't parse or a webhook with a bad signature
+	METRIC_OUTCOME_REJECTED = "rejected"
+)
+
+// Values of the `result` label of `meetnearme_seshu_run_events`
+const (
+	METRIC_EVENTS_SCRAPED   = "scraped"
+	METRIC_EVENTS_PRESERVED = "preserved"
+	METRIC_EVENTS_DELETED   = "deleted"
+	METRIC_EVENTS_INSERTED  = "inserted"
+)
+
+// metricScrapeSourceOther stands in for every job that isn't from a known
+// source, the field is free text and would make a label of any value
+const metricScrapeSourceOther = "OTHER"
+
+var (
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Time to serve a request, by route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	seshuJobsProcessed = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "seshu",
+		Name:      "jobs_processed_total",
+		Help:      "Seshu jobs taken off the queue, by known scrape source and outcome.",
+	}, []string{"source", "outcome"})
+
+	seshuRunEvents = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "seshu",
+		Name:      "run_events",
+		Help:      "Events of one Seshu job run that were scraped, preserved, deleted or inserted.",
+		Buckets:   []float64{0, 1, 5, 10, 25, 50, 100, 250, 500},
+	}, []string{"source", "result"})
+
+	llmTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "llm",
+		Name:      "tokens_total",
+		Help:      "Tokens billed by the completion API, by model and prompt or completion.",
+	}, []string{"model", "type"})
+
+	scrapingBeeRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "scrapingbee",
+		Name:      "request_duration_seconds",
+		Help:      "Time of a single ScrapingBee attempt, by outcome.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	}, []string{"outcome"})
+
+	scrapingBeeRetries = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "scrapingbee",
+		Name:      "retries_total",
+		Help:      "ScrapingBee attempts made after the first one of a fetch.",
+	})
+
+	weaviateRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "weaviate",
+		Name:      "request_duration_seconds",
+		Help:      "Time of an event store call to Weaviate, by operation and outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"operation", "outcome"})
+
+	stripeWebhooks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: "stripe",
+		Name:      "webhooks_total",
+		Help:      "Stripe webhooks received, by endpoint, event type and outcome.",
+	}, []string{"webhook", "event_type", "outcome"})
+)
+
+func outcomeOf(err error) string {
+	if err != nil {
+		return METRIC_OUTCOME_FAILURE
+	}
+	return METRIC_OUTCOME_SUCCESS
+}
+
+func metricScrapeSource(knownScrapeSource string) string {
+	switch knownScrapeSource {
+	case constants.SESHU_KNOWN_SOURCE_FB, constants.SESHU_KNOWN_SOURCE_ICS:
+		return knownScrapeSource
+	}
+	return metricScrapeSourceOther
+}
+
+func RecordSeshuJob(knownScrapeSource, outcome string) {
+	seshuJobsProcessed.WithLabelValues(metricScrapeSource(knownScrapeSource), outcome).Inc()
+}
+
+// RecordSeshuRunEvents takes the counts keyed by the `METRIC_EVENTS_*`
+// results
+func RecordSeshuRunEvents(knownScrapeSource string, counts map[string]int) {
+	source := metricScrapeSource(knownScrapeSource)
+	for result, count := range counts {
+		seshuRunEvents.WithLabelValues(source, result).Observe(float64(count))
+	}
+}
+
+func RecordLLMUsage(model string, usage Usage) {
+	llmTokens.WithLabelValues(model, "prompt").Add(float64(usage.PromptTokens))
+	llmTokens.WithLabelValues(model, "completion").Add(float64(usage.CompletionTokens))
+}
+
+// RecordScrapingBeeAttempt observes one attempt of a fetch, `attempt`
+// counts from 1 and `ok` is a 200 that could be read
+func RecordScrapingBeeAttempt(attempt int, start time.Time, ok bool) {
+	if attempt > 1 {
+		scrapingBeeRetries.Inc()
+	}
+	outcome := METRIC_OUTCOME_SUCCESS
+	if !ok {
+		outcome = METRIC_OUTCOME_FAILURE
+	}
+	scrapingBeeRequestDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+}
+
+func RecordWeaviateRequest(operation string, start time.Time, err error) {
+	weaviateRequestDuration.WithLabelValues(operation, outcomeOf(err)).Observe(time.Since(start).Seconds())
+}
+
+// RecordStripeWebhook counts a webhook of `webhook` ("checkout",
+// "subscription"). `eventType` is empty when the signature didn't verify
+func RecordStripeWebhook(webhook, eventType string, err error) {
+	outcome := outcomeOf(err)
+	if eventType == "" {
+		eventType, outcome = "unknown", METRIC_OUTCOME_REJECTED
+	}
+	stripeWebhooks.WithLabelValues(webhook, eventType, outcome).Inc()
+}
+
+// MetricsMiddleware observes the latency of every request the router
+// matched under its route template
+func MetricsMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		httpRequestDuration.WithLabelValues(r.Method, routeTemplate(r), strconv.Itoa(recorder.statusCode())).Observe(time.Since(start).Seconds())
+	})
+}
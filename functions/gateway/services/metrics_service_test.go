@@ -0,0 +1,128 @@
+package services
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecordSeshuJob(t *testing.T) {
+	facebook := testutil.ToFloat64(seshuJobsProcessed.WithLabelValues(constants.SESHU_KNOWN_SOURCE_FB, METRIC_OUTCOME_SUCCESS))
+	other := testutil.ToFloat64(seshuJobsProcessed.WithLabelValues(metricScrapeSourceOther, METRIC_OUTCOME_FAILURE))
+
+	RecordSeshuJob(constants.SESHU_KNOWN_SOURCE_FB, METRIC_OUTCOME_SUCCESS)
+	RecordSeshuJob("", METRIC_OUTCOME_FAILURE)
+	RecordSeshuJob("SOMETHING-NEW", METRIC_OUTCOME_FAILURE)
+
+	if got := testutil.ToFloat64(seshuJobsProcessed.WithLabelValues(constants.SESHU_KNOWN_SOURCE_FB, METRIC_OUTCOME_SUCCESS)); got != facebook+1 {
+		t.Errorf("expected 1 more FACEBOOK job, got %v", got-facebook)
+	}
+	if got := testutil.ToFloat64(seshuJobsProcessed.WithLabelValues(metricScrapeSourceOther, METRIC_OUTCOME_FAILURE)); got != other+2 {
+		t.Errorf("expected unknown sources to be counted as OTHER, got %v", got-other)
+	}
+}
+
+func TestRecordSeshuRunEvents(t *testing.T) {
+	labels := map[string]string{"source": constants.SESHU_KNOWN_SOURCE_ICS, "result": METRIC_EVENTS_INSERTED}
+	before := histogramSampleCount(t, "meetnearme_seshu_run_events", labels)
+	RecordSeshuRunEvents(constants.SESHU_KNOWN_SOURCE_ICS, map[string]int{
+		METRIC_EVENTS_SCRAPED:   10,
+		METRIC_EVENTS_PRESERVED: 4,
+		METRIC_EVENTS_DELETED:   1,
+		METRIC_EVENTS_INSERTED:  6,
+	})
+	if got := histogramSampleCount(t, "meetnearme_seshu_run_events", labels); got != before+1 {
+		t.Errorf("expected one more run with inserted events, got %d", got-before)
+	}
+}
+
+func TestRecordLLMUsage(t *testing.T) {
+	prompt := testutil.ToFloat64(llmTokens.WithLabelValues("test-model", "prompt"))
+	completion := testutil.ToFloat64(llmTokens.WithLabelValues("test-model", "completion"))
+	RecordLLMUsage("test-model", Usage{PromptTokens: 120, CompletionTokens: 30, TotalTokens: 150})
+	if got := testutil.ToFloat64(llmTokens.WithLabelValues("test-model", "prompt")); got != prompt+120 {
+		t.Errorf("expected 120 prompt tokens, got %v", got-prompt)
+	}
+	if got := testutil.ToFloat64(llmTokens.WithLabelValues("test-model", "completion")); got != completion+30 {
+		t.Errorf("expected 30 completion tokens, got %v", got-completion)
+	}
+}
+
+func TestRecordScrapingBeeAttempt(t *testing.T) {
+	retries := testutil.ToFloat64(scrapingBeeRetries)
+	RecordScrapingBeeAttempt(1, time.Now(), false)
+	RecordScrapingBeeAttempt(2, time.Now(), true)
+	if got := testutil.ToFloat64(scrapingBeeRetries); got != retries+1 {
+		t.Errorf("expected only the second attempt to count as a retry, got %v", got-retries)
+	}
+}
+
+func TestRecordStripeWebhook(t *testing.T) {
+	rejected := testutil.ToFloat64(stripeWebhooks.WithLabelValues("checkout", "unknown", METRIC_OUTCOME_REJECTED))
+	failed := testutil.ToFloat64(stripeWebhooks.WithLabelValues("checkout", "checkout.session.completed", METRIC_OUTCOME_FAILURE))
+
+	RecordStripeWebhook("checkout", "", errors.New("bad signature"))
+	RecordStripeWebhook("checkout", "checkout.session.completed", errors.New("dynamo is down"))
+
+	if got := testutil.ToFloat64(stripeWebhooks.WithLabelValues("checkout", "unknown", METRIC_OUTCOME_REJECTED)); got != rejected+1 {
+		t.Errorf("expected an unverified webhook to be rejected, got %v", got-rejected)
+	}
+	if got := testutil.ToFloat64(stripeWebhooks.WithLabelValues("checkout", "checkout.session.completed", METRIC_OUTCOME_FAILURE)); got != failed+1 {
+		t.Errorf("expected a failed webhook, got %v", got-failed)
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(MetricsMiddleware)
+	router.HandleFunc("/api/metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	for _, id := range []string{"1", "2"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/metrics-test/"+id, nil))
+	}
+
+	if got := histogramSampleCount(t, "meetnearme_http_request_duration_seconds", map[string]string{
+		"method": http.MethodGet,
+		"route":  "/api/metrics-test/{id}",
+		"status": "418",
+	}); got != 2 {
+		t.Errorf("expected both requests under the route template, got %d", got)
+	}
+}
+
+// histogramSampleCount is how many observations the series of histogram
+// `name` with exactly `labels` holds
+func histogramSampleCount(t *testing.T, name string, labels map[string]string) uint64 {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("failed to gather metrics: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	series:
+		for _, metric := range family.GetMetric() {
+			if len(metric.GetLabel()) != len(labels) {
+				continue
+			}
+			for _, label := range metric.GetLabel() {
+				if labels[label.GetName()] != label.GetValue() {
+					continue series
+				}
+			}
+			return metric.GetHistogram().GetSampleCount()
+		}
+	}
+	return 0
+}
@@ -62,6 +62,10 @@ func (m *MockNatsService) ConsumeMsg(ctx context.Context, workers int) error {
 	return nil
 }
 
+func (m *MockNatsService) PublishWebhookDelivery(ctx context.Context, deliveryId string) error {
+	return nil
+}
+
 func (m *MockNatsService) Close() error {
 	return nil
 }
@@ -3,15 +3,30 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/meetnearme/api/functions/gateway/constants"
 	internal_types "github.com/meetnearme/api/functions/gateway/types"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// SESHU_JOB_DRAIN_TIMEOUT is how long `ConsumeMsg` waits for running jobs
+	// once it stops pulling
+	SESHU_JOB_DRAIN_TIMEOUT = 15 * time.Second
+	// SESHU_JOB_CANCEL_GRACE is how long jobs still running at the drain
+	// timeout get to return once their context is cancelled
+	SESHU_JOB_CANCEL_GRACE = 4 * time.Second
 )
 
 var (
@@ -64,6 +79,22 @@ func NewNatsService(ctx context.Context, conn *nats.Conn) (*NatsService, error)
 		}
 	}
 
+	// Webhook deliveries get their own work queue, each message is one
+	// delivery id and is removed once acked
+	_, err = js.Stream(ctx, webhookStreamName)
+	if err != nil {
+		fmt.Printf("Stream %s does not exist, creating it...\n", webhookStreamName)
+
+		_, err = js.CreateStream(ctx, jetstream.StreamConfig{
+			Name:      webhookStreamName,
+			Subjects:  []string{webhookSubjectName},
+			Retention: jetstream.WorkQueuePolicy,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create webhook stream: %w", err)
+		}
+	}
+
 	return &NatsService{
 		conn: conn,
 		js:   js,
@@ -121,12 +152,31 @@ func (s *NatsService) PeekTopOfQueue(ctx context.Context) (*jetstream.RawStreamM
 
 func (s *NatsService) PublishMsg(ctx context.Context, job interface{}) error {
 
+	// A leader that lost its lease mid-gather stops publishing
+	if err := checkFencingToken(ctx); err != nil {
+		return err
+	}
+
 	data, err := json.Marshal(job)
 	if err != nil {
 		return fmt.Errorf("failed to marshal job: %w", err)
 	}
 
-	ack, err := s.js.Publish(ctx, subjectName, data)
+	ctx, span := Tracer().Start(ctx, "seshu.job publish",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(semconv.MessagingSystemKey.String("nats"), semconv.MessagingDestinationName(subjectName)),
+	)
+	if seshuJob, ok := job.(internal_types.SeshuJob); ok {
+		span.SetAttributes(TRACE_ATTR_JOB_URL.String(seshuJob.NormalizedUrlKey))
+	}
+	msg := nats.NewMsg(subjectName)
+	msg.Data = data
+	InjectTraceHeaders(ctx, msg.Header)
+	if token := fencingToken(ctx); token != 0 {
+		msg.Header.Set(FENCING_TOKEN_HEADER, strconv.FormatUint(token, 10))
+	}
+	ack, err := s.js.PublishMsg(ctx, msg)
+	EndSpan(span, err)
 	if err != nil {
 		return fmt.Errorf("failed to publish message: %w", err)
 	}
@@ -158,38 +208,77 @@ func (s *NatsService) ConsumeMsg(ctx context.Context, workers int) error {
 		log.Printf("Failed to get PostgresService: %v", err)
 	}
 
+	// Cancelling `ctx` only stops pulling, a job already running keeps its
+	// context until the drain timeout so it isn't cut off between deleting
+	// and re-inserting events unless it overstays
+	go func() {
+		<-ctx.Done()
+		iter.Stop()
+	}()
+	jobs := newInFlightMsgs(ctx)
+	jobCtx := jobs.ctx
 	sem := make(chan struct{}, workers)
 
 	for {
 		sem <- struct{}{}
-		go func() {
+		msg, err := iter.Next()
+		if err != nil {
+			<-sem
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				break
+			}
+			log.Printf("Error getting next message: %v", err)
+			continue
+		}
+
+		jobs.start(msg)
+		go func(msg jetstream.Msg) {
 			defer func() {
+				jobs.finish(msg)
 				<-sem
 			}()
-			msg, err := iter.Next()
-			if err != nil {
-				// Check if context is cancelled or iterator is exhausted
-				if err == jetstream.ErrMsgIteratorClosed || err == jetstream.ErrNoMessages {
-					log.Printf("Iterator closed or no messages: %v", err)
-					return
+			var err error
+
+			var seshuJob internal_types.SeshuJob
+			// jobOutcome overrides the outcome the job's status implies
+			var jobOutcome string
+			// The job's spans continue the trace of whoever published it
+			ctx, span := Tracer().Start(ExtractTraceHeaders(jobCtx, msg.Headers()), "seshu.job process",
+				trace.WithSpanKind(trace.SpanKindConsumer),
+				trace.WithAttributes(semconv.MessagingSystemKey.String("nats"), semconv.MessagingDestinationName(subjectName)),
+			)
+			defer func() {
+				if jobOutcome == "" {
+					jobOutcome = METRIC_OUTCOME_SUCCESS
+					if seshuJob.Status == "FAILING" {
+						jobOutcome = METRIC_OUTCOME_FAILURE
+					}
 				}
-				log.Printf("Error getting next message: %v", err)
-				return
-			}
+				RecordSeshuJob(seshuJob.KnownScrapeSource, jobOutcome)
+				if seshuJob.Status == "FAILING" {
+					span.SetStatus(codes.Error, "scrape failed")
+				}
+				span.End()
+			}()
 
-			// Check if msg is nil before using it
-			if msg == nil {
-				log.Printf("Received nil message from iterator")
+			if isFencedOffJob(ctx, msg) {
+				log.Printf("[WARN] Dropping Seshu job published by a deposed leader with token %s", msg.Headers().Get(FENCING_TOKEN_HEADER))
+				span.SetStatus(codes.Error, "fenced off")
+				jobOutcome = METRIC_OUTCOME_REJECTED
+				jobs.ack(msg)
 				return
 			}
 
 			// Unmarshal the SeshuJob from the message
-			var seshuJob internal_types.SeshuJob
 			if err := json.Unmarshal(msg.Data(), &seshuJob); err != nil {
 				log.Printf("Failed to unmarshal SeshuJob: %v", err)
-				msg.Ack() // Acknowledge to remove from queue even if processing failed
+				span.RecordError(err)
+				span.SetStatus(codes.Error, "invalid job")
+				jobOutcome = METRIC_OUTCOME_REJECTED
+				jobs.ack(msg) // Acknowledge to remove from queue even if processing failed
 				return
 			}
+			span.SetAttributes(TRACE_ATTR_JOB_URL.String(seshuJob.NormalizedUrlKey))
 
 			if seshuJob.Status != "SCANNING" {
 				seshuJob.Status = "SCANNING"
@@ -201,6 +290,28 @@ func (s *NatsService) ConsumeMsg(ctx context.Context, workers int) error {
 
 			log.Printf("Processing scraping job for URL: %s", seshuJob.NormalizedUrlKey)
 
+			// Structured iCal feeds skip HTML extraction entirely and are
+			// reconciled by VEVENT UID rather than name / address / time matching
+			if seshuJob.KnownScrapeSource == constants.SESHU_KNOWN_SOURCE_ICS {
+				_, err := ImportICalSeshuJob(ctx, seshuJob)
+				if err != nil {
+					log.Printf("Failed to import iCal feed %s: %v", seshuJob.NormalizedUrlKey, err)
+					seshuJob.LastScrapeFailureCount++
+					seshuJob.LastScrapeFailure = time.Now().Unix()
+					seshuJob.Status = "FAILING"
+				} else {
+					seshuJob.LastScrapeFailureCount = 0
+					seshuJob.Status = "HEALTHY"
+					seshuJob.LastScrapeSuccess = time.Now().Unix()
+				}
+				err = db.UpdateSeshuJob(ctx, seshuJob)
+				if err != nil {
+					log.Printf("Failed to update SeshuJob after iCal import: %v", err)
+				}
+				jobs.ack(msg)
+				return
+			}
+
 			var scrapeMode string
 			if seshuJob.IsRecursive {
 				scrapeMode = "rs"
@@ -208,7 +319,7 @@ func (s *NatsService) ConsumeMsg(ctx context.Context, workers int) error {
 				scrapeMode = "init"
 			}
 
-			events, _, err := ExtractEventsFromHTML(seshuJob, constants.SESHU_MODE_SCRAPE, scrapeMode, &RealScrapingService{})
+			events, _, err := ExtractEventsFromHTML(ctx, seshuJob, constants.SESHU_MODE_SCRAPE, scrapeMode, &RealScrapingService{})
 			if err != nil {
 				log.Printf("Failed to extract events from %s: %v", seshuJob.NormalizedUrlKey, err)
 				// Update job status to reflect failure in database
@@ -219,7 +330,7 @@ func (s *NatsService) ConsumeMsg(ctx context.Context, workers int) error {
 				if err != nil {
 					log.Printf("Failed to update SeshuJob after scrape failure: %v", err)
 				}
-				msg.Ack()
+				jobs.ack(msg)
 				return
 			}
 
@@ -230,18 +341,18 @@ func (s *NatsService) ConsumeMsg(ctx context.Context, workers int) error {
 				// Deduplicate newly scraped events before processing
 				events, _ = deduplicateEvents(events)
 
-				weaviateClient, err := GetWeaviateClient()
+				eventStore, err := GetEventStore()
 				if err != nil {
-					log.Printf("Failed to get Weaviate client for %s: %v", seshuJob.NormalizedUrlKey, err)
+					log.Printf("Failed to get event store for %s: %v", seshuJob.NormalizedUrlKey, err)
 					// Update job status to reflect failure in database
 					seshuJob.LastScrapeFailureCount++
 					seshuJob.LastScrapeFailure = time.Now().Unix()
 					seshuJob.Status = "FAILING"
 					err = db.UpdateSeshuJob(ctx, seshuJob)
 					if err != nil {
-						log.Printf("Failed to update SeshuJob after Weaviate client failure: %v", err)
+						log.Printf("Failed to update SeshuJob after event store failure: %v", err)
 					}
-					msg.Ack()
+					jobs.ack(msg)
 					return
 				} // Step 1: Gather all existing events in DB using EventSourceId
 				currentTime := time.Now().Unix()
@@ -251,9 +362,8 @@ func (s *NatsService) ConsumeMsg(ctx context.Context, workers int) error {
 				var duplicateIds []string
 				existingEvents := []constants.Event{}
 				if eventSourceId != "" {
-					searchResponse, err := SearchWeaviateEvents(
-						context.Background(),
-						weaviateClient,
+					searchResponse, err := eventStore.SearchEvents(
+						ctx,
 						"",                                  // no text query
 						nil,                                 // no location filter
 						0,                                   // no distance filter
@@ -278,7 +388,8 @@ func (s *NatsService) ConsumeMsg(ctx context.Context, workers int) error {
 							log.Printf("Failed to update SeshuJob after Weaviate search failure: %v", err)
 						}
 						// Continue with processing even if search fails
-						msg.Nak() // Requeue for retry
+						jobOutcome = METRIC_OUTCOME_RETRY
+						jobs.nak(msg) // Requeue for retry
 						return
 					} else {
 						existingEvents = searchResponse.Events
@@ -353,7 +464,7 @@ func (s *NatsService) ConsumeMsg(ctx context.Context, workers int) error {
 				if len(allIdsToDelete) > 0 {
 					log.Printf("Deleting %d total events from Weaviate (%d duplicates + %d obsolete)",
 						len(allIdsToDelete), duplicateCount, obsoleteCount)
-					_, err = BulkDeleteEventsFromWeaviate(context.Background(), weaviateClient, allIdsToDelete)
+					err = eventStore.BulkDeleteEvents(ctx, allIdsToDelete)
 					if err != nil {
 						log.Printf("Failed to delete events: %v", err)
 					} else {
@@ -376,7 +487,7 @@ func (s *NatsService) ConsumeMsg(ctx context.Context, workers int) error {
 					log.Printf("No new events to insert for %s (all events already exist)", seshuJob.NormalizedUrlKey)
 				} else {
 					log.Printf("Inserting %d new events for %s", len(eventsToInsert), seshuJob.NormalizedUrlKey)
-					err = PushExtractedEventsToDB(eventsToInsert, seshuJob, make(map[string]string))
+					err = PushExtractedEventsToDB(ctx, eventsToInsert, seshuJob, make(map[string]string))
 					if err != nil {
 						log.Println("Error pushing new events to DB:", err)
 						// Update job status to reflect failure in database
@@ -387,17 +498,31 @@ func (s *NatsService) ConsumeMsg(ctx context.Context, workers int) error {
 						if err != nil {
 							log.Printf("Failed to update SeshuJob after event insertion failure: %v", err)
 						}
-						msg.Ack()
+						jobs.ack(msg)
 						return
 					}
 				}
 
 				log.Printf("Successfully processed %d events for %s (%d preserved, %d deleted, %d inserted)",
 					len(events), seshuJob.NormalizedUrlKey, len(preservedEventIds), len(allIdsToDelete), len(eventsToInsert))
-				msg.Ack()
+				span.SetAttributes(
+					TRACE_ATTR_EVENTS_SCRAPED.Int(len(events)),
+					TRACE_ATTR_EVENTS_PRESERVED.Int(len(preservedEventIds)),
+					TRACE_ATTR_EVENTS_DELETED.Int(len(allIdsToDelete)),
+					TRACE_ATTR_EVENTS_INSERTED.Int(len(eventsToInsert)),
+				)
+				RecordSeshuRunEvents(seshuJob.KnownScrapeSource, map[string]int{
+					METRIC_EVENTS_SCRAPED:   len(events),
+					METRIC_EVENTS_PRESERVED: len(preservedEventIds),
+					METRIC_EVENTS_DELETED:   len(allIdsToDelete),
+					METRIC_EVENTS_INSERTED:  len(eventsToInsert),
+				})
+				jobs.ack(msg)
 			} else {
 				log.Printf("No events scraped from %s", seshuJob.NormalizedUrlKey)
-				msg.Ack()
+				span.SetAttributes(TRACE_ATTR_EVENTS_SCRAPED.Int(0))
+				RecordSeshuRunEvents(seshuJob.KnownScrapeSource, map[string]int{METRIC_EVENTS_SCRAPED: 0})
+				jobs.ack(msg)
 			}
 
 			// update job status accordingly
@@ -408,7 +533,187 @@ func (s *NatsService) ConsumeMsg(ctx context.Context, workers int) error {
 			if err != nil {
 				log.Printf("Failed to update SeshuJob after scrape failure: %v", err)
 			}
-		}()
+		}(msg)
+	}
+
+	log.Println("[INFO] Seshu consumer stopped pulling, waiting for running jobs.")
+	if unfinished := jobs.drain(SESHU_JOB_DRAIN_TIMEOUT); unfinished > 0 {
+		log.Printf("[WARN] Handed %d unfinished Seshu jobs back to the queue.", unfinished)
+	}
+	return nil
+}
+
+// inFlightMsgs tracks the messages workers are processing so shutdown can
+// wait for them. Jobs run on `ctx`, which outlives the consumer's context
+// until `drain` gives up on them
+type inFlightMsgs struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu sync.Mutex
+	wg sync.WaitGroup
+	// msgs maps each running job to whether it was already Ack'd or Nak'd
+	msgs map[jetstream.Msg]bool
+	naks int
+}
+
+func newInFlightMsgs(parent context.Context) *inFlightMsgs {
+	ctx, cancel := context.WithCancel(context.WithoutCancel(parent))
+	return &inFlightMsgs{ctx: ctx, cancel: cancel, msgs: map[jetstream.Msg]bool{}}
+}
+
+func (f *inFlightMsgs) start(msg jetstream.Msg) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.wg.Add(1)
+	f.msgs[msg] = false
+}
+
+// ack acknowledges a job, unless `drain` cancelled it: whatever it got done
+// may have been cut short, so it goes back to the queue instead
+func (f *inFlightMsgs) ack(msg jetstream.Msg) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ctx.Err() != nil {
+		f.nakLocked(msg)
+		return
+	}
+	if err := msg.Ack(); err != nil {
+		log.Printf("Failed to Ack Seshu job: %v", err)
+	}
+	f.msgs[msg] = true
+}
+
+func (f *inFlightMsgs) nak(msg jetstream.Msg) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nakLocked(msg)
+}
+
+func (f *inFlightMsgs) nakLocked(msg jetstream.Msg) {
+	if settled, ok := f.msgs[msg]; ok && settled {
+		return
+	}
+	if err := msg.Nak(); err != nil {
+		log.Printf("Failed to Nak Seshu job: %v", err)
+	}
+	f.msgs[msg] = true
+	if f.ctx.Err() != nil {
+		f.naks++
+	}
+}
+
+// finish is called once the job's handler returned, a cancelled job that
+// didn't settle its message is Nak'd here
+func (f *inFlightMsgs) finish(msg jetstream.Msg) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ctx.Err() != nil {
+		f.nakLocked(msg)
+	}
+	delete(f.msgs, msg)
+	f.wg.Done()
+}
+
+// drain waits up to `timeout` for the running jobs, then cancels the ones
+// still running and waits up to `SESHU_JOB_CANCEL_GRACE` for them to return.
+// Each is Nak'd once its handler has returned, so another instance redelivers
+// it instead of waiting out the ack wait, without two instances running it
+// at once. It returns how many were Nak'd, a job ignoring the cancellation
+// is left to the ack wait
+func (f *inFlightMsgs) drain(timeout time.Duration) int {
+	done := make(chan struct{})
+	go func() {
+		f.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		f.cancel()
+		return 0
+	case <-time.After(timeout):
+	}
+
+	f.cancel()
+	select {
+	case <-done:
+	case <-time.After(SESHU_JOB_CANCEL_GRACE):
+		f.mu.Lock()
+		log.Printf("[WARN] %d Seshu jobs ignored their cancellation, they're redelivered after the ack wait", len(f.msgs))
+		f.mu.Unlock()
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.naks
+}
+
+func (s *NatsService) PublishWebhookDelivery(ctx context.Context, deliveryId string) error {
+	if _, err := s.js.Publish(ctx, webhookSubjectName, []byte(deliveryId)); err != nil {
+		return fmt.Errorf("failed to publish webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// ConsumeWebhookDeliveries makes the webhook delivery attempts queued on
+// JetStream until `ctx` is done. A failed attempt is handed back to
+// JetStream with the delay before the next one, so retries survive restarts
+func (s *NatsService) ConsumeWebhookDeliveries(ctx context.Context, workers int) error {
+	cons, err := s.js.CreateOrUpdateConsumer(ctx, webhookStreamName, jetstream.ConsumerConfig{
+		Durable:       webhookDurableName,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		FilterSubject: webhookSubjectName,
+		AckWait:       webhookAckWait,
+		MaxDeliver:    webhookMaxDeliver,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create or update webhook consumer: %w", err)
+	}
+
+	iter, err := cons.Messages(jetstream.PullMaxMessages(workers))
+	if err != nil {
+		return fmt.Errorf("failed to get webhook iterator: %w", err)
+	}
+	go func() {
+		<-ctx.Done()
+		iter.Stop()
+	}()
+
+	db, err := GetPostgresService(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get postgres service: %w", err)
+	}
+
+	sem := make(chan struct{}, workers)
+	for {
+		msg, err := iter.Next()
+		if err != nil {
+			if errors.Is(err, jetstream.ErrMsgIteratorClosed) {
+				log.Println("[INFO] Webhook delivery consumer stopped.")
+				return nil
+			}
+			log.Printf("Error getting next webhook delivery: %v", err)
+			continue
+		}
+
+		sem <- struct{}{}
+		go func(msg jetstream.Msg) {
+			defer func() {
+				<-sem
+			}()
+			deliveryId := string(msg.Data())
+			retryIn, err := DeliverWebhook(ctx, db, webhookHTTPClient, deliveryId, time.Now())
+			if err != nil {
+				log.Printf("Failed to deliver webhook %s: %v", deliveryId, err)
+				msg.NakWithDelay(webhookStoreRetryDelay)
+				return
+			}
+			if retryIn > 0 {
+				msg.NakWithDelay(retryIn)
+				return
+			}
+			msg.Ack()
+		}(msg)
 	}
 }
 
@@ -8,6 +8,7 @@ import (
 	"github.com/meetnearme/api/functions/gateway/constants"
 	"github.com/meetnearme/api/functions/gateway/test_helpers"
 	internal_types "github.com/meetnearme/api/functions/gateway/types"
+	"github.com/nats-io/nats.go/jetstream"
 )
 
 func TestPublishMsg(t *testing.T) {
@@ -119,6 +120,73 @@ func TestConsumeMsgEmptyQueue(t *testing.T) {
 	}
 }
 
+type fakeJobMsg struct {
+	jetstream.Msg
+	acks, naks int
+}
+
+func (m *fakeJobMsg) Ack() error {
+	m.acks++
+	return nil
+}
+
+func (m *fakeJobMsg) Nak() error {
+	m.naks++
+	return nil
+}
+
+func TestInFlightMsgsDrain(t *testing.T) {
+	ctx, stopPulling := context.WithCancel(context.Background())
+	jobs := newInFlightMsgs(ctx)
+	done, stuck := &fakeJobMsg{}, &fakeJobMsg{}
+	jobs.start(done)
+	jobs.start(stuck)
+	stopPulling()
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		if jobs.ctx.Err() != nil {
+			t.Error("expected jobs to keep their context when the consumer stops pulling")
+		}
+		jobs.ack(done)
+		jobs.finish(done)
+	}()
+	stuckNaksWhileRunning := make(chan int, 1)
+	go func() {
+		// The job notices the cancellation and fails the way a cut off
+		// scrape does, its Ack has to become a Nak
+		<-jobs.ctx.Done()
+		time.Sleep(10 * time.Millisecond)
+		stuckNaksWhileRunning <- stuck.naks
+		jobs.ack(stuck)
+		jobs.finish(stuck)
+	}()
+
+	if unfinished := jobs.drain(100 * time.Millisecond); unfinished != 1 {
+		t.Fatalf("expected 1 unfinished job, got %d", unfinished)
+	}
+	if naks := <-stuckNaksWhileRunning; naks != 0 {
+		t.Errorf("expected the stuck job not to be Nak'd while it was still running, got %d", naks)
+	}
+	if done.acks != 1 || done.naks != 0 || stuck.acks != 0 || stuck.naks != 1 {
+		t.Errorf("expected only the stuck job to be Nak'd, got %+v and %+v", done, stuck)
+	}
+}
+
+func TestInFlightMsgsDrainNaksCancelledJobOnReturn(t *testing.T) {
+	jobs := newInFlightMsgs(context.Background())
+	stuck := &fakeJobMsg{}
+	jobs.start(stuck)
+	go func() {
+		<-jobs.ctx.Done()
+		jobs.finish(stuck)
+	}()
+
+	if unfinished := jobs.drain(10 * time.Millisecond); unfinished != 1 || stuck.naks != 1 {
+		t.Errorf("expected a cancelled job that never settled to be Nak'd once, got %d and %d", unfinished, stuck.naks)
+	}
+}
+
 // ========================================
 // Event Comparison Tests
 // ========================================
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedDestination is returned when a user supplied URL points at an
+// address inside our network
+var ErrBlockedDestination = errors.New("destination address is not allowed")
+
+const publicDialTimeout = 10 * time.Second
+
+// localDestinationsAllowed lets a local server reach receivers and feeds
+// running on the same machine
+func localDestinationsAllowed() bool {
+	return os.Getenv("IS_LOCAL_ACT") == "true"
+}
+
+// isBlockedIP is true for private, loopback, link-local, multicast and
+// unspecified addresses, cloud metadata endpoints like 169.254.169.254 among
+// them. Loopback is allowed in local development
+func isBlockedIP(ip net.IP) bool {
+	if ip.IsLoopback() && localDestinationsAllowed() {
+		return false
+	}
+	return ip.IsPrivate() ||
+		ip.IsLoopback() ||
+		ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() ||
+		ip.IsUnspecified()
+}
+
+// publicDialControl runs after DNS resolution on the address about to be
+// dialed, so a hostname resolving to an internal address is refused too,
+// however often it's resolved again
+func publicDialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || isBlockedIP(ip) {
+		return fmt.Errorf("%w: %s", ErrBlockedDestination, host)
+	}
+	return nil
+}
+
+// NewPublicHTTPClient is the client for URLs users give us, it only
+// connects to public addresses and never through a proxy from the
+// environment, which could reach what the dialer refuses
+func NewPublicHTTPClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   publicDialTimeout,
+		KeepAlive: 30 * time.Second,
+		Control:   publicDialControl,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsBlockedIP(t *testing.T) {
+	t.Setenv("IS_LOCAL_ACT", "false")
+	for _, addr := range []string{"127.0.0.1", "::1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "fe80::1", "fd00::1", "0.0.0.0", "::", "::ffff:127.0.0.1"} {
+		if !isBlockedIP(net.ParseIP(addr)) {
+			t.Errorf("expected %s to be blocked", addr)
+		}
+	}
+	for _, addr := range []string{"93.184.216.34", "2606:2800:220:1::1"} {
+		if isBlockedIP(net.ParseIP(addr)) {
+			t.Errorf("expected %s to be allowed", addr)
+		}
+	}
+
+	t.Setenv("IS_LOCAL_ACT", "true")
+	if isBlockedIP(net.ParseIP("127.0.0.1")) {
+		t.Error("expected loopback to be allowed in local development")
+	}
+	if !isBlockedIP(net.ParseIP("169.254.169.254")) {
+		t.Error("expected link-local to stay blocked in local development")
+	}
+}
+
+func TestNewPublicHTTPClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	// A hostname is checked once it's resolved, not by its spelling
+	url := "http://localhost:" + server.URL[len("http://127.0.0.1:"):]
+
+	t.Setenv("IS_LOCAL_ACT", "false")
+	client := NewPublicHTTPClient(time.Second)
+	if _, err := client.Get(url); !errors.Is(err, ErrBlockedDestination) {
+		t.Fatalf("expected the loopback address to be refused, got %v", err)
+	}
+
+	t.Setenv("IS_LOCAL_ACT", "true")
+	res, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("expected loopback in local development, got %v", err)
+	}
+	res.Body.Close()
+}
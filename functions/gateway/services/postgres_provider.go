@@ -60,6 +60,110 @@ func (m *MockPostgresService) ScanSeshuJobsWithInHour(ctx context.Context, hours
 	return []types.SeshuJob{}, nil
 }
 
+func (m *MockPostgresService) GetAccountDeletion(ctx context.Context, userId string) (*types.AccountDeletion, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) SaveAccountDeletion(ctx context.Context, deletion types.AccountDeletion) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetDueAccountDeletions(ctx context.Context, now int64, statuses []string) ([]types.AccountDeletion, error) {
+	return []types.AccountDeletion{}, nil
+}
+
+func (m *MockPostgresService) SaveQuarantinedEvents(ctx context.Context, events []types.QuarantinedEvent) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetQuarantinedEvents(ctx context.Context, ownerId string) ([]types.QuarantinedEvent, error) {
+	return []types.QuarantinedEvent{}, nil
+}
+
+func (m *MockPostgresService) GetQuarantinedEvent(ctx context.Context, id string) (*types.QuarantinedEvent, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) DeleteQuarantinedEvent(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *MockPostgresService) SaveWebhookSubscription(ctx context.Context, subscription types.WebhookSubscription) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetWebhookSubscriptions(ctx context.Context, ownerId string) ([]types.WebhookSubscription, error) {
+	return []types.WebhookSubscription{}, nil
+}
+
+func (m *MockPostgresService) GetWebhookSubscription(ctx context.Context, id string) (*types.WebhookSubscription, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	return nil
+}
+
+func (m *MockPostgresService) SaveWebhookDeliveries(ctx context.Context, deliveries []types.WebhookDelivery) error {
+	return nil
+}
+
+func (m *MockPostgresService) UpdateWebhookDelivery(ctx context.Context, delivery types.WebhookDelivery) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetWebhookDelivery(ctx context.Context, id string) (*types.WebhookDelivery, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetWebhookDeliveries(ctx context.Context, subscriptionId string, limit int) ([]types.WebhookDelivery, error) {
+	return []types.WebhookDelivery{}, nil
+}
+
+func (m *MockPostgresService) SaveAPIKey(ctx context.Context, key types.APIKey) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetAPIKeys(ctx context.Context, ownerId string) ([]types.APIKey, error) {
+	return []types.APIKey{}, nil
+}
+
+func (m *MockPostgresService) GetAPIKey(ctx context.Context, id string) (*types.APIKey, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetAPIKeyByHash(ctx context.Context, keyHash string) (*types.APIKey, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) TouchAPIKey(ctx context.Context, id string, usedAt int64) error {
+	return nil
+}
+
+func (m *MockPostgresService) DeleteAPIKeys(ctx context.Context, ownerId string) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockPostgresService) SaveDataExport(ctx context.Context, export types.DataExport) error {
+	return nil
+}
+
+func (m *MockPostgresService) GetDataExport(ctx context.Context, id string) (*types.DataExport, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetPendingDataExport(ctx context.Context, userId string) (*types.DataExport, error) {
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetDueDataExports(ctx context.Context, startedBefore int64) ([]types.DataExport, error) {
+	return []types.DataExport{}, nil
+}
+
+func (m *MockPostgresService) DeleteExpiredDataExports(ctx context.Context, now int64) (int64, error) {
+	return 0, nil
+}
+
 func (m *MockPostgresService) Close() error {
 	return nil
 }
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -10,6 +11,7 @@ import (
 	internal_types "github.com/meetnearme/api/functions/gateway/types"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type PostgresService struct {
@@ -130,6 +132,275 @@ func (s *PostgresService) ScanSeshuJobsWithInHour(ctx context.Context, currentHo
 	return jobs, nil
 }
 
+// GetAccountDeletion returns nil when the user has never requested deletion
+func (s *PostgresService) GetAccountDeletion(ctx context.Context, userId string) (*internal_types.AccountDeletion, error) {
+	var deletion internal_types.AccountDeletion
+	err := s.DB.WithContext(ctx).Where("user_id = ?", userId).First(&deletion).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &deletion, nil
+}
+
+func (s *PostgresService) SaveAccountDeletion(ctx context.Context, deletion internal_types.AccountDeletion) error {
+	return s.DB.WithContext(ctx).Save(&deletion).Error
+}
+
+func (s *PostgresService) GetDueAccountDeletions(ctx context.Context, now int64, statuses []string) ([]internal_types.AccountDeletion, error) {
+	var deletions []internal_types.AccountDeletion
+	if err := s.DB.WithContext(ctx).
+		Where("status IN ? AND scheduled_for <= ?", statuses, now).
+		Order("scheduled_for ASC").
+		Find(&deletions).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return deletions, nil
+}
+
+func (s *PostgresService) SaveQuarantinedEvents(ctx context.Context, events []internal_types.QuarantinedEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	// an event quarantined again on a later run replaces its earlier copy
+	return s.DB.WithContext(ctx).Clauses(clause.OnConflict{UpdateAll: true}).Create(&events).Error
+}
+
+// GetQuarantinedEvents returns every quarantined event when `ownerId` is empty
+func (s *PostgresService) GetQuarantinedEvents(ctx context.Context, ownerId string) ([]internal_types.QuarantinedEvent, error) {
+	var events []internal_types.QuarantinedEvent
+	query := s.DB.WithContext(ctx)
+	if ownerId != "" {
+		query = query.Where("owner_id = ?", ownerId)
+	}
+	if err := query.Order("created_at DESC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// GetQuarantinedEvent returns nil when there's no such event
+func (s *PostgresService) GetQuarantinedEvent(ctx context.Context, id string) (*internal_types.QuarantinedEvent, error) {
+	var event internal_types.QuarantinedEvent
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&event).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+func (s *PostgresService) DeleteQuarantinedEvent(ctx context.Context, id string) error {
+	return s.DB.WithContext(ctx).Where("id = ?", id).Delete(&internal_types.QuarantinedEvent{}).Error
+}
+
+func (s *PostgresService) SaveWebhookSubscription(ctx context.Context, subscription internal_types.WebhookSubscription) error {
+	return s.DB.WithContext(ctx).Save(&subscription).Error
+}
+
+// GetWebhookSubscriptions returns every subscription when `ownerId` is empty
+func (s *PostgresService) GetWebhookSubscriptions(ctx context.Context, ownerId string) ([]internal_types.WebhookSubscription, error) {
+	var subscriptions []internal_types.WebhookSubscription
+	query := s.DB.WithContext(ctx)
+	if ownerId != "" {
+		query = query.Where("owner_id = ?", ownerId)
+	}
+	if err := query.Order("created_at DESC").Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// GetWebhookSubscription returns nil when there's no such subscription
+func (s *PostgresService) GetWebhookSubscription(ctx context.Context, id string) (*internal_types.WebhookSubscription, error) {
+	var subscription internal_types.WebhookSubscription
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&subscription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// DeleteWebhookSubscription deletes the subscription with its delivery log
+func (s *PostgresService) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	return s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("subscription_id = ?", id).Delete(&internal_types.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&internal_types.WebhookSubscription{}).Error
+	})
+}
+
+func (s *PostgresService) SaveWebhookDeliveries(ctx context.Context, deliveries []internal_types.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return s.DB.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (s *PostgresService) UpdateWebhookDelivery(ctx context.Context, delivery internal_types.WebhookDelivery) error {
+	return s.DB.WithContext(ctx).Save(&delivery).Error
+}
+
+// GetWebhookDelivery returns nil when there's no such delivery
+func (s *PostgresService) GetWebhookDelivery(ctx context.Context, id string) (*internal_types.WebhookDelivery, error) {
+	var delivery internal_types.WebhookDelivery
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&delivery).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// GetWebhookDeliveries returns a subscription's latest deliveries first
+func (s *PostgresService) GetWebhookDeliveries(ctx context.Context, subscriptionId string, limit int) ([]internal_types.WebhookDelivery, error) {
+	var deliveries []internal_types.WebhookDelivery
+	err := s.DB.WithContext(ctx).
+		Where("subscription_id = ?", subscriptionId).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (s *PostgresService) SaveAPIKey(ctx context.Context, key internal_types.APIKey) error {
+	return s.DB.WithContext(ctx).Save(&key).Error
+}
+
+// GetAPIKeys returns an owner's keys, revoked ones included, newest first
+func (s *PostgresService) GetAPIKeys(ctx context.Context, ownerId string) ([]internal_types.APIKey, error) {
+	var keys []internal_types.APIKey
+	if err := s.DB.WithContext(ctx).
+		Where("owner_id = ?", ownerId).
+		Order("created_at DESC").
+		Find(&keys).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetAPIKey returns nil when there's no such key
+func (s *PostgresService) GetAPIKey(ctx context.Context, id string) (*internal_types.APIKey, error) {
+	var key internal_types.APIKey
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// GetAPIKeyByHash returns nil when no key hashes to `keyHash`
+func (s *PostgresService) GetAPIKeyByHash(ctx context.Context, keyHash string) (*internal_types.APIKey, error) {
+	var key internal_types.APIKey
+	err := s.DB.WithContext(ctx).Where("key_hash = ?", keyHash).First(&key).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (s *PostgresService) TouchAPIKey(ctx context.Context, id string, usedAt int64) error {
+	return s.DB.WithContext(ctx).
+		Model(&internal_types.APIKey{}).
+		Where("id = ?", id).
+		Update("last_used_at", usedAt).
+		Error
+}
+
+// DeleteAPIKeys deletes every key `ownerId` has, revoked ones included, and
+// returns how many there were
+func (s *PostgresService) DeleteAPIKeys(ctx context.Context, ownerId string) (int64, error) {
+	res := s.DB.WithContext(ctx).Where("owner_id = ?", ownerId).Delete(&internal_types.APIKey{})
+	return res.RowsAffected, res.Error
+}
+
+func (s *PostgresService) SaveDataExport(ctx context.Context, export internal_types.DataExport) error {
+	return s.DB.WithContext(ctx).Save(&export).Error
+}
+
+// GetDataExport returns nil when there's no such export
+func (s *PostgresService) GetDataExport(ctx context.Context, id string) (*internal_types.DataExport, error) {
+	var export internal_types.DataExport
+	err := s.DB.WithContext(ctx).Where("id = ?", id).First(&export).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetPendingDataExport returns the user's export that's still being built,
+// nil when there's none
+func (s *PostgresService) GetPendingDataExport(ctx context.Context, userId string) (*internal_types.DataExport, error) {
+	var export internal_types.DataExport
+	err := s.DB.WithContext(ctx).
+		Omit("data").
+		Where("user_id = ? AND status = ?", userId, DATA_EXPORT_STATUS_PENDING).
+		Order("created_at ASC").
+		First(&export).
+		Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// GetDueDataExports returns pending exports nobody started, or whose last
+// attempt started before `startedBefore`, oldest first and without their data
+func (s *PostgresService) GetDueDataExports(ctx context.Context, startedBefore int64) ([]internal_types.DataExport, error) {
+	var exports []internal_types.DataExport
+	if err := s.DB.WithContext(ctx).
+		Omit("data").
+		Where("status = ? AND started_at <= ?", DATA_EXPORT_STATUS_PENDING, startedBefore).
+		Order("created_at ASC").
+		Find(&exports).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+// DeleteExpiredDataExports deletes finished exports past their expiry and
+// returns how many there were
+func (s *PostgresService) DeleteExpiredDataExports(ctx context.Context, now int64) (int64, error) {
+	res := s.DB.WithContext(ctx).
+		Where("expires_at > 0 AND expires_at <= ?", now).
+		Delete(&internal_types.DataExport{})
+	return res.RowsAffected, res.Error
+}
+
 func (s *PostgresService) Close() error {
 	if s.DB != nil {
 		sqlDB, err := s.DB.DB()
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+const (
+	rateLimitBucketName = "rate-limits"
+	// rateLimitStateTTL drops buckets nobody used for this long, a limit's
+	// `Per` can't be longer or an idle bucket would come back full too early
+	rateLimitStateTTL = time.Hour
+	// rateLimitUpdateAttempts is how often a bucket is retried when another
+	// instance updated it at the same time
+	rateLimitUpdateAttempts = 5
+	// rateLimitMemorySweepSize is how many buckets the in-memory store holds
+	// before dropping the ones that are full again
+	rateLimitMemorySweepSize = 10000
+)
+
+// RateLimit is a token bucket holding `Requests` tokens that refills
+// completely over `Per`. The zero value doesn't limit anything
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Requests > 0 && l.Per > 0
+}
+
+// RateLimitPolicy is the limits of one or more routes sharing buckets under
+// `Name`. A request is counted against the most specific limit set: its API
+// key, then its logged in user, then its IP
+type RateLimitPolicy struct {
+	Name      string
+	PerIP     RateLimit
+	PerUser   RateLimit
+	PerAPIKey RateLimit
+}
+
+type RateLimitResult struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	Window    time.Duration
+	// Reset is how long until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is how long until the next token when not allowed
+	RetryAfter time.Duration
+}
+
+// RateLimitStore keeps the buckets, `Take` removes a token from the bucket
+// at `key` if there is one
+type RateLimitStore interface {
+	Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error)
+}
+
+type rateLimitBucket struct {
+	Tokens  float64 `json:"tokens"`
+	Updated int64   `json:"updated"`
+}
+
+func (b rateLimitBucket) take(limit RateLimit, now time.Time) (rateLimitBucket, RateLimitResult) {
+	capacity := float64(limit.Requests)
+	perToken := float64(limit.Per) / capacity
+
+	tokens := capacity
+	if b.Updated != 0 {
+		elapsed := float64(now.UnixNano() - b.Updated)
+		tokens = math.Min(capacity, b.Tokens+math.Max(elapsed, 0)/perToken)
+	}
+
+	result := RateLimitResult{Limit: limit.Requests, Window: limit.Per}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) * perToken)
+	}
+	result.Remaining = int(math.Floor(tokens))
+	result.Reset = time.Duration((capacity - tokens) * perToken)
+	return rateLimitBucket{Tokens: tokens, Updated: now.UnixNano()}, result
+}
+
+type memoryRateLimitEntry struct {
+	bucket rateLimitBucket
+	full   time.Time
+}
+
+// MemoryRateLimitStore keeps buckets in this instance only, it's used when
+// NATS isn't available
+type MemoryRateLimitStore struct {
+	mu      sync.Mutex
+	buckets map[string]memoryRateLimitEntry
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: map[string]memoryRateLimitEntry{}}
+}
+
+func (s *MemoryRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.buckets) >= rateLimitMemorySweepSize {
+		for bucketKey, entry := range s.buckets {
+			if !now.Before(entry.full) {
+				delete(s.buckets, bucketKey)
+			}
+		}
+	}
+	bucket, result := s.buckets[key].bucket.take(limit, now)
+	s.buckets[key] = memoryRateLimitEntry{bucket: bucket, full: now.Add(result.Reset)}
+	return result, nil
+}
+
+// rateLimitKV is the part of `jetstream.KeyValue` the NATS store uses
+type rateLimitKV interface {
+	Get(ctx context.Context, key string) (jetstream.KeyValueEntry, error)
+	Create(ctx context.Context, key string, value []byte, opts ...jetstream.KVCreateOpt) (uint64, error)
+	Update(ctx context.Context, key string, value []byte, revision uint64) (uint64, error)
+}
+
+// NatsRateLimitStore keeps buckets in a NATS KV bucket so every instance
+// shares them. Buckets are updated with compare-and-set on their revision
+type NatsRateLimitStore struct {
+	kv rateLimitKV
+}
+
+// NewRateLimitStore creates or updates the KV bucket holding rate limits
+func (s *NatsService) NewRateLimitStore(ctx context.Context) (*NatsRateLimitStore, error) {
+	kv, err := s.js.CreateOrUpdateKeyValue(ctx, jetstream.KeyValueConfig{
+		Bucket:  rateLimitBucketName,
+		History: 1,
+		TTL:     rateLimitStateTTL,
+		Storage: jetstream.MemoryStorage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rate limit bucket: %w", err)
+	}
+	return &NatsRateLimitStore{kv: kv}, nil
+}
+
+func (s *NatsRateLimitStore) Take(ctx context.Context, key string, limit RateLimit, now time.Time) (RateLimitResult, error) {
+	var lastErr error
+	for attempt := 0; attempt < rateLimitUpdateAttempts; attempt++ {
+		var bucket rateLimitBucket
+		var revision uint64
+		entry, err := s.kv.Get(ctx, key)
+		switch {
+		case errors.Is(err, jetstream.ErrKeyNotFound):
+		case err != nil:
+			return RateLimitResult{}, fmt.Errorf("failed to get rate limit bucket: %w", err)
+		default:
+			revision = entry.Revision()
+			if err := json.Unmarshal(entry.Value(), &bucket); err != nil {
+				log.Printf("ERR: resetting unreadable rate limit bucket %s: %v", key, err)
+				bucket = rateLimitBucket{}
+			}
+		}
+
+		bucket, result := bucket.take(limit, now)
+		data, err := json.Marshal(bucket)
+		if err != nil {
+			return RateLimitResult{}, err
+		}
+		if revision == 0 {
+			_, err = s.kv.Create(ctx, key, data)
+		} else {
+			_, err = s.kv.Update(ctx, key, data, revision)
+		}
+		if err == nil {
+			return result, nil
+		}
+		lastErr = err
+	}
+	return RateLimitResult{}, fmt.Errorf("failed to update rate limit bucket after %d attempts: %w", rateLimitUpdateAttempts, lastErr)
+}
+
+var rateLimitStore RateLimitStore = NewMemoryRateLimitStore()
+
+// SetRateLimitStore replaces the in-memory store, call it before serving
+func SetRateLimitStore(store RateLimitStore) {
+	rateLimitStore = store
+}
+
+// ClientIP is the address a request came from. Lambda gets it from API
+// Gateway. On ACT `X-Forwarded-For` is only believed when the peer is one of
+// `TRUSTED_PROXIES`, and then the client is the last hop not added by one of
+// them, anything before it is up to the client
+func ClientIP(r *http.Request) string {
+	if apiGwReq, ok := r.Context().Value(constants.ApiGwV2ReqKey).(events.APIGatewayV2HTTPRequest); ok && apiGwReq.RequestContext.HTTP.SourceIP != "" {
+		return apiGwReq.RequestContext.HTTP.SourceIP
+	}
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	proxies := trustedProxies()
+	if !isTrustedProxy(proxies, peer) {
+		return peer
+	}
+	hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if hop == "" {
+			break
+		}
+		if !isTrustedProxy(proxies, hop) {
+			return hop
+		}
+		peer = hop
+	}
+	return peer
+}
+
+// trustedProxies parses `TRUSTED_PROXIES`, a comma separated list of the
+// addresses or CIDR ranges of the proxies in front of ACT
+func trustedProxies() []*net.IPNet {
+	proxies := []*net.IPNet{}
+	for _, entry := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			log.Printf("[WARN] Ignoring invalid TRUSTED_PROXIES entry %q: %v", entry, err)
+			continue
+		}
+		proxies = append(proxies, network)
+	}
+	return proxies
+}
+
+func isTrustedProxy(proxies []*net.IPNet, addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range proxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// rateLimitKey picks the bucket and limit a request counts against, false
+// when `policy` doesn't limit it
+func rateLimitKey(r *http.Request, policy RateLimitPolicy) (string, RateLimit, bool) {
+	var identity string
+	var limit RateLimit
+	apiKeyId, _ := r.Context().Value("apiKeyId").(string)
+	userInfo, _ := r.Context().Value("userInfo").(constants.UserInfo)
+	switch {
+	case apiKeyId != "" && policy.PerAPIKey.enabled():
+		identity, limit = "key:"+apiKeyId, policy.PerAPIKey
+	case userInfo.Sub != "" && policy.PerUser.enabled():
+		identity, limit = "user:"+userInfo.Sub, policy.PerUser
+	case policy.PerIP.enabled():
+		identity, limit = "ip:"+ClientIP(r), policy.PerIP
+	default:
+		return "", RateLimit{}, false
+	}
+	// KV keys can't hold every character an IP or id might, and the
+	// identity doesn't need to be readable
+	sum := sha256.Sum256([]byte(identity))
+	return policy.Name + "." + hex.EncodeToString(sum[:16]), limit, true
+}
+
+// CheckRateLimit takes a token for `r` under `policy`, false when nothing
+// limits the request. A failing store lets the request through
+func CheckRateLimit(r *http.Request, policy RateLimitPolicy, now time.Time) (RateLimitResult, bool) {
+	key, limit, ok := rateLimitKey(r, policy)
+	if !ok {
+		return RateLimitResult{}, false
+	}
+	result, err := rateLimitStore.Take(r.Context(), key, limit, now)
+	if err != nil {
+		log.Printf("ERR: rate limit %s not checked: %v", policy.Name, err)
+		return RateLimitResult{}, false
+	}
+	return result, true
+}
+
+// SetRateLimitHeaders adds the `RateLimit-*` headers of the IETF draft, and
+// `Retry-After` when the request was refused
+func SetRateLimitHeaders(header http.Header, result RateLimitResult) {
+	header.Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+	header.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", result.Limit, ceilSeconds(result.Window)))
+	if !result.Allowed {
+		header.Set("Retry-After", strconv.Itoa(max(ceilSeconds(result.RetryAfter), 1)))
+	}
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
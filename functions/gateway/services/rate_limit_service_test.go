@@ -0,0 +1,249 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+func TestRateLimitBucket(t *testing.T) {
+	limit := RateLimit{Requests: 3, Per: 3 * time.Second}
+	now := time.Unix(1700000000, 0)
+	var bucket rateLimitBucket
+	var result RateLimitResult
+
+	for i := 2; i >= 0; i-- {
+		bucket, result = bucket.take(limit, now)
+		if !result.Allowed || result.Remaining != i {
+			t.Fatalf("expected request to be allowed with %d left, got %+v", i, result)
+		}
+	}
+	if result.Reset != 3*time.Second {
+		t.Errorf("expected an empty bucket to be full in 3s, got %v", result.Reset)
+	}
+
+	bucket, result = bucket.take(limit, now.Add(500*time.Millisecond))
+	if result.Allowed || result.RetryAfter != 500*time.Millisecond {
+		t.Errorf("expected to wait 500ms for the next token, got %+v", result)
+	}
+
+	_, result = bucket.take(limit, now.Add(time.Second))
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("expected a token after 1s, got %+v", result)
+	}
+
+	_, result = bucket.take(limit, now.Add(time.Hour))
+	if !result.Allowed || result.Remaining != 2 {
+		t.Errorf("expected the bucket to refill up to its size, got %+v", result)
+	}
+}
+
+func TestMemoryRateLimitStore(t *testing.T) {
+	store := NewMemoryRateLimitStore()
+	limit := RateLimit{Requests: 1, Per: time.Minute}
+	now := time.Unix(1700000000, 0)
+	ctx := context.Background()
+
+	if result, _ := store.Take(ctx, "a", limit, now); !result.Allowed {
+		t.Error("expected the first request of a to be allowed")
+	}
+	if result, _ := store.Take(ctx, "a", limit, now); result.Allowed {
+		t.Error("expected the second request of a to be refused")
+	}
+	if result, _ := store.Take(ctx, "b", limit, now); !result.Allowed {
+		t.Error("expected b to have its own bucket")
+	}
+}
+
+type fakeKVEntry struct {
+	jetstream.KeyValueEntry
+	value    []byte
+	revision uint64
+}
+
+func (e fakeKVEntry) Value() []byte    { return e.value }
+func (e fakeKVEntry) Revision() uint64 { return e.revision }
+
+// fakeRateLimitKV keeps one revision per key like a KV bucket with
+// `History: 1`, `conflicts` fails that many writes as if another instance
+// got there first
+type fakeRateLimitKV struct {
+	mu        sync.Mutex
+	entries   map[string]fakeKVEntry
+	revision  uint64
+	conflicts int
+}
+
+func (f *fakeRateLimitKV) Get(ctx context.Context, key string) (jetstream.KeyValueEntry, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	entry, ok := f.entries[key]
+	if !ok {
+		return nil, jetstream.ErrKeyNotFound
+	}
+	return entry, nil
+}
+
+func (f *fakeRateLimitKV) write(key string, value []byte, revision uint64) (uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conflicts > 0 {
+		f.conflicts--
+		f.revision++
+		f.entries[key] = fakeKVEntry{value: []byte(`{}`), revision: f.revision}
+		return 0, errors.New("wrong last sequence")
+	}
+	if f.entries[key].revision != revision {
+		return 0, errors.New("wrong last sequence")
+	}
+	f.revision++
+	f.entries[key] = fakeKVEntry{value: value, revision: f.revision}
+	return f.revision, nil
+}
+
+func (f *fakeRateLimitKV) Create(ctx context.Context, key string, value []byte, opts ...jetstream.KVCreateOpt) (uint64, error) {
+	return f.write(key, value, 0)
+}
+
+func (f *fakeRateLimitKV) Update(ctx context.Context, key string, value []byte, revision uint64) (uint64, error) {
+	return f.write(key, value, revision)
+}
+
+func TestNatsRateLimitStore(t *testing.T) {
+	kv := &fakeRateLimitKV{entries: map[string]fakeKVEntry{}}
+	store := &NatsRateLimitStore{kv: kv}
+	limit := RateLimit{Requests: 2, Per: time.Minute}
+	now := time.Unix(1700000000, 0)
+	ctx := context.Background()
+
+	for i, want := range []bool{true, true, false} {
+		result, err := store.Take(ctx, "search.a", limit, now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if result.Allowed != want {
+			t.Errorf("request %d: expected allowed %v, got %+v", i, want, result)
+		}
+	}
+
+	kv.conflicts = 2
+	if result, err := store.Take(ctx, "search.b", limit, now); err != nil || !result.Allowed {
+		t.Errorf("expected a conflicting write to be retried, got %+v, %v", result, err)
+	}
+
+	kv.conflicts = rateLimitUpdateAttempts
+	if _, err := store.Take(ctx, "search.c", limit, now); err == nil {
+		t.Error("expected an error once the attempts run out")
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/events", nil)
+	req.RemoteAddr = "10.0.0.1:4321"
+	if ip := ClientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expected the remote address, got %q", ip)
+	}
+
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 203.0.113.7")
+	if ip := ClientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expected a peer that isn't a trusted proxy not to be believed, got %q", ip)
+	}
+
+	t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.0.2.1, not-an-address")
+	if ip := ClientIP(req); ip != "203.0.113.7" {
+		t.Errorf("expected the last forwarded hop, got %q", ip)
+	}
+	req.Header.Set("X-Forwarded-For", "1.1.1.1, 203.0.113.7, 192.0.2.1")
+	if ip := ClientIP(req); ip != "203.0.113.7" {
+		t.Errorf("expected hops added by trusted proxies to be skipped, got %q", ip)
+	}
+	req.Header.Del("X-Forwarded-For")
+	if ip := ClientIP(req); ip != "10.0.0.1" {
+		t.Errorf("expected the proxy's address without a forwarded hop, got %q", ip)
+	}
+
+	ctx := context.WithValue(req.Context(), constants.ApiGwV2ReqKey, events.APIGatewayV2HTTPRequest{
+		RequestContext: events.APIGatewayV2HTTPRequestContext{
+			HTTP: events.APIGatewayV2HTTPRequestContextHTTPDescription{SourceIP: "198.51.100.2"},
+		},
+	})
+	if ip := ClientIP(req.WithContext(ctx)); ip != "198.51.100.2" {
+		t.Errorf("expected the API Gateway source IP, got %q", ip)
+	}
+}
+
+func TestCheckRateLimit(t *testing.T) {
+	originalStore := rateLimitStore
+	defer SetRateLimitStore(originalStore)
+	SetRateLimitStore(NewMemoryRateLimitStore())
+
+	policy := RateLimitPolicy{
+		Name:      "test",
+		PerIP:     RateLimit{Requests: 1, Per: time.Minute},
+		PerAPIKey: RateLimit{Requests: 2, Per: time.Minute},
+	}
+	now := time.Unix(1700000000, 0)
+	newRequest := func(userId, apiKeyId string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/api/events", nil)
+		req.RemoteAddr = "10.0.0.1:4321"
+		ctx := req.Context()
+		if userId != "" {
+			ctx = context.WithValue(ctx, "userInfo", constants.UserInfo{Sub: userId})
+		}
+		if apiKeyId != "" {
+			ctx = context.WithValue(ctx, "apiKeyId", apiKeyId)
+		}
+		return req.WithContext(ctx)
+	}
+
+	if result, ok := CheckRateLimit(newRequest("", ""), policy, now); !ok || !result.Allowed || result.Limit != 1 {
+		t.Errorf("expected the IP limit to apply, got %+v", result)
+	}
+	// no per user limit, so the user shares the IP's bucket
+	if result, ok := CheckRateLimit(newRequest("user-1", ""), policy, now); !ok || result.Allowed {
+		t.Errorf("expected the IP bucket to be empty, got %+v", result)
+	}
+	if result, ok := CheckRateLimit(newRequest("user-1", "key-1"), policy, now); !ok || !result.Allowed || result.Limit != 2 {
+		t.Errorf("expected the API key limit to apply, got %+v", result)
+	}
+	if _, ok := CheckRateLimit(newRequest("", ""), RateLimitPolicy{Name: "none"}, now); ok {
+		t.Error("expected an empty policy not to limit anything")
+	}
+
+	key, _, _ := rateLimitKey(newRequest("", ""), policy)
+	if !strings.HasPrefix(key, "test.") || strings.Contains(key, "10.0.0.1") {
+		t.Errorf("expected a hashed key under the policy name, got %q", key)
+	}
+}
+
+func TestSetRateLimitHeaders(t *testing.T) {
+	header := http.Header{}
+	SetRateLimitHeaders(header, RateLimitResult{Allowed: false, Limit: 10, Remaining: 0, Window: time.Hour, Reset: 61 * time.Minute / 10, RetryAfter: 100 * time.Millisecond})
+	want := map[string]string{
+		"RateLimit-Limit":     "10",
+		"RateLimit-Remaining": "0",
+		"RateLimit-Reset":     "366",
+		"RateLimit-Policy":    "10;w=3600",
+		"Retry-After":         "1",
+	}
+	for name, value := range want {
+		if got := header.Get(name); got != value {
+			t.Errorf("expected %s %q, got %q", name, value, got)
+		}
+	}
+
+	header = http.Header{}
+	SetRateLimitHeaders(header, RateLimitResult{Allowed: true, Limit: 10, Remaining: 9, Window: time.Hour, Reset: 6 * time.Minute})
+	if header.Get("Retry-After") != "" {
+		t.Error("expected no Retry-After when the request is allowed")
+	}
+}
@@ -0,0 +1,307 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/geo/s2"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/types"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
+)
+
+// ClampSimilarEventsLimit maps a client supplied `limit` onto the range the
+// similar events endpoints are willing to serve
+func ClampSimilarEventsLimit(limit int) int {
+	if limit <= 0 {
+		return constants.DEFAULT_SIMILAR_EVENTS_LIMIT
+	}
+	if limit > constants.MAX_SIMILAR_EVENTS_LIMIT {
+		return constants.MAX_SIMILAR_EVENTS_LIMIT
+	}
+	return limit
+}
+
+// eventSeriesId is the id shared by every event of the series `event` belongs
+// to, empty for a standalone event. Scraped single events also carry an
+// `eventSourceId` but that names the feed they came from, not a series
+func eventSeriesId(event types.Event) string {
+	switch event.EventSourceType {
+	case constants.ES_SERIES_PARENT, constants.ES_SERIES_PARENT_UNPUB:
+		return event.Id
+	case constants.ES_EVENT_SERIES, constants.ES_EVENT_SERIES_UNPUB:
+		return event.EventSourceId
+	}
+	return ""
+}
+
+// similarEventsWhereFilter narrows a nearObject search around `source` to
+// upcoming, searchable events within `radius` miles that aren't part of the
+// same series. Events flagged `hideCrossPromo` only ever recommend, and are
+// only recommended alongside, events of their own owners
+func similarEventsWhereFilter(source types.Event, radius float64, now int64) *filters.WhereBuilder {
+	operands := []*filters.WhereBuilder{
+		(&filters.WhereBuilder{}).WithPath([]string{"startTime"}).WithOperator(filters.GreaterThanEqual).WithValueInt(now),
+		(&filters.WhereBuilder{}).WithPath([]string{"eventSourceType"}).WithOperator(filters.ContainsAny).WithValueText(constants.DEFAULT_SEARCHABLE_EVENT_SOURCE_TYPES...),
+		(&filters.WhereBuilder{}).WithPath([]string{"id"}).WithOperator(filters.NotEqual).WithValueText(source.Id),
+	}
+
+	if source.Lat != constants.INITIAL_EMPTY_LAT_LONG && source.Long != constants.INITIAL_EMPTY_LAT_LONG && radius > 0 {
+		minLat, maxLat, minLong1, maxLong1, minLong2, maxLong2, needsSplit := calculateSearchBounds([]float64{source.Lat, source.Long}, radius)
+		longRanges := [][2]float64{{minLong1, maxLong1}}
+		if needsSplit {
+			longRanges = append(longRanges, [2]float64{minLong2, maxLong2})
+		}
+		operands = append(operands, locationWhereFilters(minLat, maxLat, longRanges)...)
+	}
+
+	if seriesId := eventSeriesId(source); seriesId != "" {
+		operands = append(operands, (&filters.WhereBuilder{}).WithPath([]string{"eventSourceId"}).WithOperator(filters.NotEqual).WithValueText(seriesId))
+		if seriesId != source.Id {
+			operands = append(operands, (&filters.WhereBuilder{}).WithPath([]string{"id"}).WithOperator(filters.NotEqual).WithValueText(seriesId))
+		}
+	}
+
+	// `hideCrossPromo` is optional on write, so match "not true" rather than
+	// "false" to keep events that never set it
+	notHidden := (&filters.WhereBuilder{}).WithPath([]string{"hideCrossPromo"}).WithOperator(filters.NotEqual).WithValueBoolean(true)
+	if len(source.EventOwners) == 0 {
+		operands = append(operands, notHidden)
+	} else {
+		sameOwner := (&filters.WhereBuilder{}).WithPath([]string{"eventOwners"}).WithOperator(filters.ContainsAny).WithValueText(source.EventOwners...)
+		if source.HideCrossPromo {
+			operands = append(operands, sameOwner)
+		} else {
+			operands = append(operands, (&filters.WhereBuilder{}).
+				WithOperator(filters.Or).
+				WithOperands([]*filters.WhereBuilder{notHidden, sameOwner}))
+		}
+	}
+
+	return (&filters.WhereBuilder{}).WithOperator(filters.And).WithOperands(operands)
+}
+
+// SearchSimilarEvents returns up to `limit` events closest to `source` in
+// vector space, see `similarEventsWhereFilter` for which events qualify
+func SearchSimilarEvents(ctx context.Context, client *weaviate.Client, source types.Event, radius float64, limit int, parseDates string) ([]types.Event, error) {
+	if source.Id == "" {
+		return nil, fmt.Errorf("source event ID cannot be empty")
+	}
+
+	className := EventClassName()
+	nearObject := client.GraphQL().NearObjectArgBuilder().WithID(source.Id)
+	result, err := client.GraphQL().Get().
+		WithClassName(className).
+		WithNearObject(nearObject).
+		WithWhere(similarEventsWhereFilter(source, radius, time.Now().Unix())).
+		WithFields(eventSearchFields...).
+		WithLimit(ClampSimilarEventsLimit(limit)).
+		Do(ctx)
+	if err != nil {
+		log.Printf("Error searching similar events: %v", err)
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("similar events search failed: %s", result.Errors[0].Message)
+	}
+
+	events := []types.Event{}
+	getMap, ok := result.Data["Get"].(map[string]interface{})
+	if !ok {
+		return events, nil
+	}
+	classData, _ := getMap[className].([]interface{})
+	for _, uncastedObj := range classData {
+		objMap, ok := uncastedObj.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		event, err := NormalizeWeaviateResultToEvent(objMap)
+		if err != nil {
+			log.Printf("Warning: Could not normalize Weaviate result: %v", err)
+			continue
+		}
+		if parseDates == "1" && event.Timezone.String() != "" {
+			event.LocalizedStartTime, event.LocalizedStartDate = helpers.GetLocalDateAndTime(event.StartTime, event.Timezone)
+		}
+		events = append(events, *event)
+	}
+	return events, nil
+}
+
+const (
+	feedWeightInterest  = 0.4
+	feedWeightRecency   = 0.25
+	feedWeightProximity = 0.2
+	feedWeightOwner     = 0.15
+
+	// An event this many days out scores half as "recent" as one starting now
+	feedRecencyHalfLifeDays = 7.0
+	// An event this many miles away scores half as "close" as one next door
+	feedProximityHalfLifeMiles = 10.0
+
+	feedSoonHours   = 48
+	feedNearbyMiles = 5.0
+	earthRadiusMi   = 3958.8
+
+	// Upper bound on the re-shared events read to find the owners a user follows
+	maxReSharedEventsForFeed = 200
+)
+
+// FeedSignals is what we know about a user when ranking their home feed
+type FeedSignals struct {
+	UserId string
+	// Category and subcategory names from the user's `interests` metadata
+	Interests []string
+	// Lat, long. Nil when the user's location is unknown
+	Location []float64
+	// Owners of events the user has re-shared, the closest thing we have to
+	// following an organizer
+	Owners []string
+}
+
+// feedInterestSet lower cases the user's interests. Picking a top level
+// category counts as interest in each of its subcategories
+func feedInterestSet(interests []string) map[string]string {
+	set := map[string]string{}
+	for _, interest := range interests {
+		interest = strings.TrimSpace(interest)
+		if interest == "" {
+			continue
+		}
+		set[strings.ToLower(interest)] = interest
+		for _, category := range constants.Categories {
+			if category.Name != interest {
+				continue
+			}
+			for _, item := range category.Items {
+				set[strings.ToLower(item.Name)] = item.Name
+			}
+		}
+	}
+	return set
+}
+
+func milesBetween(lat1, long1, lat2, long2 float64) float64 {
+	return s2.LatLngFromDegrees(lat1, long1).Distance(s2.LatLngFromDegrees(lat2, long2)).Radians() * earthRadiusMi
+}
+
+func scoreFeedEvent(event types.Event, signals FeedSignals, interests map[string]string, owners map[string]bool, now time.Time) *types.EventRank {
+	rank := &types.EventRank{}
+
+	matched := []string{}
+	seen := map[string]bool{}
+	for _, category := range event.Categories {
+		key := strings.ToLower(strings.TrimSpace(category))
+		if name, ok := interests[key]; ok && !seen[key] {
+			seen[key] = true
+			matched = append(matched, name)
+		}
+	}
+	if len(matched) > 0 {
+		rank.Interest = math.Min(1, float64(len(matched))/2)
+		rank.Reasons = append(rank.Reasons, "Matches your interest in "+strings.Join(matched, ", "))
+	}
+
+	untilStart := time.Unix(event.StartTime, 0).Sub(now)
+	if untilStart <= 0 {
+		rank.Recency = 1
+	} else {
+		rank.Recency = math.Pow(0.5, untilStart.Hours()/24/feedRecencyHalfLifeDays)
+		if untilStart.Hours() <= feedSoonHours {
+			rank.Reasons = append(rank.Reasons, "Happening soon")
+		}
+	}
+
+	if len(signals.Location) == 2 && event.Lat != constants.INITIAL_EMPTY_LAT_LONG && event.Long != constants.INITIAL_EMPTY_LAT_LONG {
+		miles := milesBetween(signals.Location[0], signals.Location[1], event.Lat, event.Long)
+		rank.Proximity = math.Pow(0.5, miles/feedProximityHalfLifeMiles)
+		if miles <= feedNearbyMiles {
+			rank.Reasons = append(rank.Reasons, fmt.Sprintf("%.1f mi away", miles))
+		}
+	}
+
+	followed := false
+	for _, owner := range event.EventOwners {
+		followed = followed || owners[owner]
+	}
+	for _, shadowOwner := range event.ShadowOwners {
+		followed = followed || (signals.UserId != "" && shadowOwner == signals.UserId)
+	}
+	if followed {
+		rank.Owner = 1
+		rank.Reasons = append(rank.Reasons, "From an organizer you re-shared")
+	}
+
+	rank.Score = feedWeightInterest*rank.Interest +
+		feedWeightRecency*rank.Recency +
+		feedWeightProximity*rank.Proximity +
+		feedWeightOwner*rank.Owner
+	return rank
+}
+
+// RankEventsForUser orders `events` for a "for you" feed, highest score
+// first with ties kept in their original order, and attaches the score
+// breakdown to each event's `Rank`
+func RankEventsForUser(events []types.Event, signals FeedSignals, now time.Time) []types.Event {
+	interests := feedInterestSet(signals.Interests)
+	owners := map[string]bool{}
+	for _, owner := range signals.Owners {
+		owners[owner] = true
+	}
+
+	ranked := make([]types.Event, len(events))
+	for i, event := range events {
+		event.Rank = scoreFeedEvent(event, signals, interests, owners, now)
+		ranked[i] = event
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Rank.Score > ranked[j].Rank.Score
+	})
+	return ranked
+}
+
+// GetReSharedOwners lists the owners of events `userId` has re-shared
+func GetReSharedOwners(ctx context.Context, client *weaviate.Client, userId string) ([]string, error) {
+	if userId == "" {
+		return []string{}, nil
+	}
+
+	className := EventClassName()
+	result, err := client.GraphQL().Get().
+		WithClassName(className).
+		WithWhere((&filters.WhereBuilder{}).WithPath([]string{"shadowOwners"}).WithOperator(filters.ContainsAny).WithValueText(userId)).
+		WithFields(graphql.Field{Name: "eventOwners"}).
+		WithLimit(maxReSharedEventsForFeed).
+		Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("re-shared events search failed: %s", result.Errors[0].Message)
+	}
+
+	owners := []string{}
+	seen := map[string]bool{userId: true}
+	getMap, _ := result.Data["Get"].(map[string]interface{})
+	classData, _ := getMap[className].([]interface{})
+	for _, uncastedObj := range classData {
+		objMap, _ := uncastedObj.(map[string]interface{})
+		eventOwners, _ := objMap["eventOwners"].([]interface{})
+		for _, owner := range eventOwners {
+			if ownerStr, ok := owner.(string); ok && !seen[ownerStr] {
+				seen[ownerStr] = true
+				owners = append(owners, ownerStr)
+			}
+		}
+	}
+	return owners, nil
+}
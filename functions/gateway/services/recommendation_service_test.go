@@ -0,0 +1,150 @@
+package services
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+func TestSimilarEventsWhereFilter(t *testing.T) {
+	now := int64(1760000000)
+
+	tests := []struct {
+		name        string
+		source      types.Event
+		expected    []string
+		notExpected []string
+	}{
+		{
+			name: "single event excludes only itself",
+			source: types.Event{
+				Id:              "ev-1",
+				EventOwners:     []string{"owner-1"},
+				EventSourceType: constants.ES_SINGLE_EVENT,
+				EventSourceId:   "scraped-feed",
+				Lat:             40.7128,
+				Long:            -74.0060,
+			},
+			expected: []string{
+				`path: ["startTime"] valueInt: 1760000000`,
+				`path: ["eventSourceType"] valueText: ["SLF_EVS","SLF"]`,
+				`operator: NotEqual path: ["id"] valueText: "ev-1"`,
+				`path: ["lat"]`,
+				`operator: NotEqual path: ["hideCrossPromo"] valueBoolean: true`,
+				`operator: ContainsAny path: ["eventOwners"] valueText: ["owner-1"]`,
+			},
+			// other events scraped from the same feed are not a series
+			notExpected: []string{`"scraped-feed"`},
+		},
+		{
+			name: "series occurrence excludes its parent and siblings",
+			source: types.Event{
+				Id:              "occurrence-1",
+				EventSourceType: constants.ES_EVENT_SERIES,
+				EventSourceId:   "parent-1",
+				Lat:             40.7128,
+				Long:            -74.0060,
+			},
+			expected: []string{
+				`operator: NotEqual path: ["id"] valueText: "parent-1"`,
+				`operator: NotEqual path: ["eventSourceId"] valueText: "parent-1"`,
+				`operator: NotEqual path: ["hideCrossPromo"] valueBoolean: true`,
+			},
+		},
+		{
+			name: "hideCrossPromo source only recommends its own owners",
+			source: types.Event{
+				Id:              "ev-2",
+				EventOwners:     []string{"owner-2"},
+				EventSourceType: constants.ES_SINGLE_EVENT,
+				HideCrossPromo:  true,
+				Lat:             constants.INITIAL_EMPTY_LAT_LONG,
+				Long:            constants.INITIAL_EMPTY_LAT_LONG,
+			},
+			expected: []string{
+				`operator: ContainsAny path: ["eventOwners"] valueText: ["owner-2"]`,
+			},
+			notExpected: []string{`"hideCrossPromo"`, `path: ["lat"]`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := similarEventsWhereFilter(tt.source, 50, now).String()
+			for _, want := range tt.expected {
+				if !strings.Contains(filter, want) {
+					t.Errorf("expected filter to contain %s\n%s", want, filter)
+				}
+			}
+			for _, unwanted := range tt.notExpected {
+				if strings.Contains(filter, unwanted) {
+					t.Errorf("expected filter not to contain %s\n%s", unwanted, filter)
+				}
+			}
+		})
+	}
+}
+
+func TestRankEventsForUser(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) int64 { return now.Add(d).Unix() }
+
+	events := []types.Event{
+		{Id: "far-later", StartTime: at(10 * 24 * time.Hour), Lat: 34.05, Long: -118.24, EventOwners: []string{"stranger"}},
+		{Id: "interest", StartTime: at(10 * 24 * time.Hour), Lat: 34.05, Long: -118.24, EventOwners: []string{"stranger"}, Categories: []string{"Public conferences"}},
+		{Id: "followed-soon", StartTime: at(time.Hour), Lat: 40.7130, Long: -74.0050, EventOwners: []string{"organizer-1"}},
+		{Id: "far-later-2", StartTime: at(10 * 24 * time.Hour), Lat: 34.05, Long: -118.24, EventOwners: []string{"stranger"}},
+	}
+	signals := FeedSignals{
+		UserId: "user-1",
+		// a top level category counts for its subcategories
+		Interests: []string{"Academic & Career Development", ""},
+		Location:  []float64{40.7128, -74.0060},
+		Owners:    []string{"organizer-1"},
+	}
+
+	ranked := RankEventsForUser(events, signals, now)
+
+	order := []string{}
+	for _, event := range ranked {
+		order = append(order, event.Id)
+		if event.Rank == nil {
+			t.Fatalf("%s has no rank", event.Id)
+		}
+	}
+	if got := strings.Join(order, ","); got != "followed-soon,interest,far-later,far-later-2" {
+		t.Errorf("order = %s", got)
+	}
+
+	top := ranked[0].Rank
+	if top.Owner != 1 || top.Recency < 0.99 || top.Proximity < 0.9 {
+		t.Errorf("unexpected signals for the followed event: %+v", top)
+	}
+	if reasons := strings.Join(top.Reasons, "|"); !strings.Contains(reasons, "Happening soon") || !strings.Contains(reasons, "mi away") || !strings.Contains(reasons, "re-shared") {
+		t.Errorf("unexpected reasons: %v", top.Reasons)
+	}
+	if ranked[1].Rank.Interest != 0.5 || len(ranked[1].Rank.Reasons) != 1 || !strings.Contains(ranked[1].Rank.Reasons[0], "Public conferences") {
+		t.Errorf("unexpected interest rank: %+v", ranked[1].Rank)
+	}
+	if ranked[2].Rank.Reasons != nil {
+		t.Errorf("an event with no matching signals should have no reasons, got %v", ranked[2].Rank.Reasons)
+	}
+	if events[0].Rank != nil {
+		t.Errorf("ranking should not modify the input slice")
+	}
+}
+
+func TestRankEventsForUserShadowOwner(t *testing.T) {
+	now := time.Now()
+	events := []types.Event{
+		{Id: "other", StartTime: now.Add(time.Hour).Unix()},
+		{Id: "reshared", StartTime: now.Add(time.Hour).Unix(), ShadowOwners: []string{"user-1"}},
+	}
+	ranked := RankEventsForUser(events, FeedSignals{UserId: "user-1"}, now)
+	if ranked[0].Id != "reshared" || ranked[0].Rank.Owner != 1 {
+		t.Errorf("an event the user re-shared should rank first, got %s %+v", ranked[0].Id, ranked[0].Rank)
+	}
+}
@@ -0,0 +1,504 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RRuleFreqDaily   = "DAILY"
+	RRuleFreqWeekly  = "WEEKLY"
+	RRuleFreqMonthly = "MONTHLY"
+	RRuleFreqYearly  = "YEARLY"
+
+	// Hard ceiling on how many occurrences a single rule may materialize, this
+	// protects Weaviate from e.g. an unbounded DAILY rule with a far horizon
+	MaxRecurrenceOccurrences = 500
+
+	// Consecutive periods that yield no candidates before we conclude a rule
+	// can never match again (e.g. BYMONTH=2;BYMONTHDAY=30)
+	maxEmptyRecurrencePeriods = 1000
+)
+
+var rruleWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// RecurrenceWeekday is a single BYDAY entry, `N` is the optional ordinal
+// (e.g. 2 for "2nd Tuesday", -1 for "last Friday", 0 for "every")
+type RecurrenceWeekday struct {
+	Weekday time.Weekday
+	N       int
+}
+
+// RecurrenceRule is the subset of the RFC 5545 RRULE grammar we support:
+// FREQ (DAILY..YEARLY), INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH,
+// BYSETPOS and WKST. Rules using other parts are rejected by ParseRRule
+// rather than silently expanded incorrectly
+type RecurrenceRule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []RecurrenceWeekday
+	ByMonthDay []int
+	ByMonth    []time.Month
+	BySetPos   []int
+	WeekStart  time.Weekday
+}
+
+// ParseRRule parses an RRULE value (with or without the "RRULE:" prefix).
+// `loc` is used to interpret a floating (non-UTC) UNTIL value
+func ParseRRule(value string, loc *time.Location) (RecurrenceRule, error) {
+	rule := RecurrenceRule{Interval: 1, WeekStart: time.Monday}
+	if loc == nil {
+		loc = time.UTC
+	}
+	value = strings.TrimSpace(value)
+	value = strings.TrimPrefix(value, "RRULE:")
+	if value == "" {
+		return rule, fmt.Errorf("empty RRULE")
+	}
+
+	for _, part := range strings.Split(value, ";") {
+		if part == "" {
+			continue
+		}
+		key, val, ok := strings.Cut(part, "=")
+		if !ok {
+			return rule, fmt.Errorf("malformed RRULE part %q", part)
+		}
+		key = strings.ToUpper(key)
+		switch key {
+		case "FREQ":
+			freq := strings.ToUpper(val)
+			switch freq {
+			case RRuleFreqDaily, RRuleFreqWeekly, RRuleFreqMonthly, RRuleFreqYearly:
+				rule.Freq = freq
+			default:
+				return rule, fmt.Errorf("unsupported RRULE FREQ %q", val)
+			}
+		case "INTERVAL":
+			interval, err := strconv.Atoi(val)
+			if err != nil || interval < 1 {
+				return rule, fmt.Errorf("invalid RRULE INTERVAL %q", val)
+			}
+			rule.Interval = interval
+		case "COUNT":
+			count, err := strconv.Atoi(val)
+			if err != nil || count < 1 {
+				return rule, fmt.Errorf("invalid RRULE COUNT %q", val)
+			}
+			rule.Count = count
+		case "UNTIL":
+			until, _, err := parseICalDateTime(val, loc)
+			if err != nil {
+				return rule, fmt.Errorf("invalid RRULE UNTIL %q: %w", val, err)
+			}
+			// A date-only UNTIL is inclusive of the whole day
+			if len(val) == len("20060102") {
+				until = until.Add(24*time.Hour - time.Second)
+			}
+			rule.Until = until
+		case "BYDAY":
+			for _, day := range strings.Split(val, ",") {
+				weekday, err := parseRRuleWeekday(day)
+				if err != nil {
+					return rule, err
+				}
+				rule.ByDay = append(rule.ByDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, day := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(day)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return rule, fmt.Errorf("invalid RRULE BYMONTHDAY %q", day)
+				}
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "BYMONTH":
+			for _, month := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(month)
+				if err != nil || n < 1 || n > 12 {
+					return rule, fmt.Errorf("invalid RRULE BYMONTH %q", month)
+				}
+				rule.ByMonth = append(rule.ByMonth, time.Month(n))
+			}
+		case "BYSETPOS":
+			for _, pos := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(pos)
+				if err != nil || n == 0 || n < -366 || n > 366 {
+					return rule, fmt.Errorf("invalid RRULE BYSETPOS %q", pos)
+				}
+				rule.BySetPos = append(rule.BySetPos, n)
+			}
+		case "WKST":
+			weekday, ok := rruleWeekdays[strings.ToUpper(val)]
+			if !ok {
+				return rule, fmt.Errorf("invalid RRULE WKST %q", val)
+			}
+			rule.WeekStart = weekday
+		default:
+			return rule, fmt.Errorf("unsupported RRULE part %q", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return rule, fmt.Errorf("RRULE is missing FREQ")
+	}
+	if rule.Count > 0 && !rule.Until.IsZero() {
+		return rule, fmt.Errorf("RRULE must not contain both COUNT and UNTIL")
+	}
+	return rule, nil
+}
+
+func parseRRuleWeekday(value string) (RecurrenceWeekday, error) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	if len(value) < 2 {
+		return RecurrenceWeekday{}, fmt.Errorf("invalid RRULE BYDAY %q", value)
+	}
+	weekday, ok := rruleWeekdays[value[len(value)-2:]]
+	if !ok {
+		return RecurrenceWeekday{}, fmt.Errorf("invalid RRULE BYDAY %q", value)
+	}
+	n := 0
+	if prefix := value[:len(value)-2]; prefix != "" {
+		var err error
+		n, err = strconv.Atoi(prefix)
+		if err != nil || n == 0 || n < -53 || n > 53 {
+			return RecurrenceWeekday{}, fmt.Errorf("invalid RRULE BYDAY %q", value)
+		}
+	}
+	return RecurrenceWeekday{Weekday: weekday, N: n}, nil
+}
+
+// String serializes the rule back to a canonical RRULE value (no prefix)
+func (r RecurrenceRule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(icalUTCLayout))
+	}
+	if len(r.ByMonth) > 0 {
+		months := make([]string, len(r.ByMonth))
+		for i, month := range r.ByMonth {
+			months[i] = strconv.Itoa(int(month))
+		}
+		parts = append(parts, "BYMONTH="+strings.Join(months, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, day := range r.ByDay {
+			days[i] = formatRRuleWeekday(day)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.WeekStart != time.Monday {
+		parts = append(parts, "WKST="+formatRRuleWeekday(RecurrenceWeekday{Weekday: r.WeekStart}))
+	}
+	return strings.Join(parts, ";")
+}
+
+func formatRRuleWeekday(day RecurrenceWeekday) string {
+	for code, weekday := range rruleWeekdays {
+		if weekday == day.Weekday {
+			if day.N != 0 {
+				return strconv.Itoa(day.N) + code
+			}
+			return code
+		}
+	}
+	return ""
+}
+
+func joinInts(values []int) string {
+	strs := make([]string, len(values))
+	for i, v := range values {
+		strs[i] = strconv.Itoa(v)
+	}
+	return strings.Join(strs, ",")
+}
+
+// ExpandRecurrenceSet returns the sorted, de-duplicated occurrence start
+// times of a recurrence set (DTSTART + RRULE + RDATE - EXDATE) that fall
+// within [windowStart, windowEnd]. Occurrences keep the wall-clock time of
+// `dtstart` in its own location, so a 7pm event stays at 7pm across DST
+// changes. `rule` may be nil for RDATE-only sets
+func ExpandRecurrenceSet(dtstart time.Time, rule *RecurrenceRule, rdates, exdates []time.Time, windowStart, windowEnd time.Time, limit int) []time.Time {
+	if limit <= 0 || limit > MaxRecurrenceOccurrences {
+		limit = MaxRecurrenceOccurrences
+	}
+
+	excluded := make(map[int64]bool, len(exdates))
+	for _, exdate := range exdates {
+		excluded[exdate.Unix()] = true
+	}
+
+	occurrences := map[int64]time.Time{}
+	add := func(t time.Time) {
+		if t.Before(windowStart) || t.After(windowEnd) || excluded[t.Unix()] {
+			return
+		}
+		occurrences[t.Unix()] = t
+	}
+
+	if rule != nil {
+		rule.iterate(dtstart, func(t time.Time) bool {
+			if t.After(windowEnd) {
+				return false
+			}
+			add(t)
+			return len(occurrences) < limit
+		})
+	} else {
+		add(dtstart)
+	}
+	for _, rdate := range rdates {
+		add(rdate.In(dtstart.Location()))
+	}
+
+	result := make([]time.Time, 0, len(occurrences))
+	for _, t := range occurrences {
+		result = append(result, t)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Before(result[j]) })
+	if len(result) > limit {
+		result = result[:limit]
+	}
+	return result
+}
+
+// iterate calls `fn` with each occurrence of the rule in chronological order,
+// starting with `dtstart` itself (which RFC 5545 counts as the first instance),
+// until `fn` returns false or COUNT / UNTIL are exhausted
+func (r RecurrenceRule) iterate(dtstart time.Time, fn func(time.Time) bool) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if !r.Until.IsZero() && t.After(r.Until) {
+			return false
+		}
+		emitted++
+		if !fn(t) {
+			return false
+		}
+		return r.Count == 0 || emitted < r.Count
+	}
+
+	if !emit(dtstart) {
+		return
+	}
+
+	emptyPeriods := 0
+	for period := 0; emptyPeriods < maxEmptyRecurrencePeriods; period++ {
+		candidates := r.periodCandidates(dtstart, period*interval)
+		if len(candidates) == 0 {
+			emptyPeriods++
+			continue
+		}
+		emptyPeriods = 0
+		for _, candidate := range candidates {
+			if !candidate.After(dtstart) {
+				continue
+			}
+			if !emit(candidate) {
+				return
+			}
+		}
+	}
+}
+
+// periodCandidates returns the sorted occurrences within the period that is
+// `offset` FREQ units after the one containing `dtstart`
+func (r RecurrenceRule) periodCandidates(dtstart time.Time, offset int) []time.Time {
+	// Date arithmetic is done at noon UTC so DST never shifts the calendar day
+	startDate := time.Date(dtstart.Year(), dtstart.Month(), dtstart.Day(), 12, 0, 0, 0, time.UTC)
+
+	var dates []time.Time
+	switch r.Freq {
+	case RRuleFreqDaily:
+		day := startDate.AddDate(0, 0, offset)
+		if r.matchesMonth(day) && r.matchesMonthDay(day) && r.matchesWeekday(day) {
+			dates = []time.Time{day}
+		}
+	case RRuleFreqWeekly:
+		daysIntoWeek := (int(startDate.Weekday()) - int(r.WeekStart) + 7) % 7
+		weekStart := startDate.AddDate(0, 0, -daysIntoWeek+offset*7)
+		weekdays := r.ByDay
+		if len(weekdays) == 0 {
+			weekdays = []RecurrenceWeekday{{Weekday: dtstart.Weekday()}}
+		}
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if !r.matchesMonth(day) {
+				continue
+			}
+			for _, weekday := range weekdays {
+				if weekday.Weekday == day.Weekday() {
+					dates = append(dates, day)
+					break
+				}
+			}
+		}
+	case RRuleFreqMonthly:
+		monthStart := time.Date(startDate.Year(), startDate.Month()+time.Month(offset), 1, 12, 0, 0, 0, time.UTC)
+		dates = r.spanCandidates(monthStart, monthStart.AddDate(0, 1, 0), dtstart)
+	case RRuleFreqYearly:
+		year := startDate.Year() + offset
+		if len(r.ByMonth) > 0 && len(r.ByDay) > 0 {
+			// With BYMONTH present, BYDAY ordinals are relative to each month
+			for month := time.January; month <= time.December; month++ {
+				monthStart := time.Date(year, month, 1, 12, 0, 0, 0, time.UTC)
+				dates = append(dates, r.spanCandidates(monthStart, monthStart.AddDate(0, 1, 0), dtstart)...)
+			}
+		} else {
+			yearStart := time.Date(year, time.January, 1, 12, 0, 0, 0, time.UTC)
+			dates = r.spanCandidates(yearStart, yearStart.AddDate(1, 0, 0), dtstart)
+		}
+	}
+
+	dates = r.applySetPos(dates)
+
+	hour, minute, second := dtstart.Clock()
+	loc := dtstart.Location()
+	result := make([]time.Time, len(dates))
+	for i, date := range dates {
+		result[i] = time.Date(date.Year(), date.Month(), date.Day(), hour, minute, second, 0, loc)
+	}
+	return result
+}
+
+// spanCandidates expands a MONTHLY or YEARLY period [spanStart, spanEnd)
+func (r RecurrenceRule) spanCandidates(spanStart, spanEnd time.Time, dtstart time.Time) []time.Time {
+	dates := []time.Time{}
+	for day := spanStart; day.Before(spanEnd); day = day.AddDate(0, 0, 1) {
+		if !r.matchesMonth(day) {
+			continue
+		}
+		if len(r.ByMonthDay) == 0 && len(r.ByDay) == 0 {
+			// Without BYxxx day selectors the rule repeats on DTSTART's day
+			if day.Day() != dtstart.Day() {
+				continue
+			}
+			if r.Freq == RRuleFreqYearly && len(r.ByMonth) == 0 && day.Month() != dtstart.Month() {
+				continue
+			}
+			dates = append(dates, day)
+			continue
+		}
+		if !r.matchesMonthDay(day) {
+			continue
+		}
+		if len(r.ByDay) > 0 && !r.matchesOrdinalWeekday(day, spanStart, spanEnd) {
+			continue
+		}
+		dates = append(dates, day)
+	}
+	return dates
+}
+
+func (r RecurrenceRule) matchesMonth(day time.Time) bool {
+	if len(r.ByMonth) == 0 {
+		return true
+	}
+	for _, month := range r.ByMonth {
+		if day.Month() == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RecurrenceRule) matchesMonthDay(day time.Time) bool {
+	if len(r.ByMonthDay) == 0 {
+		return true
+	}
+	daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 12, 0, 0, 0, time.UTC).Day()
+	for _, monthDay := range r.ByMonthDay {
+		if monthDay > 0 && day.Day() == monthDay {
+			return true
+		}
+		if monthDay < 0 && day.Day() == daysInMonth+monthDay+1 {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RecurrenceRule) matchesWeekday(day time.Time) bool {
+	if len(r.ByDay) == 0 {
+		return true
+	}
+	for _, weekday := range r.ByDay {
+		if weekday.Weekday == day.Weekday() {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RecurrenceRule) matchesOrdinalWeekday(day, spanStart, spanEnd time.Time) bool {
+	for _, weekday := range r.ByDay {
+		if weekday.Weekday != day.Weekday() {
+			continue
+		}
+		if weekday.N == 0 {
+			return true
+		}
+		daysFromStart := int(day.Sub(spanStart).Hours() / 24)
+		daysToEnd := int(spanEnd.Sub(day).Hours()/24) - 1
+		if weekday.N > 0 && daysFromStart/7+1 == weekday.N {
+			return true
+		}
+		if weekday.N < 0 && -(daysToEnd/7+1) == weekday.N {
+			return true
+		}
+	}
+	return false
+}
+
+func (r RecurrenceRule) applySetPos(dates []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 || len(dates) == 0 {
+		return dates
+	}
+	selected := map[int]bool{}
+	for _, pos := range r.BySetPos {
+		idx := pos - 1
+		if pos < 0 {
+			idx = len(dates) + pos
+		}
+		if idx >= 0 && idx < len(dates) {
+			selected[idx] = true
+		}
+	}
+	result := []time.Time{}
+	for i, date := range dates {
+		if selected[i] {
+			result = append(result, date)
+		}
+	}
+	return result
+}
@@ -0,0 +1,161 @@
+package services
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRRule(t *testing.T) {
+	rule, err := ParseRRule("RRULE:FREQ=MONTHLY;INTERVAL=2;BYDAY=2TU,-1FR;BYMONTH=1,7;WKST=SU;UNTIL=20251231T235959Z", time.UTC)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rule.Freq != RRuleFreqMonthly || rule.Interval != 2 {
+		t.Errorf("unexpected FREQ/INTERVAL: %+v", rule)
+	}
+	if len(rule.ByDay) != 2 || rule.ByDay[0] != (RecurrenceWeekday{time.Tuesday, 2}) || rule.ByDay[1] != (RecurrenceWeekday{time.Friday, -1}) {
+		t.Errorf("unexpected BYDAY: %+v", rule.ByDay)
+	}
+	if rule.WeekStart != time.Sunday {
+		t.Errorf("expected WKST=SU, got %v", rule.WeekStart)
+	}
+	if !rule.Until.Equal(time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC)) {
+		t.Errorf("unexpected UNTIL: %v", rule.Until)
+	}
+
+	roundTrip, err := ParseRRule(rule.String(), time.UTC)
+	if err != nil {
+		t.Fatalf("failed to re-parse %q: %v", rule.String(), err)
+	}
+	if roundTrip.String() != rule.String() {
+		t.Errorf("String() is not stable: %q != %q", roundTrip.String(), rule.String())
+	}
+
+	for _, invalid := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=HOURLY",
+		"FREQ=DAILY;COUNT=3;UNTIL=20250101T000000Z",
+		"FREQ=WEEKLY;BYHOUR=9",
+		"FREQ=WEEKLY;BYDAY=XX",
+	} {
+		if _, err := ParseRRule(invalid, time.UTC); err == nil {
+			t.Errorf("expected error for %q", invalid)
+		}
+	}
+}
+
+func TestExpandRecurrenceSet(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	windowStart := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	windowEnd := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	expand := func(t *testing.T, dtstart time.Time, rrule string, rdates, exdates []time.Time) []time.Time {
+		t.Helper()
+		rule, err := ParseRRule(rrule, dtstart.Location())
+		if err != nil {
+			t.Fatalf("failed to parse %q: %v", rrule, err)
+		}
+		return ExpandRecurrenceSet(dtstart, &rule, rdates, exdates, windowStart, windowEnd, 0)
+	}
+	assertDates := func(t *testing.T, got []time.Time, want ...time.Time) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("expected %d occurrences, got %d: %v", len(want), len(got), got)
+		}
+		for i := range want {
+			if !got[i].Equal(want[i]) {
+				t.Errorf("occurrence %d: expected %v, got %v", i, want[i], got[i])
+			}
+		}
+	}
+
+	t.Run("weekly BYDAY with COUNT counts DTSTART", func(t *testing.T) {
+		// Monday
+		dtstart := time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC)
+		got := expand(t, dtstart, "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4", nil, nil)
+		assertDates(t, got,
+			time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC),
+			time.Date(2025, 6, 4, 18, 0, 0, 0, time.UTC),
+			time.Date(2025, 6, 9, 18, 0, 0, 0, time.UTC),
+			time.Date(2025, 6, 11, 18, 0, 0, 0, time.UTC),
+		)
+	})
+
+	t.Run("monthly ordinal weekdays", func(t *testing.T) {
+		dtstart := time.Date(2025, 1, 14, 19, 0, 0, 0, time.UTC)
+		got := expand(t, dtstart, "FREQ=MONTHLY;BYDAY=2TU;COUNT=3", nil, nil)
+		assertDates(t, got,
+			time.Date(2025, 1, 14, 19, 0, 0, 0, time.UTC),
+			time.Date(2025, 2, 11, 19, 0, 0, 0, time.UTC),
+			time.Date(2025, 3, 11, 19, 0, 0, 0, time.UTC),
+		)
+
+		lastFriday := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+		got = expand(t, lastFriday, "FREQ=MONTHLY;BYDAY=-1FR;UNTIL=20250401T000000Z", nil, nil)
+		assertDates(t, got,
+			time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC),
+			time.Date(2025, 2, 28, 12, 0, 0, 0, time.UTC),
+			time.Date(2025, 3, 28, 12, 0, 0, 0, time.UTC),
+		)
+	})
+
+	t.Run("monthly BYMONTHDAY skips short months", func(t *testing.T) {
+		dtstart := time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC)
+		got := expand(t, dtstart, "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=3", nil, nil)
+		assertDates(t, got,
+			time.Date(2025, 1, 31, 9, 0, 0, 0, time.UTC),
+			time.Date(2025, 3, 31, 9, 0, 0, 0, time.UTC),
+			time.Date(2025, 5, 31, 9, 0, 0, 0, time.UTC),
+		)
+	})
+
+	t.Run("EXDATE removes and RDATE adds occurrences", func(t *testing.T) {
+		dtstart := time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC)
+		got := expand(t, dtstart, "FREQ=DAILY;COUNT=3",
+			[]time.Time{time.Date(2025, 6, 10, 18, 0, 0, 0, time.UTC)},
+			[]time.Time{time.Date(2025, 6, 3, 18, 0, 0, 0, time.UTC)},
+		)
+		assertDates(t, got,
+			time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC),
+			time.Date(2025, 6, 4, 18, 0, 0, 0, time.UTC),
+			time.Date(2025, 6, 10, 18, 0, 0, 0, time.UTC),
+		)
+	})
+
+	t.Run("keeps wall-clock time across DST", func(t *testing.T) {
+		// Saturdays either side of the 2025-03-09 spring-forward
+		dtstart := time.Date(2025, 3, 1, 19, 0, 0, 0, newYork)
+		got := expand(t, dtstart, "FREQ=WEEKLY;COUNT=3", nil, nil)
+		if len(got) != 3 {
+			t.Fatalf("expected 3 occurrences, got %d", len(got))
+		}
+		for _, occurrence := range got {
+			if occurrence.In(newYork).Hour() != 19 {
+				t.Errorf("expected 19:00 local, got %v", occurrence.In(newYork))
+			}
+		}
+		if got[2].Sub(got[1]) != 7*24*time.Hour-time.Hour {
+			t.Errorf("expected the DST week to be an hour short, got %v", got[2].Sub(got[1]))
+		}
+	})
+
+	t.Run("clips to the window and the limit", func(t *testing.T) {
+		dtstart := time.Date(2024, 12, 30, 8, 0, 0, 0, time.UTC)
+		rule, err := ParseRRule("FREQ=DAILY", time.UTC)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := ExpandRecurrenceSet(dtstart, &rule, nil, nil, windowStart, windowEnd, 5)
+		assertDates(t, got,
+			time.Date(2025, 1, 1, 8, 0, 0, 0, time.UTC),
+			time.Date(2025, 1, 2, 8, 0, 0, 0, time.UTC),
+			time.Date(2025, 1, 3, 8, 0, 0, 0, time.UTC),
+			time.Date(2025, 1, 4, 8, 0, 0, 0, time.UTC),
+			time.Date(2025, 1, 5, 8, 0, 0, 0, time.UTC),
+		)
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"github.com/meetnearme/api/functions/gateway/helpers"
 	"github.com/meetnearme/api/functions/gateway/types"
 	"github.com/ringsaturn/tzf"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var converter = md.NewConverter("", true, nil)
@@ -276,8 +277,10 @@ func GetHTMLFromURLWithBase(baseURL, unescapedURL string, waitMs int, jsRender b
 			continue
 		}
 
+		attemptStart := time.Now()
 		res, err := client.Do(req)
 		if err != nil {
+			RecordScrapingBeeAttempt(attempt, attemptStart, false)
 			lastErr = fmt.Errorf("ERR: executing scraping request: %v for scrapingUrl: <sanitized> baseURL: %s", err, baseURL)
 			if maxRetries > 1 {
 				log.Printf("ERR: Attempt %d for URL %s failed with error: %v", attempt, baseURL, lastErr)
@@ -290,6 +293,7 @@ func GetHTMLFromURLWithBase(baseURL, unescapedURL string, waitMs int, jsRender b
 		defer res.Body.Close()
 
 		body, err := io.ReadAll(res.Body)
+		RecordScrapingBeeAttempt(attempt, attemptStart, err == nil && res.StatusCode == 200)
 		if err != nil {
 			lastErr = fmt.Errorf("ERR: reading scraping response body: %v", err)
 			if maxRetries > 1 {
@@ -379,6 +383,7 @@ func CreateChatSession(markdownLinesAsArr string, localPrompt string) (string, s
 	if err := json.Unmarshal(body, &respData); err != nil {
 		return "", "", err
 	}
+	RecordLLMUsage(payload.Model, respData.Usage)
 
 	sessionId := respData.ID
 	if sessionId == "" {
@@ -400,7 +405,28 @@ func CreateChatSession(markdownLinesAsArr string, localPrompt string) (string, s
 	return sessionId, unpaddedJSON, nil
 }
 
-func ExtractEventsFromHTML(seshuJob types.SeshuJob, mode string, action string, scraper ScrapingService) (eventsFound []types.EventInfo, htmlContent string, err error) {
+// scrapeHTML fetches the job's page through `scraper` in a span, with
+// retries when `validate` is given
+func scrapeHTML(ctx context.Context, scraper ScrapingService, seshuJob types.SeshuJob, waitMs int, waitFor string, maxRetries int, validate ContentValidationFunc) (string, error) {
+	_, span, start := StartExternalSpan(ctx, "scrapingbee.fetch", "scrapingbee", TRACE_ATTR_JOB_URL.String(seshuJob.NormalizedUrlKey))
+	var html string
+	var err error
+	if validate != nil {
+		html, err = scraper.GetHTMLFromURLWithRetries(seshuJob, waitMs, true, waitFor, maxRetries, validate)
+	} else {
+		html, err = scraper.GetHTMLFromURL(seshuJob, waitMs, true, waitFor)
+	}
+	EndExternalSpan(span, start, err)
+	return html, err
+}
+
+func ExtractEventsFromHTML(ctx context.Context, seshuJob types.SeshuJob, mode string, action string, scraper ScrapingService) (eventsFound []types.EventInfo, htmlContent string, err error) {
+	ctx, span := StartSpan(ctx, "seshu.extract_events", TRACE_ATTR_JOB_URL.String(seshuJob.NormalizedUrlKey), attribute.String("seshu.mode", mode))
+	defer func() {
+		span.SetAttributes(TRACE_ATTR_EVENTS_SCRAPED.Int(len(eventsFound)))
+		EndSpan(span, err)
+	}()
+
 	knownScrapeSource := ""
 	isFacebook := IsFacebookEventsURL(seshuJob.NormalizedUrlKey)
 
@@ -409,7 +435,7 @@ func ExtractEventsFromHTML(seshuJob types.SeshuJob, mode string, action string,
 			return strings.Contains(content, `"__typename":"Event"`)
 		}
 
-		html, err := scraper.GetHTMLFromURLWithRetries(seshuJob, 7500, true, "script[data-sjs][data-content-len]", 7, validate)
+		html, err := scrapeHTML(ctx, scraper, seshuJob, 7500, "script[data-sjs][data-content-len]", 7, validate)
 		if err != nil {
 			log.Printf("ERR: Failed to get HTML from Facebook URL: %v", err)
 			return nil, "", err
@@ -456,7 +482,7 @@ func ExtractEventsFromHTML(seshuJob types.SeshuJob, mode string, action string,
 			}
 
 			for _, event := range childScrapeQueue {
-				childHtml, err := scraper.GetHTMLFromURLWithRetries(types.SeshuJob{NormalizedUrlKey: event.EventURL}, 7500, true, "script[data-sjs][data-content-len]", 7, validate)
+				childHtml, err := scrapeHTML(ctx, scraper, types.SeshuJob{NormalizedUrlKey: event.EventURL}, 7500, "script[data-sjs][data-content-len]", 7, validate)
 				if err != nil {
 					log.Printf("ERR: Failed to get child HTML from %s: %v", event.EventURL, err)
 					continue
@@ -510,7 +536,7 @@ func ExtractEventsFromHTML(seshuJob types.SeshuJob, mode string, action string,
 		}
 	}
 
-	html, err := scraper.GetHTMLFromURL(seshuJob, 4500, true, "")
+	html, err := scrapeHTML(ctx, scraper, seshuJob, 4500, "", 1, nil)
 	if err != nil {
 		return nil, "", err
 	}
@@ -554,7 +580,9 @@ func ExtractEventsFromHTML(seshuJob types.SeshuJob, mode string, action string,
 			return nil, "", err
 		}
 
+		_, llmSpan, llmStart := StartExternalSpan(ctx, "llm.chat_completion", "openai", attribute.Int("llm.input_lines", len(filtered)))
 		_, response, err = CreateChatSession(string(jsonPayload), localPrompt)
+		EndExternalSpan(llmSpan, llmStart, err)
 		if err != nil {
 			return nil, "", err
 		}
@@ -610,9 +638,11 @@ func FilterValidEvents(events []types.EventInfo) []types.EventInfo {
 	return validEvents
 }
 
-func PushExtractedEventsToDB(events []types.EventInfo, seshuJob types.SeshuJob, preservedEventMap map[string]string) error {
+func PushExtractedEventsToDB(ctx context.Context, events []types.EventInfo, seshuJob types.SeshuJob, preservedEventMap map[string]string) (err error) {
 	// preservedEventMap is kept for backward compatibility but not used in the new flow
 	// New events are filtered before calling this function (no longer inserting duplicates)
+	ctx, span := StartSpan(ctx, "seshu.push_events", TRACE_ATTR_JOB_URL.String(seshuJob.NormalizedUrlKey), TRACE_ATTR_EVENTS_SCRAPED.Int(len(events)))
+	defer func() { EndSpan(span, err) }()
 
 	// Handle empty events array gracefully
 	if len(events) == 0 {
@@ -620,10 +650,9 @@ func PushExtractedEventsToDB(events []types.EventInfo, seshuJob types.SeshuJob,
 		return nil
 	}
 
-	// Get Weaviate client
-	weaviateClient, err := GetWeaviateClient()
+	eventStore, err := GetEventStore()
 	if err != nil {
-		return fmt.Errorf("failed to get Weaviate client: %w", err)
+		return fmt.Errorf("failed to get event store: %w", err)
 	}
 
 	validEvents := FilterValidEvents(events)
@@ -655,7 +684,9 @@ func PushExtractedEventsToDB(events []types.EventInfo, seshuJob types.SeshuJob,
 	for i, eventInfo := range validEvents {
 
 		// Attempt to get geo coordinates and timezone from location string
+		_, geoSpan, geoStart := StartExternalSpan(ctx, "geo.lookup", "scrapingbee", attribute.String("geo.query", eventInfo.EventLocation))
 		lat, lon, address, err := GetGeo(eventInfo.EventLocation, os.Getenv("APEX_URL"))
+		EndExternalSpan(geoSpan, geoStart, err)
 		if err != nil {
 			log.Printf("ERR: Skipping event %d: GetGeo failed: %v", i, err)
 			continue
@@ -811,16 +842,31 @@ func PushExtractedEventsToDB(events []types.EventInfo, seshuJob types.SeshuJob,
 	}
 
 	log.Printf("INFO: Successfully processed %d out of %d events for %s", len(weaviateEvents), len(validEvents), seshuJob.NormalizedUrlKey)
+	span.SetAttributes(attribute.Int("seshu.events.geocoded", len(weaviateEvents)))
 
 	weaviateEventsStrict, _, err := BulkValidateEvents(weaviateEvents, false)
 	if err != nil {
 		return fmt.Errorf("failed to validate events: %w", err)
 	}
 
+	// Events breaking an error rule wait for their owner to review them
+	// rather than going live
+	postgresService, err := GetPostgresService(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get postgres service: %w", err)
+	}
+	weaviateEventsStrict, quarantined, err := QuarantineEvents(ctx, postgresService, NewEventValidator(eventStore), weaviateEvents, weaviateEventsStrict, seshuJob.NormalizedUrlKey)
+	if err != nil {
+		return fmt.Errorf("failed to quarantine events for %s: %w", seshuJob.NormalizedUrlKey, err)
+	}
+	if len(quarantined) > 0 {
+		log.Printf("INFO: Quarantined %d events for %s", len(quarantined), seshuJob.NormalizedUrlKey)
+	}
+
 	// Bulk upsert events to Weaviate
-	if len(weaviateEvents) > 0 {
-		log.Printf("Upserting %d events to Weaviate for %s", len(weaviateEvents), seshuJob.NormalizedUrlKey)
-		_, err = BulkUpsertEventsToWeaviate(context.Background(), weaviateClient, weaviateEventsStrict)
+	if len(weaviateEventsStrict) > 0 {
+		log.Printf("Upserting %d events to Weaviate for %s", len(weaviateEventsStrict), seshuJob.NormalizedUrlKey)
+		err = eventStore.BulkUpsertEvent(ctx, weaviateEventsStrict)
 		if err != nil {
 			return fmt.Errorf("failed to upsert events to Weaviate for %s: %v", seshuJob.NormalizedUrlKey, err)
 		}
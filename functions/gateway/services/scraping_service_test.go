@@ -1,6 +1,7 @@
 package services
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -424,7 +425,7 @@ func TestExtractEventsFromHTML_ScrapeMode(t *testing.T) {
 
 		ms := &mockScraper{html: "<html><body>hello</body></html>"}
 		job := types.SeshuJob{NormalizedUrlKey: "https://example.com/page"}
-		evs, _, err := ExtractEventsFromHTML(job, constants.SESHU_MODE_ONBOARD, "init", ms)
+		evs, _, err := ExtractEventsFromHTML(context.Background(), job, constants.SESHU_MODE_ONBOARD, "init", ms)
 		if err != nil {
 			t.Fatalf("unexpected err: %v", err)
 		}
@@ -450,7 +451,7 @@ func TestExtractEventsFromHTML_ScrapeMode(t *testing.T) {
 		defer func() { os.Setenv("OPENAI_API_BASE_URL", prevAI); os.Setenv("OPENAI_API_KEY", prevKey) }()
 
 		job := types.SeshuJob{NormalizedUrlKey: "https://www.facebook.com/events/1"}
-		evs, _, err := ExtractEventsFromHTML(job, constants.SESHU_MODE_SCRAPE, "init", ms)
+		evs, _, err := ExtractEventsFromHTML(context.Background(), job, constants.SESHU_MODE_SCRAPE, "init", ms)
 		if err != nil {
 			t.Fatalf("unexpected err: %v", err)
 		}
@@ -652,7 +653,7 @@ func TestExtractEventsFromHTML_FacebookListTriggersChildScrape(t *testing.T) {
 		LocationTimezone: "America/Chicago",
 	}
 
-	events, htmlContent, err := ExtractEventsFromHTML(seshuJob, constants.SESHU_MODE_SCRAPE, "", mockService)
+	events, htmlContent, err := ExtractEventsFromHTML(context.Background(), seshuJob, constants.SESHU_MODE_SCRAPE, "", mockService)
 	if err != nil {
 		t.Fatalf("Expected no error extracting Facebook events, got %v", err)
 	}
@@ -704,7 +705,7 @@ func TestExtractEventsFromHTML_FacebookOnboardSkipsChildScrapes(t *testing.T) {
 		LocationTimezone: "America/Chicago",
 	}
 
-	events, _, err := ExtractEventsFromHTML(seshuJob, constants.SESHU_MODE_ONBOARD, "", mockService)
+	events, _, err := ExtractEventsFromHTML(context.Background(), seshuJob, constants.SESHU_MODE_ONBOARD, "", mockService)
 	if err != nil {
 		t.Fatalf("Expected no error extracting Facebook events in onboard mode, got %v", err)
 	}
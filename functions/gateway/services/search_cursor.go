@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+const (
+	eventSearchCursorVersion = 1
+
+	// Without a full-text query results are ordered by `startTime` and paged by
+	// keyset, with a hybrid query Weaviate orders by fused score and we can
+	// only page by offset
+	eventSearchOrderStartTime = "start"
+	eventSearchOrderScore     = "score"
+
+	// Weaviate refuses `offset + limit` beyond QUERY_MAXIMUM_RESULTS, which
+	// defaults to 10000
+	maxEventSearchWindow = 10000
+)
+
+var ErrInvalidSearchCursor = errors.New("invalid or expired search cursor")
+
+// EventSearchPage selects one page of an event search. The zero value is the
+// first page at `constants.DEFAULT_EVENT_SEARCH_LIMIT` without facets
+type EventSearchPage struct {
+	Limit  int
+	Cursor string
+	// Facets adds `EventSearchFacets` to the response, date buckets are
+	// aligned to midnight in Timezone (UTC when nil)
+	Facets   bool
+	Timezone *time.Location
+	// Bounds searches a map viewport instead of the userLocation / maxDistance
+	// circle and raises the limit cap to `constants.MAX_MAP_SEARCH_LIMIT`
+	Bounds *MapBounds
+	// Overlap matches events running at any point in the time window rather
+	// than only those starting in it, see `eventTimeFilter`
+	Overlap bool
+}
+
+// eventSearchCursor is serialized into the opaque `cursor` handed to clients.
+// The time window is pinned from the first page so a relative window like
+// "now + 3 months" doesn't shift under a client that is paging through it
+type eventSearchCursor struct {
+	Version     int      `json:"v"`
+	Order       string   `json:"o"`
+	Fingerprint string   `json:"f"`
+	Start       int64    `json:"s,omitempty"`
+	End         int64    `json:"e,omitempty"`
+	Total       int      `json:"t,omitempty"`
+	Offset      int      `json:"n,omitempty"`
+	After       int64    `json:"a,omitempty"`
+	Seen        []string `json:"x,omitempty"`
+}
+
+// SearchAllEvents pages through every event matching the filters, for
+// lookups that have to see all of a feed's or a series' events and not only
+// the first page of them
+func SearchAllEvents(ctx context.Context, store EventStore, ownerIds, eventSourceTypes, eventSourceIds []string) ([]types.Event, error) {
+	return SearchAllEventsInWindow(ctx, store, 0, 0, ownerIds, eventSourceTypes, eventSourceIds)
+}
+
+// SearchAllEventsInWindow is `SearchAllEvents` for events starting between
+// `startTime` and `endTime`, either left at 0 leaves that side open
+func SearchAllEventsInWindow(ctx context.Context, store EventStore, startTime, endTime int64, ownerIds, eventSourceTypes, eventSourceIds []string) ([]types.Event, error) {
+	events := []types.Event{}
+	cursor := ""
+	for {
+		res, err := store.SearchEventsPage(ctx, "", nil, 0, startTime, endTime, ownerIds, "", "", "",
+			eventSourceTypes, eventSourceIds, EventSearchPage{Limit: constants.MAX_EVENT_SEARCH_LIMIT, Cursor: cursor})
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, res.Events...)
+		if !res.HasMore || res.NextCursor == "" {
+			return events, nil
+		}
+		cursor = res.NextCursor
+	}
+}
+
+// ClampEventSearchLimit maps a client supplied `limit` onto the range the
+// search endpoints are willing to serve
+func ClampEventSearchLimit(limit int) int {
+	if limit <= 0 {
+		return constants.DEFAULT_EVENT_SEARCH_LIMIT
+	}
+	if limit > constants.MAX_EVENT_SEARCH_LIMIT {
+		return constants.MAX_EVENT_SEARCH_LIMIT
+	}
+	return limit
+}
+
+func encodeEventSearchCursor(cursor eventSearchCursor) string {
+	cursor.Version = eventSearchCursorVersion
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeEventSearchCursor returns nil for an empty cursor (first page)
+func decodeEventSearchCursor(raw string) (*eventSearchCursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+	var cursor eventSearchCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidSearchCursor
+	}
+	if cursor.Version != eventSearchCursorVersion || cursor.Offset < 0 {
+		return nil, ErrInvalidSearchCursor
+	}
+	if cursor.Order != eventSearchOrderStartTime && cursor.Order != eventSearchOrderScore {
+		return nil, ErrInvalidSearchCursor
+	}
+	return &cursor, nil
+}
+
+// eventSearchFingerprint ties a cursor to the search that produced it so it
+// can't be replayed against different filters. The time window is left out
+// on purpose, it travels inside the cursor instead
+func eventSearchFingerprint(order, hybridQuery string, userLocation []float64, maxDistance float64, bounds *MapBounds, overlap bool, ownerIds, eventSourceTypes, eventSourceIds []string) string {
+	sortedCopy := func(values []string) string {
+		copied := append([]string{}, values...)
+		sort.Strings(copied)
+		return strings.Join(copied, ",")
+	}
+	area := fmt.Sprintf("%v|%g", userLocation, maxDistance)
+	if bounds != nil {
+		area = "bbox|" + bounds.String()
+	}
+	if overlap {
+		area += "|overlap"
+	}
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%s|%s|%s|%s|%s|%s",
+		order,
+		hybridQuery,
+		area,
+		sortedCopy(ownerIds),
+		sortedCopy(eventSourceTypes),
+		sortedCopy(eventSourceIds),
+	)
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// nextEventSearchCursor builds the cursor that continues after `events`, the
+// page just served out of `hits` raw results. `prev` is the cursor that page
+// was fetched with (nil on the first page)
+func nextEventSearchCursor(prev *eventSearchCursor, base eventSearchCursor, hits int, events []types.Event) string {
+	next := base
+	switch base.Order {
+	case eventSearchOrderScore:
+		offset := 0
+		if prev != nil {
+			offset = prev.Offset
+		}
+		next.Offset = offset + hits
+	default:
+		if len(events) == 0 {
+			return ""
+		}
+		// Many events share a start time (recurring series, imported feeds), so
+		// the keyset is the last start time plus every id already served at it
+		next.After = events[len(events)-1].StartTime
+		next.Seen = []string{}
+		if prev != nil && prev.After == next.After {
+			next.Seen = append(next.Seen, prev.Seen...)
+		}
+		for _, event := range events {
+			if event.StartTime == next.After {
+				next.Seen = append(next.Seen, event.Id)
+			}
+		}
+	}
+	return encodeEventSearchCursor(next)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+func TestClampEventSearchLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{"zero uses default", 0, constants.DEFAULT_EVENT_SEARCH_LIMIT},
+		{"negative uses default", -5, constants.DEFAULT_EVENT_SEARCH_LIMIT},
+		{"in range is kept", 25, 25},
+		{"above max is capped", constants.MAX_EVENT_SEARCH_LIMIT + 1, constants.MAX_EVENT_SEARCH_LIMIT},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClampEventSearchLimit(tt.limit); got != tt.want {
+				t.Errorf("ClampEventSearchLimit(%d) = %d, want %d", tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeEventSearchCursor(t *testing.T) {
+	original := eventSearchCursor{
+		Order:       eventSearchOrderStartTime,
+		Fingerprint: "abc",
+		Start:       1700000000,
+		End:         1710000000,
+		Total:       42,
+		After:       1705000000,
+		Seen:        []string{"a", "b"},
+	}
+	decoded, err := decodeEventSearchCursor(encodeEventSearchCursor(original))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	original.Version = eventSearchCursorVersion
+	if !reflect.DeepEqual(*decoded, original) {
+		t.Errorf("round trip mismatch: got %+v, want %+v", *decoded, original)
+	}
+
+	if cursor, err := decodeEventSearchCursor(""); cursor != nil || err != nil {
+		t.Errorf("empty cursor should decode to nil, nil; got %v, %v", cursor, err)
+	}
+
+	invalid := []string{
+		"%%%",
+		"bm90IGpzb24",
+		encodeEventSearchCursor(eventSearchCursor{Order: "bogus"}),
+		encodeEventSearchCursor(eventSearchCursor{Order: eventSearchOrderScore, Offset: -1}),
+	}
+	for _, raw := range invalid {
+		if _, err := decodeEventSearchCursor(raw); !errors.Is(err, ErrInvalidSearchCursor) {
+			t.Errorf("decodeEventSearchCursor(%q) error = %v, want ErrInvalidSearchCursor", raw, err)
+		}
+	}
+}
+
+func TestEventSearchFingerprint(t *testing.T) {
+	location := []float64{40.7, -74.0}
+	base := eventSearchFingerprint(eventSearchOrderScore, "jazz", location, 50, nil, false, []string{"o1", "o2"}, nil, nil)
+
+	if got := eventSearchFingerprint(eventSearchOrderScore, "jazz", location, 50, nil, false, []string{"o2", "o1"}, nil, nil); got != base {
+		t.Errorf("owner order should not change the fingerprint")
+	}
+	if got := eventSearchFingerprint(eventSearchOrderScore, "blues", location, 50, nil, false, []string{"o1", "o2"}, nil, nil); got == base {
+		t.Errorf("a different query should change the fingerprint")
+	}
+	if got := eventSearchFingerprint(eventSearchOrderScore, "jazz", location, 100, nil, false, []string{"o1", "o2"}, nil, nil); got == base {
+		t.Errorf("a different radius should change the fingerprint")
+	}
+	bounds := NewMapBounds(-75, 40, -73, 41)
+	if got := eventSearchFingerprint(eventSearchOrderScore, "jazz", location, 50, &bounds, false, []string{"o1", "o2"}, nil, nil); got == base {
+		t.Errorf("a map viewport should change the fingerprint")
+	}
+	if got := eventSearchFingerprint(eventSearchOrderScore, "jazz", location, 50, nil, true, []string{"o1", "o2"}, nil, nil); got == base {
+		t.Errorf("overlap mode should change the fingerprint")
+	}
+}
+
+func TestNextEventSearchCursor(t *testing.T) {
+	base := eventSearchCursor{Fingerprint: "f", Start: 100, End: 900, Total: 10}
+
+	t.Run("score order advances the offset", func(t *testing.T) {
+		scoreBase := base
+		scoreBase.Order = eventSearchOrderScore
+		prev := scoreBase
+		prev.Offset = 20
+		events := []types.Event{{Id: "a"}, {Id: "b"}, {Id: "c"}}
+
+		next, err := decodeEventSearchCursor(nextEventSearchCursor(&prev, scoreBase, len(events), events))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if next.Offset != 23 {
+			t.Errorf("Offset = %d, want 23", next.Offset)
+		}
+		if next.Start != 100 || next.End != 900 || next.Total != 10 {
+			t.Errorf("window and total should carry over, got %+v", next)
+		}
+	})
+
+	t.Run("start time order keys on the last start time", func(t *testing.T) {
+		timeBase := base
+		timeBase.Order = eventSearchOrderStartTime
+		events := []types.Event{
+			{Id: "a", StartTime: 200},
+			{Id: "b", StartTime: 300},
+			{Id: "c", StartTime: 300},
+		}
+
+		next, err := decodeEventSearchCursor(nextEventSearchCursor(nil, timeBase, len(events), events))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if next.After != 300 {
+			t.Errorf("After = %d, want 300", next.After)
+		}
+		if !reflect.DeepEqual(next.Seen, []string{"b", "c"}) {
+			t.Errorf("Seen = %v, want [b c]", next.Seen)
+		}
+
+		// A page made entirely of the same start time must keep excluding the
+		// ids served on earlier pages or it would loop forever
+		sameTime := []types.Event{{Id: "d", StartTime: 300}, {Id: "e", StartTime: 300}}
+		after, err := decodeEventSearchCursor(nextEventSearchCursor(next, timeBase, len(sameTime), sameTime))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !reflect.DeepEqual(after.Seen, []string{"b", "c", "d", "e"}) {
+			t.Errorf("Seen = %v, want [b c d e]", after.Seen)
+		}
+
+		later := []types.Event{{Id: "f", StartTime: 400}}
+		moved, err := decodeEventSearchCursor(nextEventSearchCursor(after, timeBase, len(later), later))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if moved.After != 400 || !reflect.DeepEqual(moved.Seen, []string{"f"}) {
+			t.Errorf("moving past the shared start time should reset Seen, got After=%d Seen=%v", moved.After, moved.Seen)
+		}
+	})
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/types"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+const (
+	EventFacetBucketDay  = "day"
+	EventFacetBucketWeek = "week"
+
+	// Windows up to two weeks are bucketed by day, anything longer by week
+	eventFacetDayBucketMaxSpan = 14 * 24 * time.Hour
+	maxEventFacetDateBuckets   = 53
+	maxEventFacetOwners        = 25
+)
+
+// eventFacetDateBuckets splits the search window into day or week buckets
+// aligned to local midnight in `loc`, weeks start on Monday. An open ended
+// window has no buckets
+func eventFacetDateBuckets(start, end int64, loc *time.Location) (string, []types.EventDateBucket) {
+	if start <= 0 || end <= 0 || end < start {
+		return "", nil
+	}
+	if loc == nil {
+		loc = time.UTC
+	}
+	windowStart := time.Unix(start, 0).In(loc)
+	windowEnd := time.Unix(end, 0).In(loc)
+
+	granularity, step := EventFacetBucketDay, 1
+	if windowEnd.Sub(windowStart) > eventFacetDayBucketMaxSpan {
+		granularity, step = EventFacetBucketWeek, 7
+	}
+
+	bucketStart := time.Date(windowStart.Year(), windowStart.Month(), windowStart.Day(), 0, 0, 0, 0, loc)
+	if granularity == EventFacetBucketWeek {
+		bucketStart = bucketStart.AddDate(0, 0, -((int(bucketStart.Weekday()) + 6) % 7))
+	}
+
+	buckets := []types.EventDateBucket{}
+	for !bucketStart.After(windowEnd) && len(buckets) < maxEventFacetDateBuckets {
+		// AddDate keeps buckets on local midnight across DST changes
+		bucketEnd := bucketStart.AddDate(0, 0, step)
+		buckets = append(buckets, types.EventDateBucket{
+			Label: bucketStart.Format("2006-01-02"),
+			Start: bucketStart.Unix(),
+			End:   bucketEnd.Unix(),
+		})
+		bucketStart = bucketEnd
+	}
+	return granularity, buckets
+}
+
+// categoryFacetNames is everything an event may be tagged with to count
+// toward `category`, the category itself and each of its subcategories
+func categoryFacetNames(category constants.Category) []string {
+	names := []string{category.Name}
+	for _, item := range category.Items {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+// eventFacetFilter is one facet value, counted as the events matching the
+// search's own filters narrowed by `filters`
+type eventFacetFilter struct {
+	alias   string
+	filters []*filters.WhereBuilder
+}
+
+// eventFacetFilters lists every facet value counted for a search. The "all"
+// alias carries no extra filters, it counts the whole search and its owners.
+// With `overlap` an event counts toward every date bucket it runs through,
+// not just the one it starts in
+func eventFacetFilters(buckets []types.EventDateBucket, overlap bool) []eventFacetFilter {
+	facetFilters := []eventFacetFilter{
+		{alias: "all"},
+		{alias: "paid", filters: []*filters.WhereBuilder{
+			(&filters.WhereBuilder{}).WithPath([]string{"hasPurchasable"}).WithOperator(filters.Equal).WithValueBoolean(true),
+			(&filters.WhereBuilder{}).WithPath([]string{"startingPrice"}).WithOperator(filters.GreaterThan).WithValueNumber(0),
+		}},
+	}
+	for i, category := range constants.Categories {
+		facetFilters = append(facetFilters, eventFacetFilter{alias: fmt.Sprintf("category%d", i), filters: []*filters.WhereBuilder{
+			(&filters.WhereBuilder{}).WithPath([]string{"categories"}).WithOperator(filters.ContainsAny).WithValueText(categoryFacetNames(category)...),
+		}})
+	}
+	for i, bucket := range buckets {
+		bucketFilter := []*filters.WhereBuilder{
+			(&filters.WhereBuilder{}).WithPath([]string{"startTime"}).WithOperator(filters.GreaterThanEqual).WithValueInt(bucket.Start),
+			(&filters.WhereBuilder{}).WithPath([]string{"startTime"}).WithOperator(filters.LessThan).WithValueInt(bucket.End),
+		}
+		if overlap {
+			bucketFilter = []*filters.WhereBuilder{eventTimeFilter(bucket.Start, bucket.End-1, true)}
+		}
+		facetFilters = append(facetFilters, eventFacetFilter{alias: fmt.Sprintf("date%d", i), filters: bucketFilter})
+	}
+	return facetFilters
+}
+
+// buildEventFacetQuery batches every facet count into a single aliased
+// Aggregate query. Each alias narrows the search's own filters (`base`) by
+// one facet value
+func buildEventFacetQuery(base []*filters.WhereBuilder, buckets []types.EventDateBucket, overlap bool) string {
+	className := EventClassName()
+	var query strings.Builder
+	query.WriteString("{Aggregate{")
+
+	for _, facet := range eventFacetFilters(buckets, overlap) {
+		operands := append(append([]*filters.WhereBuilder{}, base...), facet.filters...)
+		where := ""
+		if len(operands) > 0 {
+			where = "(" + (&filters.WhereBuilder{}).WithOperator(filters.And).WithOperands(operands).String() + ")"
+		}
+		fields := "meta{count}"
+		if facet.alias == "all" {
+			fields = fmt.Sprintf("meta{count} eventOwners{topOccurrences(limit:%d){value occurs}}", maxEventFacetOwners)
+		}
+		fmt.Fprintf(&query, "%s:%s%s{%s} ", facet.alias, className, where, fields)
+	}
+
+	query.WriteString("}}")
+	return query.String()
+}
+
+// aggregateEntry unwraps the single result row Weaviate returns for an
+// ungrouped Aggregate
+func aggregateEntry(value interface{}) (map[string]interface{}, bool) {
+	rows, ok := value.([]interface{})
+	if !ok || len(rows) == 0 {
+		return nil, false
+	}
+	entry, ok := rows[0].(map[string]interface{})
+	return entry, ok
+}
+
+func aggregateMetaCount(value interface{}) (int, bool) {
+	entry, ok := aggregateEntry(value)
+	if !ok {
+		return 0, false
+	}
+	meta, _ := entry["meta"].(map[string]interface{})
+	count, ok := meta["count"].(float64)
+	return int(count), ok
+}
+
+func parseEventFacetResponse(data map[string]models.JSONObject, granularity string, buckets []types.EventDateBucket) (*types.EventSearchFacets, error) {
+	aggregateMap, ok := data["Aggregate"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("facet response missing Aggregate field")
+	}
+	count := func(alias string) (int, error) {
+		value, ok := aggregateMetaCount(aggregateMap[alias])
+		if !ok {
+			return 0, fmt.Errorf("facet response missing %s count", alias)
+		}
+		return value, nil
+	}
+
+	owners := []types.EventFacetCount{}
+	if entry, ok := aggregateEntry(aggregateMap["all"]); ok {
+		ownersMap, _ := entry["eventOwners"].(map[string]interface{})
+		occurrences, _ := ownersMap["topOccurrences"].([]interface{})
+		for _, occurrence := range occurrences {
+			occurrenceMap, ok := occurrence.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			value, _ := occurrenceMap["value"].(string)
+			occurs, _ := occurrenceMap["occurs"].(float64)
+			if value == "" {
+				continue
+			}
+			owners = append(owners, types.EventFacetCount{Key: value, Count: int(occurs)})
+		}
+	}
+
+	return newEventFacets(count, owners, granularity, buckets)
+}
+
+// newEventFacets assembles the facets out of the count of each alias from
+// `eventFacetFilters` and the top owners of the search
+func newEventFacets(count func(alias string) (int, error), owners []types.EventFacetCount, granularity string, buckets []types.EventDateBucket) (*types.EventSearchFacets, error) {
+	all, err := count("all")
+	if err != nil {
+		return nil, err
+	}
+	paid, err := count("paid")
+	if err != nil {
+		return nil, err
+	}
+
+	facets := &types.EventSearchFacets{
+		Categories: []types.EventFacetCount{},
+		Owners:     owners,
+		DateBucket: granularity,
+		Dates:      []types.EventDateBucket{},
+		Price:      types.EventPriceFacet{Free: all - paid, Paid: paid},
+	}
+
+	for i, category := range constants.Categories {
+		categoryCount, err := count(fmt.Sprintf("category%d", i))
+		if err != nil {
+			return nil, err
+		}
+		facets.Categories = append(facets.Categories, types.EventFacetCount{
+			Key:   category.Slug,
+			Label: category.Name,
+			Count: categoryCount,
+		})
+	}
+
+	for i, bucket := range buckets {
+		bucket.Count, err = count(fmt.Sprintf("date%d", i))
+		if err != nil {
+			return nil, err
+		}
+		facets.Dates = append(facets.Dates, bucket)
+	}
+
+	return facets, nil
+}
+
+// searchWeaviateEventFacets counts the facets of a search in one round trip.
+// Aggregate can't run a hybrid query, so like the search total the counts
+// cover the structured filters and ignore the text query
+func searchWeaviateEventFacets(ctx context.Context, client *weaviate.Client, base []*filters.WhereBuilder, start, end int64, loc *time.Location, overlap bool) (*types.EventSearchFacets, error) {
+	granularity, buckets := eventFacetDateBuckets(start, end, loc)
+
+	result, err := client.GraphQL().Raw().WithQuery(buildEventFacetQuery(base, buckets, overlap)).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("facet query failed: %s", result.Errors[0].Message)
+	}
+	return parseEventFacetResponse(result.Data, granularity, buckets)
+}
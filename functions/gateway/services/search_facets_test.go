@@ -0,0 +1,157 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/types"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+func TestEventFacetDateBuckets(t *testing.T) {
+	denver, err := time.LoadLocation("America/Denver")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+
+	t.Run("short window is bucketed by local day", func(t *testing.T) {
+		start := time.Date(2025, 3, 7, 15, 30, 0, 0, denver)
+		end := time.Date(2025, 3, 10, 9, 0, 0, 0, denver)
+
+		granularity, buckets := eventFacetDateBuckets(start.Unix(), end.Unix(), denver)
+		if granularity != EventFacetBucketDay {
+			t.Fatalf("granularity = %q, want %q", granularity, EventFacetBucketDay)
+		}
+		labels := []string{}
+		for _, bucket := range buckets {
+			labels = append(labels, bucket.Label)
+		}
+		if got := strings.Join(labels, ","); got != "2025-03-07,2025-03-08,2025-03-09,2025-03-10" {
+			t.Errorf("labels = %s", got)
+		}
+		if buckets[0].Start != time.Date(2025, 3, 7, 0, 0, 0, 0, denver).Unix() {
+			t.Errorf("first bucket should start at local midnight, got %v", time.Unix(buckets[0].Start, 0).In(denver))
+		}
+		// DST starts on Mar 9, that day is 23 hours long
+		if hours := (buckets[2].End - buckets[2].Start) / 3600; hours != 23 {
+			t.Errorf("DST day bucket spans %d hours, want 23", hours)
+		}
+		for i := 1; i < len(buckets); i++ {
+			if buckets[i].Start != buckets[i-1].End {
+				t.Errorf("bucket %d does not start where bucket %d ends", i, i-1)
+			}
+		}
+	})
+
+	t.Run("long window is bucketed by week starting Monday", func(t *testing.T) {
+		start := time.Date(2025, 6, 5, 12, 0, 0, 0, time.UTC) // a Thursday
+		end := start.AddDate(0, 1, 0)
+
+		granularity, buckets := eventFacetDateBuckets(start.Unix(), end.Unix(), nil)
+		if granularity != EventFacetBucketWeek {
+			t.Fatalf("granularity = %q, want %q", granularity, EventFacetBucketWeek)
+		}
+		if buckets[0].Label != "2025-06-02" {
+			t.Errorf("first week = %s, want 2025-06-02", buckets[0].Label)
+		}
+		if len(buckets) != 5 {
+			t.Errorf("got %d weekly buckets, want 5", len(buckets))
+		}
+	})
+
+	t.Run("bucket count is capped", func(t *testing.T) {
+		start := time.Date(1971, 1, 1, 0, 0, 0, 0, time.UTC)
+		end := time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)
+		if _, buckets := eventFacetDateBuckets(start.Unix(), end.Unix(), nil); len(buckets) != maxEventFacetDateBuckets {
+			t.Errorf("got %d buckets, want %d", len(buckets), maxEventFacetDateBuckets)
+		}
+	})
+
+	t.Run("open window has no buckets", func(t *testing.T) {
+		if granularity, buckets := eventFacetDateBuckets(0, 1700000000, nil); granularity != "" || buckets != nil {
+			t.Errorf("expected no buckets, got %q %v", granularity, buckets)
+		}
+	})
+}
+
+func TestBuildEventFacetQuery(t *testing.T) {
+	base := []*filters.WhereBuilder{
+		(&filters.WhereBuilder{}).WithPath([]string{"eventSourceType"}).WithOperator(filters.ContainsAny).WithValueText(constants.ES_SINGLE_EVENT),
+	}
+	buckets := []types.EventDateBucket{{Label: "2025-03-07", Start: 100, End: 200}}
+
+	query := buildEventFacetQuery(base, buckets, false)
+
+	for _, want := range []string{
+		"all:" + EventClassName() + "(where:",
+		"paid:" + EventClassName() + "(where:",
+		"topOccurrences(limit:",
+		fmt.Sprintf("category%d:", len(constants.Categories)-1),
+		`"Civic & Advocacy"`,
+		"date0:" + EventClassName() + "(where:",
+		"valueInt: 200",
+	} {
+		if !strings.Contains(query, want) {
+			t.Errorf("expected facet query to contain %q\n%s", want, query)
+		}
+	}
+	// every alias keeps the search's own filters
+	if got, want := strings.Count(query, `"eventSourceType"`), 3+len(constants.Categories); got != want {
+		t.Errorf("base filter appears %d times, want %d", got, want)
+	}
+
+	// in overlap mode a date bucket counts events running through it
+	overlapQuery := buildEventFacetQuery(base, buckets, true)
+	if !strings.Contains(overlapQuery, `path: ["endTime"] valueInt: 100`) || !strings.Contains(overlapQuery, `path: ["startTime"] valueInt: 199`) {
+		t.Errorf("expected overlapping date buckets\n%s", overlapQuery)
+	}
+}
+
+func TestParseEventFacetResponse(t *testing.T) {
+	count := func(n int) []interface{} {
+		return []interface{}{map[string]interface{}{"meta": map[string]interface{}{"count": float64(n)}}}
+	}
+	aggregate := map[string]interface{}{
+		"all": []interface{}{map[string]interface{}{
+			"meta": map[string]interface{}{"count": float64(10)},
+			"eventOwners": map[string]interface{}{
+				"topOccurrences": []interface{}{
+					map[string]interface{}{"value": "owner-1", "occurs": float64(6)},
+					map[string]interface{}{"value": "owner-2", "occurs": float64(4)},
+				},
+			},
+		}},
+		"paid":  count(3),
+		"date0": count(7),
+	}
+	for i := range constants.Categories {
+		aggregate[fmt.Sprintf("category%d", i)] = count(i)
+	}
+	buckets := []types.EventDateBucket{{Label: "2025-03-07", Start: 100, End: 200}}
+
+	facets, err := parseEventFacetResponse(map[string]models.JSONObject{"Aggregate": aggregate}, EventFacetBucketDay, buckets)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if facets.Price.Free != 7 || facets.Price.Paid != 3 {
+		t.Errorf("price = %+v, want free 7 paid 3", facets.Price)
+	}
+	if len(facets.Categories) != len(constants.Categories) || facets.Categories[1].Key != constants.Categories[1].Slug || facets.Categories[1].Count != 1 {
+		t.Errorf("unexpected categories: %+v", facets.Categories)
+	}
+	if len(facets.Owners) != 2 || facets.Owners[0].Key != "owner-1" || facets.Owners[0].Count != 6 {
+		t.Errorf("unexpected owners: %+v", facets.Owners)
+	}
+	if len(facets.Dates) != 1 || facets.Dates[0].Count != 7 || facets.DateBucket != EventFacetBucketDay {
+		t.Errorf("unexpected dates: %+v", facets.Dates)
+	}
+
+	delete(aggregate, "paid")
+	if _, err := parseEventFacetResponse(map[string]models.JSONObject{"Aggregate": aggregate}, EventFacetBucketDay, buckets); err == nil {
+		t.Errorf("expected an error when a facet count is missing")
+	}
+}
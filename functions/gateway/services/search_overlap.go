@@ -0,0 +1,72 @@
+package services
+
+import (
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/types"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
+)
+
+// eventTimeFilter narrows a search to the `start` / `end` window, either side
+// may be 0 for an open window. By default an event matches when it starts in
+// the window. With `overlap` it matches when `[startTime, endTime]` intersects
+// the window, so a festival that started yesterday still shows up today.
+//
+// `endTime` is only stored when an event has one and Weaviate can't filter on
+// a missing property, so events without an end (or with
+// `DEFAULT_UNDEFINED_END_TIME`) are matched by assuming they last
+// `OPEN_ENDED_EVENT_DURATION`. That branch can't tell them apart from events
+// that have an end, callers drop the few that already ended with
+// `eventOverlapsWindow`
+func eventTimeFilter(start, end int64, overlap bool) *filters.WhereBuilder {
+	operands := []*filters.WhereBuilder{}
+	if overlap {
+		if end > 0 {
+			operands = append(operands, (&filters.WhereBuilder{}).WithPath([]string{"startTime"}).WithOperator(filters.LessThanEqual).WithValueInt(end))
+		}
+		if start > 0 {
+			operands = append(operands, (&filters.WhereBuilder{}).
+				WithOperator(filters.Or).
+				WithOperands([]*filters.WhereBuilder{
+					(&filters.WhereBuilder{}).
+						WithOperator(filters.And).
+						WithOperands([]*filters.WhereBuilder{
+							(&filters.WhereBuilder{}).WithPath([]string{"endTime"}).WithOperator(filters.GreaterThanEqual).WithValueInt(start),
+							(&filters.WhereBuilder{}).WithPath([]string{"endTime"}).WithOperator(filters.LessThan).WithValueInt(constants.DEFAULT_UNDEFINED_END_TIME),
+						}),
+					(&filters.WhereBuilder{}).WithPath([]string{"startTime"}).WithOperator(filters.GreaterThanEqual).WithValueInt(start - constants.OPEN_ENDED_EVENT_DURATION),
+				}))
+		}
+	} else {
+		if start > 0 {
+			operands = append(operands, (&filters.WhereBuilder{}).WithPath([]string{"startTime"}).WithOperator(filters.GreaterThanEqual).WithValueInt(start))
+		}
+		if end > 0 {
+			operands = append(operands, (&filters.WhereBuilder{}).WithPath([]string{"startTime"}).WithOperator(filters.LessThanEqual).WithValueInt(end))
+		}
+	}
+
+	switch len(operands) {
+	case 0:
+		return nil
+	case 1:
+		return operands[0]
+	default:
+		return (&filters.WhereBuilder{}).WithOperator(filters.And).WithOperands(operands)
+	}
+}
+
+// eventOverlapsWindow is the overlap test of `eventTimeFilter` applied to an
+// event already fetched
+func eventOverlapsWindow(event types.Event, start, end int64) bool {
+	if end > 0 && event.StartTime > end {
+		return false
+	}
+	if start <= 0 {
+		return true
+	}
+	eventEnd := event.EndTime
+	if eventEnd == 0 || eventEnd == constants.DEFAULT_UNDEFINED_END_TIME {
+		eventEnd = event.StartTime + constants.OPEN_ENDED_EVENT_DURATION
+	}
+	return eventEnd >= start
+}
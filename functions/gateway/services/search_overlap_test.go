@@ -0,0 +1,58 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+func TestEventTimeFilter(t *testing.T) {
+	if filter := eventTimeFilter(0, 0, true); filter != nil {
+		t.Errorf("expected no filter for an open window, got %s", filter.String())
+	}
+
+	startsIn := eventTimeFilter(1000, 2000, false).String()
+	if strings.Contains(startsIn, `"endTime"`) || !strings.Contains(startsIn, "valueInt: 1000") || !strings.Contains(startsIn, "valueInt: 2000") {
+		t.Errorf("expected a startTime only window, got %s", startsIn)
+	}
+
+	overlap := eventTimeFilter(100000, 200000, true).String()
+	for _, want := range []string{
+		`path: ["startTime"] valueInt: 200000`,
+		`path: ["endTime"] valueInt: 100000`,
+		`valueInt: 9223372036854775807`,
+		`path: ["startTime"] valueInt: 89200`,
+	} {
+		if !strings.Contains(overlap, want) {
+			t.Errorf("expected overlap filter to contain %q\n%s", want, overlap)
+		}
+	}
+}
+
+func TestEventOverlapsWindow(t *testing.T) {
+	const start, end = 100000, 200000
+	tests := []struct {
+		name      string
+		startTime int64
+		endTime   int64
+		want      bool
+	}{
+		{name: "starts in the window", startTime: 150000, endTime: 160000, want: true},
+		{name: "started before, still running", startTime: 50000, endTime: 150000, want: true},
+		{name: "ended before the window", startTime: 95000, endTime: 99000, want: false},
+		{name: "starts after the window", startTime: 250000, endTime: 260000, want: false},
+		{name: "no end, started recently", startTime: start - constants.OPEN_ENDED_EVENT_DURATION + 1, want: true},
+		{name: "no end, started long ago", startTime: start - constants.OPEN_ENDED_EVENT_DURATION - 1, want: false},
+		{name: "undefined end, started recently", startTime: start - 60, endTime: constants.DEFAULT_UNDEFINED_END_TIME, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			event := types.Event{StartTime: tt.startTime, EndTime: tt.endTime}
+			if got := eventOverlapsWindow(event, start, end); got != tt.want {
+				t.Errorf("eventOverlapsWindow() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
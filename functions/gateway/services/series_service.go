@@ -0,0 +1,490 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+const (
+	// How far ahead children of a recurring series are materialized, the
+	// series loop re-runs the sync so the window keeps rolling forward
+	SeriesMaterializationHorizon = 90 * 24 * time.Hour
+
+	// maxMaterializedSeriesChildren bounds the writes of one sync. Daily rules
+	// stay well under it over the horizon, only a long RDATE list reaches it
+	maxMaterializedSeriesChildren = 250
+)
+
+// SeriesPlan is the set of writes that brings a series' stored children in
+// line with its recurrence rule
+type SeriesPlan struct {
+	Parent        types.Event
+	ParentChanged bool
+	Upserts       []types.Event
+	Deletes       []string
+	Kept          int
+	// Truncated is set when occurrences within the horizon were left out for
+	// `maxMaterializedSeriesChildren`
+	Truncated bool
+}
+
+type SeriesSyncResult struct {
+	ParentId string `json:"parentId"`
+	Upserted int    `json:"upserted"`
+	Deleted  int    `json:"deleted"`
+	Kept     int    `json:"kept"`
+	// Truncated is set when occurrences within the horizon weren't
+	// materialized, see `SeriesPlan`
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// IsRecurringSeriesParent reports whether the server is responsible for
+// materializing the event's children from a recurrence rule
+func IsRecurringSeriesParent(event types.Event) bool {
+	return event.RecurrenceRule != "" &&
+		(event.EventSourceType == constants.ES_SERIES_PARENT || event.EventSourceType == constants.ES_SERIES_PARENT_UNPUB)
+}
+
+// SeriesOccurrenceId derives a stable child id from the parent and the
+// occurrence's originally scheduled start, so re-syncing never duplicates
+func SeriesOccurrenceId(parentId string, recurrenceId int64) string {
+	return uuid.NewSHA1(uuid.NameSpaceURL, []byte(fmt.Sprintf("series|%s|%d", parentId, recurrenceId))).String()
+}
+
+func seriesChildEventSourceType(parentEventSourceType string) string {
+	if parentEventSourceType == constants.ES_SERIES_PARENT_UNPUB {
+		return constants.ES_EVENT_SERIES_UNPUB
+	}
+	return constants.ES_EVENT_SERIES
+}
+
+// SeriesOccurrences expands the parent's recurrence set (RRULE + RDATE -
+// EXDATE) within [windowStart, windowEnd] in the series' own timezone
+func SeriesOccurrences(parent types.Event, windowStart, windowEnd time.Time, limit int) ([]time.Time, error) {
+	loc := &parent.Timezone
+	anchor := parent.RecurrenceStart
+	if anchor == 0 {
+		anchor = parent.StartTime
+	}
+	dtstart := time.Unix(anchor, 0).In(loc)
+
+	rule, err := ParseRRule(parent.RecurrenceRule, loc)
+	if err != nil {
+		return nil, fmt.Errorf("invalid recurrence rule for series %s: %w", parent.Id, err)
+	}
+
+	rdates := make([]time.Time, 0, len(parent.RecurrenceRDates))
+	for _, rdate := range parent.RecurrenceRDates {
+		rdates = append(rdates, time.Unix(rdate, 0).In(loc))
+	}
+	exdates := make([]time.Time, 0, len(parent.RecurrenceExDates))
+	for _, exdate := range parent.RecurrenceExDates {
+		exdates = append(exdates, time.Unix(exdate, 0).In(loc))
+	}
+
+	return ExpandRecurrenceSet(dtstart, &rule, rdates, exdates, windowStart, windowEnd, limit), nil
+}
+
+func seriesDuration(parent types.Event) int64 {
+	if parent.EndTime == 0 || parent.EndTime == constants.DEFAULT_UNDEFINED_END_TIME || parent.EndTime <= parent.StartTime {
+		return 0
+	}
+	return parent.EndTime - parent.StartTime
+}
+
+// NewSeriesChild copies the parent into the occurrence scheduled at
+// `recurrenceId`
+func NewSeriesChild(parent types.Event, id string, recurrenceId int64) types.Event {
+	child := parent
+	child.Id = id
+	child.EventSourceType = seriesChildEventSourceType(parent.EventSourceType)
+	child.EventSourceId = parent.Id
+	child.StartTime = recurrenceId
+	child.EndTime = 0
+	if duration := seriesDuration(parent); duration > 0 {
+		child.EndTime = recurrenceId + duration
+	}
+	child.RecurrenceRule = ""
+	child.RecurrenceStart = 0
+	child.RecurrenceRDates = nil
+	child.RecurrenceExDates = nil
+	child.RecurrenceId = recurrenceId
+	child.RecurrenceOverride = false
+	child.RefUrl = ""
+	child.CreatedAt = 0
+	child.UpdatedAt = 0
+	child.LocalizedStartDate = ""
+	child.LocalizedStartTime = ""
+	return child
+}
+
+// seriesChildDiffers compares the fields a sync is allowed to write, so
+// unchanged children aren't rewritten (which would bump their revision)
+func seriesChildDiffers(a, b types.Event) bool {
+	return a.Name != b.Name ||
+		a.Description != b.Description ||
+		a.StartTime != b.StartTime ||
+		a.EndTime != b.EndTime ||
+		a.Address != b.Address ||
+		a.Lat != b.Lat ||
+		a.Long != b.Long ||
+		a.Timezone.String() != b.Timezone.String() ||
+		a.EventSourceType != b.EventSourceType ||
+		a.EventSourceId != b.EventSourceId ||
+		a.EventOwnerName != b.EventOwnerName ||
+		a.ImageUrl != b.ImageUrl ||
+		a.SourceUrl != b.SourceUrl ||
+		a.StartingPrice != b.StartingPrice ||
+		a.Currency != b.Currency ||
+		a.PayeeId != b.PayeeId ||
+		a.HasRegistrationFields != b.HasRegistrationFields ||
+		a.HasPurchasable != b.HasPurchasable ||
+		a.HideCrossPromo != b.HideCrossPromo ||
+		a.CompetitionConfigId != b.CompetitionConfigId ||
+		a.RecurrenceId != b.RecurrenceId ||
+		!slices.Equal(a.EventOwners, b.EventOwners) ||
+		!slices.Equal(a.Categories, b.Categories) ||
+		!slices.Equal(a.Tags, b.Tags)
+}
+
+// PlanSeriesChildren diffs the upcoming children of a recurring series
+// against its rule. Occurrences between `now` and `horizon` are created or
+// refreshed from the parent, overridden occurrences are left alone while
+// their slot still exists, and upcoming children whose slot is gone (rule
+// edited, EXDATE added) are deleted. Past children are never touched. The
+// parent's StartTime / EndTime roll forward to the next occurrence so the
+// series keeps surfacing in default (upcoming) searches
+func PlanSeriesChildren(parent types.Event, existing []types.Event, now, horizon time.Time) (SeriesPlan, error) {
+	plan := SeriesPlan{Parent: parent}
+	if parent.Id == "" {
+		return plan, fmt.Errorf("series parent has no id")
+	}
+	if parent.RecurrenceStart == 0 {
+		plan.Parent.RecurrenceStart = parent.StartTime
+		plan.ParentChanged = true
+	}
+
+	// one past the cap tells whether any occurrence was left out
+	occurrences, err := SeriesOccurrences(plan.Parent, now, horizon, maxMaterializedSeriesChildren+1)
+	if err != nil {
+		return plan, err
+	}
+	if len(occurrences) > maxMaterializedSeriesChildren {
+		occurrences = occurrences[:maxMaterializedSeriesChildren]
+		plan.Truncated = true
+	}
+
+	// Hand-made children predate recurrenceId, fall back to their start time
+	existingBySlot := make(map[int64]types.Event, len(existing))
+	for _, child := range existing {
+		slot := child.RecurrenceId
+		if slot == 0 {
+			slot = child.StartTime
+		}
+		existingBySlot[slot] = child
+	}
+
+	var next *types.Event
+	inSchedule := make(map[int64]bool, len(occurrences))
+	for _, occurrence := range occurrences {
+		slot := occurrence.Unix()
+		inSchedule[slot] = true
+
+		current, exists := existingBySlot[slot]
+		if exists && current.RecurrenceOverride {
+			plan.Kept++
+			if next == nil {
+				next = &current
+			}
+			continue
+		}
+
+		id := SeriesOccurrenceId(parent.Id, slot)
+		if exists {
+			id = current.Id
+		}
+		child := NewSeriesChild(plan.Parent, id, slot)
+		if next == nil {
+			next = &child
+		}
+		if exists && !seriesChildDiffers(current, child) {
+			plan.Kept++
+			continue
+		}
+		plan.Upserts = append(plan.Upserts, child)
+	}
+
+	for slot, child := range existingBySlot {
+		if inSchedule[slot] || child.StartTime < now.Unix() {
+			continue
+		}
+		// past the cap the schedule wasn't expanded, so it can't tell
+		if plan.Truncated && slot > occurrences[len(occurrences)-1].Unix() {
+			continue
+		}
+		plan.Deletes = append(plan.Deletes, child.Id)
+	}
+	slices.Sort(plan.Deletes)
+
+	if next != nil && (plan.Parent.StartTime != next.StartTime || plan.Parent.EndTime != next.EndTime) {
+		duration := seriesDuration(plan.Parent)
+		plan.Parent.StartTime = next.StartTime
+		if duration > 0 {
+			plan.Parent.EndTime = next.StartTime + duration
+		}
+		plan.ParentChanged = true
+	}
+
+	return plan, nil
+}
+
+// SyncEventSeries materializes the upcoming children of a recurring series
+// parent and removes the ones its rule no longer produces
+func SyncEventSeries(ctx context.Context, store EventStore, parent types.Event, now time.Time) (SeriesSyncResult, error) {
+	result := SeriesSyncResult{ParentId: parent.Id}
+	if !IsRecurringSeriesParent(parent) {
+		return result, fmt.Errorf("event %s is not a recurring series parent", parent.Id)
+	}
+	// Match what a read of the parent would return, otherwise children
+	// written from a request and from the series loop would keep flip-flopping
+	if parent.ImageUrl == "" {
+		parent.ImageUrl = helpers.GetImgUrlFromHash(parent)
+	}
+
+	upcoming, err := SearchAllEventsInWindow(ctx, store, now.Unix(), 0, []string{},
+		[]string{constants.ES_EVENT_SERIES, constants.ES_EVENT_SERIES_UNPUB}, []string{parent.Id})
+	if err != nil {
+		return result, fmt.Errorf("failed to search children of series %s: %w", parent.Id, err)
+	}
+
+	// The search only returns summary fields, load the full children to diff
+	existing := []types.Event{}
+	if len(upcoming) > 0 {
+		ids := make([]string, len(upcoming))
+		for i, child := range upcoming {
+			ids[i] = child.Id
+		}
+		children, err := store.BulkGetEventByID(ctx, ids, "")
+		if err != nil {
+			return result, fmt.Errorf("failed to load children of series %s: %w", parent.Id, err)
+		}
+		for _, child := range children {
+			existing = append(existing, *child)
+		}
+	}
+
+	plan, err := PlanSeriesChildren(parent, existing, now, now.Add(SeriesMaterializationHorizon))
+	if err != nil {
+		return result, err
+	}
+
+	upserts := plan.Upserts
+	if plan.ParentChanged {
+		upserts = append([]types.Event{plan.Parent}, upserts...)
+	}
+	if len(upserts) > 0 {
+		if err := store.BulkUpsertEvent(ctx, upserts); err != nil {
+			return result, fmt.Errorf("failed to upsert children of series %s: %w", parent.Id, err)
+		}
+	}
+	if len(plan.Deletes) > 0 {
+		if err := store.BulkDeleteEvents(ctx, plan.Deletes); err != nil {
+			return result, fmt.Errorf("failed to delete children of series %s: %w", parent.Id, err)
+		}
+	}
+
+	result.Upserted = len(plan.Upserts)
+	result.Deleted = len(plan.Deletes)
+	result.Kept = plan.Kept
+	result.Truncated = plan.Truncated
+	if plan.Truncated {
+		log.Printf("WARN: series %s has more than %d occurrences in the next %d days, only the first %d are materialized",
+			parent.Id, maxMaterializedSeriesChildren, int(SeriesMaterializationHorizon.Hours()/24), maxMaterializedSeriesChildren)
+	}
+	return result, nil
+}
+
+// SyncAllEventSeries rolls the materialization horizon of every recurring
+// series forward, a failing series is logged and doesn't stop the others
+func SyncAllEventSeries(ctx context.Context, store EventStore, now time.Time) (int, error) {
+	parents, err := store.FindRecurringSeriesParents(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	synced := 0
+	for _, parent := range parents {
+		if ctx.Err() != nil {
+			return synced, ctx.Err()
+		}
+		result, err := SyncEventSeries(ctx, store, parent, now)
+		if err != nil {
+			log.Printf("ERR: failed to sync series %s: %v", parent.Id, err)
+			continue
+		}
+		synced++
+		if result.Upserted > 0 || result.Deleted > 0 {
+			log.Printf("INFO: synced series %s (%d upserted, %d deleted, %d kept)", parent.Id, result.Upserted, result.Deleted, result.Kept)
+		}
+	}
+	return synced, nil
+}
+
+// CancelSeriesOccurrence excludes the occurrence scheduled at `recurrenceId`
+// from the series (EXDATE) and removes its child
+func CancelSeriesOccurrence(ctx context.Context, store EventStore, parent types.Event, recurrenceId int64, now time.Time) (types.Event, error) {
+	if !IsRecurringSeriesParent(parent) {
+		return parent, fmt.Errorf("event %s is not a recurring series parent", parent.Id)
+	}
+	if !slices.Contains(parent.RecurrenceExDates, recurrenceId) {
+		parent.RecurrenceExDates = append(parent.RecurrenceExDates, recurrenceId)
+		slices.Sort(parent.RecurrenceExDates)
+	}
+	if err := store.BulkUpsertEvent(ctx, []types.Event{parent}); err != nil {
+		return parent, fmt.Errorf("failed to update series %s: %w", parent.Id, err)
+	}
+
+	// The sync only prunes upcoming children, an occurrence already in
+	// progress is removed explicitly
+	childId, err := seriesOccurrenceChildId(ctx, store, parent, recurrenceId)
+	if err != nil {
+		return parent, err
+	}
+	if err := store.BulkDeleteEvents(ctx, []string{childId}); err != nil {
+		return parent, fmt.Errorf("failed to delete occurrence %d of series %s: %w", recurrenceId, parent.Id, err)
+	}
+	if _, err := SyncEventSeries(ctx, store, parent, now); err != nil {
+		return parent, err
+	}
+	return parent, nil
+}
+
+// seriesOccurrenceChildId is the id of the stored child scheduled at
+// `recurrenceId`, children made by hand or imported keep their own ids. It's
+// the derived id when there's no such child yet
+func seriesOccurrenceChildId(ctx context.Context, store EventStore, parent types.Event, recurrenceId int64) (string, error) {
+	// Not limited to a window around `recurrenceId`, an earlier override may
+	// have moved the child's start anywhere
+	existing, err := SearchAllEvents(ctx, store, []string{},
+		[]string{constants.ES_EVENT_SERIES, constants.ES_EVENT_SERIES_UNPUB}, []string{parent.Id})
+	if err != nil {
+		return "", fmt.Errorf("failed to search children of series %s: %w", parent.Id, err)
+	}
+	for _, child := range existing {
+		if child.RecurrenceId == recurrenceId || (child.RecurrenceId == 0 && child.StartTime == recurrenceId) {
+			return child.Id, nil
+		}
+	}
+	return SeriesOccurrenceId(parent.Id, recurrenceId), nil
+}
+
+// OverrideSeriesOccurrence stores `occurrence` in place of the child scheduled
+// at `recurrenceId`, flagged so later syncs don't regenerate it from the rule
+func OverrideSeriesOccurrence(ctx context.Context, store EventStore, parent types.Event, recurrenceId int64, occurrence types.Event, now time.Time) (types.Event, error) {
+	if !IsRecurringSeriesParent(parent) {
+		return occurrence, fmt.Errorf("event %s is not a recurring series parent", parent.Id)
+	}
+
+	occurrences, err := SeriesOccurrences(parent, time.Unix(recurrenceId, 0), time.Unix(recurrenceId, 0), 1)
+	if err != nil {
+		return occurrence, err
+	}
+	if len(occurrences) == 0 {
+		return occurrence, fmt.Errorf("series %s has no occurrence at %s", parent.Id, time.Unix(recurrenceId, 0).In(&parent.Timezone).Format(time.RFC3339))
+	}
+
+	occurrence.Id, err = seriesOccurrenceChildId(ctx, store, parent, recurrenceId)
+	if err != nil {
+		return occurrence, err
+	}
+
+	occurrence.EventSourceType = seriesChildEventSourceType(parent.EventSourceType)
+	occurrence.EventSourceId = parent.Id
+	occurrence.EventOwners = parent.EventOwners
+	occurrence.EventOwnerName = parent.EventOwnerName
+	occurrence.RecurrenceRule = ""
+	occurrence.RecurrenceStart = 0
+	occurrence.RecurrenceRDates = nil
+	occurrence.RecurrenceExDates = nil
+	occurrence.RecurrenceId = recurrenceId
+	occurrence.RecurrenceOverride = true
+
+	if err := store.BulkUpsertEvent(ctx, []types.Event{occurrence}); err != nil {
+		return occurrence, fmt.Errorf("failed to override occurrence %d of series %s: %w", recurrenceId, parent.Id, err)
+	}
+	return occurrence, nil
+}
+
+func parseRecurrenceTime(value interface{}, loc *time.Location) (int64, error) {
+	switch v := value.(type) {
+	case float64:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case int:
+		return int64(v), nil
+	default:
+		return helpers.UtcToUnix64WithTrimZ(value, loc, false)
+	}
+}
+
+// convertRawRecurrence validates and copies the recurrence fields of a raw
+// event, a rule is only accepted on series parents
+func convertRawRecurrence(raw RawEvent, loc *time.Location, event *types.Event) error {
+	if raw.RecurrenceRule != nil && *raw.RecurrenceRule != "" {
+		if raw.EventSourceType != constants.ES_SERIES_PARENT && raw.EventSourceType != constants.ES_SERIES_PARENT_UNPUB {
+			return fmt.Errorf("recurrenceRule is only allowed on series parents")
+		}
+		rule, err := ParseRRule(*raw.RecurrenceRule, loc)
+		if err != nil {
+			return fmt.Errorf("invalid recurrenceRule: %w", err)
+		}
+		event.RecurrenceRule = rule.String()
+		event.RecurrenceStart = event.StartTime
+		if raw.RecurrenceStart != nil {
+			recurrenceStart, err := parseRecurrenceTime(raw.RecurrenceStart, loc)
+			if err != nil || recurrenceStart == 0 {
+				return fmt.Errorf("invalid recurrenceStart: %v", err)
+			}
+			event.RecurrenceStart = recurrenceStart
+		}
+	}
+
+	for _, rdate := range raw.RecurrenceRDates {
+		t, err := parseRecurrenceTime(rdate, loc)
+		if err != nil || t == 0 {
+			return fmt.Errorf("invalid recurrenceRDates entry: %v", err)
+		}
+		event.RecurrenceRDates = append(event.RecurrenceRDates, t)
+	}
+	for _, exdate := range raw.RecurrenceExDates {
+		t, err := parseRecurrenceTime(exdate, loc)
+		if err != nil || t == 0 {
+			return fmt.Errorf("invalid recurrenceExDates entry: %v", err)
+		}
+		event.RecurrenceExDates = append(event.RecurrenceExDates, t)
+	}
+	if (len(event.RecurrenceRDates) > 0 || len(event.RecurrenceExDates) > 0) && event.RecurrenceRule == "" {
+		return fmt.Errorf("recurrenceRDates and recurrenceExDates require a recurrenceRule")
+	}
+
+	if raw.RecurrenceId != nil {
+		recurrenceId, err := parseRecurrenceTime(raw.RecurrenceId, loc)
+		if err != nil {
+			return fmt.Errorf("invalid recurrenceId: %w", err)
+		}
+		event.RecurrenceId = recurrenceId
+	}
+	if raw.RecurrenceOverride != nil {
+		event.RecurrenceOverride = *raw.RecurrenceOverride
+	}
+	return nil
+}
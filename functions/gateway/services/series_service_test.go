@@ -0,0 +1,441 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+func TestPlanSeriesChildren(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+
+	// Saturdays at 7pm, the first one is before the 2025-03-09 DST change
+	dtstart := time.Date(2025, 3, 1, 19, 0, 0, 0, newYork)
+	newParent := func(rule string) types.Event {
+		return types.Event{
+			Id:              "9c5a4c56-5c4b-4b8e-8a4e-7d2d1f1c0a01",
+			EventOwners:     []string{"owner-1"},
+			EventOwnerName:  "Run Club",
+			EventSourceType: constants.ES_SERIES_PARENT,
+			Name:            "Weekly Run",
+			Description:     "Meet at the fountain",
+			Address:         "Central Park",
+			Lat:             40.7128,
+			Long:            -74.0060,
+			Timezone:        *newYork,
+			StartTime:       dtstart.Unix(),
+			EndTime:         dtstart.Add(time.Hour).Unix(),
+			RecurrenceRule:  rule,
+		}
+	}
+	now := time.Date(2025, 2, 20, 0, 0, 0, 0, time.UTC)
+	horizon := now.Add(SeriesMaterializationHorizon)
+
+	t.Run("materializes occurrences at the series' local time", func(t *testing.T) {
+		plan, err := PlanSeriesChildren(newParent("FREQ=WEEKLY;COUNT=4"), nil, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(plan.Upserts) != 4 || len(plan.Deletes) != 0 {
+			t.Fatalf("expected 4 upserts and no deletes, got %d / %d", len(plan.Upserts), len(plan.Deletes))
+		}
+		for _, child := range plan.Upserts {
+			start := time.Unix(child.StartTime, 0).In(newYork)
+			if start.Hour() != 19 || start.Weekday() != time.Saturday {
+				t.Errorf("expected Saturday 7pm local, got %v", start)
+			}
+			if child.EndTime-child.StartTime != 3600 {
+				t.Errorf("expected the parent's duration, got %ds", child.EndTime-child.StartTime)
+			}
+			if child.EventSourceType != constants.ES_EVENT_SERIES || child.EventSourceId != plan.Parent.Id {
+				t.Errorf("expected an EVS child of the parent, got %s / %s", child.EventSourceType, child.EventSourceId)
+			}
+			if child.RecurrenceRule != "" || child.RecurrenceId != child.StartTime {
+				t.Errorf("unexpected recurrence fields on child: %+v", child)
+			}
+			if child.Id != SeriesOccurrenceId(plan.Parent.Id, child.StartTime) {
+				t.Errorf("expected deterministic child id")
+			}
+		}
+		if !plan.ParentChanged || plan.Parent.RecurrenceStart != dtstart.Unix() {
+			t.Errorf("expected the rule to be anchored at the original start")
+		}
+	})
+
+	t.Run("unpublished parents get unpublished children", func(t *testing.T) {
+		parent := newParent("FREQ=WEEKLY;COUNT=2")
+		parent.EventSourceType = constants.ES_SERIES_PARENT_UNPUB
+		plan, err := PlanSeriesChildren(parent, nil, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		for _, child := range plan.Upserts {
+			if child.EventSourceType != constants.ES_EVENT_SERIES_UNPUB {
+				t.Errorf("expected %s, got %s", constants.ES_EVENT_SERIES_UNPUB, child.EventSourceType)
+			}
+		}
+	})
+
+	t.Run("re-planning an unchanged series writes nothing", func(t *testing.T) {
+		first, err := PlanSeriesChildren(newParent("FREQ=WEEKLY;COUNT=4"), nil, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		second, err := PlanSeriesChildren(first.Parent, first.Upserts, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(second.Upserts) != 0 || len(second.Deletes) != 0 || second.ParentChanged || second.Kept != 4 {
+			t.Errorf("expected a no-op plan, got %d upserts, %d deletes, parentChanged=%v, kept=%d",
+				len(second.Upserts), len(second.Deletes), second.ParentChanged, second.Kept)
+		}
+	})
+
+	t.Run("editing the rule adds and removes future children", func(t *testing.T) {
+		first, err := PlanSeriesChildren(newParent("FREQ=WEEKLY;COUNT=4"), nil, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		edited := first.Parent
+		edited.RecurrenceRule = "FREQ=WEEKLY;INTERVAL=2;COUNT=3"
+		plan, err := PlanSeriesChildren(edited, first.Upserts, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// Mar 1 and Mar 15 survive, Mar 29 is new, Mar 8 and Mar 22 go away
+		if plan.Kept != 2 || len(plan.Upserts) != 1 || len(plan.Deletes) != 2 {
+			t.Fatalf("expected 2 kept, 1 upsert, 2 deletes, got %d / %d / %d", plan.Kept, len(plan.Upserts), len(plan.Deletes))
+		}
+		if got := time.Unix(plan.Upserts[0].StartTime, 0).In(newYork); got.Day() != 29 {
+			t.Errorf("expected the new child on Mar 29, got %v", got)
+		}
+		removed := []string{
+			SeriesOccurrenceId(edited.Id, time.Date(2025, 3, 8, 19, 0, 0, 0, newYork).Unix()),
+			SeriesOccurrenceId(edited.Id, time.Date(2025, 3, 22, 19, 0, 0, 0, newYork).Unix()),
+		}
+		slices.Sort(removed)
+		if !slices.Equal(plan.Deletes, removed) {
+			t.Errorf("expected deletes %v, got %v", removed, plan.Deletes)
+		}
+	})
+
+	t.Run("EXDATE cancels an occurrence and overrides are preserved", func(t *testing.T) {
+		first, err := PlanSeriesChildren(newParent("FREQ=WEEKLY;COUNT=4"), nil, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		children := slices.Clone(first.Upserts)
+
+		// Move the Mar 15 run an hour earlier and rename it
+		override := children[2]
+		override.Name = "Hill Repeats"
+		override.StartTime -= 3600
+		override.EndTime -= 3600
+		override.RecurrenceOverride = true
+		children[2] = override
+
+		parent := first.Parent
+		parent.Description = "Meet at the boathouse"
+		parent.RecurrenceExDates = []int64{children[1].RecurrenceId}
+
+		plan, err := PlanSeriesChildren(parent, children, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !slices.Equal(plan.Deletes, []string{children[1].Id}) {
+			t.Errorf("expected the excluded occurrence to be deleted, got %v", plan.Deletes)
+		}
+		for _, child := range plan.Upserts {
+			if child.Id == override.Id {
+				t.Errorf("expected the overridden occurrence to be left alone")
+			}
+			if child.Description != "Meet at the boathouse" {
+				t.Errorf("expected parent edits to propagate, got %q", child.Description)
+			}
+		}
+		if len(plan.Upserts) != 2 || plan.Kept != 1 {
+			t.Errorf("expected 2 refreshed children and 1 kept override, got %d / %d", len(plan.Upserts), plan.Kept)
+		}
+	})
+
+	t.Run("past children are left alone and the parent rolls forward", func(t *testing.T) {
+		first, err := PlanSeriesChildren(newParent("FREQ=WEEKLY;COUNT=4"), nil, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		later := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+		edited := first.Parent
+		edited.RecurrenceRule = "FREQ=WEEKLY;COUNT=3"
+		plan, err := PlanSeriesChildren(edited, first.Upserts, later, later.Add(SeriesMaterializationHorizon))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Mar 1 and Mar 8 are in the past, Mar 22 drops out of the shortened rule
+		if !slices.Equal(plan.Deletes, []string{first.Upserts[3].Id}) {
+			t.Errorf("expected only the future Mar 22 child to be deleted, got %v", plan.Deletes)
+		}
+		next := time.Date(2025, 3, 15, 19, 0, 0, 0, newYork)
+		if plan.Parent.StartTime != next.Unix() || plan.Parent.EndTime != next.Add(time.Hour).Unix() {
+			t.Errorf("expected parent to roll forward to %v, got %v", next, time.Unix(plan.Parent.StartTime, 0).In(newYork))
+		}
+		if plan.Parent.RecurrenceStart != dtstart.Unix() {
+			t.Errorf("expected the rule anchor to stay put")
+		}
+	})
+
+	t.Run("hand-made children are adopted by start time", func(t *testing.T) {
+		handMade := newParent("")
+		handMade.Id = "0b6d1f52-8f8e-4f0e-9d8f-2f0f7a1a9b11"
+		handMade.EventSourceType = constants.ES_EVENT_SERIES
+		handMade.EventSourceId = newParent("").Id
+		handMade.RecurrenceRule = ""
+
+		plan, err := PlanSeriesChildren(newParent("FREQ=WEEKLY;COUNT=2"), []types.Event{handMade}, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(plan.Deletes) != 0 || len(plan.Upserts) != 2 || plan.Upserts[0].Id != handMade.Id {
+			t.Errorf("expected the existing child id to be reused, got %+v", plan.Upserts)
+		}
+	})
+
+	t.Run("materializes every daily occurrence over the horizon", func(t *testing.T) {
+		plan, err := PlanSeriesChildren(newParent("FREQ=DAILY"), nil, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// Mar 1 through May 20, the horizon ends May 21 at midnight UTC
+		if plan.Truncated || len(plan.Upserts) != 81 {
+			t.Errorf("expected 81 daily children, got %d (truncated %v)", len(plan.Upserts), plan.Truncated)
+		}
+	})
+
+	t.Run("reports occurrences left out past the cap", func(t *testing.T) {
+		parent := newParent("FREQ=DAILY")
+		for i := range 2 * maxMaterializedSeriesChildren {
+			parent.RecurrenceRDates = append(parent.RecurrenceRDates, dtstart.Add(time.Duration(i)*4*time.Hour+time.Hour).Unix())
+		}
+		first, err := PlanSeriesChildren(parent, nil, now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !first.Truncated || len(first.Upserts) != maxMaterializedSeriesChildren {
+			t.Fatalf("expected %d children and the plan truncated, got %d (truncated %v)", maxMaterializedSeriesChildren, len(first.Upserts), first.Truncated)
+		}
+
+		// a child past the cap, say from before the rule grew, isn't deleted
+		// just because the schedule wasn't expanded that far
+		late := NewSeriesChild(first.Parent, "5d0e2c41-6a7b-4c8d-9e0f-a1b2c3d4e5f6", horizon.Add(-time.Hour).Unix())
+		plan, err := PlanSeriesChildren(first.Parent, append(first.Upserts, late), now, horizon)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(plan.Deletes) != 0 || len(plan.Upserts) != 0 {
+			t.Errorf("expected nothing to change, got %d upserts and deletes %v", len(plan.Upserts), plan.Deletes)
+		}
+	})
+
+	t.Run("rejects parents without an id or with an invalid rule", func(t *testing.T) {
+		noId := newParent("FREQ=WEEKLY")
+		noId.Id = ""
+		if _, err := PlanSeriesChildren(noId, nil, now, horizon); err == nil {
+			t.Errorf("expected error for parent without id")
+		}
+		if _, err := PlanSeriesChildren(newParent("FREQ=SECONDLY"), nil, now, horizon); err == nil {
+			t.Errorf("expected error for unsupported rule")
+		}
+	})
+}
+
+func TestConvertRawEventRecurrence(t *testing.T) {
+	rule := "freq=weekly;byday=sa"
+	rawParent := RawEvent{
+		RawEventData: RawEventData{
+			EventOwners:     []string{"owner-1"},
+			EventOwnerName:  "Run Club",
+			EventSourceType: constants.ES_SERIES_PARENT,
+			Name:            "Weekly Run",
+			Description:     "Meet at the fountain",
+			Address:         "Central Park",
+			Lat:             40.7128,
+			Long:            -74.0060,
+			Timezone:        "America/New_York",
+		},
+		StartTime:         "2025-03-02T00:00:00Z",
+		RecurrenceRule:    &rule,
+		RecurrenceExDates: []interface{}{"2025-03-09T00:00:00Z", float64(1742086800)},
+	}
+
+	event, err := ConvertRawEventToEvent(rawParent, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if event.RecurrenceRule != "FREQ=WEEKLY;BYDAY=SA" {
+		t.Errorf("expected normalized rule, got %q", event.RecurrenceRule)
+	}
+	if event.RecurrenceStart != event.StartTime {
+		t.Errorf("expected the rule to be anchored at StartTime")
+	}
+	if !slices.Equal(event.RecurrenceExDates, []int64{1741478400, 1742086800}) {
+		t.Errorf("unexpected EXDATEs: %v", event.RecurrenceExDates)
+	}
+	if !IsRecurringSeriesParent(event) {
+		t.Errorf("expected a recurring series parent")
+	}
+
+	invalid := rawParent
+	badRule := "FREQ=WEEKLY;BYHOUR=9"
+	invalid.RecurrenceRule = &badRule
+	if _, err := ConvertRawEventToEvent(invalid, false); err == nil {
+		t.Errorf("expected error for unsupported rule part")
+	}
+
+	single := rawParent
+	single.EventSourceType = constants.ES_SINGLE_EVENT
+	if _, err := ConvertRawEventToEvent(single, false); err == nil {
+		t.Errorf("expected error for a rule on a single event")
+	}
+
+	orphanExDates := rawParent
+	orphanExDates.RecurrenceRule = nil
+	if _, err := ConvertRawEventToEvent(orphanExDates, false); err == nil {
+		t.Errorf("expected error for EXDATEs without a rule")
+	}
+}
+
+func TestOverrideSeriesOccurrenceFindsChildPastTheSearchCap(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	ctx := context.Background()
+	store := NewMemoryEventStore()
+
+	dtstart := time.Date(2025, 3, 1, 19, 0, 0, 0, newYork)
+	parent := types.Event{
+		Id:              "4f1d2e7a-3b6c-4d8e-9f0a-1b2c3d4e5f60",
+		EventOwners:     []string{"owner1"},
+		EventOwnerName:  "Run Club",
+		EventSourceType: constants.ES_SERIES_PARENT,
+		Name:            "Daily Run",
+		Timezone:        *newYork,
+		StartTime:       dtstart.Unix(),
+		EndTime:         dtstart.Add(time.Hour).Unix(),
+		RecurrenceRule:  "FREQ=DAILY;COUNT=150",
+	}
+
+	// More children than one search returns, the one being overridden was
+	// written before ids were derived and sorts past the first page
+	legacyStart := dtstart.AddDate(0, 0, 120).Unix()
+	legacyId := "7a3e9c1b-2d4f-4a6b-8c0d-e1f2a3b4c5d6"
+	children := []types.Event{}
+	for day := 0; day < 150; day++ {
+		start := dtstart.AddDate(0, 0, day).Unix()
+		child := types.Event{
+			Id:              SeriesOccurrenceId(parent.Id, start),
+			EventOwners:     parent.EventOwners,
+			EventSourceType: constants.ES_EVENT_SERIES,
+			EventSourceId:   parent.Id,
+			Name:            parent.Name,
+			Timezone:        *newYork,
+			StartTime:       start,
+			EndTime:         start + 3600,
+			RecurrenceId:    start,
+		}
+		if start == legacyStart {
+			child.Id = legacyId
+			child.RecurrenceId = 0
+		}
+		children = append(children, child)
+	}
+	if err := store.BulkUpsertEvent(ctx, children); err != nil {
+		t.Fatalf("failed to seed children: %v", err)
+	}
+
+	occurrence := types.Event{
+		Name:      "Daily Run (moved)",
+		Timezone:  *newYork,
+		StartTime: legacyStart + 1800,
+		EndTime:   legacyStart + 5400,
+	}
+	occurrence, err = OverrideSeriesOccurrence(ctx, store, parent, legacyStart, occurrence, dtstart)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if occurrence.Id != legacyId {
+		t.Fatalf("expected the existing child to be replaced, got id %s", occurrence.Id)
+	}
+
+	stored, err := SearchAllEvents(ctx, store, []string{}, []string{constants.ES_EVENT_SERIES}, []string{parent.Id})
+	if err != nil {
+		t.Fatalf("failed to search children: %v", err)
+	}
+	if len(stored) != 150 {
+		t.Errorf("expected 150 children after the override, got %d", len(stored))
+	}
+}
+
+func TestCancelSeriesOccurrenceRemovesChildWithOwnId(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	ctx := context.Background()
+	store := NewMemoryEventStore()
+
+	dtstart := time.Date(2025, 3, 1, 19, 0, 0, 0, newYork)
+	parent := types.Event{
+		Id:              "8e2f4a6c-1b3d-4e5f-8a9b-0c1d2e3f4a5b",
+		EventOwners:     []string{"owner1"},
+		EventOwnerName:  "Run Club",
+		EventSourceType: constants.ES_SERIES_PARENT,
+		Name:            "Daily Run",
+		Timezone:        *newYork,
+		StartTime:       dtstart.Unix(),
+		EndTime:         dtstart.Add(time.Hour).Unix(),
+		RecurrenceRule:  "FREQ=DAILY;COUNT=3",
+	}
+	// The second run was imported under its own id, before children had a
+	// recurrenceId, and is under way when it's cancelled
+	slot := dtstart.AddDate(0, 0, 1).Unix()
+	importedId := "2c4e6a8b-0d1f-4a3b-9c5d-7e9f1a3b5c7d"
+	imported := types.Event{
+		Id:              importedId,
+		EventOwners:     parent.EventOwners,
+		EventSourceType: constants.ES_EVENT_SERIES,
+		EventSourceId:   parent.Id,
+		Name:            parent.Name,
+		Timezone:        *newYork,
+		StartTime:       slot,
+		EndTime:         slot + 3600,
+	}
+	if err := store.BulkUpsertEvent(ctx, []types.Event{parent, imported}); err != nil {
+		t.Fatalf("failed to seed series: %v", err)
+	}
+
+	now := time.Unix(slot+1800, 0)
+	parent, err = CancelSeriesOccurrence(ctx, store, parent, slot, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(parent.RecurrenceExDates, slot) {
+		t.Errorf("expected the occurrence to be excluded, got %v", parent.RecurrenceExDates)
+	}
+	children, err := SearchAllEvents(ctx, store, []string{}, []string{constants.ES_EVENT_SERIES}, []string{parent.Id})
+	if err != nil {
+		t.Fatalf("failed to search children: %v", err)
+	}
+	for _, child := range children {
+		if child.Id == importedId || child.StartTime == slot {
+			t.Errorf("expected the cancelled occurrence to be removed, found %s", child.Id)
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/meetnearme/api/functions/gateway/constants"
 	"github.com/meetnearme/api/functions/gateway/interfaces"
@@ -264,6 +266,28 @@ func (s *StripeSubscriptionService) UpdateCustomerMetadata(customerID, externalI
 	return nil
 }
 
+// RedactCustomer clears the customer's contact details and unlinks it from
+// the Zitadel user. The customer itself stays, Stripe keeps its invoices and
+// payments for the account's accounting records
+func (s *StripeSubscriptionService) RedactCustomer(customerID string) error {
+	params := &stripe.CustomerUpdateParams{
+		Name:  stripe.String(""),
+		Email: stripe.String(""),
+		Phone: stripe.String(""),
+		Metadata: map[string]string{
+			"zitadel_user_id":    "",
+			"account_deleted_at": strconv.FormatInt(time.Now().Unix(), 10),
+		},
+	}
+
+	_, err := s.client.V1Customers.Update(context.Background(), customerID, params)
+	if err != nil {
+		return fmt.Errorf("error redacting customer: %w", err)
+	}
+
+	return nil
+}
+
 // CreateCustomer creates a new Stripe customer with external_id metadata
 func (s *StripeSubscriptionService) CreateCustomer(externalID, email, name string) (*stripe.Customer, error) {
 	log.Printf("Creating Stripe customer with email: %s, name: %s, zitadel_user_id: %s", email, name, externalID)
@@ -853,3 +853,43 @@ func TestStripeSubscriptionService_CreateCustomerPortalSession(t *testing.T) {
 		t.Logf("✅ Portal session with payment method update flow created: ID=%s, URL=%s", session.ID, session.URL)
 	})
 }
+
+func TestStripeSubscriptionService_RedactCustomer(t *testing.T) {
+	originalStripeKey := os.Getenv("STRIPE_SECRET_KEY")
+	originalTransport := http.DefaultTransport
+	defer func() {
+		os.Setenv("STRIPE_SECRET_KEY", originalStripeKey)
+		http.DefaultTransport = originalTransport
+		ResetStripeClient()
+	}()
+
+	var form url.Values
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/v1/customers/cus_test_customer" {
+			t.Errorf("unexpected Stripe request %s %s", r.Method, r.URL.Path)
+			http.Error(w, "Not Found", http.StatusNotFound)
+			return
+		}
+		r.ParseForm()
+		form = r.Form
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": "cus_test_customer", "object": "customer"}`))
+	}))
+	defer mockServer.Close()
+
+	os.Setenv("STRIPE_SECRET_KEY", "sk_test_mock_key")
+	http.DefaultTransport = &customRoundTripper{transport: &http.Transport{}, mockURL: mockServer.URL}
+	ResetStripeClient()
+
+	if err := NewStripeSubscriptionService().RedactCustomer("cus_test_customer"); err != nil {
+		t.Fatalf("RedactCustomer() failed: %v", err)
+	}
+	for _, key := range []string{"name", "email", "phone", "metadata[zitadel_user_id]"} {
+		if values, ok := form[key]; !ok || values[0] != "" {
+			t.Errorf("expected %s to be cleared, got %v", key, values)
+		}
+	}
+	if form.Get("metadata[account_deleted_at]") == "" {
+		t.Errorf("expected the deletion time in metadata, got %v", form)
+	}
+}
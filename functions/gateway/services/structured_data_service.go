@@ -0,0 +1,249 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/helpers"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+const (
+	SCHEMA_ORG_CONTEXT = "https://schema.org"
+
+	schemaEventScheduled          = "https://schema.org/EventScheduled"
+	schemaOfflineAttendanceMode   = "https://schema.org/OfflineEventAttendanceMode"
+	schemaInStock                 = "https://schema.org/InStock"
+	schemaSoldOut                 = "https://schema.org/SoldOut"
+	jsonLDDefaultCurrency         = "USD"
+	jsonLDTicketItemType          = "ticket"
+	metaDescriptionMaxRunes       = 200
+	metaDescriptionTruncateSuffix = "…"
+)
+
+var htmlTagPattern = regexp.MustCompile(`<[^>]*>`)
+
+// EventJSONLD is a schema.org `Event` as described at https://schema.org/Event,
+// limited to the properties search engines read for event rich results
+type EventJSONLD struct {
+	Context             string              `json:"@context,omitempty"`
+	Type                string              `json:"@type"`
+	Name                string              `json:"name"`
+	Description         string              `json:"description,omitempty"`
+	StartDate           string              `json:"startDate"`
+	EndDate             string              `json:"endDate,omitempty"`
+	EventStatus         string              `json:"eventStatus"`
+	EventAttendanceMode string              `json:"eventAttendanceMode"`
+	Location            PlaceJSONLD         `json:"location"`
+	Image               []string            `json:"image,omitempty"`
+	Url                 string              `json:"url,omitempty"`
+	Organizer           *OrganizationJSONLD `json:"organizer,omitempty"`
+	Offers              []OfferJSONLD       `json:"offers,omitempty"`
+}
+
+type PlaceJSONLD struct {
+	Type    string                `json:"@type"`
+	Name    string                `json:"name,omitempty"`
+	Address string                `json:"address,omitempty"`
+	Geo     *GeoCoordinatesJSONLD `json:"geo,omitempty"`
+}
+
+type GeoCoordinatesJSONLD struct {
+	Type      string  `json:"@type"`
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+}
+
+type OrganizationJSONLD struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+	Url  string `json:"url,omitempty"`
+}
+
+type OfferJSONLD struct {
+	Type          string `json:"@type"`
+	Name          string `json:"name,omitempty"`
+	Price         string `json:"price"`
+	PriceCurrency string `json:"priceCurrency"`
+	Availability  string `json:"availability,omitempty"`
+	Url           string `json:"url,omitempty"`
+	ValidThrough  string `json:"validThrough,omitempty"`
+}
+
+// ItemListJSONLD wraps the events listed on a page, see https://schema.org/ItemList
+type ItemListJSONLD struct {
+	Context         string           `json:"@context"`
+	Type            string           `json:"@type"`
+	Name            string           `json:"name,omitempty"`
+	Url             string           `json:"url,omitempty"`
+	ItemListElement []ListItemJSONLD `json:"itemListElement"`
+}
+
+type ListItemJSONLD struct {
+	Type     string      `json:"@type"`
+	Position int         `json:"position"`
+	Item     EventJSONLD `json:"item"`
+}
+
+// EventPageUrl is the canonical public URL of an event's details page
+func EventPageUrl(event types.Event) string {
+	return os.Getenv("APEX_URL") + "/event/" + event.Id
+}
+
+// UserPageUrl is the canonical public URL of a user's events page
+func UserPageUrl(userId string) string {
+	return os.Getenv("APEX_URL") + "/user/" + userId
+}
+
+// EventImageUrl is the image shown for an event, falling back to the same
+// category placeholder the event details page uses
+func EventImageUrl(event types.Event) string {
+	if event.ImageUrl != "" {
+		return event.ImageUrl
+	}
+	return helpers.GetImgUrlFromHash(event)
+}
+
+// PlainTextSummary strips markup from `s` and shortens it to fit a meta
+// description
+func PlainTextSummary(s string) string {
+	s = strings.Join(strings.Fields(htmlTagPattern.ReplaceAllString(s, " ")), " ")
+	if utf8.RuneCountInString(s) <= metaDescriptionMaxRunes {
+		return s
+	}
+	runes := []rune(s)[:metaDescriptionMaxRunes]
+	return strings.TrimSpace(string(runes)) + metaDescriptionTruncateSuffix
+}
+
+func formatJSONLDDate(unix int64, loc time.Location) string {
+	return time.Unix(unix, 0).In(&loc).Format(time.RFC3339)
+}
+
+// BuildEventJSONLD serializes `event` as a schema.org `Event`. Ticket
+// purchasables become `offers`, pass nil when they weren't loaded
+func BuildEventJSONLD(event types.Event, purchasable *types.Purchasable) EventJSONLD {
+	pageUrl := EventPageUrl(event)
+	jsonLD := EventJSONLD{
+		Context:             SCHEMA_ORG_CONTEXT,
+		Type:                "Event",
+		Name:                event.Name,
+		Description:         PlainTextSummary(event.Description),
+		StartDate:           formatJSONLDDate(event.StartTime, event.Timezone),
+		EventStatus:         schemaEventScheduled,
+		EventAttendanceMode: schemaOfflineAttendanceMode,
+		Location: PlaceJSONLD{
+			Type:    "Place",
+			Name:    strings.TrimSpace(strings.Split(event.Address, ",")[0]),
+			Address: event.Address,
+		},
+		Image: []string{EventImageUrl(event)},
+		Url:   pageUrl,
+	}
+	if event.EndTime > event.StartTime {
+		jsonLD.EndDate = formatJSONLDDate(event.EndTime, event.Timezone)
+	}
+	if event.Lat != constants.INITIAL_EMPTY_LAT_LONG && event.Long != constants.INITIAL_EMPTY_LAT_LONG {
+		jsonLD.Location.Geo = &GeoCoordinatesJSONLD{Type: "GeoCoordinates", Latitude: event.Lat, Longitude: event.Long}
+	}
+	if event.EventOwnerName != "" {
+		jsonLD.Organizer = &OrganizationJSONLD{Type: "Organization", Name: event.EventOwnerName}
+		if len(event.EventOwners) > 0 {
+			jsonLD.Organizer.Url = UserPageUrl(event.EventOwners[0])
+		}
+	}
+
+	if purchasable == nil {
+		return jsonLD
+	}
+	currency := strings.ToUpper(event.Currency)
+	if currency == "" {
+		currency = jsonLDDefaultCurrency
+	}
+	for _, item := range purchasable.PurchasableItems {
+		if item.ItemType != jsonLDTicketItemType {
+			continue
+		}
+		availability := schemaInStock
+		if item.Inventory <= 0 {
+			availability = schemaSoldOut
+		}
+		offer := OfferJSONLD{
+			Type: "Offer",
+			Name: item.Name,
+			// Purchasable costs are stored in cents
+			Price:         fmt.Sprintf("%.2f", item.Cost/100),
+			PriceCurrency: currency,
+			Availability:  availability,
+			Url:           pageUrl,
+		}
+		if item.ExpiresOn != nil {
+			offer.ValidThrough = item.ExpiresOn.Format(time.RFC3339)
+		}
+		jsonLD.Offers = append(jsonLD.Offers, offer)
+	}
+	return jsonLD
+}
+
+// BuildEventListJSONLD serializes the events listed on a page as a schema.org
+// `ItemList` of `Event`s
+func BuildEventListJSONLD(name, pageUrl string, events []types.Event) ItemListJSONLD {
+	list := ItemListJSONLD{
+		Context:         SCHEMA_ORG_CONTEXT,
+		Type:            "ItemList",
+		Name:            name,
+		Url:             pageUrl,
+		ItemListElement: []ListItemJSONLD{},
+	}
+	for i, event := range events {
+		item := BuildEventJSONLD(event, nil)
+		// The list already declares the context
+		item.Context = ""
+		list.ItemListElement = append(list.ItemListElement, ListItemJSONLD{Type: "ListItem", Position: i + 1, Item: item})
+	}
+	return list
+}
+
+// BuildEventPageMeta is the head metadata for an event's details page
+func BuildEventPageMeta(event types.Event, purchasable *types.Purchasable) constants.PageMeta {
+	return constants.PageMeta{
+		Title:       event.Name,
+		Description: PlainTextSummary(event.Description),
+		Url:         EventPageUrl(event),
+		ImageUrl:    EventImageUrl(event),
+		OgType:      "website",
+		JSONLD:      BuildEventJSONLD(event, purchasable),
+	}
+}
+
+// BuildEventListPageMeta is the head metadata for the home page or, when
+// `pageUser` is set, a user's events page
+func BuildEventListPageMeta(pageUser *types.UserSearchResult, events []types.Event) constants.PageMeta {
+	meta := constants.PageMeta{
+		Title:       "Meet Near Me",
+		Description: "Find events near you, from local organizers",
+		Url:         os.Getenv("APEX_URL") + "/",
+		ImageUrl:    os.Getenv("STATIC_BASE_URL") + "/assets/logo.svg",
+		OgType:      "website",
+	}
+	if pageUser != nil {
+		meta.Title = pageUser.DisplayName
+		meta.Url = UserPageUrl(pageUser.UserID)
+		meta.OgType = "profile"
+		meta.Description = "Upcoming events from " + pageUser.DisplayName
+		if about := PlainTextSummary(pageUser.Metadata[constants.META_ABOUT_KEY]); about != "" {
+			meta.Description = about
+		}
+		if len(events) > 0 {
+			meta.ImageUrl = EventImageUrl(events[0])
+		}
+	}
+	if len(events) > 0 {
+		meta.JSONLD = BuildEventListJSONLD(meta.Title, meta.Url, events)
+	}
+	return meta
+}
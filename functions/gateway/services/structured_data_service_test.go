@@ -0,0 +1,186 @@
+package services
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+func TestBuildEventJSONLD(t *testing.T) {
+	t.Setenv("APEX_URL", "https://meetnear.me")
+	loc, err := time.LoadLocation("America/Chicago")
+	if err != nil {
+		t.Fatalf("failed to load timezone: %v", err)
+	}
+	start := time.Date(2030, 6, 1, 19, 0, 0, 0, loc)
+	expires := time.Date(2030, 5, 31, 12, 0, 0, 0, time.UTC)
+	event := types.Event{
+		Id:             "evt-1",
+		Name:           "Trivia Night",
+		Description:    "<p>Bring   a <b>team</b></p>",
+		StartTime:      start.Unix(),
+		EndTime:        start.Add(2 * time.Hour).Unix(),
+		Timezone:       *loc,
+		Address:        "The Tavern, 123 Main St, Austin, TX",
+		Lat:            30.2672,
+		Long:           -97.7431,
+		EventOwners:    []string{"owner-1"},
+		EventOwnerName: "Quiz Club",
+		Currency:       "usd",
+		ImageUrl:       "https://cdn.example.com/trivia.jpg",
+		HasPurchasable: true,
+	}
+	purchasable := &types.Purchasable{
+		EventId: "evt-1",
+		PurchasableItems: []types.PurchasableItemInsert{
+			{Name: "General Admission", ItemType: "ticket", Cost: 1500, Inventory: 10, ExpiresOn: &expires},
+			{Name: "VIP", ItemType: "ticket", Cost: 4000, Inventory: 0},
+			{Name: "T-Shirt", ItemType: "merchandise", Cost: 2000, Inventory: 5},
+		},
+	}
+
+	jsonLD := BuildEventJSONLD(event, purchasable)
+
+	if jsonLD.Context != SCHEMA_ORG_CONTEXT || jsonLD.Type != "Event" {
+		t.Errorf("expected a schema.org Event, got context %q type %q", jsonLD.Context, jsonLD.Type)
+	}
+	if jsonLD.StartDate != "2030-06-01T19:00:00-05:00" {
+		t.Errorf("expected start date in the event's timezone, got %s", jsonLD.StartDate)
+	}
+	if jsonLD.EndDate != "2030-06-01T21:00:00-05:00" {
+		t.Errorf("expected end date in the event's timezone, got %s", jsonLD.EndDate)
+	}
+	if jsonLD.Description != "Bring a team" {
+		t.Errorf("expected markup stripped from description, got %q", jsonLD.Description)
+	}
+	if jsonLD.Url != "https://meetnear.me/event/evt-1" {
+		t.Errorf("unexpected url %s", jsonLD.Url)
+	}
+	if jsonLD.EventStatus != "https://schema.org/EventScheduled" || jsonLD.EventAttendanceMode != "https://schema.org/OfflineEventAttendanceMode" {
+		t.Errorf("unexpected status %s or attendance mode %s", jsonLD.EventStatus, jsonLD.EventAttendanceMode)
+	}
+	if jsonLD.Location.Type != "Place" || jsonLD.Location.Name != "The Tavern" || jsonLD.Location.Address != event.Address {
+		t.Errorf("unexpected location %+v", jsonLD.Location)
+	}
+	if jsonLD.Location.Geo == nil || jsonLD.Location.Geo.Latitude != event.Lat || jsonLD.Location.Geo.Longitude != event.Long {
+		t.Errorf("expected geo coordinates, got %+v", jsonLD.Location.Geo)
+	}
+	if jsonLD.Organizer == nil || jsonLD.Organizer.Name != "Quiz Club" || jsonLD.Organizer.Url != "https://meetnear.me/user/owner-1" {
+		t.Errorf("unexpected organizer %+v", jsonLD.Organizer)
+	}
+	if len(jsonLD.Image) != 1 || jsonLD.Image[0] != event.ImageUrl {
+		t.Errorf("unexpected image %v", jsonLD.Image)
+	}
+
+	if len(jsonLD.Offers) != 2 {
+		t.Fatalf("expected only ticket purchasables as offers, got %+v", jsonLD.Offers)
+	}
+	if offer := jsonLD.Offers[0]; offer.Price != "15.00" || offer.PriceCurrency != "USD" || offer.Availability != "https://schema.org/InStock" || offer.ValidThrough != "2030-05-31T12:00:00Z" {
+		t.Errorf("unexpected first offer %+v", offer)
+	}
+	if offer := jsonLD.Offers[1]; offer.Price != "40.00" || offer.Availability != "https://schema.org/SoldOut" || offer.ValidThrough != "" {
+		t.Errorf("unexpected second offer %+v", offer)
+	}
+
+	raw, err := json.Marshal(jsonLD)
+	if err != nil {
+		t.Fatalf("failed to marshal JSON-LD: %v", err)
+	}
+	for _, expected := range []string{`"@context":"https://schema.org"`, `"@type":"Offer"`, `"@type":"GeoCoordinates"`} {
+		if !strings.Contains(string(raw), expected) {
+			t.Errorf("expected %s in %s", expected, raw)
+		}
+	}
+}
+
+func TestBuildEventJSONLDMinimalEvent(t *testing.T) {
+	event := types.Event{
+		Id:        "evt-2",
+		Name:      "Pickup Soccer",
+		StartTime: time.Date(2030, 6, 1, 9, 0, 0, 0, time.UTC).Unix(),
+		Address:   "Zilker Park",
+		Lat:       constants.INITIAL_EMPTY_LAT_LONG,
+		Long:      constants.INITIAL_EMPTY_LAT_LONG,
+	}
+
+	jsonLD := BuildEventJSONLD(event, nil)
+
+	if jsonLD.EndDate != "" {
+		t.Errorf("expected no end date, got %s", jsonLD.EndDate)
+	}
+	if jsonLD.Location.Geo != nil {
+		t.Errorf("expected no geo for an event without coordinates, got %+v", jsonLD.Location.Geo)
+	}
+	if jsonLD.Organizer != nil {
+		t.Errorf("expected no organizer without an owner name, got %+v", jsonLD.Organizer)
+	}
+	if jsonLD.Offers != nil {
+		t.Errorf("expected no offers without purchasables, got %+v", jsonLD.Offers)
+	}
+	if len(jsonLD.Image) != 1 || !strings.Contains(jsonLD.Image[0], "/assets/img/cat_") {
+		t.Errorf("expected the placeholder image, got %v", jsonLD.Image)
+	}
+}
+
+func TestBuildEventListPageMeta(t *testing.T) {
+	t.Setenv("APEX_URL", "https://meetnear.me")
+	events := []types.Event{
+		{Id: "evt-1", Name: "First", ImageUrl: "https://cdn.example.com/first.jpg"},
+		{Id: "evt-2", Name: "Second"},
+	}
+
+	t.Run("home page", func(t *testing.T) {
+		meta := BuildEventListPageMeta(nil, events)
+		if meta.OgType != "website" || meta.Url != "https://meetnear.me/" {
+			t.Errorf("unexpected home page meta %+v", meta)
+		}
+		list, ok := meta.JSONLD.(ItemListJSONLD)
+		if !ok {
+			t.Fatalf("expected an ItemList, got %T", meta.JSONLD)
+		}
+		if len(list.ItemListElement) != 2 || list.ItemListElement[1].Position != 2 || list.ItemListElement[1].Item.Name != "Second" {
+			t.Errorf("unexpected list items %+v", list.ItemListElement)
+		}
+		if list.ItemListElement[0].Item.Context != "" {
+			t.Errorf("expected list items to inherit the list's context")
+		}
+	})
+
+	t.Run("user page", func(t *testing.T) {
+		pageUser := &types.UserSearchResult{
+			UserID:      "owner-1",
+			DisplayName: "Quiz Club",
+			Metadata:    map[string]string{constants.META_ABOUT_KEY: "<p>We host <em>trivia</em></p>"},
+		}
+		meta := BuildEventListPageMeta(pageUser, events)
+		if meta.Title != "Quiz Club" || meta.OgType != "profile" || meta.Url != "https://meetnear.me/user/owner-1" {
+			t.Errorf("unexpected user page meta %+v", meta)
+		}
+		if meta.Description != "We host trivia" {
+			t.Errorf("expected the user's about text as description, got %q", meta.Description)
+		}
+		if meta.ImageUrl != "https://cdn.example.com/first.jpg" {
+			t.Errorf("expected the first event's image, got %s", meta.ImageUrl)
+		}
+	})
+
+	t.Run("no events", func(t *testing.T) {
+		if meta := BuildEventListPageMeta(nil, []types.Event{}); meta.JSONLD != nil {
+			t.Errorf("expected no structured data without events, got %+v", meta.JSONLD)
+		}
+	})
+}
+
+func TestPlainTextSummary(t *testing.T) {
+	long := strings.Repeat("a", metaDescriptionMaxRunes+10)
+	if got := PlainTextSummary(long); got != strings.Repeat("a", metaDescriptionMaxRunes)+"…" {
+		t.Errorf("expected truncated summary, got %q", got)
+	}
+	if got := PlainTextSummary("<div>Line one</div>\n<div>Line two</div>"); got != "Line one Line two" {
+		t.Errorf("unexpected summary %q", got)
+	}
+}
@@ -0,0 +1,247 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName         = "github.com/meetnearme/api/functions/gateway"
+	tracingServiceName = "meetnearme-gateway"
+)
+
+// Span attributes of the Seshu scrape pipeline
+const (
+	TRACE_ATTR_JOB_URL          = attribute.Key("seshu.job.url")
+	TRACE_ATTR_EVENTS_SCRAPED   = attribute.Key("seshu.events.scraped")
+	TRACE_ATTR_EVENTS_PRESERVED = attribute.Key("seshu.events.preserved")
+	TRACE_ATTR_EVENTS_DELETED   = attribute.Key("seshu.events.deleted")
+	TRACE_ATTR_EVENTS_INSERTED  = attribute.Key("seshu.events.inserted")
+	TRACE_ATTR_JOBS_PUBLISHED   = attribute.Key("seshu.jobs.published")
+	// TRACE_ATTR_LATENCY_MS is how long an external service took to answer,
+	// set on the span around the call
+	TRACE_ATTR_LATENCY_MS = attribute.Key("external.latency_ms")
+)
+
+// tracingEnabled reports whether an OTLP endpoint is configured, without
+// one the global provider stays the no-op one and spans cost nothing
+func tracingEnabled() bool {
+	if strings.EqualFold(os.Getenv("OTEL_SDK_DISABLED"), "true") {
+		return false
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// InitTracing exports spans over OTLP/HTTP to the collector in the standard
+// `OTEL_EXPORTER_OTLP_*` variables. The returned func flushes what's left,
+// call it before exiting. Trace context is propagated either way so a
+// caller's trace survives an instance that doesn't export
+func InitTracing(ctx context.Context) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if !tracingEnabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+	// `OTEL_SERVICE_NAME` and `OTEL_RESOURCE_ATTRIBUTES` override the defaults
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName(tracingServiceName)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	log.Printf("INFO: exporting traces to %s", firstNonEmpty(os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"), os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")))
+	return provider.Shutdown, nil
+}
+
+// FlushTraces exports the spans still buffered, Lambda can freeze the
+// process between invocations before the batcher gets to them
+func FlushTraces(ctx context.Context) {
+	provider, ok := otel.GetTracerProvider().(*sdktrace.TracerProvider)
+	if !ok {
+		return
+	}
+	if err := provider.ForceFlush(ctx); err != nil {
+		log.Printf("ERR: failed to flush traces: %v", err)
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// StartSpan starts an internal span under whatever span `ctx` holds
+func StartSpan(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartExternalSpan starts a client span around a call to `peerService`,
+// `EndExternalSpan` records how long it took
+func StartExternalSpan(ctx context.Context, name, peerService string, attrs ...attribute.KeyValue) (context.Context, trace.Span, time.Time) {
+	ctx, span := Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(append(attrs, semconv.PeerService(peerService))...),
+	)
+	return ctx, span, time.Now()
+}
+
+func EndExternalSpan(span trace.Span, start time.Time, err error) {
+	span.SetAttributes(TRACE_ATTR_LATENCY_MS.Int64(time.Since(start).Milliseconds()))
+	EndSpan(span, err)
+}
+
+// EndSpan marks the span failed when `err` isn't nil and ends it
+func EndSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// natsHeaderCarrier lets the propagator read and write NATS message headers
+type natsHeaderCarrier nats.Header
+
+func (c natsHeaderCarrier) Get(key string) string {
+	return nats.Header(c).Get(key)
+}
+
+func (c natsHeaderCarrier) Set(key, value string) {
+	nats.Header(c).Set(key, value)
+}
+
+func (c natsHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for key := range c {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// InjectTraceHeaders writes the trace context of `ctx` into `header` so the
+// consumer's spans join the publisher's trace
+func InjectTraceHeaders(ctx context.Context, header nats.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, natsHeaderCarrier(header))
+}
+
+// ExtractTraceHeaders is the counterpart of `InjectTraceHeaders`
+func ExtractTraceHeaders(ctx context.Context, header nats.Header) context.Context {
+	if header == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, natsHeaderCarrier(header))
+}
+
+// statusResponseWriter remembers the status code the handler wrote
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusResponseWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusResponseWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *statusResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// statusCode is what the client got, a handler that wrote nothing sent a 200
+func (w *statusResponseWriter) statusCode() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
+
+// routeTemplate is the template of the route the router matched, ids in
+// the path would make a span name or label of every value
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if template, err := current.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return r.URL.Path
+}
+
+// TracingMiddleware starts a server span for every request the router
+// matched, named after the route template. An incoming `traceparent`
+// header continues its trace
+func TracingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := routeTemplate(r)
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := Tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+			),
+		)
+		defer span.End()
+
+		recorder := &statusResponseWriter{ResponseWriter: w}
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		status := recorder.statusCode()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	})
+}
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// useTestTracer records spans in memory for the rest of the test
+func useTestTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	originalProvider, originalPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(originalProvider)
+		otel.SetTextMapPropagator(originalPropagator)
+	})
+	return exporter
+}
+
+func spanAttribute(span tracetest.SpanStub, key attribute.Key) (attribute.Value, bool) {
+	for _, attr := range span.Attributes {
+		if attr.Key == key {
+			return attr.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestInitTracingUnconfigured(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	originalProvider := otel.GetTracerProvider()
+
+	shutdown, err := InitTracing(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if otel.GetTracerProvider() != originalProvider {
+		t.Error("expected the tracer provider to be left alone")
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("unexpected shutdown error: %v", err)
+	}
+
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318")
+	t.Setenv("OTEL_SDK_DISABLED", "true")
+	if tracingEnabled() {
+		t.Error("expected OTEL_SDK_DISABLED to win over the endpoint")
+	}
+}
+
+func TestTraceHeadersRoundTrip(t *testing.T) {
+	exporter := useTestTracer(t)
+
+	ctx, parent := StartSpan(context.Background(), "publish")
+	header := nats.Header{}
+	InjectTraceHeaders(ctx, header)
+	parent.End()
+	if header.Get("traceparent") == "" {
+		t.Fatalf("expected a traceparent header, got %v", header)
+	}
+
+	_, child := Tracer().Start(ExtractTraceHeaders(context.Background(), header), "consume")
+	child.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	if spans[1].SpanContext.TraceID() != spans[0].SpanContext.TraceID() || spans[1].Parent.SpanID() != spans[0].SpanContext.SpanID() {
+		t.Errorf("expected the consumer span to continue the publisher's trace")
+	}
+	if ctx := ExtractTraceHeaders(context.Background(), nil); trace.SpanContextFromContext(ctx).IsValid() {
+		t.Error("expected no span context without headers")
+	}
+}
+
+func TestEndExternalSpan(t *testing.T) {
+	exporter := useTestTracer(t)
+
+	_, span, start := StartExternalSpan(context.Background(), "scrapingbee.fetch", "scrapingbee", TRACE_ATTR_JOB_URL.String("https://example.com"))
+	EndExternalSpan(span, start.Add(-1500*time.Millisecond), errors.New("timed out"))
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	got := spans[0]
+	if got.SpanKind != trace.SpanKindClient || got.Status.Code != codes.Error {
+		t.Errorf("expected a failed client span, got kind %v status %+v", got.SpanKind, got.Status)
+	}
+	if latency, ok := spanAttribute(got, TRACE_ATTR_LATENCY_MS); !ok || latency.AsInt64() < 1500 {
+		t.Errorf("expected a latency of at least 1500ms, got %v", latency)
+	}
+	if url, _ := spanAttribute(got, TRACE_ATTR_JOB_URL); url.AsString() != "https://example.com" {
+		t.Errorf("expected the job url attribute, got %q", url.AsString())
+	}
+	if peer, _ := spanAttribute(got, "peer.service"); peer.AsString() != "scrapingbee" {
+		t.Errorf("expected peer.service scrapingbee, got %q", peer.AsString())
+	}
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	exporter := useTestTracer(t)
+
+	router := mux.NewRouter()
+	router.Use(TracingMiddleware)
+	router.HandleFunc("/api/events/{eventId}", func(w http.ResponseWriter, r *http.Request) {
+		if !trace.SpanContextFromContext(r.Context()).IsValid() {
+			t.Error("expected the handler to run inside the span")
+		}
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	upstream := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	req := httptest.NewRequest(http.MethodGet, "/api/events/123", nil)
+	propagation.TraceContext{}.Inject(trace.ContextWithSpanContext(context.Background(), upstream), propagation.HeaderCarrier(req.Header))
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	got := spans[0]
+	if got.Name != "GET /api/events/{eventId}" {
+		t.Errorf("expected the span to be named after the route, got %q", got.Name)
+	}
+	if got.SpanContext.TraceID() != upstream.TraceID() {
+		t.Error("expected the incoming traceparent to be continued")
+	}
+	if status, _ := spanAttribute(got, "http.response.status_code"); status.AsInt64() != http.StatusBadGateway {
+		t.Errorf("expected status 502, got %v", status)
+	}
+	if got.Status.Code != codes.Error {
+		t.Errorf("expected a 5xx to mark the span failed, got %+v", got.Status)
+	}
+}
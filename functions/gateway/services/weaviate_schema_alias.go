@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/google/uuid"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/fault"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+const (
+	// SCHEMA_ALIAS_CLASS_NAME holds one object per alias naming the versioned
+	// class it currently resolves to. The Weaviate version we run predates
+	// native collection aliases
+	SCHEMA_ALIAS_CLASS_NAME = "SchemaAlias"
+	// EVENT_CLASS_ALIAS is the alias the app reads and writes events through
+	EVENT_CLASS_ALIAS = "Event"
+	// How often running instances re-read EVENT_CLASS_ALIAS
+	EVENT_CLASS_ALIAS_REFRESH_INTERVAL = 30 * time.Second
+)
+
+// SchemaAlias points an alias at a versioned class. `Version` is the schema
+// migration that produced `ClassName`
+type SchemaAlias struct {
+	Alias     string
+	ClassName string
+	Version   int
+	UpdatedAt int64
+}
+
+var activeEventClassName atomic.Value
+
+func init() {
+	activeEventClassName.Store(constants.WeaviateEventClassName)
+}
+
+// EventClassName is the versioned events class this instance currently reads
+// and writes. It starts as `constants.WeaviateEventClassName` and follows the
+// `Event` alias once `RefreshEventClassName` has read it
+func EventClassName() string {
+	return activeEventClassName.Load().(string)
+}
+
+// SetEventClassName switches this instance to the events class `className`
+func SetEventClassName(className string) {
+	if previous := activeEventClassName.Swap(className); previous != className {
+		log.Printf("Weaviate events class switched from '%s' to '%s'", previous, className)
+	}
+}
+
+// schemaAliasID derives a stable object ID from the alias name so updating
+// the alias is a single object write
+func schemaAliasID(alias string) strfmt.UUID {
+	return strfmt.UUID(uuid.NewSHA1(uuid.NameSpaceURL, []byte("meetnearme:schema-alias:"+alias)).String())
+}
+
+func schemaAliasClassDefinition() *models.Class {
+	return &models.Class{
+		Class:       SCHEMA_ALIAS_CLASS_NAME,
+		Description: "Points schema aliases at versioned classes",
+		Vectorizer:  "none",
+		Properties: []*models.Property{
+			{Name: "alias", DataType: []string{"text"}, Tokenization: "field", Description: "Alias name"},
+			{Name: "className", DataType: []string{"text"}, Tokenization: "field", Description: "Versioned class the alias resolves to"},
+			{Name: "version", DataType: []string{"int"}, Description: "Schema migration version of the class"},
+			{Name: "updatedAt", DataType: []string{"int"}, Description: "When the alias last moved (Unix epoch)"},
+		},
+	}
+}
+
+// GetSchemaAlias reads `alias`, nil when it was never set
+func GetSchemaAlias(ctx context.Context, client *weaviate.Client, alias string) (*SchemaAlias, error) {
+	exists, err := client.Schema().ClassExistenceChecker().WithClassName(SCHEMA_ALIAS_CLASS_NAME).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed checking class existence: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	objects, err := client.Data().ObjectsGetter().WithClassName(SCHEMA_ALIAS_CLASS_NAME).WithID(string(schemaAliasID(alias))).Do(ctx)
+	var clientErr *fault.WeaviateClientError
+	if errors.As(err, &clientErr) && clientErr.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema alias '%s': %w", alias, err)
+	}
+	if len(objects) == 0 {
+		return nil, nil
+	}
+	props, _ := objects[0].Properties.(map[string]interface{})
+	schemaAlias := &SchemaAlias{Alias: alias}
+	schemaAlias.ClassName, _ = props["className"].(string)
+	if version, ok := props["version"].(float64); ok {
+		schemaAlias.Version = int(version)
+	}
+	if updatedAt, ok := props["updatedAt"].(float64); ok {
+		schemaAlias.UpdatedAt = int64(updatedAt)
+	}
+	if schemaAlias.ClassName == "" {
+		return nil, fmt.Errorf("schema alias '%s' has no class name", alias)
+	}
+	return schemaAlias, nil
+}
+
+// SetSchemaAlias points `schemaAlias.Alias` at `schemaAlias.ClassName`. The
+// alias is one object, so readers see either the old class or the new one
+func SetSchemaAlias(ctx context.Context, client *weaviate.Client, schemaAlias SchemaAlias) error {
+	exists, err := client.Schema().ClassExistenceChecker().WithClassName(SCHEMA_ALIAS_CLASS_NAME).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed checking class existence: %w", err)
+	}
+	if !exists {
+		if err := client.Schema().ClassCreator().WithClass(schemaAliasClassDefinition()).Do(ctx); err != nil {
+			return fmt.Errorf("failed to create class '%s': %w", SCHEMA_ALIAS_CLASS_NAME, err)
+		}
+	}
+
+	if schemaAlias.UpdatedAt == 0 {
+		schemaAlias.UpdatedAt = time.Now().Unix()
+	}
+	res, err := client.Batch().ObjectsBatcher().WithObjects(&models.Object{
+		Class: SCHEMA_ALIAS_CLASS_NAME,
+		ID:    schemaAliasID(schemaAlias.Alias),
+		Properties: map[string]interface{}{
+			"alias":     schemaAlias.Alias,
+			"className": schemaAlias.ClassName,
+			"version":   schemaAlias.Version,
+			"updatedAt": schemaAlias.UpdatedAt,
+		},
+	}).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to set schema alias '%s': %w", schemaAlias.Alias, err)
+	}
+	for _, r := range res {
+		if r.Result != nil && r.Result.Errors != nil && len(r.Result.Errors.Error) > 0 {
+			return fmt.Errorf("failed to set schema alias '%s': %s", schemaAlias.Alias, r.Result.Errors.Error[0].Message)
+		}
+	}
+	return nil
+}
+
+// RefreshEventClassName re-reads the `Event` alias and switches this instance
+// to the class it names. An unset alias keeps the current class
+func RefreshEventClassName(ctx context.Context, client *weaviate.Client) (string, error) {
+	schemaAlias, err := GetSchemaAlias(ctx, client, EVENT_CLASS_ALIAS)
+	if err != nil {
+		return EventClassName(), err
+	}
+	if schemaAlias != nil {
+		SetEventClassName(schemaAlias.ClassName)
+	}
+	return EventClassName(), nil
+}
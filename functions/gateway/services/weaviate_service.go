@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/go-openapi/strfmt"
@@ -36,7 +35,6 @@ const (
 )
 
 const vectorizer = "text2vec-transformers"
-const eventClassName = constants.WeaviateEventClassName
 
 // Create a new struct for raw JSON operations
 type RawEventData struct {
@@ -54,21 +52,27 @@ type RawEventData struct {
 
 type RawEvent struct {
 	RawEventData
-	EventSourceId         *string     `json:"eventSourceId,omitempty"`
-	StartTime             interface{} `json:"startTime" validate:"required"`
-	EndTime               interface{} `json:"endTime,omitempty"`
-	StartingPrice         *int32      `json:"startingPrice,omitempty"`
-	Currency              *string     `json:"currency,omitempty"`
-	PayeeId               *string     `json:"payeeId,omitempty"`
-	SourceUrl             *string     `json:"sourceUrl,omitempty"`
-	CompetitionConfigId   *string     `json:"competitionConfigId,omitempty"`
-	HasRegistrationFields *bool       `json:"hasRegistrationFields,omitempty"`
-	HasPurchasable        *bool       `json:"hasPurchasable,omitempty"`
-	ImageUrl              *string     `json:"imageUrl,omitempty"`
-	Categories            *[]string   `json:"categories,omitempty"`
-	Tags                  *[]string   `json:"tags,omitempty"`
-	UpdatedBy             *string     `json:"updatedBy,omitempty"`
-	HideCrossPromo        *bool       `json:"hideCrossPromo,omitempty"`
+	EventSourceId         *string       `json:"eventSourceId,omitempty"`
+	StartTime             interface{}   `json:"startTime" validate:"required"`
+	EndTime               interface{}   `json:"endTime,omitempty"`
+	StartingPrice         *int32        `json:"startingPrice,omitempty"`
+	Currency              *string       `json:"currency,omitempty"`
+	PayeeId               *string       `json:"payeeId,omitempty"`
+	SourceUrl             *string       `json:"sourceUrl,omitempty"`
+	CompetitionConfigId   *string       `json:"competitionConfigId,omitempty"`
+	HasRegistrationFields *bool         `json:"hasRegistrationFields,omitempty"`
+	HasPurchasable        *bool         `json:"hasPurchasable,omitempty"`
+	ImageUrl              *string       `json:"imageUrl,omitempty"`
+	Categories            *[]string     `json:"categories,omitempty"`
+	Tags                  *[]string     `json:"tags,omitempty"`
+	UpdatedBy             *string       `json:"updatedBy,omitempty"`
+	HideCrossPromo        *bool         `json:"hideCrossPromo,omitempty"`
+	RecurrenceRule        *string       `json:"recurrenceRule,omitempty"`
+	RecurrenceStart       interface{}   `json:"recurrenceStart,omitempty"`
+	RecurrenceRDates      []interface{} `json:"recurrenceRDates,omitempty"`
+	RecurrenceExDates     []interface{} `json:"recurrenceExDates,omitempty"`
+	RecurrenceId          interface{}   `json:"recurrenceId,omitempty"`
+	RecurrenceOverride    *bool         `json:"recurrenceOverride,omitempty"`
 }
 
 func GetWeaviateClient() (*weaviate.Client, error) {
@@ -127,21 +131,57 @@ func (e *WeaviateService) UpsertEventToWeaviate(client *weaviate.Client, event t
 	return nil, nil
 }
 
+// CreateWeaviateSchemaIfMissing creates the events class the app currently
+// points at, see `EventClassName`. New properties appended to the definition
+// are added to an existing class, anything else needs a migration
 func CreateWeaviateSchemaIfMissing(ctx context.Context, client *weaviate.Client) error {
-	// Delete class if exists (same as before)
-	exists, err := client.Schema().ClassExistenceChecker().WithClassName(eventClassName).Do(ctx)
+	className := EventClassName()
+	exists, err := client.Schema().ClassExistenceChecker().WithClassName(className).Do(ctx)
 	if err != nil {
 		return fmt.Errorf("failed checking class existence: %w", err)
 	}
 	if exists {
-		// log.Fatalf("FATAL: Class '%s' exists.", eventClassName)
-		log.Printf("Weaviate Class '%s' exists. Skipping schema definition.", eventClassName)
-		return nil
+		log.Printf("Weaviate Class '%s' exists. Skipping schema definition.", className)
+		return addMissingEventProperties(ctx, client, className)
 	}
 
+	err = client.Schema().ClassCreator().WithClass(EventClassDefinition(className)).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create class '%s': %w", className, err)
+	}
+	return nil
+}
+
+// addMissingEventProperties adds properties that were appended to the class
+// definition after the class was created, existing properties can't be
+// altered this way (that still needs a new class version)
+func addMissingEventProperties(ctx context.Context, client *weaviate.Client, className string) error {
+	existing, err := client.Schema().ClassGetter().WithClassName(className).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get class '%s': %w", className, err)
+	}
+	known := make(map[string]bool, len(existing.Properties))
+	for _, property := range existing.Properties {
+		known[property.Name] = true
+	}
+
+	for _, property := range EventClassDefinition(className).Properties {
+		if known[property.Name] {
+			continue
+		}
+		log.Printf("Adding property '%s' to Weaviate Class '%s'", property.Name, className)
+		err := client.Schema().PropertyCreator().WithClassName(className).WithProperty(property).Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to add property '%s' to class '%s': %w", property.Name, className, err)
+		}
+	}
+	return nil
+}
+
+func EventClassDefinition(className string) *models.Class {
 	// Define class structure using models.Property and string data types
 	eventClass := &models.Class{
-		Class:       eventClassName,
+		Class:       className,
 		Description: "Stores event information using the strict Go struct definition",
 		Vectorizer:  vectorizer,
 		ModuleConfig: map[string]interface{}{
@@ -247,6 +287,25 @@ func CreateWeaviateSchemaIfMissing(ctx context.Context, client *weaviate.Client)
 			{Name: "competitionConfigId", DataType: []string{"text"}, Description: "Optional competition config ID",
 				ModuleConfig: map[string]interface{}{vectorizer: map[string]interface{}{"skip": true}},
 			},
+			{Name: "recurrenceRule", DataType: []string{"text"}, Description: "Optional RFC 5545 RRULE of a series parent",
+				ModuleConfig: map[string]interface{}{vectorizer: map[string]interface{}{"skip": true}},
+				Tokenization: "field",
+			},
+			{Name: "recurrenceStart", DataType: []string{"int"}, Description: "Series anchor (DTSTART) timestamp (Unix epoch)",
+				ModuleConfig: map[string]interface{}{vectorizer: map[string]interface{}{"skip": true}},
+			},
+			{Name: "recurrenceRDates", DataType: []string{"int[]"}, Description: "Additional series occurrence timestamps (Unix epoch)",
+				ModuleConfig: map[string]interface{}{vectorizer: map[string]interface{}{"skip": true}},
+			},
+			{Name: "recurrenceExDates", DataType: []string{"int[]"}, Description: "Excluded series occurrence timestamps (Unix epoch)",
+				ModuleConfig: map[string]interface{}{vectorizer: map[string]interface{}{"skip": true}},
+			},
+			{Name: "recurrenceId", DataType: []string{"int"}, Description: "Originally scheduled start of a series occurrence (Unix epoch)",
+				ModuleConfig: map[string]interface{}{vectorizer: map[string]interface{}{"skip": true}},
+			},
+			{Name: "recurrenceOverride", DataType: []string{"boolean"}, Description: "Flag for a series occurrence edited independently of its rule",
+				ModuleConfig: map[string]interface{}{vectorizer: map[string]interface{}{"skip": true}},
+			},
 			{Name: "localStartDate", DataType: []string{"text"}, Description: "UI field: Localized start date string", // UI fields as text
 				ModuleConfig: map[string]interface{}{vectorizer: map[string]interface{}{"skip": true}},
 			},
@@ -256,12 +315,7 @@ func CreateWeaviateSchemaIfMissing(ctx context.Context, client *weaviate.Client)
 		},
 	}
 
-	// Create the class (same as before)
-	err = client.Schema().ClassCreator().WithClass(eventClass).Do(ctx)
-	if err != nil {
-		return fmt.Errorf("failed to create class '%s': %w", eventClassName, err)
-	}
-	return nil
+	return eventClass
 }
 
 // ToMap converts the Event struct to map[string]interface{} for Weaviate.
@@ -330,6 +384,24 @@ func EventStructToMap(e types.Event) map[string]interface{} {
 	if e.CompetitionConfigId != "" {
 		props["competitionConfigId"] = e.CompetitionConfigId
 	}
+	if e.RecurrenceRule != "" {
+		props["recurrenceRule"] = e.RecurrenceRule
+	}
+	if e.RecurrenceStart != 0 {
+		props["recurrenceStart"] = e.RecurrenceStart
+	}
+	if len(e.RecurrenceRDates) > 0 {
+		props["recurrenceRDates"] = e.RecurrenceRDates
+	}
+	if len(e.RecurrenceExDates) > 0 {
+		props["recurrenceExDates"] = e.RecurrenceExDates
+	}
+	if e.RecurrenceId != 0 {
+		props["recurrenceId"] = e.RecurrenceId
+	}
+	if e.RecurrenceOverride {
+		props["recurrenceOverride"] = e.RecurrenceOverride
+	}
 
 	return props
 }
@@ -408,6 +480,9 @@ func ConvertRawEventToEvent(raw RawEvent, requireId bool) (types.Event, error) {
 		}
 		event.EndTime = endTime
 	}
+	if err := convertRawRecurrence(raw, loc, &event); err != nil {
+		return types.Event{}, err
+	}
 	if raw.PayeeId != nil || raw.StartingPrice != nil || raw.Currency != nil {
 
 		if raw.PayeeId == nil || raw.StartingPrice == nil || raw.Currency == nil {
@@ -487,7 +562,7 @@ func SingleValidateEvent(rawEvent RawEvent, requireId bool) (types.Event, int, e
 }
 
 func BulkUpsertEventsToWeaviate(ctx context.Context, client *weaviate.Client, events []types.Event) ([]models.ObjectsGetResponse, error) {
-	className := eventClassName
+	className := EventClassName()
 	batchSize := 50
 
 	batcher := client.Batch().ObjectsBatcher()
@@ -558,216 +633,93 @@ func SearchWeaviateEvents(
 	eventSourceTypes []string,
 	eventSourceIds []string,
 ) (types.EventSearchResponse, error) {
-	className := eventClassName
-	limit := 100
-	var gqlQueryStringForResponse string
-	var whereFilterForResponse string
-
-	// Build Hybrid Search Argument
-	var hybridArgument *graphql.HybridArgumentBuilder
-	fullTextQueryParts := []string{}
-	if query != "" {
-		fullTextQueryParts = append(fullTextQueryParts, query)
-	}
-	if categories != "" {
-		fullTextQueryParts = append(fullTextQueryParts, categories)
-	}
-	if address != "" {
-		fullTextQueryParts = append(fullTextQueryParts, address)
-	}
-	finalHybridQuery := strings.Join(fullTextQueryParts, " ")
-	gqlQueryStringForResponse = finalHybridQuery
-
-	if finalHybridQuery != "" {
-		hybridArgument = client.GraphQL().HybridArgumentBuilder().
-			WithQuery(finalHybridQuery).
-			WithAlpha(0.75)
-	}
-
-	whereOperands := []*filters.WhereBuilder{}
+	return SearchWeaviateEventsPage(ctx, client, query, userLocation, maxDistance, startTime, endTime, ownerIds, categories, address, parseDates, eventSourceTypes, eventSourceIds, EventSearchPage{})
+}
 
-	// Will usually be unix now
-	searchStart := startTime
-	searchEnd := endTime
+// eventSearchFields are the summary properties returned by event searches
+var eventSearchFields = []graphql.Field{
+	{Name: "name"},
+	{Name: "description"},
+	{Name: "eventOwners"},
+	{Name: "eventOwnerName"},
+	{Name: "eventSourceType"},
+	{Name: "startTime"},
+	{Name: "endTime"},
+	{Name: "address"},
+	{Name: "lat"},
+	{Name: "long"},
+	{Name: "eventSourceId"},
+	{Name: "timezone"},
+	{Name: "categories"},
+	{Name: "shadowOwners"},
+	{Name: "recurrenceRule"},
+	{Name: "recurrenceId"},
+	{Name: "recurrenceOverride"},
+	{Name: "_additional", Fields: []graphql.Field{
+		{Name: "id"},
+		{Name: "score"},
+		{Name: "creationTimeUnix"},
+		{Name: "lastUpdateTimeUnix"},
+	}},
+}
 
-	// Time Filter
-	// Build time filter based on whether we have start and/or end time constraints
-	if searchStart > 0 && searchEnd > 0 {
-		// Both start and end time specified
-		timeFilter := (&filters.WhereBuilder{}).
-			WithOperator(filters.And).
-			WithOperands([]*filters.WhereBuilder{
-				(&filters.WhereBuilder{}).WithPath([]string{"startTime"}).WithOperator(filters.GreaterThanEqual).WithValueInt(searchStart),
-				(&filters.WhereBuilder{}).WithPath([]string{"startTime"}).WithOperator(filters.LessThanEqual).WithValueInt(searchEnd),
-			})
-		whereOperands = append(whereOperands, timeFilter)
-	} else if searchStart > 0 {
-		// Only start time specified (events starting after searchStart)
-		timeFilter := (&filters.WhereBuilder{}).
-			WithPath([]string{"startTime"}).
-			WithOperator(filters.GreaterThanEqual).
-			WithValueInt(searchStart)
-		whereOperands = append(whereOperands, timeFilter)
-	} else if searchEnd > 0 {
-		// Only end time specified (events starting before searchEnd)
-		timeFilter := (&filters.WhereBuilder{}).
-			WithPath([]string{"startTime"}).
-			WithOperator(filters.LessThanEqual).
-			WithValueInt(searchEnd)
-		whereOperands = append(whereOperands, timeFilter)
-	}
-	// If both are 0 or negative, no time filter is applied
-
-	// Location Filter (Uncommented and integrated)
-	if len(userLocation) == 2 && maxDistance > 0 {
-		// minLat, maxLat, minLong1, maxLong1, _, maxLong2, needsSplit := calculateSearchBounds(userLocation, maxDistance)
-		minLat, maxLat, minLong1, maxLong1, minLong2, maxLong2, needsSplit := calculateSearchBounds(userLocation, maxDistance)
-
-		latFilter := (&filters.WhereBuilder{}).
-			WithOperator(filters.And).
-			WithOperands([]*filters.WhereBuilder{
-				(&filters.WhereBuilder{}).WithPath([]string{"lat"}).WithOperator(filters.GreaterThanEqual).WithValueNumber(minLat),
-				(&filters.WhereBuilder{}).WithPath([]string{"lat"}).WithOperator(filters.LessThanEqual).WithValueNumber(maxLat),
-			})
-		whereOperands = append(whereOperands, latFilter)
-
-		var longFilter *filters.WhereBuilder
-		if needsSplit {
-			longCondition1 := (&filters.WhereBuilder{}).
-				WithOperator(filters.And).
-				WithOperands([]*filters.WhereBuilder{
-					(&filters.WhereBuilder{}).WithPath([]string{"long"}).WithOperator(filters.GreaterThanEqual).WithValueNumber(minLong1),
-					(&filters.WhereBuilder{}).WithPath([]string{"long"}).WithOperator(filters.LessThanEqual).WithValueNumber(maxLong1),
-				})
-			longCondition2 := (&filters.WhereBuilder{}).
-				WithOperator(filters.And).
-				WithOperands([]*filters.WhereBuilder{
-					(&filters.WhereBuilder{}).WithPath([]string{"long"}).WithOperator(filters.GreaterThanEqual).WithValueNumber(minLong2),
-					(&filters.WhereBuilder{}).WithPath([]string{"long"}).WithOperator(filters.LessThanEqual).WithValueNumber(maxLong2),
-				})
-			longFilter = (&filters.WhereBuilder{}).WithOperator(filters.Or).WithOperands([]*filters.WhereBuilder{longCondition1, longCondition2})
-		} else {
-			longFilter = (&filters.WhereBuilder{}).
-				WithOperator(filters.And).
-				WithOperands([]*filters.WhereBuilder{
-					(&filters.WhereBuilder{}).WithPath([]string{"long"}).WithOperator(filters.GreaterThanEqual).WithValueNumber(minLong1),
-					(&filters.WhereBuilder{}).WithPath([]string{"long"}).WithOperator(filters.LessThanEqual).WithValueNumber(maxLong1),
-				})
-		}
-		whereOperands = append(whereOperands, longFilter)
-	}
-
-	// Owner Filter - Search both eventOwners and shadowOwners fields
-	if len(ownerIds) > 0 {
-		eventOwnersFilter := (&filters.WhereBuilder{}).
-			WithPath([]string{"eventOwners"}).
-			WithOperator(filters.ContainsAny).
-			WithValueText(ownerIds...)
-
-		shadowOwnersFilter := (&filters.WhereBuilder{}).
-			WithPath([]string{"shadowOwners"}).
-			WithOperator(filters.ContainsAny).
-			WithValueText(ownerIds...)
-
-		ownerFilter := (&filters.WhereBuilder{}).
-			WithOperator(filters.Or).
-			WithOperands([]*filters.WhereBuilder{eventOwnersFilter, shadowOwnersFilter})
-
-		whereOperands = append(whereOperands, ownerFilter)
-	}
-
-	// Type Filter (Integrated)
-	typesToSearch := eventSourceTypes
-	if len(typesToSearch) == 0 && len(constants.DEFAULT_SEARCHABLE_EVENT_SOURCE_TYPES) > 0 {
-		typesToSearch = constants.DEFAULT_SEARCHABLE_EVENT_SOURCE_TYPES
-	}
-	if len(typesToSearch) > 0 {
-		typeFilter := (&filters.WhereBuilder{}).
-			WithPath([]string{"eventSourceType"}).
-			WithOperator(filters.ContainsAny).
-			WithValueText(typesToSearch...)
-		whereOperands = append(whereOperands, typeFilter)
-	}
-
-	// Event Source ID Filter (Uncommented and integrated)
-	// Note: eventSourceId is a single text field, not an array, so we need to use Equal operator
-	if len(eventSourceIds) > 0 {
-		if len(eventSourceIds) == 1 {
-			// Single value: use Equal operator
-			sourceIdFilter := (&filters.WhereBuilder{}).
-				WithPath([]string{"eventSourceId"}).
-				WithOperator(filters.Equal).
-				WithValueText(eventSourceIds[0])
-			whereOperands = append(whereOperands, sourceIdFilter)
-		} else {
-			// Multiple values: combine with Or operator
-			sourceIdFilterOperands := make([]*filters.WhereBuilder, 0, len(eventSourceIds))
-			for _, sourceId := range eventSourceIds {
-				sourceIdFilterOperands = append(sourceIdFilterOperands, (&filters.WhereBuilder{}).
-					WithPath([]string{"eventSourceId"}).
-					WithOperator(filters.Equal).
-					WithValueText(sourceId))
-			}
-			sourceIdFilter := (&filters.WhereBuilder{}).
-				WithOperator(filters.Or).
-				WithOperands(sourceIdFilterOperands)
-			whereOperands = append(whereOperands, sourceIdFilter)
-		}
+// SearchWeaviateEventsPage is `SearchWeaviateEvents` for callers that page
+// through results. `page.Cursor` is the `NextCursor` of a previous response
+// for the same search, a cursor from a different search is rejected with
+// `ErrInvalidSearchCursor`
+func SearchWeaviateEventsPage(
+	ctx context.Context,
+	client *weaviate.Client,
+	query string,
+	userLocation []float64,
+	maxDistance float64,
+	startTime, endTime int64,
+	ownerIds []string,
+	categories string,
+	address string,
+	parseDates string,
+	eventSourceTypes []string,
+	eventSourceIds []string,
+	page EventSearchPage,
+) (types.EventSearchResponse, error) {
+	className := EventClassName()
+	plan, err := planEventSearch(query, userLocation, maxDistance, startTime, endTime, ownerIds, categories, address, eventSourceTypes, eventSourceIds, page)
+	if err != nil {
+		return types.EventSearchResponse{Query: query, Events: []types.Event{}}, err
 	}
 
-	// Combine all operands into a single final filter
-	var finalWhereFilter *filters.WhereBuilder
-	if len(whereOperands) > 0 {
-		finalWhereFilter = (&filters.WhereBuilder{}).WithOperator(filters.And).WithOperands(whereOperands)
-		// filterBytes, _ := json.MarshalIndent(finalWhereFilter, "", "  ")
-		// whereFilterForResponse = string(filterBytes)
-	}
-
-	// Define Response Fields
-	fields := []graphql.Field{
-		{Name: "name"},
-		{Name: "description"},
-		{Name: "eventOwners"},
-		{Name: "eventOwnerName"},
-		{Name: "eventSourceType"},
-		{Name: "startTime"},
-		{Name: "endTime"},
-		{Name: "address"},
-		{Name: "lat"},
-		{Name: "long"},
-		{Name: "eventSourceId"},
-		{Name: "timezone"},
-		{Name: "shadowOwners"},
-		{Name: "_additional", Fields: []graphql.Field{
-			{Name: "id"},
-			{Name: "score"},
-			{Name: "creationTimeUnix"},
-			{Name: "lastUpdateTimeUnix"},
-		}},
+	fetchLimit := plan.fetchLimit()
+	if fetchLimit == 0 {
+		return plan.exhaustedResponse(), nil
 	}
 
 	// Construct and Execute Query
 	queryBuilder := client.GraphQL().Get().
 		WithClassName(className)
 
-	if hybridArgument != nil {
-		queryBuilder.WithHybrid(hybridArgument)
+	if plan.hybridQuery != "" {
+		queryBuilder.WithHybrid(client.GraphQL().HybridArgumentBuilder().
+			WithQuery(plan.hybridQuery).
+			WithAlpha(0.75))
+		if plan.offset > 0 {
+			queryBuilder.WithOffset(plan.offset)
+		}
+	} else {
+		queryBuilder.WithSort(graphql.Sort{Path: []string{"startTime"}, Order: graphql.Asc})
 	}
 
 	// Apply the single, consolidated filter
-	if finalWhereFilter != nil {
-		queryBuilder.WithWhere(finalWhereFilter)
+	if pageFilter := plan.pageFilter(); pageFilter != nil {
+		queryBuilder.WithWhere(pageFilter)
 	}
-	// Apply hybrid search if applicable
 	queryBuilder.
-		WithFields(fields...).WithLimit(limit)
+		WithFields(eventSearchFields...).WithLimit(fetchLimit)
 
 	searchResult, err := queryBuilder.Do(ctx)
 	if err != nil {
 		log.Printf("Error searching documents: %v", err)
 		return types.EventSearchResponse{
 			Query:  query,
-			Filter: whereFilterForResponse,
 			Events: []types.Event{},
 		}, err
 	}
@@ -794,27 +746,64 @@ func SearchWeaviateEvents(
 			rawHits = append(rawHits, objMap)
 		}
 	}
-	// (Your full normalization and date parsing logic goes here)
-	for _, doc := range rawHits {
+	for i, doc := range rawHits {
+		if i == plan.limit {
+			break
+		}
 		event, err := NormalizeWeaviateResultToEvent(doc)
 		if err != nil {
 			log.Printf("Warning: Could not normalize Weaviate result: %v", err)
 			continue
 		}
-		if parseDates == "1" && event.Timezone.String() != "" {
-			localizedTime, localizedDate := helpers.GetLocalDateAndTime(event.StartTime, event.Timezone)
-			event.LocalizedStartTime = localizedTime
-			event.LocalizedStartDate = localizedDate
+		events = append(events, *event)
+	}
+
+	// The total is counted once against the filters alone, before the keyset
+	// operands narrow them to "after the previous page"
+	res := plan.response(len(rawHits), events, parseDates, func() (int, error) {
+		return countWeaviateEvents(ctx, client, plan.countFilter())
+	})
+
+	// Facets only decorate filter controls, a failed count shouldn't fail the search
+	if page.Facets {
+		res.Facets, err = searchWeaviateEventFacets(ctx, client, plan.filters, plan.searchStart(), plan.searchEnd(), page.Timezone, page.Overlap)
+		if err != nil {
+			log.Printf("Warning: could not compute search facets: %v", err)
 		}
+	}
 
-		events = append(events, *event)
+	return res, nil
+}
+
+// countWeaviateEvents counts the events matching `where`. Hybrid search ranks
+// every object that passes the filters, so this is also the size of a hybrid
+// result set
+func countWeaviateEvents(ctx context.Context, client *weaviate.Client, where *filters.WhereBuilder) (int, error) {
+	className := EventClassName()
+	aggregateBuilder := client.GraphQL().Aggregate().
+		WithClassName(className).
+		WithFields(graphql.Field{Name: "meta", Fields: []graphql.Field{{Name: "count"}}})
+	if where != nil {
+		aggregateBuilder.WithWhere(where)
 	}
 
-	return types.EventSearchResponse{
-		Query:  gqlQueryStringForResponse,
-		Filter: whereFilterForResponse,
-		Events: events,
-	}, nil
+	result, err := aggregateBuilder.Do(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(result.Errors) > 0 {
+		return 0, fmt.Errorf("aggregate query failed: %s", result.Errors[0].Message)
+	}
+
+	aggregateMap, ok := result.Data["Aggregate"].(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("aggregate response missing Aggregate field")
+	}
+	count, ok := aggregateMetaCount(aggregateMap[className])
+	if !ok {
+		return 0, fmt.Errorf("aggregate response missing %s meta.count", className)
+	}
+	return count, nil
 }
 
 func BulkDeleteEventsFromWeaviate(ctx context.Context, client *weaviate.Client, eventIds []string) (*models.BatchDeleteResponse, error) {
@@ -829,7 +818,7 @@ func BulkDeleteEventsFromWeaviate(ctx context.Context, client *weaviate.Client,
 		}, nil
 	}
 
-	className := eventClassName
+	className := EventClassName()
 
 	whereFilter := (&filters.WhereBuilder{}).
 		WithPath([]string{"id"}).
@@ -872,36 +861,41 @@ func GetWeaviateEventByID(ctx context.Context, client *weaviate.Client, docId st
 	return events[0], nil
 }
 
+// eventDetailFields are the properties returned when loading full events
+// (as opposed to the summary `eventSearchFields`)
+var eventDetailFields = []graphql.Field{
+	{Name: "name"}, {Name: "description"}, {Name: "eventOwners"}, {Name: "eventOwnerName"},
+	{Name: "eventSourceType"}, {Name: "startTime"}, {Name: "endTime"}, {Name: "address"},
+	{Name: "lat"}, {Name: "long"}, {Name: "eventSourceId"}, {Name: "startingPrice"},
+	{Name: "currency"}, {Name: "payeeId"}, {Name: "sourceUrl"}, {Name: "hasRegistrationFields"}, {Name: "hasPurchasable"},
+	{Name: "imageUrl"}, {Name: "timezone"}, {Name: "categories"}, {Name: "tags"},
+	{Name: "updatedBy"}, {Name: "refUrl"},
+	{Name: "hideCrossPromo"}, {Name: "competitionConfigId"},
+	{Name: "shadowOwners"},
+	{Name: "recurrenceRule"}, {Name: "recurrenceStart"}, {Name: "recurrenceRDates"}, {Name: "recurrenceExDates"},
+	{Name: "recurrenceId"}, {Name: "recurrenceOverride"},
+	{Name: "_additional", Fields: []graphql.Field{
+		{Name: "id"},                 // We always need the ID
+		{Name: "creationTimeUnix"},   // creationTimeUnix is implicit in Weaviate
+		{Name: "lastUpdateTimeUnix"}, // lastUpdateTimeUnix is implicit in Weaviate
+	}},
+}
+
 func BulkGetWeaviateEventByID(ctx context.Context, client *weaviate.Client, docIds []string, parseDates string) ([]*types.Event, error) {
 	if len(docIds) == 0 {
 		return []*types.Event{}, nil
 	}
 
+	className := EventClassName()
 	whereFilter := (&filters.WhereBuilder{}).
 		WithPath([]string{"id"}).
 		WithOperator(filters.ContainsAny).
 		WithValueText(docIds...)
 
-	fields := []graphql.Field{
-		{Name: "name"}, {Name: "description"}, {Name: "eventOwners"}, {Name: "eventOwnerName"},
-		{Name: "eventSourceType"}, {Name: "startTime"}, {Name: "endTime"}, {Name: "address"},
-		{Name: "lat"}, {Name: "long"}, {Name: "eventSourceId"}, {Name: "startingPrice"},
-		{Name: "currency"}, {Name: "payeeId"}, {Name: "sourceUrl"}, {Name: "hasRegistrationFields"}, {Name: "hasPurchasable"},
-		{Name: "imageUrl"}, {Name: "timezone"}, {Name: "categories"}, {Name: "tags"},
-		{Name: "updatedBy"}, {Name: "refUrl"},
-		{Name: "hideCrossPromo"}, {Name: "competitionConfigId"},
-		{Name: "shadowOwners"},
-		{Name: "_additional", Fields: []graphql.Field{
-			{Name: "id"},                 // We always need the ID
-			{Name: "creationTimeUnix"},   // creationTimeUnix is implicit in Weaviate
-			{Name: "lastUpdateTimeUnix"}, // lastUpdateTimeUnix is implicit in Weaviate
-		}},
-	}
-
 	result, err := client.GraphQL().Get().
-		WithClassName(eventClassName).
+		WithClassName(className).
 		WithWhere(whereFilter).
-		WithFields(fields...).
+		WithFields(eventDetailFields...).
 		WithLimit(len(docIds)).
 		Do(ctx)
 
@@ -916,9 +910,9 @@ func BulkGetWeaviateEventByID(ctx context.Context, client *weaviate.Client, docI
 		log.Println("Weaviate 'Get by ID' query returned invalid data structure.")
 		return []*types.Event{}, nil
 	}
-	classData, ok := getMap[eventClassName].([]interface{})
+	classData, ok := getMap[className].([]interface{})
 	if !ok {
-		log.Printf("Weaviate 'Get by ID' query for class '%s' returned no results.", eventClassName)
+		log.Printf("Weaviate 'Get by ID' query for class '%s' returned no results.", className)
 		return []*types.Event{}, nil // Return empty, not an error
 	}
 
@@ -945,6 +939,61 @@ func BulkGetWeaviateEventByID(ctx context.Context, client *weaviate.Client, docI
 	return events, nil
 }
 
+func recurringSeriesParentsFilter() *filters.WhereBuilder {
+	return (&filters.WhereBuilder{}).
+		WithOperator(filters.And).
+		WithOperands([]*filters.WhereBuilder{
+			(&filters.WhereBuilder{}).WithPath([]string{"eventSourceType"}).WithOperator(filters.ContainsAny).
+				WithValueText(constants.ES_SERIES_PARENT, constants.ES_SERIES_PARENT_UNPUB),
+			(&filters.WhereBuilder{}).WithPath([]string{"recurrenceRule"}).WithOperator(filters.Like).WithValueText("*"),
+		})
+}
+
+// FindRecurringSeriesParents returns every series parent (published or not)
+// that carries a recurrence rule
+func FindRecurringSeriesParents(ctx context.Context, client *weaviate.Client) ([]types.Event, error) {
+	pageSize := 100
+	className := EventClassName()
+	whereFilter := recurringSeriesParentsFilter()
+
+	parents := []types.Event{}
+	for offset := 0; ; offset += pageSize {
+		result, err := client.GraphQL().Get().
+			WithClassName(className).
+			WithWhere(whereFilter).
+			WithFields(eventDetailFields...).
+			WithLimit(pageSize).
+			WithOffset(offset).
+			Do(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to query recurring series parents: %w", err)
+		}
+		if len(result.Errors) > 0 {
+			return nil, fmt.Errorf("failed to query recurring series parents: %s", result.Errors[0].Message)
+		}
+
+		getMap, _ := result.Data["Get"].(map[string]interface{})
+		classData, _ := getMap[className].([]interface{})
+		for _, uncastedObj := range classData {
+			objMap, ok := uncastedObj.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			event, err := NormalizeWeaviateResultToEvent(objMap)
+			if err != nil {
+				log.Printf("Warning: Could not normalize Weaviate result: %v", err)
+				continue
+			}
+			if event.RecurrenceRule != "" {
+				parents = append(parents, *event)
+			}
+		}
+		if len(classData) < pageSize {
+			return parents, nil
+		}
+	}
+}
+
 func BulkUpdateWeaviateEventsByID(ctx context.Context, client *weaviate.Client, events []types.Event) ([]models.ObjectsGetResponse, error) {
 	for i, event := range events {
 		if event.Id == "" {
@@ -1031,6 +1080,33 @@ func NormalizeWeaviateResultToEvent(objMap map[string]interface{}) (*types.Event
 	return &event, nil
 }
 
+// locationWhereFilters restricts `lat` to [minLat, maxLat] and `long` to any
+// of `longRanges`, more than one range when the area crosses the antimeridian
+func locationWhereFilters(minLat, maxLat float64, longRanges [][2]float64) []*filters.WhereBuilder {
+	latFilter := (&filters.WhereBuilder{}).
+		WithOperator(filters.And).
+		WithOperands([]*filters.WhereBuilder{
+			(&filters.WhereBuilder{}).WithPath([]string{"lat"}).WithOperator(filters.GreaterThanEqual).WithValueNumber(minLat),
+			(&filters.WhereBuilder{}).WithPath([]string{"lat"}).WithOperator(filters.LessThanEqual).WithValueNumber(maxLat),
+		})
+
+	longConditions := make([]*filters.WhereBuilder, 0, len(longRanges))
+	for _, longRange := range longRanges {
+		longConditions = append(longConditions, (&filters.WhereBuilder{}).
+			WithOperator(filters.And).
+			WithOperands([]*filters.WhereBuilder{
+				(&filters.WhereBuilder{}).WithPath([]string{"long"}).WithOperator(filters.GreaterThanEqual).WithValueNumber(longRange[0]),
+				(&filters.WhereBuilder{}).WithPath([]string{"long"}).WithOperator(filters.LessThanEqual).WithValueNumber(longRange[1]),
+			}))
+	}
+	longFilter := longConditions[0]
+	if len(longConditions) > 1 {
+		longFilter = (&filters.WhereBuilder{}).WithOperator(filters.Or).WithOperands(longConditions)
+	}
+
+	return []*filters.WhereBuilder{latFilter, longFilter}
+}
+
 // calculateSearchBounds calculates the latitude and longitude bounds for a given location and distance
 // Returns minLat, maxLat, minLong1, maxLong1, minLong2, maxLong2, needsSplit
 // When needsSplit is true, minLong1/maxLong1 represents the first range and minLong2/maxLong2 represents the second range
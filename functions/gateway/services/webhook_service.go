@@ -0,0 +1,386 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+const (
+	WEBHOOK_EVENT_CREATED            = "event.created"
+	WEBHOOK_EVENT_UPDATED            = "event.updated"
+	WEBHOOK_EVENT_DELETED            = "event.deleted"
+	WEBHOOK_PURCHASE_SETTLED         = "purchase.settled"
+	WEBHOOK_COMPETITION_ROUND_SCORED = "competition.round.scored"
+)
+
+// WebhookEventTypes is every event type a subscription can choose
+var WebhookEventTypes = []string{
+	WEBHOOK_EVENT_CREATED,
+	WEBHOOK_EVENT_UPDATED,
+	WEBHOOK_EVENT_DELETED,
+	WEBHOOK_PURCHASE_SETTLED,
+	WEBHOOK_COMPETITION_ROUND_SCORED,
+}
+
+const (
+	WEBHOOK_DELIVERY_STATUS_PENDING   = "PENDING"
+	WEBHOOK_DELIVERY_STATUS_SUCCEEDED = "SUCCEEDED"
+	WEBHOOK_DELIVERY_STATUS_FAILED    = "FAILED"
+)
+
+// Every delivery carries these headers. The signature is
+// `t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>" keyed by the secret>`
+const (
+	WEBHOOK_SIGNATURE_HEADER = "X-Mnm-Signature"
+	WEBHOOK_EVENT_HEADER     = "X-Mnm-Event"
+	WEBHOOK_DELIVERY_HEADER  = "X-Mnm-Delivery"
+)
+
+const (
+	MAX_WEBHOOK_SUBSCRIPTIONS  = 10
+	WEBHOOK_DELIVERY_LOG_LIMIT = 50
+)
+
+const (
+	webhookStreamName  = "WEBHOOK_DELIVERIES"
+	webhookSubjectName = "webhooks.deliveries"
+	webhookDurableName = "webhook-deliveries"
+	// webhookAckWait outlasts a delivery attempt, so JetStream doesn't hand
+	// the same delivery to a second worker while the first is still posting
+	webhookAckWait    = time.Minute
+	webhookMaxDeliver = 25
+	// webhookStoreRetryDelay is how long a delivery waits when its attempt
+	// couldn't be read or recorded, as opposed to the endpoint failing
+	webhookStoreRetryDelay = time.Minute
+	webhookRequestTimeout  = 10 * time.Second
+)
+
+// A delivery's error is one of these, the endpoint's answer or the
+// underlying network error could tell the owner about our network
+var (
+	errWebhookStatus  = errors.New("endpoint did not answer with a 2xx status")
+	errWebhookTimeout = errors.New("request to the endpoint timed out")
+	errWebhookRequest = errors.New("request to the endpoint failed")
+)
+
+// webhookRetryDelays are the waits before each retry of a failed delivery,
+// it's marked failed once they run out
+var webhookRetryDelays = []time.Duration{
+	30 * time.Second,
+	2 * time.Minute,
+	10 * time.Minute,
+	time.Hour,
+	6 * time.Hour,
+}
+
+var webhookHTTPClient = func() *http.Client {
+	client := NewPublicHTTPClient(webhookRequestTimeout)
+	// A redirect could send the payload somewhere the owner never registered
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return client
+}()
+
+// WebhookQueue hands delivery ids to the workers that make the attempts
+type WebhookQueue interface {
+	PublishWebhookDelivery(ctx context.Context, deliveryId string) error
+}
+
+// WebhookMessage is one change pushed to every subscription of its owners
+// that listens for its type
+type WebhookMessage struct {
+	Type     string
+	OwnerIds []string
+	Data     interface{}
+}
+
+// NewWebhookSubscription validates a new subscription and gives it an id and
+// a signing secret
+func NewWebhookSubscription(ownerId string, insert types.WebhookSubscriptionInsert, now time.Time) (types.WebhookSubscription, error) {
+	if err := validateWebhookUrl(insert.Url); err != nil {
+		return types.WebhookSubscription{}, err
+	}
+	eventTypes := types.WebhookEventTypes{}
+	for _, eventType := range insert.EventTypes {
+		if !slices.Contains(WebhookEventTypes, eventType) {
+			return types.WebhookSubscription{}, fmt.Errorf("unknown event type %q, expected one of %s", eventType, strings.Join(WebhookEventTypes, ", "))
+		}
+		if !slices.Contains(eventTypes, eventType) {
+			eventTypes = append(eventTypes, eventType)
+		}
+	}
+	if len(eventTypes) == 0 {
+		return types.WebhookSubscription{}, errors.New("at least one event type is required")
+	}
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return types.WebhookSubscription{}, err
+	}
+	return types.WebhookSubscription{
+		Id:          uuid.NewString(),
+		OwnerId:     ownerId,
+		Url:         insert.Url,
+		Description: strings.TrimSpace(insert.Description),
+		EventTypes:  eventTypes,
+		Secret:      secret,
+		CreatedAt:   now.Unix(),
+	}, nil
+}
+
+// validateWebhookUrl only allows plain http to this machine in local
+// development, for trying out a receiver. Hostnames are checked again when
+// each delivery dials, see `NewPublicHTTPClient`
+func validateWebhookUrl(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return fmt.Errorf("url %q is not an absolute URL", raw)
+	}
+	host := parsed.Hostname()
+	if ip := net.ParseIP(host); ip != nil && isBlockedIP(ip) {
+		return fmt.Errorf("url %q: %w", raw, ErrBlockedDestination)
+	}
+	switch parsed.Scheme {
+	case "https":
+		return nil
+	case "http":
+		if localDestinationsAllowed() && (host == "localhost" || host == "127.0.0.1" || host == "::1") {
+			return nil
+		}
+	}
+	return fmt.Errorf("url %q must use https", raw)
+}
+
+func newWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(secret), nil
+}
+
+// SignWebhookPayload is the `WEBHOOK_SIGNATURE_HEADER` value for a payload
+// sent at `timestamp`
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(payload)
+	return fmt.Sprintf("t=%d,v1=%s", timestamp, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// EmitWebhookEvents queues deliveries for `messages`. It only logs failures,
+// a webhook must never fail the request whose change it reports. Without
+// NATS nothing could deliver them, so nothing is recorded either
+func EmitWebhookEvents(ctx context.Context, messages ...WebhookMessage) {
+	if len(messages) == 0 {
+		return
+	}
+	natsService, err := GetNatsService(ctx)
+	if err != nil || natsService == nil {
+		log.Printf("ERR: NATS is unavailable, skipping %d webhook events: %v", len(messages), err)
+		return
+	}
+	store, err := GetPostgresService(ctx)
+	if err != nil {
+		log.Printf("ERR: failed to get postgres service for webhooks: %v", err)
+		return
+	}
+	if _, err := QueueWebhookDeliveries(ctx, store, natsService, messages, time.Now()); err != nil {
+		log.Printf("ERR: failed to queue webhook deliveries: %v", err)
+	}
+}
+
+// EmitEventWebhooks reports a change to each of `events` to its owners
+func EmitEventWebhooks(ctx context.Context, eventType string, events []types.Event) {
+	messages := make([]WebhookMessage, 0, len(events))
+	for _, event := range events {
+		messages = append(messages, WebhookMessage{Type: eventType, OwnerIds: event.EventOwners, Data: event})
+	}
+	EmitWebhookEvents(ctx, messages...)
+}
+
+// QueueWebhookDeliveries records a pending delivery of each message to each
+// subscription listening for it, then queues the attempts. Deliveries that
+// couldn't be queued stay pending in the log and can be redelivered
+func QueueWebhookDeliveries(ctx context.Context, store interfaces.PostgresServiceInterface, queue WebhookQueue, messages []WebhookMessage, now time.Time) ([]types.WebhookDelivery, error) {
+	ownerSubscriptions := map[string][]types.WebhookSubscription{}
+	deliveries := []types.WebhookDelivery{}
+	for _, message := range messages {
+		subscribed := map[string]bool{}
+		for _, ownerId := range message.OwnerIds {
+			subscriptions, ok := ownerSubscriptions[ownerId]
+			if !ok {
+				var err error
+				subscriptions, err = store.GetWebhookSubscriptions(ctx, ownerId)
+				if err != nil {
+					return nil, fmt.Errorf("failed to get webhook subscriptions of %s: %w", ownerId, err)
+				}
+				ownerSubscriptions[ownerId] = subscriptions
+			}
+			for _, subscription := range subscriptions {
+				if subscribed[subscription.Id] || !slices.Contains(subscription.EventTypes, message.Type) {
+					continue
+				}
+				subscribed[subscription.Id] = true
+
+				delivery := types.WebhookDelivery{
+					Id:             uuid.NewString(),
+					SubscriptionId: subscription.Id,
+					OwnerId:        subscription.OwnerId,
+					EventType:      message.Type,
+					Status:         WEBHOOK_DELIVERY_STATUS_PENDING,
+					NextAttemptAt:  now.Unix(),
+					CreatedAt:      now.Unix(),
+					UpdatedAt:      now.Unix(),
+				}
+				payload, err := json.Marshal(types.WebhookEnvelope{
+					Id:        delivery.Id,
+					Type:      message.Type,
+					CreatedAt: now.Unix(),
+					Data:      message.Data,
+				})
+				if err != nil {
+					return nil, fmt.Errorf("failed to marshal %s webhook: %w", message.Type, err)
+				}
+				delivery.Payload = types.JSONDocument(payload)
+				deliveries = append(deliveries, delivery)
+			}
+		}
+	}
+	if len(deliveries) == 0 {
+		return deliveries, nil
+	}
+
+	if err := store.SaveWebhookDeliveries(ctx, deliveries); err != nil {
+		return nil, fmt.Errorf("failed to save webhook deliveries: %w", err)
+	}
+	if queue == nil {
+		return deliveries, fmt.Errorf("no webhook queue, %d deliveries left pending", len(deliveries))
+	}
+	errs := []error{}
+	for _, delivery := range deliveries {
+		if err := queue.PublishWebhookDelivery(ctx, delivery.Id); err != nil {
+			errs = append(errs, fmt.Errorf("delivery %s: %w", delivery.Id, err))
+		}
+	}
+	return deliveries, errors.Join(errs...)
+}
+
+// RedeliverWebhook starts a delivery over with a full set of retries,
+// whatever became of it
+func RedeliverWebhook(ctx context.Context, store interfaces.PostgresServiceInterface, queue WebhookQueue, delivery types.WebhookDelivery, now time.Time) (types.WebhookDelivery, error) {
+	if queue == nil {
+		return delivery, errors.New("no webhook queue to redeliver with")
+	}
+	delivery.Status = WEBHOOK_DELIVERY_STATUS_PENDING
+	delivery.Attempts = 0
+	delivery.LastError = ""
+	delivery.NextAttemptAt = now.Unix()
+	delivery.UpdatedAt = now.Unix()
+	if err := store.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		return delivery, fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+	if err := queue.PublishWebhookDelivery(ctx, delivery.Id); err != nil {
+		return delivery, err
+	}
+	return delivery, nil
+}
+
+// DeliverWebhook makes one attempt at a pending delivery and records how it
+// went. It returns how long to wait before the next attempt, 0 once the
+// delivery succeeded, gave up or is gone. An error means the attempt
+// couldn't be read or recorded and should be tried again
+func DeliverWebhook(ctx context.Context, store interfaces.PostgresServiceInterface, client *http.Client, deliveryId string, now time.Time) (time.Duration, error) {
+	delivery, err := store.GetWebhookDelivery(ctx, deliveryId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+	if delivery == nil || delivery.Status != WEBHOOK_DELIVERY_STATUS_PENDING {
+		return 0, nil
+	}
+	// JetStream can hand a message back early, after a restart for one
+	if wait := time.Unix(delivery.NextAttemptAt, 0).Sub(now); wait > time.Second {
+		return wait, nil
+	}
+	subscription, err := store.GetWebhookSubscription(ctx, delivery.SubscriptionId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get webhook subscription: %w", err)
+	}
+	if subscription == nil {
+		return 0, nil
+	}
+
+	responseStatus, attemptErr := postWebhook(ctx, client, *subscription, *delivery, now)
+	delivery.Attempts++
+	delivery.ResponseStatus = responseStatus
+	delivery.UpdatedAt = now.Unix()
+	retryIn := time.Duration(0)
+	switch {
+	case attemptErr == nil:
+		delivery.Status = WEBHOOK_DELIVERY_STATUS_SUCCEEDED
+		delivery.LastError = ""
+		delivery.DeliveredAt = now.Unix()
+	case delivery.Attempts > len(webhookRetryDelays):
+		delivery.Status = WEBHOOK_DELIVERY_STATUS_FAILED
+		delivery.LastError = attemptErr.Error()
+	default:
+		retryIn = webhookRetryDelays[delivery.Attempts-1]
+		delivery.LastError = attemptErr.Error()
+		delivery.NextAttemptAt = now.Add(retryIn).Unix()
+	}
+	if err := store.UpdateWebhookDelivery(ctx, *delivery); err != nil {
+		return 0, fmt.Errorf("failed to record webhook delivery attempt: %w", err)
+	}
+	return retryIn, nil
+}
+
+// postWebhook sends a delivery's payload once, any answer but a 2xx is a
+// failure
+func postWebhook(ctx context.Context, client *http.Client, subscription types.WebhookSubscription, delivery types.WebhookDelivery, now time.Time) (int, error) {
+	payload := []byte(delivery.Payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.Url, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "MeetNearMe-Webhooks/1.0")
+	req.Header.Set(WEBHOOK_SIGNATURE_HEADER, SignWebhookPayload(subscription.Secret, now.Unix(), payload))
+	req.Header.Set(WEBHOOK_EVENT_HEADER, delivery.EventType)
+	req.Header.Set(WEBHOOK_DELIVERY_HEADER, delivery.Id)
+
+	res, err := client.Do(req)
+	if err != nil {
+		log.Printf("ERR: webhook delivery %s to subscription %s failed: %v", delivery.Id, subscription.Id, err)
+		var netErr net.Error
+		switch {
+		case errors.Is(err, ErrBlockedDestination):
+			return 0, ErrBlockedDestination
+		case errors.As(err, &netErr) && netErr.Timeout():
+			return 0, errWebhookTimeout
+		}
+		return 0, errWebhookRequest
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, errWebhookStatus
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/interfaces"
+	"github.com/meetnearme/api/functions/gateway/types"
+)
+
+type fakeWebhookStore struct {
+	interfaces.PostgresServiceInterface
+	mu            sync.Mutex
+	subscriptions []types.WebhookSubscription
+	deliveries    map[string]types.WebhookDelivery
+}
+
+func newFakeWebhookStore(subscriptions ...types.WebhookSubscription) *fakeWebhookStore {
+	return &fakeWebhookStore{subscriptions: subscriptions, deliveries: map[string]types.WebhookDelivery{}}
+}
+
+func (f *fakeWebhookStore) GetWebhookSubscriptions(ctx context.Context, ownerId string) ([]types.WebhookSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	subscriptions := []types.WebhookSubscription{}
+	for _, subscription := range f.subscriptions {
+		if subscription.OwnerId == ownerId {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+	return subscriptions, nil
+}
+
+func (f *fakeWebhookStore) GetWebhookSubscription(ctx context.Context, id string) (*types.WebhookSubscription, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, subscription := range f.subscriptions {
+		if subscription.Id == id {
+			return &subscription, nil
+		}
+	}
+	return nil, nil
+}
+
+func (f *fakeWebhookStore) SaveWebhookDeliveries(ctx context.Context, deliveries []types.WebhookDelivery) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, delivery := range deliveries {
+		f.deliveries[delivery.Id] = delivery
+	}
+	return nil
+}
+
+func (f *fakeWebhookStore) UpdateWebhookDelivery(ctx context.Context, delivery types.WebhookDelivery) error {
+	return f.SaveWebhookDeliveries(ctx, []types.WebhookDelivery{delivery})
+}
+
+func (f *fakeWebhookStore) GetWebhookDelivery(ctx context.Context, id string) (*types.WebhookDelivery, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delivery, ok := f.deliveries[id]
+	if !ok {
+		return nil, nil
+	}
+	return &delivery, nil
+}
+
+type fakeWebhookQueue struct {
+	published []string
+}
+
+func (f *fakeWebhookQueue) PublishWebhookDelivery(ctx context.Context, deliveryId string) error {
+	f.published = append(f.published, deliveryId)
+	return nil
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	signature := SignWebhookPayload("whsec_test", 1700000000, []byte(`{"id":"1"}`))
+	// printf '1700000000.{"id":"1"}' | openssl dgst -sha256 -hmac whsec_test
+	want := "t=1700000000,v1=11bf4466ea17c3df3fd743af0b435368e16b7a05eb8eced85e8c4670767bdec5"
+	if signature != want {
+		t.Fatalf("expected signature %q, got %q", want, signature)
+	}
+	if signature == SignWebhookPayload("whsec_other", 1700000000, []byte(`{"id":"1"}`)) {
+		t.Error("expected a different secret to sign differently")
+	}
+	if signature == SignWebhookPayload("whsec_test", 1700000001, []byte(`{"id":"1"}`)) {
+		t.Error("expected the timestamp to be signed")
+	}
+}
+
+func TestNewWebhookSubscription(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name    string
+		insert  types.WebhookSubscriptionInsert
+		local   bool
+		wantErr string
+	}{
+		{
+			name:   "https endpoint",
+			insert: types.WebhookSubscriptionInsert{Url: "https://example.com/hook", EventTypes: []string{WEBHOOK_EVENT_CREATED}},
+		},
+		{
+			name:   "plain http to localhost in local development",
+			insert: types.WebhookSubscriptionInsert{Url: "http://localhost:8080/hook", EventTypes: []string{WEBHOOK_EVENT_CREATED}},
+			local:  true,
+		},
+		{
+			name:    "plain http to localhost",
+			insert:  types.WebhookSubscriptionInsert{Url: "http://localhost:8080/hook", EventTypes: []string{WEBHOOK_EVENT_CREATED}},
+			wantErr: "must use https",
+		},
+		{
+			name:    "metadata endpoint",
+			insert:  types.WebhookSubscriptionInsert{Url: "https://169.254.169.254/latest/meta-data", EventTypes: []string{WEBHOOK_EVENT_CREATED}},
+			wantErr: ErrBlockedDestination.Error(),
+		},
+		{
+			name:    "private address",
+			insert:  types.WebhookSubscriptionInsert{Url: "https://10.0.0.5/hook", EventTypes: []string{WEBHOOK_EVENT_CREATED}},
+			wantErr: ErrBlockedDestination.Error(),
+		},
+		{
+			name:    "plain http elsewhere",
+			insert:  types.WebhookSubscriptionInsert{Url: "http://example.com/hook", EventTypes: []string{WEBHOOK_EVENT_CREATED}},
+			wantErr: "must use https",
+		},
+		{
+			name:    "relative url",
+			insert:  types.WebhookSubscriptionInsert{Url: "/hook", EventTypes: []string{WEBHOOK_EVENT_CREATED}},
+			wantErr: "not an absolute URL",
+		},
+		{
+			name:    "unknown event type",
+			insert:  types.WebhookSubscriptionInsert{Url: "https://example.com/hook", EventTypes: []string{"event.exploded"}},
+			wantErr: "unknown event type",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.local {
+				t.Setenv("IS_LOCAL_ACT", "true")
+			} else {
+				t.Setenv("IS_LOCAL_ACT", "false")
+			}
+			subscription, err := NewWebhookSubscription("owner-1", tt.insert, now)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if subscription.Id == "" || !strings.HasPrefix(subscription.Secret, "whsec_") {
+				t.Errorf("expected an id and a secret, got %+v", subscription)
+			}
+			if subscription.OwnerId != "owner-1" || subscription.CreatedAt != now.Unix() {
+				t.Errorf("unexpected subscription %+v", subscription)
+			}
+		})
+	}
+
+	subscription, err := NewWebhookSubscription("owner-1", types.WebhookSubscriptionInsert{
+		Url:        "https://example.com/hook",
+		EventTypes: []string{WEBHOOK_EVENT_CREATED, WEBHOOK_EVENT_CREATED, WEBHOOK_EVENT_DELETED},
+	}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(subscription.EventTypes) != 2 {
+		t.Errorf("expected repeated event types to be dropped, got %v", subscription.EventTypes)
+	}
+}
+
+func TestQueueWebhookDeliveries(t *testing.T) {
+	store := newFakeWebhookStore(
+		types.WebhookSubscription{Id: "sub-created", OwnerId: "owner-1", EventTypes: types.WebhookEventTypes{WEBHOOK_EVENT_CREATED}},
+		types.WebhookSubscription{Id: "sub-deleted", OwnerId: "owner-1", EventTypes: types.WebhookEventTypes{WEBHOOK_EVENT_DELETED}},
+		types.WebhookSubscription{Id: "sub-other", OwnerId: "owner-2", EventTypes: types.WebhookEventTypes{WEBHOOK_EVENT_CREATED}},
+	)
+	queue := &fakeWebhookQueue{}
+	now := time.Unix(1700000000, 0)
+
+	deliveries, err := QueueWebhookDeliveries(context.Background(), store, queue, []WebhookMessage{
+		// owner-1 listed twice still gets one delivery per subscription
+		{Type: WEBHOOK_EVENT_CREATED, OwnerIds: []string{"owner-1", "owner-1"}, Data: map[string]string{"id": "event-1"}},
+	}, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(deliveries) != 1 || deliveries[0].SubscriptionId != "sub-created" {
+		t.Fatalf("expected one delivery to sub-created, got %+v", deliveries)
+	}
+	delivery := deliveries[0]
+	if delivery.Status != WEBHOOK_DELIVERY_STATUS_PENDING || delivery.OwnerId != "owner-1" {
+		t.Errorf("unexpected delivery %+v", delivery)
+	}
+	if len(queue.published) != 1 || queue.published[0] != delivery.Id {
+		t.Errorf("expected the delivery to be queued, got %v", queue.published)
+	}
+	if _, ok := store.deliveries[delivery.Id]; !ok {
+		t.Error("expected the delivery to be saved")
+	}
+
+	var envelope struct {
+		Id   string            `json:"id"`
+		Type string            `json:"type"`
+		Data map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(delivery.Payload, &envelope); err != nil {
+		t.Fatalf("failed to unmarshal payload: %v", err)
+	}
+	if envelope.Id != delivery.Id || envelope.Type != WEBHOOK_EVENT_CREATED || envelope.Data["id"] != "event-1" {
+		t.Errorf("unexpected payload %s", delivery.Payload)
+	}
+
+	deliveries, err = QueueWebhookDeliveries(context.Background(), store, nil, []WebhookMessage{
+		{Type: WEBHOOK_EVENT_DELETED, OwnerIds: []string{"owner-1"}},
+	}, now)
+	if err == nil || len(deliveries) != 1 {
+		t.Fatalf("expected the delivery to be left pending without a queue, got %+v, %v", deliveries, err)
+	}
+}
+
+func TestDeliverWebhook(t *testing.T) {
+	var mu sync.Mutex
+	statuses := []int{}
+	var lastRequest *http.Request
+	var lastBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		lastRequest = r
+		lastBody, _ = io.ReadAll(r.Body)
+		status := http.StatusOK
+		if len(statuses) > 0 {
+			status, statuses = statuses[0], statuses[1:]
+		}
+		w.WriteHeader(status)
+		w.Write([]byte("receiver says hi"))
+	}))
+	defer server.Close()
+
+	subscription := types.WebhookSubscription{Id: "sub-1", OwnerId: "owner-1", Url: server.URL, Secret: "whsec_test", EventTypes: types.WebhookEventTypes{WEBHOOK_EVENT_CREATED}}
+	now := time.Unix(1700000000, 0)
+	queue := func(t *testing.T, store *fakeWebhookStore) string {
+		t.Helper()
+		deliveries, err := QueueWebhookDeliveries(context.Background(), store, &fakeWebhookQueue{}, []WebhookMessage{
+			{Type: WEBHOOK_EVENT_CREATED, OwnerIds: []string{"owner-1"}, Data: map[string]string{"id": "event-1"}},
+		}, now)
+		if err != nil || len(deliveries) != 1 {
+			t.Fatalf("failed to queue delivery: %+v, %v", deliveries, err)
+		}
+		return deliveries[0].Id
+	}
+
+	t.Run("signed success", func(t *testing.T) {
+		store := newFakeWebhookStore(subscription)
+		deliveryId := queue(t, store)
+		retryIn, err := DeliverWebhook(context.Background(), store, server.Client(), deliveryId, now)
+		if err != nil || retryIn != 0 {
+			t.Fatalf("expected no retry, got %v, %v", retryIn, err)
+		}
+		delivery := store.deliveries[deliveryId]
+		if delivery.Status != WEBHOOK_DELIVERY_STATUS_SUCCEEDED || delivery.Attempts != 1 || delivery.DeliveredAt != now.Unix() {
+			t.Errorf("unexpected delivery %+v", delivery)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if got := lastRequest.Header.Get(WEBHOOK_SIGNATURE_HEADER); got != SignWebhookPayload("whsec_test", now.Unix(), lastBody) {
+			t.Errorf("unexpected signature %q", got)
+		}
+		if lastRequest.Header.Get(WEBHOOK_EVENT_HEADER) != WEBHOOK_EVENT_CREATED || lastRequest.Header.Get(WEBHOOK_DELIVERY_HEADER) != deliveryId {
+			t.Errorf("unexpected headers %v", lastRequest.Header)
+		}
+	})
+
+	t.Run("retries with backoff then gives up", func(t *testing.T) {
+		store := newFakeWebhookStore(subscription)
+		deliveryId := queue(t, store)
+		mu.Lock()
+		statuses = []int{500, 500, 500, 500, 500, 500}
+		mu.Unlock()
+
+		attemptAt := now
+		for i, want := range webhookRetryDelays {
+			retryIn, err := DeliverWebhook(context.Background(), store, server.Client(), deliveryId, attemptAt)
+			if err != nil {
+				t.Fatalf("attempt %d: unexpected error: %v", i+1, err)
+			}
+			if retryIn != want {
+				t.Fatalf("attempt %d: expected a retry in %v, got %v", i+1, want, retryIn)
+			}
+			delivery := store.deliveries[deliveryId]
+			if delivery.Status != WEBHOOK_DELIVERY_STATUS_PENDING || delivery.ResponseStatus != 500 || delivery.LastError != errWebhookStatus.Error() {
+				t.Fatalf("attempt %d: unexpected delivery %+v", i+1, delivery)
+			}
+
+			// Handed back early, the attempt waits out the rest of the delay
+			early, err := DeliverWebhook(context.Background(), store, server.Client(), deliveryId, attemptAt.Add(want/2))
+			if err != nil || early <= time.Second {
+				t.Fatalf("attempt %d: expected an early redelivery to wait, got %v, %v", i+1, early, err)
+			}
+			attemptAt = attemptAt.Add(want)
+		}
+
+		retryIn, err := DeliverWebhook(context.Background(), store, server.Client(), deliveryId, attemptAt)
+		if err != nil || retryIn != 0 {
+			t.Fatalf("expected the last attempt to give up, got %v, %v", retryIn, err)
+		}
+		delivery := store.deliveries[deliveryId]
+		if delivery.Status != WEBHOOK_DELIVERY_STATUS_FAILED || delivery.Attempts != len(webhookRetryDelays)+1 {
+			t.Errorf("unexpected delivery %+v", delivery)
+		}
+
+		redelivered, err := RedeliverWebhook(context.Background(), store, &fakeWebhookQueue{}, delivery, attemptAt)
+		if err != nil || redelivered.Status != WEBHOOK_DELIVERY_STATUS_PENDING || redelivered.Attempts != 0 {
+			t.Errorf("expected redelivery to start over, got %+v, %v", redelivered, err)
+		}
+	})
+
+	t.Run("redirects are not followed", func(t *testing.T) {
+		redirecting := httptest.NewServer(http.RedirectHandler(server.URL, http.StatusFound))
+		defer redirecting.Close()
+		redirected := subscription
+		redirected.Url = redirecting.URL
+		store := newFakeWebhookStore(redirected)
+		deliveryId := queue(t, store)
+
+		client := *webhookHTTPClient
+		client.Transport = redirecting.Client().Transport
+		retryIn, err := DeliverWebhook(context.Background(), store, &client, deliveryId, now)
+		if err != nil || retryIn != webhookRetryDelays[0] {
+			t.Fatalf("expected the redirect to be retried, got %v, %v", retryIn, err)
+		}
+		if delivery := store.deliveries[deliveryId]; delivery.ResponseStatus != http.StatusFound {
+			t.Errorf("expected the redirect to be recorded, got %+v", delivery)
+		}
+	})
+
+	t.Run("deleted subscription", func(t *testing.T) {
+		store := newFakeWebhookStore(subscription)
+		deliveryId := queue(t, store)
+		store.subscriptions = nil
+		retryIn, err := DeliverWebhook(context.Background(), store, server.Client(), deliveryId, now)
+		if err != nil || retryIn != 0 {
+			t.Errorf("expected the delivery to be dropped, got %v, %v", retryIn, err)
+		}
+	})
+}
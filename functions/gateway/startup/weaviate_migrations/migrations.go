@@ -0,0 +1,62 @@
+package weaviate_migrations
+
+import (
+	"github.com/meetnearme/api/functions/gateway/constants"
+)
+
+// Migration is one version of the events class. Weaviate can't change an
+// existing property's type, tokenization or vectorization, so every version
+// lives in a class of its own and running a migration copies the previous
+// version's objects into it
+type Migration struct {
+	Version int
+	// ClassName is the class this version lives in, created from
+	// `services.EventClassDefinition`. It must not be used by an earlier version
+	ClassName   string
+	Description string
+	// Transform rewrites the properties of each object copied into
+	// `ClassName`, nil copies them unchanged
+	Transform func(properties map[string]interface{}) (map[string]interface{}, error)
+	// Revectorize leaves stored vectors behind so the vectorizer recomputes
+	// them. Needed when vectorized properties or the vectorizer change
+	Revectorize bool
+}
+
+// Migrations lists every version of the events class, oldest first. To
+// change the schema, edit `services.EventClassDefinition`, append a
+// migration with a new dated class name and point
+// `constants.WeaviateEventClassName` at it for fresh installs
+var Migrations = []Migration{
+	{
+		Version:     1,
+		ClassName:   "EventStrict_2025_11_07_000000",
+		Description: "Events class in use when versioned migrations were introduced, nothing is copied into it",
+	},
+}
+
+// Latest is the newest version of the events class
+func Latest() Migration {
+	return Migrations[len(Migrations)-1]
+}
+
+// Pending lists the migrations newer than `version`, oldest first
+func Pending(version int) []Migration {
+	pending := []Migration{}
+	for _, migration := range Migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// baselineVersion is the version an install that predates the `Event` alias
+// is on, the migration whose class is `constants.WeaviateEventClassName`
+func baselineVersion() int {
+	for _, migration := range Migrations {
+		if migration.ClassName == constants.WeaviateEventClassName {
+			return migration.Version
+		}
+	}
+	return Latest().Version
+}
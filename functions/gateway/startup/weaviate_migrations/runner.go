@@ -0,0 +1,437 @@
+package weaviate_migrations
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/filters"
+	"github.com/weaviate/weaviate-go-client/v4/weaviate/graphql"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+const (
+	DEFAULT_BATCH_SIZE = 200
+	DEFAULT_MAX_PASSES = 5
+	// Writes can still land on the old class until every running instance
+	// has re-read the alias
+	DEFAULT_DRAIN_WAIT = services.EVENT_CLASS_ALIAS_REFRESH_INTERVAL + 15*time.Second
+	// Object timestamps come from the Weaviate server's clock
+	clockSkewMargin = time.Minute
+)
+
+type Options struct {
+	BatchSize int
+	// MaxPasses bounds the copy passes made before the alias moves. The class
+	// is only switched after a pass that finds nothing left to copy
+	MaxPasses int
+	// DrainWait is how long to wait after moving the alias before copying
+	// writes that reached the old class in the meantime
+	DrainWait time.Duration
+	// DryRun reports what would be migrated without writing anything
+	DryRun bool
+}
+
+func (o Options) withDefaults() Options {
+	if o.BatchSize <= 0 {
+		o.BatchSize = DEFAULT_BATCH_SIZE
+	}
+	if o.MaxPasses <= 0 {
+		o.MaxPasses = DEFAULT_MAX_PASSES
+	}
+	if o.DrainWait < 0 {
+		o.DrainWait = 0
+	}
+	return o
+}
+
+type Result struct {
+	Version     int
+	FromClass   string
+	ToClass     string
+	SourceCount int
+	TargetCount int
+	Copied      int
+	Pruned      int
+	Passes      int
+	// Stragglers were written to the old class after the alias moved
+	Stragglers int
+	DryRun     bool
+}
+
+// EnsureEventAlias returns the `Event` alias, first pointing it at
+// `constants.WeaviateEventClassName` on installs that predate it
+func EnsureEventAlias(ctx context.Context, client *weaviate.Client) (*services.SchemaAlias, error) {
+	schemaAlias, err := services.GetSchemaAlias(ctx, client, services.EVENT_CLASS_ALIAS)
+	if err != nil || schemaAlias != nil {
+		return schemaAlias, err
+	}
+	schemaAlias = &services.SchemaAlias{
+		Alias:     services.EVENT_CLASS_ALIAS,
+		ClassName: constants.WeaviateEventClassName,
+		Version:   baselineVersion(),
+	}
+	log.Printf("Pointing Weaviate alias '%s' at '%s' (version %d)", schemaAlias.Alias, schemaAlias.ClassName, schemaAlias.Version)
+	if err := services.SetSchemaAlias(ctx, client, *schemaAlias); err != nil {
+		return nil, err
+	}
+	return schemaAlias, nil
+}
+
+// PointEventAlias moves the `Event` alias straight to `className`, without
+// copying anything. Used to roll back to a class an earlier migration left
+// behind
+func PointEventAlias(ctx context.Context, client *weaviate.Client, className string) (*services.SchemaAlias, error) {
+	var target *Migration
+	for i := range Migrations {
+		if Migrations[i].ClassName == className {
+			target = &Migrations[i]
+		}
+	}
+	if target == nil {
+		return nil, fmt.Errorf("'%s' is not the class of any migration", className)
+	}
+	exists, err := client.Schema().ClassExistenceChecker().WithClassName(className).Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed checking class existence: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("class '%s' does not exist", className)
+	}
+	schemaAlias := services.SchemaAlias{Alias: services.EVENT_CLASS_ALIAS, ClassName: className, Version: target.Version}
+	if err := services.SetSchemaAlias(ctx, client, schemaAlias); err != nil {
+		return nil, err
+	}
+	return &schemaAlias, nil
+}
+
+// Run applies every pending migration in order. Each one copies the current
+// class into the migration's class while the app keeps serving from the old
+// one, verifies the counts match, then moves the `Event` alias
+func Run(ctx context.Context, client *weaviate.Client, opts Options) ([]Result, error) {
+	opts = opts.withDefaults()
+	schemaAlias, err := EnsureEventAlias(ctx, client)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events class alias: %w", err)
+	}
+
+	results := []Result{}
+	fromClass := schemaAlias.ClassName
+	for _, migration := range Pending(schemaAlias.Version) {
+		result, err := runMigration(ctx, client, fromClass, migration, opts)
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("migration %d to '%s' failed: %w", migration.Version, migration.ClassName, err)
+		}
+		fromClass = migration.ClassName
+	}
+	return results, nil
+}
+
+func runMigration(ctx context.Context, client *weaviate.Client, fromClass string, migration Migration, opts Options) (Result, error) {
+	result := Result{Version: migration.Version, FromClass: fromClass, ToClass: migration.ClassName, DryRun: opts.DryRun}
+	if fromClass == migration.ClassName {
+		return result, fmt.Errorf("class '%s' is already in use", fromClass)
+	}
+
+	sourceCount, err := classCount(ctx, client, fromClass)
+	if err != nil {
+		return result, err
+	}
+	result.SourceCount = sourceCount
+	if opts.DryRun {
+		log.Printf("[dry run] migration %d would copy %d objects from '%s' to '%s'", migration.Version, sourceCount, fromClass, migration.ClassName)
+		return result, nil
+	}
+
+	exists, err := client.Schema().ClassExistenceChecker().WithClassName(migration.ClassName).Do(ctx)
+	if err != nil {
+		return result, fmt.Errorf("failed checking class existence: %w", err)
+	}
+	if !exists {
+		if err := client.Schema().ClassCreator().WithClass(services.EventClassDefinition(migration.ClassName)).Do(ctx); err != nil {
+			return result, fmt.Errorf("failed to create class '%s': %w", migration.ClassName, err)
+		}
+	}
+
+	// Repeat passes until one finds the classes already in sync. Every pass
+	// re-copies what changed in the old class since the previous one started
+	var lastPassStart int64
+	verified := false
+	for result.Passes < opts.MaxPasses {
+		result.Passes++
+		lastPassStart = time.Now().UnixMilli()
+		stats, err := syncPass(ctx, client, fromClass, migration, opts.BatchSize, passOptions{prune: true})
+		if err != nil {
+			return result, err
+		}
+		result.Copied += stats.copied
+		result.Pruned += stats.pruned
+		log.Printf("Migration %d pass %d: copied %d, pruned %d", migration.Version, result.Passes, stats.copied, stats.pruned)
+
+		if result.SourceCount, err = classCount(ctx, client, fromClass); err != nil {
+			return result, err
+		}
+		if result.TargetCount, err = classCount(ctx, client, migration.ClassName); err != nil {
+			return result, err
+		}
+		if stats.copied == 0 && stats.pruned == 0 && result.SourceCount == result.TargetCount {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return result, fmt.Errorf("'%s' has %d objects but '%s' has %d after %d passes, the old class is still changing, re-run to resume",
+			fromClass, result.SourceCount, migration.ClassName, result.TargetCount, result.Passes)
+	}
+
+	err = services.SetSchemaAlias(ctx, client, services.SchemaAlias{
+		Alias:     services.EVENT_CLASS_ALIAS,
+		ClassName: migration.ClassName,
+		Version:   migration.Version,
+	})
+	if err != nil {
+		return result, err
+	}
+	services.SetEventClassName(migration.ClassName)
+	log.Printf("Weaviate alias '%s' now points at '%s'", services.EVENT_CLASS_ALIAS, migration.ClassName)
+
+	// Instances that haven't re-read the alias yet still write to the old
+	// class. Copy those writes over once they've all switched, without
+	// pruning since deletes now happen in the new class
+	if opts.DrainWait > 0 {
+		log.Printf("Waiting %s for running instances to switch classes", opts.DrainWait)
+		select {
+		case <-time.After(opts.DrainWait):
+		case <-ctx.Done():
+			return result, ctx.Err()
+		}
+	}
+	stats, err := syncPass(ctx, client, fromClass, migration, opts.BatchSize, passOptions{createdSince: lastPassStart - clockSkewMargin.Milliseconds()})
+	if err != nil {
+		return result, fmt.Errorf("alias moved but copying late writes failed, finish with a resync from '%s': %w", fromClass, err)
+	}
+	result.Stragglers = stats.copied
+	if result.TargetCount, err = classCount(ctx, client, migration.ClassName); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// Resync copies objects written to `fromClass` after the `Event` alias moved
+// off it, the last step of a migration, for when that step was interrupted
+func Resync(ctx context.Context, client *weaviate.Client, fromClass string, opts Options) (Result, error) {
+	opts = opts.withDefaults()
+	schemaAlias, err := services.GetSchemaAlias(ctx, client, services.EVENT_CLASS_ALIAS)
+	if err != nil {
+		return Result{}, err
+	}
+	if schemaAlias == nil || schemaAlias.ClassName == fromClass {
+		return Result{}, fmt.Errorf("the '%s' alias doesn't point away from '%s'", services.EVENT_CLASS_ALIAS, fromClass)
+	}
+	migration := Migration{Version: schemaAlias.Version, ClassName: schemaAlias.ClassName}
+	for _, m := range Migrations {
+		if m.Version == schemaAlias.Version {
+			migration = m
+		}
+	}
+
+	result := Result{Version: migration.Version, FromClass: fromClass, ToClass: migration.ClassName, DryRun: opts.DryRun, Passes: 1}
+	if opts.DryRun {
+		result.SourceCount, err = classCount(ctx, client, fromClass)
+		return result, err
+	}
+	createdSince := schemaAlias.UpdatedAt*1000 - clockSkewMargin.Milliseconds()
+	stats, err := syncPass(ctx, client, fromClass, migration, opts.BatchSize, passOptions{createdSince: createdSince})
+	if err != nil {
+		return result, err
+	}
+	result.Stragglers = stats.copied
+	if result.SourceCount, err = classCount(ctx, client, fromClass); err != nil {
+		return result, err
+	}
+	result.TargetCount, err = classCount(ctx, client, migration.ClassName)
+	return result, err
+}
+
+type passOptions struct {
+	// prune deletes objects from the new class that no longer exist in the old
+	prune bool
+	// createdSince skips objects missing from the new class that were created
+	// before this time (ms). Set after the alias moved, when a missing object
+	// may have been deleted through the new class
+	createdSince int64
+}
+
+type passStats struct {
+	copied int
+	pruned int
+}
+
+// shouldCopy decides whether `source` from the old class needs writing to
+// the new one. `targetUpdatedAt` is when the new class's copy was last
+// written, zero when it has none
+func shouldCopy(source *models.Object, targetUpdatedAt int64, inTarget bool, opts passOptions) bool {
+	if !inTarget {
+		return opts.createdSince == 0 || source.CreationTimeUnix >= opts.createdSince
+	}
+	return source.LastUpdateTimeUnix > targetUpdatedAt
+}
+
+// migrateObject builds the copy of `source` written to the new class
+func migrateObject(source *models.Object, migration Migration) (*models.Object, error) {
+	properties, _ := source.Properties.(map[string]interface{})
+	if properties == nil {
+		properties = map[string]interface{}{}
+	}
+	if migration.Transform != nil {
+		var err error
+		if properties, err = migration.Transform(properties); err != nil {
+			return nil, fmt.Errorf("failed to transform object %s: %w", source.ID, err)
+		}
+	}
+	target := &models.Object{
+		Class:      migration.ClassName,
+		ID:         source.ID,
+		Properties: properties,
+	}
+	if !migration.Revectorize {
+		target.Vector = source.Vector
+	}
+	return target, nil
+}
+
+// syncPass streams every object of `fromClass` and writes the ones that are
+// missing or stale in the migration's class
+func syncPass(ctx context.Context, client *weaviate.Client, fromClass string, migration Migration, batchSize int, opts passOptions) (passStats, error) {
+	stats := passStats{}
+
+	targetUpdatedAt := map[string]int64{}
+	err := streamObjects(ctx, client, migration.ClassName, batchSize, false, func(objects []*models.Object) error {
+		for _, object := range objects {
+			targetUpdatedAt[string(object.ID)] = object.LastUpdateTimeUnix
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	sourceIds := map[string]bool{}
+	err = streamObjects(ctx, client, fromClass, batchSize, !migration.Revectorize, func(objects []*models.Object) error {
+		batch := []*models.Object{}
+		for _, object := range objects {
+			id := string(object.ID)
+			sourceIds[id] = true
+			updatedAt, inTarget := targetUpdatedAt[id]
+			if !shouldCopy(object, updatedAt, inTarget, opts) {
+				continue
+			}
+			target, err := migrateObject(object, migration)
+			if err != nil {
+				return err
+			}
+			batch = append(batch, target)
+		}
+		if err := writeObjects(ctx, client, batch); err != nil {
+			return err
+		}
+		stats.copied += len(batch)
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+
+	if !opts.prune {
+		return stats, nil
+	}
+	stale := []string{}
+	for id := range targetUpdatedAt {
+		if !sourceIds[id] {
+			stale = append(stale, id)
+		}
+	}
+	for start := 0; start < len(stale); start += batchSize {
+		end := min(start+batchSize, len(stale))
+		_, err := client.Batch().ObjectsBatchDeleter().
+			WithClassName(migration.ClassName).
+			WithWhere((&filters.WhereBuilder{}).WithPath([]string{"id"}).WithOperator(filters.ContainsAny).WithValueText(stale[start:end]...)).
+			Do(ctx)
+		if err != nil {
+			return stats, fmt.Errorf("failed to prune objects from '%s': %w", migration.ClassName, err)
+		}
+		stats.pruned += end - start
+	}
+	return stats, nil
+}
+
+// streamObjects pages through every object of `className` in ID order
+func streamObjects(ctx context.Context, client *weaviate.Client, className string, batchSize int, withVector bool, each func([]*models.Object) error) error {
+	after := ""
+	for {
+		getter := client.Data().ObjectsGetter().WithClassName(className).WithLimit(batchSize)
+		if after != "" {
+			getter = getter.WithAfter(after)
+		}
+		if withVector {
+			getter = getter.WithVector()
+		}
+		objects, err := getter.Do(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list objects of '%s': %w", className, err)
+		}
+		if len(objects) == 0 {
+			return nil
+		}
+		if err := each(objects); err != nil {
+			return err
+		}
+		after = string(objects[len(objects)-1].ID)
+	}
+}
+
+func writeObjects(ctx context.Context, client *weaviate.Client, objects []*models.Object) error {
+	if len(objects) == 0 {
+		return nil
+	}
+	res, err := client.Batch().ObjectsBatcher().WithObjects(objects...).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to write batch: %w", err)
+	}
+	for _, r := range res {
+		if r.Result != nil && r.Result.Errors != nil && len(r.Result.Errors.Error) > 0 {
+			return fmt.Errorf("failed to write object %s: %s", r.ID, r.Result.Errors.Error[0].Message)
+		}
+	}
+	return nil
+}
+
+func classCount(ctx context.Context, client *weaviate.Client, className string) (int, error) {
+	result, err := client.GraphQL().Aggregate().
+		WithClassName(className).
+		WithFields(graphql.Field{Name: "meta", Fields: []graphql.Field{{Name: "count"}}}).
+		Do(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count '%s': %w", className, err)
+	}
+	if len(result.Errors) > 0 {
+		return 0, fmt.Errorf("failed to count '%s': %s", className, result.Errors[0].Message)
+	}
+	aggregate, _ := result.Data["Aggregate"].(map[string]interface{})
+	rows, _ := aggregate[className].([]interface{})
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	row, _ := rows[0].(map[string]interface{})
+	meta, _ := row["meta"].(map[string]interface{})
+	count, ok := meta["count"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("aggregate response missing %s meta.count", className)
+	}
+	return int(count), nil
+}
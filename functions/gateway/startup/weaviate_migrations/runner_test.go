@@ -0,0 +1,453 @@
+package weaviate_migrations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-openapi/strfmt"
+	"github.com/meetnearme/api/functions/gateway/constants"
+	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/test_helpers"
+	"github.com/weaviate/weaviate/entities/models"
+)
+
+// fakeWeaviate keeps classes and objects in memory and serves the REST and
+// GraphQL calls the migration runner makes
+type fakeWeaviate struct {
+	mu      sync.Mutex
+	classes map[string]map[string]*models.Object
+	// afterWrite runs after each batch write with the classes written to
+	afterWrite func(f *fakeWeaviate, classNames []string)
+}
+
+var aggregateClassPattern = regexp.MustCompile(`Aggregate\s*\{\s*(\w+)`)
+
+func newFakeWeaviate() *fakeWeaviate {
+	return &fakeWeaviate{classes: map[string]map[string]*models.Object{}}
+}
+
+// put stores a copy of `object` with fresh timestamps, callers hold the lock
+func (f *fakeWeaviate) put(object *models.Object) {
+	if f.classes[object.Class] == nil {
+		f.classes[object.Class] = map[string]*models.Object{}
+	}
+	now := time.Now().UnixMilli()
+	stored := *object
+	stored.CreationTimeUnix = now
+	if existing, ok := f.classes[object.Class][string(object.ID)]; ok {
+		stored.CreationTimeUnix = existing.CreationTimeUnix
+	}
+	stored.LastUpdateTimeUnix = now
+	f.classes[object.Class][string(object.ID)] = &stored
+}
+
+func (f *fakeWeaviate) Put(object *models.Object) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.put(object)
+}
+
+func (f *fakeWeaviate) Get(className, id string) *models.Object {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.classes[className][id]
+}
+
+func (f *fakeWeaviate) Count(className string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.classes[className])
+}
+
+func (f *fakeWeaviate) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	var written []string
+	defer func() {
+		f.mu.Unlock()
+		if len(written) > 0 && f.afterWrite != nil {
+			f.afterWrite(f, written)
+		}
+	}()
+
+	writeJSON := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	}
+	path := strings.TrimPrefix(r.URL.Path, "/v1")
+
+	switch {
+	case path == "/meta":
+		writeJSON(http.StatusOK, map[string]string{"version": "1.30.1"})
+	case r.Method == http.MethodPost && path == "/schema":
+		var class models.Class
+		json.NewDecoder(r.Body).Decode(&class)
+		if f.classes[class.Class] == nil {
+			f.classes[class.Class] = map[string]*models.Object{}
+		}
+		writeJSON(http.StatusOK, class)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/schema/"):
+		className := strings.TrimPrefix(path, "/schema/")
+		if _, ok := f.classes[className]; !ok {
+			writeJSON(http.StatusNotFound, map[string]interface{}{})
+			return
+		}
+		writeJSON(http.StatusOK, models.Class{Class: className})
+	case r.Method == http.MethodGet && path == "/objects":
+		f.listObjects(r.URL.Query(), writeJSON)
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/objects/"):
+		parts := strings.Split(strings.TrimPrefix(path, "/objects/"), "/")
+		if len(parts) != 2 || f.classes[parts[0]][parts[1]] == nil {
+			writeJSON(http.StatusNotFound, map[string]interface{}{})
+			return
+		}
+		writeJSON(http.StatusOK, f.classes[parts[0]][parts[1]])
+	case r.Method == http.MethodPost && path == "/batch/objects":
+		var body struct {
+			Objects []*models.Object `json:"objects"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		res := []models.ObjectsGetResponse{}
+		for _, object := range body.Objects {
+			f.put(object)
+			written = append(written, object.Class)
+			res = append(res, models.ObjectsGetResponse{Object: *object})
+		}
+		writeJSON(http.StatusOK, res)
+	case r.Method == http.MethodDelete && path == "/batch/objects":
+		var body models.BatchDelete
+		json.NewDecoder(r.Body).Decode(&body)
+		matched := int64(0)
+		for _, id := range body.Match.Where.ValueTextArray {
+			if _, ok := f.classes[body.Match.Class][id]; ok {
+				delete(f.classes[body.Match.Class], id)
+				matched++
+			}
+		}
+		writeJSON(http.StatusOK, models.BatchDeleteResponse{Results: &models.BatchDeleteResponseResults{Matches: matched, Successful: matched}})
+	case r.Method == http.MethodPost && path == "/graphql":
+		var body struct {
+			Query string `json:"query"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		match := aggregateClassPattern.FindStringSubmatch(body.Query)
+		if match == nil {
+			writeJSON(http.StatusBadRequest, map[string]string{"error": "unsupported query"})
+			return
+		}
+		writeJSON(http.StatusOK, models.GraphQLResponse{Data: map[string]models.JSONObject{
+			"Aggregate": map[string]interface{}{
+				match[1]: []interface{}{map[string]interface{}{"meta": map[string]interface{}{"count": len(f.classes[match[1]])}}},
+			},
+		}})
+	default:
+		writeJSON(http.StatusNotFound, map[string]interface{}{})
+	}
+}
+
+func (f *fakeWeaviate) listObjects(query url.Values, writeJSON func(int, interface{})) {
+	className := query.Get("class")
+	ids := []string{}
+	for id := range f.classes[className] {
+		if id > query.Get("after") {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit < len(ids) {
+		ids = ids[:limit]
+	}
+	objects := []*models.Object{}
+	for _, id := range ids {
+		object := *f.classes[className][id]
+		if !strings.Contains(query.Get("include"), "vector") {
+			object.Vector = nil
+		}
+		objects = append(objects, &object)
+	}
+	writeJSON(http.StatusOK, models.ObjectsListResponse{Objects: objects, TotalResults: int64(len(objects))})
+}
+
+func startFakeWeaviate(t *testing.T) *fakeWeaviate {
+	fake := newFakeWeaviate()
+	hostAndPort := test_helpers.GetNextPort()
+	server := httptest.NewUnstartedServer(fake)
+	listener, err := test_helpers.BindToPort(t, hostAndPort)
+	if err != nil {
+		t.Fatalf("BindToPort failed: %v", err)
+	}
+	server.Listener = listener
+	server.Start()
+	t.Cleanup(server.Close)
+
+	host, port, _ := strings.Cut(hostAndPort, ":")
+	t.Setenv("WEAVIATE_HOST", host)
+	t.Setenv("WEAVIATE_PORT", port)
+	t.Setenv("WEAVIATE_SCHEME", "http")
+	t.Setenv("WEAVIATE_API_KEY_ALLOWED_KEYS", "test-weaviate-api-key")
+	return fake
+}
+
+func eventObject(className string, n int, name string) *models.Object {
+	return &models.Object{
+		Class:      className,
+		ID:         strfmt.UUID(fmt.Sprintf("00000000-0000-0000-0000-%012d", n)),
+		Properties: map[string]interface{}{"name": name},
+		Vector:     models.C11yVector{float32(n), 0.5},
+	}
+}
+
+// withMigrations swaps in `migrations` for one test
+func withMigrations(t *testing.T, migrations []Migration) {
+	original := Migrations
+	Migrations = migrations
+	t.Cleanup(func() {
+		Migrations = original
+		services.SetEventClassName(constants.WeaviateEventClassName)
+	})
+}
+
+func TestMigrationsRegistry(t *testing.T) {
+	if Latest().ClassName != constants.WeaviateEventClassName {
+		t.Errorf("latest migration class %s should be constants.WeaviateEventClassName (%s) so fresh installs start on it",
+			Latest().ClassName, constants.WeaviateEventClassName)
+	}
+	seenClasses := map[string]bool{}
+	for i, migration := range Migrations {
+		if migration.Version != i+1 {
+			t.Errorf("migration %d has version %d, versions must count up from 1", i, migration.Version)
+		}
+		if seenClasses[migration.ClassName] {
+			t.Errorf("class %s is used by more than one migration", migration.ClassName)
+		}
+		seenClasses[migration.ClassName] = true
+	}
+
+	withMigrations(t, []Migration{
+		{Version: 1, ClassName: "EventV1"},
+		{Version: 2, ClassName: "EventV2"},
+		{Version: 3, ClassName: constants.WeaviateEventClassName},
+	})
+	if pending := Pending(1); len(pending) != 2 || pending[0].Version != 2 || pending[1].Version != 3 {
+		t.Errorf("Pending(1) = %+v", pending)
+	}
+	if pending := Pending(3); len(pending) != 0 {
+		t.Errorf("expected nothing pending on the latest version, got %+v", pending)
+	}
+	if version := baselineVersion(); version != 3 {
+		t.Errorf("baselineVersion() = %d, want 3", version)
+	}
+}
+
+func TestShouldCopy(t *testing.T) {
+	source := &models.Object{CreationTimeUnix: 1000, LastUpdateTimeUnix: 2000}
+	tests := []struct {
+		name            string
+		targetUpdatedAt int64
+		inTarget        bool
+		opts            passOptions
+		want            bool
+	}{
+		{name: "missing from target", want: true},
+		{name: "target copy is current", targetUpdatedAt: 2500, inTarget: true, want: false},
+		{name: "source changed after copy", targetUpdatedAt: 1500, inTarget: true, want: true},
+		{name: "created after cutoff", opts: passOptions{createdSince: 900}, want: true},
+		// Missing after the alias moved and older than the cutoff, so it was
+		// deleted through the new class and must not come back
+		{name: "created before cutoff", opts: passOptions{createdSince: 1100}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shouldCopy(source, tt.targetUpdatedAt, tt.inTarget, tt.opts); got != tt.want {
+				t.Errorf("shouldCopy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateObject(t *testing.T) {
+	source := eventObject("EventV1", 7, "Trivia")
+
+	copied, err := migrateObject(source, Migration{ClassName: "EventV2"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if copied.Class != "EventV2" || copied.ID != source.ID || copied.Properties.(map[string]interface{})["name"] != "Trivia" {
+		t.Errorf("unexpected copy %+v", copied)
+	}
+	if len(copied.Vector) != 2 {
+		t.Errorf("expected the vector to be carried over, got %v", copied.Vector)
+	}
+
+	upper := Migration{
+		ClassName:   "EventV2",
+		Revectorize: true,
+		Transform: func(properties map[string]interface{}) (map[string]interface{}, error) {
+			properties["name"] = strings.ToUpper(properties["name"].(string))
+			return properties, nil
+		},
+	}
+	copied, err = migrateObject(source, upper)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if copied.Properties.(map[string]interface{})["name"] != "TRIVIA" {
+		t.Errorf("expected transformed name, got %v", copied.Properties)
+	}
+	if copied.Vector != nil {
+		t.Errorf("expected no vector when revectorizing, got %v", copied.Vector)
+	}
+
+	failing := Migration{ClassName: "EventV2", Transform: func(map[string]interface{}) (map[string]interface{}, error) {
+		return nil, fmt.Errorf("bad row")
+	}}
+	if _, err := migrateObject(source, failing); err == nil || !strings.Contains(err.Error(), string(source.ID)) {
+		t.Errorf("expected a transform error naming the object, got %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	fake := startFakeWeaviate(t)
+	withMigrations(t, []Migration{
+		{Version: 1, ClassName: "EventV1"},
+		{Version: 2, ClassName: "EventV2", Transform: func(properties map[string]interface{}) (map[string]interface{}, error) {
+			properties["name"] = strings.ToUpper(properties["name"].(string))
+			return properties, nil
+		}},
+	})
+	for n := 1; n <= 5; n++ {
+		fake.Put(eventObject("EventV1", n, fmt.Sprintf("event %d", n)))
+	}
+	// Left over from an interrupted run: one object since deleted from the
+	// source and one that changed after it was copied
+	fake.Put(eventObject("EventV2", 99, "deleted"))
+	fake.Put(eventObject("EventV2", 3, "OLD COPY"))
+	time.Sleep(2 * time.Millisecond)
+	fake.Put(eventObject("EventV1", 3, "event 3 renamed"))
+
+	client, err := services.GetWeaviateClient()
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	ctx := context.Background()
+	if err := services.SetSchemaAlias(ctx, client, services.SchemaAlias{Alias: services.EVENT_CLASS_ALIAS, ClassName: "EventV1", Version: 1}); err != nil {
+		t.Fatalf("failed to set alias: %v", err)
+	}
+
+	t.Run("dry run writes nothing", func(t *testing.T) {
+		results, err := Run(ctx, client, Options{DryRun: true, BatchSize: 2})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(results) != 1 || !results[0].DryRun || results[0].SourceCount != 5 {
+			t.Errorf("unexpected dry run results %+v", results)
+		}
+		if fake.Count("EventV2") != 2 {
+			t.Errorf("expected dry run to leave the target alone")
+		}
+		if alias, _ := services.GetSchemaAlias(ctx, client, services.EVENT_CLASS_ALIAS); alias.ClassName != "EventV1" {
+			t.Errorf("expected dry run to leave the alias on EventV1, got %s", alias.ClassName)
+		}
+	})
+
+	// An instance that hasn't picked up the flip yet writes to the old class
+	fake.afterWrite = func(f *fakeWeaviate, classNames []string) {
+		for _, className := range classNames {
+			if className == services.SCHEMA_ALIAS_CLASS_NAME {
+				f.afterWrite = nil
+				f.Put(eventObject("EventV1", 6, "late write"))
+			}
+		}
+	}
+
+	results, err := Run(ctx, client, Options{BatchSize: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected one migration, got %+v", results)
+	}
+	result := results[0]
+	if result.FromClass != "EventV1" || result.ToClass != "EventV2" || result.Version != 2 {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if result.Pruned != 1 || result.Stragglers != 1 || result.TargetCount != 6 {
+		t.Errorf("expected 1 pruned, 1 late write and 6 objects, got %+v", result)
+	}
+
+	if fake.Get("EventV2", "00000000-0000-0000-0000-000000000099") != nil {
+		t.Errorf("expected the object deleted from the source to be pruned")
+	}
+	if object := fake.Get("EventV2", "00000000-0000-0000-0000-000000000003"); object.Properties.(map[string]interface{})["name"] != "EVENT 3 RENAMED" {
+		t.Errorf("expected the stale copy to be refreshed, got %v", object.Properties)
+	}
+	if object := fake.Get("EventV2", "00000000-0000-0000-0000-000000000006"); object == nil || len(object.Vector) != 2 {
+		t.Errorf("expected the late write copied with its vector, got %+v", object)
+	}
+
+	alias, err := services.GetSchemaAlias(ctx, client, services.EVENT_CLASS_ALIAS)
+	if err != nil || alias.ClassName != "EventV2" || alias.Version != 2 {
+		t.Errorf("expected the alias on EventV2 version 2, got %+v (%v)", alias, err)
+	}
+	if services.EventClassName() != "EventV2" {
+		t.Errorf("expected this process to switch classes, got %s", services.EventClassName())
+	}
+
+	if results, err := Run(ctx, client, Options{}); err != nil || len(results) != 0 {
+		t.Errorf("expected nothing left to run, got %+v (%v)", results, err)
+	}
+	if _, err := Resync(ctx, client, "EventV2", Options{}); err == nil {
+		t.Errorf("expected resync from the live class to fail")
+	}
+
+	t.Run("point back at the old class", func(t *testing.T) {
+		if _, err := PointEventAlias(ctx, client, "EventMissing"); err == nil {
+			t.Errorf("expected an error for a class no migration uses")
+		}
+		alias, err := PointEventAlias(ctx, client, "EventV1")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if alias.Version != 1 {
+			t.Errorf("expected version 1, got %d", alias.Version)
+		}
+		if pending := Pending(alias.Version); len(pending) != 1 {
+			t.Errorf("expected the migration to be pending again, got %+v", pending)
+		}
+	})
+}
+
+func TestEnsureEventAlias(t *testing.T) {
+	startFakeWeaviate(t)
+	client, err := services.GetWeaviateClient()
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	ctx := context.Background()
+
+	alias, err := EnsureEventAlias(ctx, client)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if alias.ClassName != constants.WeaviateEventClassName || alias.Version != baselineVersion() {
+		t.Errorf("expected a new alias on the baseline class, got %+v", alias)
+	}
+
+	if err := services.SetSchemaAlias(ctx, client, services.SchemaAlias{Alias: services.EVENT_CLASS_ALIAS, ClassName: "EventOther", Version: 7}); err != nil {
+		t.Fatalf("failed to set alias: %v", err)
+	}
+	if alias, err = EnsureEventAlias(ctx, client); err != nil || alias.ClassName != "EventOther" {
+		t.Errorf("expected an existing alias to be kept, got %+v (%v)", alias, err)
+	}
+}
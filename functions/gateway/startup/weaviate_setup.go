@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/meetnearme/api/functions/gateway/services"
+	"github.com/meetnearme/api/functions/gateway/startup/weaviate_migrations"
 
 	_ "github.com/joho/godotenv/autoload"
 )
@@ -21,7 +22,18 @@ func InitWeaviate() error {
 	}
 	log.Println("Successfully connected to Weaviate.")
 
-	err = services.CreateWeaviateSchemaIfMissing(context.Background(), client)
+	ctx := context.Background()
+	schemaAlias, err := weaviate_migrations.EnsureEventAlias(ctx, client)
+	if err != nil {
+		return fmt.Errorf("could not read Weaviate events class alias: %w", err)
+	}
+	services.SetEventClassName(schemaAlias.ClassName)
+	if pending := weaviate_migrations.Pending(schemaAlias.Version); len(pending) > 0 {
+		log.Printf("WARN: Weaviate events class '%s' is at version %d, %d migration(s) pending. Run cmd/migrate_weaviate_db to apply them",
+			schemaAlias.ClassName, schemaAlias.Version, len(pending))
+	}
+
+	err = services.CreateWeaviateSchemaIfMissing(ctx, client)
 	if err != nil {
 		return fmt.Errorf("could not define Weaviate schema: %w", err)
 	}
@@ -37,6 +49,10 @@ func init() {
 		log.Println("Skipping Weaviate initialization in test environment")
 		return
 	}
+	if os.Getenv("EVENT_STORE") == services.EVENT_STORE_MEMORY {
+		log.Println("Skipping Weaviate initialization, EVENT_STORE=memory")
+		return
+	}
 
 	if err := InitWeaviate(); err != nil {
 		log.Fatalf("Weaviate setup failed: %v", err)
@@ -19,7 +19,7 @@ func TestNestedCheckboxList(t *testing.T) {
 		{
 			name:            string("Nested checkbox list, in dropdown"),
 			isInDropdown:    true,
-			expectedContent: []string{"Civic &amp; Advocacy", "<summary"},
+			expectedContent: []string{"Civic &amp; Advocacy", "<summary", `data-category-slug="civic-advocacy"`, "data-facet-count"},
 			interests:       []string{},
 		},
 		{
@@ -55,6 +55,7 @@ func TestEventDetailsPage(t *testing.T) {
 				"12:00am",
 				"abc-uuid",
 				"Brians Pub",
+				`hx-get="/api/html/events/123/similar"`,
 			},
 			canEdit: false,
 		},
@@ -360,3 +361,37 @@ func TestEventDetailsPage(t *testing.T) {
 		})
 	}
 }
+
+func TestSimilarEvents(t *testing.T) {
+	loc, _ := time.LoadLocation("America/New_York")
+	events := []types.Event{
+		{
+			Id:              "similar-1",
+			Name:            "Jazz in the Park",
+			Address:         "1 Park Ave",
+			StartTime:       time.Date(2099, 5, 2, 19, 0, 0, 0, loc).Unix(),
+			EventSourceType: constants.ES_SINGLE_EVENT,
+			Lat:             40.7128,
+			Long:            -74.0060,
+			Timezone:        *loc,
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := SimilarEvents(events).Render(context.Background(), &buf); err != nil {
+		t.Fatalf("Error rendering component: %v", err)
+	}
+	for _, exp := range []string{"MORE LIKE THIS", "Jazz in the Park", "/event/similar-1", "May 2, 2099"} {
+		if !strings.Contains(buf.String(), exp) {
+			t.Errorf("Expected string not found: %s", exp)
+		}
+	}
+
+	buf.Reset()
+	if err := SimilarEvents([]types.Event{}).Render(context.Background(), &buf); err != nil {
+		t.Fatalf("Error rendering component: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "" {
+		t.Errorf("Expected no markup without recommendations, got %s", buf.String())
+	}
+}
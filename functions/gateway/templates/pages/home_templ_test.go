@@ -883,6 +883,31 @@ func TestEventsInnerEdgeCases(t *testing.T) {
 				"No events found",
 			},
 		},
+		{
+			name: "EV_MODE_UPCOMING with a ranked event explains its rank",
+			mode: constants.EV_MODE_UPCOMING,
+			events: []types.Event{
+				{
+					Id:              "event-ranked",
+					Name:            "Ranked Event",
+					Address:         "123 Main St",
+					Lat:             40.7128,
+					Long:            -74.0060,
+					StartTime:       1704067200,
+					Timezone:        *loc,
+					EventOwners:     []string{"owner-1"},
+					EventSourceType: constants.ES_SINGLE_EVENT,
+					Rank: &types.EventRank{
+						Score:   0.5,
+						Reasons: []string{"Matches your interest in Book clubs", "Happening soon"},
+					},
+				},
+			},
+			expectedItems: []string{
+				"data-feed-reasons",
+				"Matches your interest in Book clubs · Happening soon",
+			},
+		},
 	}
 
 	for _, tt := range tests {
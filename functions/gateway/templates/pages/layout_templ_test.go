@@ -97,6 +97,64 @@ func TestLayout(t *testing.T) {
 	}
 }
 
+func TestLayoutPageMeta(t *testing.T) {
+	t.Run("no page meta in context", func(t *testing.T) {
+		var buf bytes.Buffer
+		err := Layout(constants.SitePages["about"], constants.UserInfo{}, templ.Raw("hello world!"), types.Event{}, false, context.Background(), []string{}).Render(context.Background(), &buf)
+		if err != nil {
+			t.Fatalf("Error rendering Layout: %v", err)
+		}
+		for _, unexpected := range []string{`property="og:title"`, `application/ld+json`} {
+			if strings.Contains(buf.String(), unexpected) {
+				t.Errorf("Expected rendered content not to contain '%s'", unexpected)
+			}
+		}
+	})
+
+	t.Run("page meta in context", func(t *testing.T) {
+		ctx := context.WithValue(context.Background(), constants.PAGE_META_CTX_KEY, constants.PageMeta{
+			Title:       "Trivia & Tacos",
+			Description: "Weekly trivia night",
+			Url:         "https://example.com/event/123",
+			ImageUrl:    "https://example.com/trivia.jpg",
+			OgType:      "website",
+			JSONLD: map[string]string{
+				"@context": "https://schema.org",
+				"@type":    "Event",
+				"name":     "Trivia & Tacos </script>",
+			},
+		})
+		event := types.Event{Id: "123", Name: "Trivia & Tacos"}
+		var buf bytes.Buffer
+		err := Layout(constants.SitePages["event-detail"], constants.UserInfo{}, templ.Raw("hello world!"), event, false, ctx, []string{}).Render(ctx, &buf)
+		if err != nil {
+			t.Fatalf("Error rendering Layout: %v", err)
+		}
+		rendered := buf.String()
+		for _, expected := range []string{
+			`<meta name="description" content="Weekly trivia night">`,
+			`<link rel="canonical" href="https://example.com/event/123">`,
+			`<meta property="og:type" content="website">`,
+			`<meta property="og:title" content="Trivia &amp; Tacos">`,
+			`<meta property="og:url" content="https://example.com/event/123">`,
+			`<meta property="og:image" content="https://example.com/trivia.jpg">`,
+			`<meta name="twitter:card" content="summary_large_image">`,
+			`<meta name="twitter:image" content="https://example.com/trivia.jpg">`,
+			`<script type="application/ld+json">`,
+			`"@type":"Event"`,
+			// The JSON encoder escapes markup so the payload can't close the script early
+			`Trivia \u0026 Tacos \u003c/script\u003e`,
+		} {
+			if !strings.Contains(rendered, expected) {
+				t.Errorf("Expected rendered content to contain '%s'", expected)
+			}
+		}
+		if t.Failed() {
+			t.Logf("Rendered content:\n%s", rendered)
+		}
+	})
+}
+
 func TestGenerateSecondaryColor(t *testing.T) {
 	testCases := []struct {
 		name           string
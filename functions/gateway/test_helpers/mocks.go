@@ -20,6 +20,7 @@ import (
 	"errors"
 
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	dynamodb_types "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/rdsdata"
 	rds_types "github.com/aws/aws-sdk-go-v2/service/rdsdata/types"
 	"github.com/gorilla/mux"
@@ -36,6 +37,7 @@ type MockDynamoDBClient struct {
 	QueryFunc            func(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error)                   // New Query method
 	BatchWriteItemFunc   func(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) // New Query method
 	ExecuteStatementFunc func(ctx context.Context, params *dynamodb.ExecuteStatementInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteStatementOutput, error)
+	DescribeTableFunc    func(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error)
 }
 
 func (m *MockDynamoDBClient) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
@@ -86,6 +88,13 @@ func (m *MockDynamoDBClient) ExecuteStatement(ctx context.Context, params *dynam
 	return &dynamodb.ExecuteStatementOutput{}, nil
 }
 
+func (m *MockDynamoDBClient) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	if m.DescribeTableFunc != nil {
+		return m.DescribeTableFunc(ctx, params, optFns...)
+	}
+	return &dynamodb.DescribeTableOutput{Table: &dynamodb_types.TableDescription{TableName: params.TableName, TableStatus: dynamodb_types.TableStatusActive}}, nil
+}
+
 // MockGeoService
 type MockGeoService struct {
 	GetGeoFunc func(location, baseUrl string) (string, string, string, error)
@@ -419,11 +428,37 @@ func (lt *LoggingTransport) RoundTrip(req *http.Request) (*http.Response, error)
 }
 
 type MockPostgresService struct {
-	GetSeshuJobsFunc            func(ctx context.Context, limit, offset int) ([]types.SeshuJob, int64, error)
-	CreateSeshuJobFunc          func(ctx context.Context, job types.SeshuJob) error
-	UpdateSeshuJobFunc          func(ctx context.Context, job types.SeshuJob) error
-	DeleteSeshuJobFunc          func(ctx context.Context, id string) error
-	ScanSeshuJobsWithInHourFunc func(ctx context.Context, hours int) ([]types.SeshuJob, error)
+	GetSeshuJobsFunc              func(ctx context.Context, limit, offset int) ([]types.SeshuJob, int64, error)
+	CreateSeshuJobFunc            func(ctx context.Context, job types.SeshuJob) error
+	UpdateSeshuJobFunc            func(ctx context.Context, job types.SeshuJob) error
+	DeleteSeshuJobFunc            func(ctx context.Context, id string) error
+	ScanSeshuJobsWithInHourFunc   func(ctx context.Context, hours int) ([]types.SeshuJob, error)
+	GetAccountDeletionFunc        func(ctx context.Context, userId string) (*types.AccountDeletion, error)
+	SaveAccountDeletionFunc       func(ctx context.Context, deletion types.AccountDeletion) error
+	GetDueAccountDeletionsFunc    func(ctx context.Context, now int64, statuses []string) ([]types.AccountDeletion, error)
+	SaveQuarantinedEventsFunc     func(ctx context.Context, events []types.QuarantinedEvent) error
+	GetQuarantinedEventsFunc      func(ctx context.Context, ownerId string) ([]types.QuarantinedEvent, error)
+	GetQuarantinedEventFunc       func(ctx context.Context, id string) (*types.QuarantinedEvent, error)
+	DeleteQuarantinedEventFunc    func(ctx context.Context, id string) error
+	SaveWebhookSubscriptionFunc   func(ctx context.Context, subscription types.WebhookSubscription) error
+	GetWebhookSubscriptionsFunc   func(ctx context.Context, ownerId string) ([]types.WebhookSubscription, error)
+	GetWebhookSubscriptionFunc    func(ctx context.Context, id string) (*types.WebhookSubscription, error)
+	DeleteWebhookSubscriptionFunc func(ctx context.Context, id string) error
+	SaveWebhookDeliveriesFunc     func(ctx context.Context, deliveries []types.WebhookDelivery) error
+	UpdateWebhookDeliveryFunc     func(ctx context.Context, delivery types.WebhookDelivery) error
+	GetWebhookDeliveryFunc        func(ctx context.Context, id string) (*types.WebhookDelivery, error)
+	GetWebhookDeliveriesFunc      func(ctx context.Context, subscriptionId string, limit int) ([]types.WebhookDelivery, error)
+	SaveAPIKeyFunc                func(ctx context.Context, key types.APIKey) error
+	GetAPIKeysFunc                func(ctx context.Context, ownerId string) ([]types.APIKey, error)
+	GetAPIKeyFunc                 func(ctx context.Context, id string) (*types.APIKey, error)
+	GetAPIKeyByHashFunc           func(ctx context.Context, keyHash string) (*types.APIKey, error)
+	TouchAPIKeyFunc               func(ctx context.Context, id string, usedAt int64) error
+	DeleteAPIKeysFunc             func(ctx context.Context, ownerId string) (int64, error)
+	SaveDataExportFunc            func(ctx context.Context, export types.DataExport) error
+	GetDataExportFunc             func(ctx context.Context, id string) (*types.DataExport, error)
+	GetPendingDataExportFunc      func(ctx context.Context, userId string) (*types.DataExport, error)
+	GetDueDataExportsFunc         func(ctx context.Context, startedBefore int64) ([]types.DataExport, error)
+	DeleteExpiredDataExportsFunc  func(ctx context.Context, now int64) (int64, error)
 }
 
 func (m *MockPostgresService) GetSeshuJobs(ctx context.Context, limit, offset int) ([]types.SeshuJob, int64, error) {
@@ -461,6 +496,188 @@ func (m *MockPostgresService) ScanSeshuJobsWithInHour(ctx context.Context, hours
 	return []types.SeshuJob{}, nil
 }
 
+func (m *MockPostgresService) GetAccountDeletion(ctx context.Context, userId string) (*types.AccountDeletion, error) {
+	if m.GetAccountDeletionFunc != nil {
+		return m.GetAccountDeletionFunc(ctx, userId)
+	}
+	return nil, nil
+}
+
+func (m *MockPostgresService) SaveAccountDeletion(ctx context.Context, deletion types.AccountDeletion) error {
+	if m.SaveAccountDeletionFunc != nil {
+		return m.SaveAccountDeletionFunc(ctx, deletion)
+	}
+	return nil
+}
+
+func (m *MockPostgresService) GetDueAccountDeletions(ctx context.Context, now int64, statuses []string) ([]types.AccountDeletion, error) {
+	if m.GetDueAccountDeletionsFunc != nil {
+		return m.GetDueAccountDeletionsFunc(ctx, now, statuses)
+	}
+	return []types.AccountDeletion{}, nil
+}
+
+func (m *MockPostgresService) SaveQuarantinedEvents(ctx context.Context, events []types.QuarantinedEvent) error {
+	if m.SaveQuarantinedEventsFunc != nil {
+		return m.SaveQuarantinedEventsFunc(ctx, events)
+	}
+	return nil
+}
+
+func (m *MockPostgresService) GetQuarantinedEvents(ctx context.Context, ownerId string) ([]types.QuarantinedEvent, error) {
+	if m.GetQuarantinedEventsFunc != nil {
+		return m.GetQuarantinedEventsFunc(ctx, ownerId)
+	}
+	return []types.QuarantinedEvent{}, nil
+}
+
+func (m *MockPostgresService) GetQuarantinedEvent(ctx context.Context, id string) (*types.QuarantinedEvent, error) {
+	if m.GetQuarantinedEventFunc != nil {
+		return m.GetQuarantinedEventFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *MockPostgresService) DeleteQuarantinedEvent(ctx context.Context, id string) error {
+	if m.DeleteQuarantinedEventFunc != nil {
+		return m.DeleteQuarantinedEventFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockPostgresService) SaveWebhookSubscription(ctx context.Context, subscription types.WebhookSubscription) error {
+	if m.SaveWebhookSubscriptionFunc != nil {
+		return m.SaveWebhookSubscriptionFunc(ctx, subscription)
+	}
+	return nil
+}
+
+func (m *MockPostgresService) GetWebhookSubscriptions(ctx context.Context, ownerId string) ([]types.WebhookSubscription, error) {
+	if m.GetWebhookSubscriptionsFunc != nil {
+		return m.GetWebhookSubscriptionsFunc(ctx, ownerId)
+	}
+	return []types.WebhookSubscription{}, nil
+}
+
+func (m *MockPostgresService) GetWebhookSubscription(ctx context.Context, id string) (*types.WebhookSubscription, error) {
+	if m.GetWebhookSubscriptionFunc != nil {
+		return m.GetWebhookSubscriptionFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *MockPostgresService) DeleteWebhookSubscription(ctx context.Context, id string) error {
+	if m.DeleteWebhookSubscriptionFunc != nil {
+		return m.DeleteWebhookSubscriptionFunc(ctx, id)
+	}
+	return nil
+}
+
+func (m *MockPostgresService) SaveWebhookDeliveries(ctx context.Context, deliveries []types.WebhookDelivery) error {
+	if m.SaveWebhookDeliveriesFunc != nil {
+		return m.SaveWebhookDeliveriesFunc(ctx, deliveries)
+	}
+	return nil
+}
+
+func (m *MockPostgresService) UpdateWebhookDelivery(ctx context.Context, delivery types.WebhookDelivery) error {
+	if m.UpdateWebhookDeliveryFunc != nil {
+		return m.UpdateWebhookDeliveryFunc(ctx, delivery)
+	}
+	return nil
+}
+
+func (m *MockPostgresService) GetWebhookDelivery(ctx context.Context, id string) (*types.WebhookDelivery, error) {
+	if m.GetWebhookDeliveryFunc != nil {
+		return m.GetWebhookDeliveryFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetWebhookDeliveries(ctx context.Context, subscriptionId string, limit int) ([]types.WebhookDelivery, error) {
+	if m.GetWebhookDeliveriesFunc != nil {
+		return m.GetWebhookDeliveriesFunc(ctx, subscriptionId, limit)
+	}
+	return []types.WebhookDelivery{}, nil
+}
+
+func (m *MockPostgresService) SaveAPIKey(ctx context.Context, key types.APIKey) error {
+	if m.SaveAPIKeyFunc != nil {
+		return m.SaveAPIKeyFunc(ctx, key)
+	}
+	return nil
+}
+
+func (m *MockPostgresService) GetAPIKeys(ctx context.Context, ownerId string) ([]types.APIKey, error) {
+	if m.GetAPIKeysFunc != nil {
+		return m.GetAPIKeysFunc(ctx, ownerId)
+	}
+	return []types.APIKey{}, nil
+}
+
+func (m *MockPostgresService) GetAPIKey(ctx context.Context, id string) (*types.APIKey, error) {
+	if m.GetAPIKeyFunc != nil {
+		return m.GetAPIKeyFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetAPIKeyByHash(ctx context.Context, keyHash string) (*types.APIKey, error) {
+	if m.GetAPIKeyByHashFunc != nil {
+		return m.GetAPIKeyByHashFunc(ctx, keyHash)
+	}
+	return nil, nil
+}
+
+func (m *MockPostgresService) TouchAPIKey(ctx context.Context, id string, usedAt int64) error {
+	if m.TouchAPIKeyFunc != nil {
+		return m.TouchAPIKeyFunc(ctx, id, usedAt)
+	}
+	return nil
+}
+
+func (m *MockPostgresService) DeleteAPIKeys(ctx context.Context, ownerId string) (int64, error) {
+	if m.DeleteAPIKeysFunc != nil {
+		return m.DeleteAPIKeysFunc(ctx, ownerId)
+	}
+	return 0, nil
+}
+
+func (m *MockPostgresService) SaveDataExport(ctx context.Context, export types.DataExport) error {
+	if m.SaveDataExportFunc != nil {
+		return m.SaveDataExportFunc(ctx, export)
+	}
+	return nil
+}
+
+func (m *MockPostgresService) GetDataExport(ctx context.Context, id string) (*types.DataExport, error) {
+	if m.GetDataExportFunc != nil {
+		return m.GetDataExportFunc(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetPendingDataExport(ctx context.Context, userId string) (*types.DataExport, error) {
+	if m.GetPendingDataExportFunc != nil {
+		return m.GetPendingDataExportFunc(ctx, userId)
+	}
+	return nil, nil
+}
+
+func (m *MockPostgresService) GetDueDataExports(ctx context.Context, startedBefore int64) ([]types.DataExport, error) {
+	if m.GetDueDataExportsFunc != nil {
+		return m.GetDueDataExportsFunc(ctx, startedBefore)
+	}
+	return []types.DataExport{}, nil
+}
+
+func (m *MockPostgresService) DeleteExpiredDataExports(ctx context.Context, now int64) (int64, error) {
+	if m.DeleteExpiredDataExportsFunc != nil {
+		return m.DeleteExpiredDataExportsFunc(ctx, now)
+	}
+	return 0, nil
+}
+
 func (m *MockPostgresService) Close() error {
 	return nil
 }
@@ -182,6 +182,8 @@ func SetCORSAllowAll(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
 	// Include all headers that HTMX/json-enc might send
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Accept, HX-Request, HX-Trigger, HX-Trigger-Name, HX-Target, HX-Current-URL")
+	// Paging metadata for the events partial, see `handlers.GetEventsPartial`
+	w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, X-Next-Cursor")
 
 	// Handle preflight OPTIONS request
 	if r.Method == "OPTIONS" {
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// AccountDeletion is a user's request to delete their account. It's kept after
+// the account is gone, with the receipt, as the record that the deletion ran
+type AccountDeletion struct {
+	UserId       string                 `json:"userId" gorm:"column:user_id;primaryKey"`
+	RequestedBy  string                 `json:"requestedBy" gorm:"column:requested_by"`
+	Status       string                 `json:"status" gorm:"column:status"`
+	AnonymousId  string                 `json:"anonymousId" gorm:"column:anonymous_id"` // replaces the user ID on records kept for event owners
+	RequestedAt  int64                  `json:"requestedAt" gorm:"column:requested_at"`
+	ScheduledFor int64                  `json:"scheduledFor" gorm:"column:scheduled_for"` // end of the grace period
+	CompletedAt  int64                  `json:"completedAt,omitempty" gorm:"column:completed_at"`
+	Attempts     int                    `json:"attempts" gorm:"column:attempts"`
+	LastError    string                 `json:"lastError,omitempty" gorm:"column:last_error"`
+	Receipt      AccountDeletionReceipt `json:"receipt" gorm:"column:receipt;type:jsonb"`
+	UpdatedAt    int64                  `json:"updatedAt" gorm:"column:updated_at"`
+}
+
+func (AccountDeletion) TableName() string {
+	return "account_deletions"
+}
+
+// AccountDeletionReceiptEntry records one finished step. `Hash` chains over the
+// previous entry's hash so editing or dropping an entry breaks every hash after it
+type AccountDeletionReceiptEntry struct {
+	Step   string `json:"step"`
+	Count  int    `json:"count"`
+	Detail string `json:"detail,omitempty"`
+	At     int64  `json:"at"`
+	Hash   string `json:"hash"`
+}
+
+type AccountDeletionReceipt []AccountDeletionReceiptEntry
+
+func (r AccountDeletionReceipt) Value() (driver.Value, error) {
+	if r == nil {
+		r = AccountDeletionReceipt{}
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (r *AccountDeletionReceipt) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*r = AccountDeletionReceipt{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("unsupported receipt type %T", value)
+	}
+	return json.Unmarshal(data, r)
+}
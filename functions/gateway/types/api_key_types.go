@@ -0,0 +1,83 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/meetnearme/api/functions/gateway/constants"
+)
+
+// APIKeyScopes is what an API key may be used for, e.g. `events:write`
+type APIKeyScopes []string
+
+func (s APIKeyScopes) Value() (driver.Value, error) {
+	if s == nil {
+		s = APIKeyScopes{}
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (s *APIKeyScopes) Scan(value interface{}) error {
+	data, err := scanJSONColumn(value)
+	if err != nil || data == nil {
+		*s = APIKeyScopes{}
+		return err
+	}
+	return json.Unmarshal(data, s)
+}
+
+// APIKeyUserInfo is the owner's profile when the key was created, requests
+// made with the key see it as their `userInfo`
+type APIKeyUserInfo constants.UserInfo
+
+func (u APIKeyUserInfo) Value() (driver.Value, error) {
+	data, err := json.Marshal(u)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (u *APIKeyUserInfo) Scan(value interface{}) error {
+	data, err := scanJSONColumn(value)
+	if err != nil || data == nil {
+		*u = APIKeyUserInfo{}
+		return err
+	}
+	return json.Unmarshal(data, u)
+}
+
+// APIKey lets a headless client call the API as its owner without a
+// Zitadel session. Only the SHA-256 of the key is stored, the key itself is
+// shown once when it's created and `Prefix` tells keys apart after that
+type APIKey struct {
+	Id         string         `json:"id" gorm:"column:id;primaryKey"`
+	OwnerId    string         `json:"ownerId" gorm:"column:owner_id"`
+	Name       string         `json:"name" gorm:"column:name"`
+	Prefix     string         `json:"prefix" gorm:"column:prefix"`
+	KeyHash    string         `json:"-" gorm:"column:key_hash"`
+	Scopes     APIKeyScopes   `json:"scopes" gorm:"column:scopes;type:jsonb"`
+	UserInfo   APIKeyUserInfo `json:"-" gorm:"column:user_info;type:jsonb"`
+	CreatedAt  int64          `json:"createdAt" gorm:"column:created_at"`
+	LastUsedAt int64          `json:"lastUsedAt" gorm:"column:last_used_at"`
+	RevokedAt  int64          `json:"revokedAt" gorm:"column:revoked_at"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+type APIKeyInsert struct {
+	Name   string   `json:"name" validate:"required"`
+	Scopes []string `json:"scopes" validate:"required,min=1"`
+}
+
+// CreatedAPIKey is the response to creating a key, the only one with `Key`
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key"`
+}
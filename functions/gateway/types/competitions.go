@@ -27,6 +27,7 @@ type CompetitionRoundServiceInterface interface {
 type CompetitionWaitingRoomParticipantServiceInterface interface {
 	PutCompetitionWaitingRoomParticipant(ctx context.Context, dynamodbClient DynamoDBAPI, waitingRoomParticipant CompetitionWaitingRoomParticipantUpdate) (dynamodb.PutItemOutput, error)
 	GetCompetitionWaitingRoomParticipants(ctx context.Context, dynamodbClient DynamoDBAPI, competitionId string) ([]CompetitionWaitingRoomParticipant, error)
+	GetCompetitionWaitingRoomParticipantsByUserId(ctx context.Context, dynamodbClient DynamoDBAPI, userId string) ([]CompetitionWaitingRoomParticipant, error)
 	DeleteCompetitionWaitingRoomParticipant(ctx context.Context, dynamodbClient DynamoDBAPI, competitionId, userId string) error
 }
 
@@ -34,6 +35,7 @@ type CompetitionWaitingRoomParticipantServiceInterface interface {
 type CompetitionVoteServiceInterface interface {
 	PutCompetitionVote(ctx context.Context, dynamodbClient DynamoDBAPI, voteUpdate CompetitionVoteUpdate) (dynamodb.PutItemOutput, error)
 	GetCompetitionVotesByCompetitionRound(ctx context.Context, dynamodbClient DynamoDBAPI, compositePartitionKey string) ([]CompetitionVote, error)
+	GetCompetitionVotesByUserId(ctx context.Context, dynamodbClient DynamoDBAPI, userId string) ([]CompetitionVote, error)
 	DeleteCompetitionVote(ctx context.Context, dynamodbClient DynamoDBAPI, compositePartitionKey, userId string) error
 }
 
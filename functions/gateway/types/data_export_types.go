@@ -0,0 +1,52 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// DataExport is a user data export job. `Data` holds the ZIP once the export
+// is complete and is left out of listings
+type DataExport struct {
+	Id          string          `json:"id" gorm:"column:id;primaryKey"`
+	UserId      string          `json:"userId" gorm:"column:user_id"`
+	RequestedBy string          `json:"requestedBy" gorm:"column:requested_by"`
+	Status      string          `json:"status" gorm:"column:status"`
+	Error       string          `json:"error,omitempty" gorm:"column:error"`
+	Files       DataExportFiles `json:"files,omitempty" gorm:"column:files;type:jsonb"`
+	Size        int             `json:"size,omitempty" gorm:"column:size"`
+	Data        []byte          `json:"-" gorm:"column:data"`
+	Attempts    int             `json:"attempts" gorm:"column:attempts"`
+	CreatedAt   int64           `json:"createdAt" gorm:"column:created_at"`
+	StartedAt   int64           `json:"startedAt,omitempty" gorm:"column:started_at"` // when the last attempt began
+	CompletedAt int64           `json:"completedAt,omitempty" gorm:"column:completed_at"`
+	ExpiresAt   int64           `json:"expiresAt,omitempty" gorm:"column:expires_at"`
+	UpdatedAt   int64           `json:"updatedAt" gorm:"column:updated_at"`
+}
+
+func (DataExport) TableName() string {
+	return "data_exports"
+}
+
+// DataExportFiles counts the records in each file of an export
+type DataExportFiles map[string]int
+
+func (f DataExportFiles) Value() (driver.Value, error) {
+	if f == nil {
+		f = DataExportFiles{}
+	}
+	data, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (f *DataExportFiles) Scan(value interface{}) error {
+	data, err := scanJSONColumn(value)
+	if err != nil || data == nil {
+		*f = DataExportFiles{}
+		return err
+	}
+	return json.Unmarshal(data, f)
+}
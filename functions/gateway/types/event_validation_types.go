@@ -0,0 +1,101 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// EventValidationIssue is one rule an event in a batch broke. `Index` is the
+// event's position in the batch
+type EventValidationIssue struct {
+	Index    int    `json:"index"`
+	EventId  string `json:"eventId,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+type EventValidationIssues []EventValidationIssue
+
+func (i EventValidationIssues) Value() (driver.Value, error) {
+	if i == nil {
+		i = EventValidationIssues{}
+	}
+	data, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (i *EventValidationIssues) Scan(value interface{}) error {
+	data, err := scanJSONColumn(value)
+	if err != nil || data == nil {
+		*i = EventValidationIssues{}
+		return err
+	}
+	return json.Unmarshal(data, i)
+}
+
+// JSONDocument is a JSON value kept as is in a jsonb column
+type JSONDocument json.RawMessage
+
+func (d JSONDocument) MarshalJSON() ([]byte, error) {
+	if len(d) == 0 {
+		return []byte("null"), nil
+	}
+	return d, nil
+}
+
+func (d *JSONDocument) UnmarshalJSON(data []byte) error {
+	*d = append((*d)[:0], data...)
+	return nil
+}
+
+func (d JSONDocument) Value() (driver.Value, error) {
+	if len(d) == 0 {
+		return "null", nil
+	}
+	return string(d), nil
+}
+
+func (d *JSONDocument) Scan(value interface{}) error {
+	data, err := scanJSONColumn(value)
+	if err != nil {
+		return err
+	}
+	*d = append((*d)[:0], data...)
+	return nil
+}
+
+func scanJSONColumn(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported JSON column type %T", value)
+	}
+}
+
+// QuarantinedEvent is a Seshu-ingested event held back from publishing
+// because it broke an error-severity validation rule. `Event` is the
+// RawEvent as it would have been upserted
+type QuarantinedEvent struct {
+	Id          string                `json:"id" gorm:"column:id;primaryKey"`
+	OwnerId     string                `json:"ownerId" gorm:"column:owner_id"`
+	SeshuJobUrl string                `json:"seshuJobUrl" gorm:"column:seshu_job_url"`
+	Name        string                `json:"name" gorm:"column:name"`
+	StartTime   int64                 `json:"startTime" gorm:"column:start_time"`
+	Event       JSONDocument          `json:"event" gorm:"column:event;type:jsonb"`
+	Issues      EventValidationIssues `json:"issues" gorm:"column:issues;type:jsonb"`
+	CreatedAt   int64                 `json:"createdAt" gorm:"column:created_at"`
+}
+
+func (QuarantinedEvent) TableName() string {
+	return "quarantined_events"
+}
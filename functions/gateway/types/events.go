@@ -8,10 +8,46 @@ import (
 
 type Event = constants.Event
 
+type EventRank = constants.EventRank
+
 type EventSearchResponse struct {
-	Events []Event `json:"events"`
-	Filter string  `json:"filter,omitempty"`
-	Query  string  `json:"query,omitempty"`
+	Events     []Event            `json:"events"`
+	Filter     string             `json:"filter,omitempty"`
+	Query      string             `json:"query,omitempty"`
+	Total      int                `json:"total"`
+	NextCursor string             `json:"nextCursor,omitempty"`
+	HasMore    bool               `json:"hasMore"`
+	Facets     *EventSearchFacets `json:"facets,omitempty"`
+}
+
+// EventSearchFacets counts how the events matching a search's filters break
+// down, so filter UIs can show counts next to each option
+type EventSearchFacets struct {
+	Categories []EventFacetCount `json:"categories"`
+	Owners     []EventFacetCount `json:"owners"`
+	DateBucket string            `json:"dateBucket,omitempty"`
+	Dates      []EventDateBucket `json:"dates"`
+	Price      EventPriceFacet   `json:"price"`
+}
+
+type EventFacetCount struct {
+	Key   string `json:"key"`
+	Label string `json:"label,omitempty"`
+	Count int    `json:"count"`
+}
+
+// EventDateBucket covers `[Start, End)` in unix seconds, `Label` is the
+// bucket's first day in the requester's timezone
+type EventDateBucket struct {
+	Label string `json:"label"`
+	Start int64  `json:"start"`
+	End   int64  `json:"end"`
+	Count int    `json:"count"`
+}
+
+type EventPriceFacet struct {
+	Free int `json:"free"`
+	Paid int `json:"paid"`
 }
 
 type EventService interface {
@@ -0,0 +1,31 @@
+package types
+
+const (
+	GeoJSONFeatureCollectionType = "FeatureCollection"
+	GeoJSONFeatureType           = "Feature"
+	GeoJSONPointType             = "Point"
+)
+
+// GeoJSONFeatureCollection is the RFC 7946 envelope returned by the map
+// search. `Truncated` is a foreign member set when the viewport held more
+// events than the search limit
+type GeoJSONFeatureCollection struct {
+	Type      string           `json:"type"`
+	BBox      []float64        `json:"bbox,omitempty"`
+	Features  []GeoJSONFeature `json:"features"`
+	Total     int              `json:"total"`
+	Truncated bool             `json:"truncated"`
+}
+
+type GeoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Id         string                 `json:"id,omitempty"`
+	Geometry   GeoJSONPoint           `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// GeoJSONPoint coordinates are `[longitude, latitude]`
+type GeoJSONPoint struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
@@ -102,4 +102,5 @@ type PurchaseServiceInterface interface {
 	UpdatePurchase(ctx context.Context, dynamodbClient DynamoDBAPI, eventId, userId, createdAtString string, Purchase PurchaseUpdate) (*Purchase, error)
 	DeletePurchase(ctx context.Context, dynamodbClient DynamoDBAPI, eventId, userId string) error
 	HasPurchaseForEvent(ctx context.Context, dynamodbClient DynamoDBAPI, childEventId, parentEventId, userId string) (bool, error)
+	AnonymizePurchase(ctx context.Context, dynamodbClient DynamoDBAPI, purchase Purchase, anonymousUserId string) (*Purchase, error)
 }
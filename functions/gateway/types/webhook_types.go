@@ -0,0 +1,83 @@
+package types
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+// WebhookEventTypes is the event types a subscription is sent
+type WebhookEventTypes []string
+
+func (t WebhookEventTypes) Value() (driver.Value, error) {
+	if t == nil {
+		t = WebhookEventTypes{}
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (t *WebhookEventTypes) Scan(value interface{}) error {
+	data, err := scanJSONColumn(value)
+	if err != nil || data == nil {
+		*t = WebhookEventTypes{}
+		return err
+	}
+	return json.Unmarshal(data, t)
+}
+
+// WebhookSubscription is an endpoint an owner registered to be pushed
+// changes to their events, purchases and competitions. `Secret` signs every
+// delivery and is only shown when the subscription is created
+type WebhookSubscription struct {
+	Id          string            `json:"id" gorm:"column:id;primaryKey"`
+	OwnerId     string            `json:"ownerId" gorm:"column:owner_id"`
+	Url         string            `json:"url" gorm:"column:url"`
+	Description string            `json:"description" gorm:"column:description"`
+	EventTypes  WebhookEventTypes `json:"eventTypes" gorm:"column:event_types;type:jsonb"`
+	Secret      string            `json:"secret,omitempty" gorm:"column:secret"`
+	CreatedAt   int64             `json:"createdAt" gorm:"column:created_at"`
+}
+
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// WebhookSubscriptionInsert is the body of a new subscription
+type WebhookSubscriptionInsert struct {
+	Url         string   `json:"url" validate:"required"`
+	Description string   `json:"description"`
+	EventTypes  []string `json:"eventTypes" validate:"required,min=1"`
+}
+
+// WebhookDelivery is one change sent, or being sent, to one subscription.
+// `Payload` is the exact body POSTed on every attempt
+type WebhookDelivery struct {
+	Id             string       `json:"id" gorm:"column:id;primaryKey"`
+	SubscriptionId string       `json:"subscriptionId" gorm:"column:subscription_id"`
+	OwnerId        string       `json:"ownerId" gorm:"column:owner_id"`
+	EventType      string       `json:"eventType" gorm:"column:event_type"`
+	Payload        JSONDocument `json:"payload" gorm:"column:payload;type:jsonb"`
+	Status         string       `json:"status" gorm:"column:status"`
+	Attempts       int          `json:"attempts" gorm:"column:attempts"`
+	ResponseStatus int          `json:"responseStatus" gorm:"column:response_status"`
+	LastError      string       `json:"lastError" gorm:"column:last_error"`
+	NextAttemptAt  int64        `json:"nextAttemptAt" gorm:"column:next_attempt_at"`
+	DeliveredAt    int64        `json:"deliveredAt" gorm:"column:delivered_at"`
+	CreatedAt      int64        `json:"createdAt" gorm:"column:created_at"`
+	UpdatedAt      int64        `json:"updatedAt" gorm:"column:updated_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+// WebhookEnvelope is the JSON body of a delivery
+type WebhookEnvelope struct {
+	Id        string      `json:"id"`
+	Type      string      `json:"type"`
+	CreatedAt int64       `json:"createdAt"`
+	Data      interface{} `json:"data"`
+}
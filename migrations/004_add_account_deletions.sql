@@ -0,0 +1,20 @@
+-- Migration 004: Add account_deletions table
+-- Tracks account deletion requests through their grace period and each store
+-- the deletion has been applied to, so a failed deletion resumes where it stopped
+
+CREATE TABLE IF NOT EXISTS account_deletions (
+    user_id TEXT PRIMARY KEY,
+    requested_by TEXT NOT NULL,
+    status TEXT NOT NULL,
+    anonymous_id TEXT NOT NULL,
+    requested_at BIGINT NOT NULL,
+    scheduled_for BIGINT NOT NULL,
+    completed_at BIGINT NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    receipt JSONB NOT NULL DEFAULT '[]'::jsonb,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS account_deletions_status_scheduled_for_idx
+    ON account_deletions (status, scheduled_for);
@@ -0,0 +1,17 @@
+-- Migration 005: Add quarantined_events table
+-- Holds Seshu-ingested events that broke an error-severity validation rule
+-- until their owner publishes or discards them
+
+CREATE TABLE IF NOT EXISTS quarantined_events (
+    id TEXT PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    seshu_job_url TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    start_time BIGINT NOT NULL DEFAULT 0,
+    event JSONB NOT NULL,
+    issues JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS quarantined_events_owner_id_created_at_idx
+    ON quarantined_events (owner_id, created_at);
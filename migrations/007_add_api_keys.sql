@@ -0,0 +1,21 @@
+-- Migration 007: Add api_keys table
+-- Owner-scoped keys for headless clients, stored as a SHA-256 of the key
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '[]'::jsonb,
+    user_info JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at BIGINT NOT NULL,
+    last_used_at BIGINT NOT NULL DEFAULT 0,
+    revoked_at BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_keys_key_hash_idx
+    ON api_keys (key_hash);
+
+CREATE INDEX IF NOT EXISTS api_keys_owner_id_idx
+    ON api_keys (owner_id);
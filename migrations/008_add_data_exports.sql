@@ -0,0 +1,29 @@
+-- Migration 008: Add data_exports table
+-- Data export jobs and their finished ZIPs, so any instance can report on or
+-- serve an export the ACT worker built
+
+CREATE TABLE IF NOT EXISTS data_exports (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    requested_by TEXT NOT NULL,
+    status TEXT NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    files JSONB NOT NULL DEFAULT '{}'::jsonb,
+    size INTEGER NOT NULL DEFAULT 0,
+    data BYTEA,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    started_at BIGINT NOT NULL DEFAULT 0,
+    completed_at BIGINT NOT NULL DEFAULT 0,
+    expires_at BIGINT NOT NULL DEFAULT 0,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_status_started_at_idx
+    ON data_exports (status, started_at);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_id_idx
+    ON data_exports (user_id);
+
+CREATE INDEX IF NOT EXISTS data_exports_expires_at_idx
+    ON data_exports (expires_at);
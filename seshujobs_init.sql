@@ -33,3 +33,85 @@ CREATE TABLE IF NOT EXISTS seshujobs (
     owner_id TEXT NOT NULL,
     known_scrape_source TEXT NOT NULL
 );
+
+CREATE TABLE IF NOT EXISTS account_deletions (
+    user_id TEXT PRIMARY KEY,
+    requested_by TEXT NOT NULL,
+    status TEXT NOT NULL,
+    anonymous_id TEXT NOT NULL,
+    requested_at BIGINT NOT NULL,
+    scheduled_for BIGINT NOT NULL,
+    completed_at BIGINT NOT NULL DEFAULT 0,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    receipt JSONB NOT NULL DEFAULT '[]'::jsonb,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS account_deletions_status_scheduled_for_idx
+    ON account_deletions (status, scheduled_for);
+
+CREATE TABLE IF NOT EXISTS quarantined_events (
+    id TEXT PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    seshu_job_url TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    start_time BIGINT NOT NULL DEFAULT 0,
+    event JSONB NOT NULL,
+    issues JSONB NOT NULL DEFAULT '[]'::jsonb,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS quarantined_events_owner_id_created_at_idx
+    ON quarantined_events (owner_id, created_at);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id TEXT PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    url TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    event_types JSONB NOT NULL DEFAULT '[]'::jsonb,
+    secret TEXT NOT NULL,
+    created_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_subscriptions_owner_id_idx
+    ON webhook_subscriptions (owner_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id TEXT PRIMARY KEY,
+    subscription_id TEXT NOT NULL,
+    owner_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    next_attempt_at BIGINT NOT NULL DEFAULT 0,
+    delivered_at BIGINT NOT NULL DEFAULT 0,
+    created_at BIGINT NOT NULL,
+    updated_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_id_created_at_idx
+    ON webhook_deliveries (subscription_id, created_at);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    owner_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL,
+    key_hash TEXT NOT NULL,
+    scopes JSONB NOT NULL DEFAULT '[]'::jsonb,
+    user_info JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at BIGINT NOT NULL,
+    last_used_at BIGINT NOT NULL DEFAULT 0,
+    revoked_at BIGINT NOT NULL DEFAULT 0
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_keys_key_hash_idx
+    ON api_keys (key_hash);
+
+CREATE INDEX IF NOT EXISTS api_keys_owner_id_idx
+    ON api_keys (owner_id);